@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -8,30 +9,40 @@ import (
 )
 
 type Config struct {
-	Server     ServerConfig      `yaml:"server"`
-	Log        LogConfig         `yaml:"log"`
-	Database   DatabaseConfig    `yaml:"database"`
-	Storage    StorageConfig     `yaml:"storage"`
-	Redis      RedisConfig       `yaml:"redis"`
-	JWT        JWTConfig         `yaml:"jwt"`
-	AuthCookie AuthCookieConfig  `yaml:"auth_cookie"`
-	CSRF       CSRFConfig        `yaml:"csrf"`
-	Thumbnail  ThumbnailConfig   `yaml:"thumbnail"`
-	RecycleBin RecycleBinConfig  `yaml:"recycle_bin"`
-	Pagination PaginationConfig  `yaml:"pagination"`
-	Health     HealthCheckConfig `yaml:"health_check"`
+	Server         ServerConfig         `yaml:"server"`
+	Log            LogConfig            `yaml:"log"`
+	Database       DatabaseConfig       `yaml:"database"`
+	Storage        StorageConfig        `yaml:"storage"`
+	Redis          RedisConfig          `yaml:"redis"`
+	UploadProgress UploadProgressConfig `yaml:"upload_progress"`
+	JWT            JWTConfig            `yaml:"jwt"`
+	DownloadURL    DownloadURLConfig    `yaml:"download_url"`
+	AuthCookie     AuthCookieConfig     `yaml:"auth_cookie"`
+	CSRF           CSRFConfig           `yaml:"csrf"`
+	Thumbnail      ThumbnailConfig      `yaml:"thumbnail"`
+	Archive        ArchiveConfig        `yaml:"archive"`
+	RecycleBin     RecycleBinConfig     `yaml:"recycle_bin"`
+	Pagination     PaginationConfig     `yaml:"pagination"`
+	Health         HealthCheckConfig    `yaml:"health_check"`
+	Metrics        MetricsConfig        `yaml:"metrics"`
 }
 
 type ServerConfig struct {
 	Port int    `yaml:"port"`
 	Host string `yaml:"host"`
+	// TrustedProxies 为可信反向代理的 IP 或 CIDR，只有来自这些地址的 X-Forwarded-For / X-Forwarded-Proto 才被采信；默认不信任任何代理。
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type LogConfig struct {
 	Level string `yaml:"level"`
+	// Format 可选 json | text，默认 json。
+	Format string `yaml:"format"`
 }
 
 type DatabaseConfig struct {
+	// Driver 可选 mysql | postgres | sqlite，默认 mysql。
+	Driver       string `yaml:"driver"`
 	Host         string `yaml:"host"`
 	Port         int    `yaml:"port"`
 	Username     string `yaml:"username"`
@@ -40,6 +51,12 @@ type DatabaseConfig struct {
 	Charset      string `yaml:"charset"`
 	MaxIdleConns int    `yaml:"max_idle_conns"`
 	MaxOpenConns int    `yaml:"max_open_conns"`
+	// SSLMode 仅 postgres 使用，默认 disable。
+	SSLMode string `yaml:"ssl_mode"`
+	// Path 仅 sqlite 使用，为数据库文件路径。
+	Path string `yaml:"path"`
+	// SlowThresholdMs 为慢查询阈值（毫秒），超过时以 warn 级别记录，默认 200，负数关闭。
+	SlowThresholdMs int `yaml:"slow_threshold_ms"`
 }
 
 type StorageConfig struct {
@@ -61,12 +78,31 @@ type RedisConfig struct {
 	UploadTaskExpire int    `yaml:"upload_task_expire"`
 }
 
+type UploadProgressConfig struct {
+	// Store 可选 redis | memory | database | none，默认 redis；none 不记录进度，续传时只扫描磁盘上的分片。
+	Store string `yaml:"store"`
+	// Fallback 为 Redis 不可用时的降级存储：memory | database | none，默认 memory。
+	Fallback string `yaml:"fallback"`
+	// RetryInterval 为 Redis 故障后重新尝试的间隔（秒），默认 30。
+	RetryInterval int `yaml:"retry_interval"`
+}
+
 type JWTConfig struct {
 	Secret             string `yaml:"secret"`
 	ExpireHours        int    `yaml:"expire_hours"`
 	RefreshExpireHours int    `yaml:"refresh_expire_hours"`
 }
 
+// DownloadURLConfig 配置免登录的签名下载直链。
+type DownloadURLConfig struct {
+	// Secret 为签名密钥，为空时由 JWT 密钥派生；修改后已签发的直链全部失效。
+	Secret string `yaml:"secret"`
+	// DefaultExpireSeconds 为未指定有效期时的默认值，默认 3600。
+	DefaultExpireSeconds int `yaml:"default_expire_seconds"`
+	// MaxExpireSeconds 为允许的最长有效期，默认 7 天。
+	MaxExpireSeconds int `yaml:"max_expire_seconds"`
+}
+
 type AuthCookieConfig struct {
 	AccessName  string `yaml:"access_name"`
 	RefreshName string `yaml:"refresh_name"`
@@ -89,6 +125,27 @@ type ThumbnailConfig struct {
 	AsyncGeneration bool `yaml:"async_generation"`
 	WorkerCount     int  `yaml:"worker_count"`
 	RetryMax        int  `yaml:"retry_max"`
+	// Presets 为命名尺寸预设（如网格小图、大图预览、全屏），VariantSizes 为按需变体允许的边长白名单。
+	Presets      map[string]ThumbnailPreset `yaml:"presets"`
+	VariantSizes []int                      `yaml:"variant_sizes"`
+}
+
+// ThumbnailPreset 描述一个命名缩略图尺寸；Fit 为 contain（等比缩放）或 cover（裁剪填满），Format 为 jpeg 或 webp。
+type ThumbnailPreset struct {
+	Width  int    `yaml:"width"`
+	Height int    `yaml:"height"`
+	Fit    string `yaml:"fit"`
+	Format string `yaml:"format"`
+}
+
+// ArchiveConfig 限制压缩包浏览与解压的资源占用，防御压缩炸弹。
+type ArchiveConfig struct {
+	// MaxEntries 为单个压缩包允许的最大条目数（含目录）。
+	MaxEntries int `yaml:"max_entries"`
+	// MaxTotalSize 为解压后的总字节数上限，tar.gz 的整个解压流同样受此限制。
+	MaxTotalSize int64 `yaml:"max_total_size"`
+	// MaxCompressionRatio 为解压大小与压缩大小之比的上限，超过 16MB 的条目才检查。
+	MaxCompressionRatio int `yaml:"max_compression_ratio"`
 }
 
 type RecycleBinConfig struct {
@@ -96,6 +153,9 @@ type RecycleBinConfig struct {
 	RetentionDays   int  `yaml:"retention_days"`
 	CleanupInterval int  `yaml:"cleanup_interval"`
 	MaxItemsPerUser int  `yaml:"max_items_per_user"`
+	// MinRetentionDays / MaxRetentionDays 限定用户可自行设置的保留天数范围。
+	MinRetentionDays int `yaml:"min_retention_days"`
+	MaxRetentionDays int `yaml:"max_retention_days"`
 }
 
 type PaginationConfig struct {
@@ -111,6 +171,15 @@ type HealthCheckConfig struct {
 	TimeoutMs int    `yaml:"timeout_ms"`
 }
 
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	// Listen 非空时在独立端口暴露指标（如 127.0.0.1:9091），否则挂在主服务上。
+	Listen string `yaml:"listen"`
+	// Token 非空时要求 Authorization: Bearer <token>；Listen 为空时必须配置，否则不暴露抓取端点。
+	Token string `yaml:"token"`
+}
+
 var AppConfig *Config
 
 func LoadConfig(path string) (*Config, error) {
@@ -125,6 +194,9 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	applyDefaults(&cfg)
+	if err := validateUploadProgress(cfg.UploadProgress); err != nil {
+		return nil, err
+	}
 
 	AppConfig = &cfg
 	return &cfg, nil
@@ -136,6 +208,16 @@ func applyDefaults(cfg *Config) {
 		level = "info"
 	}
 	cfg.Log.Level = level
+	cfg.Log.Format = strings.ToLower(strings.TrimSpace(cfg.Log.Format))
+	if cfg.Log.Format == "" {
+		cfg.Log.Format = "json"
+	}
+
+	applyDatabaseDefaults(&cfg.Database)
+	applyUploadProgressDefaults(&cfg.UploadProgress)
+	applyRecycleBinDefaults(&cfg.RecycleBin)
+	applyThumbnailDefaults(&cfg.Thumbnail)
+	applyArchiveDefaults(&cfg.Archive)
 
 	if cfg.AuthCookie.AccessName == "" {
 		cfg.AuthCookie.AccessName = "access_token"
@@ -152,6 +234,18 @@ func applyDefaults(cfg *Config) {
 	if cfg.CSRF.CookieName == "" {
 		cfg.CSRF.CookieName = "csrf_token"
 	}
+	if cfg.Metrics.Path == "" {
+		cfg.Metrics.Path = "/metrics"
+	}
+	if cfg.DownloadURL.DefaultExpireSeconds <= 0 {
+		cfg.DownloadURL.DefaultExpireSeconds = 3600
+	}
+	if cfg.DownloadURL.MaxExpireSeconds <= 0 {
+		cfg.DownloadURL.MaxExpireSeconds = 7 * 24 * 3600
+	}
+	if cfg.DownloadURL.MaxExpireSeconds < cfg.DownloadURL.DefaultExpireSeconds {
+		cfg.DownloadURL.MaxExpireSeconds = cfg.DownloadURL.DefaultExpireSeconds
+	}
 	if cfg.JWT.RefreshExpireHours == 0 {
 		if cfg.JWT.ExpireHours > 0 {
 			cfg.JWT.RefreshExpireHours = cfg.JWT.ExpireHours * 4
@@ -160,3 +254,122 @@ func applyDefaults(cfg *Config) {
 		}
 	}
 }
+
+func applyThumbnailDefaults(thumb *ThumbnailConfig) {
+	if len(thumb.Presets) == 0 {
+		thumb.Presets = map[string]ThumbnailPreset{
+			"small": {Width: 256, Height: 256, Fit: "cover", Format: "jpeg"},
+			"large": {Width: 1024, Height: 1024, Fit: "contain", Format: "jpeg"},
+			"full":  {Width: 2048, Height: 2048, Fit: "contain", Format: "jpeg"},
+		}
+	}
+	for name, preset := range thumb.Presets {
+		if preset.Fit == "" {
+			preset.Fit = "contain"
+		}
+		if preset.Format == "" {
+			preset.Format = "jpeg"
+		}
+		thumb.Presets[name] = preset
+	}
+	if len(thumb.VariantSizes) == 0 {
+		thumb.VariantSizes = []int{64, 128, 256, 320, 480, 640, 800, 1024, 1280, 1600, 1920, 2048}
+	}
+}
+
+func applyArchiveDefaults(archive *ArchiveConfig) {
+	if archive.MaxEntries <= 0 {
+		archive.MaxEntries = 10000
+	}
+	if archive.MaxTotalSize <= 0 {
+		archive.MaxTotalSize = 10 << 30
+	}
+	if archive.MaxCompressionRatio <= 0 {
+		archive.MaxCompressionRatio = 100
+	}
+}
+
+func applyRecycleBinDefaults(rb *RecycleBinConfig) {
+	if rb.RetentionDays <= 0 {
+		rb.RetentionDays = 30
+	}
+	if rb.MinRetentionDays <= 0 {
+		rb.MinRetentionDays = 1
+	}
+	if rb.MaxRetentionDays <= 0 {
+		rb.MaxRetentionDays = 90
+	}
+	// 全局默认值必须落在用户可选范围内。
+	if rb.MinRetentionDays > rb.RetentionDays {
+		rb.MinRetentionDays = rb.RetentionDays
+	}
+	if rb.MaxRetentionDays < rb.RetentionDays {
+		rb.MaxRetentionDays = rb.RetentionDays
+	}
+}
+
+func applyDatabaseDefaults(db *DatabaseConfig) {
+	db.Driver = strings.ToLower(strings.TrimSpace(db.Driver))
+	switch db.Driver {
+	case "":
+		db.Driver = "mysql"
+	case "postgresql", "pg":
+		db.Driver = "postgres"
+	case "sqlite3":
+		db.Driver = "sqlite"
+	}
+
+	if db.SlowThresholdMs == 0 {
+		db.SlowThresholdMs = 200
+	}
+
+	switch db.Driver {
+	case "mysql":
+		if db.Port == 0 {
+			db.Port = 3306
+		}
+		if db.Charset == "" {
+			db.Charset = "utf8mb4"
+		}
+	case "postgres":
+		if db.Port == 0 {
+			db.Port = 5432
+		}
+		if db.SSLMode == "" {
+			db.SSLMode = "disable"
+		}
+	case "sqlite":
+		if db.Path == "" {
+			db.Path = "mcloud.db"
+		}
+	}
+}
+
+func applyUploadProgressDefaults(progress *UploadProgressConfig) {
+	progress.Store = strings.ToLower(strings.TrimSpace(progress.Store))
+	if progress.Store == "" {
+		progress.Store = "redis"
+	}
+	progress.Fallback = strings.ToLower(strings.TrimSpace(progress.Fallback))
+	if progress.Fallback == "" {
+		progress.Fallback = "memory"
+	}
+	if progress.RetryInterval <= 0 {
+		progress.RetryInterval = 30
+	}
+}
+
+// validateUploadProgress 拒绝未知的进度存储；否则拼写错误会落入 Redis 分支却不连接 Redis，悄悄退化为进程内存储。
+func validateUploadProgress(progress UploadProgressConfig) error {
+	switch progress.Store {
+	case "redis", "memory", "database", "none":
+	default:
+		return fmt.Errorf("upload_progress.store 取值无效: %q（可选 redis | memory | database | none）", progress.Store)
+	}
+	switch progress.Fallback {
+	case "memory", "database", "none":
+	default:
+		return fmt.Errorf("upload_progress.fallback 取值无效: %q（可选 memory | database | none）", progress.Fallback)
+	}
+	return nil
+}
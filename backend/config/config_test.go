@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigValidatesUploadProgressStore(t *testing.T) {
+	cases := []struct {
+		yaml string
+		ok   bool
+	}{
+		{"", true},
+		{"upload_progress:\n  store: Database\n", true},
+		{"upload_progress:\n  store: none\n  fallback: none\n", true},
+		{"upload_progress:\n  store: redsi\n", false},
+		{"upload_progress:\n  fallback: disk\n", false},
+	}
+	for _, tc := range cases {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(tc.yaml), 0644); err != nil {
+			t.Fatalf("write config failed: %v", err)
+		}
+		_, err := LoadConfig(path)
+		if (err == nil) != tc.ok {
+			t.Fatalf("LoadConfig(%q) error = %v, want ok=%v", tc.yaml, err, tc.ok)
+		}
+	}
+}
@@ -1,38 +1,41 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
+	"path/filepath"
 	"time"
 
 	"mcloud/config"
+	"mcloud/logger"
 
+	"github.com/glebarez/sqlite"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
 var DB *gorm.DB
 var RedisClient *redis.Client
 
-func InitMySQL(cfg *config.DatabaseConfig) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
-		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database, cfg.Charset)
-
-	var err error
-	gormLogger := logger.New(
-		log.New(os.Stdout, "", log.LstdFlags),
-		logger.Config{
-			SlowThreshold:             time.Second,
-			LogLevel:                  logger.Info,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  false,
-		},
-	)
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger:                                   gormLogger,
+// InitDatabase 按 database.driver 配置连接 MySQL / PostgreSQL / SQLite。
+func InitDatabase(cfg *config.DatabaseConfig) error {
+	dialector, err := NewDialector(cfg)
+	if err != nil {
+		return err
+	}
+
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		Logger:                                   logger.NewGormLogger(time.Duration(cfg.SlowThresholdMs) * time.Millisecond),
 		DisableForeignKeyConstraintWhenMigrating: true,
 	})
 	if err != nil {
@@ -44,13 +47,54 @@ func InitMySQL(cfg *config.DatabaseConfig) error {
 		return fmt.Errorf("获取数据库实例失败: %w", err)
 	}
 
-	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
 
-	log.Println("MySQL 连接成功")
+	logger.Infof("数据库连接成功 (driver=%s)", cfg.Driver)
 	return nil
 }
 
+// NewDialector 根据驱动类型构造 GORM 方言，不建立连接。
+func NewDialector(cfg *config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverMySQL, "":
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+			cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database, cfg.Charset)
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Database, cfg.SSLMode)
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		if dir := filepath.Dir(cfg.Path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, fmt.Errorf("创建 SQLite 目录失败: %w", err)
+			}
+		}
+		return sqlite.Open(SQLiteDSN(cfg.Path)), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+	}
+}
+
+// SQLiteDSN 为 SQLite 文件追加运行参数：
+// WAL 与 busy_timeout 缓解并发写锁冲突，IMMEDIATE 事务避免读锁升级死锁，
+// case_sensitive_like 让路径前缀 LIKE 区分大小写，与 PostgreSQL 及 MySQL 下使用 utf8mb4_bin 的路径比较一致。
+func SQLiteDSN(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "case_sensitive_like(1)")
+	params.Set("_txlock", "immediate")
+	return "file:" + path + "?" + params.Encode()
+}
+
+// InitRedis 创建 Redis 客户端并探测连通性；探测失败时仍保留客户端，
+// 由调用方决定是否继续启动（上传进度存储会自动降级并在之后重试）。
 func InitRedis(cfg *config.RedisConfig) error {
 	RedisClient = redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
@@ -58,6 +102,12 @@ func InitRedis(cfg *config.RedisConfig) error {
 		DB:       cfg.DB,
 	})
 
-	log.Println("Redis 客户端初始化成功")
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := RedisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("Redis 连接失败: %w", err)
+	}
+
+	logger.Infof("Redis 客户端初始化成功")
 	return nil
 }
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"mcloud/metrics"
+	"mcloud/services"
+	"mcloud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAlbumRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateAlbumRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// CoverFileID 为 0 表示取消手动封面，改用相册中排序最靠前的文件。
+	CoverFileID *uint `json:"cover_file_id"`
+}
+
+type AlbumFilesRequest struct {
+	FileIDs []uint `json:"file_ids" binding:"required,min=1"`
+}
+
+// parseAlbumID 解析路径中的相册 ID，非法时直接返回 400。
+func parseAlbumID(c *gin.Context) (uint, bool) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的相册ID")
+		return 0, false
+	}
+	return uint(albumID), true
+}
+
+func ListAlbums(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albums, err := getServices().Album.ListAlbums(c.Request.Context(), userID)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, albums)
+}
+
+func CreateAlbum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	album, err := getServices().Album.CreateAlbum(c.Request.Context(), userID, req.Name, req.Description)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "相册已创建", album)
+}
+
+func GetAlbum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	album, err := getServices().Album.GetAlbum(c.Request.Context(), userID, albumID)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, album)
+}
+
+func UpdateAlbum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	album, err := getServices().Album.UpdateAlbum(c.Request.Context(), userID, albumID, services.UpdateAlbumInput{
+		Name:        req.Name,
+		Description: req.Description,
+		CoverFileID: req.CoverFileID,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "相册已更新", album)
+}
+
+func DeleteAlbum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	if err := getServices().Album.DeleteAlbum(c.Request.Context(), userID, albumID); respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "相册已删除", nil)
+}
+
+func ListAlbumItems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	result, err := getServices().Album.ListAlbumItems(c.Request.Context(), userID, albumID, page, pageSize)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+func AddAlbumItems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req AlbumFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	added, err := getServices().Album.AddAlbumItems(c.Request.Context(), userID, albumID, req.FileIDs)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已添加到相册", gin.H{"added": added})
+}
+
+func RemoveAlbumItems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req AlbumFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if err := getServices().Album.RemoveAlbumItems(c.Request.Context(), userID, albumID, req.FileIDs); respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已从相册移除", nil)
+}
+
+func ReorderAlbumItems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req AlbumFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if err := getServices().Album.ReorderAlbumItems(c.Request.Context(), userID, albumID, req.FileIDs); respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "相册顺序已更新", nil)
+}
+
+func ShareAlbum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	album, err := getServices().Album.ShareAlbum(c.Request.Context(), userID, albumID)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "相册已分享", gin.H{
+		"share_token": album.ShareToken,
+		"shared_at":   album.SharedAt,
+		"share_url":   "/api/shared/albums/" + *album.ShareToken,
+	})
+}
+
+func UnshareAlbum(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	if err := getServices().Album.UnshareAlbum(c.Request.Context(), userID, albumID); respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已取消分享", nil)
+}
+
+func GetSharedAlbum(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	result, err := getServices().Album.GetSharedAlbum(c.Request.Context(), c.Param("token"), page, pageSize)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// serveSharedAlbumFile 输出分享相册中文件的原图或缩略图；分享可随时取消，因此只允许短时缓存。
+func serveSharedAlbumFile(c *gin.Context, thumbnail bool) {
+	fileID, err := strconv.ParseUint(c.Param("file_id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+
+	info, err := getServices().Album.GetSharedFileAccess(c.Request.Context(), c.Param("token"), uint(fileID), thumbnail)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	servePreviewFile(c, info)
+	if thumbnail {
+		metrics.AddDownloadBytes("thumbnail", int64(c.Writer.Size()))
+	} else {
+		metrics.AddDownloadBytes("preview", int64(c.Writer.Size()))
+	}
+}
+
+func GetSharedAlbumThumbnail(c *gin.Context) {
+	serveSharedAlbumFile(c, true)
+}
+
+func PreviewSharedAlbumFile(c *gin.Context) {
+	serveSharedAlbumFile(c, false)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"mcloud/logger"
 	"mcloud/services"
 	"mcloud/utils"
 
@@ -25,6 +26,10 @@ func respondServiceError(c *gin.Context, err error) bool {
 		return false
 	}
 	if appErr, ok := err.(*services.AppError); ok {
+		// 5xx 的底层错误不会返回给客户端，在此记录以便按 request_id 排查。
+		if appErr.HTTPCode >= 500 {
+			logger.Ctx(c.Request.Context()).With("status", appErr.HTTPCode).Errorf("%v", appErr)
+		}
 		if appErr.Data != nil {
 			utils.ErrorWithData(c, appErr.HTTPCode, appErr.Message, appErr.Data)
 		} else {
@@ -32,6 +37,7 @@ func respondServiceError(c *gin.Context, err error) bool {
 		}
 		return true
 	}
+	logger.Ctx(c.Request.Context()).Errorf("%v", err)
 	utils.Error(c, 500, "服务器内部错误")
 	return true
 }
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"mcloud/logger"
+	"mcloud/metrics"
+	"mcloud/middleware"
 	"mcloud/services"
 	"mcloud/utils"
 
@@ -25,8 +31,12 @@ func ListFiles(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	order := c.DefaultQuery("order", "desc")
+	tags, ok := parseTagQuery(c)
+	if !ok {
+		return
+	}
 
-	result, err := getServices().File.ListFiles(c.Request.Context(), userID, uint(folderID), page, pageSize, sortBy, order)
+	result, err := getServices().File.ListFiles(c.Request.Context(), userID, uint(folderID), page, pageSize, sortBy, order, tags)
 	if respondServiceError(c, err) {
 		return
 	}
@@ -46,6 +56,13 @@ func UploadFile(c *gin.Context) {
 			return
 		}
 		folderID = parsed
+	} else if folderPath := c.PostForm("folder_path"); folderPath != "" {
+		// 脚本场景可直接按路径上传，目标目录需已存在。
+		resolved, err := getServices().Folder.ResolvePath(c.Request.Context(), userID, folderPath, services.PathTypeFolder)
+		if respondServiceError(c, err) {
+			return
+		}
+		folderID = uint64(resolved.Folder.ID)
 	}
 
 	file, header, err := c.Request.FormFile("file")
@@ -84,16 +101,16 @@ func InitChunkedUpload(c *gin.Context) {
 		FolderID: req.FolderID,
 	})
 	if respondServiceError(c, err) {
-		logger.Debugf("[upload] init failed user=%d file=%q size=%d err=%v", userID, req.FileName, req.FileSize, err)
+		logger.Ctx(c.Request.Context()).Debugf("[upload] init failed user=%d file=%q size=%d err=%v", userID, req.FileName, req.FileSize, err)
 		return
 	}
 
 	if result.Status == "instant_upload" {
-		logger.Debugf("[upload] instant success user=%d file=%q size=%d file_id=%d", userID, req.FileName, req.FileSize, result.FileID)
+		logger.Ctx(c.Request.Context()).Debugf("[upload] instant success user=%d file=%q size=%d file_id=%d", userID, req.FileName, req.FileSize, result.FileID)
 		utils.SuccessWithMessage(c, "秒传成功", gin.H{"status": result.Status, "file_id": result.FileID})
 		return
 	}
-	logger.Infof("[upload] init success user=%d upload_id=%s file=%q size=%d chunks=%d chunk_size=%d", userID, result.UploadID, req.FileName, req.FileSize, result.TotalChunks, result.ChunkSize)
+	logger.Ctx(c.Request.Context()).Infof("[upload] init success user=%d upload_id=%s file=%q size=%d chunks=%d chunk_size=%d", userID, result.UploadID, req.FileName, req.FileSize, result.TotalChunks, result.ChunkSize)
 
 	utils.Success(c, gin.H{
 		"upload_id":    result.UploadID,
@@ -134,14 +151,14 @@ func UploadChunk(c *gin.Context) {
 	uploadID := c.PostForm("upload_id")
 	chunkIndex, err := strconv.Atoi(c.PostForm("chunk_index"))
 	if err != nil {
-		logger.Debugf("[upload] chunk invalid index user=%d upload_id=%s value=%q", userID, uploadID, c.PostForm("chunk_index"))
+		logger.Ctx(c.Request.Context()).Debugf("[upload] chunk invalid index user=%d upload_id=%s value=%q", userID, uploadID, c.PostForm("chunk_index"))
 		utils.Error(c, http.StatusBadRequest, "无效的分片索引")
 		return
 	}
 
 	chunk, header, err := c.Request.FormFile("chunk")
 	if err != nil {
-		logger.Debugf("[upload] chunk read failed user=%d upload_id=%s chunk=%d err=%v", userID, uploadID, chunkIndex, err)
+		logger.Ctx(c.Request.Context()).Debugf("[upload] chunk read failed user=%d upload_id=%s chunk=%d err=%v", userID, uploadID, chunkIndex, err)
 		utils.Error(c, http.StatusBadRequest, "读取分片失败")
 		return
 	}
@@ -149,10 +166,10 @@ func UploadChunk(c *gin.Context) {
 
 	result, err := getServices().File.UploadChunk(c.Request.Context(), userID, uploadID, chunkIndex, chunk)
 	if respondServiceError(c, err) {
-		logger.Debugf("[upload] chunk save failed user=%d upload_id=%s chunk=%d size=%d cost=%s err=%v", userID, uploadID, chunkIndex, header.Size, time.Since(start), err)
+		logger.Ctx(c.Request.Context()).Debugf("[upload] chunk save failed user=%d upload_id=%s chunk=%d size=%d cost=%s err=%v", userID, uploadID, chunkIndex, header.Size, time.Since(start), err)
 		return
 	}
-	logger.Debugf("[upload] chunk saved user=%d upload_id=%s chunk=%d size=%d uploaded=%d/%d cost=%s", userID, uploadID, chunkIndex, header.Size, result.UploadedChunks, result.TotalChunks, time.Since(start))
+	logger.Ctx(c.Request.Context()).Debugf("[upload] chunk saved user=%d upload_id=%s chunk=%d size=%d uploaded=%d/%d cost=%s", userID, uploadID, chunkIndex, header.Size, result.UploadedChunks, result.TotalChunks, time.Since(start))
 	utils.Success(c, result)
 }
 
@@ -201,10 +218,10 @@ func CompleteUpload(c *gin.Context) {
 
 	record, err := getServices().File.CompleteUpload(c.Request.Context(), userID, req.UploadID)
 	if respondServiceError(c, err) {
-		logger.Debugf("[upload] complete failed user=%d upload_id=%s cost=%s err=%v", userID, req.UploadID, time.Since(start), err)
+		logger.Ctx(c.Request.Context()).Debugf("[upload] complete failed user=%d upload_id=%s cost=%s err=%v", userID, req.UploadID, time.Since(start), err)
 		return
 	}
-	logger.Debugf("[upload] complete success user=%d upload_id=%s file_id=%d cost=%s", userID, req.UploadID, record.ID, time.Since(start))
+	logger.Ctx(c.Request.Context()).Debugf("[upload] complete success user=%d upload_id=%s file_id=%d cost=%s", userID, req.UploadID, record.ID, time.Since(start))
 
 	utils.Success(c, record)
 }
@@ -222,9 +239,17 @@ func DownloadFile(c *gin.Context) {
 		return
 	}
 
+	recordFileAccess(c, userID, info.File.ID)
+	serveAttachment(c, info)
+}
+
+// serveAttachment 以附件形式输出文件，支持 Range 与条件请求；HEAD 请求只返回响应头。
+func serveAttachment(c *gin.Context, info services.FileAccessOutput) {
+	setCacheValidators(c, info.ETag, "private, no-cache")
 	c.Header("Accept-Ranges", "bytes")
 	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, info.DownloadName))
 	http.ServeFile(c.Writer, c.Request, info.AbsPath)
+	metrics.AddDownloadBytes("download", int64(c.Writer.Size()))
 }
 
 func DownloadFileHead(c *gin.Context) {
@@ -240,11 +265,79 @@ func DownloadFileHead(c *gin.Context) {
 		return
 	}
 
-	c.Header("Accept-Ranges", "bytes")
-	c.Header("Content-Length", fmt.Sprintf("%d", info.File.FileObject.FileSize))
-	c.Header("Content-Type", info.ContentType)
-	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, info.DownloadName))
-	c.Status(http.StatusOK)
+	// 与 GET 走同一条输出路径，由 net/http 处理条件请求与 Range 并省略响应体。
+	serveAttachment(c, info)
+}
+
+func CreateDownloadURL(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+
+	var req struct {
+		ExpiresIn int    `json:"expires_in"`
+		BindIP    bool   `json:"bind_ip"`
+		IP        string `json:"ip"`
+	}
+	// 请求体可省略，默认有效期且不绑定 IP。
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	clientIP := req.IP
+	if req.BindIP {
+		clientIP = c.ClientIP()
+	}
+
+	result, err := getServices().File.CreateDownloadURL(c.Request.Context(), userID, uint(fileID), services.DownloadURLInput{ExpiresIn: req.ExpiresIn, ClientIP: clientIP})
+	if respondServiceError(c, err) {
+		return
+	}
+	result.URL = requestOrigin(c) + result.URL
+	utils.Success(c, result)
+}
+
+// requestOrigin 按当前请求拼出协议与主机；反向代理终止 TLS 时参考 X-Forwarded-Proto，但只采信 server.trusted_proxies 中的代理。
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	forwardedHTTPS := middleware.FromTrustedProxy(c) && strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+	if c.Request.TLS != nil || forwardedHTTPS {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func SignedDownload(c *gin.Context) {
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+	userID, errU := strconv.ParseUint(c.Query("uid"), 10, 32)
+	expires, errE := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if errU != nil || errE != nil {
+		utils.Error(c, http.StatusForbidden, "下载链接无效")
+		return
+	}
+
+	info, err := getServices().File.GetSignedDownloadInfo(c.Request.Context(), uint(fileID), services.SignedDownloadInput{
+		UserID:    uint(userID),
+		Expires:   expires,
+		IP:        c.Query("ip"),
+		Signature: c.Query("sig"),
+		ClientIP:  c.ClientIP(),
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	if c.Request.Method == http.MethodGet {
+		recordFileAccess(c, uint(userID), info.File.ID)
+	}
+	serveAttachment(c, info)
 }
 
 func PreviewFile(c *gin.Context) {
@@ -260,8 +353,28 @@ func PreviewFile(c *gin.Context) {
 		return
 	}
 
-	if info.ContentType != "" {
-		c.Header("Content-Type", info.ContentType)
+	recordFileAccess(c, userID, info.File.ID)
+	servePreviewFile(c, info)
+	metrics.AddDownloadBytes("preview", int64(c.Writer.Size()))
+}
+
+// servePreviewFile 内联输出文件供浏览器预览。始终声明内容类型并禁止浏览器嗅探；HTML、SVG 等可执行脚本的类型
+// 改为附件下载，并以 CSP sandbox 兜底，防止文件在站点源下运行脚本。调用方未设置缓存策略时按私有内容处理。
+func servePreviewFile(c *gin.Context, info services.FileAccessOutput) {
+	setCacheValidators(c, info.ETag, "private, no-cache")
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if services.IsActiveContentType(contentType) {
+		disposition := "attachment"
+		if info.DownloadName != "" {
+			disposition = mime.FormatMediaType("attachment", map[string]string{"filename": info.DownloadName})
+		}
+		c.Header("Content-Disposition", disposition)
+		c.Header("Content-Security-Policy", "sandbox; default-src 'none'")
 	}
 	c.File(info.AbsPath)
 }
@@ -274,14 +387,176 @@ func GetThumbnail(c *gin.Context) {
 		return
 	}
 
-	info, err := getServices().File.GetThumbnailInfo(c.Request.Context(), userID, uint(fileID))
+	var info services.FileAccessOutput
+	if preset := c.Query("preset"); preset != "" {
+		info, err = getServices().File.GetImageVariant(c.Request.Context(), userID, uint(fileID), services.ImageVariantInput{Preset: preset})
+	} else {
+		info, err = getServices().File.GetThumbnailInfo(c.Request.Context(), userID, uint(fileID))
+	}
 	if respondServiceError(c, err) {
 		return
 	}
+	serveThumbnailFile(c, info)
+}
 
+func GetImageVariant(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+	width, errW := strconv.Atoi(c.DefaultQuery("w", "0"))
+	height, errH := strconv.Atoi(c.DefaultQuery("h", "0"))
+	if errW != nil || errH != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的图片尺寸")
+		return
+	}
+
+	info, err := getServices().File.GetImageVariant(c.Request.Context(), userID, uint(fileID), services.ImageVariantInput{
+		Width:  width,
+		Height: height,
+		Fit:    c.Query("fit"),
+		Format: c.Query("format"),
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+	serveThumbnailFile(c, info)
+}
+
+// serveThumbnailFile 输出缩略图或图片变体；内容由文件对象决定且很少变化，可在浏览器中长期缓存，
+// 但属于需要登录的资源，不允许共享缓存保存。
+func serveThumbnailFile(c *gin.Context, info services.FileAccessOutput) {
 	c.Header("Content-Type", info.ContentType)
-	c.Header("Cache-Control", "public, max-age=86400")
+	setCacheValidators(c, info.ETag, "private, max-age=86400")
 	c.File(info.AbsPath)
+	metrics.AddDownloadBytes("thumbnail", int64(c.Writer.Size()))
+}
+
+// setCacheValidators 输出 ETag 与缓存策略，已设置的 Cache-Control 保持不变。If-None-Match、If-Modified-Since
+// 与 If-Range 由 http.ServeFile 依据 ETag 和文件修改时间处理，If-Range 不匹配时返回完整内容。
+func setCacheValidators(c *gin.Context, etag string, cacheControl string) {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if c.Writer.Header().Get("Cache-Control") == "" {
+		c.Header("Cache-Control", cacheControl)
+	}
+}
+
+func GetTextPreview(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+	offset, errOffset := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	startLine, errStart := strconv.Atoi(c.DefaultQuery("start_line", "0"))
+	lineOffset, errLineOffset := strconv.ParseInt(c.DefaultQuery("line_offset", "0"), 10, 64)
+	lines, errLines := strconv.Atoi(c.DefaultQuery("lines", "0"))
+	if errOffset != nil || errLimit != nil || errStart != nil || errLineOffset != nil || errLines != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	result, err := getServices().File.GetTextPreview(c.Request.Context(), userID, uint(fileID), services.TextPreviewInput{
+		Offset:     offset,
+		Limit:      limit,
+		StartLine:  startLine,
+		LineOffset: lineOffset,
+		Lines:      lines,
+		Charset:    c.Query("charset"),
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+	if offset == 0 && startLine <= 1 && lineOffset == 0 {
+		recordFileAccess(c, userID, uint(fileID))
+	}
+	utils.Success(c, result)
+}
+
+func GetFileDetail(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+
+	file, err := getServices().File.GetFileDetail(c.Request.Context(), userID, uint(fileID))
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, file)
+}
+
+func ListArchiveEntries(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+
+	result, err := getServices().File.ListArchiveEntries(c.Request.Context(), userID, uint(fileID))
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, result)
+}
+
+func GetArchiveEntry(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+
+	stream, err := getServices().File.OpenArchiveEntry(c.Request.Context(), userID, uint(fileID), c.Query("path"))
+	if respondServiceError(c, err) {
+		return
+	}
+	defer stream.Reader.Close()
+
+	// 条目内容来自用户上传的压缩包，只有已知的安全类型内联展示，其余一律作为附件下载。
+	disposition := "attachment"
+	if stream.ContentType != "application/octet-stream" {
+		disposition = "inline"
+	}
+	c.DataFromReader(http.StatusOK, stream.Entry.Size, stream.ContentType, stream.Reader, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": stream.Name}),
+		"X-Content-Type-Options": "nosniff",
+	})
+	metrics.AddDownloadBytes("archive_entry", int64(c.Writer.Size()))
+}
+
+func ExtractArchive(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return
+	}
+
+	var req struct {
+		FolderID uint `json:"folder_id"`
+	}
+	// 请求体可省略，默认解压到压缩包所在目录。
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result, err := getServices().File.ExtractArchive(c.Request.Context(), userID, uint(fileID), services.ArchiveExtractInput{FolderID: req.FolderID})
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, result)
 }
 
 func DeleteFile(c *gin.Context) {
@@ -381,13 +656,14 @@ func BatchGetThumbnails(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	var req struct {
 		FileIDs []uint `json:"file_ids" binding:"required,min=1,max=200"`
+		Preset  string `json:"preset"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, http.StatusBadRequest, "invalid request")
 		return
 	}
 
-	result, err := getServices().File.BatchGetThumbnails(c.Request.Context(), userID, req.FileIDs)
+	result, err := getServices().File.BatchGetThumbnails(c.Request.Context(), userID, req.FileIDs, req.Preset)
 	if respondServiceError(c, err) {
 		return
 	}
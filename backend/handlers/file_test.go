@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcloud/middleware"
+	"mcloud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func headAttachment(t *testing.T, info services.FileAccessOutput, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.HEAD("/download", func(c *gin.Context) { serveAttachment(c, info) })
+
+	req := httptest.NewRequest(http.MethodHead, "/download", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestServeAttachmentHeadHonorsConditionalRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.bin")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	info := services.FileAccessOutput{AbsPath: path, DownloadName: "a.bin", ETag: `"abc"`}
+
+	w := headAttachment(t, info, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "11" || w.Body.Len() != 0 {
+		t.Fatalf("expected 200 with length and no body, got %d %q (%d bytes)", w.Code, w.Header().Get("Content-Length"), w.Body.Len())
+	}
+	if w.Header().Get("ETag") != `"abc"` || w.Header().Get("Last-Modified") == "" {
+		t.Fatalf("expected validators, got %v", w.Header())
+	}
+
+	if w := headAttachment(t, info, map[string]string{"If-None-Match": `"abc"`}); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for matching If-None-Match, got %d", w.Code)
+	}
+	if w := headAttachment(t, info, map[string]string{"If-None-Match": `"other"`}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for stale If-None-Match, got %d", w.Code)
+	}
+
+	// If-Range 匹配时按 Range 返回，不匹配时忽略 Range 返回完整内容的响应头。
+	ranged := map[string]string{"Range": "bytes=0-4", "If-Range": `"abc"`}
+	if w := headAttachment(t, info, ranged); w.Code != http.StatusPartialContent || w.Header().Get("Content-Length") != "5" {
+		t.Fatalf("expected 206 for matching If-Range, got %d %q", w.Code, w.Header().Get("Content-Length"))
+	}
+	ranged["If-Range"] = `"other"`
+	if w := headAttachment(t, info, ranged); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for stale If-Range, got %d", w.Code)
+	}
+}
+
+func TestRequestOriginTrustsForwardedProtoOnlyFromTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	originFrom := func(proxies []string, remoteAddr string) string {
+		r := gin.New()
+		if err := middleware.SetTrustedProxies(r, proxies); err != nil {
+			t.Fatalf("SetTrustedProxies failed: %v", err)
+		}
+		r.GET("/origin", func(c *gin.Context) { c.String(http.StatusOK, requestOrigin(c)) })
+
+		req := httptest.NewRequest(http.MethodGet, "http://cloud.example.com/origin", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	t.Cleanup(func() { _ = middleware.SetTrustedProxies(gin.New(), nil) })
+
+	if got := originFrom(nil, "203.0.113.5:4321"); got != "http://cloud.example.com" {
+		t.Fatalf("expected spoofed X-Forwarded-Proto to be ignored, got %q", got)
+	}
+	if got := originFrom([]string{"127.0.0.1"}, "127.0.0.1:4321"); got != "https://cloud.example.com" {
+		t.Fatalf("expected X-Forwarded-Proto from trusted proxy to be used, got %q", got)
+	}
+}
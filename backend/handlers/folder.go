@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"mcloud/services"
 	"mcloud/utils"
 
 	"github.com/gin-gonic/gin"
@@ -40,6 +41,35 @@ func ListFolders(c *gin.Context) {
 	utils.Success(c, folders)
 }
 
+// ListEntries 合并列出目录下的子目录与文件，使用 cursor 游标翻页。
+func ListEntries(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	folderID, err := strconv.ParseUint(c.DefaultQuery("folder_id", "0"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件夹ID")
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	tags, ok := parseTagQuery(c)
+	if !ok {
+		return
+	}
+
+	result, err := getServices().Folder.ListEntries(c.Request.Context(), userID, services.ListEntriesInput{
+		FolderID: uint(folderID),
+		SortBy:   c.DefaultQuery("sort_by", "created_at"),
+		Order:    c.DefaultQuery("order", "desc"),
+		Cursor:   c.Query("cursor"),
+		Limit:    limit,
+		Tags:     tags,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
 func CreateFolder(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
@@ -93,3 +123,51 @@ func DeleteFolder(c *gin.Context) {
 
 	utils.SuccessWithMessage(c, "文件夹已删除", nil)
 }
+
+func GetFolderStats(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	folderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件夹ID")
+		return
+	}
+
+	stats, err := getServices().Folder.GetFolderStats(c.Request.Context(), userID, uint(folderID))
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, stats)
+}
+
+func GetFolderTree(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "0"))
+	if err != nil || depth < 0 {
+		utils.Error(c, http.StatusBadRequest, "无效的深度")
+		return
+	}
+
+	tree, err := getServices().Folder.GetFolderTree(c.Request.Context(), userID, depth)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, tree)
+}
+
+func GetFolderAncestors(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	folderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件夹ID")
+		return
+	}
+
+	ancestors, err := getServices().Folder.GetAncestors(c.Request.Context(), userID, uint(folderID))
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, ancestors)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"mcloud/services"
+	"mcloud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EnsureFolderPathRequest struct {
+	Path string `json:"path" binding:"required,max=1000"`
+}
+
+func ResolvePath(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	resolved, err := getServices().Folder.ResolvePath(c.Request.Context(), userID, c.Query("path"), c.Query("type"))
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, resolved)
+}
+
+func DownloadByPath(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	resolved, err := getServices().Folder.ResolvePath(c.Request.Context(), userID, c.Query("path"), services.PathTypeFile)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	info, err := getServices().File.GetDownloadInfo(c.Request.Context(), userID, resolved.File.ID)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	recordFileAccess(c, userID, info.File.ID)
+	serveAttachment(c, info)
+}
+
+func DeleteByPath(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	ctx := c.Request.Context()
+
+	resolved, err := getServices().Folder.ResolvePath(ctx, userID, c.Query("path"), c.Query("type"))
+	if respondServiceError(c, err) {
+		return
+	}
+
+	if resolved.Type == services.PathTypeFile {
+		err = getServices().File.DeleteFile(ctx, userID, resolved.File.ID)
+	} else {
+		err = getServices().Folder.DeleteFolder(ctx, userID, resolved.Folder.ID)
+	}
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已删除", resolved)
+}
+
+func EnsureFolderPath(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req EnsureFolderPathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	folder, err := getServices().Folder.EnsureFolderPath(c.Request.Context(), userID, req.Path)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, folder)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"mcloud/services"
+	"mcloud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPhotoTimeline(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	result, err := getServices().Photo.Timeline(c.Request.Context(), userID, services.PhotoTimelineQuery{
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+func GetSimilarImages(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	query := services.SimilarImagesQuery{Page: page, PageSize: pageSize}
+	if raw := c.Query("max_distance"); raw != "" {
+		distance, err := strconv.Atoi(raw)
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "无效的相似阈值")
+			return
+		}
+		query.MaxDistance = &distance
+	}
+
+	result, err := getServices().Photo.SimilarImages(c.Request.Context(), userID, query)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
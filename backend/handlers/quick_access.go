@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mcloud/services"
+	"mcloud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddFavoriteRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=file folder"`
+	TargetID   uint   `json:"target_id" binding:"required"`
+}
+
+func ListFavorites(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := getServices().QuickAccess.ListFavorites(c.Request.Context(), userID, services.FavoriteQuery{
+		Type:     c.Query("type"),
+		Keyword:  strings.TrimSpace(c.Query("keyword")),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+func AddFavorite(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req AddFavoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	item, err := getServices().QuickAccess.AddFavorite(c.Request.Context(), userID, req.TargetType, req.TargetID)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已收藏", item)
+}
+
+func RemoveFavorite(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的收藏目标ID")
+		return
+	}
+
+	err = getServices().QuickAccess.RemoveFavorite(c.Request.Context(), userID, c.Param("type"), uint(targetID))
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已取消收藏", nil)
+}
+
+func ListRecentUploads(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	query, ok := parseRecentQuery(c)
+	if !ok {
+		return
+	}
+	result, err := getServices().QuickAccess.ListRecentUploads(c.Request.Context(), userID, query)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+func ListRecentAccessed(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	query, ok := parseRecentQuery(c)
+	if !ok {
+		return
+	}
+	result, err := getServices().QuickAccess.ListRecentAccessed(c.Request.Context(), userID, query)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// parseRecentQuery 解析最近视图的公共筛选参数，非法数字按缺省处理；标签参数非法时直接返回 400。
+func parseRecentQuery(c *gin.Context) (services.RecentQuery, bool) {
+	tags, ok := parseTagQuery(c)
+	if !ok {
+		return services.RecentQuery{}, false
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "0"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	return services.RecentQuery{
+		Keyword:  strings.TrimSpace(c.Query("keyword")),
+		Category: c.Query("category"),
+		Days:     days,
+		Limit:    limit,
+		Tags:     tags,
+	}, true
+}
+
+// recordFileAccess 记录下载或预览访问；续传产生的后续分段请求不重复计数。
+func recordFileAccess(c *gin.Context, userID uint, fileID uint) {
+	if r := c.GetHeader("Range"); r != "" && !strings.HasPrefix(r, "bytes=0-") {
+		return
+	}
+	getServices().QuickAccess.RecordAccess(c.Request.Context(), userID, fileID)
+}
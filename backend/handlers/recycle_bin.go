@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
+	"mcloud/services"
 	"mcloud/utils"
 
 	"github.com/gin-gonic/gin"
@@ -30,11 +33,26 @@ func RestoreItem(c *gin.Context) {
 		return
 	}
 
-	if err := getServices().RecycleBin.RestoreItem(c.Request.Context(), userID, uint(itemID)); respondServiceError(c, err) {
+	// 请求体可省略，此时恢复到原位置并在重名时自动改名。
+	var req services.RestoreOptions
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.Error(c, http.StatusBadRequest, "invalid request")
 		return
 	}
 
-	utils.SuccessWithMessage(c, "已恢复", nil)
+	result, err := getServices().RecycleBin.RestoreItem(c.Request.Context(), userID, uint(itemID), req)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	switch {
+	case result.DryRun:
+		utils.SuccessWithMessage(c, "恢复预览", result)
+	case result.Action == services.RestoreActionSkipped:
+		utils.SuccessWithMessage(c, "存在同名项目，已跳过", result)
+	default:
+		utils.SuccessWithMessage(c, "已恢复", result)
+	}
 }
 
 func PermanentDelete(c *gin.Context) {
@@ -59,3 +77,128 @@ func EmptyRecycleBin(c *gin.Context) {
 	}
 	utils.SuccessWithMessage(c, "回收站已清空", nil)
 }
+
+func BatchRestoreItems(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req services.RecycleBinBatchRestoreInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	result, err := getServices().RecycleBin.BatchRestore(c.Request.Context(), userID, req)
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, result)
+}
+
+func BatchPermanentDelete(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req services.RecycleBinBatchInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	result, err := getServices().RecycleBin.BatchPermanentDelete(c.Request.Context(), userID, req)
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, result)
+}
+
+func GetRecycleBinSettings(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	result, err := getServices().RecycleBin.GetSettings(c.Request.Context(), userID)
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, result)
+}
+
+func UpdateRecycleBinSettings(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req services.RecycleBinSettingsInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	result, err := getServices().RecycleBin.UpdateSettings(c.Request.Context(), userID, req)
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.SuccessWithMessage(c, "回收站设置已更新", result)
+}
+
+func ListRecycleBinFolderContents(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的回收站项目ID")
+		return
+	}
+
+	result, err := getServices().RecycleBin.ListFolderContents(c.Request.Context(), userID, uint(itemID), c.DefaultQuery("path", "/"))
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, result)
+}
+
+func RestoreRecycleBinFolderFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	itemID, fileID, ok := parseRecycleBinFileParams(c)
+	if !ok {
+		return
+	}
+
+	var req services.RestoreOptions
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.Error(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	result, err := getServices().RecycleBin.RestoreFolderFile(c.Request.Context(), userID, itemID, fileID, req)
+	if respondServiceError(c, err) {
+		return
+	}
+	switch {
+	case result.DryRun:
+		utils.SuccessWithMessage(c, "恢复预览", result)
+	case result.Action == services.RestoreActionSkipped:
+		utils.SuccessWithMessage(c, "存在同名项目，已跳过", result)
+	default:
+		utils.SuccessWithMessage(c, "已恢复", result)
+	}
+}
+
+func DownloadRecycleBinFolderFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	itemID, fileID, ok := parseRecycleBinFileParams(c)
+	if !ok {
+		return
+	}
+
+	info, err := getServices().RecycleBin.GetFolderFileDownloadInfo(c.Request.Context(), userID, itemID, fileID)
+	if respondServiceError(c, err) {
+		return
+	}
+	serveAttachment(c, info)
+}
+
+// parseRecycleBinFileParams 解析回收站目录条目 ID 与其中的文件 ID，失败时已写出 400 响应。
+func parseRecycleBinFileParams(c *gin.Context) (uint, uint, bool) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的回收站项目ID")
+		return 0, 0, false
+	}
+	fileID, err := strconv.ParseUint(c.Param("file_id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的文件ID")
+		return 0, 0, false
+	}
+	return uint(itemID), uint(fileID), true
+}
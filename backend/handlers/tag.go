@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mcloud/services"
+	"mcloud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+}
+
+type UpdateTagRequest struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+type MergeTagsRequest struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+}
+
+type TagBindingRequest struct {
+	TagIDs    []uint `json:"tag_ids" binding:"required,min=1"`
+	FileIDs   []uint `json:"file_ids"`
+	FolderIDs []uint `json:"folder_ids"`
+}
+
+func ListTags(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tags, err := getServices().Tag.ListTags(c.Request.Context(), userID)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, tags)
+}
+
+func CreateTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	tag, err := getServices().Tag.CreateTag(c.Request.Context(), userID, req.Name, req.Color)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "标签已创建", tag)
+}
+
+func UpdateTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的标签ID")
+		return
+	}
+
+	var req UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	tag, err := getServices().Tag.UpdateTag(c.Request.Context(), userID, uint(tagID), services.UpdateTagInput{Name: req.Name, Color: req.Color})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "标签已更新", tag)
+}
+
+func DeleteTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的标签ID")
+		return
+	}
+
+	if err := getServices().Tag.DeleteTag(c.Request.Context(), userID, uint(tagID)); respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "标签已删除", nil)
+}
+
+func MergeTags(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的标签ID")
+		return
+	}
+
+	var req MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	tag, err := getServices().Tag.MergeTags(c.Request.Context(), userID, uint(tagID), req.SourceIDs)
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "标签已合并", tag)
+}
+
+func BindTags(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req TagBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	err := getServices().Tag.BindTags(c.Request.Context(), userID, services.TagBindingInput{
+		TagIDs:    req.TagIDs,
+		FileIDs:   req.FileIDs,
+		FolderIDs: req.FolderIDs,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "标签已添加", nil)
+}
+
+func UnbindTags(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req TagBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	err := getServices().Tag.UnbindTags(c.Request.Context(), userID, services.TagBindingInput{
+		TagIDs:    req.TagIDs,
+		FileIDs:   req.FileIDs,
+		FolderIDs: req.FolderIDs,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, "标签已移除", nil)
+}
+
+func Search(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tags, ok := parseTagQuery(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := getServices().Search.Search(c.Request.Context(), userID, services.SearchQuery{
+		Keyword:  c.Query("keyword"),
+		Category: c.Query("category"),
+		Type:     c.Query("type"),
+		Tags:     tags,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if respondServiceError(c, err) {
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// parseTagQuery 解析 tag_ids（逗号分隔）与 tag_mode 查询参数；格式非法时直接返回 400。
+func parseTagQuery(c *gin.Context) (services.TagQuery, bool) {
+	query := services.TagQuery{Mode: c.Query("tag_mode")}
+	raw := strings.TrimSpace(c.Query("tag_ids"))
+	if raw == "" {
+		return query, true
+	}
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil || id == 0 {
+			utils.Error(c, http.StatusBadRequest, "无效的标签ID")
+			return services.TagQuery{}, false
+		}
+		query.TagIDs = append(query.TagIDs, uint(id))
+	}
+	return query, true
+}
@@ -14,3 +14,12 @@ func GetStorageQuota(c *gin.Context) {
 	}
 	utils.Success(c, quota)
 }
+
+func GetStorageBreakdown(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	breakdown, err := getServices().User.GetStorageBreakdown(c.Request.Context(), userID)
+	if respondServiceError(c, err) {
+		return
+	}
+	utils.Success(c, breakdown)
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// RequestIDKey 为日志中请求 ID 的字段名。
+const RequestIDKey = "request_id"
+
+// WithRequestID 将请求 ID 写入 ctx，后续经 Ctx(ctx) 输出的日志都会带上该字段。
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithAttrs(ctx, RequestIDKey, requestID)
+}
+
+// WithAttrs 向 ctx 追加日志字段，参数形式同 slog.Logger.With。
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	record := slog.Record{}
+	record.Add(args...)
+	attrs := append([]slog.Attr(nil), attrsFromContext(ctx)...)
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+// RequestID 返回 ctx 中的请求 ID，不存在时返回空串。
+func RequestID(ctx context.Context) string {
+	for _, a := range attrsFromContext(ctx) {
+		if a.Key == RequestIDKey {
+			return a.Value.String()
+		}
+	}
+	return ""
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler 在输出前附加 ctx 中的字段。
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger 将 GORM 日志转发到全局 logger：
+// 出错的 SQL 记为 error，超过 slowThreshold 的记为 warn，其余 SQL 仅在 debug 级别输出。
+type GormLogger struct {
+	slowThreshold time.Duration
+	mode          gormlogger.LogLevel
+}
+
+// NewGormLogger 创建 GORM 日志适配器，slowThreshold 为 0 时不记录慢查询。
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{slowThreshold: slowThreshold, mode: gormlogger.Info}
+}
+
+func (l *GormLogger) LogMode(mode gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.mode = mode
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.mode >= gormlogger.Info {
+		Ctx(ctx).With("component", "gorm").Infof(msg, args...)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.mode >= gormlogger.Warn {
+		Ctx(ctx).With("component", "gorm").Warnf(msg, args...)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.mode >= gormlogger.Error {
+		Ctx(ctx).With("component", "gorm").Errorf(msg, args...)
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.mode <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	var lvl slog.Level
+	var msg string
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.mode >= gormlogger.Error:
+		lvl, msg = slog.LevelError, "sql error"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.mode >= gormlogger.Warn:
+		lvl, msg = slog.LevelWarn, fmt.Sprintf("slow sql >= %s", l.slowThreshold)
+	case l.mode >= gormlogger.Info:
+		lvl, msg = slog.LevelDebug, "sql"
+	default:
+		return
+	}
+	if !current.Load().Enabled(ctx, lvl) {
+		return
+	}
+
+	sql, rows := fc()
+	entry := Ctx(ctx).With(
+		"component", "gorm",
+		"sql", sql,
+		"rows", rows,
+		"elapsed_ms", float64(elapsed.Microseconds())/1000,
+	)
+	if err != nil && lvl == slog.LevelError {
+		entry = entry.With("error", err.Error())
+	}
+	entry.log(lvl, msg, nil)
+}
@@ -1,50 +1,118 @@
+// Package logger 基于 log/slog 提供分级结构化日志，默认输出 JSON。
+// 通过 WithRequestID / WithAttrs 写入 context 的字段会自动附加到 Ctx(ctx) 输出的每一行。
 package logger
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
 	"strings"
 	"sync/atomic"
 )
 
 const (
-	levelDebug int32 = 1
-	levelInfo  int32 = 2
+	FormatJSON = "json"
+	FormatText = "text"
 )
 
-var logLevel atomic.Int32
+var (
+	level   = new(slog.LevelVar)
+	current atomic.Pointer[slog.Logger]
+)
 
 func init() {
-	logLevel.Store(levelInfo)
+	Setup(FormatJSON, os.Stdout)
 }
 
-func SetLevel(level string) {
-	if strings.EqualFold(strings.TrimSpace(level), "debug") {
-		logLevel.Store(levelDebug)
-		return
+// Setup 重建全局 logger，并接管标准库 log 的输出，使未迁移的 log.Printf 也输出为结构化日志。
+func Setup(format string, w io.Writer) {
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.EqualFold(strings.TrimSpace(format), FormatText) {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
 	}
+	l := slog.New(&contextHandler{Handler: handler})
+	current.Store(l)
+	slog.SetDefault(l)
+}
+
+// SetLevel 设置日志级别：debug | info | warn | error，无法识别时使用 info。
+func SetLevel(name string) {
+	level.Set(ParseLevel(name))
+}
 
-	logLevel.Store(levelInfo)
+// ParseLevel 解析级别名称，无法识别时返回 info。
+func ParseLevel(name string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
 }
 
 func IsDebugEnabled() bool {
-	return logLevel.Load() == levelDebug
+	return level.Level() <= slog.LevelDebug
 }
 
 func IsInfoEnabled() bool {
-	return logLevel.Load() == levelInfo
+	return level.Level() <= slog.LevelInfo
 }
 
-func Debugf(format string, v ...any) {
-	if !IsDebugEnabled() {
-		return
+func Debugf(format string, v ...any) { Ctx(context.Background()).Debugf(format, v...) }
+func Infof(format string, v ...any)  { Ctx(context.Background()).Infof(format, v...) }
+func Warnf(format string, v ...any)  { Ctx(context.Background()).Warnf(format, v...) }
+func Errorf(format string, v ...any) { Ctx(context.Background()).Errorf(format, v...) }
+
+// Fatalf 以 error 级别记录后退出进程，仅用于启动阶段。
+func Fatalf(format string, v ...any) {
+	Errorf(format, v...)
+	os.Exit(1)
+}
+
+// Entry 绑定 context 与附加字段的日志入口。
+type Entry struct {
+	ctx   context.Context
+	attrs []any
+}
+
+// Ctx 返回绑定 ctx 的日志入口，输出时附带 ctx 中的请求 ID 等字段。
+func Ctx(ctx context.Context) Entry {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	return Entry{ctx: ctx}
+}
 
-	log.Printf("[DEBUG] "+format, v...)
+// With 追加键值对字段，参数形式同 slog.Logger.With。
+func (e Entry) With(args ...any) Entry {
+	attrs := make([]any, 0, len(e.attrs)+len(args))
+	attrs = append(attrs, e.attrs...)
+	e.attrs = append(attrs, args...)
+	return e
 }
 
-func Infof(format string, v ...any) {
-	if !IsInfoEnabled() {
+func (e Entry) Debugf(format string, v ...any) { e.log(slog.LevelDebug, format, v) }
+func (e Entry) Infof(format string, v ...any)  { e.log(slog.LevelInfo, format, v) }
+func (e Entry) Warnf(format string, v ...any)  { e.log(slog.LevelWarn, format, v) }
+func (e Entry) Errorf(format string, v ...any) { e.log(slog.LevelError, format, v) }
+
+func (e Entry) log(lvl slog.Level, format string, v []any) {
+	l := current.Load()
+	if !l.Enabled(e.ctx, lvl) {
 		return
 	}
-	log.Printf("[INFO] "+format, v...)
+	msg := format
+	if len(v) > 0 {
+		msg = fmt.Sprintf(format, v...)
+	}
+	l.Log(e.ctx, lvl, msg, e.attrs...)
 }
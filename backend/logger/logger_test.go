@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func captureLogs(t *testing.T, lvl string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	Setup(FormatJSON, &buf)
+	SetLevel(lvl)
+	t.Cleanup(func() {
+		Setup(FormatJSON, nopWriter{})
+		SetLevel("info")
+	})
+	return &buf
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line map[string]any
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("log line is not JSON: %q: %v", raw, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestCtxAddsRequestIDAndFields(t *testing.T) {
+	buf := captureLogs(t, "info")
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithAttrs(ctx, "user_id", 7)
+
+	Ctx(ctx).With("file_id", 3).Warnf("删除失败: %s", "boom")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(lines))
+	}
+	line := lines[0]
+	if line["level"] != "WARN" || line["msg"] != "删除失败: boom" {
+		t.Fatalf("unexpected level/msg: %v", line)
+	}
+	if line[RequestIDKey] != "req-1" || line["user_id"] != float64(7) || line["file_id"] != float64(3) {
+		t.Fatalf("expected context fields on line, got %v", line)
+	}
+	if RequestID(ctx) != "req-1" {
+		t.Fatalf("expected RequestID to read back req-1, got %q", RequestID(ctx))
+	}
+}
+
+func TestLevelFiltering(t *testing.T) {
+	buf := captureLogs(t, "warn")
+
+	Debugf("debug")
+	Infof("info")
+	Warnf("warn")
+	Errorf("error")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 || lines[0]["msg"] != "warn" || lines[1]["msg"] != "error" {
+		t.Fatalf("expected only warn and error lines, got %v", lines)
+	}
+	if IsInfoEnabled() || IsDebugEnabled() {
+		t.Fatalf("info/debug should be disabled at warn level")
+	}
+}
+
+func TestMessageWithoutArgsIsNotFormatted(t *testing.T) {
+	buf := captureLogs(t, "info")
+	Infof("100% done")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 1 || lines[0]["msg"] != "100% done" {
+		t.Fatalf("expected literal message, got %v", lines)
+	}
+}
+
+func TestGormLoggerTrace(t *testing.T) {
+	buf := captureLogs(t, "info")
+	l := NewGormLogger(100 * time.Millisecond)
+	ctx := WithRequestID(context.Background(), "req-sql")
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+
+	l.Trace(ctx, time.Now(), fc, nil)
+	l.Trace(ctx, time.Now(), fc, gorm.ErrRecordNotFound)
+	l.Trace(ctx, time.Now().Add(-time.Second), fc, nil)
+	l.Trace(ctx, time.Now(), fc, errors.New("syntax error"))
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected slow and error lines only at info level, got %v", lines)
+	}
+	if lines[0]["level"] != "WARN" || !strings.HasPrefix(lines[0]["msg"].(string), "slow sql") {
+		t.Fatalf("expected slow sql warning, got %v", lines[0])
+	}
+	if lines[1]["level"] != "ERROR" || lines[1]["error"] != "syntax error" || lines[1]["sql"] != "SELECT 1" {
+		t.Fatalf("expected sql error line, got %v", lines[1])
+	}
+	for _, line := range lines {
+		if line[RequestIDKey] != "req-sql" {
+			t.Fatalf("expected request id on gorm line, got %v", line)
+		}
+	}
+}
+
+func TestGormLoggerTraceAllAtDebug(t *testing.T) {
+	buf := captureLogs(t, "debug")
+	l := NewGormLogger(0)
+	l.Trace(context.Background(), time.Now().Add(-time.Hour), func() (string, int64) { return "SELECT 1", 1 }, nil)
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 1 || lines[0]["level"] != "DEBUG" || lines[0]["msg"] != "sql" {
+		t.Fatalf("expected plain debug sql line when slow threshold disabled, got %v", lines)
+	}
+
+	silent := l.LogMode(gormlogger.Silent)
+	silent.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 2", 1 }, errors.New("x"))
+	if got := len(decodeLines(t, buf)); got != 1 {
+		t.Fatalf("silent mode should not log, got %d lines", got)
+	}
+}
@@ -1,17 +1,21 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"mcloud/config"
 	"mcloud/database"
 	"mcloud/handlers"
 	"mcloud/logger"
+	"mcloud/metrics"
 	"mcloud/middleware"
-	"mcloud/models"
+	"mcloud/migrations"
 	"mcloud/repositories"
 	"mcloud/services"
 
@@ -19,61 +23,161 @@ import (
 )
 
 func main() {
-	log.Println("starting mCloud service")
+	migrateCmd := flag.String("migrate", "", "run schema migrations and exit: up | down | status")
+	migrateSteps := flag.Int("steps", 1, "number of migrations to roll back with -migrate down")
+	flag.Parse()
+
+	logger.Infof("starting mCloud service")
 
 	cfg, err := config.LoadConfig("config.yaml")
 	if err != nil {
-		log.Fatalf("load config failed: %v", err)
+		logger.Fatalf("load config failed: %v", err)
 	}
+	logger.Setup(cfg.Log.Format, os.Stdout)
 	logger.SetLevel(cfg.Log.Level)
 
-	if err := database.InitMySQL(&cfg.Database); err != nil {
-		log.Fatalf("init mysql failed: %v", err)
+	if err := database.InitDatabase(&cfg.Database); err != nil {
+		logger.Fatalf("init database failed: %v", err)
 	}
 
-	database.DB.AutoMigrate(
-		&models.User{},
-		&models.Folder{},
-		&models.FileObject{},
-		&models.File{},
-		&models.UploadTask{},
-		&models.RecycleBinItem{},
-		&models.ThumbnailTask{},
-	)
-	log.Println("database migration completed")
+	if *migrateCmd != "" {
+		if err := runMigrationCommand(*migrateCmd, *migrateSteps); err != nil {
+			logger.Fatalf("migrate %s failed: %v", *migrateCmd, err)
+		}
+		return
+	}
+	if err := runMigrationCommand("up", 0); err != nil {
+		logger.Fatalf("database migration failed: %v", err)
+	}
+	logger.Infof("database migration completed")
 
-	if err := database.InitRedis(&cfg.Redis); err != nil {
-		log.Fatalf("init redis failed: %v", err)
+	if cfg.UploadProgress.Store == repositories.UploadProgressStoreRedis {
+		if err := database.InitRedis(&cfg.Redis); err != nil {
+			logger.Warnf("redis unavailable, upload progress falls back to %s: %v", cfg.UploadProgress.Fallback, err)
+		}
 	}
 
 	if err := os.MkdirAll(filepath.Join(cfg.Storage.BasePath, "files"), 0o755); err != nil {
-		log.Fatalf("create files dir failed: %v", err)
+		logger.Fatalf("create files dir failed: %v", err)
 	}
 	if err := os.MkdirAll(filepath.Join(cfg.Storage.BasePath, "thumbnails"), 0o755); err != nil {
-		log.Fatalf("create thumbnails dir failed: %v", err)
+		logger.Fatalf("create thumbnails dir failed: %v", err)
 	}
 	if err := os.MkdirAll(filepath.Join(cfg.Storage.BasePath, "temp"), 0o755); err != nil {
-		log.Fatalf("create temp dir failed: %v", err)
+		logger.Fatalf("create temp dir failed: %v", err)
 	}
 
-	repoContainer := repositories.NewGormRepositories(database.DB, database.RedisClient).BuildContainer()
+	repoContainer := repositories.NewGormRepositories(database.DB, database.RedisClient).
+		WithUploadProgress(repositories.UploadProgressOptions{
+			Store:         cfg.UploadProgress.Store,
+			Fallback:      cfg.UploadProgress.Fallback,
+			RetryInterval: time.Duration(cfg.UploadProgress.RetryInterval) * time.Second,
+		}).
+		BuildContainer()
 	serviceContainer := services.NewContainer(repoContainer)
 	handlers.SetServices(serviceContainer)
 
 	services.StartCleanupWorkers()
-	log.Println("cleanup workers started")
+	logger.Infof("cleanup workers started")
 
 	r := gin.New()
+	if err := middleware.SetTrustedProxies(r, cfg.Server.TrustedProxies); err != nil {
+		logger.Fatalf("invalid server.trusted_proxies: %v", err)
+	}
 	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+	}
 	r.Use(middleware.RequestLogger())
 	r.Use(middleware.CORSMiddleware())
 	setupRoutes(r)
+	if cfg.Metrics.Enabled {
+		setupMetrics(r, &cfg.Metrics, repoContainer)
+	}
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	log.Printf("server listening on http://%s", addr)
+	logger.Infof("server listening on http://%s", addr)
 	if err := r.Run(addr); err != nil {
-		log.Fatalf("server start failed: %v", err)
+		logger.Fatalf("server start failed: %v", err)
+	}
+}
+
+// setupMetrics 注册连接池与存储用量采集器，并在主服务或独立端口上暴露抓取端点。
+func setupMetrics(r *gin.Engine, cfg *config.MetricsConfig, repos repositories.Container) {
+	if sqlDB, err := database.DB.DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB)
+	}
+	metrics.RegisterRedisPoolStats(database.RedisClient)
+	metrics.RegisterStorageTotals(func(ctx context.Context) (metrics.StorageTotals, error) {
+		totals, err := repos.StorageStats.Totals(ctx)
+		return metrics.StorageTotals(totals), err
+	}, 30*time.Second)
+
+	if cfg.Listen == "" {
+		// 反向代理与应用同机部署时所有请求都来自本机地址，无法按来源限制访问，挂在主服务上必须配置令牌。
+		if cfg.Token == "" {
+			logger.Errorf("metrics endpoint not exposed: metrics.token is required when metrics.listen is empty")
+			return
+		}
+		r.GET(cfg.Path, middleware.MetricsAuth(cfg.Token), gin.WrapH(metrics.Handler()))
+		logger.Infof("metrics exposed at %s", cfg.Path)
+		return
+	}
+
+	// 独立端口通常只绑定内网地址，由监听地址本身限制访问范围。
+	mr := gin.New()
+	mr.Use(gin.Recovery())
+	mr.GET(cfg.Path, middleware.MetricsAuth(cfg.Token), gin.WrapH(metrics.Handler()))
+	srv := &http.Server{Addr: cfg.Listen, Handler: mr, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		logger.Infof("metrics listening on http://%s%s", cfg.Listen, cfg.Path)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("metrics server stopped: %v", err)
+		}
+	}()
+}
+
+func runMigrationCommand(cmd string, steps int) error {
+	all, err := migrations.All(database.DB.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	runner := migrations.NewRunner(database.DB, all)
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Infof("%d migration(s) applied", len(applied))
+	case "down":
+		reverted, err := runner.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Infof("%d migration(s) reverted", len(reverted))
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied at " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Unknown {
+				state += " (unknown to this build)"
+			}
+			logger.Infof("%04d_%s: %s", st.Version, st.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", cmd)
 	}
+	return nil
 }
 
 func setupRoutes(r *gin.Engine) {
@@ -87,17 +191,73 @@ func setupRoutes(r *gin.Engine) {
 		auth.POST("/login", handlers.Login)
 	}
 
+	// 分享相册通过令牌匿名访问，不经过登录校验。
+	shared := api.Group("/shared")
+	{
+		shared.GET("/albums/:token", handlers.GetSharedAlbum)
+		shared.GET("/albums/:token/files/:file_id/thumbnail", handlers.GetSharedAlbumThumbnail)
+		shared.GET("/albums/:token/files/:file_id/preview", handlers.PreviewSharedAlbumFile)
+	}
+
+	// 签名直链自带鉴权信息，供下载工具与 <video> 等无法携带认证头的场景使用。
+	direct := api.Group("/direct")
+	{
+		direct.GET("/files/:id", handlers.SignedDownload)
+		direct.HEAD("/files/:id", handlers.SignedDownload)
+	}
+
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware())
 	{
 		protected.GET("/auth/profile", handlers.GetProfile)
 		protected.GET("/user/storage/quota", handlers.GetStorageQuota)
+		protected.GET("/user/storage/breakdown", handlers.GetStorageBreakdown)
 
 		protected.GET("/folders", handlers.ListFolders)
+		protected.GET("/folders/tree", handlers.GetFolderTree)
+		protected.GET("/folders/:id/ancestors", handlers.GetFolderAncestors)
+		protected.POST("/folders/path", handlers.EnsureFolderPath)
+		protected.GET("/folders/:id/stats", handlers.GetFolderStats)
 		protected.POST("/folders", handlers.CreateFolder)
 		protected.PUT("/folders/:id", handlers.RenameFolder)
 		protected.DELETE("/folders/:id", handlers.DeleteFolder)
 
+		protected.GET("/entries", handlers.ListEntries)
+
+		protected.GET("/favorites", handlers.ListFavorites)
+		protected.POST("/favorites", handlers.AddFavorite)
+		protected.DELETE("/favorites/:type/:id", handlers.RemoveFavorite)
+		protected.GET("/recent/uploads", handlers.ListRecentUploads)
+		protected.GET("/recent/accessed", handlers.ListRecentAccessed)
+
+		protected.GET("/tags", handlers.ListTags)
+		protected.POST("/tags", handlers.CreateTag)
+		protected.PUT("/tags/:id", handlers.UpdateTag)
+		protected.DELETE("/tags/:id", handlers.DeleteTag)
+		protected.POST("/tags/:id/merge", handlers.MergeTags)
+		protected.POST("/tags/bind", handlers.BindTags)
+		protected.POST("/tags/unbind", handlers.UnbindTags)
+		protected.GET("/search", handlers.Search)
+
+		protected.GET("/photos/timeline", handlers.GetPhotoTimeline)
+		protected.GET("/photos/similar", handlers.GetSimilarImages)
+
+		protected.GET("/albums", handlers.ListAlbums)
+		protected.POST("/albums", handlers.CreateAlbum)
+		protected.GET("/albums/:id", handlers.GetAlbum)
+		protected.PUT("/albums/:id", handlers.UpdateAlbum)
+		protected.DELETE("/albums/:id", handlers.DeleteAlbum)
+		protected.GET("/albums/:id/items", handlers.ListAlbumItems)
+		protected.POST("/albums/:id/items", handlers.AddAlbumItems)
+		protected.POST("/albums/:id/items/remove", handlers.RemoveAlbumItems)
+		protected.PUT("/albums/:id/items/order", handlers.ReorderAlbumItems)
+		protected.POST("/albums/:id/share", handlers.ShareAlbum)
+		protected.DELETE("/albums/:id/share", handlers.UnshareAlbum)
+
+		protected.GET("/resolve", handlers.ResolvePath)
+		protected.GET("/resolve/download", handlers.DownloadByPath)
+		protected.DELETE("/resolve", handlers.DeleteByPath)
+
 		protected.GET("/files", handlers.ListFiles)
 		protected.POST("/files/upload", handlers.UploadFile)
 		protected.POST("/files/upload/init", handlers.InitChunkedUpload)
@@ -107,10 +267,17 @@ func setupRoutes(r *gin.Engine) {
 		protected.GET("/files/upload/tasks", handlers.ListUploadTasks)
 		protected.GET("/files/upload/tasks/:upload_id", handlers.GetUploadTaskDetail)
 		protected.DELETE("/files/upload/tasks/:upload_id", handlers.CancelUploadTask)
+		protected.GET("/files/:id", handlers.GetFileDetail)
 		protected.GET("/files/:id/download", handlers.DownloadFile)
 		protected.HEAD("/files/:id/download", handlers.DownloadFileHead)
+		protected.POST("/files/:id/download-url", handlers.CreateDownloadURL)
 		protected.GET("/files/:id/preview", handlers.PreviewFile)
 		protected.GET("/files/:id/thumbnail", handlers.GetThumbnail)
+		protected.GET("/files/:id/variant", handlers.GetImageVariant)
+		protected.GET("/files/:id/text", handlers.GetTextPreview)
+		protected.GET("/files/:id/archive/entries", handlers.ListArchiveEntries)
+		protected.GET("/files/:id/archive/entry", handlers.GetArchiveEntry)
+		protected.POST("/files/:id/archive/extract", handlers.ExtractArchive)
 		protected.DELETE("/files/:id", handlers.DeleteFile)
 		protected.PUT("/files/:id/rename", handlers.RenameFile)
 		protected.PUT("/files/:id/move", handlers.MoveFile)
@@ -122,5 +289,12 @@ func setupRoutes(r *gin.Engine) {
 		protected.POST("/recycle-bin/:id/restore", handlers.RestoreItem)
 		protected.DELETE("/recycle-bin/:id", handlers.PermanentDelete)
 		protected.POST("/recycle-bin/empty", handlers.EmptyRecycleBin)
+		protected.POST("/recycle-bin/batch/restore", handlers.BatchRestoreItems)
+		protected.POST("/recycle-bin/batch/delete", handlers.BatchPermanentDelete)
+		protected.GET("/recycle-bin/settings", handlers.GetRecycleBinSettings)
+		protected.GET("/recycle-bin/:id/contents", handlers.ListRecycleBinFolderContents)
+		protected.POST("/recycle-bin/:id/files/:file_id/restore", handlers.RestoreRecycleBinFolderFile)
+		protected.GET("/recycle-bin/:id/files/:file_id/download", handlers.DownloadRecycleBinFolderFile)
+		protected.PUT("/recycle-bin/settings", handlers.UpdateRecycleBinSettings)
 	}
 }
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mcloud/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPoolCollector 在抓取时读取 go-redis 连接池统计。
+type redisPoolCollector struct {
+	client *redis.Client
+
+	hits, misses, timeouts *prometheus.Desc
+	total, idle, stale     *prometheus.Desc
+}
+
+// RegisterRedisPoolStats 注册 Redis 连接池指标；client 为空时忽略。
+func RegisterRedisPoolStats(client *redis.Client) {
+	if client == nil {
+		return
+	}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, nil, nil)
+	}
+	registry.MustRegister(&redisPoolCollector{
+		client:   client,
+		hits:     desc("hits_total", "Times a free connection was found in the pool."),
+		misses:   desc("misses_total", "Times a free connection was not found in the pool."),
+		timeouts: desc("timeouts_total", "Times a wait for a connection timed out."),
+		total:    desc("connections", "Total connections in the pool."),
+		idle:     desc("idle_connections", "Idle connections in the pool."),
+		stale:    desc("stale_connections_total", "Stale connections removed from the pool."),
+	})
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.hits, c.misses, c.timeouts, c.total, c.idle, c.stale} {
+		ch <- d
+	}
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.StaleConns))
+}
+
+// StorageTotals 为全站存储用量快照。
+type StorageTotals struct {
+	Users           int64
+	QuotaBytes      int64
+	UsedBytes       int64
+	FileObjects     int64
+	FileObjectBytes int64
+	Files           int64
+	RecycleBinItems int64
+	RecycleBinBytes int64
+}
+
+// storageCollector 抓取时查询存储汇总，结果缓存 ttl 以免频繁抓取压垮数据库。
+type storageCollector struct {
+	load func(ctx context.Context) (StorageTotals, error)
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	cached   StorageTotals
+	loadedAt time.Time
+
+	descs map[string]*prometheus.Desc
+}
+
+// RegisterStorageTotals 注册存储用量指标。
+func RegisterStorageTotals(load func(ctx context.Context) (StorageTotals, error), ttl time.Duration) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "storage", name), help, nil, nil)
+	}
+	registry.MustRegister(&storageCollector{
+		load: load,
+		ttl:  ttl,
+		descs: map[string]*prometheus.Desc{
+			"users":             desc("users", "Registered users."),
+			"quota_bytes":       desc("quota_bytes", "Sum of user storage quotas."),
+			"used_bytes":        desc("used_bytes", "Sum of storage accounted to users."),
+			"file_objects":      desc("file_objects", "Physical file objects."),
+			"file_object_bytes": desc("file_object_bytes", "Bytes held by physical file objects on disk."),
+			"files":             desc("files", "Active logical files."),
+			"recycle_bin_items": desc("recycle_bin_items", "Items in recycle bins."),
+			"recycle_bin_bytes": desc("recycle_bin_bytes", "File bytes held in recycle bins."),
+		},
+	})
+}
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	totals, ok := c.snapshot()
+	if !ok {
+		return
+	}
+	values := map[string]int64{
+		"users":             totals.Users,
+		"quota_bytes":       totals.QuotaBytes,
+		"used_bytes":        totals.UsedBytes,
+		"file_objects":      totals.FileObjects,
+		"file_object_bytes": totals.FileObjectBytes,
+		"files":             totals.Files,
+		"recycle_bin_items": totals.RecycleBinItems,
+		"recycle_bin_bytes": totals.RecycleBinBytes,
+	}
+	for name, value := range values {
+		ch <- prometheus.MustNewConstMetric(c.descs[name], prometheus.GaugeValue, float64(value))
+	}
+}
+
+func (c *storageCollector) snapshot() (StorageTotals, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl {
+		return c.cached, true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	totals, err := c.load(ctx)
+	if err != nil {
+		logger.Warnf("统计存储用量失败: %v", err)
+		// 查询失败时沿用上一次结果，首次失败则不输出。
+		return c.cached, !c.loadedAt.IsZero()
+	}
+	c.cached = totals
+	c.loadedAt = time.Now()
+	return totals, true
+}
@@ -0,0 +1,172 @@
+// Package metrics 汇总 mCloud 的 Prometheus 指标，业务代码只通过本包的函数上报，
+// 不直接依赖 prometheus 客户端。
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mcloud"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	uploadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes written to storage by uploads (kind: form | chunk).",
+	}, []string{"kind"})
+
+	downloadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_bytes_total",
+		Help:      "Bytes sent to clients (kind: download | preview | thumbnail).",
+	}, []string{"kind"})
+
+	chunkUploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chunk_uploads_total",
+		Help:      "Chunk upload requests (result: stored | duplicate).",
+	}, []string{"result"})
+
+	instantUploadHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "instant_upload_hits_total",
+		Help:      "Uploads satisfied by an existing file object (source: init | form | merge).",
+	}, []string{"source"})
+
+	mergeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_merge_duration_seconds",
+		Help:      "Time spent merging chunks and verifying MD5.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"result"})
+
+	thumbnails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "thumbnail_generations_total",
+		Help:      "Thumbnail generation attempts (result: success | failure).",
+	}, []string{"result"})
+
+	cleanupRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cleanup_runs_total",
+		Help:      "Cleanup job runs (job: upload_tasks | recycle_bin).",
+	}, []string{"job"})
+
+	cleanupPurged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cleanup_items_purged_total",
+		Help:      "Items removed by cleanup jobs.",
+	}, []string{"job"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		uploadBytes,
+		downloadBytes,
+		chunkUploads,
+		instantUploadHits,
+		mergeDuration,
+		thumbnails,
+		cleanupRuns,
+		cleanupPurged,
+	)
+}
+
+// Handler 返回 Prometheus 文本格式的指标输出。
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// RegisterDBStats 注册数据库连接池指标。
+func RegisterDBStats(db *sql.DB) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// ObserveHTTPRequest 记录一次 HTTP 请求；route 为路由模板而非实际路径，避免标签基数爆炸。
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// AddUploadBytes 累计写入存储的上传字节数。
+func AddUploadBytes(kind string, n int64) {
+	if n > 0 {
+		uploadBytes.WithLabelValues(kind).Add(float64(n))
+	}
+}
+
+// AddDownloadBytes 累计发送给客户端的字节数。
+func AddDownloadBytes(kind string, n int64) {
+	if n > 0 {
+		downloadBytes.WithLabelValues(kind).Add(float64(n))
+	}
+}
+
+// IncChunkUpload 记录一次分片上传，duplicate 表示分片已存在而跳过写盘。
+func IncChunkUpload(duplicate bool) {
+	result := "stored"
+	if duplicate {
+		result = "duplicate"
+	}
+	chunkUploads.WithLabelValues(result).Inc()
+}
+
+// IncInstantUploadHit 记录一次命中已有文件对象的秒传/去重。
+func IncInstantUploadHit(source string) {
+	instantUploadHits.WithLabelValues(source).Inc()
+}
+
+// ObserveMerge 记录分片合并（含 MD5 校验）耗时。
+func ObserveMerge(duration time.Duration, ok bool) {
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	mergeDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// ObserveThumbnail 记录一次缩略图生成结果。
+func ObserveThumbnail(err error) {
+	thumbnails.WithLabelValues(resultLabel(err)).Inc()
+}
+
+// ObserveCleanup 记录一次清理任务执行及清理条目数。
+func ObserveCleanup(job string, purged int) {
+	cleanupRuns.WithLabelValues(job).Inc()
+	if purged > 0 {
+		cleanupPurged.WithLabelValues(job).Add(float64(purged))
+	}
+}
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
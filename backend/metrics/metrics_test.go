@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestHandlerExposesObservations(t *testing.T) {
+	ObserveHTTPRequest("GET", "/api/files", 200, 20*time.Millisecond)
+	AddUploadBytes("chunk", 1024)
+	AddDownloadBytes("download", 2048)
+	IncChunkUpload(true)
+	IncInstantUploadHit("init")
+	ObserveMerge(time.Second, true)
+	ObserveThumbnail(errors.New("decode failed"))
+	ObserveCleanup("recycle_bin", 3)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`mcloud_http_requests_total{method="GET",route="/api/files",status="200"} 1`,
+		`mcloud_upload_bytes_total{kind="chunk"} 1024`,
+		`mcloud_download_bytes_total{kind="download"} 2048`,
+		`mcloud_chunk_uploads_total{result="duplicate"} 1`,
+		`mcloud_instant_upload_hits_total{source="init"} 1`,
+		`mcloud_upload_merge_duration_seconds_count{result="success"} 1`,
+		`mcloud_thumbnail_generations_total{result="failure"} 1`,
+		`mcloud_cleanup_items_purged_total{job="recycle_bin"} 3`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestStorageCollectorCachesAndKeepsLastValueOnError(t *testing.T) {
+	calls := 0
+	fail := false
+	c := &storageCollector{
+		ttl: time.Hour,
+		load: func(context.Context) (StorageTotals, error) {
+			calls++
+			if fail {
+				return StorageTotals{}, errors.New("db down")
+			}
+			return StorageTotals{Users: int64(calls)}, nil
+		},
+	}
+
+	first, ok := c.snapshot()
+	if !ok || first.Users != 1 {
+		t.Fatalf("expected first load, got %+v %v", first, ok)
+	}
+	if again, _ := c.snapshot(); again.Users != 1 || calls != 1 {
+		t.Fatalf("expected cached value within ttl, got %+v after %d calls", again, calls)
+	}
+
+	c.loadedAt = time.Now().Add(-2 * time.Hour)
+	fail = true
+	stale, ok := c.snapshot()
+	if !ok || stale.Users != 1 {
+		t.Fatalf("expected last value to be kept on error, got %+v %v", stale, ok)
+	}
+}
+
+func TestStorageCollectorSkipsOutputUntilFirstSuccess(t *testing.T) {
+	c := &storageCollector{
+		ttl:  time.Hour,
+		load: func(context.Context) (StorageTotals, error) { return StorageTotals{}, errors.New("db down") },
+	}
+	if _, ok := c.snapshot(); ok {
+		t.Fatalf("expected no snapshot before the first successful load")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"mcloud/logger"
 	"mcloud/utils"
 
 	"github.com/gin-gonic/gin"
@@ -33,6 +34,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		c.Set("user_id", claims.UserID)
+		c.Request = c.Request.WithContext(logger.WithAttrs(c.Request.Context(), "user_id", claims.UserID))
 		c.Next()
 	}
 }
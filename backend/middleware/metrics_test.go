@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsAuthRequiresTokenRegardlessOfSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", MetricsAuth("secret"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		// 同机反向代理转发的请求来自本机地址，不能因此放行。
+		req.RemoteAddr = "127.0.0.1:5000"
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("auth %q: expected %d, got %d", tc.auth, tc.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"mcloud/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 为请求 ID 的请求/响应头。
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID 为每个请求分配请求 ID：沿用上游代理传入的合法 ID，否则生成新的 UUID。
+// ID 写入响应头与请求 context，经 logger.Ctx 输出的日志都会带上该字段。
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID 只接受长度受限的可见 ASCII 字符，防止日志注入。
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		isAlnum := ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+		if !isAlnum && ch != '-' && ch != '_' && ch != '.' {
+			return false
+		}
+	}
+	return true
+}
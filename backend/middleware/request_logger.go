@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net/http"
 	"time"
 
 	"mcloud/logger"
@@ -8,31 +9,34 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RequestLogger writes per-request logs at debug level.
+// RequestLogger 记录每个请求的访问日志：5xx 记为 error，其余为 debug。
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !logger.IsDebugEnabled() {
-			c.Next()
-			return
-		}
-
 		start := time.Now()
 		path := c.Request.URL.Path
 		rawQuery := c.Request.URL.RawQuery
 
 		c.Next()
 
+		status := c.Writer.Status()
+		if status < http.StatusInternalServerError && !logger.IsDebugEnabled() {
+			return
+		}
 		if rawQuery != "" {
 			path = path + "?" + rawQuery
 		}
 
-		logger.Debugf(
-			"%s | %d | %s | %s | %s",
-			c.Request.Method,
-			c.Writer.Status(),
-			time.Since(start),
-			c.ClientIP(),
-			path,
+		entry := logger.Ctx(c.Request.Context()).With(
+			"method", c.Request.Method,
+			"path", path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"client_ip", c.ClientIP(),
 		)
+		if status >= http.StatusInternalServerError {
+			entry.Errorf("request failed")
+			return
+		}
+		entry.Debugf("request")
 	}
 }
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trustedProxyNets 为 SetTrustedProxies 解析出的可信代理网段，供 FromTrustedProxy 判断转发头是否可信。
+var trustedProxyNets []*net.IPNet
+
+// SetTrustedProxies 配置允许通过 X-Forwarded-For / X-Real-IP 传递客户端地址的反向代理（IP 或 CIDR）。
+// gin 默认信任所有代理，任何客户端都能伪造 ClientIP；列表为空时不信任任何代理，ClientIP 即连接的对端地址。
+func SetTrustedProxies(r *gin.Engine, proxies []string) error {
+	if len(proxies) == 0 {
+		proxies = nil
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		return err
+	}
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, ipNet)
+	}
+	trustedProxyNets = nets
+	return nil
+}
+
+// FromTrustedProxy 判断请求是否直接来自可信反向代理；X-Forwarded-Proto 等转发头只在此时采信。
+func FromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxyNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
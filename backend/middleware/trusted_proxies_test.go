@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func clientIPFor(t *testing.T, proxies []string, remoteAddr string, headers map[string]string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := SetTrustedProxies(r, proxies); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+	r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestSetTrustedProxiesIgnoresSpoofedHeadersByDefault(t *testing.T) {
+	spoofed := map[string]string{"X-Forwarded-For": "10.0.0.8", "X-Real-IP": "10.0.0.8"}
+	if got := clientIPFor(t, nil, "203.0.113.5:4321", spoofed); got != "203.0.113.5" {
+		t.Fatalf("expected peer address without trusted proxies, got %q", got)
+	}
+	// 未列入信任列表的代理同样不能改写客户端地址。
+	if got := clientIPFor(t, []string{"127.0.0.1"}, "203.0.113.5:4321", spoofed); got != "203.0.113.5" {
+		t.Fatalf("expected untrusted peer to be used, got %q", got)
+	}
+}
+
+func TestSetTrustedProxiesHonorsConfiguredProxy(t *testing.T) {
+	headers := map[string]string{"X-Forwarded-For": "10.0.0.8"}
+	if got := clientIPFor(t, []string{"127.0.0.1", "192.168.0.0/16"}, "192.168.1.2:4321", headers); got != "10.0.0.8" {
+		t.Fatalf("expected forwarded address from trusted proxy, got %q", got)
+	}
+	if err := SetTrustedProxies(gin.New(), []string{"not-an-ip"}); err == nil {
+		t.Fatal("expected invalid proxy to be rejected")
+	}
+}
+
+func TestFromTrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { trustedProxyNets = nil })
+	fromProxy := func(remoteAddr string) bool {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.RemoteAddr = remoteAddr
+		return FromTrustedProxy(c)
+	}
+
+	if err := SetTrustedProxies(gin.New(), nil); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+	if fromProxy("127.0.0.1:4321") {
+		t.Fatal("expected no trusted proxy by default")
+	}
+	if err := SetTrustedProxies(gin.New(), []string{"127.0.0.1", "192.168.0.0/16", "::1"}); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+	for addr, want := range map[string]bool{"127.0.0.1:1": true, "192.168.3.4:1": true, "[::1]:1": true, "203.0.113.5:1": false} {
+		if got := fromProxy(addr); got != want {
+			t.Fatalf("FromTrustedProxy(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
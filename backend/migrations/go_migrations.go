@@ -0,0 +1,387 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// goMigrations 登记需要用 Go 代码实现的迁移（如数据回填），与 sql 目录下的迁移共享版本号空间。
+// 新增表优先在此用 gorm Migrator 建表，一份代码即可覆盖全部方言；结构体需在迁移内定义快照，
+// 不要直接引用 models，避免模型后续变化改写历史迁移。
+var goMigrations = []Migration{
+	{
+		Version: 2,
+		Name:    "upload_chunk_progress",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&uploadChunkProgressV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&uploadChunkProgressV2{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "user_recycle_retention",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&userV3{}, "RecycleRetentionDays")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&userV3{}, "RecycleRetentionDays")
+		},
+	},
+	{
+		// 目录内列表按 (排序键, id) 做游标分页，复合索引让每一页都能直接定位而不必扫描前序数据。
+		Version: 4,
+		Name:    "listing_keyset_indexes",
+		Up: func(tx *gorm.DB) error {
+			for _, idx := range listingIndexesV4 {
+				if err := tx.Migrator().CreateIndex(idx.model, idx.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, idx := range listingIndexesV4 {
+				if err := tx.Migrator().DropIndex(idx.model, idx.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "favorites_and_file_accesses",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&favoriteV5{}, &fileAccessV5{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&fileAccessV5{}, &favoriteV5{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "tags",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&tagV6{}, &tagBindingV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&tagBindingV6{}, &tagV6{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "image_metadata",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&imageMetadataV7{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&imageMetadataV7{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "albums",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&albumV8{}, &albumItemV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&albumItemV8{}, &albumV8{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "file_object_perceptual_hash",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&fileObjectV9{}, "PerceptualHash"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&fileObjectV9{}, "PerceptualHash")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&fileObjectV9{}, "PerceptualHash"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&fileObjectV9{}, "PerceptualHash")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "media_metadata",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&mediaMetadataV10{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&mediaMetadataV10{})
+		},
+	},
+	{
+		// 早期回收站条目可能缺少删除前完整路径，按路径前缀筛选时会被漏掉；由仍保留的已删除记录回填。
+		Version: 11,
+		Name:    "recycle_bin_original_full_path",
+		Up:      backfillRecycleFullPathV11,
+		// 回填的路径与新条目写入的一致，回滚时无需撤销。
+		Down: func(*gorm.DB) error { return nil },
+	},
+}
+
+type uploadChunkProgressV2 struct {
+	UploadID   string     `gorm:"type:varchar(36);primaryKey"`
+	ChunkIndex int        `gorm:"primaryKey;autoIncrement:false"`
+	ExpiresAt  *time.Time `gorm:"index"`
+}
+
+func (uploadChunkProgressV2) TableName() string {
+	return "upload_chunk_progress"
+}
+
+type userV3 struct {
+	ID                   uint `gorm:"primaryKey"`
+	RecycleRetentionDays *int
+}
+
+func (userV3) TableName() string {
+	return "users"
+}
+
+type fileV4 struct {
+	ID           uint      `gorm:"primaryKey;index:idx_files_folder_created,priority:3;index:idx_files_folder_name,priority:3"`
+	FolderID     uint      `gorm:"index:idx_files_folder_created,priority:1;index:idx_files_folder_name,priority:1"`
+	OriginalName string    `gorm:"type:varchar(255);index:idx_files_folder_name,priority:2"`
+	CreatedAt    time.Time `gorm:"index:idx_files_folder_created,priority:2"`
+}
+
+func (fileV4) TableName() string {
+	return "files"
+}
+
+type folderV4 struct {
+	ID        uint      `gorm:"primaryKey;index:idx_folders_parent_created,priority:3;index:idx_folders_parent_name,priority:3"`
+	ParentID  *uint     `gorm:"index:idx_folders_parent_created,priority:1;index:idx_folders_parent_name,priority:1"`
+	Name      string    `gorm:"type:varchar(255);index:idx_folders_parent_name,priority:2"`
+	CreatedAt time.Time `gorm:"index:idx_folders_parent_created,priority:2"`
+}
+
+func (folderV4) TableName() string {
+	return "folders"
+}
+
+var listingIndexesV4 = []struct {
+	model interface{}
+	name  string
+}{
+	{&fileV4{}, "idx_files_folder_created"},
+	{&fileV4{}, "idx_files_folder_name"},
+	{&folderV4{}, "idx_folders_parent_created"},
+	{&folderV4{}, "idx_folders_parent_name"},
+}
+
+type favoriteV5 struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	UserID     uint   `gorm:"not null;uniqueIndex:uk_favorites_target,priority:1"`
+	TargetType string `gorm:"type:varchar(10);not null;uniqueIndex:uk_favorites_target,priority:2"`
+	TargetID   uint   `gorm:"not null;uniqueIndex:uk_favorites_target,priority:3"`
+	CreatedAt  time.Time
+}
+
+func (favoriteV5) TableName() string {
+	return "favorites"
+}
+
+type fileAccessV5 struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	UserID         uint      `gorm:"not null;uniqueIndex:uk_file_accesses_user_file,priority:1;index:idx_file_accesses_user_time,priority:1"`
+	FileID         uint      `gorm:"not null;uniqueIndex:uk_file_accesses_user_file,priority:2"`
+	AccessCount    int64     `gorm:"not null;default:1"`
+	LastAccessedAt time.Time `gorm:"not null;index:idx_file_accesses_user_time,priority:2"`
+	CreatedAt      time.Time
+}
+
+func (fileAccessV5) TableName() string {
+	return "file_accesses"
+}
+
+type tagV6 struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    uint   `gorm:"not null;uniqueIndex:uk_tags_user_name,priority:1"`
+	Name      string `gorm:"type:varchar(64);not null;uniqueIndex:uk_tags_user_name,priority:2"`
+	Color     string `gorm:"type:varchar(7);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (tagV6) TableName() string {
+	return "tags"
+}
+
+type tagBindingV6 struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	UserID     uint   `gorm:"not null;index"`
+	TagID      uint   `gorm:"not null;index;uniqueIndex:uk_tag_bindings_target,priority:3"`
+	TargetType string `gorm:"type:varchar(10);not null;uniqueIndex:uk_tag_bindings_target,priority:1"`
+	TargetID   uint   `gorm:"not null;uniqueIndex:uk_tag_bindings_target,priority:2"`
+	CreatedAt  time.Time
+}
+
+func (tagBindingV6) TableName() string {
+	return "tag_bindings"
+}
+
+type imageMetadataV7 struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement"`
+	FileObjectID uint       `gorm:"not null;uniqueIndex"`
+	TakenAt      *time.Time `gorm:"index"`
+	CameraMake   string     `gorm:"type:varchar(100)"`
+	CameraModel  string     `gorm:"type:varchar(100)"`
+	LensModel    string     `gorm:"type:varchar(100)"`
+	Orientation  int        `gorm:"not null;default:0"`
+	Latitude     *float64
+	Longitude    *float64
+	CreatedAt    time.Time
+}
+
+func (imageMetadataV7) TableName() string {
+	return "image_metadata"
+}
+
+type albumV8 struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	UserID      uint   `gorm:"not null;index"`
+	Name        string `gorm:"type:varchar(100);not null"`
+	Description string `gorm:"type:varchar(500)"`
+	CoverFileID *uint
+	ShareToken  *string `gorm:"type:varchar(64);uniqueIndex"`
+	SharedAt    *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (albumV8) TableName() string {
+	return "albums"
+}
+
+type albumItemV8 struct {
+	ID        uint `gorm:"primaryKey;autoIncrement"`
+	AlbumID   uint `gorm:"not null;uniqueIndex:uk_album_items_album_file,priority:1"`
+	FileID    uint `gorm:"not null;index;uniqueIndex:uk_album_items_album_file,priority:2"`
+	Position  int  `gorm:"not null;default:0"`
+	CreatedAt time.Time
+}
+
+func (albumItemV8) TableName() string {
+	return "album_items"
+}
+
+type fileObjectV9 struct {
+	ID             uint    `gorm:"primaryKey"`
+	PerceptualHash *string `gorm:"type:varchar(16);index"`
+}
+
+func (fileObjectV9) TableName() string {
+	return "file_objects"
+}
+
+type mediaMetadataV10 struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`
+	FileObjectID uint   `gorm:"not null;uniqueIndex"`
+	Kind         string `gorm:"type:varchar(10)"`
+	Format       string `gorm:"type:varchar(10)"`
+	DurationMs   int64  `gorm:"not null;default:0"`
+	Width        int    `gorm:"not null;default:0"`
+	Height       int    `gorm:"not null;default:0"`
+	VideoCodec   string `gorm:"type:varchar(32)"`
+	AudioCodec   string `gorm:"type:varchar(32)"`
+	SampleRate   int    `gorm:"not null;default:0"`
+	Channels     int    `gorm:"not null;default:0"`
+	Title        string `gorm:"type:varchar(255)"`
+	Artist       string `gorm:"type:varchar(255)"`
+	Album        string `gorm:"type:varchar(255)"`
+	HasCover     bool   `gorm:"not null;default:false"`
+	CreatedAt    time.Time
+}
+
+func (mediaMetadataV10) TableName() string {
+	return "media_metadata"
+}
+
+type recycleBinItemV11 struct {
+	ID               uint `gorm:"primaryKey"`
+	UserID           uint
+	OriginalID       uint
+	OriginalType     string
+	OriginalFullPath string
+}
+
+func (recycleBinItemV11) TableName() string {
+	return "recycle_bin"
+}
+
+type folderV11 struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID uint
+	Path   string
+}
+
+func (folderV11) TableName() string {
+	return "folders"
+}
+
+type fileV11 struct {
+	ID           uint `gorm:"primaryKey"`
+	UserID       uint
+	FolderID     uint
+	OriginalName string
+}
+
+func (fileV11) TableName() string {
+	return "files"
+}
+
+// backfillRecycleFullPathV11 为缺少完整路径的条目按原目录或原文件记录补全路径；记录已不存在的条目保持为空。
+func backfillRecycleFullPathV11(tx *gorm.DB) error {
+	var items []recycleBinItemV11
+	return tx.Where("original_full_path IS NULL OR original_full_path = ''").
+		FindInBatches(&items, 500, func(*gorm.DB, int) error {
+			for _, item := range items {
+				path, err := recycleFullPathV11(tx, item)
+				if err != nil {
+					return err
+				}
+				if path == "" {
+					continue
+				}
+				if err := tx.Model(&recycleBinItemV11{}).Where("id = ?", item.ID).
+					Update("original_full_path", path).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// recycleFullPathV11 与回收站写入条目时的规则一致：目录取自身路径，文件取所在目录路径拼接原文件名。
+func recycleFullPathV11(tx *gorm.DB, item recycleBinItemV11) (string, error) {
+	folderID := item.OriginalID
+	name := ""
+	if item.OriginalType == "file" {
+		var files []fileV11
+		if err := tx.Where("id = ? AND user_id = ?", item.OriginalID, item.UserID).Limit(1).Find(&files).Error; err != nil || len(files) == 0 {
+			return "", err
+		}
+		folderID, name = files[0].FolderID, files[0].OriginalName
+	}
+	var folders []folderV11
+	if err := tx.Where("id = ? AND user_id = ?", folderID, item.UserID).Limit(1).Find(&folders).Error; err != nil || len(folders) == 0 {
+		return "", err
+	}
+	if name == "" {
+		return folders[0].Path, nil
+	}
+	if folders[0].Path == "" || folders[0].Path == "/" {
+		return "/" + name, nil
+	}
+	return strings.TrimRight(folders[0].Path, "/") + "/" + name, nil
+}
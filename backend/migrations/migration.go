@@ -0,0 +1,184 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqlFiles 按方言分目录存放 SQL 迁移：sql/mysql、sql/postgres、sql/sqlite，
+// 三个目录须保持相同的版本号集合。
+//
+//go:embed sql
+var sqlFiles embed.FS
+
+// sqlFilePattern 匹配 "<版本号>_<名称>.<up|down>.sql" 格式的迁移文件名。
+var sqlFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 描述一个带版本号的结构变更，Down 为空表示不可回滚。
+type Migration struct {
+	// Version 为全局唯一且单调递增的版本号。
+	Version int64
+	// Name 为便于识别的迁移名称。
+	Name string
+	// Up 执行正向变更。
+	Up func(tx *gorm.DB) error
+	// Down 撤销 Up 的变更（可选）。
+	Down func(tx *gorm.DB) error
+}
+
+// All 汇总指定方言（gorm Dialector.Name()）的 SQL 迁移与 Go 迁移，并按版本号升序返回。
+func All(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	if _, err := fs.Stat(sqlFiles, dir); err != nil {
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+	sqlMigrations, err := loadSQLMigrations(sqlFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return merge(sqlMigrations, goMigrations)
+}
+
+// merge 合并多组迁移，版本号冲突时直接报错。
+func merge(groups ...[]Migration) ([]Migration, error) {
+	seen := make(map[int64]string)
+	var result []Migration
+	for _, group := range groups {
+		for _, m := range group {
+			if m.Version <= 0 {
+				return nil, fmt.Errorf("migration %q has invalid version %d", m.Name, m.Version)
+			}
+			if m.Up == nil {
+				return nil, fmt.Errorf("migration %d_%s has no up step", m.Version, m.Name)
+			}
+			if name, ok := seen[m.Version]; ok {
+				return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", m.Version, name, m.Name)
+			}
+			seen[m.Version] = m.Name
+			result = append(result, m)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// loadSQLMigrations 从目录读取成对的 up/down SQL 文件并转换为迁移。
+func loadSQLMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := sqlFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse migration version %q: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has mismatched names %q and %q", version, m.Name, match[2])
+		}
+
+		step := execSQL(splitStatements(string(content)))
+		if match[3] == "up" {
+			m.Up = step
+		} else {
+			m.Down = step
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		result = append(result, *m)
+	}
+	return result, nil
+}
+
+// execSQL 生成按顺序执行多条语句的迁移步骤。
+func execSQL(statements []string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("exec %q: %w", firstLine(stmt), err)
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements 按分号切分 SQL 脚本，忽略引号内分号与 "--" 行注释。
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      rune
+		inComment  bool
+	)
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if inComment {
+			if ch == '\n' {
+				inComment = false
+				current.WriteRune(ch)
+			}
+			continue
+		}
+		if quote != 0 {
+			current.WriteRune(ch)
+			if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			current.WriteRune(ch)
+		case ch == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			inComment = true
+		case ch == ';':
+			if stmt := strings.TrimSpace(current.String()); stmt != "" {
+				statements = append(statements, stmt)
+			}
+			current.Reset()
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		statements = append(statements, stmt)
+	}
+	return statements
+}
+
+func firstLine(stmt string) string {
+	if idx := strings.IndexByte(stmt, '\n'); idx >= 0 {
+		return strings.TrimSpace(stmt[:idx])
+	}
+	return stmt
+}
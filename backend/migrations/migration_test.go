@@ -0,0 +1,172 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gorm.io/gorm"
+)
+
+func TestSplitStatementsIgnoresCommentsAndQuotedSemicolons(t *testing.T) {
+	script := `-- header; comment
+CREATE TABLE a (id INT, note VARCHAR(10) COMMENT 'x;y'); -- trailing
+INSERT INTO a VALUES (1, "a;b");
+`
+	got := splitStatements(script)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(got), got)
+	}
+	if !strings.Contains(got[0], "'x;y'") {
+		t.Fatalf("quoted semicolon should be preserved, got %q", got[0])
+	}
+	if strings.Contains(got[0], "header") || strings.Contains(got[1], "trailing") {
+		t.Fatalf("comments should be stripped, got %q", got)
+	}
+}
+
+func TestLoadSQLMigrationsPairsUpAndDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":  {Data: []byte("SELECT 2;")},
+		"sql/0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+		"sql/0001_first.down.sql": {Data: []byte("SELECT 0;")},
+	}
+	got, err := loadSQLMigrations(fsys, "sql")
+	if err != nil {
+		t.Fatalf("loadSQLMigrations failed: %v", err)
+	}
+	sorted, err := merge(got)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(sorted) != 2 || sorted[0].Version != 1 || sorted[1].Version != 2 {
+		t.Fatalf("unexpected migrations: %+v", sorted)
+	}
+	if sorted[0].Down == nil || sorted[1].Down != nil {
+		t.Fatalf("expected only first migration to be reversible")
+	}
+}
+
+func TestLoadSQLMigrationsRejectsBadFileName(t *testing.T) {
+	fsys := fstest.MapFS{"sql/init.sql": {Data: []byte("SELECT 1;")}}
+	if _, err := loadSQLMigrations(fsys, "sql"); err == nil {
+		t.Fatalf("expected invalid file name to be rejected")
+	}
+}
+
+func TestMergeRejectsDuplicateVersions(t *testing.T) {
+	noop := func(*gorm.DB) error { return nil }
+	_, err := merge(
+		[]Migration{{Version: 1, Name: "a", Up: noop}},
+		[]Migration{{Version: 1, Name: "b", Up: noop}},
+	)
+	if err == nil {
+		t.Fatalf("expected duplicate version error")
+	}
+}
+
+func TestAllIncludesInitMigrationForEveryDialect(t *testing.T) {
+	var versions []int64
+	for _, dialect := range []string{"mysql", "postgres", "sqlite"} {
+		all, err := All(dialect)
+		if err != nil {
+			t.Fatalf("All(%s) failed: %v", dialect, err)
+		}
+		if len(all) == 0 || all[0].Version != 1 || all[0].Name != "init" {
+			t.Fatalf("expected 0001_init to be first for %s, got %+v", dialect, all)
+		}
+		if all[0].Down == nil {
+			t.Fatalf("expected %s init migration to be reversible", dialect)
+		}
+
+		var current []int64
+		for _, m := range all {
+			current = append(current, m.Version)
+		}
+		if versions != nil && fmt.Sprint(versions) != fmt.Sprint(current) {
+			t.Fatalf("dialect %s has versions %v, expected %v", dialect, current, versions)
+		}
+		versions = current
+	}
+}
+
+func TestAllRejectsUnknownDialect(t *testing.T) {
+	if _, err := All("oracle"); err == nil {
+		t.Fatalf("expected unknown dialect to be rejected")
+	}
+}
+
+func TestSQLiteInitMigrationUpAndDown(t *testing.T) {
+	db := newTestDB(t)
+	all, err := All(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	runner := NewRunner(db, all)
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	for _, table := range []string{"users", "folders", "files", "file_objects", "upload_tasks", "recycle_bin", "thumbnail_tasks"} {
+		if !db.Migrator().HasTable(table) {
+			t.Fatalf("expected table %s to exist", table)
+		}
+	}
+
+	if _, err := runner.Down(ctx, len(all)); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if db.Migrator().HasTable("users") {
+		t.Fatalf("expected users table to be dropped")
+	}
+}
+
+func TestBackfillRecycleFullPathFromDeletedRecords(t *testing.T) {
+	db := newTestDB(t)
+	all, err := All(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	var before []Migration
+	for _, m := range all {
+		if m.Version < 11 {
+			before = append(before, m)
+		}
+	}
+	ctx := context.Background()
+	if _, err := NewRunner(db, before).Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// 目录与文件均已软删除，回收站条目缺少完整路径；条目 4 的原记录已不存在。
+	seed := []string{
+		"INSERT INTO folders (id, name, user_id, path, deleted_at) VALUES (1, 'root', 7, '/', NULL), (2, 'docs', 7, '/docs', '2026-01-01 00:00:00')",
+		"INSERT INTO files (id, name, original_name, folder_id, user_id, file_object_id, deleted_at) VALUES (5, 'x', 'a.txt', 2, 7, 1, '2026-01-01 00:00:00'), (6, 'y', 'b.txt', 1, 7, 1, '2026-01-01 00:00:00')",
+		"INSERT INTO recycle_bin (id, user_id, original_id, original_type, original_name, original_full_path, expires_at) VALUES " +
+			"(1, 7, 2, 'folder', 'docs', NULL, '2026-02-01 00:00:00'), (2, 7, 5, 'file', 'a.txt', '', '2026-02-01 00:00:00'), " +
+			"(3, 7, 6, 'file', 'b.txt', NULL, '2026-02-01 00:00:00'), (4, 7, 9, 'file', 'gone.txt', NULL, '2026-02-01 00:00:00'), " +
+			"(5, 7, 6, 'file', 'b.txt', '/kept.txt', '2026-02-01 00:00:00')",
+	}
+	for _, stmt := range seed {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+
+	if _, err := NewRunner(db, all).Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	var items []recycleBinItemV11
+	if err := db.Order("id").Find(&items).Error; err != nil {
+		t.Fatalf("load recycle items failed: %v", err)
+	}
+	want := []string{"/docs", "/docs/a.txt", "/b.txt", "", "/kept.txt"}
+	for i, item := range items {
+		if item.OriginalFullPath != want[i] {
+			t.Fatalf("item %d: expected %q, got %q", item.ID, want[i], item.OriginalFullPath)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"mcloud/logger"
+
+	"gorm.io/gorm"
+)
+
+// ErrLockTimeout 表示在等待时限内未能获取迁移锁。
+var ErrLockTimeout = errors.New("timed out waiting for schema migration lock")
+
+const migrationLockID = 1
+
+// schemaMigration 记录已执行的迁移版本。
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaMigrationLock 依赖主键唯一性实现跨实例互斥，仅允许存在一行。
+type schemaMigrationLock struct {
+	ID       int       `gorm:"primaryKey;autoIncrement:false"`
+	LockedBy string    `gorm:"type:varchar(128);not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Status 描述单个迁移的执行状态。
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Unknown 表示数据库中有记录但代码里已不存在该迁移。
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Runner 负责按版本顺序执行、回滚迁移，并在多实例间加锁。
+type Runner struct {
+	db           *gorm.DB
+	migrations   []Migration
+	owner        string
+	lockTimeout  time.Duration
+	staleAfter   time.Duration
+	pollInterval time.Duration
+	// heartbeat 为持锁期间刷新 locked_at 的间隔，须明显小于 staleAfter，长时间的迁移才不会被当作陈旧锁抢占。
+	heartbeat time.Duration
+}
+
+// NewRunner 创建迁移执行器；migrations 需已按版本号排序（见 All）。
+func NewRunner(db *gorm.DB, migrations []Migration) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		db:           db,
+		migrations:   migrations,
+		owner:        fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		lockTimeout:  2 * time.Minute,
+		staleAfter:   10 * time.Minute,
+		pollInterval: 500 * time.Millisecond,
+		heartbeat:    time.Minute,
+	}
+}
+
+// Up 执行全部未应用的迁移，返回本次实际执行的列表。
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := r.withLock(ctx, func() error {
+		done, err := r.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := r.apply(ctx, m); err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down 按版本倒序回滚最近 steps 个已应用的迁移。
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, nil
+	}
+
+	byVersion := make(map[int64]Migration, len(r.migrations))
+	for _, m := range r.migrations {
+		byVersion[m.Version] = m
+	}
+
+	var reverted []Migration
+	err := r.withLock(ctx, func() error {
+		var records []schemaMigration
+		if err := r.db.WithContext(ctx).Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+		for _, record := range records {
+			m, ok := byVersion[record.Version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s is applied but missing from code", record.Version, record.Name)
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %d_%s is irreversible", m.Version, m.Name)
+			}
+			if err := r.revert(ctx, m); err != nil {
+				return err
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status 返回代码与数据库中全部迁移的合并视图。
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	if err := r.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+	done, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if record, ok := done[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(done, m.Version)
+		}
+		result = append(result, status)
+	}
+	for _, record := range done {
+		appliedAt := record.AppliedAt
+		result = append(result, Status{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// apply 在事务内执行单个迁移并写入版本记录。
+func (r *Runner) apply(ctx context.Context, m Migration) error {
+	start := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	logger.Infof("migration %d_%s applied in %s", m.Version, m.Name, time.Since(start))
+	return nil
+}
+
+// revert 在事务内回滚单个迁移并删除版本记录。
+func (r *Runner) revert(ctx context.Context, m Migration) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+		return tx.Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	logger.Infof("migration %d_%s reverted", m.Version, m.Name)
+	return nil
+}
+
+func (r *Runner) appliedVersions(ctx context.Context) (map[int64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := r.db.WithContext(ctx).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		result[record.Version] = record
+	}
+	return result, nil
+}
+
+// ensureTables 创建迁移记录表与锁表；并发建表失败时以表是否存在为准。
+func (r *Runner) ensureTables(ctx context.Context) error {
+	migrator := r.db.WithContext(ctx).Migrator()
+	for _, model := range []interface{}{&schemaMigration{}, &schemaMigrationLock{}} {
+		if migrator.HasTable(model) {
+			continue
+		}
+		if err := migrator.CreateTable(model); err != nil && !migrator.HasTable(model) {
+			return err
+		}
+	}
+	return nil
+}
+
+// withLock 持有迁移锁执行 fn，结束后释放锁。
+func (r *Runner) withLock(ctx context.Context, fn func() error) error {
+	if err := r.ensureTables(ctx); err != nil {
+		return err
+	}
+	if err := r.acquireLock(ctx); err != nil {
+		return err
+	}
+	defer r.releaseLock()
+	stop := r.keepLockAlive(ctx)
+	defer stop()
+	return fn()
+}
+
+// keepLockAlive 在后台按 heartbeat 间隔刷新锁的 locked_at，返回的 stop 会等待后台协程退出。
+func (r *Runner) keepLockAlive(ctx context.Context) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(r.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			result := r.db.WithContext(ctx).Model(&schemaMigrationLock{}).
+				Where("id = ? AND locked_by = ?", migrationLockID, r.owner).
+				Update("locked_at", time.Now())
+			switch {
+			case result.Error != nil:
+				logger.Warnf("refresh schema migration lock failed: %v", result.Error)
+			case result.RowsAffected == 0:
+				logger.Errorf("schema migration lock held by %s was taken over by another instance", r.owner)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// acquireLock 循环尝试插入锁记录；持有者超时未释放时视为陈旧锁并抢占。
+func (r *Runner) acquireLock(ctx context.Context) error {
+	deadline := time.Now().Add(r.lockTimeout)
+	for {
+		lock := schemaMigrationLock{ID: migrationLockID, LockedBy: r.owner, LockedAt: time.Now()}
+		insertErr := r.db.WithContext(ctx).Create(&lock).Error
+		if insertErr == nil {
+			return nil
+		}
+
+		var current schemaMigrationLock
+		if err := r.db.WithContext(ctx).Where("id = ?", migrationLockID).First(&current).Error; err == nil {
+			if time.Since(current.LockedAt) > r.staleAfter {
+				logger.Warnf("breaking stale schema migration lock held by %s since %s", current.LockedBy, current.LockedAt.Format(time.RFC3339))
+				r.db.WithContext(ctx).Where("id = ? AND locked_by = ?", migrationLockID, current.LockedBy).Delete(&schemaMigrationLock{})
+				continue
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: %v", ErrLockTimeout, insertErr)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+func (r *Runner) releaseLock() {
+	err := r.db.Where("id = ? AND locked_by = ?", migrationLockID, r.owner).Delete(&schemaMigrationLock{}).Error
+	if err != nil {
+		logger.Warnf("release schema migration lock failed: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:migrations_test_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite test db failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql db failed: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_a",
+			Up:      execSQL([]string{"CREATE TABLE a (id INTEGER PRIMARY KEY)"}),
+			Down:    execSQL([]string{"DROP TABLE a"}),
+		},
+		{
+			Version: 2,
+			Name:    "create_b",
+			Up:      execSQL([]string{"CREATE TABLE b (id INTEGER PRIMARY KEY)"}),
+			Down:    execSQL([]string{"DROP TABLE b"}),
+		},
+	}
+}
+
+func TestRunnerUpAppliesPendingInOrderAndIsIdempotent(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testMigrations())
+	ctx := context.Background()
+
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0].Version != 1 || applied[1].Version != 2 {
+		t.Fatalf("unexpected applied migrations: %+v", applied)
+	}
+	if !db.Migrator().HasTable("a") || !db.Migrator().HasTable("b") {
+		t.Fatalf("expected tables a and b to exist")
+	}
+
+	applied, err = runner.Up(ctx)
+	if err != nil {
+		t.Fatalf("second Up failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no pending migrations, got %d", len(applied))
+	}
+
+	var lockCount int64
+	db.Model(&schemaMigrationLock{}).Count(&lockCount)
+	if lockCount != 0 {
+		t.Fatalf("expected lock to be released, got %d rows", lockCount)
+	}
+}
+
+func TestRunnerDownRevertsLatestSteps(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testMigrations())
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	reverted, err := runner.Down(ctx, 1)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("expected migration 2 to be reverted, got %+v", reverted)
+	}
+	if db.Migrator().HasTable("b") || !db.Migrator().HasTable("a") {
+		t.Fatalf("expected only table b to be dropped")
+	}
+
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestRunnerDownRejectsIrreversibleMigration(t *testing.T) {
+	db := newTestDB(t)
+	migrations := testMigrations()
+	migrations[1].Down = nil
+	runner := NewRunner(db, migrations)
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if _, err := runner.Down(ctx, 1); err == nil {
+		t.Fatalf("expected irreversible migration error")
+	}
+}
+
+func TestRunnerUpFailureKeepsVersionUnrecorded(t *testing.T) {
+	db := newTestDB(t)
+	migrations := append(testMigrations(), Migration{
+		Version: 3,
+		Name:    "broken",
+		Up:      func(*gorm.DB) error { return errors.New("boom") },
+	})
+	runner := NewRunner(db, migrations)
+
+	if _, err := runner.Up(context.Background()); err == nil {
+		t.Fatalf("expected Up to fail")
+	}
+	var count int64
+	db.Model(&schemaMigration{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 recorded versions, got %d", count)
+	}
+}
+
+func TestRunnerWaitsForHeldLock(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testMigrations())
+	runner.lockTimeout = 50 * time.Millisecond
+	runner.pollInterval = 10 * time.Millisecond
+	ctx := context.Background()
+
+	if err := runner.ensureTables(ctx); err != nil {
+		t.Fatalf("ensureTables failed: %v", err)
+	}
+	other := schemaMigrationLock{ID: migrationLockID, LockedBy: "other-instance", LockedAt: time.Now()}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatalf("seed lock failed: %v", err)
+	}
+
+	if _, err := runner.Up(ctx); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("expected ErrLockTimeout, got %v", err)
+	}
+}
+
+func TestRunnerBreaksStaleLock(t *testing.T) {
+	db := newTestDB(t)
+	runner := NewRunner(db, testMigrations())
+	runner.staleAfter = time.Minute
+	ctx := context.Background()
+
+	if err := runner.ensureTables(ctx); err != nil {
+		t.Fatalf("ensureTables failed: %v", err)
+	}
+	stale := schemaMigrationLock{ID: migrationLockID, LockedBy: "crashed-instance", LockedAt: time.Now().Add(-time.Hour)}
+	if err := db.Create(&stale).Error; err != nil {
+		t.Fatalf("seed lock failed: %v", err)
+	}
+
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 applied migrations, got %d", len(applied))
+	}
+}
+
+func TestRunnerHeartbeatKeepsLockFromBeingBroken(t *testing.T) {
+	db := newTestDB(t)
+	holder := NewRunner(db, testMigrations())
+	holder.heartbeat = 10 * time.Millisecond
+	ctx := context.Background()
+
+	if err := holder.ensureTables(ctx); err != nil {
+		t.Fatalf("ensureTables failed: %v", err)
+	}
+	if err := holder.acquireLock(ctx); err != nil {
+		t.Fatalf("acquireLock failed: %v", err)
+	}
+	// 模拟持锁已久的长时间迁移：锁的初始时间早于陈旧阈值，依靠心跳刷新。
+	if err := db.Model(&schemaMigrationLock{}).Where("id = ?", migrationLockID).Update("locked_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("age lock failed: %v", err)
+	}
+	stop := holder.keepLockAlive(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	other := NewRunner(db, testMigrations())
+	other.staleAfter = time.Second
+	other.lockTimeout = 50 * time.Millisecond
+	other.pollInterval = 10 * time.Millisecond
+	if err := other.acquireLock(ctx); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("expected refreshed lock to be kept, got %v", err)
+	}
+
+	stop()
+	holder.releaseLock()
+	if err := other.acquireLock(ctx); err != nil {
+		t.Fatalf("expected lock to be free after release, got %v", err)
+	}
+}
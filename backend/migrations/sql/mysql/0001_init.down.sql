@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS thumbnail_tasks;
+DROP TABLE IF EXISTS recycle_bin;
+DROP TABLE IF EXISTS upload_tasks;
+DROP TABLE IF EXISTS file_objects;
+DROP TABLE IF EXISTS files;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS users;
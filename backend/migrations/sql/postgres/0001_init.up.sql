@@ -0,0 +1,130 @@
+-- 初始表结构（PostgreSQL），与 mysql/0001_init.up.sql 保持字段一致；索引名在库内全局唯一，故带表名前缀。
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(50) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    nickname VARCHAR(100),
+    avatar VARCHAR(255),
+    storage_quota BIGINT DEFAULT 10737418240,
+    storage_used BIGINT DEFAULT 0,
+    deleted_at TIMESTAMPTZ NULL DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_users_username UNIQUE (username)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS folders (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_id INT NULL,
+    user_id INT NOT NULL,
+    is_root BOOLEAN NULL DEFAULT NULL,
+    path VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ NULL DEFAULT NULL,
+    CONSTRAINT uk_user_root UNIQUE (user_id, is_root),
+    CONSTRAINT uk_sibling_name UNIQUE (user_id, parent_id, name, deleted_at)
+);
+CREATE INDEX IF NOT EXISTS idx_folders_user_id ON folders (user_id);
+CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON folders (parent_id);
+
+CREATE TABLE IF NOT EXISTS files (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    original_name VARCHAR(255) NOT NULL,
+    folder_id INT NOT NULL,
+    user_id INT NOT NULL,
+    file_object_id INT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ NULL DEFAULT NULL,
+    deleted_by INT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_files_user_id ON files (user_id);
+CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files (folder_id);
+CREATE INDEX IF NOT EXISTS idx_files_file_object_id ON files (file_object_id);
+CREATE INDEX IF NOT EXISTS idx_files_deleted_at ON files (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_files_created_at ON files (created_at);
+
+CREATE TABLE IF NOT EXISTS file_objects (
+    id SERIAL PRIMARY KEY,
+    file_path VARCHAR(1000) NOT NULL,
+    thumbnail_path VARCHAR(1000),
+    file_size BIGINT NOT NULL,
+    mime_type VARCHAR(100),
+    is_image BOOLEAN DEFAULT FALSE,
+    width INT,
+    height INT,
+    file_md5 VARCHAR(32) NOT NULL,
+    ref_count INT DEFAULT 1,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_file_objects_md5 ON file_objects (file_md5);
+CREATE INDEX IF NOT EXISTS idx_file_objects_created_at ON file_objects (created_at);
+
+CREATE TABLE IF NOT EXISTS upload_tasks (
+    id SERIAL PRIMARY KEY,
+    upload_id VARCHAR(36) NOT NULL,
+    user_id INT NOT NULL,
+    folder_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    file_size BIGINT NOT NULL,
+    file_md5 VARCHAR(32) NOT NULL,
+    total_chunks INT NOT NULL,
+    uploaded_chunks TEXT,
+    uploaded_chunks_count INT DEFAULT 0,
+    uploaded_size BIGINT DEFAULT 0,
+    last_chunk_at TIMESTAMPTZ NULL DEFAULT NULL,
+    completed_at TIMESTAMPTZ NULL DEFAULT NULL,
+    last_error VARCHAR(500) DEFAULT '',
+    status VARCHAR(20) DEFAULT 'pending'
+        CHECK (status IN ('pending', 'uploading', 'paused', 'completed', 'failed', 'canceled', 'expired')),
+    temp_dir VARCHAR(500),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uk_upload_tasks_upload_id UNIQUE (upload_id)
+);
+CREATE INDEX IF NOT EXISTS idx_upload_tasks_user_id ON upload_tasks (user_id);
+CREATE INDEX IF NOT EXISTS idx_upload_tasks_expires_at ON upload_tasks (expires_at);
+CREATE INDEX IF NOT EXISTS idx_upload_tasks_status ON upload_tasks (status);
+CREATE INDEX IF NOT EXISTS idx_upload_tasks_completed_at ON upload_tasks (completed_at);
+
+CREATE TABLE IF NOT EXISTS recycle_bin (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    original_id INT NOT NULL,
+    original_type VARCHAR(10) NOT NULL CHECK (original_type IN ('file', 'folder')),
+    original_name VARCHAR(255) NOT NULL,
+    original_path VARCHAR(1000),
+    original_full_path VARCHAR(1000),
+    original_folder_id INT,
+    file_object_id INT NULL,
+    file_size BIGINT,
+    deleted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    metadata JSON
+);
+CREATE INDEX IF NOT EXISTS idx_recycle_bin_user_id ON recycle_bin (user_id);
+CREATE INDEX IF NOT EXISTS idx_recycle_bin_deleted_at ON recycle_bin (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_recycle_bin_expires_at ON recycle_bin (expires_at);
+CREATE INDEX IF NOT EXISTS idx_recycle_bin_original_type ON recycle_bin (original_type);
+
+CREATE TABLE IF NOT EXISTS thumbnail_tasks (
+    id SERIAL PRIMARY KEY,
+    file_id INT NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending'
+        CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    retry_count INT DEFAULT 0,
+    max_retries INT DEFAULT 3,
+    error_message TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS idx_thumbnail_tasks_file_id ON thumbnail_tasks (file_id);
+CREATE INDEX IF NOT EXISTS idx_thumbnail_tasks_status ON thumbnail_tasks (status);
+CREATE INDEX IF NOT EXISTS idx_thumbnail_tasks_created_at ON thumbnail_tasks (created_at);
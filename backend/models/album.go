@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Album 为相册：只引用已有文件，不移动文件；ShareToken 非空表示已开启分享。
+type Album struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	Description string `gorm:"type:varchar(500)" json:"description"`
+	// CoverFileID 为手动指定的封面，为空或封面文件不可用时取排序最靠前的文件。
+	CoverFileID *uint      `json:"cover_file_id"`
+	ShareToken  *string    `gorm:"type:varchar(64);uniqueIndex" json:"share_token,omitempty"`
+	SharedAt    *time.Time `json:"shared_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AlbumItem 为相册中引用的一个文件，Position 越小越靠前；文件进入回收站时条目保留但不展示。
+type AlbumItem struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AlbumID   uint      `gorm:"not null;uniqueIndex:uk_album_items_album_file,priority:1" json:"album_id"`
+	FileID    uint      `gorm:"not null;index;uniqueIndex:uk_album_items_album_file,priority:2" json:"file_id"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
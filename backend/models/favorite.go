@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// 收藏目标类型。
+const (
+	FavoriteTargetFile   = "file"
+	FavoriteTargetFolder = "folder"
+)
+
+// Favorite 记录用户收藏（星标）的文件或目录，同一目标只保留一条。
+type Favorite struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:uk_favorites_target,priority:1" json:"user_id"`
+	TargetType string    `gorm:"type:varchar(10);not null;uniqueIndex:uk_favorites_target,priority:2" json:"target_type"`
+	TargetID   uint      `gorm:"not null;uniqueIndex:uk_favorites_target,priority:3" json:"target_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
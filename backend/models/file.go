@@ -18,4 +18,6 @@ type File struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 	DeletedBy    *uint          `json:"deleted_by,omitempty"`
+	// Tags 为列表接口附带的标签，不对应数据库列。
+	Tags []Tag `gorm:"-" json:"tags,omitempty"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// FileAccess 记录用户对文件的最近一次访问（下载或预览），每个用户每个文件一条。
+type FileAccess struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:uk_file_accesses_user_file,priority:1;index:idx_file_accesses_user_time,priority:1" json:"user_id"`
+	FileID         uint      `gorm:"not null;uniqueIndex:uk_file_accesses_user_file,priority:2" json:"file_id"`
+	File           File      `json:"file,omitempty"`
+	AccessCount    int64     `gorm:"not null;default:1" json:"access_count"`
+	LastAccessedAt time.Time `gorm:"not null;index:idx_file_accesses_user_time,priority:2" json:"last_accessed_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -3,15 +3,21 @@ package models
 import "time"
 
 type FileObject struct {
-	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	FilePath      string    `gorm:"type:varchar(1000);not null" json:"file_path"`
-	ThumbnailPath string    `gorm:"type:varchar(1000)" json:"thumbnail_path"`
-	FileSize      int64     `gorm:"not null" json:"file_size"`
-	MimeType      string    `gorm:"type:varchar(100)" json:"mime_type"`
-	IsImage       bool      `gorm:"default:false" json:"is_image"`
-	Width         int       `json:"width"`
-	Height        int       `json:"height"`
-	FileMD5       string    `gorm:"type:varchar(32);index" json:"file_md5"`
-	RefCount      int       `gorm:"default:1" json:"ref_count"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	FilePath      string `gorm:"type:varchar(1000);not null" json:"file_path"`
+	ThumbnailPath string `gorm:"type:varchar(1000)" json:"thumbnail_path"`
+	FileSize      int64  `gorm:"not null" json:"file_size"`
+	MimeType      string `gorm:"type:varchar(100)" json:"mime_type"`
+	IsImage       bool   `gorm:"default:false" json:"is_image"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	FileMD5       string `gorm:"type:varchar(32);index" json:"file_md5"`
+	RefCount      int    `gorm:"default:1" json:"ref_count"`
+	// PerceptualHash 为图片的 64 位 dHash（16 位十六进制）；NULL 表示尚未计算，空串表示无法解码。
+	PerceptualHash *string   `gorm:"type:varchar(16);index" json:"perceptual_hash,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Metadata 为图片的 EXIF 元数据，仅在需要时预加载。
+	Metadata *ImageMetadata `gorm:"foreignKey:FileObjectID" json:"metadata,omitempty"`
+	// Media 为音视频的时长、编码与标签信息，仅在需要时预加载。
+	Media *MediaMetadata `gorm:"foreignKey:FileObjectID" json:"media,omitempty"`
 }
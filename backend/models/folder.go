@@ -16,4 +16,6 @@ type Folder struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// Tags 为列表接口附带的标签，不对应数据库列。
+	Tags []Tag `gorm:"-" json:"tags,omitempty"`
 }
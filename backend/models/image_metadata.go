@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ImageMetadata 为图片文件对象解析出的 EXIF 元数据，每个文件对象至多一条。
+// 没有 EXIF 的图片同样写入一条空记录，标记已解析过，避免重复读取原图。
+type ImageMetadata struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	FileObjectID uint       `gorm:"not null;uniqueIndex" json:"-"`
+	TakenAt      *time.Time `gorm:"index" json:"taken_at"`
+	CameraMake   string     `gorm:"type:varchar(100)" json:"camera_make,omitempty"`
+	CameraModel  string     `gorm:"type:varchar(100)" json:"camera_model,omitempty"`
+	LensModel    string     `gorm:"type:varchar(100)" json:"lens_model,omitempty"`
+	// Orientation 为 EXIF 方向值 1-8，0 表示未记录。
+	Orientation int       `gorm:"not null;default:0" json:"orientation"`
+	Latitude    *float64  `json:"latitude"`
+	Longitude   *float64  `json:"longitude"`
+	CreatedAt   time.Time `json:"-"`
+}
+
+func (ImageMetadata) TableName() string {
+	return "image_metadata"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MediaMetadata 为音视频文件对象解析出的容器与标签信息，每个文件对象至多一条。
+// 无法识别的文件同样写入一条空记录（Kind 为空），标记已解析过，避免重复读取。
+type MediaMetadata struct {
+	ID           uint `gorm:"primaryKey;autoIncrement" json:"-"`
+	FileObjectID uint `gorm:"not null;uniqueIndex" json:"-"`
+	// Kind 为 video 或 audio；Format 为容器格式，如 mp4、mov、m4a、mp3、flac。
+	Kind       string `gorm:"type:varchar(10)" json:"kind"`
+	Format     string `gorm:"type:varchar(10)" json:"format,omitempty"`
+	DurationMs int64  `gorm:"not null;default:0" json:"duration_ms"`
+	Width      int    `gorm:"not null;default:0" json:"width,omitempty"`
+	Height     int    `gorm:"not null;default:0" json:"height,omitempty"`
+	VideoCodec string `gorm:"type:varchar(32)" json:"video_codec,omitempty"`
+	AudioCodec string `gorm:"type:varchar(32)" json:"audio_codec,omitempty"`
+	SampleRate int    `gorm:"not null;default:0" json:"sample_rate,omitempty"`
+	Channels   int    `gorm:"not null;default:0" json:"channels,omitempty"`
+	Title      string `gorm:"type:varchar(255)" json:"title,omitempty"`
+	Artist     string `gorm:"type:varchar(255)" json:"artist,omitempty"`
+	Album      string `gorm:"type:varchar(255)" json:"album,omitempty"`
+	// HasCover 表示文件内嵌了封面图，封面会用作该文件的缩略图。
+	HasCover  bool      `gorm:"not null;default:false" json:"has_cover"`
+	CreatedAt time.Time `json:"-"`
+}
+
+func (MediaMetadata) TableName() string {
+	return "media_metadata"
+}
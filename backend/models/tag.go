@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 标签绑定目标类型。
+const (
+	TagTargetFile   = "file"
+	TagTargetFolder = "folder"
+)
+
+// Tag 为用户自定义的彩色标签，同一用户下名称唯一。
+type Tag struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:uk_tags_user_name,priority:1" json:"user_id"`
+	Name      string    `gorm:"type:varchar(64);not null;uniqueIndex:uk_tags_user_name,priority:2" json:"name"`
+	Color     string    `gorm:"type:varchar(7);not null" json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TagBinding 将标签挂到文件或目录上；按目标 ID 关联，移动、重命名、进入回收站与恢复均不影响绑定。
+type TagBinding struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	TagID      uint      `gorm:"not null;index;uniqueIndex:uk_tag_bindings_target,priority:3" json:"tag_id"`
+	TargetType string    `gorm:"type:varchar(10);not null;uniqueIndex:uk_tag_bindings_target,priority:1" json:"target_type"`
+	TargetID   uint      `gorm:"not null;uniqueIndex:uk_tag_bindings_target,priority:2" json:"target_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
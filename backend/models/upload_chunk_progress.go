@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UploadChunkProgress 记录已上传分片，供不依赖 Redis 的数据库进度存储使用。
+type UploadChunkProgress struct {
+	UploadID   string     `gorm:"type:varchar(36);primaryKey" json:"upload_id"`
+	ChunkIndex int        `gorm:"primaryKey;autoIncrement:false" json:"chunk_index"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`
+}
+
+func (UploadChunkProgress) TableName() string {
+	return "upload_chunk_progress"
+}
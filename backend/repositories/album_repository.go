@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activeAlbumItemsFrom 为相册中正常状态文件的子查询片段，需要 album_items.album_id 已与外层关联。
+const activeAlbumItemsFrom = "FROM album_items JOIN files ON files.id = album_items.file_id AND files.deleted_at IS NULL" +
+	" WHERE album_items.album_id = albums.id"
+
+type GormAlbumRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAlbumRepository(db *gorm.DB) *GormAlbumRepository {
+	return &GormAlbumRepository{db: db}
+}
+
+func (r *GormAlbumRepository) Create(ctx context.Context, tx *gorm.DB, album *models.Album) error {
+	return useTx(ctx, r.db, tx).Create(album).Error
+}
+
+func (r *GormAlbumRepository) GetByIDAndUser(ctx context.Context, tx *gorm.DB, albumID uint, userID uint) (models.Album, error) {
+	var album models.Album
+	err := useTx(ctx, r.db, tx).Where("id = ? AND user_id = ?", albumID, userID).First(&album).Error
+	return album, err
+}
+
+func (r *GormAlbumRepository) GetByShareToken(ctx context.Context, tx *gorm.DB, token string) (models.Album, error) {
+	var album models.Album
+	err := useTx(ctx, r.db, tx).Where("share_token = ?", token).First(&album).Error
+	return album, err
+}
+
+// ListSummariesByUser 按最近更新列出用户的相册，附带正常状态条目数与排序最靠前的正常状态文件。
+func (r *GormAlbumRepository) ListSummariesByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]AlbumSummary, error) {
+	var summaries []AlbumSummary
+	err := useTx(ctx, r.db, tx).Model(&models.Album{}).
+		Select("albums.*, (SELECT COUNT(*) "+activeAlbumItemsFrom+") AS item_count, "+
+			"(SELECT album_items.file_id "+activeAlbumItemsFrom+" ORDER BY album_items.position ASC, album_items.id ASC LIMIT 1) AS first_file_id").
+		Where("albums.user_id = ?", userID).
+		Order("albums.updated_at DESC, albums.id DESC").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+func (r *GormAlbumRepository) UpdateByID(ctx context.Context, tx *gorm.DB, albumID uint, updates map[string]interface{}) error {
+	return useTx(ctx, r.db, tx).Model(&models.Album{}).Where("id = ?", albumID).Updates(updates).Error
+}
+
+// DeleteByID 删除相册及其全部条目，被引用的文件不受影响。
+func (r *GormAlbumRepository) DeleteByID(ctx context.Context, tx *gorm.DB, albumID uint) error {
+	db := useTx(ctx, r.db, tx)
+	if err := db.Where("album_id = ?", albumID).Delete(&models.AlbumItem{}).Error; err != nil {
+		return err
+	}
+	return db.Delete(&models.Album{}, albumID).Error
+}
+
+// AddItems 按给定顺序把文件追加到相册末尾；已在相册中的文件保持原位置不变。
+func (r *GormAlbumRepository) AddItems(ctx context.Context, tx *gorm.DB, albumID uint, fileIDs []uint) (int64, error) {
+	if len(fileIDs) == 0 {
+		return 0, nil
+	}
+	db := useTx(ctx, r.db, tx)
+	var last int
+	if err := db.Model(&models.AlbumItem{}).Where("album_id = ?", albumID).
+		Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+		return 0, err
+	}
+
+	var existing []uint
+	if err := db.Model(&models.AlbumItem{}).Where("album_id = ? AND file_id IN ?", albumID, fileIDs).
+		Pluck("file_id", &existing).Error; err != nil {
+		return 0, err
+	}
+	skip := make(map[uint]bool, len(existing))
+	for _, fileID := range existing {
+		skip[fileID] = true
+	}
+
+	items := make([]models.AlbumItem, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		if skip[fileID] {
+			continue
+		}
+		last++
+		items = append(items, models.AlbumItem{AlbumID: albumID, FileID: fileID, Position: last})
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+	// 并发添加同一文件时由唯一索引兜底。
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&items, 500)
+	return result.RowsAffected, result.Error
+}
+
+func (r *GormAlbumRepository) RemoveItems(ctx context.Context, tx *gorm.DB, albumID uint, fileIDs []uint) (int64, error) {
+	if len(fileIDs) == 0 {
+		return 0, nil
+	}
+	result := useTx(ctx, r.db, tx).Where("album_id = ? AND file_id IN ?", albumID, fileIDs).Delete(&models.AlbumItem{})
+	return result.RowsAffected, result.Error
+}
+
+// ListItems 按位置列出相册全部条目，包括文件在回收站中的条目。
+func (r *GormAlbumRepository) ListItems(ctx context.Context, tx *gorm.DB, albumID uint) ([]models.AlbumItem, error) {
+	var items []models.AlbumItem
+	err := useTx(ctx, r.db, tx).Where("album_id = ?", albumID).Order("position ASC, id ASC").Find(&items).Error
+	return items, err
+}
+
+// UpdateItemPositions 按文件 ID 更新条目位置。
+func (r *GormAlbumRepository) UpdateItemPositions(ctx context.Context, tx *gorm.DB, albumID uint, positions map[uint]int) error {
+	db := useTx(ctx, r.db, tx)
+	for fileID, position := range positions {
+		if err := db.Model(&models.AlbumItem{}).
+			Where("album_id = ? AND file_id = ?", albumID, fileID).
+			Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GormAlbumRepository) activeFilesQuery(db *gorm.DB, albumID uint) *gorm.DB {
+	return db.Model(&models.File{}).
+		Joins("JOIN album_items ON album_items.file_id = files.id").
+		Where("album_items.album_id = ?", albumID)
+}
+
+func (r *GormAlbumRepository) CountActiveItems(ctx context.Context, tx *gorm.DB, albumID uint) (int64, error) {
+	var count int64
+	err := r.activeFilesQuery(useTx(ctx, r.db, tx), albumID).Count(&count).Error
+	return count, err
+}
+
+// ListActiveFiles 按相册位置分页列出正常状态的文件，并预加载文件对象。
+func (r *GormAlbumRepository) ListActiveFiles(ctx context.Context, tx *gorm.DB, in AlbumItemsInput) ([]models.File, error) {
+	var files []models.File
+	err := r.activeFilesQuery(useTx(ctx, r.db, tx).Preload("FileObject"), in.AlbumID).
+		Select("files.*").
+		Order("album_items.position ASC, album_items.id ASC").
+		Offset(in.Offset).
+		Limit(in.Limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *GormAlbumRepository) HasActiveFile(ctx context.Context, tx *gorm.DB, albumID uint, fileID uint) (bool, error) {
+	var count int64
+	err := r.activeFilesQuery(useTx(ctx, r.db, tx), albumID).Where("files.id = ?", fileID).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteOrphanItems 删除文件已被彻底删除的条目并清除指向这些文件的封面；回收站中的文件仍有记录，条目保留。
+func (r *GormAlbumRepository) DeleteOrphanItems(ctx context.Context, tx *gorm.DB) (int64, error) {
+	db := useTx(ctx, r.db, tx)
+	if err := db.Model(&models.Album{}).
+		Where("cover_file_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM files WHERE files.id = albums.cover_file_id)").
+		Update("cover_file_id", nil).Error; err != nil {
+		return 0, err
+	}
+	result := db.Where("NOT EXISTS (SELECT 1 FROM files WHERE files.id = album_items.file_id)").
+		Delete(&models.AlbumItem{})
+	return result.RowsAffected, result.Error
+}
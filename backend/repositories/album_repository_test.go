@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormAlbumRepository_ListActiveFiles_HidesRecycledFiles(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormAlbumRepository(db)
+
+		if _, err := repo.ListActiveFiles(context.Background(), nil, AlbumItemsInput{AlbumID: 4, Offset: 50, Limit: 50}); err != nil {
+			t.Fatalf("ListActiveFiles failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"join album_items on album_items.file_id = files.id",
+			"album_items.album_id = 4",
+			"files.deleted_at is null",
+			"order by album_items.position asc, album_items.id asc",
+		)
+	})
+}
+
+func TestGormAlbumRepository_AddItems_EmptyInputSkipsSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormAlbumRepository(db)
+
+		added, err := repo.AddItems(context.Background(), nil, 4, nil)
+		if err != nil || added != 0 {
+			t.Fatalf("expected no-op add, got %d (%v)", added, err)
+		}
+		assertNoSQLCaptured(t, rec)
+	})
+}
+
+func TestGormAlbumRepository_LiveLifecycle(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormAlbumRepository(db)
+		userID := liveUserID(t, db)
+		t.Cleanup(func() {
+			db.Where("album_id IN (?)", db.Model(&models.Album{}).Select("id").Where("user_id = ?", userID)).Delete(&models.AlbumItem{})
+			db.Where("user_id = ?", userID).Delete(&models.Album{})
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+		})
+
+		folder := models.Folder{Name: "Albums", UserID: userID, Path: "/Albums"}
+		if err := db.Create(&folder).Error; err != nil {
+			t.Fatalf("create folder failed: %v", err)
+		}
+		var fileList []models.File
+		for i := 0; i < 3; i++ {
+			file := models.File{Name: fmt.Sprintf("a%d", i), OriginalName: fmt.Sprintf("p%d.jpg", i), FolderID: folder.ID, UserID: userID, FileObjectID: 1}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			fileList = append(fileList, file)
+		}
+
+		album := models.Album{UserID: userID, Name: "Trip"}
+		if err := repo.Create(ctx, nil, &album); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		// 重复添加的文件保持原位置，新文件追加到末尾。
+		ids := []uint{fileList[0].ID, fileList[1].ID}
+		if added, err := repo.AddItems(ctx, nil, album.ID, ids); err != nil || added != 2 {
+			t.Fatalf("AddItems failed: %d (%v)", added, err)
+		}
+		if added, err := repo.AddItems(ctx, nil, album.ID, []uint{fileList[0].ID, fileList[2].ID}); err != nil || added != 1 {
+			t.Fatalf("repeated AddItems failed: %d (%v)", added, err)
+		}
+		items, err := repo.ListItems(ctx, nil, album.ID)
+		if err != nil || len(items) != 3 || items[2].FileID != fileList[2].ID || items[2].Position != 3 {
+			t.Fatalf("unexpected items: %+v (%v)", items, err)
+		}
+
+		if err := repo.UpdateItemPositions(ctx, nil, album.ID, map[uint]int{fileList[2].ID: 0}); err != nil {
+			t.Fatalf("UpdateItemPositions failed: %v", err)
+		}
+		coverID := fileList[1].ID
+		if err := repo.UpdateByID(ctx, nil, album.ID, map[string]interface{}{"cover_file_id": coverID}); err != nil {
+			t.Fatalf("UpdateByID failed: %v", err)
+		}
+
+		// 进入回收站的文件不计数、不展示，条目保留以便恢复后重新出现。
+		if err := db.Delete(&fileList[2]).Error; err != nil {
+			t.Fatalf("soft delete failed: %v", err)
+		}
+		summaries, err := repo.ListSummariesByUser(ctx, nil, userID)
+		if err != nil || len(summaries) != 1 || summaries[0].ItemCount != 2 || summaries[0].FirstFileID == nil || *summaries[0].FirstFileID != fileList[0].ID {
+			t.Fatalf("unexpected summaries: %+v (%v)", summaries, err)
+		}
+		if ok, err := repo.HasActiveFile(ctx, nil, album.ID, fileList[2].ID); err != nil || ok {
+			t.Fatalf("expected recycled file to be hidden, got %v (%v)", ok, err)
+		}
+		if err := db.Unscoped().Model(&fileList[2]).Update("deleted_at", nil).Error; err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		files, err := repo.ListActiveFiles(ctx, nil, AlbumItemsInput{AlbumID: album.ID, Limit: 10})
+		if err != nil || len(files) != 3 || files[0].ID != fileList[2].ID {
+			t.Fatalf("expected restored file first, got %+v (%v)", files, err)
+		}
+
+		// 彻底删除后清理条目与指向它的封面。
+		if err := db.Unscoped().Delete(&fileList[1]).Error; err != nil {
+			t.Fatalf("hard delete failed: %v", err)
+		}
+		if purged, err := repo.DeleteOrphanItems(ctx, nil); err != nil || purged < 1 {
+			t.Fatalf("DeleteOrphanItems failed: %d (%v)", purged, err)
+		}
+		got, err := repo.GetByIDAndUser(ctx, nil, album.ID, userID)
+		if err != nil || got.CoverFileID != nil {
+			t.Fatalf("expected stale cover to be cleared, got %+v (%v)", got, err)
+		}
+		if count, err := repo.CountActiveItems(ctx, nil, album.ID); err != nil || count != 2 {
+			t.Fatalf("unexpected count after purge: %d (%v)", count, err)
+		}
+
+		if err := repo.DeleteByID(ctx, nil, album.ID); err != nil {
+			t.Fatalf("DeleteByID failed: %v", err)
+		}
+		var left int64
+		db.Model(&models.AlbumItem{}).Where("album_id = ?", album.ID).Count(&left)
+		if left != 0 {
+			t.Fatalf("expected items to be removed with album, got %d", left)
+		}
+	})
+}
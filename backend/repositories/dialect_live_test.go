@@ -0,0 +1,400 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/database"
+	"mcloud/migrations"
+	"mcloud/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 真实执行的方言测试：SQLite 总是运行；MySQL / PostgreSQL 需通过环境变量提供 DSN，
+// 例如 MCLOUD_TEST_MYSQL_DSN="root:root@tcp(127.0.0.1:3306)/mcloud_test?parseTime=True&loc=Local"。
+var liveDSNEnv = map[string]string{
+	dialectMySQL:    "MCLOUD_TEST_MYSQL_DSN",
+	dialectPostgres: "MCLOUD_TEST_POSTGRES_DSN",
+}
+
+func forEachLiveDialect(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+	for _, dialect := range testDialects {
+		t.Run(dialect, func(t *testing.T) {
+			fn(t, newLiveDB(t, dialect))
+		})
+	}
+}
+
+func newLiveDB(t *testing.T, dialect string) *gorm.DB {
+	t.Helper()
+
+	var dialector gorm.Dialector
+	switch dialect {
+	case dialectSQLite:
+		dialector = sqlite.Open(database.SQLiteDSN(filepath.Join(t.TempDir(), "mcloud.db")))
+	case dialectMySQL, dialectPostgres:
+		dsn := os.Getenv(liveDSNEnv[dialect])
+		if dsn == "" {
+			t.Skipf("%s not set", liveDSNEnv[dialect])
+		}
+		if dialect == dialectMySQL {
+			dialector = mysql.Open(dsn)
+		} else {
+			dialector = postgres.Open(dsn)
+		}
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open %s db failed: %v", dialect, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql db failed: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("load migrations failed: %v", err)
+	}
+	if _, err := migrations.NewRunner(db, all).Up(context.Background()); err != nil {
+		t.Fatalf("migrate %s db failed: %v", dialect, err)
+	}
+	return db
+}
+
+// liveUserID 为共享库上的每个用例生成独立的用户 ID，并在结束时清理其数据。
+func liveUserID(t *testing.T, db *gorm.DB) uint {
+	t.Helper()
+	userID := uint(time.Now().UnixNano()%1_000_000_000) + 1
+	t.Cleanup(func() {
+		db.Unscoped().Where("user_id = ?", userID).Delete(&models.Folder{})
+		db.Unscoped().Where("user_id = ?", userID).Delete(&models.RecycleBinItem{})
+		db.Unscoped().Where("id = ?", userID).Delete(&models.User{})
+	})
+	return userID
+}
+
+func TestLive_FolderSubtreeTreatsWildcardsLiterally(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormFolderRepository(db)
+		userID := liveUserID(t, db)
+
+		isRoot := true
+		root := models.Folder{Name: "root", UserID: userID, IsRoot: &isRoot, Path: "/"}
+		if err := repo.Create(ctx, nil, &root); err != nil {
+			t.Fatalf("create root failed: %v", err)
+		}
+		create := func(parentID uint, name, path string) models.Folder {
+			folder := models.Folder{Name: name, UserID: userID, ParentID: &parentID, Path: path}
+			if err := repo.Create(ctx, nil, &folder); err != nil {
+				t.Fatalf("create %s failed: %v", path, err)
+			}
+			return folder
+		}
+		target := create(root.ID, "a_b", "/a_b")
+		create(target.ID, "child", "/a_b/child")
+		lookalike := create(root.ID, "aXb", "/aXb")
+		create(lookalike.ID, "child", "/aXb/child")
+		// uk_sibling_name 含可空的 deleted_at，MySQL 上活动的同名目录不受其约束，大小写不同的兄弟目录可以并存；
+		// utf8mb4 默认排序规则不区分大小写，子树条件需按二进制排序规则比较才不会带上 /A_B。
+		upper := create(root.ID, "A_B", "/A_B")
+		create(upper.ID, "child", "/A_B/child")
+
+		ids, err := repo.PluckIDsByPathPrefix(ctx, nil, userID, target.ID, target.Path)
+		if err != nil {
+			t.Fatalf("PluckIDsByPathPrefix failed: %v", err)
+		}
+		if len(ids) != 2 {
+			t.Fatalf("expected target and its child only, got %v", ids)
+		}
+
+		got, err := repo.GetRootByUser(ctx, nil, userID)
+		if err != nil || got.ID != root.ID {
+			t.Fatalf("GetRootByUser returned %+v, %v", got, err)
+		}
+	})
+}
+
+func TestLive_SubStorageUsedClampsAtZero(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormUserRepository(db)
+		userID := liveUserID(t, db)
+
+		user := models.User{ID: userID, Username: "live_" + time.Now().Format("150405.000000000"), Password: "x", StorageUsed: 100}
+		if err := repo.Create(ctx, nil, &user); err != nil {
+			t.Fatalf("create user failed: %v", err)
+		}
+		if err := repo.SubStorageUsed(ctx, nil, userID, 40); err != nil {
+			t.Fatalf("SubStorageUsed failed: %v", err)
+		}
+		if got, _ := repo.GetByID(ctx, nil, userID); got.StorageUsed != 60 {
+			t.Fatalf("expected 60 bytes used, got %d", got.StorageUsed)
+		}
+		if err := repo.SubStorageUsed(ctx, nil, userID, 1000); err != nil {
+			t.Fatalf("SubStorageUsed failed: %v", err)
+		}
+		if got, _ := repo.GetByID(ctx, nil, userID); got.StorageUsed != 0 {
+			t.Fatalf("expected usage clamped to 0, got %d", got.StorageUsed)
+		}
+	})
+}
+
+func TestLive_UserRecycleRetentionRoundTrip(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormUserRepository(db)
+		userID := liveUserID(t, db)
+
+		user := models.User{ID: userID, Username: "live_" + time.Now().Format("150405.000000000"), Password: "x"}
+		if err := repo.Create(ctx, nil, &user); err != nil {
+			t.Fatalf("create user failed: %v", err)
+		}
+		days := 7
+		if err := repo.UpdateRecycleRetentionDays(ctx, nil, userID, &days); err != nil {
+			t.Fatalf("UpdateRecycleRetentionDays failed: %v", err)
+		}
+		if got, _ := repo.GetByID(ctx, nil, userID); got.RecycleRetentionDays == nil || *got.RecycleRetentionDays != 7 {
+			t.Fatalf("expected retention 7, got %v", got.RecycleRetentionDays)
+		}
+		if err := repo.UpdateRecycleRetentionDays(ctx, nil, userID, nil); err != nil {
+			t.Fatalf("UpdateRecycleRetentionDays reset failed: %v", err)
+		}
+		if got, _ := repo.GetByID(ctx, nil, userID); got.RecycleRetentionDays != nil {
+			t.Fatalf("expected retention reset, got %v", *got.RecycleRetentionDays)
+		}
+	})
+}
+
+func TestLive_RecycleBinMetadataRoundTrip(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormRecycleBinRepository(db)
+		userID := liveUserID(t, db)
+
+		item := models.RecycleBinItem{
+			UserID:       userID,
+			OriginalID:   1,
+			OriginalType: "folder",
+			OriginalName: "docs",
+			ExpiresAt:    time.Now().Add(time.Hour),
+			Metadata:     `{"parent_id":3}`,
+		}
+		if err := repo.Create(ctx, nil, &item); err != nil {
+			t.Fatalf("create recycle item failed: %v", err)
+		}
+		got, err := repo.GetByIDAndUser(ctx, nil, item.ID, userID)
+		if err != nil {
+			t.Fatalf("GetByIDAndUser failed: %v", err)
+		}
+		// MySQL 会规范化 JSON 文本，因此按解析后的值比较。
+		var meta struct {
+			ParentID uint `json:"parent_id"`
+		}
+		if err := json.Unmarshal([]byte(got.Metadata), &meta); err != nil || meta.ParentID != 3 {
+			t.Fatalf("unexpected metadata %q: %v", got.Metadata, err)
+		}
+	})
+}
+
+func TestLive_RecycleBinFilterByPathPrefix(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormRecycleBinRepository(db)
+		userID := liveUserID(t, db)
+
+		paths := []string{"/docs", "/docs/a.txt", "/docs/sub/b.txt", "/docs2/c.txt", "/do_s/d.txt", "/Docs/e.txt"}
+		for i, p := range paths {
+			item := models.RecycleBinItem{
+				UserID:           userID,
+				OriginalID:       uint(i + 1),
+				OriginalType:     "file",
+				OriginalName:     p,
+				OriginalFullPath: p,
+				ExpiresAt:        time.Now().Add(time.Hour),
+				Metadata:         "{}",
+			}
+			if err := repo.Create(ctx, nil, &item); err != nil {
+				t.Fatalf("create recycle item failed: %v", err)
+			}
+		}
+
+		items, err := repo.ListByFilter(ctx, nil, RecycleBinFilter{UserID: userID, PathPrefix: "/docs"})
+		if err != nil {
+			t.Fatalf("ListByFilter failed: %v", err)
+		}
+		got := make([]string, 0, len(items))
+		for _, item := range items {
+			got = append(got, item.OriginalFullPath)
+		}
+		want := []string{"/docs", "/docs/a.txt", "/docs/sub/b.txt"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestLive_OrphanSweepsAfterHardDelete(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		userID := liveUserID(t, db)
+		t.Cleanup(func() {
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+			db.Where("user_id = ?", userID).Delete(&models.Favorite{})
+			db.Where("user_id = ?", userID).Delete(&models.FileAccess{})
+			db.Where("user_id = ?", userID).Delete(&models.TagBinding{})
+			db.Where("user_id = ?", userID).Delete(&models.Album{})
+		})
+
+		mustCreate := func(value interface{}) {
+			if err := db.Create(value).Error; err != nil {
+				t.Fatalf("create %T failed: %v", value, err)
+			}
+		}
+		folder := models.Folder{Name: "docs", UserID: userID, Path: "/docs"}
+		mustCreate(&folder)
+		file := models.File{Name: "a.jpg", OriginalName: "a.jpg", FolderID: folder.ID, UserID: userID, FileObjectID: 1}
+		kept := models.File{Name: "b.jpg", OriginalName: "b.jpg", FolderID: folder.ID, UserID: userID, FileObjectID: 1}
+		mustCreate(&file)
+		mustCreate(&kept)
+		album := models.Album{UserID: userID, Name: "live", CoverFileID: &file.ID}
+		mustCreate(&album)
+		for _, id := range []uint{file.ID, kept.ID} {
+			mustCreate(&models.Favorite{UserID: userID, TargetType: models.FavoriteTargetFile, TargetID: id})
+			mustCreate(&models.FileAccess{UserID: userID, FileID: id, LastAccessedAt: time.Now()})
+			mustCreate(&models.TagBinding{UserID: userID, TagID: 1, TargetType: models.TagTargetFile, TargetID: id})
+			mustCreate(&models.AlbumItem{AlbumID: album.ID, FileID: id})
+		}
+		mustCreate(&models.Favorite{UserID: userID, TargetType: models.FavoriteTargetFolder, TargetID: folder.ID})
+		mustCreate(&models.TagBinding{UserID: userID, TagID: 1, TargetType: models.TagTargetFolder, TargetID: folder.ID})
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := NewGormFileRepository(db).UnscopedDeleteByIDAndUser(ctx, tx, file.ID, userID); err != nil {
+				return err
+			}
+			return NewGormFolderRepository(db).UnscopedDeleteByIDs(ctx, tx, []uint{folder.ID})
+		})
+		if err != nil {
+			t.Fatalf("hard delete failed: %v", err)
+		}
+		// 彻删只删除文件与目录本身，收藏、访问记录、标签绑定与相册条目由定时清理任务按孤儿回收。
+		sweeps := []func(context.Context, *gorm.DB) (int64, error){
+			NewGormFavoriteRepository(db).DeleteOrphans,
+			NewGormFileAccessRepository(db).DeleteOrphans,
+			NewGormTagRepository(db).DeleteOrphanBindings,
+			NewGormAlbumRepository(db).DeleteOrphanItems,
+		}
+		for _, sweep := range sweeps {
+			if _, err := sweep(ctx, nil); err != nil {
+				t.Fatalf("orphan sweep failed: %v", err)
+			}
+		}
+
+		count := func(model interface{}, query string, args ...interface{}) int64 {
+			var n int64
+			if err := db.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+				t.Fatalf("count %T failed: %v", model, err)
+			}
+			return n
+		}
+		for _, tc := range []struct {
+			model interface{}
+			query string
+			args  []interface{}
+		}{
+			{&models.Favorite{}, "user_id = ?", []interface{}{userID}},
+			{&models.FileAccess{}, "user_id = ?", []interface{}{userID}},
+			{&models.TagBinding{}, "user_id = ?", []interface{}{userID}},
+			{&models.AlbumItem{}, "album_id = ?", []interface{}{album.ID}},
+		} {
+			// 只剩指向未删除文件的一条。
+			if n := count(tc.model, tc.query, tc.args...); n != 1 {
+				t.Fatalf("expected only the kept file's %T row, got %d", tc.model, n)
+			}
+		}
+		if n := count(&models.Album{}, "id = ? AND cover_file_id IS NULL", album.ID); n != 1 {
+			t.Fatalf("expected album cover cleared")
+		}
+	})
+}
+
+func TestLive_RecycleBinUpdateExpiresAtByUser(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormRecycleBinRepository(db)
+		userID := liveUserID(t, db)
+		otherID := liveUserID(t, db) + 1
+		t.Cleanup(func() { db.Where("user_id = ?", otherID).Delete(&models.RecycleBinItem{}) })
+
+		// 带时区偏移与小数秒的删除时间，校验 SQLite 文本时间的偏移与精度得以保留。
+		deletedAts := []time.Time{
+			time.Date(2026, 1, 30, 10, 20, 30, 0, time.Local),
+			time.Date(2026, 2, 27, 23, 59, 59, 0, time.FixedZone("UTC+8", 8*3600)),
+		}
+		if db.Dialector.Name() == dialectSQLite {
+			deletedAts = append(deletedAts, time.Date(2026, 12, 31, 0, 0, 0, 125000000, time.UTC))
+		}
+		create := func(owner uint, deletedAt time.Time) models.RecycleBinItem {
+			item := models.RecycleBinItem{
+				UserID:       owner,
+				OriginalID:   1,
+				OriginalType: "file",
+				OriginalName: "a.txt",
+				DeletedAt:    deletedAt,
+				ExpiresAt:    deletedAt.AddDate(0, 0, 30),
+				Metadata:     "{}",
+			}
+			if err := repo.Create(ctx, nil, &item); err != nil {
+				t.Fatalf("create recycle item failed: %v", err)
+			}
+			return item
+		}
+		items := make([]models.RecycleBinItem, 0, len(deletedAts))
+		for _, deletedAt := range deletedAts {
+			items = append(items, create(userID, deletedAt))
+		}
+		other := create(otherID, deletedAts[0])
+
+		if err := repo.UpdateExpiresAtByUser(ctx, nil, userID, 7); err != nil {
+			t.Fatalf("UpdateExpiresAtByUser failed: %v", err)
+		}
+		for i, item := range items {
+			got, err := repo.GetByIDAndUser(ctx, nil, item.ID, userID)
+			if err != nil {
+				t.Fatalf("GetByIDAndUser failed: %v", err)
+			}
+			if want := deletedAts[i].AddDate(0, 0, 7); !got.ExpiresAt.Equal(want) {
+				t.Fatalf("expected expiry %v, got %v", want, got.ExpiresAt)
+			}
+		}
+		// 按文本比较的 SQLite 上，重算后的值也要能被过期查询正确命中。
+		expired, err := repo.ListExpired(ctx, nil, deletedAts[0].AddDate(0, 0, 7).Add(time.Second))
+		if err != nil {
+			t.Fatalf("ListExpired failed: %v", err)
+		}
+		found := false
+		for _, item := range expired {
+			found = found || item.ID == items[0].ID
+		}
+		if !found {
+			t.Fatalf("expected recomputed item to be listed as expired")
+		}
+		if got, _ := repo.GetByIDAndUser(ctx, nil, other.ID, otherID); !got.ExpiresAt.Equal(other.ExpiresAt) {
+			t.Fatalf("expected other user's expiry untouched, got %v", got.ExpiresAt)
+		}
+	})
+}
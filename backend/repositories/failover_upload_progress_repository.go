@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"mcloud/logger"
+)
+
+// FailoverUploadProgressRepository 优先使用主存储（通常为 Redis），
+// 主存储出错后在 retryInterval 内改用备用存储，到期后再尝试恢复主存储。
+// 降级期间写入备用存储的进度不会回填主存储；分片上传流程会同时扫描磁盘分片，缺失的进度不影响正确性。
+type FailoverUploadProgressRepository struct {
+	primary       UploadProgressRepository
+	fallback      UploadProgressRepository
+	retryInterval time.Duration
+
+	mu        sync.Mutex
+	downUntil time.Time
+	now       func() time.Time
+}
+
+func NewFailoverUploadProgressRepository(primary UploadProgressRepository, fallback UploadProgressRepository, retryInterval time.Duration) *FailoverUploadProgressRepository {
+	if retryInterval <= 0 {
+		retryInterval = 30 * time.Second
+	}
+	return &FailoverUploadProgressRepository{
+		primary:       primary,
+		fallback:      fallback,
+		retryInterval: retryInterval,
+		now:           time.Now,
+	}
+}
+
+// PrimaryHealthy 报告当前是否在使用主存储。
+func (r *FailoverUploadProgressRepository) PrimaryHealthy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.now().Before(r.downUntil)
+}
+
+func (r *FailoverUploadProgressRepository) IsChunkUploaded(ctx context.Context, uploadID string, chunkIndex int) (bool, error) {
+	var uploaded bool
+	err := r.do(ctx, func(repo UploadProgressRepository) error {
+		var err error
+		uploaded, err = repo.IsChunkUploaded(ctx, uploadID, chunkIndex)
+		return err
+	})
+	return uploaded, err
+}
+
+func (r *FailoverUploadProgressRepository) AddChunk(ctx context.Context, uploadID string, chunkIndex int, expireSeconds int) error {
+	return r.do(ctx, func(repo UploadProgressRepository) error {
+		return repo.AddChunk(ctx, uploadID, chunkIndex, expireSeconds)
+	})
+}
+
+func (r *FailoverUploadProgressRepository) UploadedCount(ctx context.Context, uploadID string) (int64, error) {
+	var count int64
+	err := r.do(ctx, func(repo UploadProgressRepository) error {
+		var err error
+		count, err = repo.UploadedCount(ctx, uploadID)
+		return err
+	})
+	return count, err
+}
+
+func (r *FailoverUploadProgressRepository) UploadedChunks(ctx context.Context, uploadID string) ([]int, error) {
+	var chunks []int
+	err := r.do(ctx, func(repo UploadProgressRepository) error {
+		var err error
+		chunks, err = repo.UploadedChunks(ctx, uploadID)
+		return err
+	})
+	return chunks, err
+}
+
+// Clear 同时清理两侧存储，避免降级期间留下的记录残留。
+func (r *FailoverUploadProgressRepository) Clear(ctx context.Context, uploadID string) error {
+	fallbackErr := r.fallback.Clear(ctx, uploadID)
+	if !r.PrimaryHealthy() {
+		return fallbackErr
+	}
+	if err := r.primary.Clear(ctx, uploadID); err != nil {
+		r.markDown(err)
+	}
+	return fallbackErr
+}
+
+// do 在主存储健康时调用主存储，失败则标记降级并改用备用存储重试一次。
+func (r *FailoverUploadProgressRepository) do(ctx context.Context, fn func(repo UploadProgressRepository) error) error {
+	if !r.PrimaryHealthy() {
+		return fn(r.fallback)
+	}
+	err := fn(r.primary)
+	if err == nil {
+		return nil
+	}
+	// 调用方取消不代表存储故障，直接返回。
+	if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+		return err
+	}
+	r.markDown(err)
+	return fn(r.fallback)
+}
+
+func (r *FailoverUploadProgressRepository) markDown(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Before(r.downUntil) {
+		return
+	}
+	r.downUntil = now.Add(r.retryInterval)
+	logger.Warnf("上传进度主存储不可用，%s 内改用备用存储: %v", r.retryInterval, err)
+}
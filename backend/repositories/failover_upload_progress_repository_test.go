@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type flakyUploadProgressRepo struct {
+	*MemoryUploadProgressRepository
+	err   error
+	calls int
+}
+
+func (r *flakyUploadProgressRepo) AddChunk(ctx context.Context, uploadID string, chunkIndex int, expireSeconds int) error {
+	r.calls++
+	if r.err != nil {
+		return r.err
+	}
+	return r.MemoryUploadProgressRepository.AddChunk(ctx, uploadID, chunkIndex, expireSeconds)
+}
+
+func (r *flakyUploadProgressRepo) UploadedCount(ctx context.Context, uploadID string) (int64, error) {
+	r.calls++
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.MemoryUploadProgressRepository.UploadedCount(ctx, uploadID)
+}
+
+func TestFailoverUploadProgressRepository_FallsBackAndRecovers(t *testing.T) {
+	primary := &flakyUploadProgressRepo{MemoryUploadProgressRepository: NewMemoryUploadProgressRepository(), err: errors.New("connection refused")}
+	fallback := NewMemoryUploadProgressRepository()
+	repo := NewFailoverUploadProgressRepository(primary, fallback, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := repo.AddChunk(ctx, "u1", 0, 0); err != nil {
+		t.Fatalf("AddChunk should succeed via fallback: %v", err)
+	}
+	if repo.PrimaryHealthy() {
+		t.Fatalf("expected primary to be marked down")
+	}
+	if count, _ := fallback.UploadedCount(ctx, "u1"); count != 1 {
+		t.Fatalf("expected fallback to record the chunk, got %d", count)
+	}
+
+	// 降级期间不再访问主存储。
+	if err := repo.AddChunk(ctx, "u1", 1, 0); err != nil {
+		t.Fatalf("AddChunk failed: %v", err)
+	}
+	if primary.calls != 1 {
+		t.Fatalf("expected primary to be skipped while down, got %d calls", primary.calls)
+	}
+
+	primary.err = nil
+	now = now.Add(time.Minute)
+	if err := repo.AddChunk(ctx, "u2", 0, 0); err != nil {
+		t.Fatalf("AddChunk failed: %v", err)
+	}
+	if !repo.PrimaryHealthy() || primary.calls != 2 {
+		t.Fatalf("expected primary to be retried after the interval")
+	}
+	if count, _ := repo.UploadedCount(ctx, "u2"); count != 1 {
+		t.Fatalf("expected primary to serve reads after recovery, got %d", count)
+	}
+}
+
+func TestFailoverUploadProgressRepository_ClearCleansBothStores(t *testing.T) {
+	primary := NewMemoryUploadProgressRepository()
+	fallback := NewMemoryUploadProgressRepository()
+	repo := NewFailoverUploadProgressRepository(primary, fallback, time.Minute)
+	ctx := context.Background()
+
+	_ = primary.AddChunk(ctx, "u1", 0, 0)
+	_ = fallback.AddChunk(ctx, "u1", 1, 0)
+	if err := repo.Clear(ctx, "u1"); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+
+	if count, _ := primary.UploadedCount(ctx, "u1"); count != 0 {
+		t.Fatalf("expected primary to be cleared")
+	}
+	if count, _ := fallback.UploadedCount(ctx, "u1"); count != 0 {
+		t.Fatalf("expected fallback to be cleared")
+	}
+}
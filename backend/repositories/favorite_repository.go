@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormFavoriteRepository struct {
+	db *gorm.DB
+}
+
+func NewGormFavoriteRepository(db *gorm.DB) *GormFavoriteRepository {
+	return &GormFavoriteRepository{db: db}
+}
+
+// Add 新增收藏；目标已收藏时保持原记录不变。
+func (r *GormFavoriteRepository) Add(ctx context.Context, tx *gorm.DB, favorite *models.Favorite) error {
+	return useTx(ctx, r.db, tx).Clauses(clause.OnConflict{DoNothing: true}).Create(favorite).Error
+}
+
+func (r *GormFavoriteRepository) Remove(ctx context.Context, tx *gorm.DB, userID uint, targetType string, targetID uint) error {
+	return useTx(ctx, r.db, tx).
+		Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+		Delete(&models.Favorite{}).Error
+}
+
+// activeQuery 关联收藏目标并过滤掉已软删除或不存在的目标。
+func (r *GormFavoriteRepository) activeQuery(db *gorm.DB, in FavoriteListInput) *gorm.DB {
+	query := db.Model(&models.Favorite{}).
+		Joins("LEFT JOIN files ON favorites.target_type = ? AND files.id = favorites.target_id AND files.user_id = favorites.user_id AND files.deleted_at IS NULL", models.FavoriteTargetFile).
+		Joins("LEFT JOIN folders ON favorites.target_type = ? AND folders.id = favorites.target_id AND folders.user_id = favorites.user_id AND folders.deleted_at IS NULL", models.FavoriteTargetFolder).
+		Where("favorites.user_id = ? AND (files.id IS NOT NULL OR folders.id IS NOT NULL)", in.UserID)
+	if in.TargetType != "" {
+		query = query.Where("favorites.target_type = ?", in.TargetType)
+	}
+	if in.Keyword != "" {
+		pattern := containsPattern(in.Keyword)
+		query = query.Where("(LOWER(files.original_name) LIKE ? ESCAPE '!' OR LOWER(folders.name) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	return query
+}
+
+func (r *GormFavoriteRepository) CountActive(ctx context.Context, tx *gorm.DB, in FavoriteListInput) (int64, error) {
+	var total int64
+	err := r.activeQuery(useTx(ctx, r.db, tx), in).Count(&total).Error
+	return total, err
+}
+
+// ListActive 按收藏时间倒序分页列出有效收藏。
+func (r *GormFavoriteRepository) ListActive(ctx context.Context, tx *gorm.DB, in FavoriteListInput) ([]models.Favorite, error) {
+	var favorites []models.Favorite
+	err := r.activeQuery(useTx(ctx, r.db, tx), in).
+		Select("favorites.*").
+		Order("favorites.created_at DESC, favorites.id DESC").
+		Offset(in.Offset).
+		Limit(in.Limit).
+		Find(&favorites).Error
+	return favorites, err
+}
+
+// DeleteOrphans 清理目标已被彻底删除的收藏；目标仍在回收站时保留，恢复后收藏随之恢复。
+func (r *GormFavoriteRepository) DeleteOrphans(ctx context.Context, tx *gorm.DB) (int64, error) {
+	result := useTx(ctx, r.db, tx).
+		Where("(target_type = ? AND NOT EXISTS (SELECT 1 FROM files WHERE files.id = favorites.target_id))"+
+			" OR (target_type = ? AND NOT EXISTS (SELECT 1 FROM folders WHERE folders.id = favorites.target_id))",
+			models.FavoriteTargetFile, models.FavoriteTargetFolder).
+		Delete(&models.Favorite{})
+	return result.RowsAffected, result.Error
+}
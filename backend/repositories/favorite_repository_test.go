@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormFavoriteRepository_ListActive_JoinsActiveTargets(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFavoriteRepository(db)
+
+		_, err := repo.ListActive(context.Background(), nil, FavoriteListInput{UserID: 4, TargetType: "file", Keyword: "Re_port", Limit: 20})
+		if err != nil {
+			t.Fatalf("ListActive failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"from `favorites`",
+			"left join files on favorites.target_type = ? and files.id = favorites.target_id",
+			"files.deleted_at is null",
+			"folders.deleted_at is null",
+			"files.id is not null or folders.id is not null",
+			"favorites.target_type = ?",
+			"lower files.original_name  like ? escape '!'",
+			"order by favorites.created_at desc, favorites.id desc",
+		)
+	})
+}
+
+func TestGormFavoriteRepository_LiveLifecycle(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormFavoriteRepository(db)
+		userID := liveUserID(t, db)
+		t.Cleanup(func() {
+			db.Where("user_id = ?", userID).Delete(&models.Favorite{})
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+		})
+
+		folder := models.Folder{Name: "Reports", UserID: userID, Path: "/Reports"}
+		if err := db.Create(&folder).Error; err != nil {
+			t.Fatalf("create folder failed: %v", err)
+		}
+		var files []models.File
+		for i := 0; i < 2; i++ {
+			file := models.File{Name: fmt.Sprintf("s%d", i), OriginalName: fmt.Sprintf("note%d.txt", i), FolderID: folder.ID, UserID: userID, FileObjectID: 1}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			files = append(files, file)
+		}
+
+		for _, fav := range []models.Favorite{
+			{UserID: userID, TargetType: models.FavoriteTargetFolder, TargetID: folder.ID},
+			{UserID: userID, TargetType: models.FavoriteTargetFile, TargetID: files[0].ID},
+			{UserID: userID, TargetType: models.FavoriteTargetFile, TargetID: files[1].ID},
+			// 重复收藏保持幂等。
+			{UserID: userID, TargetType: models.FavoriteTargetFile, TargetID: files[1].ID},
+		} {
+			if err := repo.Add(ctx, nil, &fav); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+
+		// 文件进入回收站（软删除）后不再出现在收藏中。
+		if err := db.Delete(&files[0]).Error; err != nil {
+			t.Fatalf("soft delete failed: %v", err)
+		}
+		in := FavoriteListInput{UserID: userID, Limit: 10}
+		total, err := repo.CountActive(ctx, nil, in)
+		if err != nil {
+			t.Fatalf("CountActive failed: %v", err)
+		}
+		list, err := repo.ListActive(ctx, nil, in)
+		if err != nil {
+			t.Fatalf("ListActive failed: %v", err)
+		}
+		if total != 2 || len(list) != 2 {
+			t.Fatalf("expected 2 active favorites, got total=%d list=%+v", total, list)
+		}
+
+		in.Keyword = "REPORT"
+		list, err = repo.ListActive(ctx, nil, in)
+		if err != nil {
+			t.Fatalf("ListActive with keyword failed: %v", err)
+		}
+		if len(list) != 1 || list[0].TargetType != models.FavoriteTargetFolder {
+			t.Fatalf("expected keyword to match folder only, got %+v", list)
+		}
+
+		// 回收站中的文件收藏保留，彻底删除后才被清理。
+		if _, err := repo.DeleteOrphans(ctx, nil); err != nil {
+			t.Fatalf("DeleteOrphans failed: %v", err)
+		}
+		var kept int64
+		db.Model(&models.Favorite{}).Where("user_id = ?", userID).Count(&kept)
+		if kept != 3 {
+			t.Fatalf("expected recycled favorite to be kept, got %d rows", kept)
+		}
+		if err := db.Unscoped().Delete(&files[0]).Error; err != nil {
+			t.Fatalf("hard delete failed: %v", err)
+		}
+		if _, err := repo.DeleteOrphans(ctx, nil); err != nil {
+			t.Fatalf("DeleteOrphans failed: %v", err)
+		}
+		db.Model(&models.Favorite{}).Where("user_id = ?", userID).Count(&kept)
+		if kept != 2 {
+			t.Fatalf("expected orphan favorite to be removed, got %d rows", kept)
+		}
+
+		if err := repo.Remove(ctx, nil, userID, models.FavoriteTargetFolder, folder.ID); err != nil {
+			t.Fatalf("Remove failed: %v", err)
+		}
+		total, err = repo.CountActive(ctx, nil, FavoriteListInput{UserID: userID})
+		if err != nil || total != 1 {
+			t.Fatalf("expected 1 favorite after remove, got %d (%v)", total, err)
+		}
+	})
+}
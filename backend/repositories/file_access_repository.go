@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormFileAccessRepository struct {
+	db *gorm.DB
+}
+
+func NewGormFileAccessRepository(db *gorm.DB) *GormFileAccessRepository {
+	return &GormFileAccessRepository{db: db}
+}
+
+// Touch 记录一次访问：首次访问插入，之后累加次数并刷新访问时间。
+func (r *GormFileAccessRepository) Touch(ctx context.Context, tx *gorm.DB, userID uint, fileID uint, at time.Time) error {
+	access := models.FileAccess{UserID: userID, FileID: fileID, AccessCount: 1, LastAccessedAt: at}
+	return useTx(ctx, r.db, tx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "file_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"access_count":     gorm.Expr("file_accesses.access_count + 1"),
+			"last_accessed_at": at,
+		}),
+	}).Create(&access).Error
+}
+
+// ListRecent 按最近访问时间倒序列出访问记录，并预加载文件与文件对象。
+func (r *GormFileAccessRepository) ListRecent(ctx context.Context, tx *gorm.DB, in RecentFilesInput) ([]models.FileAccess, error) {
+	query := useTx(ctx, r.db, tx).Preload("File").Preload("File.FileObject").Model(&models.FileAccess{}).
+		Joins("JOIN files ON files.id = file_accesses.file_id AND files.user_id = file_accesses.user_id AND files.deleted_at IS NULL").
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Select("file_accesses.*").
+		Where("file_accesses.user_id = ?", in.UserID)
+	query = applyFileQuickFilter(query, in.Filter)
+	if in.Since != nil {
+		query = query.Where("file_accesses.last_accessed_at >= ?", *in.Since)
+	}
+
+	var accesses []models.FileAccess
+	err := query.Order("file_accesses.last_accessed_at DESC, file_accesses.id DESC").Limit(in.Limit).Find(&accesses).Error
+	return accesses, err
+}
+
+// DeleteOrphans 清理文件已被彻底删除的访问记录。
+func (r *GormFileAccessRepository) DeleteOrphans(ctx context.Context, tx *gorm.DB) (int64, error) {
+	result := useTx(ctx, r.db, tx).
+		Where("NOT EXISTS (SELECT 1 FROM files WHERE files.id = file_accesses.file_id)").
+		Delete(&models.FileAccess{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormFileAccessRepository_Touch_BuildsUpsertSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileAccessRepository(db)
+
+		if err := repo.Touch(context.Background(), nil, 3, 9, time.Now()); err != nil {
+			t.Fatalf("Touch failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "insert into `file_accesses`", "file_accesses.access_count + 1")
+	})
+}
+
+func TestGormFileAccessRepository_ListRecent_FiltersByMimeAndKeyword(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileAccessRepository(db)
+		since := time.Now().Add(-time.Hour)
+
+		_, err := repo.ListRecent(context.Background(), nil, RecentFilesInput{
+			UserID: 3,
+			Filter: FileQuickFilter{Keyword: "a", MimePrefixes: []string{"image/"}, MimeTypes: []string{"application/pdf"}},
+			Since:  &since,
+			Limit:  10,
+		})
+		if err != nil {
+			t.Fatalf("ListRecent failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"join files on files.id = file_accesses.file_id",
+			"files.deleted_at is null",
+			"file_objects.mime_type like ? escape '!' or file_objects.mime_type in",
+			"file_accesses.last_accessed_at >= ?",
+			"order by file_accesses.last_accessed_at desc, file_accesses.id desc",
+		)
+	})
+}
+
+func TestGormFileAccessRepository_LiveTouchAndListRecent(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormFileAccessRepository(db)
+		userID := liveUserID(t, db)
+
+		var objects []models.FileObject
+		t.Cleanup(func() {
+			db.Where("user_id = ?", userID).Delete(&models.FileAccess{})
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+			for i := range objects {
+				db.Delete(&objects[i])
+			}
+		})
+		var files []models.File
+		for i, mimeType := range []string{"image/png", "text/plain", "image/jpeg"} {
+			obj := models.FileObject{FilePath: fmt.Sprintf("access/%d/%d", userID, i), FileSize: 1, MimeType: mimeType}
+			if err := db.Create(&obj).Error; err != nil {
+				t.Fatalf("create file object failed: %v", err)
+			}
+			objects = append(objects, obj)
+			file := models.File{Name: fmt.Sprintf("s%d", i), OriginalName: fmt.Sprintf("f%d", i), FolderID: 1, UserID: userID, FileObjectID: obj.ID}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			files = append(files, file)
+		}
+
+		base := time.Now().Truncate(time.Second)
+		for i, file := range files {
+			if err := repo.Touch(ctx, nil, userID, file.ID, base.Add(time.Duration(i)*time.Minute)); err != nil {
+				t.Fatalf("Touch failed: %v", err)
+			}
+		}
+		// 再次访问 f0：次数累加且排到最前。
+		if err := repo.Touch(ctx, nil, userID, files[0].ID, base.Add(time.Hour)); err != nil {
+			t.Fatalf("Touch failed: %v", err)
+		}
+		if err := db.Delete(&files[2]).Error; err != nil {
+			t.Fatalf("soft delete failed: %v", err)
+		}
+
+		list, err := repo.ListRecent(ctx, nil, RecentFilesInput{UserID: userID, Filter: FileQuickFilter{MimePrefixes: []string{"image/"}}, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListRecent failed: %v", err)
+		}
+		if len(list) != 1 || list[0].FileID != files[0].ID || list[0].AccessCount != 2 || list[0].File.FileObject.MimeType != "image/png" {
+			t.Fatalf("unexpected recent accesses: %+v", list)
+		}
+
+		if err := db.Unscoped().Delete(&files[2]).Error; err != nil {
+			t.Fatalf("hard delete failed: %v", err)
+		}
+		removed, err := repo.DeleteOrphans(ctx, nil)
+		if err != nil {
+			t.Fatalf("DeleteOrphans failed: %v", err)
+		}
+		if removed < 1 {
+			t.Fatalf("expected orphan access rows to be removed, got %d", removed)
+		}
+	})
+}
@@ -16,34 +16,42 @@ func NewGormFileObjectRepository(db *gorm.DB) *GormFileObjectRepository {
 	return &GormFileObjectRepository{db: db}
 }
 
-func (r *GormFileObjectRepository) Create(_ context.Context, tx *gorm.DB, fileObject *models.FileObject) error {
-	return useTx(r.db, tx).Create(fileObject).Error
+func (r *GormFileObjectRepository) Create(ctx context.Context, tx *gorm.DB, fileObject *models.FileObject) error {
+	return useTx(ctx, r.db, tx).Create(fileObject).Error
 }
 
-func (r *GormFileObjectRepository) GetByID(_ context.Context, tx *gorm.DB, fileObjectID uint) (models.FileObject, error) {
+func (r *GormFileObjectRepository) GetByID(ctx context.Context, tx *gorm.DB, fileObjectID uint) (models.FileObject, error) {
 	var obj models.FileObject
-	err := useTx(r.db, tx).First(&obj, fileObjectID).Error
+	err := useTx(ctx, r.db, tx).First(&obj, fileObjectID).Error
 	return obj, err
 }
 
-func (r *GormFileObjectRepository) GetByMD5(_ context.Context, tx *gorm.DB, md5 string) (models.FileObject, error) {
+func (r *GormFileObjectRepository) GetByMD5(ctx context.Context, tx *gorm.DB, md5 string) (models.FileObject, error) {
 	var obj models.FileObject
-	err := useTx(r.db, tx).Where("file_md5 = ?", md5).First(&obj).Error
+	err := useTx(ctx, r.db, tx).Where("file_md5 = ?", md5).First(&obj).Error
 	return obj, err
 }
 
-func (r *GormFileObjectRepository) IncrementRefCount(_ context.Context, tx *gorm.DB, fileObjectID uint) error {
-	return useTx(r.db, tx).Model(&models.FileObject{}).
+func (r *GormFileObjectRepository) IncrementRefCount(ctx context.Context, tx *gorm.DB, fileObjectID uint) error {
+	return useTx(ctx, r.db, tx).Model(&models.FileObject{}).
 		Where("id = ?", fileObjectID).
 		Update("ref_count", gorm.Expr("ref_count + 1")).Error
 }
 
-func (r *GormFileObjectRepository) DecrementRefCount(_ context.Context, tx *gorm.DB, fileObjectID uint) error {
-	return useTx(r.db, tx).Model(&models.FileObject{}).
+func (r *GormFileObjectRepository) DecrementRefCount(ctx context.Context, tx *gorm.DB, fileObjectID uint) error {
+	return useTx(ctx, r.db, tx).Model(&models.FileObject{}).
 		Where("id = ?", fileObjectID).
 		Update("ref_count", gorm.Expr("ref_count - 1")).Error
 }
 
-func (r *GormFileObjectRepository) DeleteByID(_ context.Context, tx *gorm.DB, fileObjectID uint) error {
-	return useTx(r.db, tx).Delete(&models.FileObject{}, fileObjectID).Error
+// DeleteByID 删除文件对象及其图片、音视频元数据。
+func (r *GormFileObjectRepository) DeleteByID(ctx context.Context, tx *gorm.DB, fileObjectID uint) error {
+	db := useTx(ctx, r.db, tx)
+	if err := db.Where("file_object_id = ?", fileObjectID).Delete(&models.ImageMetadata{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("file_object_id = ?", fileObjectID).Delete(&models.MediaMetadata{}).Error; err != nil {
+		return err
+	}
+	return db.Delete(&models.FileObject{}, fileObjectID).Error
 }
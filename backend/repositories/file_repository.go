@@ -24,15 +24,17 @@ func (r *GormFileRepository) folderQuery(db *gorm.DB, userID uint, folderID uint
 	return db.Where("user_id = ? AND folder_id = ?", userID, folderID)
 }
 
-func (r *GormFileRepository) CountByFolder(_ context.Context, tx *gorm.DB, userID uint, folderID uint, rootFolderID uint, includeLegacyRoot bool) (int64, error) {
-	db := useTx(r.db, tx)
+// CountByFolder 统计目录内满足标签筛选的文件数，忽略分页与排序参数。
+func (r *GormFileRepository) CountByFolder(ctx context.Context, tx *gorm.DB, in ListFilesInput) (int64, error) {
+	db := useTx(ctx, r.db, tx)
+	query := r.folderQuery(db.Model(&models.File{}), in.UserID, in.FolderID, in.RootFolderID, in.IncludeLegacyRoot)
 	var total int64
-	err := r.folderQuery(db.Model(&models.File{}), userID, folderID, rootFolderID, includeLegacyRoot).Count(&total).Error
+	err := applyTagFilter(query, models.TagTargetFile, "files.id", in.Tags).Count(&total).Error
 	return total, err
 }
 
-func (r *GormFileRepository) CountByFolderAndOriginalName(_ context.Context, tx *gorm.DB, userID uint, folderID uint, originalName string, excludeID uint, unscoped bool) (int64, error) {
-	db := useTx(r.db, tx)
+func (r *GormFileRepository) CountByFolderAndOriginalName(ctx context.Context, tx *gorm.DB, userID uint, folderID uint, originalName string, excludeID uint, unscoped bool) (int64, error) {
+	db := useTx(ctx, r.db, tx)
 	if unscoped {
 		db = db.Unscoped()
 	}
@@ -46,9 +48,22 @@ func (r *GormFileRepository) CountByFolderAndOriginalName(_ context.Context, tx
 	return count, err
 }
 
-func (r *GormFileRepository) ListByFolder(_ context.Context, tx *gorm.DB, in ListFilesInput) ([]models.File, error) {
-	db := useTx(r.db, tx)
+// GetByFolderAndOriginalName 查找目录内同名的活动文件，并预加载文件对象。
+func (r *GormFileRepository) GetByFolderAndOriginalName(ctx context.Context, tx *gorm.DB, userID uint, folderID uint, originalName string, excludeID uint) (models.File, error) {
+	query := useTx(ctx, r.db, tx).Preload("FileObject").
+		Where("user_id = ? AND folder_id = ? AND original_name = ?", userID, folderID, originalName)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var file models.File
+	err := query.First(&file).Error
+	return file, err
+}
+
+func (r *GormFileRepository) ListByFolder(ctx context.Context, tx *gorm.DB, in ListFilesInput) ([]models.File, error) {
+	db := useTx(ctx, r.db, tx)
 	query := r.folderQuery(db.Preload("FileObject").Model(&models.File{}), in.UserID, in.FolderID, in.RootFolderID, in.IncludeLegacyRoot)
+	query = applyTagFilter(query, models.TagTargetFile, "files.id", in.Tags)
 
 	if in.SortBy == "file_size" {
 		query = query.Joins("LEFT JOIN file_objects ON file_objects.id = files.file_object_id").Select("files.*")
@@ -74,8 +89,100 @@ func (r *GormFileRepository) ListByFolder(_ context.Context, tx *gorm.DB, in Lis
 	return files, err
 }
 
-func (r *GormFileRepository) ListByFolderIDs(_ context.Context, tx *gorm.DB, userID uint, folderIDs []uint, preloadObject bool, unscoped bool) ([]models.File, error) {
-	db := useTx(r.db, tx)
+// ListByFolderAfter 按 (排序键, id) 游标分页列出目录内文件；name 排序使用用户可见的原始文件名。
+func (r *GormFileRepository) ListByFolderAfter(ctx context.Context, tx *gorm.DB, in KeysetListInput) ([]models.File, error) {
+	db := useTx(ctx, r.db, tx)
+	query := r.folderQuery(db.Preload("FileObject").Model(&models.File{}), in.UserID, in.FolderID, in.RootFolderID, in.IncludeLegacyRoot)
+	query = applyTagFilter(query, models.TagTargetFile, "files.id", in.Tags)
+
+	sortColumns := map[string]string{
+		"name":       "files.original_name",
+		"created_at": "files.created_at",
+		"file_size":  "file_objects.file_size",
+	}
+	sortCol := sortColumns[in.SortBy]
+	if sortCol == "" {
+		sortCol = sortColumns["created_at"]
+	}
+	if in.SortBy == "file_size" {
+		query = query.Joins("LEFT JOIN file_objects ON file_objects.id = files.file_object_id").Select("files.*")
+	}
+
+	desc := strings.ToUpper(in.Order) != "ASC"
+	if in.AfterKey != nil {
+		query = query.Where(keysetCondition(sortCol, "files.id", desc), in.AfterKey, in.AfterKey, in.AfterID)
+	}
+
+	var files []models.File
+	err := query.Order(keysetOrder(sortCol, "files.id", desc)).Limit(in.Limit).Find(&files).Error
+	return files, err
+}
+
+// ListRecentUploads 按上传时间倒序列出用户的正常文件。
+func (r *GormFileRepository) ListRecentUploads(ctx context.Context, tx *gorm.DB, in RecentFilesInput) ([]models.File, error) {
+	query := useTx(ctx, r.db, tx).Preload("FileObject").Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Select("files.*").
+		Where("files.user_id = ?", in.UserID)
+	query = applyFileQuickFilter(query, in.Filter)
+	if in.Since != nil {
+		query = query.Where("files.created_at >= ?", *in.Since)
+	}
+
+	var files []models.File
+	err := query.Order("files.created_at DESC, files.id DESC").Limit(in.Limit).Find(&files).Error
+	return files, err
+}
+
+func (r *GormFileRepository) searchQuery(db *gorm.DB, in SearchInput) *gorm.DB {
+	query := db.Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Where("files.user_id = ?", in.UserID)
+	return applyFileQuickFilter(query, in.Filter)
+}
+
+func (r *GormFileRepository) CountSearch(ctx context.Context, tx *gorm.DB, in SearchInput) (int64, error) {
+	var total int64
+	err := r.searchQuery(useTx(ctx, r.db, tx), in).Count(&total).Error
+	return total, err
+}
+
+// Search 按原始文件名排序分页搜索用户的正常文件。
+func (r *GormFileRepository) Search(ctx context.Context, tx *gorm.DB, in SearchInput) ([]models.File, error) {
+	var files []models.File
+	err := r.searchQuery(useTx(ctx, r.db, tx).Preload("FileObject"), in).
+		Select("files.*").
+		Order("files.original_name ASC, files.id ASC").
+		Offset(in.Offset).
+		Limit(in.Limit).
+		Find(&files).Error
+	return files, err
+}
+
+// applyFileQuickFilter 追加快捷视图筛选条件，要求查询已关联 files 与 file_objects。
+func applyFileQuickFilter(query *gorm.DB, f FileQuickFilter) *gorm.DB {
+	if f.Keyword != "" {
+		query = query.Where("LOWER(files.original_name) LIKE ? ESCAPE '!'", containsPattern(f.Keyword))
+	}
+	query = applyTagFilter(query, models.TagTargetFile, "files.id", f.Tags)
+	if len(f.MimePrefixes) == 0 && len(f.MimeTypes) == 0 {
+		return query
+	}
+	conditions := make([]string, 0, len(f.MimePrefixes)+1)
+	args := make([]interface{}, 0, len(f.MimePrefixes)+1)
+	for _, prefix := range f.MimePrefixes {
+		conditions = append(conditions, "file_objects.mime_type LIKE ? ESCAPE '!'")
+		args = append(args, likeEscaper.Replace(prefix)+"%")
+	}
+	if len(f.MimeTypes) > 0 {
+		conditions = append(conditions, "file_objects.mime_type IN ?")
+		args = append(args, f.MimeTypes)
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+func (r *GormFileRepository) ListByFolderIDs(ctx context.Context, tx *gorm.DB, userID uint, folderIDs []uint, preloadObject bool, unscoped bool) ([]models.File, error) {
+	db := useTx(ctx, r.db, tx)
 	if preloadObject {
 		db = db.Preload("FileObject")
 	}
@@ -87,12 +194,12 @@ func (r *GormFileRepository) ListByFolderIDs(_ context.Context, tx *gorm.DB, use
 	return files, err
 }
 
-func (r *GormFileRepository) Create(_ context.Context, tx *gorm.DB, file *models.File) error {
-	return useTx(r.db, tx).Create(file).Error
+func (r *GormFileRepository) Create(ctx context.Context, tx *gorm.DB, file *models.File) error {
+	return useTx(ctx, r.db, tx).Create(file).Error
 }
 
-func (r *GormFileRepository) GetByIDAndUser(_ context.Context, tx *gorm.DB, fileID uint, userID uint, preloadObject bool) (models.File, error) {
-	db := useTx(r.db, tx)
+func (r *GormFileRepository) GetByIDAndUser(ctx context.Context, tx *gorm.DB, fileID uint, userID uint, preloadObject bool) (models.File, error) {
+	db := useTx(ctx, r.db, tx)
 	if preloadObject {
 		db = db.Preload("FileObject")
 	}
@@ -101,8 +208,8 @@ func (r *GormFileRepository) GetByIDAndUser(_ context.Context, tx *gorm.DB, file
 	return file, err
 }
 
-func (r *GormFileRepository) GetByIDAndUserUnscoped(_ context.Context, tx *gorm.DB, fileID uint, userID uint, preloadObject bool) (models.File, error) {
-	db := useTx(r.db, tx).Unscoped()
+func (r *GormFileRepository) GetByIDAndUserUnscoped(ctx context.Context, tx *gorm.DB, fileID uint, userID uint, preloadObject bool) (models.File, error) {
+	db := useTx(ctx, r.db, tx).Unscoped()
 	if preloadObject {
 		db = db.Preload("FileObject")
 	}
@@ -111,8 +218,8 @@ func (r *GormFileRepository) GetByIDAndUserUnscoped(_ context.Context, tx *gorm.
 	return file, err
 }
 
-func (r *GormFileRepository) GetByIDsAndUser(_ context.Context, tx *gorm.DB, userID uint, fileIDs []uint, preloadObject bool) ([]models.File, error) {
-	db := useTx(r.db, tx)
+func (r *GormFileRepository) GetByIDsAndUser(ctx context.Context, tx *gorm.DB, userID uint, fileIDs []uint, preloadObject bool) ([]models.File, error) {
+	db := useTx(ctx, r.db, tx)
 	if preloadObject {
 		db = db.Preload("FileObject")
 	}
@@ -121,46 +228,46 @@ func (r *GormFileRepository) GetByIDsAndUser(_ context.Context, tx *gorm.DB, use
 	return files, err
 }
 
-func (r *GormFileRepository) UpdateByIDAndUser(_ context.Context, tx *gorm.DB, fileID uint, userID uint, updates map[string]interface{}) error {
-	return useTx(r.db, tx).Model(&models.File{}).Where("id = ? AND user_id = ?", fileID, userID).Updates(updates).Error
+func (r *GormFileRepository) UpdateByIDAndUser(ctx context.Context, tx *gorm.DB, fileID uint, userID uint, updates map[string]interface{}) error {
+	return useTx(ctx, r.db, tx).Model(&models.File{}).Where("id = ? AND user_id = ?", fileID, userID).Updates(updates).Error
 }
 
-func (r *GormFileRepository) UpdateByIDsAndUser(_ context.Context, tx *gorm.DB, fileIDs []uint, userID uint, updates map[string]interface{}) error {
+func (r *GormFileRepository) UpdateByIDsAndUser(ctx context.Context, tx *gorm.DB, fileIDs []uint, userID uint, updates map[string]interface{}) error {
 	if len(fileIDs) == 0 {
 		return nil
 	}
-	return useTx(r.db, tx).Model(&models.File{}).Where("id IN ? AND user_id = ?", fileIDs, userID).Updates(updates).Error
+	return useTx(ctx, r.db, tx).Model(&models.File{}).Where("id IN ? AND user_id = ?", fileIDs, userID).Updates(updates).Error
 }
 
-func (r *GormFileRepository) SoftDeleteByIDAndUser(_ context.Context, tx *gorm.DB, fileID uint, userID uint) error {
-	return useTx(r.db, tx).Where("id = ? AND user_id = ?", fileID, userID).Delete(&models.File{}).Error
+func (r *GormFileRepository) SoftDeleteByIDAndUser(ctx context.Context, tx *gorm.DB, fileID uint, userID uint) error {
+	return useTx(ctx, r.db, tx).Where("id = ? AND user_id = ?", fileID, userID).Delete(&models.File{}).Error
 }
 
-func (r *GormFileRepository) SoftDeleteByFolderIDs(_ context.Context, tx *gorm.DB, userID uint, folderIDs []uint) error {
+func (r *GormFileRepository) SoftDeleteByFolderIDs(ctx context.Context, tx *gorm.DB, userID uint, folderIDs []uint) error {
 	if len(folderIDs) == 0 {
 		return nil
 	}
-	return useTx(r.db, tx).Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Delete(&models.File{}).Error
+	return useTx(ctx, r.db, tx).Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Delete(&models.File{}).Error
 }
 
-func (r *GormFileRepository) UnscopedDeleteByIDAndUser(_ context.Context, tx *gorm.DB, fileID uint, userID uint) error {
-	return useTx(r.db, tx).Unscoped().Where("id = ? AND user_id = ?", fileID, userID).Delete(&models.File{}).Error
+func (r *GormFileRepository) UnscopedDeleteByIDAndUser(ctx context.Context, tx *gorm.DB, fileID uint, userID uint) error {
+	return useTx(ctx, r.db, tx).Unscoped().Where("id = ? AND user_id = ?", fileID, userID).Delete(&models.File{}).Error
 }
 
-func (r *GormFileRepository) UnscopedRestoreByIDAndUser(_ context.Context, tx *gorm.DB, fileID uint, userID uint, updates map[string]interface{}) error {
-	return useTx(r.db, tx).Unscoped().Model(&models.File{}).Where("id = ? AND user_id = ?", fileID, userID).Updates(updates).Error
+func (r *GormFileRepository) UnscopedRestoreByIDAndUser(ctx context.Context, tx *gorm.DB, fileID uint, userID uint, updates map[string]interface{}) error {
+	return useTx(ctx, r.db, tx).Unscoped().Model(&models.File{}).Where("id = ? AND user_id = ?", fileID, userID).Updates(updates).Error
 }
 
-func (r *GormFileRepository) UnscopedRestoreByFolderIDs(_ context.Context, tx *gorm.DB, userID uint, folderIDs []uint, updates map[string]interface{}) error {
+func (r *GormFileRepository) UnscopedRestoreByFolderIDs(ctx context.Context, tx *gorm.DB, userID uint, folderIDs []uint, updates map[string]interface{}) error {
 	if len(folderIDs) == 0 {
 		return nil
 	}
-	return useTx(r.db, tx).Unscoped().Model(&models.File{}).Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Updates(updates).Error
+	return useTx(ctx, r.db, tx).Unscoped().Model(&models.File{}).Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Updates(updates).Error
 }
 
-func (r *GormFileRepository) FindByUserAndMD5(_ context.Context, tx *gorm.DB, userID uint, md5 string) (models.FileObject, error) {
+func (r *GormFileRepository) FindByUserAndMD5(ctx context.Context, tx *gorm.DB, userID uint, md5 string) (models.FileObject, error) {
 	var obj models.FileObject
-	err := useTx(r.db, tx).
+	err := useTx(ctx, r.db, tx).
 		Joins("JOIN files ON files.file_object_id = file_objects.id").
 		Where("files.user_id = ? AND file_objects.file_md5 = ? AND files.deleted_at IS NULL", userID, md5).
 		First(&obj).Error
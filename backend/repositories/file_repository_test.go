@@ -2,313 +2,485 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"mcloud/models"
+
+	"gorm.io/gorm"
 )
 
 func TestGormFileRepository_CountByFolder_NormalFolder_BuildsScopedSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
-
-	total, err := repo.CountByFolder(context.Background(), nil, 2, 9, 1, false)
-	if err != nil {
-		t.Fatalf("CountByFolder failed: %v", err)
-	}
-	if total != 0 {
-		t.Fatalf("expected dry-run total 0, got %d", total)
-	}
-
-	assertLastSQLContains(t, rec, "select count(*)", "from `files`", "where user_id = ? and folder_id = ?")
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		total, err := repo.CountByFolder(context.Background(), nil, ListFilesInput{UserID: 2, FolderID: 9, RootFolderID: 1})
+		if err != nil {
+			t.Fatalf("CountByFolder failed: %v", err)
+		}
+		if total != 0 {
+			t.Fatalf("expected dry-run total 0, got %d", total)
+		}
+
+		assertLastSQLContains(t, rec, "select count(*)", "from `files`", "where user_id = ? and folder_id = ?")
+	})
 }
 
 func TestGormFileRepository_CountByFolder_LegacyRoot_BuildsCompatSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.CountByFolder(context.Background(), nil, 2, 1, 1, true)
-	if err != nil {
-		t.Fatalf("CountByFolder with legacy root failed: %v", err)
-	}
+		_, err := repo.CountByFolder(context.Background(), nil, ListFilesInput{UserID: 2, FolderID: 1, RootFolderID: 1, IncludeLegacyRoot: true})
+		if err != nil {
+			t.Fatalf("CountByFolder with legacy root failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and (folder_id = ? or folder_id = 0)")
+		assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and (folder_id = ? or folder_id = 0)")
+	})
 }
 
 func TestGormFileRepository_CountByFolderAndOriginalName_ScopedWithoutExclude(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.CountByFolderAndOriginalName(context.Background(), nil, 2, 9, "a.txt", 0, false)
-	if err != nil {
-		t.Fatalf("CountByFolderAndOriginalName failed: %v", err)
-	}
+		_, err := repo.CountByFolderAndOriginalName(context.Background(), nil, 2, 9, "a.txt", 0, false)
+		if err != nil {
+			t.Fatalf("CountByFolderAndOriginalName failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "select count(*)", "from `files`", "user_id = ? and folder_id = ? and original_name = ?")
-	assertLastSQLNotContains(t, rec, "id <> ?")
+		assertLastSQLContains(t, rec, "select count(*)", "from `files`", "user_id = ? and folder_id = ? and original_name = ?")
+		assertLastSQLNotContains(t, rec, "id <> ?")
+	})
 }
 
 func TestGormFileRepository_CountByFolderAndOriginalName_UnscopedWithExclude(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.CountByFolderAndOriginalName(context.Background(), nil, 2, 9, "a.txt", 88, true)
-	if err != nil {
-		t.Fatalf("CountByFolderAndOriginalName failed: %v", err)
-	}
+		_, err := repo.CountByFolderAndOriginalName(context.Background(), nil, 2, 9, "a.txt", 88, true)
+		if err != nil {
+			t.Fatalf("CountByFolderAndOriginalName failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "select count(*)", "from `files`", "id <> ?")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "select count(*)", "from `files`", "id <> ?")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
-func TestGormFileRepository_ListByFolder_DefaultSort_FallsBackToCreatedAtDesc(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
-
-	_, err := repo.ListByFolder(context.Background(), nil, ListFilesInput{
-		UserID:            2,
-		FolderID:          9,
-		RootFolderID:      1,
-		IncludeLegacyRoot: false,
-		SortBy:            "invalid_sort",
-		Order:             "invalid",
-		Offset:            0,
-		Limit:             20,
+func TestGormFileRepository_GetByFolderAndOriginalName_ScopedWithExclude(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		_, err := repo.GetByFolderAndOriginalName(context.Background(), nil, 2, 9, "a.txt", 88)
+		if err != nil {
+			t.Fatalf("GetByFolderAndOriginalName failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `files`", "user_id = ? and folder_id = ? and original_name = ?", "id <> ?", "deleted_at is null")
 	})
-	if err != nil {
-		t.Fatalf("ListByFolder failed: %v", err)
-	}
+}
 
-	assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and folder_id = ?", "order by files.created_at desc")
+func TestGormFileRepository_ListByFolder_DefaultSort_FallsBackToCreatedAtDesc(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		_, err := repo.ListByFolder(context.Background(), nil, ListFilesInput{
+			UserID:            2,
+			FolderID:          9,
+			RootFolderID:      1,
+			IncludeLegacyRoot: false,
+			SortBy:            "invalid_sort",
+			Order:             "invalid",
+			Offset:            0,
+			Limit:             20,
+		})
+		if err != nil {
+			t.Fatalf("ListByFolder failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and folder_id = ?", "order by files.created_at desc")
+	})
 }
 
 func TestGormFileRepository_ListByFolder_SortByFileSize_UsesJoinAndOrder(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
-
-	_, err := repo.ListByFolder(context.Background(), nil, ListFilesInput{
-		UserID:            2,
-		FolderID:          1,
-		RootFolderID:      1,
-		IncludeLegacyRoot: true,
-		SortBy:            "file_size",
-		Order:             "asc",
-		Offset:            5,
-		Limit:             10,
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		_, err := repo.ListByFolder(context.Background(), nil, ListFilesInput{
+			UserID:            2,
+			FolderID:          1,
+			RootFolderID:      1,
+			IncludeLegacyRoot: true,
+			SortBy:            "file_size",
+			Order:             "asc",
+			Offset:            5,
+			Limit:             10,
+		})
+		if err != nil {
+			t.Fatalf("ListByFolder by file_size failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"from `files`",
+			"left join file_objects on file_objects.id = files.file_object_id",
+			"where user_id = ? and (folder_id = ? or folder_id = 0)",
+			"order by file_objects.file_size asc",
+		)
+	})
+}
+
+func TestGormFileRepository_ListByFolderAfter_SortByFileSize_BuildsKeysetSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		_, err := repo.ListByFolderAfter(context.Background(), nil, KeysetListInput{
+			UserID:   2,
+			FolderID: 9,
+			SortBy:   "file_size",
+			Order:    "desc",
+			AfterKey: int64(1024),
+			AfterID:  77,
+			Limit:    11,
+		})
+		if err != nil {
+			t.Fatalf("ListByFolderAfter failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"left join file_objects on file_objects.id = files.file_object_id",
+			"where user_id = ? and folder_id = ?",
+			"file_objects.file_size < ? or  file_objects.file_size = ? and files.id < ?",
+			"order by file_objects.file_size desc, files.id desc",
+		)
+	})
+}
+
+func TestGormFileRepository_ListByFolderAfter_SortByName_UsesOriginalName(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		_, err := repo.ListByFolderAfter(context.Background(), nil, KeysetListInput{
+			UserID:   2,
+			FolderID: 9,
+			SortBy:   "name",
+			Order:    "asc",
+			Limit:    11,
+		})
+		if err != nil {
+			t.Fatalf("ListByFolderAfter failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "order by files.original_name asc, files.id asc")
+		assertLastSQLNotContains(t, rec, "join file_objects", "files.id >")
+	})
+}
+
+func TestGormFileRepository_ListRecentUploads_BuildsFilteredSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+		since := time.Now().Add(-24 * time.Hour)
+
+		_, err := repo.ListRecentUploads(context.Background(), nil, RecentFilesInput{
+			UserID: 2,
+			Filter: FileQuickFilter{MimePrefixes: []string{"video/"}},
+			Since:  &since,
+			Limit:  30,
+		})
+		if err != nil {
+			t.Fatalf("ListRecentUploads failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"join file_objects on file_objects.id = files.file_object_id",
+			"files.user_id = ?",
+			"file_objects.mime_type like ? escape '!'",
+			"files.created_at >= ?",
+			"deleted_at is null",
+			"order by files.created_at desc, files.id desc",
+		)
+		assertLastSQLNotContains(t, rec, "original_name")
+	})
+}
+
+func TestGormFileRepository_ListByFolderAfter_PagesThroughTiedTimestamps(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormFileRepository(db)
+		userID := liveUserID(t, db)
+
+		obj := models.FileObject{FilePath: fmt.Sprintf("keyset/%d", userID), FileSize: 1}
+		if err := db.Create(&obj).Error; err != nil {
+			t.Fatalf("create file object failed: %v", err)
+		}
+		t.Cleanup(func() {
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+			db.Delete(&obj)
+		})
+		// 一半文件共享同一创建时间，验证排序键相同时靠 id 继续推进。
+		base := time.Now().Truncate(time.Millisecond)
+		for i := 0; i < 6; i++ {
+			file := models.File{
+				Name:         fmt.Sprintf("s%d", i),
+				OriginalName: fmt.Sprintf("f%d.txt", i),
+				FolderID:     5,
+				UserID:       userID,
+				FileObjectID: obj.ID,
+				CreatedAt:    base.Add(time.Duration(i/3) * time.Second),
+			}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+		}
+
+		seen := map[uint]bool{}
+		in := KeysetListInput{UserID: userID, FolderID: 5, SortBy: "created_at", Order: "desc", Limit: 2}
+		for page := 0; page < 4; page++ {
+			list, err := repo.ListByFolderAfter(ctx, nil, in)
+			if err != nil {
+				t.Fatalf("ListByFolderAfter failed: %v", err)
+			}
+			if len(list) == 0 {
+				break
+			}
+			for _, file := range list {
+				if seen[file.ID] {
+					t.Fatalf("file %d returned twice", file.ID)
+				}
+				seen[file.ID] = true
+			}
+			// 游标中的时间经过文本往返，与服务层的编码方式保持一致。
+			last := list[len(list)-1]
+			after, err := time.Parse(time.RFC3339Nano, last.CreatedAt.Format(time.RFC3339Nano))
+			if err != nil {
+				t.Fatalf("parse cursor time failed: %v", err)
+			}
+			in.AfterKey, in.AfterID = after, last.ID
+		}
+		if len(seen) != 6 {
+			t.Fatalf("expected all 6 files across pages, got %d", len(seen))
+		}
 	})
-	if err != nil {
-		t.Fatalf("ListByFolder by file_size failed: %v", err)
-	}
-
-	assertLastSQLContains(t, rec,
-		"from `files`",
-		"left join file_objects on file_objects.id = files.file_object_id",
-		"where user_id = ? and (folder_id = ? or folder_id = 0)",
-		"order by file_objects.file_size asc",
-	)
 }
 
 func TestGormFileRepository_ListByFolderIDs_BuildsINQuery(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.ListByFolderIDs(context.Background(), nil, 3, []uint{1, 2}, false, true)
-	if err != nil {
-		t.Fatalf("ListByFolderIDs failed: %v", err)
-	}
+		_, err := repo.ListByFolderIDs(context.Background(), nil, 3, []uint{1, 2}, false, true)
+		if err != nil {
+			t.Fatalf("ListByFolderIDs failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and folder_id in")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and folder_id in")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFileRepository_Create_BuildsInsertSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
-
-	file := &models.File{
-		Name:         "a.txt",
-		OriginalName: "a.txt",
-		FolderID:     1,
-		UserID:       2,
-		FileObjectID: 3,
-	}
-	if err := repo.Create(context.Background(), nil, file); err != nil {
-		t.Fatalf("Create failed: %v", err)
-	}
-
-	assertLastSQLContains(t, rec, "insert into `files`")
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		file := &models.File{
+			Name:         "a.txt",
+			OriginalName: "a.txt",
+			FolderID:     1,
+			UserID:       2,
+			FileObjectID: 3,
+		}
+		if err := repo.Create(context.Background(), nil, file); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "insert into `files`")
+	})
 }
 
 func TestGormFileRepository_GetByIDAndUser_BuildsSelectSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.GetByIDAndUser(context.Background(), nil, 7, 2, false)
-	if err != nil {
-		t.Fatalf("GetByIDAndUser failed: %v", err)
-	}
+		_, err := repo.GetByIDAndUser(context.Background(), nil, 7, 2, false)
+		if err != nil {
+			t.Fatalf("GetByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `files`", "where id = ? and user_id = ?")
+		assertLastSQLContains(t, rec, "from `files`", "where id = ? and user_id = ?")
+	})
 }
 
 func TestGormFileRepository_GetByIDAndUserUnscoped_BuildsUnscopedSelectSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.GetByIDAndUserUnscoped(context.Background(), nil, 7, 2, false)
-	if err != nil {
-		t.Fatalf("GetByIDAndUserUnscoped failed: %v", err)
-	}
+		_, err := repo.GetByIDAndUserUnscoped(context.Background(), nil, 7, 2, false)
+		if err != nil {
+			t.Fatalf("GetByIDAndUserUnscoped failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `files`", "where id = ? and user_id = ?")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "from `files`", "where id = ? and user_id = ?")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFileRepository_GetByIDsAndUser_BuildsINQuery(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	_, err := repo.GetByIDsAndUser(context.Background(), nil, 2, []uint{7, 8}, false)
-	if err != nil {
-		t.Fatalf("GetByIDsAndUser failed: %v", err)
-	}
+		_, err := repo.GetByIDsAndUser(context.Background(), nil, 2, []uint{7, 8}, false)
+		if err != nil {
+			t.Fatalf("GetByIDsAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and id in")
+		assertLastSQLContains(t, rec, "from `files`", "where user_id = ? and id in")
+	})
 }
 
 func TestGormFileRepository_UpdateByIDAndUser_BuildsUpdateSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UpdateByIDAndUser(context.Background(), nil, 7, 2, map[string]interface{}{"name": "b.txt"})
-	if err != nil {
-		t.Fatalf("UpdateByIDAndUser failed: %v", err)
-	}
+		err := repo.UpdateByIDAndUser(context.Background(), nil, 7, 2, map[string]interface{}{"name": "b.txt"})
+		if err != nil {
+			t.Fatalf("UpdateByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `files` set", "where id = ? and user_id = ?")
+		assertLastSQLContains(t, rec, "update `files` set", "where id = ? and user_id = ?")
+	})
 }
 
 func TestGormFileRepository_UpdateByIDsAndUser_EmptyIDs_NoSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UpdateByIDsAndUser(context.Background(), nil, nil, 2, map[string]interface{}{"name": "b.txt"})
-	if err != nil {
-		t.Fatalf("UpdateByIDsAndUser failed: %v", err)
-	}
+		err := repo.UpdateByIDsAndUser(context.Background(), nil, nil, 2, map[string]interface{}{"name": "b.txt"})
+		if err != nil {
+			t.Fatalf("UpdateByIDsAndUser failed: %v", err)
+		}
 
-	assertNoSQLCaptured(t, rec)
+		assertNoSQLCaptured(t, rec)
+	})
 }
 
 func TestGormFileRepository_UpdateByIDsAndUser_BuildsBatchUpdateSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UpdateByIDsAndUser(context.Background(), nil, []uint{1, 2}, 2, map[string]interface{}{"name": "b.txt"})
-	if err != nil {
-		t.Fatalf("UpdateByIDsAndUser failed: %v", err)
-	}
+		err := repo.UpdateByIDsAndUser(context.Background(), nil, []uint{1, 2}, 2, map[string]interface{}{"name": "b.txt"})
+		if err != nil {
+			t.Fatalf("UpdateByIDsAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `files` set", "where id in", "and user_id = ?")
+		assertLastSQLContains(t, rec, "update `files` set", "where id in", "and user_id = ?")
+	})
 }
 
 func TestGormFileRepository_SoftDeleteByIDAndUser_BuildsSoftDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.SoftDeleteByIDAndUser(context.Background(), nil, 7, 2)
-	if err != nil {
-		t.Fatalf("SoftDeleteByIDAndUser failed: %v", err)
-	}
+		err := repo.SoftDeleteByIDAndUser(context.Background(), nil, 7, 2)
+		if err != nil {
+			t.Fatalf("SoftDeleteByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `files` set `deleted_at`", "where (id = ? and user_id = ?)")
+		assertLastSQLContains(t, rec, "update `files` set `deleted_at`", "where (id = ? and user_id = ?)")
+	})
 }
 
 func TestGormFileRepository_SoftDeleteByFolderIDs_EmptyIDs_NoSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.SoftDeleteByFolderIDs(context.Background(), nil, 2, nil)
-	if err != nil {
-		t.Fatalf("SoftDeleteByFolderIDs failed: %v", err)
-	}
+		err := repo.SoftDeleteByFolderIDs(context.Background(), nil, 2, nil)
+		if err != nil {
+			t.Fatalf("SoftDeleteByFolderIDs failed: %v", err)
+		}
 
-	assertNoSQLCaptured(t, rec)
+		assertNoSQLCaptured(t, rec)
+	})
 }
 
 func TestGormFileRepository_SoftDeleteByFolderIDs_BuildsBatchSoftDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.SoftDeleteByFolderIDs(context.Background(), nil, 2, []uint{1, 2})
-	if err != nil {
-		t.Fatalf("SoftDeleteByFolderIDs failed: %v", err)
-	}
+		err := repo.SoftDeleteByFolderIDs(context.Background(), nil, 2, []uint{1, 2})
+		if err != nil {
+			t.Fatalf("SoftDeleteByFolderIDs failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `files` set `deleted_at`", "where (user_id = ? and folder_id in")
+		assertLastSQLContains(t, rec, "update `files` set `deleted_at`", "where (user_id = ? and folder_id in")
+	})
 }
 
 func TestGormFileRepository_UnscopedDeleteByIDAndUser_BuildsHardDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UnscopedDeleteByIDAndUser(context.Background(), nil, 7, 2)
-	if err != nil {
-		t.Fatalf("UnscopedDeleteByIDAndUser failed: %v", err)
-	}
+		err := repo.UnscopedDeleteByIDAndUser(context.Background(), nil, 7, 2)
+		if err != nil {
+			t.Fatalf("UnscopedDeleteByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "delete from `files`", "where id = ? and user_id = ?")
+		assertLastSQLContains(t, rec, "delete from `files`", "where id = ? and user_id = ?")
+	})
 }
 
 func TestGormFileRepository_UnscopedRestoreByIDAndUser_BuildsUnscopedUpdateSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UnscopedRestoreByIDAndUser(context.Background(), nil, 7, 2, map[string]interface{}{"deleted_at": nil, "folder_id": 1})
-	if err != nil {
-		t.Fatalf("UnscopedRestoreByIDAndUser failed: %v", err)
-	}
+		err := repo.UnscopedRestoreByIDAndUser(context.Background(), nil, 7, 2, map[string]interface{}{"deleted_at": nil, "folder_id": 1})
+		if err != nil {
+			t.Fatalf("UnscopedRestoreByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `files` set", "where id = ? and user_id = ?")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "update `files` set", "where id = ? and user_id = ?")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFileRepository_UnscopedRestoreByFolderIDs_EmptyIDs_NoSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UnscopedRestoreByFolderIDs(context.Background(), nil, 2, nil, map[string]interface{}{"deleted_at": nil})
-	if err != nil {
-		t.Fatalf("UnscopedRestoreByFolderIDs failed: %v", err)
-	}
+		err := repo.UnscopedRestoreByFolderIDs(context.Background(), nil, 2, nil, map[string]interface{}{"deleted_at": nil})
+		if err != nil {
+			t.Fatalf("UnscopedRestoreByFolderIDs failed: %v", err)
+		}
 
-	assertNoSQLCaptured(t, rec)
+		assertNoSQLCaptured(t, rec)
+	})
 }
 
 func TestGormFileRepository_UnscopedRestoreByFolderIDs_BuildsBatchUpdateSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
 
-	err := repo.UnscopedRestoreByFolderIDs(context.Background(), nil, 2, []uint{1, 2}, map[string]interface{}{"deleted_at": nil})
-	if err != nil {
-		t.Fatalf("UnscopedRestoreByFolderIDs failed: %v", err)
-	}
+		err := repo.UnscopedRestoreByFolderIDs(context.Background(), nil, 2, []uint{1, 2}, map[string]interface{}{"deleted_at": nil})
+		if err != nil {
+			t.Fatalf("UnscopedRestoreByFolderIDs failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `files` set", "where user_id = ? and folder_id in")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "update `files` set", "where user_id = ? and folder_id in")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFileRepository_FindByUserAndMD5_BuildsJoinSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFileRepository(db)
-
-	_, err := repo.FindByUserAndMD5(context.Background(), nil, 2, "abc")
-	if err != nil {
-		t.Fatalf("FindByUserAndMD5 failed: %v", err)
-	}
-
-	assertLastSQLContains(t, rec,
-		"from `file_objects`",
-		"join files on files.file_object_id = file_objects.id",
-		"files.user_id = ? and file_objects.file_md5 = ? and files.deleted_at is null",
-	)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+
+		_, err := repo.FindByUserAndMD5(context.Background(), nil, 2, "abc")
+		if err != nil {
+			t.Fatalf("FindByUserAndMD5 failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"from `file_objects`",
+			"join files on files.file_object_id = file_objects.id",
+			"files.user_id = ? and file_objects.file_md5 = ? and files.deleted_at is null",
+		)
+	})
 }
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"strings"
 
 	"mcloud/models"
 
@@ -16,43 +17,65 @@ func NewGormFolderRepository(db *gorm.DB) *GormFolderRepository {
 	return &GormFolderRepository{db: db}
 }
 
-func (r *GormFolderRepository) GetByIDAndUser(_ context.Context, tx *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
+func (r *GormFolderRepository) GetByIDAndUser(ctx context.Context, tx *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
 	var folder models.Folder
-	err := useTx(r.db, tx).Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error
+	err := useTx(ctx, r.db, tx).Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error
 	return folder, err
 }
 
-func (r *GormFolderRepository) GetByIDAndUserUnscoped(_ context.Context, tx *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
+func (r *GormFolderRepository) GetByIDAndUserUnscoped(ctx context.Context, tx *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
 	var folder models.Folder
-	err := useTx(r.db, tx).Unscoped().Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error
+	err := useTx(ctx, r.db, tx).Unscoped().Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error
 	return folder, err
 }
 
-func (r *GormFolderRepository) GetRootByUser(_ context.Context, tx *gorm.DB, userID uint) (models.Folder, error) {
+func (r *GormFolderRepository) GetRootByUser(ctx context.Context, tx *gorm.DB, userID uint) (models.Folder, error) {
 	var folder models.Folder
-	err := useTx(r.db, tx).Where("user_id = ? AND is_root = 1", userID).First(&folder).Error
+	err := useTx(ctx, r.db, tx).Where("user_id = ? AND is_root = ?", userID, true).First(&folder).Error
 	return folder, err
 }
 
-func (r *GormFolderRepository) Create(_ context.Context, tx *gorm.DB, folder *models.Folder) error {
-	return useTx(r.db, tx).Create(folder).Error
+func (r *GormFolderRepository) Create(ctx context.Context, tx *gorm.DB, folder *models.Folder) error {
+	return useTx(ctx, r.db, tx).Create(folder).Error
 }
 
-func (r *GormFolderRepository) ListByParent(_ context.Context, tx *gorm.DB, userID uint, parentID uint, includeLegacyRoot bool) ([]models.Folder, error) {
-	db := useTx(r.db, tx).Model(&models.Folder{}).Where("user_id = ?", userID)
+func (r *GormFolderRepository) parentQuery(db *gorm.DB, userID uint, parentID uint, includeLegacyRoot bool) *gorm.DB {
+	db = db.Model(&models.Folder{}).Where("user_id = ?", userID)
 	if includeLegacyRoot {
-		db = db.Where("((parent_id = ?) OR (parent_id IS NULL AND (is_root IS NULL OR is_root = 0)))", parentID)
-	} else {
-		db = db.Where("parent_id = ?", parentID)
+		return db.Where("((parent_id = ?) OR (parent_id IS NULL AND (is_root IS NULL OR is_root = ?)))", parentID, false)
 	}
+	return db.Where("parent_id = ?", parentID)
+}
+
+func (r *GormFolderRepository) ListByParent(ctx context.Context, tx *gorm.DB, userID uint, parentID uint, includeLegacyRoot bool) ([]models.Folder, error) {
+	db := r.parentQuery(useTx(ctx, r.db, tx), userID, parentID, includeLegacyRoot)
 
 	var folders []models.Folder
 	err := db.Order("name ASC").Find(&folders).Error
 	return folders, err
 }
 
-func (r *GormFolderRepository) CountByParentAndName(_ context.Context, tx *gorm.DB, userID uint, parentID uint, name string, excludeID uint) (int64, error) {
-	db := useTx(r.db, tx).Model(&models.Folder{}).
+// ListByParentAfter 按 (排序键, id) 游标分页列出子目录，仅支持 name 与 created_at 排序。
+func (r *GormFolderRepository) ListByParentAfter(ctx context.Context, tx *gorm.DB, in KeysetListInput) ([]models.Folder, error) {
+	db := r.parentQuery(useTx(ctx, r.db, tx), in.UserID, in.FolderID, in.IncludeLegacyRoot)
+	db = applyTagFilter(db, models.TagTargetFolder, "folders.id", in.Tags)
+
+	sortCol := "name"
+	if in.SortBy == "created_at" {
+		sortCol = "created_at"
+	}
+	desc := strings.ToUpper(in.Order) == "DESC"
+	if in.AfterKey != nil {
+		db = db.Where(keysetCondition(sortCol, "id", desc), in.AfterKey, in.AfterKey, in.AfterID)
+	}
+
+	var folders []models.Folder
+	err := db.Order(keysetOrder(sortCol, "id", desc)).Limit(in.Limit).Find(&folders).Error
+	return folders, err
+}
+
+func (r *GormFolderRepository) CountByParentAndName(ctx context.Context, tx *gorm.DB, userID uint, parentID uint, name string, excludeID uint) (int64, error) {
+	db := useTx(ctx, r.db, tx).Model(&models.Folder{}).
 		Where("user_id = ? AND parent_id = ? AND name = ?", userID, parentID, name)
 	if excludeID > 0 {
 		db = db.Where("id <> ?", excludeID)
@@ -62,40 +85,107 @@ func (r *GormFolderRepository) CountByParentAndName(_ context.Context, tx *gorm.
 	return count, err
 }
 
-func (r *GormFolderRepository) UpdateByID(_ context.Context, tx *gorm.DB, folderID uint, updates map[string]interface{}) error {
-	return useTx(r.db, tx).Model(&models.Folder{}).Where("id = ?", folderID).Updates(updates).Error
+// GetByParentAndName 查找父目录下同名的活动子目录。
+func (r *GormFolderRepository) GetByParentAndName(ctx context.Context, tx *gorm.DB, userID uint, parentID uint, name string, excludeID uint) (models.Folder, error) {
+	db := useTx(ctx, r.db, tx).Where("user_id = ? AND parent_id = ? AND name = ?", userID, parentID, name)
+	if excludeID > 0 {
+		db = db.Where("id <> ?", excludeID)
+	}
+	var folder models.Folder
+	err := db.First(&folder).Error
+	return folder, err
 }
 
-func (r *GormFolderRepository) UpdateByIDUnscoped(_ context.Context, tx *gorm.DB, folderID uint, updates map[string]interface{}) error {
-	return useTx(r.db, tx).Unscoped().Model(&models.Folder{}).Where("id = ?", folderID).Updates(updates).Error
+// ListByUser 按路径顺序列出用户全部活动目录，供构建目录树使用。
+func (r *GormFolderRepository) ListByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := useTx(ctx, r.db, tx).Where("user_id = ?", userID).Order("path ASC").Find(&folders).Error
+	return folders, err
 }
 
-func (r *GormFolderRepository) ListByPathPrefix(_ context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string, unscoped bool) ([]models.Folder, error) {
-	db := useTx(r.db, tx)
+// ListByPaths 按完整路径批量查找活动目录，结果按路径排序。
+func (r *GormFolderRepository) ListByPaths(ctx context.Context, tx *gorm.DB, userID uint, paths []string) ([]models.Folder, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	var folders []models.Folder
+	db := useTx(ctx, r.db, tx)
+	err := db.Where("user_id = ? AND "+caseSensitivePath(db, "path")+" IN ?", userID, paths).Order("path ASC").Find(&folders).Error
+	return folders, err
+}
+
+func (r *GormFolderRepository) GetByIDsAndUser(ctx context.Context, tx *gorm.DB, userID uint, folderIDs []uint) ([]models.Folder, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+	var folders []models.Folder
+	err := useTx(ctx, r.db, tx).Where("user_id = ? AND id IN ?", userID, folderIDs).Find(&folders).Error
+	return folders, err
+}
+
+func (r *GormFolderRepository) UpdateByID(ctx context.Context, tx *gorm.DB, folderID uint, updates map[string]interface{}) error {
+	return useTx(ctx, r.db, tx).Model(&models.Folder{}).Where("id = ?", folderID).Updates(updates).Error
+}
+
+func (r *GormFolderRepository) UpdateByIDUnscoped(ctx context.Context, tx *gorm.DB, folderID uint, updates map[string]interface{}) error {
+	return useTx(ctx, r.db, tx).Unscoped().Model(&models.Folder{}).Where("id = ?", folderID).Updates(updates).Error
+}
+
+func (r *GormFolderRepository) ListByPathPrefix(ctx context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string, unscoped bool) ([]models.Folder, error) {
+	db := useTx(ctx, r.db, tx)
 	if unscoped {
 		db = db.Unscoped()
 	}
 
 	var folders []models.Folder
-	err := db.Where("user_id = ? AND (id = ? OR path LIKE ?)", userID, rootID, rootPath+"/%").Find(&folders).Error
+	err := db.Where(subtreeCondition(db), userID, rootID, subtreePathPattern(rootPath)).Find(&folders).Error
 	return folders, err
 }
 
-func (r *GormFolderRepository) PluckIDsByPathPrefix(_ context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string) ([]uint, error) {
+func (r *GormFolderRepository) PluckIDsByPathPrefix(ctx context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string) ([]uint, error) {
 	var ids []uint
-	err := useTx(r.db, tx).Model(&models.Folder{}).
-		Where("user_id = ? AND (id = ? OR path LIKE ?)", userID, rootID, rootPath+"/%").
+	db := useTx(ctx, r.db, tx)
+	err := db.Model(&models.Folder{}).
+		Where(subtreeCondition(db), userID, rootID, subtreePathPattern(rootPath)).
 		Pluck("id", &ids).Error
 	return ids, err
 }
 
-func (r *GormFolderRepository) SoftDeleteByPathPrefix(_ context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string) error {
-	return useTx(r.db, tx).Where("user_id = ? AND (id = ? OR path LIKE ?)", userID, rootID, rootPath+"/%").Delete(&models.Folder{}).Error
+func (r *GormFolderRepository) SoftDeleteByPathPrefix(ctx context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string) error {
+	db := useTx(ctx, r.db, tx)
+	return db.Where(subtreeCondition(db), userID, rootID, subtreePathPattern(rootPath)).Delete(&models.Folder{}).Error
 }
 
-func (r *GormFolderRepository) UnscopedDeleteByIDs(_ context.Context, tx *gorm.DB, folderIDs []uint) error {
+func (r *GormFolderRepository) UnscopedDeleteByIDs(ctx context.Context, tx *gorm.DB, folderIDs []uint) error {
 	if len(folderIDs) == 0 {
 		return nil
 	}
-	return useTx(r.db, tx).Unscoped().Where("id IN ?", folderIDs).Delete(&models.Folder{}).Error
+	return useTx(ctx, r.db, tx).Unscoped().Where("id IN ?", folderIDs).Delete(&models.Folder{}).Error
+}
+
+// searchQuery 构造全盘目录搜索条件，根目录不参与搜索。
+func (r *GormFolderRepository) searchQuery(db *gorm.DB, in SearchInput) *gorm.DB {
+	query := db.Model(&models.Folder{}).
+		Where("folders.user_id = ? AND (folders.is_root IS NULL OR folders.is_root = ?)", in.UserID, false)
+	if in.Filter.Keyword != "" {
+		query = query.Where("LOWER(folders.name) LIKE ? ESCAPE '!'", containsPattern(in.Filter.Keyword))
+	}
+	return applyTagFilter(query, models.TagTargetFolder, "folders.id", in.Filter.Tags)
+}
+
+func (r *GormFolderRepository) CountSearch(ctx context.Context, tx *gorm.DB, in SearchInput) (int64, error) {
+	var total int64
+	err := r.searchQuery(useTx(ctx, r.db, tx), in).Count(&total).Error
+	return total, err
+}
+
+// Search 按名称排序分页搜索用户的正常目录。
+func (r *GormFolderRepository) Search(ctx context.Context, tx *gorm.DB, in SearchInput) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := r.searchQuery(useTx(ctx, r.db, tx), in).
+		Order("folders.name ASC, folders.id ASC").
+		Offset(in.Offset).
+		Limit(in.Limit).
+		Find(&folders).Error
+	return folders, err
 }
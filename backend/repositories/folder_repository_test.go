@@ -5,211 +5,342 @@ import (
 	"testing"
 
 	"mcloud/models"
+
+	"gorm.io/gorm"
 )
 
 func TestGormFolderRepository_GetByIDAndUser_BuildsSelectSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.GetByIDAndUser(context.Background(), nil, 10, 2)
-	if err != nil {
-		t.Fatalf("GetByIDAndUser failed: %v", err)
-	}
+		_, err := repo.GetByIDAndUser(context.Background(), nil, 10, 2)
+		if err != nil {
+			t.Fatalf("GetByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "where id = ? and user_id = ?")
+		assertLastSQLContains(t, rec, "from `folders`", "where id = ? and user_id = ?")
+	})
 }
 
 func TestGormFolderRepository_GetByIDAndUserUnscoped_BuildsUnscopedSelectSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.GetByIDAndUserUnscoped(context.Background(), nil, 10, 2)
-	if err != nil {
-		t.Fatalf("GetByIDAndUserUnscoped failed: %v", err)
-	}
+		_, err := repo.GetByIDAndUserUnscoped(context.Background(), nil, 10, 2)
+		if err != nil {
+			t.Fatalf("GetByIDAndUserUnscoped failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "where id = ? and user_id = ?")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "from `folders`", "where id = ? and user_id = ?")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFolderRepository_GetRootByUser_BuildsRootLookupSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.GetRootByUser(context.Background(), nil, 9)
-	if err != nil {
-		t.Fatalf("GetRootByUser failed: %v", err)
-	}
+		_, err := repo.GetRootByUser(context.Background(), nil, 9)
+		if err != nil {
+			t.Fatalf("GetRootByUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "user_id = ?", "is_root = 1")
+		assertLastSQLContains(t, rec, "from `folders`", "user_id = ?", "is_root = true")
+	})
 }
 
 func TestGormFolderRepository_Create_BuildsInsertSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
-
-	folder := &models.Folder{
-		Name:   "docs",
-		UserID: 2,
-		Path:   "/docs",
-	}
-	if err := repo.Create(context.Background(), nil, folder); err != nil {
-		t.Fatalf("Create failed: %v", err)
-	}
-
-	assertLastSQLContains(t, rec, "insert into `folders`")
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		folder := &models.Folder{
+			Name:   "docs",
+			UserID: 2,
+			Path:   "/docs",
+		}
+		if err := repo.Create(context.Background(), nil, folder); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "insert into `folders`")
+	})
 }
 
 func TestGormFolderRepository_ListByParent_NormalParent_BuildsScopedSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.ListByParent(context.Background(), nil, 3, 8, false)
-	if err != nil {
-		t.Fatalf("ListByParent failed: %v", err)
-	}
+		_, err := repo.ListByParent(context.Background(), nil, 3, 8, false)
+		if err != nil {
+			t.Fatalf("ListByParent failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "where user_id = ? and parent_id = ?", "order by name asc")
-	assertLastSQLNotContains(t, rec, "parent_id is null")
+		assertLastSQLContains(t, rec, "from `folders`", "where user_id = ? and parent_id = ?", "order by name asc")
+		assertLastSQLNotContains(t, rec, "parent_id is null")
+	})
 }
 
 func TestGormFolderRepository_ListByParent_IncludeLegacyRoot_BuildsCompatSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.ListByParent(context.Background(), nil, 3, 8, true)
-	if err != nil {
-		t.Fatalf("ListByParent with legacy root failed: %v", err)
-	}
+		_, err := repo.ListByParent(context.Background(), nil, 3, 8, true)
+		if err != nil {
+			t.Fatalf("ListByParent with legacy root failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "where user_id = ?", "parent_id is null", "is_root is null", "order by name asc")
+		assertLastSQLContains(t, rec, "from `folders`", "where user_id = ?", "parent_id is null", "is_root is null", "order by name asc")
+	})
 }
 
-func TestGormFolderRepository_CountByParentAndName_WithExclude_BuildsExcludeSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+func TestGormFolderRepository_ListByParentAfter_WithCursor_BuildsKeysetSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.ListByParentAfter(context.Background(), nil, KeysetListInput{
+			UserID:   3,
+			FolderID: 8,
+			SortBy:   "name",
+			Order:    "asc",
+			AfterKey: "docs",
+			AfterID:  12,
+			Limit:    21,
+		})
+		if err != nil {
+			t.Fatalf("ListByParentAfter failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"from `folders`",
+			"where user_id = ? and parent_id = ?",
+			"name > ? or  name = ? and id > ?",
+			"order by name asc, id asc",
+			"limit",
+		)
+	})
+}
 
-	count, err := repo.CountByParentAndName(context.Background(), nil, 2, 5, "docs", 99)
-	if err != nil {
-		t.Fatalf("CountByParentAndName failed: %v", err)
-	}
-	if count != 0 {
-		t.Fatalf("expected dry-run count 0, got %d", count)
-	}
+func TestGormFolderRepository_ListByParentAfter_FirstPageDesc_OmitsKeyset(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.ListByParentAfter(context.Background(), nil, KeysetListInput{
+			UserID:            3,
+			FolderID:          1,
+			IncludeLegacyRoot: true,
+			SortBy:            "created_at",
+			Order:             "desc",
+			Limit:             21,
+		})
+		if err != nil {
+			t.Fatalf("ListByParentAfter failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "parent_id is null", "order by created_at desc, id desc")
+		assertLastSQLNotContains(t, rec, "created_at <")
+	})
+}
 
-	assertLastSQLContains(t, rec, "select count(*)", "from `folders`", "user_id = ?", "parent_id = ?", "name = ?", "id <> ?")
+func TestGormFolderRepository_CountByParentAndName_WithExclude_BuildsExcludeSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		count, err := repo.CountByParentAndName(context.Background(), nil, 2, 5, "docs", 99)
+		if err != nil {
+			t.Fatalf("CountByParentAndName failed: %v", err)
+		}
+		if count != 0 {
+			t.Fatalf("expected dry-run count 0, got %d", count)
+		}
+
+		assertLastSQLContains(t, rec, "select count(*)", "from `folders`", "user_id = ?", "parent_id = ?", "name = ?", "id <> ?")
+	})
 }
 
 func TestGormFolderRepository_CountByParentAndName_WithoutExclude_OmitsExcludeCondition(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.CountByParentAndName(context.Background(), nil, 2, 5, "docs", 0)
+		if err != nil {
+			t.Fatalf("CountByParentAndName failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "select count(*)", "from `folders`", "name = ?")
+		assertLastSQLNotContains(t, rec, "id <> ?")
+	})
+}
+
+func TestGormFolderRepository_GetByParentAndName_ScopedWithExclude(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.GetByParentAndName(context.Background(), nil, 2, 5, "docs", 99)
+		if err != nil {
+			t.Fatalf("GetByParentAndName failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `folders`", "user_id = ? and parent_id = ? and name = ?", "id <> ?", "deleted_at is null")
+	})
+}
+
+func TestGormFolderRepository_ListByUser_OrdersByPath(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.CountByParentAndName(context.Background(), nil, 2, 5, "docs", 0)
-	if err != nil {
-		t.Fatalf("CountByParentAndName failed: %v", err)
-	}
+		_, err := repo.ListByUser(context.Background(), nil, 2)
+		if err != nil {
+			t.Fatalf("ListByUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "select count(*)", "from `folders`", "name = ?")
-	assertLastSQLNotContains(t, rec, "id <> ?")
+		assertLastSQLContains(t, rec, "from `folders`", "where user_id = ?", "deleted_at is null", "order by path asc")
+	})
+}
+
+func TestGormFolderRepository_ListByPaths_Empty_NoSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		folders, err := repo.ListByPaths(context.Background(), nil, 2, nil)
+		if err != nil || folders != nil {
+			t.Fatalf("expected empty result without error, got %v %v", folders, err)
+		}
+
+		assertNoSQLCaptured(t, rec)
+	})
+}
+
+func TestGormFolderRepository_ListByPaths_BuildsInSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.ListByPaths(context.Background(), nil, 2, []string{"/", "/a", "/a/b"})
+		if err != nil {
+			t.Fatalf("ListByPaths failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `folders`", "user_id = ? and "+pathColumnSQL(db, "path")+" in (?,?,?)", "deleted_at is null", "order by path asc")
+	})
+}
+
+func TestGormFolderRepository_GetByIDsAndUser_BuildsScopedInSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.GetByIDsAndUser(context.Background(), nil, 2, []uint{5, 6})
+		if err != nil {
+			t.Fatalf("GetByIDsAndUser failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `folders`", "user_id = ? and id in (?,?)", "deleted_at is null")
+	})
 }
 
 func TestGormFolderRepository_UpdateByID_BuildsUpdateSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	err := repo.UpdateByID(context.Background(), nil, 6, map[string]interface{}{"name": "new-name"})
-	if err != nil {
-		t.Fatalf("UpdateByID failed: %v", err)
-	}
+		err := repo.UpdateByID(context.Background(), nil, 6, map[string]interface{}{"name": "new-name"})
+		if err != nil {
+			t.Fatalf("UpdateByID failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `folders` set", "where id = ?")
+		assertLastSQLContains(t, rec, "update `folders` set", "where id = ?")
+	})
 }
 
 func TestGormFolderRepository_UpdateByIDUnscoped_BuildsUnscopedUpdateSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	err := repo.UpdateByIDUnscoped(context.Background(), nil, 6, map[string]interface{}{"name": "new-name"})
-	if err != nil {
-		t.Fatalf("UpdateByIDUnscoped failed: %v", err)
-	}
+		err := repo.UpdateByIDUnscoped(context.Background(), nil, 6, map[string]interface{}{"name": "new-name"})
+		if err != nil {
+			t.Fatalf("UpdateByIDUnscoped failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `folders` set", "where id = ?")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "update `folders` set", "where id = ?")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFolderRepository_ListByPathPrefix_Scoped_BuildsPathSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.ListByPathPrefix(context.Background(), nil, 2, 8, "/a/b", false)
-	if err != nil {
-		t.Fatalf("ListByPathPrefix failed: %v", err)
-	}
+		_, err := repo.ListByPathPrefix(context.Background(), nil, 2, 8, "/a/b", false)
+		if err != nil {
+			t.Fatalf("ListByPathPrefix failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "user_id = ?", "id = ? or path like ?")
+		assertLastSQLContains(t, rec, "from `folders`", "user_id = ?", "id = ? or "+pathColumnSQL(db, "path")+" like ?")
+	})
 }
 
 func TestGormFolderRepository_ListByPathPrefix_Unscoped_OmitsSoftDeleteFilter(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	_, err := repo.ListByPathPrefix(context.Background(), nil, 2, 8, "/a/b", true)
-	if err != nil {
-		t.Fatalf("ListByPathPrefix unscoped failed: %v", err)
-	}
+		_, err := repo.ListByPathPrefix(context.Background(), nil, 2, 8, "/a/b", true)
+		if err != nil {
+			t.Fatalf("ListByPathPrefix unscoped failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `folders`", "id = ? or path like ?")
-	assertLastSQLNotContains(t, rec, "deleted_at is null")
+		assertLastSQLContains(t, rec, "from `folders`", "id = ? or "+pathColumnSQL(db, "path")+" like ?")
+		assertLastSQLNotContains(t, rec, "deleted_at is null")
+	})
 }
 
 func TestGormFolderRepository_PluckIDsByPathPrefix_BuildsPluckSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
-
-	ids, err := repo.PluckIDsByPathPrefix(context.Background(), nil, 2, 8, "/a/b")
-	if err != nil {
-		t.Fatalf("PluckIDsByPathPrefix failed: %v", err)
-	}
-	if len(ids) != 0 {
-		t.Fatalf("expected dry-run empty ids, got %v", ids)
-	}
-
-	assertLastSQLContains(t, rec, "select `id` from `folders`", "user_id = ?", "id = ? or path like ?")
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		ids, err := repo.PluckIDsByPathPrefix(context.Background(), nil, 2, 8, "/a/b")
+		if err != nil {
+			t.Fatalf("PluckIDsByPathPrefix failed: %v", err)
+		}
+		if len(ids) != 0 {
+			t.Fatalf("expected dry-run empty ids, got %v", ids)
+		}
+
+		assertLastSQLContains(t, rec, "select `id` from `folders`", "user_id = ?", "id = ? or "+pathColumnSQL(db, "path")+" like ?")
+	})
 }
 
 func TestGormFolderRepository_SoftDeleteByPathPrefix_BuildsSoftDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	err := repo.SoftDeleteByPathPrefix(context.Background(), nil, 2, 8, "/a/b")
-	if err != nil {
-		t.Fatalf("SoftDeleteByPathPrefix failed: %v", err)
-	}
+		err := repo.SoftDeleteByPathPrefix(context.Background(), nil, 2, 8, "/a/b")
+		if err != nil {
+			t.Fatalf("SoftDeleteByPathPrefix failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "update `folders` set `deleted_at`", "where (user_id = ? and (id = ? or path like ?))")
+		assertLastSQLContains(t, rec, "update `folders` set `deleted_at`", "where (user_id = ? and (id = ? or "+pathColumnSQL(db, "path")+" like ?))")
+	})
 }
 
 func TestGormFolderRepository_UnscopedDeleteByIDs_Empty_NoSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	err := repo.UnscopedDeleteByIDs(context.Background(), nil, nil)
-	if err != nil {
-		t.Fatalf("UnscopedDeleteByIDs failed: %v", err)
-	}
+		err := repo.UnscopedDeleteByIDs(context.Background(), nil, nil)
+		if err != nil {
+			t.Fatalf("UnscopedDeleteByIDs failed: %v", err)
+		}
 
-	assertNoSQLCaptured(t, rec)
+		assertNoSQLCaptured(t, rec)
+	})
 }
 
 func TestGormFolderRepository_UnscopedDeleteByIDs_BuildsDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormFolderRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
 
-	err := repo.UnscopedDeleteByIDs(context.Background(), nil, []uint{1, 2, 3})
-	if err != nil {
-		t.Fatalf("UnscopedDeleteByIDs failed: %v", err)
-	}
+		err := repo.UnscopedDeleteByIDs(context.Background(), nil, []uint{1, 2, 3})
+		if err != nil {
+			t.Fatalf("UnscopedDeleteByIDs failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "delete from `folders`", "where id in")
+		assertLastSQLContains(t, rec, "delete from `folders`", "where id in")
+	})
 }
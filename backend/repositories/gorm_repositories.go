@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
@@ -15,17 +16,42 @@ func NewGormTxManager(db *gorm.DB) *GormTxManager {
 	return &GormTxManager{db: db}
 }
 
-func (m *GormTxManager) WithTransaction(_ context.Context, fn func(tx *gorm.DB) error) error {
-	return m.db.Transaction(fn)
+func (m *GormTxManager) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(fn)
+}
+
+const (
+	UploadProgressStoreRedis    = "redis"
+	UploadProgressStoreMemory   = "memory"
+	UploadProgressStoreDatabase = "database"
+	UploadProgressStoreNone     = "none"
+)
+
+// UploadProgressOptions 选择上传进度存储；Store 为 redis 时 Fallback 用于 Redis 不可用期间。
+type UploadProgressOptions struct {
+	Store         string
+	Fallback      string
+	RetryInterval time.Duration
 }
 
 type GormRepositories struct {
-	db    *gorm.DB
-	redis *redis.Client
+	db       *gorm.DB
+	redis    *redis.Client
+	progress UploadProgressOptions
 }
 
 func NewGormRepositories(db *gorm.DB, redisClient *redis.Client) *GormRepositories {
-	return &GormRepositories{db: db, redis: redisClient}
+	return &GormRepositories{
+		db:       db,
+		redis:    redisClient,
+		progress: UploadProgressOptions{Store: UploadProgressStoreRedis, Fallback: UploadProgressStoreMemory},
+	}
+}
+
+// WithUploadProgress 覆盖默认的上传进度存储选择。
+func (r *GormRepositories) WithUploadProgress(opts UploadProgressOptions) *GormRepositories {
+	r.progress = opts
+	return r
 }
 
 func (r *GormRepositories) BuildContainer() Container {
@@ -37,13 +63,51 @@ func (r *GormRepositories) BuildContainer() Container {
 		FileObjects:    NewGormFileObjectRepository(r.db),
 		UploadTasks:    NewGormUploadTaskRepository(r.db),
 		RecycleBin:     NewGormRecycleBinRepository(r.db),
-		UploadProgress: NewRedisUploadProgressRepository(r.redis),
+		UploadProgress: r.buildUploadProgress(),
+		StorageStats:   NewGormStorageStatsRepository(r.db),
+		Favorites:      NewGormFavoriteRepository(r.db),
+		FileAccesses:   NewGormFileAccessRepository(r.db),
+		Tags:           NewGormTagRepository(r.db),
+		ImageMetadata:  NewGormImageMetadataRepository(r.db),
+		MediaMetadata:  NewGormMediaMetadataRepository(r.db),
+		Albums:         NewGormAlbumRepository(r.db),
+	}
+}
+
+// buildUploadProgress 按配置构造进度存储；未配置 Redis 客户端时直接使用备用存储。
+func (r *GormRepositories) buildUploadProgress() UploadProgressRepository {
+	switch r.progress.Store {
+	case UploadProgressStoreMemory, UploadProgressStoreDatabase, UploadProgressStoreNone:
+		return r.buildLocalUploadProgress(r.progress.Store)
+	}
+
+	fallback := r.buildLocalUploadProgress(r.progress.Fallback)
+	if r.redis == nil {
+		return fallback
+	}
+	primary := NewRedisUploadProgressRepository(r.redis)
+	if fallback == nil {
+		return primary
+	}
+	return NewFailoverUploadProgressRepository(primary, fallback, r.progress.RetryInterval)
+}
+
+// buildLocalUploadProgress 构造不依赖 Redis 的存储；none 返回 nil，服务层此时只扫描磁盘分片。
+func (r *GormRepositories) buildLocalUploadProgress(store string) UploadProgressRepository {
+	switch store {
+	case UploadProgressStoreDatabase:
+		return NewGormUploadProgressRepository(r.db)
+	case UploadProgressStoreNone:
+		return nil
+	default:
+		return NewMemoryUploadProgressRepository()
 	}
 }
 
-func useTx(db *gorm.DB, tx *gorm.DB) *gorm.DB {
+// useTx 优先使用外部事务，并绑定 ctx 以便 SQL 日志带上请求 ID、随请求取消。
+func useTx(ctx context.Context, db *gorm.DB, tx *gorm.DB) *gorm.DB {
 	if tx != nil {
-		return tx
+		return tx.WithContext(ctx)
 	}
-	return db
+	return db.WithContext(ctx)
 }
@@ -18,10 +18,14 @@ var (
 	_ UploadTaskRepository      = (*GormUploadTaskRepository)(nil)
 	_ RecycleBinRepository      = (*GormRecycleBinRepository)(nil)
 	_ UploadProgressRepository  = (*RedisUploadProgressRepository)(nil)
+	_ UploadProgressRepository  = (*MemoryUploadProgressRepository)(nil)
+	_ UploadProgressRepository  = (*GormUploadProgressRepository)(nil)
+	_ UploadProgressRepository  = (*FailoverUploadProgressRepository)(nil)
+	_ StorageStatsRepository    = (*GormStorageStatsRepository)(nil)
 )
 
 func TestGormTxManager_WithTransaction_Success(t *testing.T) {
-	db, _ := newDryRunMySQL(t)
+	db, _ := newDryRunDB(t, dialectSQLite)
 	manager := NewGormTxManager(db)
 
 	called := false
@@ -41,7 +45,7 @@ func TestGormTxManager_WithTransaction_Success(t *testing.T) {
 }
 
 func TestGormTxManager_WithTransaction_PropagatesError(t *testing.T) {
-	db, _ := newDryRunMySQL(t)
+	db, _ := newDryRunDB(t, dialectSQLite)
 	manager := NewGormTxManager(db)
 	wantErr := errors.New("boom")
 
@@ -54,7 +58,7 @@ func TestGormTxManager_WithTransaction_PropagatesError(t *testing.T) {
 }
 
 func TestGormRepositories_BuildContainer_WiresAllRepositories(t *testing.T) {
-	db, _ := newDryRunMySQL(t)
+	db, _ := newDryRunDB(t, dialectSQLite)
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:            "127.0.0.1:0",
 		Protocol:        2,
@@ -91,21 +95,89 @@ func TestGormRepositories_BuildContainer_WiresAllRepositories(t *testing.T) {
 	}
 }
 
+type useTxCtxKey struct{}
+
 func TestUseTx_ReturnsTxWhenProvided(t *testing.T) {
-	db, _ := newDryRunMySQL(t)
-	tx := db.Session(&gorm.Session{NewDB: true})
+	db, _ := newDryRunDB(t, dialectSQLite)
+	// 用 SkipDefaultTransaction 标记 tx，便于区分返回的是 tx 还是 db。
+	tx := db.Session(&gorm.Session{NewDB: true, SkipDefaultTransaction: true})
+	ctx := context.WithValue(context.Background(), useTxCtxKey{}, "tx")
 
-	got := useTx(db, tx)
-	if got != tx {
-		t.Fatalf("expected tx to be returned when provided")
+	got := useTx(ctx, db, tx)
+	if !got.SkipDefaultTransaction {
+		t.Fatalf("expected tx to be used when provided")
+	}
+	if got.Statement.Context != ctx {
+		t.Fatalf("expected ctx to be bound to tx")
 	}
 }
 
 func TestUseTx_FallsBackToDBWhenTxNil(t *testing.T) {
-	db, _ := newDryRunMySQL(t)
+	db, _ := newDryRunDB(t, dialectSQLite)
+	ctx := context.WithValue(context.Background(), useTxCtxKey{}, "db")
+
+	got := useTx(ctx, db, nil)
+	if got.SkipDefaultTransaction != db.SkipDefaultTransaction {
+		t.Fatalf("expected db to be used when tx is nil")
+	}
+	if got.Statement.Context != ctx {
+		t.Fatalf("expected ctx to be bound to db")
+	}
+}
+
+func TestGormRepositories_BuildContainer_SelectsUploadProgressStore(t *testing.T) {
+	db, _ := newDryRunDB(t, dialectSQLite)
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	t.Cleanup(func() { _ = redisClient.Close() })
+
+	cases := []struct {
+		name  string
+		redis *redis.Client
+		opts  UploadProgressOptions
+		check func(UploadProgressRepository) bool
+	}{
+		{
+			name:  "redis with memory fallback",
+			redis: redisClient,
+			opts:  UploadProgressOptions{Store: UploadProgressStoreRedis, Fallback: UploadProgressStoreMemory},
+			check: func(r UploadProgressRepository) bool { _, ok := r.(*FailoverUploadProgressRepository); return ok },
+		},
+		{
+			name:  "redis without fallback",
+			redis: redisClient,
+			opts:  UploadProgressOptions{Store: UploadProgressStoreRedis, Fallback: UploadProgressStoreNone},
+			check: func(r UploadProgressRepository) bool { _, ok := r.(*RedisUploadProgressRepository); return ok },
+		},
+		{
+			name:  "redis store but no client",
+			opts:  UploadProgressOptions{Store: UploadProgressStoreRedis, Fallback: UploadProgressStoreDatabase},
+			check: func(r UploadProgressRepository) bool { _, ok := r.(*GormUploadProgressRepository); return ok },
+		},
+		{
+			name:  "memory store ignores redis",
+			redis: redisClient,
+			opts:  UploadProgressOptions{Store: UploadProgressStoreMemory},
+			check: func(r UploadProgressRepository) bool { _, ok := r.(*MemoryUploadProgressRepository); return ok },
+		},
+		{
+			name:  "none store ignores redis",
+			redis: redisClient,
+			opts:  UploadProgressOptions{Store: UploadProgressStoreNone, Fallback: UploadProgressStoreMemory},
+			check: func(r UploadProgressRepository) bool { return r == nil },
+		},
+		{
+			name:  "no store at all",
+			opts:  UploadProgressOptions{Store: UploadProgressStoreRedis, Fallback: UploadProgressStoreNone},
+			check: func(r UploadProgressRepository) bool { return r == nil },
+		},
+	}
 
-	got := useTx(db, nil)
-	if got != db {
-		t.Fatalf("expected db to be returned when tx is nil")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			container := NewGormRepositories(db, tc.redis).WithUploadProgress(tc.opts).BuildContainer()
+			if !tc.check(container.UploadProgress) {
+				t.Fatalf("unexpected upload progress repository %T", container.UploadProgress)
+			}
+		})
 	}
 }
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormProgressSweepInterval 控制过期分片记录的惰性清理频率。
+const gormProgressSweepInterval = 10 * time.Minute
+
+// GormUploadProgressRepository 将分片进度写入数据库，多实例共享且无需 Redis。
+type GormUploadProgressRepository struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewGormUploadProgressRepository(db *gorm.DB) *GormUploadProgressRepository {
+	return &GormUploadProgressRepository{db: db}
+}
+
+// activeQuery 限定指定上传任务中未过期的分片记录。
+func (r *GormUploadProgressRepository) activeQuery(ctx context.Context, uploadID string) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.UploadChunkProgress{}).
+		Where("upload_id = ? AND (expires_at IS NULL OR expires_at > ?)", uploadID, time.Now())
+}
+
+func (r *GormUploadProgressRepository) IsChunkUploaded(ctx context.Context, uploadID string, chunkIndex int) (bool, error) {
+	var count int64
+	err := r.activeQuery(ctx, uploadID).Where("chunk_index = ?", chunkIndex).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormUploadProgressRepository) AddChunk(ctx context.Context, uploadID string, chunkIndex int, expireSeconds int) error {
+	r.sweepExpired(ctx)
+
+	var expiresAt *time.Time
+	if expireSeconds > 0 {
+		at := time.Now().Add(timeDurationSeconds(expireSeconds))
+		expiresAt = &at
+	}
+
+	db := r.db.WithContext(ctx)
+	row := models.UploadChunkProgress{UploadID: uploadID, ChunkIndex: chunkIndex, ExpiresAt: expiresAt}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return err
+	}
+	if expiresAt == nil {
+		return nil
+	}
+	// 与 Redis EXPIRE 语义一致：每次写入刷新整组过期时间。
+	return db.Model(&models.UploadChunkProgress{}).
+		Where("upload_id = ?", uploadID).
+		Update("expires_at", expiresAt).Error
+}
+
+func (r *GormUploadProgressRepository) UploadedCount(ctx context.Context, uploadID string) (int64, error) {
+	var count int64
+	err := r.activeQuery(ctx, uploadID).Count(&count).Error
+	return count, err
+}
+
+func (r *GormUploadProgressRepository) UploadedChunks(ctx context.Context, uploadID string) ([]int, error) {
+	result := []int{}
+	err := r.activeQuery(ctx, uploadID).Order("chunk_index ASC").Pluck("chunk_index", &result).Error
+	return result, err
+}
+
+func (r *GormUploadProgressRepository) Clear(ctx context.Context, uploadID string) error {
+	return r.db.WithContext(ctx).Where("upload_id = ?", uploadID).Delete(&models.UploadChunkProgress{}).Error
+}
+
+// sweepExpired 周期性删除过期记录，失败只影响表体积，不影响读写语义。
+func (r *GormUploadProgressRepository) sweepExpired(ctx context.Context) {
+	now := time.Now()
+	r.mu.Lock()
+	if now.Sub(r.lastSweep) < gormProgressSweepInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastSweep = now
+	r.mu.Unlock()
+
+	_ = r.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+		Delete(&models.UploadChunkProgress{}).Error
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormUploadProgressRepository_TracksChunks(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormUploadProgressRepository(db)
+		ctx := context.Background()
+		uploadID := "live-" + time.Now().Format("150405.000000000")
+		t.Cleanup(func() { _ = repo.Clear(ctx, uploadID) })
+
+		for _, idx := range []int{2, 0, 2} {
+			if err := repo.AddChunk(ctx, uploadID, idx, 60); err != nil {
+				t.Fatalf("AddChunk failed: %v", err)
+			}
+		}
+
+		uploaded, err := repo.IsChunkUploaded(ctx, uploadID, 2)
+		if err != nil || !uploaded {
+			t.Fatalf("expected chunk 2 to be uploaded, got %v, %v", uploaded, err)
+		}
+		count, err := repo.UploadedCount(ctx, uploadID)
+		if err != nil || count != 2 {
+			t.Fatalf("expected 2 chunks, got %d, %v", count, err)
+		}
+		chunks, err := repo.UploadedChunks(ctx, uploadID)
+		if err != nil || !reflect.DeepEqual(chunks, []int{0, 2}) {
+			t.Fatalf("expected chunks [0 2], got %v, %v", chunks, err)
+		}
+
+		if err := repo.Clear(ctx, uploadID); err != nil {
+			t.Fatalf("Clear failed: %v", err)
+		}
+		count, _ = repo.UploadedCount(ctx, uploadID)
+		if count != 0 {
+			t.Fatalf("expected progress to be cleared, got %d", count)
+		}
+	})
+}
+
+func TestGormUploadProgressRepository_IgnoresExpiredChunks(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormUploadProgressRepository(db)
+		ctx := context.Background()
+		uploadID := "expired-" + time.Now().Format("150405.000000000")
+		t.Cleanup(func() { _ = repo.Clear(ctx, uploadID) })
+
+		past := time.Now().Add(-time.Minute)
+		if err := db.Create(&models.UploadChunkProgress{UploadID: uploadID, ChunkIndex: 0, ExpiresAt: &past}).Error; err != nil {
+			t.Fatalf("seed expired chunk failed: %v", err)
+		}
+
+		uploaded, err := repo.IsChunkUploaded(ctx, uploadID, 0)
+		if err != nil || uploaded {
+			t.Fatalf("expected expired chunk to be ignored, got %v, %v", uploaded, err)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// photoTakenAtExpr 为时间线排序使用的拍摄时间：没有 EXIF 拍摄时间的图片按上传时间排列。
+const photoTakenAtExpr = "COALESCE(image_metadata.taken_at, files.created_at)"
+
+type GormImageMetadataRepository struct {
+	db *gorm.DB
+}
+
+func NewGormImageMetadataRepository(db *gorm.DB) *GormImageMetadataRepository {
+	return &GormImageMetadataRepository{db: db}
+}
+
+// Create 写入元数据；同一文件对象已有记录时保持原记录不变，上传与后台补录并发时不会报错。
+func (r *GormImageMetadataRepository) Create(ctx context.Context, tx *gorm.DB, meta *models.ImageMetadata) error {
+	return useTx(ctx, r.db, tx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "file_object_id"}}, DoNothing: true}).
+		Create(meta).Error
+}
+
+func (r *GormImageMetadataRepository) GetByFileObjectID(ctx context.Context, tx *gorm.DB, fileObjectID uint) (models.ImageMetadata, error) {
+	var meta models.ImageMetadata
+	err := useTx(ctx, r.db, tx).Where("file_object_id = ?", fileObjectID).First(&meta).Error
+	return meta, err
+}
+
+// ListUnparsedObjects 按 ID 升序列出尚未解析元数据的图片文件对象，供后台补录。
+func (r *GormImageMetadataRepository) ListUnparsedObjects(ctx context.Context, tx *gorm.DB, limit int) ([]models.FileObject, error) {
+	var objects []models.FileObject
+	err := useTx(ctx, r.db, tx).
+		Where("file_objects.is_image = ?", true).
+		Where("NOT EXISTS (SELECT 1 FROM image_metadata WHERE image_metadata.file_object_id = file_objects.id)").
+		Order("file_objects.id ASC").
+		Limit(limit).
+		Find(&objects).Error
+	return objects, err
+}
+
+// ListTimeline 按 (拍摄时间, id) 倒序游标分页列出用户正常状态的图片文件，并预加载文件对象与元数据。
+func (r *GormImageMetadataRepository) ListTimeline(ctx context.Context, tx *gorm.DB, in PhotoTimelineInput) ([]models.File, error) {
+	query := useTx(ctx, r.db, tx).Preload("FileObject.Metadata").Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Joins("LEFT JOIN image_metadata ON image_metadata.file_object_id = files.file_object_id").
+		Select("files.*").
+		Where("files.user_id = ? AND file_objects.is_image = ?", in.UserID, true)
+	if in.AfterTakenAt != nil {
+		query = query.Where(keysetCondition(photoTakenAtExpr, "files.id", true), *in.AfterTakenAt, *in.AfterTakenAt, in.AfterID)
+	}
+
+	var files []models.File
+	err := query.Order(keysetOrder(photoTakenAtExpr, "files.id", true)).Limit(in.Limit).Find(&files).Error
+	return files, err
+}
+
+// ListUnhashedObjects 按 ID 升序列出尚未计算感知哈希的图片文件对象，供后台补录。
+func (r *GormImageMetadataRepository) ListUnhashedObjects(ctx context.Context, tx *gorm.DB, limit int) ([]models.FileObject, error) {
+	var objects []models.FileObject
+	err := useTx(ctx, r.db, tx).
+		Where("is_image = ? AND perceptual_hash IS NULL", true).
+		Order("id ASC").
+		Limit(limit).
+		Find(&objects).Error
+	return objects, err
+}
+
+func (r *GormImageMetadataRepository) UpdatePerceptualHash(ctx context.Context, tx *gorm.DB, fileObjectID uint, hash string) error {
+	return useTx(ctx, r.db, tx).Model(&models.FileObject{}).
+		Where("id = ?", fileObjectID).
+		Update("perceptual_hash", hash).Error
+}
+
+// ListHashedImages 列出用户正常状态且已有感知哈希的图片文件，按 ID 升序，最多 limit 条，并预加载文件对象。
+func (r *GormImageMetadataRepository) ListHashedImages(ctx context.Context, tx *gorm.DB, userID uint, limit int) ([]models.File, error) {
+	var files []models.File
+	err := useTx(ctx, r.db, tx).Preload("FileObject").Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Select("files.*").
+		Where("files.user_id = ? AND file_objects.is_image = ?", userID, true).
+		Where("file_objects.perceptual_hash IS NOT NULL AND file_objects.perceptual_hash <> ''").
+		Order("files.id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
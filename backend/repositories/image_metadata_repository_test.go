@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormImageMetadataRepository_ListTimeline_BuildsKeysetSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormImageMetadataRepository(db)
+		after := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+		_, err := repo.ListTimeline(context.Background(), nil, PhotoTimelineInput{UserID: 2, AfterTakenAt: &after, AfterID: 9, Limit: 21})
+		if err != nil {
+			t.Fatalf("ListTimeline failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"left join image_metadata on image_metadata.file_object_id = files.file_object_id",
+			"files.user_id = 2 and file_objects.is_image = true",
+			"coalesce image_metadata.taken_at, files.created_at  < '2024-05-01 00:00:00'",
+			"files.id < 9",
+			"order by coalesce image_metadata.taken_at, files.created_at  desc, files.id desc",
+			"deleted_at is null",
+		)
+	})
+}
+
+func TestGormImageMetadataRepository_ListUnparsedObjects_BuildsNotExistsSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormImageMetadataRepository(db)
+
+		if _, err := repo.ListUnparsedObjects(context.Background(), nil, 200); err != nil {
+			t.Fatalf("ListUnparsedObjects failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"file_objects.is_image = true",
+			"not exists  select 1 from image_metadata where image_metadata.file_object_id = file_objects.id",
+			"order by file_objects.id asc",
+		)
+	})
+}
+
+func TestGormImageMetadataRepository_ListHashedImages_SkipsUndecodable(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormImageMetadataRepository(db)
+
+		if _, err := repo.ListHashedImages(context.Background(), nil, 2, 20001); err != nil {
+			t.Fatalf("ListHashedImages failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"join file_objects on file_objects.id = files.file_object_id",
+			"files.user_id = 2 and file_objects.is_image = true",
+			"file_objects.perceptual_hash is not null and file_objects.perceptual_hash <> ''",
+			"deleted_at is null",
+			"order by files.id asc",
+		)
+	})
+}
+
+func TestGormImageMetadataRepository_LivePerceptualHashBackfill(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormImageMetadataRepository(db)
+		userID := liveUserID(t, db)
+		var objectIDs []uint
+		t.Cleanup(func() {
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+			db.Where("id IN ?", objectIDs).Delete(&models.FileObject{})
+		})
+
+		var files []models.File
+		for _, name := range []string{"a.jpg", "b.jpg"} {
+			obj := models.FileObject{FilePath: "phash/" + name, FileSize: 10, IsImage: true, RefCount: 1}
+			if err := db.Create(&obj).Error; err != nil {
+				t.Fatalf("create object failed: %v", err)
+			}
+			objectIDs = append(objectIDs, obj.ID)
+			file := models.File{Name: name, OriginalName: name, FolderID: 1, UserID: userID, FileObjectID: obj.ID}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			files = append(files, file)
+		}
+
+		pending, err := repo.ListUnhashedObjects(ctx, nil, 1000)
+		if err != nil {
+			t.Fatalf("ListUnhashedObjects failed: %v", err)
+		}
+		found := 0
+		for _, obj := range pending {
+			if obj.ID == objectIDs[0] || obj.ID == objectIDs[1] {
+				found++
+			}
+		}
+		if found != 2 {
+			t.Fatalf("expected both objects pending, got %+v", pending)
+		}
+
+		// 空串表示无法解码：不再待补算，也不参与相似比较。
+		if err := repo.UpdatePerceptualHash(ctx, nil, objectIDs[0], "00ff00ff00ff00ff"); err != nil {
+			t.Fatalf("UpdatePerceptualHash failed: %v", err)
+		}
+		if err := repo.UpdatePerceptualHash(ctx, nil, objectIDs[1], ""); err != nil {
+			t.Fatalf("UpdatePerceptualHash failed: %v", err)
+		}
+		hashed, err := repo.ListHashedImages(ctx, nil, userID, 10)
+		if err != nil || len(hashed) != 1 || hashed[0].ID != files[0].ID || *hashed[0].FileObject.PerceptualHash != "00ff00ff00ff00ff" {
+			t.Fatalf("unexpected hashed images: %+v (%v)", hashed, err)
+		}
+		pending, err = repo.ListUnhashedObjects(ctx, nil, 1000)
+		if err != nil {
+			t.Fatalf("ListUnhashedObjects failed: %v", err)
+		}
+		for _, obj := range pending {
+			if obj.ID == objectIDs[0] || obj.ID == objectIDs[1] {
+				t.Fatalf("expected object %d to be settled", obj.ID)
+			}
+		}
+
+		// 进入回收站的图片不参与相似比较。
+		if err := db.Delete(&files[0]).Error; err != nil {
+			t.Fatalf("soft delete failed: %v", err)
+		}
+		if hashed, err := repo.ListHashedImages(ctx, nil, userID, 10); err != nil || len(hashed) != 0 {
+			t.Fatalf("expected recycled image to be skipped, got %+v (%v)", hashed, err)
+		}
+	})
+}
+
+func TestGormImageMetadataRepository_LiveTimelineAndCascade(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormImageMetadataRepository(db)
+		objects := NewGormFileObjectRepository(db)
+		userID := liveUserID(t, db)
+		var objectIDs []uint
+		t.Cleanup(func() {
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+			db.Where("file_object_id IN ?", objectIDs).Delete(&models.ImageMetadata{})
+			db.Where("id IN ?", objectIDs).Delete(&models.FileObject{})
+		})
+
+		uploaded := time.Now().Add(-time.Hour).Truncate(time.Second)
+		create := func(name string, isImage bool, takenAt *time.Time) models.File {
+			obj := models.FileObject{FilePath: name, FileSize: 1, IsImage: isImage, RefCount: 1}
+			if err := objects.Create(ctx, nil, &obj); err != nil {
+				t.Fatalf("create object failed: %v", err)
+			}
+			objectIDs = append(objectIDs, obj.ID)
+			if takenAt != nil {
+				if err := repo.Create(ctx, nil, &models.ImageMetadata{FileObjectID: obj.ID, TakenAt: takenAt, CameraModel: "X100V"}); err != nil {
+					t.Fatalf("create metadata failed: %v", err)
+				}
+			}
+			file := models.File{Name: name, OriginalName: name, FolderID: 1, UserID: userID, FileObjectID: obj.ID, CreatedAt: uploaded}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			return file
+		}
+		older := uploaded.Add(-48 * time.Hour)
+		newer := uploaded.Add(-time.Minute)
+		oldPhoto := create("old.jpg", true, &older)
+		undated := create("undated.png", true, nil)
+		recent := create("recent.jpg", true, &newer)
+		create("doc.txt", false, nil)
+
+		// 重复写入同一文件对象的元数据保持原记录。
+		if err := repo.Create(ctx, nil, &models.ImageMetadata{FileObjectID: recent.FileObjectID, CameraModel: "dup"}); err != nil {
+			t.Fatalf("repeated Create failed: %v", err)
+		}
+		meta, err := repo.GetByFileObjectID(ctx, nil, recent.FileObjectID)
+		if err != nil || meta.CameraModel != "X100V" {
+			t.Fatalf("expected original metadata to be kept, got %+v (%v)", meta, err)
+		}
+
+		page, err := repo.ListTimeline(ctx, nil, PhotoTimelineInput{UserID: userID, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListTimeline failed: %v", err)
+		}
+		if len(page) != 2 || page[0].ID != undated.ID || page[1].ID != recent.ID {
+			t.Fatalf("unexpected first page: %+v", page)
+		}
+		if page[1].FileObject.Metadata == nil || page[1].FileObject.Metadata.TakenAt == nil {
+			t.Fatalf("expected metadata to be preloaded, got %+v", page[1].FileObject)
+		}
+		after := *page[1].FileObject.Metadata.TakenAt
+		rest, err := repo.ListTimeline(ctx, nil, PhotoTimelineInput{UserID: userID, AfterTakenAt: &after, AfterID: page[1].ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListTimeline after cursor failed: %v", err)
+		}
+		if len(rest) != 1 || rest[0].ID != oldPhoto.ID {
+			t.Fatalf("unexpected second page: %+v", rest)
+		}
+
+		unparsed, err := repo.ListUnparsedObjects(ctx, nil, 100)
+		if err != nil {
+			t.Fatalf("ListUnparsedObjects failed: %v", err)
+		}
+		found := false
+		for _, obj := range unparsed {
+			if obj.ID == oldPhoto.FileObjectID || obj.ID == recent.FileObjectID {
+				t.Fatalf("parsed object listed as unparsed: %+v", obj)
+			}
+			found = found || obj.ID == undated.FileObjectID
+		}
+		if !found {
+			t.Fatalf("expected undated image to be listed, got %+v", unparsed)
+		}
+
+		// 删除文件对象时一并删除元数据。
+		if err := objects.DeleteByID(ctx, nil, oldPhoto.FileObjectID); err != nil {
+			t.Fatalf("DeleteByID failed: %v", err)
+		}
+		if _, err := repo.GetByFileObjectID(ctx, nil, oldPhoto.FileObjectID); err != gorm.ErrRecordNotFound {
+			t.Fatalf("expected metadata to be deleted, got %v", err)
+		}
+	})
+}
@@ -20,6 +20,7 @@ type UserRepository interface {
 	GetByID(ctx context.Context, tx *gorm.DB, userID uint) (models.User, error)
 	AddStorageUsed(ctx context.Context, tx *gorm.DB, userID uint, delta int64) error
 	SubStorageUsed(ctx context.Context, tx *gorm.DB, userID uint, delta int64) error
+	UpdateRecycleRetentionDays(ctx context.Context, tx *gorm.DB, userID uint, days *int) error
 }
 
 type FolderRepository interface {
@@ -29,12 +30,19 @@ type FolderRepository interface {
 	Create(ctx context.Context, tx *gorm.DB, folder *models.Folder) error
 	ListByParent(ctx context.Context, tx *gorm.DB, userID uint, parentID uint, includeLegacyRoot bool) ([]models.Folder, error)
 	CountByParentAndName(ctx context.Context, tx *gorm.DB, userID uint, parentID uint, name string, excludeID uint) (int64, error)
+	GetByParentAndName(ctx context.Context, tx *gorm.DB, userID uint, parentID uint, name string, excludeID uint) (models.Folder, error)
+	ListByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]models.Folder, error)
+	ListByPaths(ctx context.Context, tx *gorm.DB, userID uint, paths []string) ([]models.Folder, error)
+	GetByIDsAndUser(ctx context.Context, tx *gorm.DB, userID uint, folderIDs []uint) ([]models.Folder, error)
+	ListByParentAfter(ctx context.Context, tx *gorm.DB, in KeysetListInput) ([]models.Folder, error)
 	UpdateByID(ctx context.Context, tx *gorm.DB, folderID uint, updates map[string]interface{}) error
 	UpdateByIDUnscoped(ctx context.Context, tx *gorm.DB, folderID uint, updates map[string]interface{}) error
 	ListByPathPrefix(ctx context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string, unscoped bool) ([]models.Folder, error)
 	PluckIDsByPathPrefix(ctx context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string) ([]uint, error)
 	SoftDeleteByPathPrefix(ctx context.Context, tx *gorm.DB, userID uint, rootID uint, rootPath string) error
 	UnscopedDeleteByIDs(ctx context.Context, tx *gorm.DB, folderIDs []uint) error
+	CountSearch(ctx context.Context, tx *gorm.DB, in SearchInput) (int64, error)
+	Search(ctx context.Context, tx *gorm.DB, in SearchInput) ([]models.Folder, error)
 }
 
 type ListFilesInput struct {
@@ -46,12 +54,66 @@ type ListFilesInput struct {
 	Order             string
 	Offset            int
 	Limit             int
+	Tags              TagFilter
+}
+
+// KeysetListInput 为目录内游标分页参数，按 (排序键, id) 严格递增或递减取下一页。
+type KeysetListInput struct {
+	UserID            uint
+	FolderID          uint
+	RootFolderID      uint
+	IncludeLegacyRoot bool
+	SortBy            string
+	Order             string
+	// AfterKey 为上一页末条记录的排序键值，nil 表示从头开始。
+	AfterKey interface{}
+	AfterID  uint
+	Limit    int
+	Tags     TagFilter
+}
+
+// TagFilter 为按标签筛选的条件，TagIDs 为空时不过滤；TagIDs 须已去重。
+type TagFilter struct {
+	TagIDs []uint
+	// MatchAll 为 true 时要求带有全部标签（AND），否则带有任一标签即可（OR）。
+	MatchAll bool
+}
+
+// FileQuickFilter 为快捷视图的文件筛选条件，零值字段不参与过滤。
+type FileQuickFilter struct {
+	// Keyword 对原始文件名做不区分大小写的包含匹配。
+	Keyword string
+	// MimePrefixes 与 MimeTypes 任一命中即保留，二者都为空时不按类型过滤。
+	MimePrefixes []string
+	MimeTypes    []string
+	Tags         TagFilter
+}
+
+// SearchInput 为全盘搜索参数；目录只使用 Filter 中的 Keyword 与 Tags。
+type SearchInput struct {
+	UserID uint
+	Filter FileQuickFilter
+	Offset int
+	Limit  int
+}
+
+// RecentFilesInput 为最近上传/最近访问视图的查询参数，Since 限定上传或访问时间下限。
+type RecentFilesInput struct {
+	UserID uint
+	Filter FileQuickFilter
+	Since  *time.Time
+	Limit  int
 }
 
 type FileRepository interface {
-	CountByFolder(ctx context.Context, tx *gorm.DB, userID uint, folderID uint, rootFolderID uint, includeLegacyRoot bool) (int64, error)
+	CountByFolder(ctx context.Context, tx *gorm.DB, in ListFilesInput) (int64, error)
 	CountByFolderAndOriginalName(ctx context.Context, tx *gorm.DB, userID uint, folderID uint, originalName string, excludeID uint, unscoped bool) (int64, error)
+	GetByFolderAndOriginalName(ctx context.Context, tx *gorm.DB, userID uint, folderID uint, originalName string, excludeID uint) (models.File, error)
 	ListByFolder(ctx context.Context, tx *gorm.DB, in ListFilesInput) ([]models.File, error)
+	ListByFolderAfter(ctx context.Context, tx *gorm.DB, in KeysetListInput) ([]models.File, error)
+	ListRecentUploads(ctx context.Context, tx *gorm.DB, in RecentFilesInput) ([]models.File, error)
+	CountSearch(ctx context.Context, tx *gorm.DB, in SearchInput) (int64, error)
+	Search(ctx context.Context, tx *gorm.DB, in SearchInput) ([]models.File, error)
 	ListByFolderIDs(ctx context.Context, tx *gorm.DB, userID uint, folderIDs []uint, preloadObject bool, unscoped bool) ([]models.File, error)
 	Create(ctx context.Context, tx *gorm.DB, file *models.File) error
 	GetByIDAndUser(ctx context.Context, tx *gorm.DB, fileID uint, userID uint, preloadObject bool) (models.File, error)
@@ -97,13 +159,26 @@ type RecycleBinListInput struct {
 	SortSQL string
 }
 
+// RecycleBinFilter 为回收站批量操作的筛选条件，各字段之间为 AND 关系，零值字段不参与筛选。
+type RecycleBinFilter struct {
+	UserID        uint
+	IDs           []uint
+	OriginalType  string
+	DeletedBefore *time.Time
+	// PathPrefix 匹配原始完整路径等于该路径或位于其下。
+	PathPrefix string
+	Limit      int
+}
+
 type RecycleBinRepository interface {
 	CountByUser(ctx context.Context, tx *gorm.DB, userID uint) (int64, error)
 	ListByUser(ctx context.Context, tx *gorm.DB, in RecycleBinListInput) ([]models.RecycleBinItem, error)
+	ListByFilter(ctx context.Context, tx *gorm.DB, filter RecycleBinFilter) ([]models.RecycleBinItem, error)
 	ListAllByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]models.RecycleBinItem, error)
 	ListExpired(ctx context.Context, tx *gorm.DB, now time.Time) ([]models.RecycleBinItem, error)
 	GetByIDAndUser(ctx context.Context, tx *gorm.DB, itemID uint, userID uint) (models.RecycleBinItem, error)
 	Create(ctx context.Context, tx *gorm.DB, item *models.RecycleBinItem) error
+	UpdateExpiresAtByUser(ctx context.Context, tx *gorm.DB, userID uint, retentionDays int) error
 	DeleteByID(ctx context.Context, tx *gorm.DB, itemID uint) error
 	DeleteByUser(ctx context.Context, tx *gorm.DB, userID uint) error
 	DeleteByOriginalIDs(ctx context.Context, tx *gorm.DB, userID uint, originalType string, originalIDs []uint) error
@@ -117,6 +192,172 @@ type UploadProgressRepository interface {
 	Clear(ctx context.Context, uploadID string) error
 }
 
+// StorageTotals 为全站存储用量汇总。
+type StorageTotals struct {
+	Users           int64
+	QuotaBytes      int64
+	UsedBytes       int64
+	FileObjects     int64
+	FileObjectBytes int64
+	Files           int64
+	RecycleBinItems int64
+	RecycleBinBytes int64
+}
+
+// UserStorageUsage 为单个用户的文件用量汇总，已软删除（位于回收站）的文件单独统计。
+type UserStorageUsage struct {
+	ActiveFiles   int64
+	ActiveBytes   int64
+	RecycledFiles int64
+	RecycledBytes int64
+	// UniqueObjectBytes 为用户所有文件（含回收站）引用的去重后文件对象总大小。
+	UniqueObjectBytes int64
+}
+
+// MimeTypeUsage 为按 MIME 类型分组的用量。
+type MimeTypeUsage struct {
+	MimeType  string
+	FileCount int64
+	Bytes     int64
+}
+
+// FolderPathUsage 为按所在目录分组的用量，Path 为空表示文件不属于任何有效目录。
+type FolderPathUsage struct {
+	Path      string
+	FileCount int64
+	Bytes     int64
+}
+
+// FolderUsage 为单个目录直接包含的文件用量，不含子孙目录。
+type FolderUsage struct {
+	FolderID  uint
+	Path      string
+	FileCount int64
+	Bytes     int64
+}
+
+type StorageStatsRepository interface {
+	Totals(ctx context.Context) (StorageTotals, error)
+	UserUsage(ctx context.Context, userID uint) (UserStorageUsage, error)
+	UserUsageByMimeType(ctx context.Context, userID uint) ([]MimeTypeUsage, error)
+	UserUsageByFolder(ctx context.Context, userID uint) ([]FolderPathUsage, error)
+	UserFolderUsage(ctx context.Context, userID uint) ([]FolderUsage, error)
+}
+
+// FavoriteListInput 为收藏列表查询参数，TargetType 为空表示文件与目录都返回。
+type FavoriteListInput struct {
+	UserID     uint
+	TargetType string
+	Keyword    string
+	Offset     int
+	Limit      int
+}
+
+// FavoriteRepository 的列表与计数只返回目标仍处于正常状态的收藏，目标进入回收站或被删除后自动隐藏。
+type FavoriteRepository interface {
+	Add(ctx context.Context, tx *gorm.DB, favorite *models.Favorite) error
+	Remove(ctx context.Context, tx *gorm.DB, userID uint, targetType string, targetID uint) error
+	CountActive(ctx context.Context, tx *gorm.DB, in FavoriteListInput) (int64, error)
+	ListActive(ctx context.Context, tx *gorm.DB, in FavoriteListInput) ([]models.Favorite, error)
+	DeleteOrphans(ctx context.Context, tx *gorm.DB) (int64, error)
+}
+
+// FileAccessRepository 记录并查询用户最近访问的文件，列表同样只返回正常状态的文件。
+type FileAccessRepository interface {
+	Touch(ctx context.Context, tx *gorm.DB, userID uint, fileID uint, at time.Time) error
+	ListRecent(ctx context.Context, tx *gorm.DB, in RecentFilesInput) ([]models.FileAccess, error)
+	DeleteOrphans(ctx context.Context, tx *gorm.DB) (int64, error)
+}
+
+// TagUsage 为标签及其绑定的正常状态目标数量。
+type TagUsage struct {
+	models.Tag
+	ItemCount int64
+}
+
+// TargetTag 为某个目标上绑定的一个标签。
+type TargetTag struct {
+	TargetID uint
+	models.Tag
+}
+
+// TagRepository 管理标签及其与文件、目录的绑定；绑定按目标 ID 关联，目标彻底删除后由 DeleteOrphanBindings 回收。
+type TagRepository interface {
+	Create(ctx context.Context, tx *gorm.DB, tag *models.Tag) error
+	GetByIDAndUser(ctx context.Context, tx *gorm.DB, tagID uint, userID uint) (models.Tag, error)
+	GetByIDsAndUser(ctx context.Context, tx *gorm.DB, userID uint, tagIDs []uint) ([]models.Tag, error)
+	CountByUserAndName(ctx context.Context, tx *gorm.DB, userID uint, name string, excludeID uint) (int64, error)
+	ListUsageByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]TagUsage, error)
+	UpdateByID(ctx context.Context, tx *gorm.DB, tagID uint, updates map[string]interface{}) error
+	DeleteByIDs(ctx context.Context, tx *gorm.DB, userID uint, tagIDs []uint) error
+	Bind(ctx context.Context, tx *gorm.DB, bindings []models.TagBinding) error
+	Unbind(ctx context.Context, tx *gorm.DB, userID uint, tagIDs []uint, targetType string, targetIDs []uint) (int64, error)
+	MergeBindings(ctx context.Context, tx *gorm.DB, userID uint, targetTagID uint, sourceTagIDs []uint) error
+	ListByTargets(ctx context.Context, tx *gorm.DB, userID uint, targetType string, targetIDs []uint) ([]TargetTag, error)
+	DeleteOrphanBindings(ctx context.Context, tx *gorm.DB) (int64, error)
+}
+
+// PhotoTimelineInput 为照片时间线查询参数；AfterTakenAt 为空表示从最新一张开始。
+type PhotoTimelineInput struct {
+	UserID       uint
+	AfterTakenAt *time.Time
+	AfterID      uint
+	Limit        int
+}
+
+// ImageMetadataRepository 管理图片文件对象的 EXIF 元数据，并按拍摄时间查询用户的照片。
+// 时间线中没有拍摄时间的图片以上传时间代替，元数据随文件对象一起删除。
+type ImageMetadataRepository interface {
+	Create(ctx context.Context, tx *gorm.DB, meta *models.ImageMetadata) error
+	GetByFileObjectID(ctx context.Context, tx *gorm.DB, fileObjectID uint) (models.ImageMetadata, error)
+	ListUnparsedObjects(ctx context.Context, tx *gorm.DB, limit int) ([]models.FileObject, error)
+	ListTimeline(ctx context.Context, tx *gorm.DB, in PhotoTimelineInput) ([]models.File, error)
+	ListUnhashedObjects(ctx context.Context, tx *gorm.DB, limit int) ([]models.FileObject, error)
+	UpdatePerceptualHash(ctx context.Context, tx *gorm.DB, fileObjectID uint, hash string) error
+	ListHashedImages(ctx context.Context, tx *gorm.DB, userID uint, limit int) ([]models.File, error)
+}
+
+// MediaMetadataRepository 管理音视频文件对象的时长、编码与标签信息，元数据随文件对象一起删除。
+type MediaMetadataRepository interface {
+	Create(ctx context.Context, tx *gorm.DB, meta *models.MediaMetadata) error
+	GetByFileObjectID(ctx context.Context, tx *gorm.DB, fileObjectID uint) (models.MediaMetadata, error)
+	ListUnparsedObjects(ctx context.Context, tx *gorm.DB, limit int) ([]models.FileObject, error)
+	UpdateThumbnailPath(ctx context.Context, tx *gorm.DB, fileObjectID uint, thumbnailPath string) error
+}
+
+// AlbumSummary 为相册及其正常状态条目的统计，FirstFileID 为排序最靠前的正常状态文件。
+type AlbumSummary struct {
+	models.Album
+	ItemCount   int64
+	FirstFileID *uint
+}
+
+// AlbumItemsInput 为相册内容分页参数。
+type AlbumItemsInput struct {
+	AlbumID uint
+	Offset  int
+	Limit   int
+}
+
+// AlbumRepository 管理相册及其条目；条目按文件 ID 引用，Active 系列方法只返回正常状态的文件，
+// 文件进入回收站时条目保留，恢复后重新可见，文件被彻底删除后由 DeleteOrphanItems 回收。
+type AlbumRepository interface {
+	Create(ctx context.Context, tx *gorm.DB, album *models.Album) error
+	GetByIDAndUser(ctx context.Context, tx *gorm.DB, albumID uint, userID uint) (models.Album, error)
+	GetByShareToken(ctx context.Context, tx *gorm.DB, token string) (models.Album, error)
+	ListSummariesByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]AlbumSummary, error)
+	UpdateByID(ctx context.Context, tx *gorm.DB, albumID uint, updates map[string]interface{}) error
+	DeleteByID(ctx context.Context, tx *gorm.DB, albumID uint) error
+	AddItems(ctx context.Context, tx *gorm.DB, albumID uint, fileIDs []uint) (int64, error)
+	RemoveItems(ctx context.Context, tx *gorm.DB, albumID uint, fileIDs []uint) (int64, error)
+	ListItems(ctx context.Context, tx *gorm.DB, albumID uint) ([]models.AlbumItem, error)
+	UpdateItemPositions(ctx context.Context, tx *gorm.DB, albumID uint, positions map[uint]int) error
+	CountActiveItems(ctx context.Context, tx *gorm.DB, albumID uint) (int64, error)
+	ListActiveFiles(ctx context.Context, tx *gorm.DB, in AlbumItemsInput) ([]models.File, error)
+	HasActiveFile(ctx context.Context, tx *gorm.DB, albumID uint, fileID uint) (bool, error)
+	DeleteOrphanItems(ctx context.Context, tx *gorm.DB) (int64, error)
+}
+
 type Container struct {
 	TxManager      TxManager
 	Users          UserRepository
@@ -126,4 +367,11 @@ type Container struct {
 	UploadTasks    UploadTaskRepository
 	RecycleBin     RecycleBinRepository
 	UploadProgress UploadProgressRepository
+	StorageStats   StorageStatsRepository
+	Favorites      FavoriteRepository
+	FileAccesses   FileAccessRepository
+	Tags           TagRepository
+	ImageMetadata  ImageMetadataRepository
+	MediaMetadata  MediaMetadataRepository
+	Albums         AlbumRepository
 }
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormMediaMetadataRepository struct {
+	db *gorm.DB
+}
+
+func NewGormMediaMetadataRepository(db *gorm.DB) *GormMediaMetadataRepository {
+	return &GormMediaMetadataRepository{db: db}
+}
+
+// Create 写入元数据；同一文件对象已有记录时保持原记录不变，上传与后台补录并发时不会报错。
+func (r *GormMediaMetadataRepository) Create(ctx context.Context, tx *gorm.DB, meta *models.MediaMetadata) error {
+	return useTx(ctx, r.db, tx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "file_object_id"}}, DoNothing: true}).
+		Create(meta).Error
+}
+
+func (r *GormMediaMetadataRepository) GetByFileObjectID(ctx context.Context, tx *gorm.DB, fileObjectID uint) (models.MediaMetadata, error) {
+	var meta models.MediaMetadata
+	err := useTx(ctx, r.db, tx).Where("file_object_id = ?", fileObjectID).First(&meta).Error
+	return meta, err
+}
+
+// ListUnparsedObjects 按 ID 升序列出 MIME 为音频或视频、尚未解析元数据的文件对象，供后台补录。
+func (r *GormMediaMetadataRepository) ListUnparsedObjects(ctx context.Context, tx *gorm.DB, limit int) ([]models.FileObject, error) {
+	var objects []models.FileObject
+	err := useTx(ctx, r.db, tx).
+		Where("file_objects.mime_type LIKE ? OR file_objects.mime_type LIKE ?", "audio/%", "video/%").
+		Where("NOT EXISTS (SELECT 1 FROM media_metadata WHERE media_metadata.file_object_id = file_objects.id)").
+		Order("file_objects.id ASC").
+		Limit(limit).
+		Find(&objects).Error
+	return objects, err
+}
+
+// UpdateThumbnailPath 记录由内嵌封面生成的缩略图路径。
+func (r *GormMediaMetadataRepository) UpdateThumbnailPath(ctx context.Context, tx *gorm.DB, fileObjectID uint, thumbnailPath string) error {
+	return useTx(ctx, r.db, tx).Model(&models.FileObject{}).
+		Where("id = ?", fileObjectID).
+		Update("thumbnail_path", thumbnailPath).Error
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormMediaMetadataRepository_ListUnparsedObjects_BuildsNotExistsSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormMediaMetadataRepository(db)
+
+		if _, err := repo.ListUnparsedObjects(context.Background(), nil, 100); err != nil {
+			t.Fatalf("ListUnparsedObjects failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"where file_objects.mime_type like 'audio/%' or file_objects.mime_type like 'video/%'  and not exists",
+			"select 1 from media_metadata where media_metadata.file_object_id = file_objects.id",
+			"order by file_objects.id asc",
+		)
+	})
+}
+
+func TestGormMediaMetadataRepository_LiveBackfillAndCascade(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormMediaMetadataRepository(db)
+		objects := NewGormFileObjectRepository(db)
+		var objectIDs []uint
+		t.Cleanup(func() {
+			db.Where("file_object_id IN ?", objectIDs).Delete(&models.MediaMetadata{})
+			db.Where("id IN ?", objectIDs).Delete(&models.FileObject{})
+		})
+
+		create := func(name, mimeType string) models.FileObject {
+			obj := models.FileObject{FilePath: name, FileSize: 1, MimeType: mimeType, RefCount: 1}
+			if err := db.Create(&obj).Error; err != nil {
+				t.Fatalf("create object failed: %v", err)
+			}
+			objectIDs = append(objectIDs, obj.ID)
+			return obj
+		}
+		song := create("media/song.mp3", "audio/mpeg")
+		// 已解析的对象使用音频 MIME：MIME 的 OR 条件未整体加括号时，NOT EXISTS 只约束视频分支，它会被误列出。
+		clip := create("media/clip.m4a", "audio/mp4")
+		doc := create("media/doc.pdf", "application/pdf")
+
+		if err := repo.Create(ctx, nil, &models.MediaMetadata{FileObjectID: clip.ID, Kind: "audio", DurationMs: 1200}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		// 上传与补录并发写入同一对象时保留先写入的记录。
+		if err := repo.Create(ctx, nil, &models.MediaMetadata{FileObjectID: clip.ID}); err != nil {
+			t.Fatalf("repeated Create failed: %v", err)
+		}
+		meta, err := repo.GetByFileObjectID(ctx, nil, clip.ID)
+		if err != nil || meta.Kind != "audio" || meta.DurationMs != 1200 {
+			t.Fatalf("expected original metadata to be kept, got %+v (%v)", meta, err)
+		}
+
+		unparsed, err := repo.ListUnparsedObjects(ctx, nil, 1000)
+		if err != nil {
+			t.Fatalf("ListUnparsedObjects failed: %v", err)
+		}
+		found := false
+		for _, obj := range unparsed {
+			if obj.ID == clip.ID || obj.ID == doc.ID {
+				t.Fatalf("unexpected object listed as unparsed: %+v", obj)
+			}
+			found = found || obj.ID == song.ID
+		}
+		if !found {
+			t.Fatalf("expected audio object to be listed, got %+v", unparsed)
+		}
+
+		if err := repo.UpdateThumbnailPath(ctx, nil, song.ID, "thumbnails/covers/1.jpg"); err != nil {
+			t.Fatalf("UpdateThumbnailPath failed: %v", err)
+		}
+		var reloaded models.FileObject
+		if err := db.First(&reloaded, song.ID).Error; err != nil || reloaded.ThumbnailPath != "thumbnails/covers/1.jpg" {
+			t.Fatalf("expected thumbnail path to be updated, got %+v (%v)", reloaded, err)
+		}
+
+		// 删除文件对象时一并删除元数据。
+		if err := objects.DeleteByID(ctx, nil, clip.ID); err != nil {
+			t.Fatalf("DeleteByID failed: %v", err)
+		}
+		if _, err := repo.GetByFileObjectID(ctx, nil, clip.ID); err != gorm.ErrRecordNotFound {
+			t.Fatalf("expected metadata to be deleted, got %v", err)
+		}
+	})
+}
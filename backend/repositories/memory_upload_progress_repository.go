@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryProgressSweepInterval 控制过期条目的惰性清理频率。
+const memoryProgressSweepInterval = time.Minute
+
+type memoryUploadProgress struct {
+	chunks    map[int]struct{}
+	expiresAt time.Time
+}
+
+// MemoryUploadProgressRepository 在进程内保存分片进度，适合单实例部署或作为 Redis 降级存储。
+type MemoryUploadProgressRepository struct {
+	mu        sync.Mutex
+	uploads   map[string]*memoryUploadProgress
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryUploadProgressRepository() *MemoryUploadProgressRepository {
+	return &MemoryUploadProgressRepository{
+		uploads: make(map[string]*memoryUploadProgress),
+		now:     time.Now,
+	}
+}
+
+func (r *MemoryUploadProgressRepository) IsChunkUploaded(_ context.Context, uploadID string, chunkIndex int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progress := r.getLocked(uploadID)
+	if progress == nil {
+		return false, nil
+	}
+	_, ok := progress.chunks[chunkIndex]
+	return ok, nil
+}
+
+func (r *MemoryUploadProgressRepository) AddChunk(_ context.Context, uploadID string, chunkIndex int, expireSeconds int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.sweepLocked(now)
+
+	progress := r.getLocked(uploadID)
+	if progress == nil {
+		progress = &memoryUploadProgress{chunks: make(map[int]struct{})}
+		r.uploads[uploadID] = progress
+	}
+	progress.chunks[chunkIndex] = struct{}{}
+	// 与 Redis EXPIRE 语义一致：每次写入刷新整组过期时间。
+	if expireSeconds > 0 {
+		progress.expiresAt = now.Add(timeDurationSeconds(expireSeconds))
+	}
+	return nil
+}
+
+func (r *MemoryUploadProgressRepository) UploadedCount(_ context.Context, uploadID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progress := r.getLocked(uploadID)
+	if progress == nil {
+		return 0, nil
+	}
+	return int64(len(progress.chunks)), nil
+}
+
+func (r *MemoryUploadProgressRepository) UploadedChunks(_ context.Context, uploadID string) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progress := r.getLocked(uploadID)
+	if progress == nil {
+		return []int{}, nil
+	}
+	result := make([]int, 0, len(progress.chunks))
+	for idx := range progress.chunks {
+		result = append(result, idx)
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+func (r *MemoryUploadProgressRepository) Clear(_ context.Context, uploadID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.uploads, uploadID)
+	return nil
+}
+
+// getLocked 返回未过期的进度记录，已过期的记录顺带删除。
+func (r *MemoryUploadProgressRepository) getLocked(uploadID string) *memoryUploadProgress {
+	progress, ok := r.uploads[uploadID]
+	if !ok {
+		return nil
+	}
+	if !progress.expiresAt.IsZero() && !r.now().Before(progress.expiresAt) {
+		delete(r.uploads, uploadID)
+		return nil
+	}
+	return progress
+}
+
+func (r *MemoryUploadProgressRepository) sweepLocked(now time.Time) {
+	if now.Sub(r.lastSweep) < memoryProgressSweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for uploadID, progress := range r.uploads {
+		if !progress.expiresAt.IsZero() && !now.Before(progress.expiresAt) {
+			delete(r.uploads, uploadID)
+		}
+	}
+}
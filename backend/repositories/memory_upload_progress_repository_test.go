@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryUploadProgressRepository_TracksChunks(t *testing.T) {
+	repo := NewMemoryUploadProgressRepository()
+	ctx := context.Background()
+
+	for _, idx := range []int{3, 1, 3} {
+		if err := repo.AddChunk(ctx, "u1", idx, 60); err != nil {
+			t.Fatalf("AddChunk failed: %v", err)
+		}
+	}
+
+	uploaded, _ := repo.IsChunkUploaded(ctx, "u1", 1)
+	if !uploaded {
+		t.Fatalf("expected chunk 1 to be uploaded")
+	}
+	count, _ := repo.UploadedCount(ctx, "u1")
+	if count != 2 {
+		t.Fatalf("expected 2 chunks, got %d", count)
+	}
+	chunks, _ := repo.UploadedChunks(ctx, "u1")
+	if !reflect.DeepEqual(chunks, []int{1, 3}) {
+		t.Fatalf("expected sorted chunks [1 3], got %v", chunks)
+	}
+
+	if err := repo.Clear(ctx, "u1"); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	count, _ = repo.UploadedCount(ctx, "u1")
+	if count != 0 {
+		t.Fatalf("expected progress to be cleared, got %d", count)
+	}
+}
+
+func TestMemoryUploadProgressRepository_ExpiresWholeUpload(t *testing.T) {
+	repo := NewMemoryUploadProgressRepository()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = repo.AddChunk(ctx, "u1", 0, 10)
+	now = now.Add(8 * time.Second)
+	// 再次写入刷新整组过期时间。
+	_ = repo.AddChunk(ctx, "u1", 1, 10)
+	now = now.Add(8 * time.Second)
+
+	count, _ := repo.UploadedCount(ctx, "u1")
+	if count != 2 {
+		t.Fatalf("expected refreshed expiry to keep 2 chunks, got %d", count)
+	}
+
+	now = now.Add(5 * time.Second)
+	uploaded, _ := repo.IsChunkUploaded(ctx, "u1", 0)
+	if uploaded {
+		t.Fatalf("expected progress to expire")
+	}
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"mcloud/models"
@@ -17,14 +18,14 @@ func NewGormRecycleBinRepository(db *gorm.DB) *GormRecycleBinRepository {
 	return &GormRecycleBinRepository{db: db}
 }
 
-func (r *GormRecycleBinRepository) CountByUser(_ context.Context, tx *gorm.DB, userID uint) (int64, error) {
+func (r *GormRecycleBinRepository) CountByUser(ctx context.Context, tx *gorm.DB, userID uint) (int64, error) {
 	var total int64
-	err := useTx(r.db, tx).Model(&models.RecycleBinItem{}).Where("user_id = ?", userID).Count(&total).Error
+	err := useTx(ctx, r.db, tx).Model(&models.RecycleBinItem{}).Where("user_id = ?", userID).Count(&total).Error
 	return total, err
 }
 
-func (r *GormRecycleBinRepository) ListByUser(_ context.Context, tx *gorm.DB, in RecycleBinListInput) ([]models.RecycleBinItem, error) {
-	db := useTx(r.db, tx).Where("user_id = ?", in.UserID)
+func (r *GormRecycleBinRepository) ListByUser(ctx context.Context, tx *gorm.DB, in RecycleBinListInput) ([]models.RecycleBinItem, error) {
+	db := useTx(ctx, r.db, tx).Where("user_id = ?", in.UserID)
 	if in.SortSQL != "" {
 		db = db.Order(in.SortSQL)
 	}
@@ -33,41 +34,73 @@ func (r *GormRecycleBinRepository) ListByUser(_ context.Context, tx *gorm.DB, in
 	return items, err
 }
 
-func (r *GormRecycleBinRepository) ListAllByUser(_ context.Context, tx *gorm.DB, userID uint) ([]models.RecycleBinItem, error) {
+// ListByFilter 按筛选条件查询条目，按删除时间先后排序以保证分批处理顺序稳定。
+func (r *GormRecycleBinRepository) ListByFilter(ctx context.Context, tx *gorm.DB, filter RecycleBinFilter) ([]models.RecycleBinItem, error) {
+	db := useTx(ctx, r.db, tx).Where("user_id = ?", filter.UserID)
+	if len(filter.IDs) > 0 {
+		db = db.Where("id IN ?", filter.IDs)
+	}
+	if filter.OriginalType != "" {
+		db = db.Where("original_type = ?", filter.OriginalType)
+	}
+	if filter.DeletedBefore != nil {
+		db = db.Where("deleted_at < ?", *filter.DeletedBefore)
+	}
+	if prefix := strings.TrimRight(filter.PathPrefix, "/"); prefix != "" {
+		column := caseSensitivePath(db, "original_full_path")
+		db = db.Where("("+column+" = ? OR "+column+" LIKE ? ESCAPE '!')", prefix, subtreePathPattern(prefix))
+	}
+	if filter.Limit > 0 {
+		db = db.Limit(filter.Limit)
+	}
 	var items []models.RecycleBinItem
-	err := useTx(r.db, tx).Where("user_id = ?", userID).Find(&items).Error
+	err := db.Order("deleted_at ASC, id ASC").Find(&items).Error
 	return items, err
 }
 
-func (r *GormRecycleBinRepository) ListExpired(_ context.Context, tx *gorm.DB, now time.Time) ([]models.RecycleBinItem, error) {
+func (r *GormRecycleBinRepository) ListAllByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]models.RecycleBinItem, error) {
 	var items []models.RecycleBinItem
-	err := useTx(r.db, tx).Where("expires_at < ?", now).Find(&items).Error
+	err := useTx(ctx, r.db, tx).Where("user_id = ?", userID).Find(&items).Error
 	return items, err
 }
 
-func (r *GormRecycleBinRepository) GetByIDAndUser(_ context.Context, tx *gorm.DB, itemID uint, userID uint) (models.RecycleBinItem, error) {
+func (r *GormRecycleBinRepository) ListExpired(ctx context.Context, tx *gorm.DB, now time.Time) ([]models.RecycleBinItem, error) {
+	var items []models.RecycleBinItem
+	err := useTx(ctx, r.db, tx).Where("expires_at < ?", now).Find(&items).Error
+	return items, err
+}
+
+func (r *GormRecycleBinRepository) GetByIDAndUser(ctx context.Context, tx *gorm.DB, itemID uint, userID uint) (models.RecycleBinItem, error) {
 	var item models.RecycleBinItem
-	err := useTx(r.db, tx).Where("id = ? AND user_id = ?", itemID, userID).First(&item).Error
+	err := useTx(ctx, r.db, tx).Where("id = ? AND user_id = ?", itemID, userID).First(&item).Error
 	return item, err
 }
 
-func (r *GormRecycleBinRepository) Create(_ context.Context, tx *gorm.DB, item *models.RecycleBinItem) error {
-	return useTx(r.db, tx).Create(item).Error
+func (r *GormRecycleBinRepository) Create(ctx context.Context, tx *gorm.DB, item *models.RecycleBinItem) error {
+	return useTx(ctx, r.db, tx).Create(item).Error
+}
+
+// UpdateExpiresAtByUser 以一条语句把用户全部条目的过期时间重算为各自删除时间加保留天数。
+func (r *GormRecycleBinRepository) UpdateExpiresAtByUser(ctx context.Context, tx *gorm.DB, userID uint, retentionDays int) error {
+	db := useTx(ctx, r.db, tx)
+	return db.Model(&models.RecycleBinItem{}).
+		Where("user_id = ?", userID).
+		Update("expires_at", gorm.Expr(addDaysExpr(db, "deleted_at"), retentionDays)).Error
 }
 
-func (r *GormRecycleBinRepository) DeleteByID(_ context.Context, tx *gorm.DB, itemID uint) error {
-	return useTx(r.db, tx).Delete(&models.RecycleBinItem{}, itemID).Error
+func (r *GormRecycleBinRepository) DeleteByID(ctx context.Context, tx *gorm.DB, itemID uint) error {
+	return useTx(ctx, r.db, tx).Delete(&models.RecycleBinItem{}, itemID).Error
 }
 
-func (r *GormRecycleBinRepository) DeleteByUser(_ context.Context, tx *gorm.DB, userID uint) error {
-	return useTx(r.db, tx).Where("user_id = ?", userID).Delete(&models.RecycleBinItem{}).Error
+func (r *GormRecycleBinRepository) DeleteByUser(ctx context.Context, tx *gorm.DB, userID uint) error {
+	return useTx(ctx, r.db, tx).Where("user_id = ?", userID).Delete(&models.RecycleBinItem{}).Error
 }
 
-func (r *GormRecycleBinRepository) DeleteByOriginalIDs(_ context.Context, tx *gorm.DB, userID uint, originalType string, originalIDs []uint) error {
+func (r *GormRecycleBinRepository) DeleteByOriginalIDs(ctx context.Context, tx *gorm.DB, userID uint, originalType string, originalIDs []uint) error {
 	if len(originalIDs) == 0 {
 		return nil
 	}
-	return useTx(r.db, tx).
+	return useTx(ctx, r.db, tx).
 		Where("user_id = ? AND original_type = ? AND original_id IN ?", userID, originalType, originalIDs).
 		Delete(&models.RecycleBinItem{}).Error
 }
@@ -6,155 +6,223 @@ import (
 	"time"
 
 	"mcloud/models"
+
+	"gorm.io/gorm"
 )
 
 func TestGormRecycleBinRepository_CountByUser_BuildsCountSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
-
-	total, err := repo.CountByUser(context.Background(), nil, 2)
-	if err != nil {
-		t.Fatalf("CountByUser failed: %v", err)
-	}
-	if total != 0 {
-		t.Fatalf("expected dry-run total 0, got %d", total)
-	}
-
-	assertLastSQLContains(t, rec, "select count(*)", "from `recycle_bin`", "where user_id = ?")
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+
+		total, err := repo.CountByUser(context.Background(), nil, 2)
+		if err != nil {
+			t.Fatalf("CountByUser failed: %v", err)
+		}
+		if total != 0 {
+			t.Fatalf("expected dry-run total 0, got %d", total)
+		}
+
+		assertLastSQLContains(t, rec, "select count(*)", "from `recycle_bin`", "where user_id = ?")
+	})
 }
 
 func TestGormRecycleBinRepository_ListByUser_WithSortSQL_BuildsOrderedQuery(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
-
-	_, err := repo.ListByUser(context.Background(), nil, RecycleBinListInput{
-		UserID:  2,
-		Offset:  10,
-		Limit:   20,
-		SortSQL: "expires_at DESC",
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+
+		_, err := repo.ListByUser(context.Background(), nil, RecycleBinListInput{
+			UserID:  2,
+			Offset:  10,
+			Limit:   20,
+			SortSQL: "expires_at DESC",
+		})
+		if err != nil {
+			t.Fatalf("ListByUser failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `recycle_bin`", "where user_id = ?", "order by expires_at desc")
 	})
-	if err != nil {
-		t.Fatalf("ListByUser failed: %v", err)
-	}
+}
 
-	assertLastSQLContains(t, rec, "from `recycle_bin`", "where user_id = ?", "order by expires_at desc")
+func TestGormRecycleBinRepository_ListByFilter_AppliesAllConditions(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+		before := time.Now()
+
+		_, err := repo.ListByFilter(context.Background(), nil, RecycleBinFilter{
+			UserID:        2,
+			IDs:           []uint{1, 2},
+			OriginalType:  "file",
+			DeletedBefore: &before,
+			PathPrefix:    "/docs/",
+			Limit:         501,
+		})
+		if err != nil {
+			t.Fatalf("ListByFilter failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec,
+			"from `recycle_bin`",
+			"where user_id = ?",
+			"id in",
+			"original_type = ?",
+			"deleted_at < ?",
+			"("+pathColumnSQL(db, "original_full_path")+" = ? or "+pathColumnSQL(db, "original_full_path")+" like ? escape '!')",
+			"order by deleted_at asc, id asc",
+			"limit",
+		)
+	})
 }
 
-func TestGormRecycleBinRepository_ListByUser_WithoutSortSQL_OmitsOrderClause(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+func TestGormRecycleBinRepository_ListByFilter_RootPrefixMatchesAll(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+
+		if _, err := repo.ListByFilter(context.Background(), nil, RecycleBinFilter{UserID: 2, PathPrefix: "/"}); err != nil {
+			t.Fatalf("ListByFilter failed: %v", err)
+		}
 
-	_, err := repo.ListByUser(context.Background(), nil, RecycleBinListInput{
-		UserID: 2,
-		Offset: 0,
-		Limit:  10,
+		assertLastSQLContains(t, rec, "where user_id = ?")
+		assertLastSQLNotContains(t, rec, "original_full_path", "limit")
 	})
-	if err != nil {
-		t.Fatalf("ListByUser failed: %v", err)
-	}
+}
 
-	assertLastSQLContains(t, rec, "from `recycle_bin`", "where user_id = ?")
-	assertLastSQLNotContains(t, rec, "order by")
+func TestGormRecycleBinRepository_ListByUser_WithoutSortSQL_OmitsOrderClause(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+
+		_, err := repo.ListByUser(context.Background(), nil, RecycleBinListInput{
+			UserID: 2,
+			Offset: 0,
+			Limit:  10,
+		})
+		if err != nil {
+			t.Fatalf("ListByUser failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "from `recycle_bin`", "where user_id = ?")
+		assertLastSQLNotContains(t, rec, "order by")
+	})
 }
 
 func TestGormRecycleBinRepository_ListAllByUser_BuildsSelectSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	_, err := repo.ListAllByUser(context.Background(), nil, 2)
-	if err != nil {
-		t.Fatalf("ListAllByUser failed: %v", err)
-	}
+		_, err := repo.ListAllByUser(context.Background(), nil, 2)
+		if err != nil {
+			t.Fatalf("ListAllByUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `recycle_bin`", "where user_id = ?")
+		assertLastSQLContains(t, rec, "from `recycle_bin`", "where user_id = ?")
+	})
 }
 
 func TestGormRecycleBinRepository_ListExpired_BuildsExpireFilterSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	_, err := repo.ListExpired(context.Background(), nil, time.Now())
-	if err != nil {
-		t.Fatalf("ListExpired failed: %v", err)
-	}
+		_, err := repo.ListExpired(context.Background(), nil, time.Now())
+		if err != nil {
+			t.Fatalf("ListExpired failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `recycle_bin`", "where expires_at < ?")
+		assertLastSQLContains(t, rec, "from `recycle_bin`", "where expires_at < ?")
+	})
 }
 
 func TestGormRecycleBinRepository_GetByIDAndUser_BuildsLookupSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	_, err := repo.GetByIDAndUser(context.Background(), nil, 7, 2)
-	if err != nil {
-		t.Fatalf("GetByIDAndUser failed: %v", err)
-	}
+		_, err := repo.GetByIDAndUser(context.Background(), nil, 7, 2)
+		if err != nil {
+			t.Fatalf("GetByIDAndUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "from `recycle_bin`", "where id = ? and user_id = ?")
+		assertLastSQLContains(t, rec, "from `recycle_bin`", "where id = ? and user_id = ?")
+	})
 }
 
 func TestGormRecycleBinRepository_Create_BuildsInsertSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
-
-	item := &models.RecycleBinItem{
-		UserID:       2,
-		OriginalID:   8,
-		OriginalType: "file",
-		OriginalName: "a.txt",
-		ExpiresAt:    time.Now().Add(24 * time.Hour),
-	}
-	if err := repo.Create(context.Background(), nil, item); err != nil {
-		t.Fatalf("Create failed: %v", err)
-	}
-
-	assertLastSQLContains(t, rec, "insert into `recycle_bin`")
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+
+		item := &models.RecycleBinItem{
+			UserID:       2,
+			OriginalID:   8,
+			OriginalType: "file",
+			OriginalName: "a.txt",
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+		}
+		if err := repo.Create(context.Background(), nil, item); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "insert into `recycle_bin`")
+	})
+}
+
+func TestGormRecycleBinRepository_UpdateExpiresAtByUser_BuildsSetBasedUpdateSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
+
+		if err := repo.UpdateExpiresAtByUser(context.Background(), nil, 2, 7); err != nil {
+			t.Fatalf("UpdateExpiresAtByUser failed: %v", err)
+		}
+
+		assertLastSQLContains(t, rec, "update `recycle_bin`", "expires_at", "deleted_at", "where user_id = ?")
+	})
 }
 
 func TestGormRecycleBinRepository_DeleteByID_BuildsDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	err := repo.DeleteByID(context.Background(), nil, 7)
-	if err != nil {
-		t.Fatalf("DeleteByID failed: %v", err)
-	}
+		err := repo.DeleteByID(context.Background(), nil, 7)
+		if err != nil {
+			t.Fatalf("DeleteByID failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "delete from `recycle_bin`", "where `recycle_bin`.`id` = ?")
+		assertLastSQLContains(t, rec, "delete from `recycle_bin`", "where `recycle_bin`.`id` = ?")
+	})
 }
 
 func TestGormRecycleBinRepository_DeleteByUser_BuildsDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	err := repo.DeleteByUser(context.Background(), nil, 2)
-	if err != nil {
-		t.Fatalf("DeleteByUser failed: %v", err)
-	}
+		err := repo.DeleteByUser(context.Background(), nil, 2)
+		if err != nil {
+			t.Fatalf("DeleteByUser failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "delete from `recycle_bin`", "where user_id = ?")
+		assertLastSQLContains(t, rec, "delete from `recycle_bin`", "where user_id = ?")
+	})
 }
 
 func TestGormRecycleBinRepository_DeleteByOriginalIDs_EmptyIDs_NoSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	err := repo.DeleteByOriginalIDs(context.Background(), nil, 2, "file", nil)
-	if err != nil {
-		t.Fatalf("DeleteByOriginalIDs failed: %v", err)
-	}
+		err := repo.DeleteByOriginalIDs(context.Background(), nil, 2, "file", nil)
+		if err != nil {
+			t.Fatalf("DeleteByOriginalIDs failed: %v", err)
+		}
 
-	assertNoSQLCaptured(t, rec)
+		assertNoSQLCaptured(t, rec)
+	})
 }
 
 func TestGormRecycleBinRepository_DeleteByOriginalIDs_BuildsBatchDeleteSQL(t *testing.T) {
-	db, rec := newDryRunMySQL(t)
-	repo := NewGormRecycleBinRepository(db)
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormRecycleBinRepository(db)
 
-	err := repo.DeleteByOriginalIDs(context.Background(), nil, 2, "file", []uint{1, 2, 3})
-	if err != nil {
-		t.Fatalf("DeleteByOriginalIDs failed: %v", err)
-	}
+		err := repo.DeleteByOriginalIDs(context.Background(), nil, 2, "file", []uint{1, 2, 3})
+		if err != nil {
+			t.Fatalf("DeleteByOriginalIDs failed: %v", err)
+		}
 
-	assertLastSQLContains(t, rec, "delete from `recycle_bin`", "where user_id = ? and original_type = ? and original_id in")
+		assertLastSQLContains(t, rec, "delete from `recycle_bin`", "where user_id = ? and original_type = ? and original_id in")
+	})
 }
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 本文件集中放置需要同时兼容 MySQL / PostgreSQL / SQLite 的 SQL 片段。
+
+// subtreeCondition 匹配文件夹自身及全部子孙；LIKE 转义符选用 "!"，
+// 因为反斜杠在 MySQL 字面量中本身需要转义，各方言写法不一致。
+func subtreeCondition(db *gorm.DB) string {
+	return "user_id = ? AND (id = ? OR " + caseSensitivePath(db, "path") + " LIKE ? ESCAPE '!')"
+}
+
+// caseSensitivePath 让路径列按字节区分大小写比较。PostgreSQL 的 LIKE 本身区分大小写，SQLite 通过
+// case_sensitive_like 开启；MySQL 的 utf8mb4 默认排序规则不区分大小写，/a_b 的子树会误含 /A_B，需指定二进制排序规则。
+func caseSensitivePath(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "mysql" {
+		return column + " COLLATE utf8mb4_bin"
+	}
+	return column
+}
+
+// addDaysExpr 生成“时间列加 ? 天”的表达式，用于按条目各自的时间批量重算。
+// SQLite 中时间以“2006-01-02 15:04:05.999999999-07:00”格式的文本保存，只对前 19 位本地时刻做加法并保留原有的小数秒与偏移，
+// 避免 datetime() 换算为 UTC 后与其它以本地时间写入的值按文本比较时错位；跨夏令时切换时偏移不变，最多相差一小时。
+func addDaysExpr(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "DATE_ADD(" + column + ", INTERVAL ? DAY)"
+	case "postgres":
+		return column + " + make_interval(days => ?)"
+	default:
+		return "strftime('%Y-%m-%d %H:%M:%S', substr(" + column + ", 1, 19), '+' || ? || ' days') || substr(" + column + ", 20)"
+	}
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// subtreePathPattern 生成子孙路径的 LIKE 模式，避免文件夹名中的 % 与 _ 被当作通配符。
+func subtreePathPattern(rootPath string) string {
+	return likeEscaper.Replace(rootPath) + "/%"
+}
+
+// containsPattern 生成不区分大小写的包含匹配模式，需配合 LOWER(列) LIKE ? ESCAPE '!' 使用。
+func containsPattern(keyword string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(keyword)) + "%"
+}
+
+// keysetCondition 生成 (col, idCol) 的游标比较条件，参数依次为键值、键值、id。
+// 不使用行值比较 (a, b) > (?, ?)，避免各方言对其索引利用程度不一。
+func keysetCondition(col string, idCol string, desc bool) string {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return "(" + col + " " + op + " ? OR (" + col + " = ? AND " + idCol + " " + op + " ?))"
+}
+
+// keysetOrder 生成与 keysetCondition 对应的排序子句。
+func keysetOrder(col string, idCol string, desc bool) string {
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	return col + dir + ", " + idCol + dir
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+type GormStorageStatsRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStorageStatsRepository(db *gorm.DB) *GormStorageStatsRepository {
+	return &GormStorageStatsRepository{db: db}
+}
+
+// Totals 以聚合查询统计全站用量，每张表一条 SQL。
+func (r *GormStorageStatsRepository) Totals(ctx context.Context) (StorageTotals, error) {
+	db := r.db.WithContext(ctx)
+	var totals StorageTotals
+
+	var users struct {
+		Count int64
+		Quota int64
+		Used  int64
+	}
+	if err := db.Model(&models.User{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(storage_quota), 0) AS quota, COALESCE(SUM(storage_used), 0) AS used").
+		Scan(&users).Error; err != nil {
+		return totals, err
+	}
+	totals.Users, totals.QuotaBytes, totals.UsedBytes = users.Count, users.Quota, users.Used
+
+	var objects struct {
+		Count int64
+		Bytes int64
+	}
+	if err := db.Model(&models.FileObject{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(file_size), 0) AS bytes").
+		Scan(&objects).Error; err != nil {
+		return totals, err
+	}
+	totals.FileObjects, totals.FileObjectBytes = objects.Count, objects.Bytes
+
+	if err := db.Model(&models.File{}).Count(&totals.Files).Error; err != nil {
+		return totals, err
+	}
+
+	var recycle struct {
+		Count int64
+		Bytes int64
+	}
+	if err := db.Model(&models.RecycleBinItem{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(file_size), 0) AS bytes").
+		Scan(&recycle).Error; err != nil {
+		return totals, err
+	}
+	totals.RecycleBinItems, totals.RecycleBinBytes = recycle.Count, recycle.Bytes
+	return totals, nil
+}
+
+// UserUsage 汇总用户正常文件与回收站文件的数量和字节数，并统计去重后的实际对象大小。
+func (r *GormStorageStatsRepository) UserUsage(ctx context.Context, userID uint) (UserStorageUsage, error) {
+	db := r.db.WithContext(ctx)
+	var usage UserStorageUsage
+
+	// 回收站中的文件仍计入 storage_used，需 Unscoped 一并统计。
+	if err := db.Unscoped().Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Select("COALESCE(SUM(CASE WHEN files.deleted_at IS NULL THEN 1 ELSE 0 END), 0) AS active_files, "+
+			"COALESCE(SUM(CASE WHEN files.deleted_at IS NULL THEN file_objects.file_size ELSE 0 END), 0) AS active_bytes, "+
+			"COALESCE(SUM(CASE WHEN files.deleted_at IS NOT NULL THEN 1 ELSE 0 END), 0) AS recycled_files, "+
+			"COALESCE(SUM(CASE WHEN files.deleted_at IS NOT NULL THEN file_objects.file_size ELSE 0 END), 0) AS recycled_bytes").
+		Where("files.user_id = ?", userID).
+		Scan(&usage).Error; err != nil {
+		return usage, err
+	}
+
+	objectIDs := db.Unscoped().Model(&models.File{}).Select("file_object_id").Where("user_id = ?", userID)
+	if err := db.Model(&models.FileObject{}).
+		Select("COALESCE(SUM(file_size), 0)").
+		Where("id IN (?)", objectIDs).
+		Scan(&usage.UniqueObjectBytes).Error; err != nil {
+		return usage, err
+	}
+	return usage, nil
+}
+
+// UserUsageByMimeType 按 MIME 类型聚合用户正常文件的用量。
+func (r *GormStorageStatsRepository) UserUsageByMimeType(ctx context.Context, userID uint) ([]MimeTypeUsage, error) {
+	var rows []MimeTypeUsage
+	err := r.db.WithContext(ctx).Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Select("file_objects.mime_type AS mime_type, COUNT(*) AS file_count, COALESCE(SUM(file_objects.file_size), 0) AS bytes").
+		Where("files.user_id = ?", userID).
+		Group("file_objects.mime_type").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// UserUsageByFolder 按所在目录路径聚合用户正常文件的用量，按顶层目录归并由调用方完成。
+func (r *GormStorageStatsRepository) UserUsageByFolder(ctx context.Context, userID uint) ([]FolderPathUsage, error) {
+	var rows []FolderPathUsage
+	err := r.db.WithContext(ctx).Model(&models.File{}).
+		Joins("JOIN file_objects ON file_objects.id = files.file_object_id").
+		Joins("LEFT JOIN folders ON folders.id = files.folder_id AND folders.deleted_at IS NULL").
+		Select("COALESCE(folders.path, '') AS path, COUNT(*) AS file_count, COALESCE(SUM(file_objects.file_size), 0) AS bytes").
+		Where("files.user_id = ?", userID).
+		Group("folders.path").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// UserFolderUsage 列出用户全部正常目录及其直接包含的文件用量，空目录同样返回一行。
+func (r *GormStorageStatsRepository) UserFolderUsage(ctx context.Context, userID uint) ([]FolderUsage, error) {
+	var rows []FolderUsage
+	err := r.db.WithContext(ctx).Model(&models.Folder{}).
+		Joins("LEFT JOIN files ON files.folder_id = folders.id AND files.deleted_at IS NULL").
+		Joins("LEFT JOIN file_objects ON file_objects.id = files.file_object_id").
+		Select("folders.id AS folder_id, folders.path AS path, COUNT(files.id) AS file_count, COALESCE(SUM(file_objects.file_size), 0) AS bytes").
+		Where("folders.user_id = ?", userID).
+		Group("folders.id, folders.path").
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormStorageStatsRepository_Totals(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormStorageStatsRepository(db)
+		userID := liveUserID(t, db)
+
+		before, err := repo.Totals(ctx)
+		if err != nil {
+			t.Fatalf("Totals failed: %v", err)
+		}
+
+		user := models.User{ID: userID, Username: fmt.Sprintf("stats-%d", userID), Password: "x", StorageQuota: 1000, StorageUsed: 300}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("create user failed: %v", err)
+		}
+		size := int64(120)
+		item := models.RecycleBinItem{
+			UserID:       userID,
+			OriginalID:   1,
+			OriginalType: "file",
+			OriginalName: "a.txt",
+			FileSize:     &size,
+			ExpiresAt:    time.Now().Add(time.Hour),
+			Metadata:     "{}",
+		}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatalf("create recycle item failed: %v", err)
+		}
+
+		after, err := repo.Totals(ctx)
+		if err != nil {
+			t.Fatalf("Totals failed: %v", err)
+		}
+		if after.Users-before.Users != 1 || after.QuotaBytes-before.QuotaBytes != 1000 || after.UsedBytes-before.UsedBytes != 300 {
+			t.Fatalf("unexpected user totals: before=%+v after=%+v", before, after)
+		}
+		if after.RecycleBinItems-before.RecycleBinItems != 1 || after.RecycleBinBytes-before.RecycleBinBytes != size {
+			t.Fatalf("unexpected recycle totals: before=%+v after=%+v", before, after)
+		}
+	})
+}
+
+func TestGormStorageStatsRepository_UserUsage(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormStorageStatsRepository(db)
+		userID := liveUserID(t, db)
+
+		var objectIDs []uint
+		t.Cleanup(func() {
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+			if len(objectIDs) > 0 {
+				db.Where("id IN ?", objectIDs).Delete(&models.FileObject{})
+			}
+		})
+		newObject := func(size int64, mimeType string) models.FileObject {
+			obj := models.FileObject{FilePath: fmt.Sprintf("stats/%d/%d", userID, len(objectIDs)), FileSize: size, MimeType: mimeType}
+			if err := db.Create(&obj).Error; err != nil {
+				t.Fatalf("create file object failed: %v", err)
+			}
+			objectIDs = append(objectIDs, obj.ID)
+			return obj
+		}
+		folders := NewGormFolderRepository(db)
+		docs := models.Folder{Name: "docs", UserID: userID, Path: "/docs"}
+		if err := folders.Create(ctx, nil, &docs); err != nil {
+			t.Fatalf("create folder failed: %v", err)
+		}
+		newFile := func(name string, folderID uint, obj models.FileObject) models.File {
+			file := models.File{Name: name, OriginalName: name, FolderID: folderID, UserID: userID, FileObjectID: obj.ID}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			return file
+		}
+
+		photo := newObject(100, "image/png")
+		note := newObject(30, "text/plain")
+		newFile("a.png", docs.ID, photo)
+		newFile("b.png", 0, photo)
+		trashed := newFile("c.txt", docs.ID, note)
+		if err := db.Delete(&trashed).Error; err != nil {
+			t.Fatalf("soft delete file failed: %v", err)
+		}
+
+		usage, err := repo.UserUsage(ctx, userID)
+		if err != nil {
+			t.Fatalf("UserUsage failed: %v", err)
+		}
+		want := UserStorageUsage{ActiveFiles: 2, ActiveBytes: 200, RecycledFiles: 1, RecycledBytes: 30, UniqueObjectBytes: 130}
+		if usage != want {
+			t.Fatalf("unexpected usage: got %+v want %+v", usage, want)
+		}
+
+		byMime, err := repo.UserUsageByMimeType(ctx, userID)
+		if err != nil {
+			t.Fatalf("UserUsageByMimeType failed: %v", err)
+		}
+		if len(byMime) != 1 || byMime[0] != (MimeTypeUsage{MimeType: "image/png", FileCount: 2, Bytes: 200}) {
+			t.Fatalf("unexpected mime usage: %+v", byMime)
+		}
+
+		byFolder, err := repo.UserUsageByFolder(ctx, userID)
+		if err != nil {
+			t.Fatalf("UserUsageByFolder failed: %v", err)
+		}
+		got := map[string]FolderPathUsage{}
+		for _, row := range byFolder {
+			got[row.Path] = row
+		}
+		if len(got) != 2 || got["/docs"].Bytes != 100 || got[""].FileCount != 1 {
+			t.Fatalf("unexpected folder usage: %+v", byFolder)
+		}
+
+		empty := models.Folder{Name: "empty", UserID: userID, Path: "/empty"}
+		if err := folders.Create(ctx, nil, &empty); err != nil {
+			t.Fatalf("create folder failed: %v", err)
+		}
+		perFolder, err := repo.UserFolderUsage(ctx, userID)
+		if err != nil {
+			t.Fatalf("UserFolderUsage failed: %v", err)
+		}
+		wantFolders := map[uint]FolderUsage{
+			docs.ID:  {FolderID: docs.ID, Path: "/docs", FileCount: 1, Bytes: 100},
+			empty.ID: {FolderID: empty.ID, Path: "/empty"},
+		}
+		if len(perFolder) != len(wantFolders) {
+			t.Fatalf("unexpected per-folder usage: %+v", perFolder)
+		}
+		for _, row := range perFolder {
+			if row != wantFolders[row.FolderID] {
+				t.Fatalf("unexpected per-folder usage row: got %+v want %+v", row, wantFolders[row.FolderID])
+			}
+		}
+	})
+}
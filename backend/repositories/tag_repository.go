@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormTagRepository struct {
+	db *gorm.DB
+}
+
+func NewGormTagRepository(db *gorm.DB) *GormTagRepository {
+	return &GormTagRepository{db: db}
+}
+
+// activeTagBindingCondition 匹配目标仍处于正常状态（未删除、不在回收站）的绑定，需要两个目标类型参数。
+const activeTagBindingCondition = "((tag_bindings.target_type = ? AND EXISTS (SELECT 1 FROM files WHERE files.id = tag_bindings.target_id AND files.deleted_at IS NULL))" +
+	" OR (tag_bindings.target_type = ? AND EXISTS (SELECT 1 FROM folders WHERE folders.id = tag_bindings.target_id AND folders.deleted_at IS NULL)))"
+
+// applyTagFilter 追加标签筛选条件，idColumn 为被筛选目标的主键列（如 files.id）。
+func applyTagFilter(query *gorm.DB, targetType string, idColumn string, f TagFilter) *gorm.DB {
+	if len(f.TagIDs) == 0 {
+		return query
+	}
+	if f.MatchAll {
+		return query.Where("(SELECT COUNT(DISTINCT tag_bindings.tag_id) FROM tag_bindings"+
+			" WHERE tag_bindings.target_type = ? AND tag_bindings.target_id = "+idColumn+" AND tag_bindings.tag_id IN ?) = ?",
+			targetType, f.TagIDs, len(f.TagIDs))
+	}
+	return query.Where("EXISTS (SELECT 1 FROM tag_bindings"+
+		" WHERE tag_bindings.target_type = ? AND tag_bindings.target_id = "+idColumn+" AND tag_bindings.tag_id IN ?)",
+		targetType, f.TagIDs)
+}
+
+func (r *GormTagRepository) Create(ctx context.Context, tx *gorm.DB, tag *models.Tag) error {
+	return useTx(ctx, r.db, tx).Create(tag).Error
+}
+
+func (r *GormTagRepository) GetByIDAndUser(ctx context.Context, tx *gorm.DB, tagID uint, userID uint) (models.Tag, error) {
+	var tag models.Tag
+	err := useTx(ctx, r.db, tx).Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error
+	return tag, err
+}
+
+func (r *GormTagRepository) GetByIDsAndUser(ctx context.Context, tx *gorm.DB, userID uint, tagIDs []uint) ([]models.Tag, error) {
+	if len(tagIDs) == 0 {
+		return nil, nil
+	}
+	var tags []models.Tag
+	err := useTx(ctx, r.db, tx).Where("user_id = ? AND id IN ?", userID, tagIDs).Find(&tags).Error
+	return tags, err
+}
+
+func (r *GormTagRepository) CountByUserAndName(ctx context.Context, tx *gorm.DB, userID uint, name string, excludeID uint) (int64, error) {
+	query := useTx(ctx, r.db, tx).Model(&models.Tag{}).Where("user_id = ? AND name = ?", userID, name)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// ListUsageByUser 按名称列出用户全部标签，并统计每个标签绑定的正常状态目标数。
+func (r *GormTagRepository) ListUsageByUser(ctx context.Context, tx *gorm.DB, userID uint) ([]TagUsage, error) {
+	var usages []TagUsage
+	err := useTx(ctx, r.db, tx).Model(&models.Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM tag_bindings WHERE tag_bindings.tag_id = tags.id AND "+activeTagBindingCondition+") AS item_count",
+			models.TagTargetFile, models.TagTargetFolder).
+		Where("tags.user_id = ?", userID).
+		Order("tags.name ASC, tags.id ASC").
+		Scan(&usages).Error
+	return usages, err
+}
+
+func (r *GormTagRepository) UpdateByID(ctx context.Context, tx *gorm.DB, tagID uint, updates map[string]interface{}) error {
+	return useTx(ctx, r.db, tx).Model(&models.Tag{}).Where("id = ?", tagID).Updates(updates).Error
+}
+
+// DeleteByIDs 删除标签及其全部绑定。
+func (r *GormTagRepository) DeleteByIDs(ctx context.Context, tx *gorm.DB, userID uint, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	db := useTx(ctx, r.db, tx)
+	if err := db.Where("user_id = ? AND tag_id IN ?", userID, tagIDs).Delete(&models.TagBinding{}).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND id IN ?", userID, tagIDs).Delete(&models.Tag{}).Error
+}
+
+// Bind 批量写入绑定；已存在的绑定保持不变。
+func (r *GormTagRepository) Bind(ctx context.Context, tx *gorm.DB, bindings []models.TagBinding) error {
+	if len(bindings) == 0 {
+		return nil
+	}
+	return useTx(ctx, r.db, tx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&bindings, 500).Error
+}
+
+func (r *GormTagRepository) Unbind(ctx context.Context, tx *gorm.DB, userID uint, tagIDs []uint, targetType string, targetIDs []uint) (int64, error) {
+	if len(tagIDs) == 0 || len(targetIDs) == 0 {
+		return 0, nil
+	}
+	result := useTx(ctx, r.db, tx).
+		Where("user_id = ? AND target_type = ? AND target_id IN ? AND tag_id IN ?", userID, targetType, targetIDs, tagIDs).
+		Delete(&models.TagBinding{})
+	return result.RowsAffected, result.Error
+}
+
+// MergeBindings 把来源标签的绑定转移到目标标签：目标已有的绑定保留原记录，来源标签上的绑定随后删除。
+func (r *GormTagRepository) MergeBindings(ctx context.Context, tx *gorm.DB, userID uint, targetTagID uint, sourceTagIDs []uint) error {
+	if len(sourceTagIDs) == 0 {
+		return nil
+	}
+	db := useTx(ctx, r.db, tx)
+	var sources []models.TagBinding
+	if err := db.Where("user_id = ? AND tag_id IN ?", userID, sourceTagIDs).Order("id ASC").Find(&sources).Error; err != nil {
+		return err
+	}
+
+	type targetKey struct {
+		targetType string
+		targetID   uint
+	}
+	seen := make(map[targetKey]bool, len(sources))
+	moved := make([]models.TagBinding, 0, len(sources))
+	for _, binding := range sources {
+		key := targetKey{binding.TargetType, binding.TargetID}
+		// 同一目标可能同时带有多个来源标签，只转移一次。
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		moved = append(moved, models.TagBinding{
+			UserID:     userID,
+			TagID:      targetTagID,
+			TargetType: binding.TargetType,
+			TargetID:   binding.TargetID,
+			CreatedAt:  binding.CreatedAt,
+		})
+	}
+	if err := r.Bind(ctx, tx, moved); err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND tag_id IN ?", userID, sourceTagIDs).Delete(&models.TagBinding{}).Error
+}
+
+// ListByTargets 批量查询目标上的标签，按标签名称排序。
+func (r *GormTagRepository) ListByTargets(ctx context.Context, tx *gorm.DB, userID uint, targetType string, targetIDs []uint) ([]TargetTag, error) {
+	if len(targetIDs) == 0 {
+		return nil, nil
+	}
+	var out []TargetTag
+	err := useTx(ctx, r.db, tx).Table("tag_bindings").
+		Select("tag_bindings.target_id, tags.*").
+		Joins("JOIN tags ON tags.id = tag_bindings.tag_id").
+		Where("tag_bindings.user_id = ? AND tag_bindings.target_type = ? AND tag_bindings.target_id IN ?", userID, targetType, targetIDs).
+		Order("tags.name ASC, tags.id ASC").
+		Scan(&out).Error
+	return out, err
+}
+
+// DeleteOrphanBindings 清理目标已被彻底删除的绑定；目标仍在回收站时保留，恢复后标签随之恢复。
+func (r *GormTagRepository) DeleteOrphanBindings(ctx context.Context, tx *gorm.DB) (int64, error) {
+	result := useTx(ctx, r.db, tx).
+		Where("(target_type = ? AND NOT EXISTS (SELECT 1 FROM files WHERE files.id = tag_bindings.target_id))"+
+			" OR (target_type = ? AND NOT EXISTS (SELECT 1 FROM folders WHERE folders.id = tag_bindings.target_id))",
+			models.TagTargetFile, models.TagTargetFolder).
+		Delete(&models.TagBinding{})
+	return result.RowsAffected, result.Error
+}
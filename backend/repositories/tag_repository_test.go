@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGormFileRepository_ListByFolder_TagFilterModes(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFileRepository(db)
+		in := ListFilesInput{UserID: 2, FolderID: 9, RootFolderID: 1, SortBy: "name", Order: "asc", Limit: 20, Tags: TagFilter{TagIDs: []uint{3, 5}}}
+
+		if _, err := repo.ListByFolder(context.Background(), nil, in); err != nil {
+			t.Fatalf("ListByFolder failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"exists  select 1 from tag_bindings where tag_bindings.target_type = ? and tag_bindings.target_id = files.id and tag_bindings.tag_id in  ?,?",
+		)
+
+		in.Tags.MatchAll = true
+		if _, err := repo.CountByFolder(context.Background(), nil, in); err != nil {
+			t.Fatalf("CountByFolder failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"select count(*)",
+			"select count distinct tag_bindings.tag_id  from tag_bindings",
+			"tag_bindings.tag_id in  ?,?  = ?",
+		)
+		assertLastSQLNotContains(t, rec, "exists")
+	})
+}
+
+func TestGormFolderRepository_Search_ExcludesRootAndFiltersTags(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormFolderRepository(db)
+
+		_, err := repo.Search(context.Background(), nil, SearchInput{
+			UserID: 2,
+			Filter: FileQuickFilter{Keyword: "Plan", Tags: TagFilter{TagIDs: []uint{4}}},
+			Limit:  20,
+		})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		assertLastSQLContains(t, rec,
+			"folders.is_root is null or folders.is_root = false",
+			"lower folders.name  like ? escape '!'",
+			"tag_bindings.target_id = folders.id",
+			"order by folders.name asc, folders.id asc",
+		)
+	})
+}
+
+func TestGormTagRepository_Unbind_EmptyInputSkipsSQL(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB, rec *sqlRecorder) {
+		repo := NewGormTagRepository(db)
+
+		affected, err := repo.Unbind(context.Background(), nil, 2, []uint{1}, models.TagTargetFile, nil)
+		if err != nil || affected != 0 {
+			t.Fatalf("expected no-op unbind, got %d (%v)", affected, err)
+		}
+		assertNoSQLCaptured(t, rec)
+	})
+}
+
+func TestGormTagRepository_LiveLifecycle(t *testing.T) {
+	forEachLiveDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewGormTagRepository(db)
+		files := NewGormFileRepository(db)
+		userID := liveUserID(t, db)
+		t.Cleanup(func() {
+			db.Where("user_id = ?", userID).Delete(&models.TagBinding{})
+			db.Where("user_id = ?", userID).Delete(&models.Tag{})
+			db.Unscoped().Where("user_id = ?", userID).Delete(&models.File{})
+		})
+
+		folder := models.Folder{Name: "Tagged", UserID: userID, Path: "/Tagged"}
+		if err := db.Create(&folder).Error; err != nil {
+			t.Fatalf("create folder failed: %v", err)
+		}
+		var fileList []models.File
+		for i := 0; i < 3; i++ {
+			file := models.File{Name: fmt.Sprintf("t%d", i), OriginalName: fmt.Sprintf("doc%d.txt", i), FolderID: folder.ID, UserID: userID, FileObjectID: 1}
+			if err := db.Create(&file).Error; err != nil {
+				t.Fatalf("create file failed: %v", err)
+			}
+			fileList = append(fileList, file)
+		}
+
+		var tags []models.Tag
+		for _, name := range []string{"work", "urgent", "todo"} {
+			tag := models.Tag{UserID: userID, Name: name, Color: "#409EFF"}
+			if err := repo.Create(ctx, nil, &tag); err != nil {
+				t.Fatalf("create tag failed: %v", err)
+			}
+			tags = append(tags, tag)
+		}
+		work, urgent, todo := tags[0].ID, tags[1].ID, tags[2].ID
+
+		// doc0: work+urgent，doc1: work，doc2: todo；重复绑定保持幂等。
+		bindings := []models.TagBinding{
+			{UserID: userID, TagID: work, TargetType: models.TagTargetFile, TargetID: fileList[0].ID},
+			{UserID: userID, TagID: urgent, TargetType: models.TagTargetFile, TargetID: fileList[0].ID},
+			{UserID: userID, TagID: work, TargetType: models.TagTargetFile, TargetID: fileList[1].ID},
+			{UserID: userID, TagID: todo, TargetType: models.TagTargetFile, TargetID: fileList[2].ID},
+			{UserID: userID, TagID: todo, TargetType: models.TagTargetFolder, TargetID: folder.ID},
+		}
+		if err := repo.Bind(ctx, nil, bindings); err != nil {
+			t.Fatalf("Bind failed: %v", err)
+		}
+		if err := repo.Bind(ctx, nil, bindings[:1]); err != nil {
+			t.Fatalf("repeated Bind failed: %v", err)
+		}
+
+		base := ListFilesInput{UserID: userID, FolderID: folder.ID, RootFolderID: 0, Limit: 10}
+		base.Tags = TagFilter{TagIDs: []uint{work, urgent}}
+		anyCount, err := files.CountByFolder(ctx, nil, base)
+		if err != nil {
+			t.Fatalf("CountByFolder (or) failed: %v", err)
+		}
+		base.Tags.MatchAll = true
+		allList, err := files.ListByFolder(ctx, nil, base)
+		if err != nil {
+			t.Fatalf("ListByFolder (and) failed: %v", err)
+		}
+		if anyCount != 2 || len(allList) != 1 || allList[0].ID != fileList[0].ID {
+			t.Fatalf("unexpected tag filter results: or=%d and=%+v", anyCount, allList)
+		}
+
+		// 进入回收站的文件不计入标签使用数，但绑定保留。
+		if err := db.Delete(&fileList[1]).Error; err != nil {
+			t.Fatalf("soft delete failed: %v", err)
+		}
+		usages, err := repo.ListUsageByUser(ctx, nil, userID)
+		if err != nil {
+			t.Fatalf("ListUsageByUser failed: %v", err)
+		}
+		counts := map[string]int64{}
+		for _, usage := range usages {
+			counts[usage.Name] = usage.ItemCount
+		}
+		if len(usages) != 3 || usages[0].Name != "todo" || counts["work"] != 1 || counts["urgent"] != 1 || counts["todo"] != 2 {
+			t.Fatalf("unexpected usages: %+v", usages)
+		}
+
+		// 合并 urgent、todo 到 work：doc0 上的重复绑定只保留一条，目录绑定随之转移。
+		if err := repo.MergeBindings(ctx, nil, userID, work, []uint{urgent, todo}); err != nil {
+			t.Fatalf("MergeBindings failed: %v", err)
+		}
+		if err := repo.DeleteByIDs(ctx, nil, userID, []uint{urgent, todo}); err != nil {
+			t.Fatalf("DeleteByIDs failed: %v", err)
+		}
+		targetTags, err := repo.ListByTargets(ctx, nil, userID, models.TagTargetFile, []uint{fileList[0].ID, fileList[1].ID, fileList[2].ID})
+		if err != nil {
+			t.Fatalf("ListByTargets failed: %v", err)
+		}
+		if len(targetTags) != 3 {
+			t.Fatalf("expected each file to carry work once, got %+v", targetTags)
+		}
+		for _, tt := range targetTags {
+			if tt.ID != work || tt.Name != "work" {
+				t.Fatalf("expected merged tag, got %+v", tt)
+			}
+		}
+		folderTags, err := repo.ListByTargets(ctx, nil, userID, models.TagTargetFolder, []uint{folder.ID})
+		if err != nil || len(folderTags) != 1 || folderTags[0].TargetID != folder.ID {
+			t.Fatalf("expected folder binding to move, got %+v (%v)", folderTags, err)
+		}
+
+		// 彻底删除后才回收绑定。
+		if _, err := repo.DeleteOrphanBindings(ctx, nil); err != nil {
+			t.Fatalf("DeleteOrphanBindings failed: %v", err)
+		}
+		var kept int64
+		db.Model(&models.TagBinding{}).Where("user_id = ?", userID).Count(&kept)
+		if kept != 4 {
+			t.Fatalf("expected recycled binding to be kept, got %d rows", kept)
+		}
+		if err := db.Unscoped().Delete(&fileList[1]).Error; err != nil {
+			t.Fatalf("hard delete failed: %v", err)
+		}
+		if _, err := repo.DeleteOrphanBindings(ctx, nil); err != nil {
+			t.Fatalf("DeleteOrphanBindings failed: %v", err)
+		}
+		db.Model(&models.TagBinding{}).Where("user_id = ?", userID).Count(&kept)
+		if kept != 3 {
+			t.Fatalf("expected orphan binding to be removed, got %d rows", kept)
+		}
+	})
+}
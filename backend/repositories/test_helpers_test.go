@@ -14,22 +14,26 @@ import (
 	"mcloud/models"
 
 	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
 )
 
 type sqlRecorder struct {
 	gormlogger.Interface
-	mu   sync.Mutex
-	sqls []string
+	dialect string
+	mu      sync.Mutex
+	sqls    []string
 }
 
 var (
-	sqlStringLiteralPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	sqlStringLiteralPattern  = regexp.MustCompile(`"[^"]*"|'[^']*'`)
 	sqlNumericLiteralPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlPostgresBindPattern   = regexp.MustCompile(`\$\d+`)
 )
 
-func newSQLRecorder() *sqlRecorder {
+func newSQLRecorder(dialect string) *sqlRecorder {
 	base := gormlogger.New(log.New(io.Discard, "", 0), gormlogger.Config{
 		SlowThreshold:             time.Second,
 		LogLevel:                  gormlogger.Info,
@@ -37,7 +41,7 @@ func newSQLRecorder() *sqlRecorder {
 		Colorful:                  false,
 		ParameterizedQueries:      true,
 	})
-	return &sqlRecorder{Interface: base}
+	return &sqlRecorder{Interface: base, dialect: dialect}
 }
 
 func (r *sqlRecorder) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
@@ -50,6 +54,11 @@ func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, i
 	if strings.TrimSpace(sql) == "" {
 		return
 	}
+	if r.dialect == dialectPostgres {
+		// 统一为 MySQL/SQLite 的写法，便于各方言共用断言。
+		sql = strings.ReplaceAll(sql, `"`, "`")
+		sql = sqlPostgresBindPattern.ReplaceAllString(sql, "?")
+	}
 
 	r.mu.Lock()
 	r.sqls = append(r.sqls, sql)
@@ -77,38 +86,91 @@ func (r *sqlRecorder) Last() string {
 	return r.sqls[len(r.sqls)-1]
 }
 
-func newDryRunMySQL(t *testing.T) (*gorm.DB, *sqlRecorder) {
+const (
+	dialectMySQL    = "mysql"
+	dialectPostgres = "postgres"
+	dialectSQLite   = "sqlite"
+)
+
+// testDialects 为仓储测试覆盖的全部数据库方言。
+var testDialects = []string{dialectMySQL, dialectPostgres, dialectSQLite}
+
+// forEachDialect 以子测试形式在每种方言的 DryRun 连接上执行 fn。
+func forEachDialect(t *testing.T, fn func(t *testing.T, db *gorm.DB, rec *sqlRecorder)) {
+	t.Helper()
+	for _, dialect := range testDialects {
+		t.Run(dialect, func(t *testing.T) {
+			db, rec := newDryRunDB(t, dialect)
+			fn(t, db, rec)
+		})
+	}
+}
+
+// pathColumnSQL 返回路径列在比较条件中的小写 SQL；MySQL 需指定二进制排序规则才区分大小写。
+func pathColumnSQL(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == dialectMySQL {
+		return column + " collate utf8mb4_bin"
+	}
+	return column
+}
+
+// newDryRunDB 创建只生成 SQL 不执行的连接；mysql/postgres 不会真正建立网络连接。
+func newDryRunDB(t *testing.T, dialect string) (*gorm.DB, *sqlRecorder) {
 	t.Helper()
 
-	rec := newSQLRecorder()
-	dsn := fmt.Sprintf("file:repo_test_%d?mode=memory&cache=shared", time.Now().UnixNano())
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+	rec := newSQLRecorder(dialect)
+	cfg := &gorm.Config{
 		Logger:                 rec,
 		SkipDefaultTransaction: true,
-	})
+		DisableAutomaticPing:   true,
+	}
+
+	var (
+		db  *gorm.DB
+		err error
+	)
+	switch dialect {
+	case dialectMySQL:
+		db, err = gorm.Open(mysql.New(mysql.Config{
+			DSN:                       "dryrun:dryrun@tcp(127.0.0.1:3306)/mcloud?parseTime=True",
+			SkipInitializeWithVersion: true,
+		}), cfg)
+	case dialectPostgres:
+		db, err = gorm.Open(postgres.New(postgres.Config{
+			DSN: "host=127.0.0.1 user=dryrun dbname=mcloud sslmode=disable",
+		}), cfg)
+	case dialectSQLite:
+		dsn := fmt.Sprintf("file:repo_test_%d?mode=memory&cache=shared", time.Now().UnixNano())
+		db, err = gorm.Open(sqlite.Open(dsn), cfg)
+		if err == nil {
+			err = migrateSQLiteTestDB(db)
+		}
+	default:
+		t.Fatalf("unknown test dialect %q", dialect)
+	}
 	if err != nil {
-		t.Fatalf("open sqlite test db failed: %v", err)
+		t.Fatalf("open %s test db failed: %v", dialect, err)
 	}
 
+	rec.Reset()
+	return db.Session(&gorm.Session{DryRun: true}), rec
+}
+
+func migrateSQLiteTestDB(db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
-		t.Fatalf("get sql db failed: %v", err)
+		return err
 	}
 	sqlDB.SetMaxOpenConns(1)
 
-	if err := db.AutoMigrate(
+	return db.AutoMigrate(
 		&models.User{},
 		&models.Folder{},
 		&models.FileObject{},
 		&models.File{},
 		&models.UploadTask{},
 		&models.RecycleBinItem{},
-	); err != nil {
-		t.Fatalf("auto migrate failed: %v", err)
-	}
-
-	rec.Reset()
-	return db.Session(&gorm.Session{DryRun: true}), rec
+	)
 }
 
 func assertLastSQLContains(t *testing.T, rec *sqlRecorder, fragments ...string) {
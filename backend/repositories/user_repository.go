@@ -22,36 +22,43 @@ func (r *GormUserRepository) CountByUsername(_ context.Context, username string)
 	return count, err
 }
 
-func (r *GormUserRepository) Create(_ context.Context, tx *gorm.DB, user *models.User) error {
-	return useTx(r.db, tx).Create(user).Error
+func (r *GormUserRepository) Create(ctx context.Context, tx *gorm.DB, user *models.User) error {
+	return useTx(ctx, r.db, tx).Create(user).Error
 }
 
-func (r *GormUserRepository) GetByUsername(_ context.Context, tx *gorm.DB, username string) (models.User, error) {
+func (r *GormUserRepository) GetByUsername(ctx context.Context, tx *gorm.DB, username string) (models.User, error) {
 	var user models.User
-	err := useTx(r.db, tx).Where("username = ?", username).First(&user).Error
+	err := useTx(ctx, r.db, tx).Where("username = ?", username).First(&user).Error
 	return user, err
 }
 
-func (r *GormUserRepository) GetByID(_ context.Context, tx *gorm.DB, userID uint) (models.User, error) {
+func (r *GormUserRepository) GetByID(ctx context.Context, tx *gorm.DB, userID uint) (models.User, error) {
 	var user models.User
-	err := useTx(r.db, tx).First(&user, userID).Error
+	err := useTx(ctx, r.db, tx).First(&user, userID).Error
 	return user, err
 }
 
-func (r *GormUserRepository) AddStorageUsed(_ context.Context, tx *gorm.DB, userID uint, delta int64) error {
+func (r *GormUserRepository) AddStorageUsed(ctx context.Context, tx *gorm.DB, userID uint, delta int64) error {
 	if delta == 0 {
 		return nil
 	}
-	return useTx(r.db, tx).Model(&models.User{}).
+	return useTx(ctx, r.db, tx).Model(&models.User{}).
 		Where("id = ?", userID).
 		UpdateColumn("storage_used", gorm.Expr("storage_used + ?", delta)).Error
 }
 
-func (r *GormUserRepository) SubStorageUsed(_ context.Context, tx *gorm.DB, userID uint, delta int64) error {
+func (r *GormUserRepository) SubStorageUsed(ctx context.Context, tx *gorm.DB, userID uint, delta int64) error {
 	if delta <= 0 {
 		return nil
 	}
-	return useTx(r.db, tx).Model(&models.User{}).
+	return useTx(ctx, r.db, tx).Model(&models.User{}).
 		Where("id = ?", userID).
-		UpdateColumn("storage_used", gorm.Expr("GREATEST(storage_used - ?, 0)", delta)).Error
+		UpdateColumn("storage_used", gorm.Expr("CASE WHEN storage_used > ? THEN storage_used - ? ELSE 0 END", delta, delta)).Error
+}
+
+// UpdateRecycleRetentionDays 设置用户回收站保留天数，days 为空表示恢复系统默认值。
+func (r *GormUserRepository) UpdateRecycleRetentionDays(ctx context.Context, tx *gorm.DB, userID uint, days *int) error {
+	return useTx(ctx, r.db, tx).Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("recycle_retention_days", days).Error
 }
@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mcloud/models"
+	"mcloud/repositories"
+	"mcloud/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxAlbumNameLength        = 50
+	maxAlbumDescriptionLength = 500
+	// maxAlbumItems 为单个相册的条目上限，手动排序需要一次性读取全部条目。
+	maxAlbumItems = 5000
+	// maxAlbumBatchFiles 为单次添加、移除或排序的文件数量上限。
+	maxAlbumBatchFiles = 500
+)
+
+// AlbumService 定义相册管理：相册只引用已有文件，不移动文件。
+type AlbumService interface {
+	ListAlbums(ctx context.Context, userID uint) ([]AlbumListItem, error)
+	GetAlbum(ctx context.Context, userID uint, albumID uint) (AlbumListItem, error)
+	CreateAlbum(ctx context.Context, userID uint, name string, description string) (models.Album, error)
+	UpdateAlbum(ctx context.Context, userID uint, albumID uint, in UpdateAlbumInput) (models.Album, error)
+	DeleteAlbum(ctx context.Context, userID uint, albumID uint) error
+	// ListAlbumItems 按手动排序分页列出相册中正常状态的文件，回收站中的文件不展示。
+	ListAlbumItems(ctx context.Context, userID uint, albumID uint, page int, pageSize int) (AlbumItemsOutput, error)
+	AddAlbumItems(ctx context.Context, userID uint, albumID uint, fileIDs []uint) (int64, error)
+	RemoveAlbumItems(ctx context.Context, userID uint, albumID uint, fileIDs []uint) error
+	// ReorderAlbumItems 将给定文件按顺序排到相册最前，其余条目保持原有相对顺序排在其后。
+	ReorderAlbumItems(ctx context.Context, userID uint, albumID uint, fileIDs []uint) error
+	ShareAlbum(ctx context.Context, userID uint, albumID uint) (models.Album, error)
+	UnshareAlbum(ctx context.Context, userID uint, albumID uint) error
+	// GetSharedAlbum 通过分享令牌匿名查看相册内容。
+	GetSharedAlbum(ctx context.Context, token string, page int, pageSize int) (SharedAlbumOutput, error)
+	// GetSharedFileAccess 返回分享相册中某个文件的原图或缩略图访问信息。
+	GetSharedFileAccess(ctx context.Context, token string, fileID uint, thumbnail bool) (FileAccessOutput, error)
+}
+
+// AlbumListItem 为相册列表项，DisplayCoverFileID 为实际展示的封面文件，相册为空时为 null。
+type AlbumListItem struct {
+	models.Album
+	ItemCount          int64 `json:"item_count"`
+	DisplayCoverFileID *uint `json:"display_cover_file_id"`
+}
+
+// UpdateAlbumInput 为相册更新参数，nil 字段保持不变；CoverFileID 为 0 表示取消手动封面。
+type UpdateAlbumInput struct {
+	Name        *string
+	Description *string
+	CoverFileID *uint
+}
+
+// AlbumItemsOutput 为相册内容分页返回体。
+type AlbumItemsOutput struct {
+	Items      []models.File        `json:"items"`
+	Pagination utils.PaginationData `json:"pagination"`
+}
+
+// SharedAlbumItem 为分享相册中的一个文件，只暴露展示所需字段。
+type SharedAlbumItem struct {
+	FileID       uint   `json:"file_id"`
+	Name         string `json:"name"`
+	MimeType     string `json:"mime_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	HasThumbnail bool   `json:"has_thumbnail"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	PreviewURL   string `json:"preview_url"`
+}
+
+// SharedAlbumOutput 为分享相册的匿名查看结果。
+type SharedAlbumOutput struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Items       []SharedAlbumItem    `json:"items"`
+	Pagination  utils.PaginationData `json:"pagination"`
+}
+
+type albumService struct {
+	txManager   TxManager
+	albums      repositories.AlbumRepository
+	files       repositories.FileRepository
+	tagAttacher tagAttacher
+}
+
+// NewAlbumService 创建相册服务实例。
+func NewAlbumService(txManager TxManager, albums repositories.AlbumRepository, files repositories.FileRepository, tags repositories.TagRepository) AlbumService {
+	return &albumService{txManager: txManager, albums: albums, files: files, tagAttacher: tagAttacher{tags: tags}}
+}
+
+// uniqueIDsInOrder 去掉 0 与重复 ID，保留首次出现的顺序。
+func uniqueIDsInOrder(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// isAlbumMedia 判断文件能否加入相册：只接受图片与视频。
+func isAlbumMedia(obj models.FileObject) bool {
+	if obj.IsImage {
+		return true
+	}
+	category := mimeCategory(obj.MimeType)
+	return category == "image" || category == "video"
+}
+
+func normalizeAlbumName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", newAppError(http.StatusBadRequest, "相册名称不能为空", nil)
+	}
+	if utf8.RuneCountInString(name) > maxAlbumNameLength {
+		return "", newAppError(http.StatusBadRequest, fmt.Sprintf("相册名称不能超过 %d 个字符", maxAlbumNameLength), nil)
+	}
+	return name, nil
+}
+
+func normalizeAlbumDescription(description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > maxAlbumDescriptionLength {
+		return "", newAppError(http.StatusBadRequest, fmt.Sprintf("相册描述不能超过 %d 个字符", maxAlbumDescriptionLength), nil)
+	}
+	return description, nil
+}
+
+func (s *albumService) getOwnedAlbum(ctx context.Context, userID uint, albumID uint) (models.Album, error) {
+	album, err := s.albums.GetByIDAndUser(ctx, nil, albumID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Album{}, newAppError(http.StatusNotFound, "相册不存在", nil)
+		}
+		return models.Album{}, newAppError(http.StatusInternalServerError, "查询相册失败", err)
+	}
+	return album, nil
+}
+
+// resolveCovers 计算每个相册实际展示的封面：手动封面仍为正常状态时使用它，否则取排序最靠前的正常文件。
+func (s *albumService) resolveCovers(ctx context.Context, userID uint, summaries []repositories.AlbumSummary) []AlbumListItem {
+	coverIDs := make([]uint, 0, len(summaries))
+	for _, summary := range summaries {
+		if summary.CoverFileID != nil {
+			coverIDs = append(coverIDs, *summary.CoverFileID)
+		}
+	}
+	active := make(map[uint]bool, len(coverIDs))
+	if len(coverIDs) > 0 {
+		files, err := s.files.GetByIDsAndUser(ctx, nil, userID, coverIDs, false)
+		// 封面只是展示信息，查询失败时退回默认封面。
+		warnOnError(ctx, "查询相册封面", err)
+		for _, file := range files {
+			active[file.ID] = true
+		}
+	}
+
+	items := make([]AlbumListItem, 0, len(summaries))
+	for _, summary := range summaries {
+		item := AlbumListItem{Album: summary.Album, ItemCount: summary.ItemCount, DisplayCoverFileID: summary.FirstFileID}
+		if summary.CoverFileID != nil && active[*summary.CoverFileID] {
+			item.DisplayCoverFileID = summary.CoverFileID
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (s *albumService) ListAlbums(ctx context.Context, userID uint) ([]AlbumListItem, error) {
+	summaries, err := s.albums.ListSummariesByUser(ctx, nil, userID)
+	if err != nil {
+		return nil, newAppError(http.StatusInternalServerError, "获取相册列表失败", err)
+	}
+	return s.resolveCovers(ctx, userID, summaries), nil
+}
+
+func (s *albumService) GetAlbum(ctx context.Context, userID uint, albumID uint) (AlbumListItem, error) {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return AlbumListItem{}, err
+	}
+	count, err := s.albums.CountActiveItems(ctx, nil, album.ID)
+	if err != nil {
+		return AlbumListItem{}, newAppError(http.StatusInternalServerError, "统计相册内容失败", err)
+	}
+	summary := repositories.AlbumSummary{Album: album, ItemCount: count}
+	if count > 0 {
+		first, err := s.albums.ListActiveFiles(ctx, nil, repositories.AlbumItemsInput{AlbumID: album.ID, Limit: 1})
+		if err != nil {
+			return AlbumListItem{}, newAppError(http.StatusInternalServerError, "获取相册内容失败", err)
+		}
+		if len(first) > 0 {
+			summary.FirstFileID = &first[0].ID
+		}
+	}
+	return s.resolveCovers(ctx, userID, []repositories.AlbumSummary{summary})[0], nil
+}
+
+func (s *albumService) CreateAlbum(ctx context.Context, userID uint, name string, description string) (models.Album, error) {
+	name, err := normalizeAlbumName(name)
+	if err != nil {
+		return models.Album{}, err
+	}
+	description, err = normalizeAlbumDescription(description)
+	if err != nil {
+		return models.Album{}, err
+	}
+
+	album := models.Album{UserID: userID, Name: name, Description: description}
+	if err := s.albums.Create(ctx, nil, &album); err != nil {
+		return models.Album{}, newAppError(http.StatusInternalServerError, "创建相册失败", err)
+	}
+	return album, nil
+}
+
+func (s *albumService) UpdateAlbum(ctx context.Context, userID uint, albumID uint, in UpdateAlbumInput) (models.Album, error) {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return models.Album{}, err
+	}
+
+	updates := map[string]interface{}{}
+	if in.Name != nil {
+		name, err := normalizeAlbumName(*in.Name)
+		if err != nil {
+			return models.Album{}, err
+		}
+		album.Name = name
+		updates["name"] = name
+	}
+	if in.Description != nil {
+		description, err := normalizeAlbumDescription(*in.Description)
+		if err != nil {
+			return models.Album{}, err
+		}
+		album.Description = description
+		updates["description"] = description
+	}
+	if in.CoverFileID != nil {
+		if *in.CoverFileID == 0 {
+			album.CoverFileID = nil
+			updates["cover_file_id"] = nil
+		} else {
+			ok, err := s.albums.HasActiveFile(ctx, nil, album.ID, *in.CoverFileID)
+			if err != nil {
+				return models.Album{}, newAppError(http.StatusInternalServerError, "校验封面失败", err)
+			}
+			if !ok {
+				return models.Album{}, newAppError(http.StatusBadRequest, "封面必须是相册中的文件", nil)
+			}
+			album.CoverFileID = in.CoverFileID
+			updates["cover_file_id"] = *in.CoverFileID
+		}
+	}
+	if len(updates) == 0 {
+		return album, nil
+	}
+
+	album.UpdatedAt = time.Now()
+	updates["updated_at"] = album.UpdatedAt
+	if err := s.albums.UpdateByID(ctx, nil, album.ID, updates); err != nil {
+		return models.Album{}, newAppError(http.StatusInternalServerError, "更新相册失败", err)
+	}
+	return album, nil
+}
+
+func (s *albumService) DeleteAlbum(ctx context.Context, userID uint, albumID uint) error {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return s.albums.DeleteByID(ctx, tx, album.ID)
+	})
+	if err != nil {
+		return newAppError(http.StatusInternalServerError, "删除相册失败", err)
+	}
+	return nil
+}
+
+func normalizeAlbumPage(page int, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 50
+	}
+	return page, pageSize
+}
+
+// newPaginationData 按总数计算分页信息，没有数据时也视为 1 页。
+func newPaginationData(page int, pageSize int, total int64) utils.PaginationData {
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	return utils.PaginationData{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
+// listActiveFiles 分页读取相册中正常状态的文件与总数。
+func (s *albumService) listActiveFiles(ctx context.Context, albumID uint, page int, pageSize int) ([]models.File, int64, error) {
+	total, err := s.albums.CountActiveItems(ctx, nil, albumID)
+	if err != nil {
+		return nil, 0, newAppError(http.StatusInternalServerError, "统计相册内容失败", err)
+	}
+	files, err := s.albums.ListActiveFiles(ctx, nil, repositories.AlbumItemsInput{
+		AlbumID: albumID,
+		Offset:  (page - 1) * pageSize,
+		Limit:   pageSize,
+	})
+	if err != nil {
+		return nil, 0, newAppError(http.StatusInternalServerError, "获取相册内容失败", err)
+	}
+	return files, total, nil
+}
+
+func (s *albumService) ListAlbumItems(ctx context.Context, userID uint, albumID uint, page int, pageSize int) (AlbumItemsOutput, error) {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return AlbumItemsOutput{}, err
+	}
+	page, pageSize = normalizeAlbumPage(page, pageSize)
+	files, total, err := s.listActiveFiles(ctx, album.ID, page, pageSize)
+	if err != nil {
+		return AlbumItemsOutput{}, err
+	}
+	s.tagAttacher.files(ctx, userID, files)
+	return AlbumItemsOutput{Items: files, Pagination: newPaginationData(page, pageSize, total)}, nil
+}
+
+// normalizeAlbumFileIDs 去重并校验单次操作的文件数量。
+func normalizeAlbumFileIDs(fileIDs []uint) ([]uint, error) {
+	fileIDs = uniqueIDsInOrder(fileIDs)
+	if len(fileIDs) == 0 {
+		return nil, newAppError(http.StatusBadRequest, "请指定文件", nil)
+	}
+	if len(fileIDs) > maxAlbumBatchFiles {
+		return nil, newAppError(http.StatusBadRequest, "单次操作的文件数量过多", nil)
+	}
+	return fileIDs, nil
+}
+
+func (s *albumService) AddAlbumItems(ctx context.Context, userID uint, albumID uint, fileIDs []uint) (int64, error) {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return 0, err
+	}
+	fileIDs, err = normalizeAlbumFileIDs(fileIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	// 只能添加自己的正常状态文件，回收站中的文件视为不存在。
+	files, err := s.files.GetByIDsAndUser(ctx, nil, userID, fileIDs, true)
+	if err != nil {
+		return 0, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+	}
+	if len(files) != len(fileIDs) {
+		return 0, newAppError(http.StatusNotFound, "部分文件不存在", nil)
+	}
+	for _, file := range files {
+		if !isAlbumMedia(file.FileObject) {
+			return 0, newAppError(http.StatusBadRequest, "相册只能添加图片或视频", nil)
+		}
+	}
+
+	var added int64
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		existing, err := s.albums.ListItems(ctx, tx, album.ID)
+		if err != nil {
+			return err
+		}
+		if len(existing)+len(fileIDs) > maxAlbumItems {
+			return newAppError(http.StatusBadRequest, fmt.Sprintf("单个相册最多包含 %d 个文件", maxAlbumItems), nil)
+		}
+		if added, err = s.albums.AddItems(ctx, tx, album.ID, fileIDs); err != nil {
+			return err
+		}
+		return s.albums.UpdateByID(ctx, tx, album.ID, map[string]interface{}{"updated_at": time.Now()})
+	})
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			return 0, err
+		}
+		return 0, newAppError(http.StatusInternalServerError, "添加到相册失败", err)
+	}
+	return added, nil
+}
+
+func (s *albumService) RemoveAlbumItems(ctx context.Context, userID uint, albumID uint, fileIDs []uint) error {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	fileIDs, err = normalizeAlbumFileIDs(fileIDs)
+	if err != nil {
+		return err
+	}
+
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if _, err := s.albums.RemoveItems(ctx, tx, album.ID, fileIDs); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"updated_at": time.Now()}
+		// 移出相册的文件不能继续做封面。
+		if album.CoverFileID != nil {
+			for _, fileID := range fileIDs {
+				if fileID == *album.CoverFileID {
+					updates["cover_file_id"] = nil
+					break
+				}
+			}
+		}
+		return s.albums.UpdateByID(ctx, tx, album.ID, updates)
+	})
+	if err != nil {
+		return newAppError(http.StatusInternalServerError, "从相册移除失败", err)
+	}
+	return nil
+}
+
+func (s *albumService) ReorderAlbumItems(ctx context.Context, userID uint, albumID uint, fileIDs []uint) error {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	fileIDs = uniqueIDsInOrder(fileIDs)
+	if len(fileIDs) == 0 {
+		return newAppError(http.StatusBadRequest, "请指定文件", nil)
+	}
+	if len(fileIDs) > maxAlbumItems {
+		return newAppError(http.StatusBadRequest, "单次操作的文件数量过多", nil)
+	}
+
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		items, err := s.albums.ListItems(ctx, tx, album.ID)
+		if err != nil {
+			return err
+		}
+		current := make(map[uint]int, len(items))
+		for _, item := range items {
+			current[item.FileID] = item.Position
+		}
+		listed := make(map[uint]bool, len(fileIDs))
+		for _, fileID := range fileIDs {
+			if _, ok := current[fileID]; !ok {
+				return newAppError(http.StatusBadRequest, "排序列表包含不在相册中的文件", nil)
+			}
+			listed[fileID] = true
+		}
+
+		// 给定文件依次占据最前的位置，其余条目按原顺序接在后面；只写入位置有变化的条目。
+		order := append([]uint{}, fileIDs...)
+		for _, item := range items {
+			if !listed[item.FileID] {
+				order = append(order, item.FileID)
+			}
+		}
+		positions := make(map[uint]int)
+		for i, fileID := range order {
+			if current[fileID] != i+1 {
+				positions[fileID] = i + 1
+			}
+		}
+		if err := s.albums.UpdateItemPositions(ctx, tx, album.ID, positions); err != nil {
+			return err
+		}
+		return s.albums.UpdateByID(ctx, tx, album.ID, map[string]interface{}{"updated_at": time.Now()})
+	})
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			return err
+		}
+		return newAppError(http.StatusInternalServerError, "调整相册顺序失败", err)
+	}
+	return nil
+}
+
+// newShareToken 生成 32 位十六进制的随机分享令牌。
+func newShareToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *albumService) ShareAlbum(ctx context.Context, userID uint, albumID uint) (models.Album, error) {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return models.Album{}, err
+	}
+	// 已分享的相册直接返回原令牌，重复点击不会让已发出的链接失效。
+	if album.ShareToken != nil {
+		return album, nil
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return models.Album{}, newAppError(http.StatusInternalServerError, "生成分享令牌失败", err)
+	}
+	now := time.Now()
+	if err := s.albums.UpdateByID(ctx, nil, album.ID, map[string]interface{}{"share_token": token, "shared_at": now}); err != nil {
+		return models.Album{}, newAppError(http.StatusInternalServerError, "开启分享失败", err)
+	}
+	album.ShareToken, album.SharedAt = &token, &now
+	return album, nil
+}
+
+func (s *albumService) UnshareAlbum(ctx context.Context, userID uint, albumID uint) error {
+	album, err := s.getOwnedAlbum(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	if album.ShareToken == nil {
+		return nil
+	}
+	if err := s.albums.UpdateByID(ctx, nil, album.ID, map[string]interface{}{"share_token": nil, "shared_at": nil}); err != nil {
+		return newAppError(http.StatusInternalServerError, "取消分享失败", err)
+	}
+	return nil
+}
+
+func (s *albumService) getSharedAlbum(ctx context.Context, token string) (models.Album, error) {
+	if token == "" {
+		return models.Album{}, newAppError(http.StatusNotFound, "分享不存在或已取消", nil)
+	}
+	album, err := s.albums.GetByShareToken(ctx, nil, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Album{}, newAppError(http.StatusNotFound, "分享不存在或已取消", nil)
+		}
+		return models.Album{}, newAppError(http.StatusInternalServerError, "查询分享失败", err)
+	}
+	return album, nil
+}
+
+func (s *albumService) GetSharedAlbum(ctx context.Context, token string, page int, pageSize int) (SharedAlbumOutput, error) {
+	album, err := s.getSharedAlbum(ctx, token)
+	if err != nil {
+		return SharedAlbumOutput{}, err
+	}
+	page, pageSize = normalizeAlbumPage(page, pageSize)
+	files, total, err := s.listActiveFiles(ctx, album.ID, page, pageSize)
+	if err != nil {
+		return SharedAlbumOutput{}, err
+	}
+
+	items := make([]SharedAlbumItem, 0, len(files))
+	for _, file := range files {
+		base := fmt.Sprintf("/api/shared/albums/%s/files/%d", token, file.ID)
+		item := SharedAlbumItem{
+			FileID:       file.ID,
+			Name:         file.OriginalName,
+			MimeType:     file.FileObject.MimeType,
+			Width:        file.FileObject.Width,
+			Height:       file.FileObject.Height,
+			HasThumbnail: file.FileObject.ThumbnailPath != "",
+			PreviewURL:   base + "/preview",
+		}
+		if item.HasThumbnail {
+			item.ThumbnailURL = base + "/thumbnail"
+		}
+		items = append(items, item)
+	}
+	return SharedAlbumOutput{
+		Name:        album.Name,
+		Description: album.Description,
+		Items:       items,
+		Pagination:  newPaginationData(page, pageSize, total),
+	}, nil
+}
+
+func (s *albumService) GetSharedFileAccess(ctx context.Context, token string, fileID uint, thumbnail bool) (FileAccessOutput, error) {
+	album, err := s.getSharedAlbum(ctx, token)
+	if err != nil {
+		return FileAccessOutput{}, err
+	}
+	// 只允许访问相册中仍处于正常状态的文件，进入回收站的文件对分享访问者不可见。
+	ok, err := s.albums.HasActiveFile(ctx, nil, album.ID, fileID)
+	if err != nil {
+		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+	}
+	if !ok {
+		return FileAccessOutput{}, newAppError(http.StatusNotFound, "文件不存在", nil)
+	}
+	file, err := s.files.GetByIDAndUser(ctx, nil, fileID, album.UserID, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return FileAccessOutput{}, newAppError(http.StatusNotFound, "文件不存在", nil)
+		}
+		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+	}
+	if thumbnail {
+		return thumbnailAccessInfo(file)
+	}
+	return fileAccessInfo(file)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"mcloud/config"
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// fakeAlbumRepo 在内存中保存相册与条目，files 中存在的文件视为正常状态。
+type fakeAlbumRepo struct {
+	albums    map[uint]models.Album
+	items     map[uint][]models.AlbumItem
+	files     *quickAccessFileRepo
+	updates   []map[string]interface{}
+	positions map[uint]int
+}
+
+func newAlbumFixture() (AlbumService, *fakeAlbumRepo) {
+	files := &quickAccessFileRepo{fakeFileRepo: newFakeFileRepo(), files: map[uint]models.File{
+		1: {ID: 1, UserID: 7, OriginalName: "a.jpg", FileObject: models.FileObject{IsImage: true, MimeType: "image/jpeg", FilePath: "a.jpg"}},
+		2: {ID: 2, UserID: 7, OriginalName: "b.mp4", FileObject: models.FileObject{MimeType: "video/mp4", FilePath: "b.mp4"}},
+		3: {ID: 3, UserID: 7, OriginalName: "c.jpg", FileObject: models.FileObject{IsImage: true, MimeType: "image/jpeg", FilePath: "c.jpg"}},
+		4: {ID: 4, UserID: 7, OriginalName: "notes.txt", FileObject: models.FileObject{MimeType: "text/plain"}},
+		5: {ID: 5, UserID: 8, OriginalName: "other.jpg", FileObject: models.FileObject{IsImage: true, MimeType: "image/jpeg"}},
+	}}
+	token := "share-token"
+	repo := &fakeAlbumRepo{
+		albums: map[uint]models.Album{
+			1: {ID: 1, UserID: 7, Name: "Trip", ShareToken: &token},
+		},
+		items: map[uint][]models.AlbumItem{},
+		files: files,
+	}
+	return NewAlbumService(fakeTxManager{}, repo, files, nil), repo
+}
+
+func (r *fakeAlbumRepo) Create(_ context.Context, _ *gorm.DB, album *models.Album) error {
+	album.ID = uint(len(r.albums) + 1)
+	r.albums[album.ID] = *album
+	return nil
+}
+
+func (r *fakeAlbumRepo) GetByIDAndUser(_ context.Context, _ *gorm.DB, albumID uint, userID uint) (models.Album, error) {
+	album, ok := r.albums[albumID]
+	if !ok || album.UserID != userID {
+		return models.Album{}, gorm.ErrRecordNotFound
+	}
+	return album, nil
+}
+
+func (r *fakeAlbumRepo) GetByShareToken(_ context.Context, _ *gorm.DB, token string) (models.Album, error) {
+	for _, album := range r.albums {
+		if album.ShareToken != nil && *album.ShareToken == token {
+			return album, nil
+		}
+	}
+	return models.Album{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeAlbumRepo) ListSummariesByUser(context.Context, *gorm.DB, uint) ([]repositories.AlbumSummary, error) {
+	return nil, nil
+}
+
+func (r *fakeAlbumRepo) UpdateByID(_ context.Context, _ *gorm.DB, albumID uint, updates map[string]interface{}) error {
+	r.updates = append(r.updates, updates)
+	album := r.albums[albumID]
+	if v, ok := updates["cover_file_id"]; ok {
+		if id, ok := v.(uint); ok {
+			album.CoverFileID = &id
+		} else {
+			album.CoverFileID = nil
+		}
+	}
+	r.albums[albumID] = album
+	return nil
+}
+
+func (r *fakeAlbumRepo) DeleteByID(_ context.Context, _ *gorm.DB, albumID uint) error {
+	delete(r.albums, albumID)
+	delete(r.items, albumID)
+	return nil
+}
+
+func (r *fakeAlbumRepo) AddItems(_ context.Context, _ *gorm.DB, albumID uint, fileIDs []uint) (int64, error) {
+	var added int64
+	for _, fileID := range fileIDs {
+		exists := false
+		for _, item := range r.items[albumID] {
+			exists = exists || item.FileID == fileID
+		}
+		if !exists {
+			r.items[albumID] = append(r.items[albumID], models.AlbumItem{AlbumID: albumID, FileID: fileID, Position: len(r.items[albumID]) + 1})
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (r *fakeAlbumRepo) RemoveItems(_ context.Context, _ *gorm.DB, albumID uint, fileIDs []uint) (int64, error) {
+	remove := make(map[uint]bool)
+	for _, id := range fileIDs {
+		remove[id] = true
+	}
+	kept := r.items[albumID][:0]
+	for _, item := range r.items[albumID] {
+		if !remove[item.FileID] {
+			kept = append(kept, item)
+		}
+	}
+	removed := int64(len(r.items[albumID]) - len(kept))
+	r.items[albumID] = kept
+	return removed, nil
+}
+
+func (r *fakeAlbumRepo) ListItems(_ context.Context, _ *gorm.DB, albumID uint) ([]models.AlbumItem, error) {
+	items := append([]models.AlbumItem{}, r.items[albumID]...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Position < items[j].Position })
+	return items, nil
+}
+
+func (r *fakeAlbumRepo) UpdateItemPositions(_ context.Context, _ *gorm.DB, albumID uint, positions map[uint]int) error {
+	r.positions = positions
+	for i, item := range r.items[albumID] {
+		if position, ok := positions[item.FileID]; ok {
+			r.items[albumID][i].Position = position
+		}
+	}
+	return nil
+}
+
+func (r *fakeAlbumRepo) activeFiles(albumID uint) []models.File {
+	items, _ := r.ListItems(context.Background(), nil, albumID)
+	var files []models.File
+	for _, item := range items {
+		if file, ok := r.files.files[item.FileID]; ok {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (r *fakeAlbumRepo) CountActiveItems(_ context.Context, _ *gorm.DB, albumID uint) (int64, error) {
+	return int64(len(r.activeFiles(albumID))), nil
+}
+
+func (r *fakeAlbumRepo) ListActiveFiles(_ context.Context, _ *gorm.DB, in repositories.AlbumItemsInput) ([]models.File, error) {
+	files := r.activeFiles(in.AlbumID)
+	if in.Offset >= len(files) {
+		return nil, nil
+	}
+	return files[in.Offset:min(len(files), in.Offset+in.Limit)], nil
+}
+
+func (r *fakeAlbumRepo) HasActiveFile(_ context.Context, _ *gorm.DB, albumID uint, fileID uint) (bool, error) {
+	for _, file := range r.activeFiles(albumID) {
+		if file.ID == fileID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeAlbumRepo) DeleteOrphanItems(context.Context, *gorm.DB) (int64, error) {
+	return 0, nil
+}
+
+func TestAlbumServiceAddItemsAcceptsOnlyOwnedMedia(t *testing.T) {
+	svc, repo := newAlbumFixture()
+	ctx := context.Background()
+
+	_, err := svc.AddAlbumItems(ctx, 7, 1, []uint{1, 4})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	_, err = svc.AddAlbumItems(ctx, 7, 1, []uint{1, 5})
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	_, err = svc.AddAlbumItems(ctx, 8, 1, []uint{5})
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	if len(repo.items[1]) != 0 {
+		t.Fatalf("expected rejected batches to add nothing, got %+v", repo.items[1])
+	}
+
+	added, err := svc.AddAlbumItems(ctx, 7, 1, []uint{3, 0, 1, 3, 2})
+	if err != nil || added != 3 {
+		t.Fatalf("expected 3 added, got %d (%v)", added, err)
+	}
+	if items := repo.items[1]; items[0].FileID != 3 || items[1].FileID != 1 || items[2].FileID != 2 {
+		t.Fatalf("expected request order to be kept, got %+v", items)
+	}
+}
+
+func TestAlbumServiceReorderMovesListedFilesFirst(t *testing.T) {
+	svc, repo := newAlbumFixture()
+	ctx := context.Background()
+	if _, err := svc.AddAlbumItems(ctx, 7, 1, []uint{1, 2, 3}); err != nil {
+		t.Fatalf("AddAlbumItems failed: %v", err)
+	}
+
+	if err := svc.ReorderAlbumItems(ctx, 7, 1, []uint{3, 1}); err != nil {
+		t.Fatalf("ReorderAlbumItems failed: %v", err)
+	}
+	// 未列出的文件 2 排在最后，只写入位置发生变化的条目。
+	if len(repo.positions) != 3 || repo.positions[3] != 1 || repo.positions[1] != 2 || repo.positions[2] != 3 {
+		t.Fatalf("unexpected positions: %+v", repo.positions)
+	}
+	if err := svc.ReorderAlbumItems(ctx, 7, 1, []uint{3}); err != nil {
+		t.Fatalf("ReorderAlbumItems failed: %v", err)
+	}
+	if len(repo.positions) != 0 {
+		t.Fatalf("expected unchanged order to write nothing, got %+v", repo.positions)
+	}
+
+	err := svc.ReorderAlbumItems(ctx, 7, 1, []uint{3, 4})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
+
+func TestAlbumServiceCoverFollowsAlbumContents(t *testing.T) {
+	svc, repo := newAlbumFixture()
+	ctx := context.Background()
+	if _, err := svc.AddAlbumItems(ctx, 7, 1, []uint{1, 3}); err != nil {
+		t.Fatalf("AddAlbumItems failed: %v", err)
+	}
+
+	cover := uint(2)
+	_, err := svc.UpdateAlbum(ctx, 7, 1, UpdateAlbumInput{CoverFileID: &cover})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+
+	cover = 3
+	album, err := svc.UpdateAlbum(ctx, 7, 1, UpdateAlbumInput{CoverFileID: &cover})
+	if err != nil || album.CoverFileID == nil || *album.CoverFileID != 3 {
+		t.Fatalf("expected cover 3, got %+v (%v)", album, err)
+	}
+	got, err := svc.GetAlbum(ctx, 7, 1)
+	if err != nil || got.ItemCount != 2 || *got.DisplayCoverFileID != 3 {
+		t.Fatalf("unexpected album: %+v (%v)", got, err)
+	}
+
+	// 封面进入回收站时退回排序最靠前的文件；移出相册时清除手动封面。
+	delete(repo.files.files, 3)
+	got, err = svc.GetAlbum(ctx, 7, 1)
+	if err != nil || got.ItemCount != 1 || *got.DisplayCoverFileID != 1 {
+		t.Fatalf("expected fallback cover, got %+v (%v)", got, err)
+	}
+	if err := svc.RemoveAlbumItems(ctx, 7, 1, []uint{3}); err != nil {
+		t.Fatalf("RemoveAlbumItems failed: %v", err)
+	}
+	if repo.albums[1].CoverFileID != nil {
+		t.Fatalf("expected cover to be cleared, got %v", *repo.albums[1].CoverFileID)
+	}
+}
+
+func TestAlbumServiceSharedAccessLimitedToActiveItems(t *testing.T) {
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{Storage: config.StorageConfig{BasePath: baseDir}}
+	if err := os.WriteFile(filepath.Join(baseDir, "a.jpg"), []byte("jpeg"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	svc, repo := newAlbumFixture()
+	ctx := context.Background()
+	if _, err := svc.AddAlbumItems(ctx, 7, 1, []uint{1, 3}); err != nil {
+		t.Fatalf("AddAlbumItems failed: %v", err)
+	}
+
+	out, err := svc.GetSharedAlbum(ctx, "share-token", 1, 50)
+	if err != nil || len(out.Items) != 2 || out.Items[0].PreviewURL != "/api/shared/albums/share-token/files/1/preview" || out.Items[0].ThumbnailURL != "" {
+		t.Fatalf("unexpected shared album: %+v (%v)", out, err)
+	}
+	info, err := svc.GetSharedFileAccess(ctx, "share-token", 1, false)
+	if err != nil || info.AbsPath != filepath.Join(baseDir, "a.jpg") {
+		t.Fatalf("unexpected access info: %+v (%v)", info, err)
+	}
+
+	_, err = svc.GetSharedFileAccess(ctx, "share-token", 2, false)
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	delete(repo.files.files, 3)
+	_, err = svc.GetSharedFileAccess(ctx, "share-token", 3, false)
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	_, err = svc.GetSharedAlbum(ctx, "unknown", 1, 50)
+	assertAppErrorCode(t, err, http.StatusNotFound)
+
+	if err := svc.UnshareAlbum(ctx, 7, 1); err != nil {
+		t.Fatalf("UnshareAlbum failed: %v", err)
+	}
+	if last := repo.updates[len(repo.updates)-1]; last["share_token"] != nil {
+		t.Fatalf("expected token to be cleared, got %+v", last)
+	}
+}
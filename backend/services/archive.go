@@ -0,0 +1,615 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mcloud/config"
+	"mcloud/logger"
+	"mcloud/models"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"gorm.io/gorm"
+)
+
+// 压缩包格式，由文件头识别而非扩展名。
+const (
+	archiveFormatZip   = "zip"
+	archiveFormatTar   = "tar"
+	archiveFormatTarGz = "tar.gz"
+	// archiveRatioFloor 为压缩比检查的起点：解压后不超过该大小的条目不检查压缩比，避免误伤高度重复的小文件。
+	archiveRatioFloor = 16 << 20
+	// maxArchiveFolderRenameAttempts 为解压目标目录重名时最多尝试的候选名称数。
+	maxArchiveFolderRenameAttempts = 100
+)
+
+var (
+	errArchiveTooLarge       = errors.New("压缩包解压后过大")
+	errArchiveTooManyEntries = errors.New("压缩包条目过多")
+	errArchiveUnsupported    = errors.New("不支持的压缩包格式")
+)
+
+// ArchiveEntry 为压缩包中的一个条目；Path 为规范化后的相对路径，目录以 IsDir 标记。
+type ArchiveEntry struct {
+	Path           string    `json:"path"`
+	Size           int64     `json:"size"`
+	CompressedSize int64     `json:"compressed_size,omitempty"` // 仅 zip 提供
+	ModTime        time.Time `json:"mod_time"`
+	IsDir          bool      `json:"is_dir"`
+}
+
+// ArchiveListOutput 为压缩包条目列表；Skipped 为因路径不安全或类型不支持（如符号链接）而忽略的条目数。
+type ArchiveListOutput struct {
+	Format    string         `json:"format"`
+	Entries   []ArchiveEntry `json:"entries"`
+	FileCount int            `json:"file_count"`
+	TotalSize int64          `json:"total_size"`
+	Skipped   int            `json:"skipped"`
+}
+
+// ArchiveEntryStream 为单个条目的内容流，调用方读完后必须 Close。
+type ArchiveEntryStream struct {
+	Entry       ArchiveEntry
+	Name        string
+	ContentType string
+	Reader      io.ReadCloser
+}
+
+// ArchiveExtractInput 为服务端解压参数；FolderID 为 0 时解压到压缩包所在目录。
+type ArchiveExtractInput struct {
+	FolderID uint
+}
+
+// ArchiveSkippedEntry 为解压时跳过的文件及原因。
+type ArchiveSkippedEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ArchiveExtractOutput 为服务端解压结果；文件解压到新建的 Folder 中。
+type ArchiveExtractOutput struct {
+	Folder      models.Folder         `json:"folder"`
+	FileCount   int                   `json:"file_count"`
+	FolderCount int                   `json:"folder_count"`
+	Skipped     []ArchiveSkippedEntry `json:"skipped"`
+}
+
+// archiveCursor 顺序遍历压缩包条目：zip 按中央目录遍历，tar 只能顺序读取，当前条目的内容在调用 next 前有效。
+type archiveCursor struct {
+	format  string
+	file    *os.File
+	zip     *zip.Reader
+	zipNext int
+	zipCur  *zip.File
+	tar     *tar.Reader
+	// seen 为已遍历的原始条目数（含被跳过的条目），用于限制条目总数。
+	seen    int
+	skipped int
+}
+
+// openArchive 按文件头识别 zip、tar 与 tar.gz；gzip 流的解压总量受限，防止小文件解压出海量数据。
+func openArchive(absPath string) (*archiveCursor, error) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var head [512]byte
+	n, _ := io.ReadFull(f, head[:])
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	c := &archiveCursor{file: f}
+	switch {
+	case n >= 4 && (bytes.Equal(head[:4], []byte("PK\x03\x04")) || bytes.Equal(head[:4], []byte("PK\x05\x06"))):
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%w: %v", errArchiveUnsupported, err)
+		}
+		if len(zr.File) > config.AppConfig.Archive.MaxEntries {
+			f.Close()
+			return nil, errArchiveTooManyEntries
+		}
+		c.format, c.zip = archiveFormatZip, zr
+	case n >= 2 && head[0] == 0x1f && head[1] == 0x8b:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%w: %v", errArchiveUnsupported, err)
+		}
+		c.format = archiveFormatTarGz
+		c.tar = tar.NewReader(&archiveLimitReader{r: gz, remaining: archiveInflateLimit(info.Size())})
+	case n >= 262 && string(head[257:262]) == "ustar":
+		c.format, c.tar = archiveFormatTar, tar.NewReader(f)
+	default:
+		f.Close()
+		return nil, errArchiveUnsupported
+	}
+	return c, nil
+}
+
+func (c *archiveCursor) Close() error {
+	return c.file.Close()
+}
+
+// next 返回下一个可用条目，遍历结束时返回 io.EOF；路径不安全或类型不支持的条目自动跳过并计数。
+func (c *archiveCursor) next() (ArchiveEntry, error) {
+	for {
+		if c.seen >= config.AppConfig.Archive.MaxEntries {
+			return ArchiveEntry{}, errArchiveTooManyEntries
+		}
+		entry, ok, err := c.nextRaw()
+		if err != nil {
+			return ArchiveEntry{}, err
+		}
+		c.seen++
+		if ok {
+			return entry, nil
+		}
+		c.skipped++
+	}
+}
+
+func (c *archiveCursor) nextRaw() (ArchiveEntry, bool, error) {
+	if c.zip != nil {
+		if c.zipNext >= len(c.zip.File) {
+			return ArchiveEntry{}, false, io.EOF
+		}
+		f := c.zip.File[c.zipNext]
+		c.zipNext++
+		c.zipCur = f
+		name := f.Name
+		if f.NonUTF8 {
+			name = decodeArchiveName(name)
+		}
+		clean, ok := cleanArchivePath(name)
+		mode := f.Mode()
+		if !ok || !(mode.IsDir() || mode.IsRegular()) {
+			return ArchiveEntry{}, false, nil
+		}
+		return ArchiveEntry{
+			Path:           clean,
+			Size:           int64(f.UncompressedSize64),
+			CompressedSize: int64(f.CompressedSize64),
+			ModTime:        f.Modified,
+			IsDir:          mode.IsDir(),
+		}, true, nil
+	}
+
+	hdr, err := c.tar.Next()
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, errArchiveTooLarge) {
+			return ArchiveEntry{}, false, err
+		}
+		return ArchiveEntry{}, false, fmt.Errorf("%w: %v", errArchiveUnsupported, err)
+	}
+	name := hdr.Name
+	if !utf8.ValidString(name) {
+		name = decodeArchiveName(name)
+	}
+	clean, ok := cleanArchivePath(name)
+	if !ok || (hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir) {
+		return ArchiveEntry{}, false, nil
+	}
+	return ArchiveEntry{Path: clean, Size: hdr.Size, ModTime: hdr.ModTime, IsDir: hdr.Typeflag == tar.TypeDir}, true, nil
+}
+
+// open 返回当前条目的内容；zip 条目按声明的大小与压缩比校验，标准库会在实际数据超出声明大小时报错。
+func (c *archiveCursor) open() (io.ReadCloser, error) {
+	if c.zip == nil {
+		return io.NopCloser(c.tar), nil
+	}
+	f := c.zipCur
+	if f.UncompressedSize64 > archiveRatioFloor && f.UncompressedSize64/max(f.CompressedSize64, 1) > uint64(config.AppConfig.Archive.MaxCompressionRatio) {
+		return nil, errArchiveTooLarge
+	}
+	if f.UncompressedSize64 > uint64(config.AppConfig.Archive.MaxTotalSize) {
+		return nil, errArchiveTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errArchiveUnsupported, err)
+	}
+	return rc, nil
+}
+
+// archiveInflateLimit 返回 gzip 流允许解压出的最大字节数：按压缩比换算，并受总大小上限约束。
+func archiveInflateLimit(compressedSize int64) int64 {
+	cfg := config.AppConfig.Archive
+	limit := max(compressedSize*int64(cfg.MaxCompressionRatio), archiveRatioFloor)
+	return min(limit, cfg.MaxTotalSize)
+}
+
+// archiveLimitReader 在读出的数据超过 remaining 时返回 errArchiveTooLarge，而不是静默截断。
+type archiveLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *archiveLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		l.remaining = 0
+		return 0, errArchiveTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// cleanArchivePath 规范化条目路径：统一分隔符并去掉开头的 "/" 与 "./"；含 ".." 的条目可能逃逸出解压目录，直接拒绝。
+func cleanArchivePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.ContainsRune(name, 0) {
+		return "", false
+	}
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			return "", false
+		}
+		if len(part) > maxFolderNameLength {
+			return "", false
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, "/"), true
+}
+
+// decodeArchiveName 将未标记 UTF-8 的条目名按 GBK 解码；Windows 中文环境打包的 zip 通常如此，解码失败时保留原文。
+func decodeArchiveName(name string) string {
+	if utf8.ValidString(name) {
+		return name
+	}
+	decoded, err := simplifiedchinese.GBK.NewDecoder().String(name)
+	if err != nil {
+		return strings.ToValidUTF8(name, "_")
+	}
+	return decoded
+}
+
+// archiveErrorToAppError 将压缩包读取错误映射为接口错误码。
+func archiveErrorToAppError(err error) error {
+	switch {
+	case errors.Is(err, errArchiveTooLarge):
+		return newAppError(http.StatusRequestEntityTooLarge, "压缩包解压后过大", err)
+	case errors.Is(err, errArchiveTooManyEntries):
+		return newAppError(http.StatusRequestEntityTooLarge, "压缩包条目过多", err)
+	case errors.Is(err, errArchiveUnsupported):
+		return newAppError(http.StatusBadRequest, "不支持的压缩包格式或压缩包已损坏", err)
+	}
+	return newAppError(http.StatusInternalServerError, "读取压缩包失败", err)
+}
+
+// listArchive 列出压缩包全部可用条目；tar.gz 需要完整解压一遍才能得到条目列表。
+func listArchive(absPath string) (ArchiveListOutput, error) {
+	c, err := openArchive(absPath)
+	if err != nil {
+		return ArchiveListOutput{}, err
+	}
+	defer c.Close()
+
+	out := ArchiveListOutput{Format: c.format, Entries: []ArchiveEntry{}}
+	for {
+		entry, err := c.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ArchiveListOutput{}, err
+		}
+		out.Entries = append(out.Entries, entry)
+		if !entry.IsDir {
+			out.FileCount++
+			out.TotalSize += entry.Size
+		}
+	}
+	out.Skipped = c.skipped
+	if out.TotalSize > config.AppConfig.Archive.MaxTotalSize {
+		return ArchiveListOutput{}, errArchiveTooLarge
+	}
+	return out, nil
+}
+
+// ListArchiveEntries 列出压缩包中的条目及其大小与修改时间。
+func (s *fileService) ListArchiveEntries(ctx context.Context, userID uint, fileID uint) (ArchiveListOutput, error) {
+	info, err := s.getFileAccessInfo(ctx, userID, fileID)
+	if err != nil {
+		return ArchiveListOutput{}, err
+	}
+	out, err := listArchive(info.AbsPath)
+	if err != nil {
+		return ArchiveListOutput{}, archiveErrorToAppError(err)
+	}
+	return out, nil
+}
+
+// OpenArchiveEntry 打开压缩包中的单个文件条目；tar 格式需要从头顺序读到该条目。
+func (s *fileService) OpenArchiveEntry(ctx context.Context, userID uint, fileID uint, entryPath string) (ArchiveEntryStream, error) {
+	target, ok := cleanArchivePath(entryPath)
+	if !ok {
+		return ArchiveEntryStream{}, newAppError(http.StatusBadRequest, "无效的条目路径", nil)
+	}
+	info, err := s.getFileAccessInfo(ctx, userID, fileID)
+	if err != nil {
+		return ArchiveEntryStream{}, err
+	}
+	c, err := openArchive(info.AbsPath)
+	if err != nil {
+		return ArchiveEntryStream{}, archiveErrorToAppError(err)
+	}
+
+	for {
+		entry, err := c.next()
+		if errors.Is(err, io.EOF) {
+			c.Close()
+			return ArchiveEntryStream{}, newAppError(http.StatusNotFound, "条目不存在", nil)
+		}
+		if err != nil {
+			c.Close()
+			return ArchiveEntryStream{}, archiveErrorToAppError(err)
+		}
+		if entry.Path != target || entry.IsDir {
+			continue
+		}
+		rc, err := c.open()
+		if err != nil {
+			c.Close()
+			return ArchiveEntryStream{}, archiveErrorToAppError(err)
+		}
+		name := path.Base(entry.Path)
+		return ArchiveEntryStream{
+			Entry:       entry,
+			Name:        name,
+			ContentType: getMimeType(filepath.Ext(name)),
+			Reader:      archiveEntryReadCloser{Reader: rc, closers: []io.Closer{rc, c}},
+		}, nil
+	}
+}
+
+// archiveEntryReadCloser 关闭条目流时一并关闭压缩包文件。
+type archiveEntryReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r archiveEntryReadCloser) Close() error {
+	var firstErr error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ExtractArchive 将压缩包解压到目标目录下新建的同名文件夹中，每个文件都成为普通文件记录；
+// 先完整列出一遍条目校验数量、大小与配额，再逐个落盘，相同内容照常复用已有文件对象。
+func (s *fileService) ExtractArchive(ctx context.Context, userID uint, fileID uint, in ArchiveExtractInput) (ArchiveExtractOutput, error) {
+	info, err := s.getFileAccessInfo(ctx, userID, fileID)
+	if err != nil {
+		return ArchiveExtractOutput{}, err
+	}
+	listing, err := listArchive(info.AbsPath)
+	if err != nil {
+		return ArchiveExtractOutput{}, archiveErrorToAppError(err)
+	}
+
+	folderID := in.FolderID
+	if folderID == 0 {
+		folderID = info.File.FolderID
+	}
+	resolvedFolderID, err := s.resolver.resolveFolderIDForUser(ctx, nil, userID, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ArchiveExtractOutput{}, newAppError(http.StatusNotFound, "目标文件夹不存在", nil)
+		}
+		return ArchiveExtractOutput{}, newAppError(http.StatusInternalServerError, "校验目标文件夹失败", err)
+	}
+	parent, err := s.folders.GetByIDAndUser(ctx, nil, resolvedFolderID, userID)
+	if err != nil {
+		return ArchiveExtractOutput{}, newAppError(http.StatusInternalServerError, "查询目标文件夹失败", err)
+	}
+
+	user, err := s.users.GetByID(ctx, nil, userID)
+	if err != nil {
+		return ArchiveExtractOutput{}, newAppError(http.StatusInternalServerError, "查询用户失败", err)
+	}
+	if user.StorageUsed+listing.TotalSize > user.StorageQuota {
+		return ArchiveExtractOutput{}, newAppErrorWithData(http.StatusBadRequest, "存储空间不足", map[string]interface{}{
+			"storage_quota":   user.StorageQuota,
+			"storage_used":    user.StorageUsed,
+			"available_space": user.StorageQuota - user.StorageUsed,
+			"required_space":  listing.TotalSize,
+		}, nil)
+	}
+
+	root, err := s.createArchiveRootFolder(ctx, userID, parent, archiveBaseName(info.File.OriginalName))
+	if err != nil {
+		return ArchiveExtractOutput{}, err
+	}
+	s.folderStats.Invalidate(userID)
+	out := ArchiveExtractOutput{Folder: root, Skipped: []ArchiveSkippedEntry{}}
+
+	c, err := openArchive(info.AbsPath)
+	if err != nil {
+		return out, archiveErrorToAppError(err)
+	}
+	defer c.Close()
+
+	folders := map[string]models.Folder{"": root}
+	for {
+		entry, err := c.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return out, archiveErrorToAppError(err)
+		}
+
+		dir, name := entry.Path, ""
+		if !entry.IsDir {
+			dir, name = path.Dir(entry.Path), path.Base(entry.Path)
+			if dir == "." {
+				dir = ""
+			}
+		}
+		folder, created, err := s.ensureArchiveFolders(ctx, userID, folders, dir)
+		if err != nil {
+			return out, newAppError(http.StatusInternalServerError, "创建文件夹失败", err)
+		}
+		out.FolderCount += created
+		if entry.IsDir {
+			continue
+		}
+
+		// 单个文件超出大小或扩展名限制时跳过，其余错误（如配额不足、压缩包损坏）终止解压，已解压的文件保留。
+		if entry.Size > config.AppConfig.Storage.MaxFileSize {
+			out.Skipped = append(out.Skipped, ArchiveSkippedEntry{Path: entry.Path, Reason: "文件大小超出限制"})
+			continue
+		}
+		if !isFileExtensionAllowed(name) {
+			out.Skipped = append(out.Skipped, ArchiveSkippedEntry{Path: entry.Path, Reason: "不支持的文件类型"})
+			continue
+		}
+		if err := s.extractArchiveEntry(ctx, userID, folder.ID, c, name); err != nil {
+			var appErr *AppError
+			if errors.As(err, &appErr) {
+				return out, err
+			}
+			return out, archiveErrorToAppError(err)
+		}
+		out.FileCount++
+	}
+	logger.Ctx(ctx).Infof("已解压压缩包 %d：%d 个文件，%d 个文件夹", fileID, out.FileCount, out.FolderCount)
+	return out, nil
+}
+
+// extractArchiveEntry 把当前条目写入临时文件后按普通上传保存；条目内容需要先计算 MD5，无法直接流式入库。
+func (s *fileService) extractArchiveEntry(ctx context.Context, userID uint, folderID uint, c *archiveCursor, name string) error {
+	rc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	tempDir := filepath.Join(config.AppConfig.Storage.BasePath, "temp")
+	if err := os.MkdirAll(tempDir, 0o755); err != nil {
+		return newAppError(http.StatusInternalServerError, "创建临时目录失败", err)
+	}
+	tmp, err := os.CreateTemp(tempDir, "archive-*")
+	if err != nil {
+		return newAppError(http.StatusInternalServerError, "创建临时文件失败", err)
+	}
+	defer func() {
+		tmp.Close()
+		warnOnError(ctx, "删除解压临时文件", os.Remove(tmp.Name()))
+	}()
+
+	written, err := io.Copy(tmp, rc)
+	if err != nil {
+		return err
+	}
+	_, err = s.storeFile(ctx, userID, folderID, tmp, storeFileInput{
+		Name:   name,
+		Size:   written,
+		Source: "archive",
+	})
+	return err
+}
+
+// ensureArchiveFolders 按条目目录逐级创建文件夹，返回最深一级目录与本次新建的数量；folders 缓存已创建的目录。
+func (s *fileService) ensureArchiveFolders(ctx context.Context, userID uint, folders map[string]models.Folder, dir string) (models.Folder, int, error) {
+	if folder, ok := folders[dir]; ok {
+		return folder, 0, nil
+	}
+	parentDir := path.Dir(dir)
+	if parentDir == "." {
+		parentDir = ""
+	}
+	parent, created, err := s.ensureArchiveFolders(ctx, userID, folders, parentDir)
+	if err != nil {
+		return models.Folder{}, 0, err
+	}
+	name := path.Base(dir)
+	parentID := parent.ID
+	folder := models.Folder{
+		Name:     name,
+		ParentID: &parentID,
+		UserID:   userID,
+		Path:     buildChildFolderPath(parent.Path, name),
+	}
+	if err := s.folders.Create(ctx, nil, &folder); err != nil {
+		return models.Folder{}, 0, err
+	}
+	folders[dir] = folder
+	return folder, created + 1, nil
+}
+
+// createArchiveRootFolder 在目标目录下创建解压根目录，重名时依次尝试 name (2)、name (3)…
+func (s *fileService) createArchiveRootFolder(ctx context.Context, userID uint, parent models.Folder, name string) (models.Folder, error) {
+	for n := 1; n <= maxArchiveFolderRenameAttempts; n++ {
+		candidate := name
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%d)", name, n)
+		}
+		count, err := s.folders.CountByParentAndName(ctx, nil, userID, parent.ID, candidate, 0)
+		if err != nil {
+			return models.Folder{}, newAppError(http.StatusInternalServerError, "检查文件夹重名失败", err)
+		}
+		if count > 0 {
+			continue
+		}
+		parentID := parent.ID
+		folder := models.Folder{
+			Name:     candidate,
+			ParentID: &parentID,
+			UserID:   userID,
+			Path:     buildChildFolderPath(parent.Path, candidate),
+		}
+		if err := s.folders.Create(ctx, nil, &folder); err != nil {
+			return models.Folder{}, newAppError(http.StatusInternalServerError, "创建文件夹失败", err)
+		}
+		return folder, nil
+	}
+	return models.Folder{}, newAppError(http.StatusConflict, "无法生成不冲突的文件夹名称", nil)
+}
+
+// archiveBaseName 去掉压缩包扩展名作为解压目录名，如 backup.tar.gz 得到 backup。
+func archiveBaseName(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
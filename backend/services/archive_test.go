@@ -0,0 +1,390 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func setArchiveTestConfig(baseDir string) {
+	config.AppConfig = &config.Config{
+		Storage: config.StorageConfig{
+			BasePath:          baseDir,
+			MaxFileSize:       10 * 1024 * 1024,
+			AllowedExtensions: []string{".txt", ".md"},
+		},
+		Archive: config.ArchiveConfig{MaxEntries: 100, MaxTotalSize: 64 << 20, MaxCompressionRatio: 100},
+	}
+}
+
+type testArchiveEntry struct {
+	name    string
+	body    []byte
+	mode    os.FileMode
+	nonUTF8 bool
+}
+
+func writeTestZip(t *testing.T, path string, entries []testArchiveEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), NonUTF8: e.nonUTF8}
+		if e.mode != 0 {
+			hdr.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("create zip entry failed: %v", err)
+		}
+		if _, err := w.Write(e.body); err != nil {
+			t.Fatalf("write zip entry failed: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip failed: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write zip failed: %v", err)
+	}
+}
+
+func writeTestTarGz(t *testing.T, path string, headers []*tar.Header, bodies map[string][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range headers {
+		body := bodies[hdr.Name]
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write tar header failed: %v", err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatalf("write tar body failed: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar failed: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip failed: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write tar.gz failed: %v", err)
+	}
+}
+
+func gbkName(t *testing.T, name string) string {
+	t.Helper()
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(name)
+	if err != nil {
+		t.Fatalf("encode gbk failed: %v", err)
+	}
+	return encoded
+}
+
+func TestCleanArchivePath(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"docs/readme.txt", "docs/readme.txt", true},
+		{"./docs//readme.txt", "docs/readme.txt", true},
+		{"/etc/passwd", "etc/passwd", true},
+		{`docs\sub\a.txt`, "docs/sub/a.txt", true},
+		{"docs/", "docs", true},
+		{"../evil.txt", "", false},
+		{"docs/../../evil.txt", "", false},
+		{"a\x00b", "", false},
+		{"./", "", false},
+		{strings.Repeat("a", maxFolderNameLength+1), "", false},
+	}
+	for _, tc := range cases {
+		got, ok := cleanArchivePath(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("cleanArchivePath(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestArchiveBaseName(t *testing.T) {
+	cases := map[string]string{
+		"backup.tar.gz": "backup",
+		"photos.ZIP":    "photos",
+		"logs.tgz":      "logs",
+		"data.tar":      "data",
+		".zip":          ".zip",
+		"notes.txt":     "notes.txt",
+	}
+	for in, want := range cases {
+		if got := archiveBaseName(in); got != want {
+			t.Fatalf("archiveBaseName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestListArchiveZipSkipsUnsafeEntriesAndDecodesGBK(t *testing.T) {
+	baseDir := t.TempDir()
+	setArchiveTestConfig(baseDir)
+	path := filepath.Join(baseDir, "a.zip")
+	writeTestZip(t, path, []testArchiveEntry{
+		{name: "docs/", mode: os.ModeDir | 0755},
+		{name: "docs/readme.txt", body: []byte("hello archive")},
+		{name: gbkName(t, "中文/说明.txt"), body: []byte("你好"), nonUTF8: true},
+		{name: "../evil.txt", body: []byte("escape")},
+		{name: "link", body: []byte("/etc/passwd"), mode: os.ModeSymlink | 0777},
+	})
+
+	out, err := listArchive(path)
+	if err != nil {
+		t.Fatalf("listArchive failed: %v", err)
+	}
+	if out.Format != archiveFormatZip || out.FileCount != 2 || out.Skipped != 2 {
+		t.Fatalf("unexpected listing: %+v", out)
+	}
+	if out.TotalSize != int64(len("hello archive")+len("你好")) {
+		t.Fatalf("unexpected total size %d", out.TotalSize)
+	}
+	var paths []string
+	for _, e := range out.Entries {
+		paths = append(paths, e.Path)
+	}
+	if strings.Join(paths, ",") != "docs,docs/readme.txt,中文/说明.txt" {
+		t.Fatalf("unexpected entries: %v", paths)
+	}
+	if !out.Entries[0].IsDir || out.Entries[1].CompressedSize == 0 || !out.Entries[1].ModTime.Equal(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected entry details: %+v", out.Entries)
+	}
+}
+
+func TestListArchiveTarGzSkipsSymlinks(t *testing.T) {
+	baseDir := t.TempDir()
+	setArchiveTestConfig(baseDir)
+	path := filepath.Join(baseDir, "a.tar.gz")
+	writeTestTarGz(t, path, []*tar.Header{
+		{Name: "src/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "src/main.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "src/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	}, map[string][]byte{"src/main.txt": []byte("package main")})
+
+	out, err := listArchive(path)
+	if err != nil {
+		t.Fatalf("listArchive failed: %v", err)
+	}
+	if out.Format != archiveFormatTarGz || out.FileCount != 1 || out.Skipped != 1 || len(out.Entries) != 2 {
+		t.Fatalf("unexpected listing: %+v", out)
+	}
+	if out.Entries[1].Path != "src/main.txt" || out.Entries[1].Size != int64(len("package main")) {
+		t.Fatalf("unexpected entry: %+v", out.Entries[1])
+	}
+}
+
+func TestListArchiveRejectsBombsAndUnknownFormats(t *testing.T) {
+	baseDir := t.TempDir()
+	setArchiveTestConfig(baseDir)
+
+	// gzip 流解压量超过总大小上限时报错，而不是读完整个流。
+	config.AppConfig.Archive.MaxTotalSize = 1 << 20
+	gzPath := filepath.Join(baseDir, "bomb.tar.gz")
+	writeTestTarGz(t, gzPath, []*tar.Header{{Name: "zeros.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		map[string][]byte{"zeros.txt": make([]byte, 2<<20)})
+	if _, err := listArchive(gzPath); !errors.Is(err, errArchiveTooLarge) {
+		t.Fatalf("expected errArchiveTooLarge for gzip bomb, got %v", err)
+	}
+
+	setArchiveTestConfig(baseDir)
+	config.AppConfig.Archive.MaxEntries = 3
+	manyPath := filepath.Join(baseDir, "many.zip")
+	var many []testArchiveEntry
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		many = append(many, testArchiveEntry{name: name, body: []byte(name)})
+	}
+	writeTestZip(t, manyPath, many)
+	if _, err := listArchive(manyPath); !errors.Is(err, errArchiveTooManyEntries) {
+		t.Fatalf("expected errArchiveTooManyEntries, got %v", err)
+	}
+
+	plainPath := filepath.Join(baseDir, "plain.txt")
+	if err := os.WriteFile(plainPath, []byte("not an archive"), 0644); err != nil {
+		t.Fatalf("write plain file failed: %v", err)
+	}
+	_, err := listArchive(plainPath)
+	if !errors.Is(err, errArchiveUnsupported) {
+		t.Fatalf("expected errArchiveUnsupported, got %v", err)
+	}
+	var appErr *AppError
+	if !errors.As(archiveErrorToAppError(err), &appErr) || appErr.HTTPCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 app error, got %v", archiveErrorToAppError(err))
+	}
+}
+
+func TestArchiveCursorRejectsHighCompressionRatio(t *testing.T) {
+	baseDir := t.TempDir()
+	setArchiveTestConfig(baseDir)
+	path := filepath.Join(baseDir, "ratio.zip")
+	writeTestZip(t, path, []testArchiveEntry{{name: "zeros.txt", body: make([]byte, archiveRatioFloor+1024)}})
+
+	c, err := openArchive(path)
+	if err != nil {
+		t.Fatalf("openArchive failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.next(); err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	if _, err := c.open(); !errors.Is(err, errArchiveTooLarge) {
+		t.Fatalf("expected errArchiveTooLarge, got %v", err)
+	}
+}
+
+func setupArchiveFileService(t *testing.T, archiveName string, write func(path string)) (FileService, *folderServiceFolderRepo, *trackingUserRepo, *quickAccessFileRepo) {
+	t.Helper()
+	baseDir := t.TempDir()
+	setArchiveTestConfig(baseDir)
+	if err := os.MkdirAll(filepath.Join(baseDir, "files"), 0755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	write(filepath.Join(baseDir, "files", "archive.bin"))
+
+	folders := newFolderServiceFolderRepo()
+	isRoot := true
+	folders.folders[1] = models.Folder{ID: 1, Name: "/", UserID: 7, Path: "/", IsRoot: &isRoot}
+	folders.rootByUser[7] = 1
+	rootID := uint(1)
+	folders.folders[2] = models.Folder{ID: 2, Name: "uploads", UserID: 7, ParentID: &rootID, Path: "/uploads"}
+	folders.nextID = 10
+
+	users := newTrackingUserRepo()
+	users.usersByID[7] = models.User{ID: 7, Username: "alice", StorageQuota: 1 << 20}
+	users.usersByName["alice"] = users.usersByID[7]
+
+	files := &quickAccessFileRepo{fakeFileRepo: newFakeFileRepo(), files: map[uint]models.File{
+		1: {ID: 1, UserID: 7, FolderID: 2, OriginalName: archiveName, FileObject: models.FileObject{ID: 42, FilePath: filepath.Join("files", "archive.bin")}},
+	}}
+	svc := NewFileService(fakeTxManager{}, users, folders, files, newFakeFileObjectRepo(), nil, nil, nil, nil, nil, nil, nil)
+	return svc, folders, users, files
+}
+
+func TestOpenArchiveEntryStreamsTarEntry(t *testing.T) {
+	svc, _, _, _ := setupArchiveFileService(t, "src.tar.gz", func(path string) {
+		writeTestTarGz(t, path, []*tar.Header{
+			{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "docs/b.md", Typeflag: tar.TypeReg, Mode: 0644},
+		}, map[string][]byte{"a.txt": []byte("first"), "docs/b.md": []byte("# second")})
+	})
+	ctx := context.Background()
+
+	stream, err := svc.OpenArchiveEntry(ctx, 7, 1, "/docs/b.md")
+	if err != nil {
+		t.Fatalf("OpenArchiveEntry failed: %v", err)
+	}
+	data, err := io.ReadAll(stream.Reader)
+	stream.Reader.Close()
+	if err != nil || string(data) != "# second" {
+		t.Fatalf("unexpected entry content %q (%v)", data, err)
+	}
+	if stream.Name != "b.md" || stream.Entry.Size != int64(len("# second")) {
+		t.Fatalf("unexpected stream info: %+v", stream)
+	}
+
+	for entryPath, code := range map[string]int{"docs/missing.txt": http.StatusNotFound, "docs": http.StatusNotFound, "../a.txt": http.StatusBadRequest} {
+		_, err := svc.OpenArchiveEntry(ctx, 7, 1, entryPath)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.HTTPCode != code {
+			t.Fatalf("OpenArchiveEntry(%q): expected %d, got %v", entryPath, code, err)
+		}
+	}
+}
+
+func TestExtractArchiveCreatesFoldersAndFiles(t *testing.T) {
+	svc, folders, users, files := setupArchiveFileService(t, "backup.zip", func(path string) {
+		writeTestZip(t, path, []testArchiveEntry{
+			{name: "readme.txt", body: []byte("top level")},
+			{name: "docs/guide/intro.md", body: []byte("# intro")},
+			{name: "docs/guide/copy.md", body: []byte("# intro")},
+			{name: "bin/tool.exe", body: []byte("MZ")},
+			{name: "empty/", mode: os.ModeDir | 0755},
+		})
+	})
+	// 目标目录下已有同名文件夹，解压目录应顺延为 backup (2)。
+	parentID := uint(2)
+	folders.folders[3] = models.Folder{ID: 3, Name: "backup", UserID: 7, ParentID: &parentID, Path: "/uploads/backup"}
+
+	out, err := svc.ExtractArchive(context.Background(), 7, 1, ArchiveExtractInput{})
+	if err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	if out.Folder.Name != "backup (2)" || out.Folder.Path != "/uploads/backup (2)" || *out.Folder.ParentID != 2 {
+		t.Fatalf("unexpected root folder: %+v", out.Folder)
+	}
+	if out.FileCount != 3 || out.FolderCount != 4 {
+		t.Fatalf("expected 3 files and 4 folders, got %+v", out)
+	}
+	if len(out.Skipped) != 1 || out.Skipped[0].Path != "bin/tool.exe" {
+		t.Fatalf("unexpected skipped entries: %+v", out.Skipped)
+	}
+
+	byPath := map[string]models.Folder{}
+	for _, f := range folders.folders {
+		byPath[f.Path] = f
+	}
+	guide, ok := byPath["/uploads/backup (2)/docs/guide"]
+	if !ok || *guide.ParentID != byPath["/uploads/backup (2)/docs"].ID {
+		t.Fatalf("expected nested folders to be created, got %+v", byPath)
+	}
+	if _, ok := byPath["/uploads/backup (2)/empty"]; !ok {
+		t.Fatalf("expected empty directory entry to be created")
+	}
+
+	created := map[string]models.File{}
+	for _, f := range files.created {
+		created[f.OriginalName] = f
+	}
+	if created["readme.txt"].FolderID != out.Folder.ID || created["intro.md"].FolderID != guide.ID {
+		t.Fatalf("unexpected file placement: %+v", files.created)
+	}
+	// 内容相同的条目复用同一文件对象。
+	if created["copy.md"].FileObjectID != created["intro.md"].FileObjectID {
+		t.Fatalf("expected identical entries to share a file object: %+v", files.created)
+	}
+	if users.usersByID[7].StorageUsed != int64(len("top level")+2*len("# intro")) {
+		t.Fatalf("unexpected storage used %d", users.usersByID[7].StorageUsed)
+	}
+}
+
+func TestExtractArchiveChecksQuotaBeforeWriting(t *testing.T) {
+	svc, folders, users, files := setupArchiveFileService(t, "big.zip", func(path string) {
+		writeTestZip(t, path, []testArchiveEntry{{name: "a.txt", body: bytes.Repeat([]byte("x"), 2048)}})
+	})
+	user := users.usersByID[7]
+	user.StorageQuota = 1024
+	users.usersByID[7] = user
+
+	_, err := svc.ExtractArchive(context.Background(), 7, 1, ArchiveExtractInput{})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusBadRequest {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	if len(folders.folders) != 2 || len(files.created) != 0 {
+		t.Fatalf("expected nothing to be created, got folders=%d files=%d", len(folders.folders), len(files.created))
+	}
+}
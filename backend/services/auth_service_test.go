@@ -8,6 +8,7 @@ import (
 
 	"mcloud/config"
 	"mcloud/models"
+	"mcloud/repositories"
 	"mcloud/utils"
 
 	"gorm.io/gorm"
@@ -79,6 +80,16 @@ func (r *fakeUserRepo) SubStorageUsed(_ context.Context, _ *gorm.DB, _ uint, _ i
 	return nil
 }
 
+func (r *fakeUserRepo) UpdateRecycleRetentionDays(_ context.Context, _ *gorm.DB, userID uint, days *int) error {
+	user, ok := r.usersByID[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.RecycleRetentionDays = days
+	r.usersByID[userID] = user
+	return nil
+}
+
 type fakeFolderRepo struct {
 	roots  map[uint]models.Folder
 	nextID uint
@@ -126,6 +137,34 @@ func (r *fakeFolderRepo) CountByParentAndName(context.Context, *gorm.DB, uint, u
 	return 0, errors.New("not implemented")
 }
 
+func (r *fakeFolderRepo) GetByParentAndName(context.Context, *gorm.DB, uint, uint, string, uint) (models.Folder, error) {
+	return models.Folder{}, errors.New("not implemented")
+}
+
+func (r *fakeFolderRepo) ListByUser(context.Context, *gorm.DB, uint) ([]models.Folder, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeFolderRepo) ListByPaths(context.Context, *gorm.DB, uint, []string) ([]models.Folder, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeFolderRepo) GetByIDsAndUser(context.Context, *gorm.DB, uint, []uint) ([]models.Folder, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeFolderRepo) CountSearch(context.Context, *gorm.DB, repositories.SearchInput) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (r *fakeFolderRepo) Search(context.Context, *gorm.DB, repositories.SearchInput) ([]models.Folder, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeFolderRepo) ListByParentAfter(context.Context, *gorm.DB, repositories.KeysetListInput) ([]models.Folder, error) {
+	return nil, errors.New("not implemented")
+}
+
 func (r *fakeFolderRepo) UpdateByID(context.Context, *gorm.DB, uint, map[string]interface{}) error {
 	return errors.New("not implemented")
 }
@@ -3,12 +3,14 @@ package services
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
 	"mcloud/config"
+	"mcloud/logger"
+	"mcloud/metrics"
 	"mcloud/models"
 	"mcloud/repositories"
 
@@ -30,6 +32,12 @@ type cleanupService struct {
 	fileObjects repositories.FileObjectRepository
 	uploadTasks repositories.UploadTaskRepository
 	recycle     repositories.RecycleBinRepository
+	favorites   repositories.FavoriteRepository
+	accesses    repositories.FileAccessRepository
+	tags        repositories.TagRepository
+	metadata    repositories.ImageMetadataRepository
+	albums      repositories.AlbumRepository
+	media       repositories.MediaMetadataRepository
 }
 
 var defaultCleanupService CleanupService
@@ -43,6 +51,12 @@ func NewCleanupService(
 	fileObjects repositories.FileObjectRepository,
 	uploadTasks repositories.UploadTaskRepository,
 	recycle repositories.RecycleBinRepository,
+	favorites repositories.FavoriteRepository,
+	accesses repositories.FileAccessRepository,
+	tags repositories.TagRepository,
+	metadata repositories.ImageMetadataRepository,
+	albums repositories.AlbumRepository,
+	media repositories.MediaMetadataRepository,
 ) CleanupService {
 	return &cleanupService{
 		txManager:   txManager,
@@ -52,6 +66,12 @@ func NewCleanupService(
 		fileObjects: fileObjects,
 		uploadTasks: uploadTasks,
 		recycle:     recycle,
+		favorites:   favorites,
+		accesses:    accesses,
+		tags:        tags,
+		metadata:    metadata,
+		albums:      albums,
+		media:       media,
 	}
 }
 
@@ -87,7 +107,149 @@ func (s *cleanupService) tempFileCleanupLoop() {
 
 	for range ticker.C {
 		// 使用后台上下文执行定时清理，避免依赖外部请求生命周期。
-		s.cleanExpiredUploadTasks(context.Background())
+		s.cleanExpiredUploadTasks(logger.WithAttrs(context.Background(), "job", "upload_tasks"))
+		// 收藏与访问记录的孤儿清理开销很小，复用同一周期。
+		s.cleanOrphanQuickAccess(logger.WithAttrs(context.Background(), "job", "quick_access"))
+		s.cleanOrphanTagBindings(logger.WithAttrs(context.Background(), "job", "tag_bindings"))
+		s.cleanOrphanAlbumItems(logger.WithAttrs(context.Background(), "job", "album_items"))
+		s.backfillImageMetadata(logger.WithAttrs(context.Background(), "job", "image_metadata"))
+		s.backfillPerceptualHashes(logger.WithAttrs(context.Background(), "job", "perceptual_hash"))
+		s.backfillMediaMetadata(logger.WithAttrs(context.Background(), "job", "media_metadata"))
+	}
+}
+
+// cleanOrphanQuickAccess 删除目标已被彻底删除的收藏与访问记录。
+// 列表查询本身已过滤失效目标，这里只负责回收存储，无需与各条彻删路径耦合。
+func (s *cleanupService) cleanOrphanQuickAccess(ctx context.Context) {
+	favorites, err := s.favorites.DeleteOrphans(ctx, nil)
+	warnOnError(ctx, "清理失效收藏", err)
+	accesses, err := s.accesses.DeleteOrphans(ctx, nil)
+	warnOnError(ctx, "清理失效访问记录", err)
+
+	purged := int(favorites + accesses)
+	metrics.ObserveCleanup("quick_access", purged)
+	if purged > 0 {
+		logger.Ctx(ctx).Infof("已清理 %d 条失效收藏与访问记录", purged)
+	}
+}
+
+// cleanOrphanTagBindings 删除目标已被彻底删除的标签绑定，回收站中的目标保留绑定以便恢复后标签仍在。
+func (s *cleanupService) cleanOrphanTagBindings(ctx context.Context) {
+	purged, err := s.tags.DeleteOrphanBindings(ctx, nil)
+	warnOnError(ctx, "清理失效标签绑定", err)
+
+	metrics.ObserveCleanup("tag_bindings", int(purged))
+	if purged > 0 {
+		logger.Ctx(ctx).Infof("已清理 %d 条失效标签绑定", purged)
+	}
+}
+
+// cleanOrphanAlbumItems 删除文件已被彻底删除的相册条目，回收站中的文件保留条目以便恢复后重新出现在相册中。
+func (s *cleanupService) cleanOrphanAlbumItems(ctx context.Context) {
+	purged, err := s.albums.DeleteOrphanItems(ctx, nil)
+	warnOnError(ctx, "清理失效相册条目", err)
+
+	metrics.ObserveCleanup("album_items", int(purged))
+	if purged > 0 {
+		logger.Ctx(ctx).Infof("已清理 %d 条失效相册条目", purged)
+	}
+}
+
+// imageMetadataBackfillBatch 为每轮补录元数据的图片数量上限，避免单轮读取过多原图。
+const imageMetadataBackfillBatch = 200
+
+// backfillImageMetadata 为尚未解析元数据的图片补录 EXIF，覆盖元数据表上线前的存量图片；
+// 读取失败的图片同样写入空记录，避免每轮重复尝试。
+func (s *cleanupService) backfillImageMetadata(ctx context.Context) {
+	if s.metadata == nil {
+		return
+	}
+	objects, err := s.metadata.ListUnparsedObjects(ctx, nil, imageMetadataBackfillBatch)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("查询待解析图片失败: %v", err)
+		return
+	}
+
+	parsed := 0
+	for _, obj := range objects {
+		meta, _ := ReadImageMetadata(filepath.Join(config.AppConfig.Storage.BasePath, obj.FilePath))
+		meta.FileObjectID = obj.ID
+		if err := s.metadata.Create(ctx, nil, &meta); err != nil {
+			logger.Ctx(ctx).Errorf("保存图片元数据失败 %d: %v", obj.ID, err)
+			continue
+		}
+		parsed++
+	}
+	if parsed > 0 {
+		logger.Ctx(ctx).Infof("已补录 %d 张图片的元数据", parsed)
+	}
+}
+
+// perceptualHashBackfillBatch 为每轮补算感知哈希的图片数量上限，补算需要解码原图，批量小于元数据补录。
+const perceptualHashBackfillBatch = 100
+
+// backfillPerceptualHashes 为感知哈希上线前的存量图片补算哈希；无法解码的图片写入空串，避免每轮重复尝试。
+func (s *cleanupService) backfillPerceptualHashes(ctx context.Context) {
+	if s.metadata == nil {
+		return
+	}
+	objects, err := s.metadata.ListUnhashedObjects(ctx, nil, perceptualHashBackfillBatch)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("查询待计算哈希的图片失败: %v", err)
+		return
+	}
+
+	hashed := 0
+	for _, obj := range objects {
+		hash := ""
+		if img, err := openOrientedImage(filepath.Join(config.AppConfig.Storage.BasePath, obj.FilePath)); err == nil {
+			hash = formatPerceptualHash(DifferenceHash(img))
+		}
+		if err := s.metadata.UpdatePerceptualHash(ctx, nil, obj.ID, hash); err != nil {
+			logger.Ctx(ctx).Errorf("保存感知哈希失败 %d: %v", obj.ID, err)
+			continue
+		}
+		hashed++
+	}
+	if hashed > 0 {
+		logger.Ctx(ctx).Infof("已补算 %d 张图片的感知哈希", hashed)
+	}
+}
+
+// mediaMetadataBackfillBatch 为每轮补录元数据的音视频数量上限，解析只读取容器头部与索引。
+const mediaMetadataBackfillBatch = 100
+
+// backfillMediaMetadata 为音视频元数据表上线前的存量文件补录时长与标签，没有缩略图的文件用内嵌封面补齐；
+// 解析失败的文件同样写入空记录，避免每轮重复尝试。
+func (s *cleanupService) backfillMediaMetadata(ctx context.Context) {
+	if s.media == nil {
+		return
+	}
+	objects, err := s.media.ListUnparsedObjects(ctx, nil, mediaMetadataBackfillBatch)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("查询待解析音视频失败: %v", err)
+		return
+	}
+
+	parsed := 0
+	for _, obj := range objects {
+		thumbRelPath := ""
+		if obj.ThumbnailPath == "" {
+			thumbRelPath = filepath.Join("thumbnails", "covers", fmt.Sprintf("%d.jpg", obj.ID))
+		}
+		meta, thumbnailPath := probeMedia(ctx, filepath.Join(config.AppConfig.Storage.BasePath, obj.FilePath), thumbRelPath)
+		if thumbRelPath != "" && thumbnailPath != "" {
+			warnOnError(ctx, "记录封面缩略图", s.media.UpdateThumbnailPath(ctx, nil, obj.ID, thumbnailPath))
+		}
+		meta.FileObjectID = obj.ID
+		if err := s.media.Create(ctx, nil, &meta); err != nil {
+			logger.Ctx(ctx).Errorf("保存音视频元数据失败 %d: %v", obj.ID, err)
+			continue
+		}
+		parsed++
+	}
+	if parsed > 0 {
+		logger.Ctx(ctx).Infof("已补录 %d 个音视频文件的元数据", parsed)
 	}
 }
 
@@ -95,22 +257,26 @@ func (s *cleanupService) tempFileCleanupLoop() {
 func (s *cleanupService) cleanExpiredUploadTasks(ctx context.Context) {
 	tasks, err := s.uploadTasks.ListExpiredAndUncompleted(ctx, nil, time.Now())
 	if err != nil {
-		log.Printf("查询过期上传任务失败: %v", err)
+		logger.Ctx(ctx).Errorf("查询过期上传任务失败: %v", err)
 		return
 	}
 
+	purged := 0
 	for _, task := range tasks {
 		// 先清理临时分片目录，再删除任务元数据，避免磁盘残留。
 		if task.TempDir != "" {
-			_ = os.RemoveAll(task.TempDir)
+			warnOnError(ctx, "清理临时分片目录", os.RemoveAll(task.TempDir))
 		}
 		if err := s.uploadTasks.DeleteByID(ctx, nil, task.ID); err != nil {
-			log.Printf("删除过期上传任务失败 %s: %v", task.UploadID, err)
+			logger.Ctx(ctx).Errorf("删除过期上传任务失败 %s: %v", task.UploadID, err)
+			continue
 		}
+		purged++
 	}
+	metrics.ObserveCleanup("upload_tasks", purged)
 
-	if len(tasks) > 0 {
-		log.Printf("已清理 %d 个过期上传任务", len(tasks))
+	if purged > 0 {
+		logger.Ctx(ctx).Infof("已清理 %d 个过期上传任务", purged)
 	}
 }
 
@@ -129,7 +295,7 @@ func (s *cleanupService) recycleBinCleanupLoop() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		s.cleanExpiredRecycleBinItems(context.Background())
+		s.cleanExpiredRecycleBinItems(logger.WithAttrs(context.Background(), "job", "recycle_bin"))
 	}
 }
 
@@ -137,10 +303,11 @@ func (s *cleanupService) recycleBinCleanupLoop() {
 func (s *cleanupService) cleanExpiredRecycleBinItems(ctx context.Context) {
 	items, err := s.recycle.ListExpired(ctx, nil, time.Now())
 	if err != nil {
-		log.Printf("查询过期回收站项目失败: %v", err)
+		logger.Ctx(ctx).Errorf("查询过期回收站项目失败: %v", err)
 		return
 	}
 
+	purged := 0
 	for i := range items {
 		item := &items[i]
 		// 每个回收站项独立事务处理，避免单个失败阻塞全部清理。
@@ -157,12 +324,15 @@ func (s *cleanupService) cleanExpiredRecycleBinItems(ctx context.Context) {
 			return s.recycle.DeleteByID(ctx, tx, item.ID)
 		})
 		if err != nil {
-			log.Printf("清理回收站项目失败(ID=%d): %v", item.ID, err)
+			logger.Ctx(ctx).Errorf("清理回收站项目失败(ID=%d): %v", item.ID, err)
+			continue
 		}
+		purged++
 	}
+	metrics.ObserveCleanup("recycle_bin", purged)
 
-	if len(items) > 0 {
-		log.Printf("已清理 %d 个过期回收站项目", len(items))
+	if purged > 0 {
+		logger.Ctx(ctx).Infof("已清理 %d 个过期回收站项目", purged)
 	}
 }
 
@@ -273,10 +443,11 @@ func (s *cleanupService) cleanupDecrementFileObjectRef(ctx context.Context, tx *
 
 	if fileObj.RefCount <= 1 {
 		// 最后一个引用被删除时，物理文件与缩略图都应清理。
-		_ = os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.FilePath))
+		warnOnError(ctx, "删除物理文件", os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.FilePath)))
 		if fileObj.ThumbnailPath != "" {
-			_ = os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.ThumbnailPath))
+			warnOnError(ctx, "删除缩略图", os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.ThumbnailPath)))
 		}
+		removeImageVariants(ctx, fileObj.ID)
 		return s.fileObjects.DeleteByID(ctx, tx, fileObj.ID)
 	}
 
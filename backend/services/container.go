@@ -14,19 +14,36 @@ type Container struct {
 	File FileService
 	// RecycleBin 负责回收站查询、恢复与彻底删除。
 	RecycleBin RecycleBinService
+	// QuickAccess 负责收藏、最近上传与最近访问视图。
+	QuickAccess QuickAccessService
+	// Tag 负责标签管理与批量打标签。
+	Tag TagService
+	// Search 负责按名称、分类与标签的全盘搜索。
+	Search SearchService
+	// Photo 负责照片时间线等按拍摄信息组织的视图。
+	Photo PhotoService
+	// Album 负责相册管理与相册分享。
+	Album AlbumService
 	// Cleanup 负责后台清理任务。
 	Cleanup CleanupService
 }
 
 // NewContainer 组装服务实例并注册全局清理任务入口。
 func NewContainer(repos repositories.Container) *Container {
+	// 目录统计缓存由会改动文件与目录的服务共享，任一写入都能使其失效。
+	folderStats := NewFolderStatsCache(repos.StorageStats)
 	container := &Container{
-		Auth:       NewAuthService(repos.TxManager, repos.Users, repos.Folders),
-		User:       NewUserService(repos.Users),
-		Folder:     NewFolderService(repos.TxManager, repos.Folders, repos.Files, repos.RecycleBin),
-		File:       NewFileService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.UploadTasks, repos.RecycleBin, repos.UploadProgress),
-		RecycleBin: NewRecycleBinService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.RecycleBin),
-		Cleanup:    NewCleanupService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.UploadTasks, repos.RecycleBin),
+		Auth:        NewAuthService(repos.TxManager, repos.Users, repos.Folders),
+		User:        NewUserService(repos.Users, repos.Folders, repos.StorageStats),
+		Folder:      NewFolderService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.RecycleBin, folderStats, repos.Tags),
+		File:        NewFileService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.UploadTasks, repos.RecycleBin, repos.UploadProgress, folderStats, repos.Tags, repos.ImageMetadata, repos.MediaMetadata),
+		RecycleBin:  NewRecycleBinService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.RecycleBin, folderStats),
+		QuickAccess: NewQuickAccessService(repos.Folders, repos.Files, repos.Favorites, repos.FileAccesses),
+		Tag:         NewTagService(repos.TxManager, repos.Tags, repos.Folders, repos.Files),
+		Search:      NewSearchService(repos.Folders, repos.Files, repos.Tags),
+		Photo:       NewPhotoService(repos.ImageMetadata, repos.Folders, repos.Tags),
+		Album:       NewAlbumService(repos.TxManager, repos.Albums, repos.Files, repos.Tags),
+		Cleanup:     NewCleanupService(repos.TxManager, repos.Users, repos.Folders, repos.Files, repos.FileObjects, repos.UploadTasks, repos.RecycleBin, repos.Favorites, repos.FileAccesses, repos.Tags, repos.ImageMetadata, repos.Albums, repos.MediaMetadata),
 	}
 	SetCleanupService(container.Cleanup)
 	return container
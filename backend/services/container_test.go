@@ -16,7 +16,7 @@ func TestNewContainerInitializesServicesAndRegistersCleanup(t *testing.T) {
 	if container == nil {
 		t.Fatalf("expected container instance")
 	}
-	if container.Auth == nil || container.User == nil || container.Folder == nil || container.File == nil || container.RecycleBin == nil || container.Cleanup == nil {
+	if container.Auth == nil || container.User == nil || container.Folder == nil || container.File == nil || container.RecycleBin == nil || container.QuickAccess == nil || container.Tag == nil || container.Search == nil || container.Photo == nil || container.Album == nil || container.Cleanup == nil {
 		t.Fatalf("expected all services to be initialized")
 	}
 	if defaultCleanupService != container.Cleanup {
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+)
+
+func setupDownloadURLFixture(t *testing.T) FileService {
+	t.Helper()
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{
+		Storage:     config.StorageConfig{BasePath: baseDir},
+		JWT:         config.JWTConfig{Secret: "jwt-secret"},
+		DownloadURL: config.DownloadURLConfig{DefaultExpireSeconds: 3600, MaxExpireSeconds: 86400},
+	}
+	if err := os.MkdirAll(filepath.Join(baseDir, "files"), 0755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "files", "a.bin"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	files := &quickAccessFileRepo{fakeFileRepo: newFakeFileRepo(), files: map[uint]models.File{
+		1: {ID: 1, UserID: 7, OriginalName: "a.bin", FileObject: models.FileObject{FilePath: filepath.Join("files", "a.bin"), FileMD5: "abc"}},
+	}}
+	return NewFileService(fakeTxManager{}, nil, newFakeFolderRepo(), files, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+// parseDownloadURL 把签发的直链还原为校验参数。
+func parseDownloadURL(t *testing.T, raw string, clientIP string) (uint, SignedDownloadInput) {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse url failed: %v", err)
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(u.Path, "/api/direct/files/"), 10, 32)
+	if err != nil {
+		t.Fatalf("unexpected path %q", u.Path)
+	}
+	q := u.Query()
+	userID, _ := strconv.ParseUint(q.Get("uid"), 10, 32)
+	expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+	return uint(id), SignedDownloadInput{UserID: uint(userID), Expires: expires, IP: q.Get("ip"), Signature: q.Get("sig"), ClientIP: clientIP}
+}
+
+func expectForbidden(t *testing.T, err error, msg string) {
+	t.Helper()
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusForbidden || appErr.Message != msg {
+		t.Fatalf("expected 403 %q, got %v", msg, err)
+	}
+}
+
+func TestDownloadURLRoundTripAndTampering(t *testing.T) {
+	svc := setupDownloadURLFixture(t)
+	ctx := context.Background()
+
+	out, err := svc.CreateDownloadURL(ctx, 7, 1, DownloadURLInput{})
+	if err != nil {
+		t.Fatalf("CreateDownloadURL failed: %v", err)
+	}
+	if d := time.Until(out.ExpiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("expected default expiry of one hour, got %v", d)
+	}
+	fileID, in := parseDownloadURL(t, out.URL, "10.0.0.1")
+	info, err := svc.GetSignedDownloadInfo(ctx, fileID, in)
+	if err != nil || info.File.ID != 1 || info.ETag != `"abc"` {
+		t.Fatalf("expected signed download to resolve, got %+v (%v)", info, err)
+	}
+
+	tampered := in
+	tampered.UserID = 8
+	_, err = svc.GetSignedDownloadInfo(ctx, fileID, tampered)
+	expectForbidden(t, err, "下载链接无效")
+	tampered = in
+	tampered.Expires += 3600
+	_, err = svc.GetSignedDownloadInfo(ctx, fileID, tampered)
+	expectForbidden(t, err, "下载链接无效")
+	_, err = svc.GetSignedDownloadInfo(ctx, 2, in)
+	expectForbidden(t, err, "下载链接无效")
+
+	// 更换密钥后已签发的直链失效。
+	config.AppConfig.DownloadURL.Secret = "rotated"
+	_, err = svc.GetSignedDownloadInfo(ctx, fileID, in)
+	expectForbidden(t, err, "下载链接无效")
+
+	past := time.Now().Add(-time.Minute).Unix()
+	expired := SignedDownloadInput{UserID: 7, Expires: past, Signature: signDownloadURL(1, 7, past, "")}
+	_, err = svc.GetSignedDownloadInfo(ctx, 1, expired)
+	expectForbidden(t, err, "下载链接已过期")
+}
+
+func TestDownloadURLBindsClientIP(t *testing.T) {
+	svc := setupDownloadURLFixture(t)
+	ctx := context.Background()
+
+	out, err := svc.CreateDownloadURL(ctx, 7, 1, DownloadURLInput{ExpiresIn: 60, ClientIP: "::ffff:192.168.1.5"})
+	if err != nil {
+		t.Fatalf("CreateDownloadURL failed: %v", err)
+	}
+	fileID, in := parseDownloadURL(t, out.URL, "192.168.1.5")
+	if in.IP != "192.168.1.5" {
+		t.Fatalf("expected normalized ip in url, got %q", in.IP)
+	}
+	if _, err := svc.GetSignedDownloadInfo(ctx, fileID, in); err != nil {
+		t.Fatalf("expected bound ip to pass, got %v", err)
+	}
+	in.ClientIP = "192.168.1.6"
+	_, err = svc.GetSignedDownloadInfo(ctx, fileID, in)
+	expectForbidden(t, err, "下载链接不允许在当前网络使用")
+	// 去掉 IP 参数会使签名失效。
+	in.IP, in.ClientIP = "", "192.168.1.6"
+	_, err = svc.GetSignedDownloadInfo(ctx, fileID, in)
+	expectForbidden(t, err, "下载链接无效")
+}
+
+func TestCreateDownloadURLRejectsInvalidRequests(t *testing.T) {
+	svc := setupDownloadURLFixture(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		userID uint
+		fileID uint
+		in     DownloadURLInput
+		code   int
+	}{
+		{7, 1, DownloadURLInput{ExpiresIn: -1}, http.StatusBadRequest},
+		{7, 1, DownloadURLInput{ExpiresIn: 86401}, http.StatusBadRequest},
+		{7, 1, DownloadURLInput{ClientIP: "not-an-ip"}, http.StatusBadRequest},
+		{8, 1, DownloadURLInput{}, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		_, err := svc.CreateDownloadURL(ctx, tc.userID, tc.fileID, tc.in)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.HTTPCode != tc.code {
+			t.Fatalf("expected %d for %+v, got %v", tc.code, tc, err)
+		}
+	}
+}
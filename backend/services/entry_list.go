@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 目录条目类型。
+const (
+	EntryTypeFolder = "folder"
+	EntryTypeFile   = "file"
+)
+
+// ListEntriesInput 为目录条目合并列表的查询参数。
+type ListEntriesInput struct {
+	FolderID uint
+	SortBy   string
+	Order    string
+	// Cursor 为上一页返回的 next_cursor，空串表示第一页。
+	Cursor string
+	Limit  int
+	Tags   TagQuery
+}
+
+// EntryItem 为合并列表中的一项，Folder 与 File 二选一。
+type EntryItem struct {
+	Type   string          `json:"type"`
+	Folder *FolderListItem `json:"folder,omitempty"`
+	File   *models.File    `json:"file,omitempty"`
+}
+
+// EntryListOutput 为目录条目合并列表返回体。
+type EntryListOutput struct {
+	Items      []EntryItem `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// entryCursor 为游标的解码形态；对客户端不透明，只原样回传。
+// ID 为 0 表示该阶段尚未取过数据，从头开始。
+type entryCursor struct {
+	FolderID uint   `json:"f"`
+	Phase    string `json:"p"`
+	SortBy   string `json:"s"`
+	Order    string `json:"o"`
+	Key      string `json:"k,omitempty"`
+	ID       uint   `json:"i,omitempty"`
+	// Tags 为标签筛选条件的规范化表示，翻页期间不允许变更。
+	Tags string `json:"t,omitempty"`
+}
+
+func encodeEntryCursor(c entryCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeEntryCursor(s string) (entryCursor, error) {
+	var c entryCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, err
+	}
+	if c.Phase != EntryTypeFolder && c.Phase != EntryTypeFile {
+		return c, errors.New("unknown cursor phase")
+	}
+	return c, nil
+}
+
+// afterKey 将游标中的排序键还原为查询参数，sortBy 为该阶段实际使用的排序字段。
+func (c entryCursor) afterKey(sortBy string) (interface{}, error) {
+	if c.ID == 0 {
+		return nil, nil
+	}
+	switch sortBy {
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, c.Key)
+	case "file_size":
+		return strconv.ParseInt(c.Key, 10, 64)
+	default:
+		return c.Key, nil
+	}
+}
+
+// entryFolderSort 返回目录阶段的排序方式：目录没有大小，按大小排序时目录按名称升序。
+func entryFolderSort(sortBy string, order string) (string, string) {
+	if sortBy == "file_size" {
+		return "name", "asc"
+	}
+	return sortBy, order
+}
+
+func folderSortKey(folder models.Folder, sortBy string) string {
+	if sortBy == "created_at" {
+		return folder.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return folder.Name
+}
+
+func fileSortKey(file models.File, sortBy string) string {
+	switch sortBy {
+	case "created_at":
+		return file.CreatedAt.Format(time.RFC3339Nano)
+	case "file_size":
+		return strconv.FormatInt(file.FileObject.FileSize, 10)
+	default:
+		return file.OriginalName
+	}
+}
+
+// ListEntries 合并列出目录下的子目录与文件：先目录后文件，按 (排序键, id) 游标分页。
+// 游标只记录上一页末条记录的位置，翻页代价与目录大小无关，翻页期间新增的条目也不会导致重复或遗漏已返回条目。
+func (s *folderService) ListEntries(ctx context.Context, userID uint, in ListEntriesInput) (EntryListOutput, error) {
+	limit := in.Limit
+	if limit < 1 || limit > config.AppConfig.Pagination.MaxPageSize {
+		limit = config.AppConfig.Pagination.DefaultPageSize
+	}
+	sortBy := in.SortBy
+	allowedSortFields := map[string]bool{"name": true, "created_at": true, "file_size": true}
+	if !allowedSortFields[sortBy] {
+		sortBy = "created_at"
+	}
+	order := in.Order
+	if order != "asc" && order != "desc" {
+		order = "desc"
+	}
+	tagFilter, err := in.Tags.toFilter()
+	if err != nil {
+		return EntryListOutput{}, err
+	}
+	tagKey := tagFilterKey(tagFilter)
+
+	rootFolder, err := s.resolver.getOrCreateUserRootFolder(ctx, nil, userID)
+	if err != nil {
+		return EntryListOutput{}, newAppError(http.StatusInternalServerError, "获取根目录失败", err)
+	}
+	folderID, err := s.resolver.resolveFolderIDForUser(ctx, nil, userID, in.FolderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EntryListOutput{}, newAppError(http.StatusNotFound, "目标文件夹不存在", nil)
+		}
+		return EntryListOutput{}, newAppError(http.StatusInternalServerError, "校验目标文件夹失败", err)
+	}
+
+	cursor := entryCursor{FolderID: folderID, Phase: EntryTypeFolder, SortBy: sortBy, Order: order, Tags: tagKey}
+	if in.Cursor != "" {
+		decoded, err := decodeEntryCursor(in.Cursor)
+		if err != nil {
+			return EntryListOutput{}, newAppError(http.StatusBadRequest, "无效的分页游标", nil)
+		}
+		// 游标与目录、排序及标签筛选绑定，换了条件必须从第一页重新开始。
+		if decoded.FolderID != folderID || decoded.SortBy != sortBy || decoded.Order != order || decoded.Tags != tagKey {
+			return EntryListOutput{}, newAppError(http.StatusBadRequest, "分页游标与当前目录、排序或筛选条件不匹配", nil)
+		}
+		cursor = decoded
+	}
+
+	base := repositories.KeysetListInput{
+		UserID:            userID,
+		FolderID:          folderID,
+		RootFolderID:      rootFolder.ID,
+		IncludeLegacyRoot: folderID == rootFolder.ID,
+		Tags:              tagFilter,
+	}
+	out := EntryListOutput{Items: make([]EntryItem, 0, limit)}
+
+	if cursor.Phase == EntryTypeFolder {
+		folderSortBy, folderOrder := entryFolderSort(sortBy, order)
+		after, err := cursor.afterKey(folderSortBy)
+		if err != nil {
+			return EntryListOutput{}, newAppError(http.StatusBadRequest, "无效的分页游标", nil)
+		}
+		query := base
+		query.SortBy, query.Order = folderSortBy, folderOrder
+		query.AfterKey, query.AfterID = after, cursor.ID
+		// 多取一条用于判断是否还有下一页。
+		query.Limit = limit + 1
+		folders, err := s.folders.ListByParentAfter(ctx, nil, query)
+		if err != nil {
+			return EntryListOutput{}, newAppError(http.StatusInternalServerError, "获取文件夹列表失败", err)
+		}
+		if len(folders) > limit {
+			folders = folders[:limit]
+			out.HasMore = true
+		}
+		s.tagAttacher.folders(ctx, userID, folders)
+
+		// 统计只是附加信息，失败时记录日志并返回不带统计的列表。
+		stats, err := s.folderStats.Get(ctx, userID)
+		warnOnError(ctx, "统计文件夹大小", err)
+		for _, folder := range folders {
+			item := FolderListItem{Folder: folder}
+			if folderStats, ok := stats[folder.ID]; ok {
+				item.Stats = &folderStats
+			}
+			out.Items = append(out.Items, EntryItem{Type: EntryTypeFolder, Folder: &item})
+		}
+
+		if out.HasMore {
+			last := folders[len(folders)-1]
+			cursor.Key, cursor.ID = folderSortKey(last, folderSortBy), last.ID
+			out.NextCursor = encodeEntryCursor(cursor)
+			return out, nil
+		}
+		// 目录已取完，本页剩余名额从第一个文件开始补齐。
+		cursor = entryCursor{FolderID: folderID, Phase: EntryTypeFile, SortBy: sortBy, Order: order, Tags: tagKey}
+	}
+
+	remaining := limit - len(out.Items)
+	after, err := cursor.afterKey(sortBy)
+	if err != nil {
+		return EntryListOutput{}, newAppError(http.StatusBadRequest, "无效的分页游标", nil)
+	}
+	query := base
+	query.SortBy, query.Order = sortBy, order
+	query.AfterKey, query.AfterID = after, cursor.ID
+	query.Limit = remaining + 1
+	files, err := s.files.ListByFolderAfter(ctx, nil, query)
+	if err != nil {
+		return EntryListOutput{}, newAppError(http.StatusInternalServerError, "查询文件列表失败", err)
+	}
+	if len(files) > remaining {
+		files = files[:remaining]
+		out.HasMore = true
+	}
+	s.tagAttacher.files(ctx, userID, files)
+	for i := range files {
+		out.Items = append(out.Items, EntryItem{Type: EntryTypeFile, File: &files[i]})
+	}
+	if out.HasMore {
+		if len(files) > 0 {
+			last := files[len(files)-1]
+			cursor.Key, cursor.ID = fileSortKey(last, sortBy), last.ID
+		}
+		out.NextCursor = encodeEntryCursor(cursor)
+	}
+	return out, nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// entryFileRepo 在内存中模拟目录内文件的游标分页，支持按创建时间或大小排序。
+type entryFileRepo struct {
+	*folderServiceFileRepo
+	files []models.File
+}
+
+func (r *entryFileRepo) ListByFolderAfter(_ context.Context, _ *gorm.DB, in repositories.KeysetListInput) ([]models.File, error) {
+	if in.SortBy != "created_at" && in.SortBy != "file_size" {
+		return nil, errors.New("unsupported sort")
+	}
+	less := func(a, b models.File) bool {
+		if in.SortBy == "file_size" && a.FileObject.FileSize != b.FileObject.FileSize {
+			return a.FileObject.FileSize < b.FileObject.FileSize
+		}
+		if in.SortBy == "created_at" && !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	}
+	desc := in.Order == "desc"
+
+	out := make([]models.File, 0)
+	for _, file := range r.files {
+		if file.UserID != in.UserID || file.FolderID != in.FolderID {
+			continue
+		}
+		if in.AfterKey != nil {
+			after := models.File{ID: in.AfterID}
+			switch key := in.AfterKey.(type) {
+			case time.Time:
+				after.CreatedAt = key
+			case int64:
+				after.FileObject.FileSize = key
+			}
+			if desc && !less(file, after) || !desc && !less(after, file) {
+				continue
+			}
+		}
+		out = append(out, file)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if desc {
+			return less(out[j], out[i])
+		}
+		return less(out[i], out[j])
+	})
+	if len(out) > in.Limit {
+		out = out[:in.Limit]
+	}
+	return out, nil
+}
+
+// newEntryFixture 在根目录下构建 3 个子目录与 5 个文件。
+func newEntryFixture(t *testing.T) (*entryFileRepo, FolderService) {
+	t.Helper()
+	config.AppConfig = &config.Config{Pagination: config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100}}
+
+	repo := newFolderServiceFolderRepo()
+	isRoot := true
+	rootID := uint(1)
+	repo.folders[rootID] = models.Folder{ID: rootID, Name: "root", UserID: 1, Path: "/", IsRoot: &isRoot}
+	repo.rootByUser[1] = rootID
+	for i, name := range []string{"b", "a", "c"} {
+		id := uint(2 + i)
+		repo.folders[id] = models.Folder{ID: id, Name: name, UserID: 1, ParentID: &rootID, Path: "/" + name}
+	}
+	repo.nextID = 10
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := &entryFileRepo{folderServiceFileRepo: newFolderServiceFileRepo()}
+	for i := 0; i < 5; i++ {
+		files.files = append(files.files, models.File{
+			ID:           uint(20 + i),
+			UserID:       1,
+			FolderID:     rootID,
+			OriginalName: fmt.Sprintf("f%d.txt", i),
+			FileObject:   models.FileObject{FileSize: int64(100 - i*10)},
+			// f0/f1 共享创建时间，验证排序键相同时按 id 推进。
+			CreatedAt: base.Add(time.Duration(max(i, 1)) * time.Minute),
+		})
+	}
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, files, newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
+	return files, svc
+}
+
+func entryLabels(items []EntryItem) []string {
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Type == EntryTypeFolder {
+			labels = append(labels, "d:"+item.Folder.Name)
+		} else {
+			labels = append(labels, "f:"+item.File.OriginalName)
+		}
+	}
+	return labels
+}
+
+func TestFolderServiceListEntriesPagesFoldersThenFiles(t *testing.T) {
+	_, svc := newEntryFixture(t)
+	ctx := context.Background()
+
+	var got []string
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		out, err := svc.ListEntries(ctx, 1, ListEntriesInput{SortBy: "created_at", Order: "desc", Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("unexpected error on page %d: %v", page, err)
+		}
+		got = append(got, entryLabels(out.Items)...)
+		if !out.HasMore {
+			if out.NextCursor != "" {
+				t.Fatalf("expected no cursor on last page, got %q", out.NextCursor)
+			}
+			break
+		}
+		cursor = out.NextCursor
+	}
+
+	// 目录创建时间均为零值，按 id 倒序；随后文件按创建时间倒序，f1 与 f0 同时间时 id 大者在前。
+	want := []string{"d:c", "d:a", "d:b", "f:f4.txt", "f:f3.txt", "f:f2.txt", "f:f1.txt", "f:f0.txt"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected entries: got %v want %v", got, want)
+	}
+}
+
+func TestFolderServiceListEntriesFolderPageBoundaryContinuesWithFiles(t *testing.T) {
+	_, svc := newEntryFixture(t)
+	ctx := context.Background()
+
+	first, err := svc.ListEntries(ctx, 1, ListEntriesInput{SortBy: "created_at", Order: "desc", Limit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Items) != 3 || !first.HasMore || first.Items[2].Type != EntryTypeFolder {
+		t.Fatalf("expected a full page of folders with more to come, got %+v", first)
+	}
+
+	second, err := svc.ListEntries(ctx, 1, ListEntriesInput{SortBy: "created_at", Order: "desc", Cursor: first.NextCursor, Limit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := entryLabels(second.Items); fmt.Sprint(got) != "[f:f4.txt f:f3.txt f:f2.txt]" {
+		t.Fatalf("unexpected second page: %v", got)
+	}
+}
+
+func TestFolderServiceListEntriesIgnoresFilesAddedAfterCursor(t *testing.T) {
+	files, svc := newEntryFixture(t)
+	ctx := context.Background()
+
+	first, err := svc.ListEntries(ctx, 1, ListEntriesInput{SortBy: "created_at", Order: "desc", Limit: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 翻页期间上传新文件：倒序下它排在已返回数据之前，不影响后续页。
+	files.files = append(files.files, models.File{ID: 99, UserID: 1, FolderID: 1, OriginalName: "new.txt", CreatedAt: time.Now()})
+
+	second, err := svc.ListEntries(ctx, 1, ListEntriesInput{SortBy: "created_at", Order: "desc", Cursor: first.NextCursor, Limit: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := entryLabels(second.Items); fmt.Sprint(got) != "[f:f2.txt f:f1.txt f:f0.txt]" || second.HasMore {
+		t.Fatalf("unexpected second page: %v has_more=%v", got, second.HasMore)
+	}
+}
+
+func TestFolderServiceListEntriesRejectsMismatchedCursor(t *testing.T) {
+	_, svc := newEntryFixture(t)
+	ctx := context.Background()
+
+	first, err := svc.ListEntries(ctx, 1, ListEntriesInput{SortBy: "created_at", Order: "desc", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []ListEntriesInput{
+		{SortBy: "created_at", Order: "asc", Cursor: first.NextCursor, Limit: 2},
+		{FolderID: 2, SortBy: "created_at", Order: "desc", Cursor: first.NextCursor, Limit: 2},
+		{SortBy: "created_at", Order: "desc", Cursor: "not-a-cursor", Limit: 2},
+		// 翻页期间修改标签筛选同样需要从第一页开始。
+		{SortBy: "created_at", Order: "desc", Cursor: first.NextCursor, Limit: 2, Tags: TagQuery{TagIDs: []uint{1}}},
+	}
+	for _, in := range cases {
+		_, err := svc.ListEntries(ctx, 1, in)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %+v, got %v", in, err)
+		}
+	}
+}
+
+func TestFolderServiceListEntriesFileSizeSortListsFoldersByName(t *testing.T) {
+	_, svc := newEntryFixture(t)
+
+	out, err := svc.ListEntries(context.Background(), 1, ListEntriesInput{SortBy: "file_size", Order: "desc", Limit: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := entryLabels(out.Items); fmt.Sprint(got) != "[d:a d:b d:c]" || !out.HasMore {
+		t.Fatalf("expected folders by name ascending, got %v has_more=%v", got, out.HasMore)
+	}
+
+	next, err := svc.ListEntries(context.Background(), 1, ListEntriesInput{SortBy: "file_size", Order: "desc", Cursor: out.NextCursor, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := entryLabels(next.Items); fmt.Sprint(got) != "[f:f0.txt f:f1.txt]" {
+		t.Fatalf("expected largest files first, got %v", got)
+	}
+}
@@ -11,12 +11,14 @@ import (
 	"math"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
 
 	"mcloud/config"
+	"mcloud/metrics"
 	"mcloud/models"
 	"mcloud/repositories"
 	"mcloud/utils"
@@ -111,6 +113,8 @@ type FileAccessOutput struct {
 	AbsPath      string
 	ContentType  string
 	DownloadName string
+	// ETag 为强校验值，由文件对象的内容 MD5 派生；为空时不输出校验头。
+	ETag string
 }
 
 // ThumbnailBatchOutput 批量缩略图查询结果。
@@ -120,7 +124,7 @@ type ThumbnailBatchOutput struct {
 
 // FileService 定义文件管理能力：上传、断点续传、访问与回收站联动。
 type FileService interface {
-	ListFiles(ctx context.Context, userID uint, folderID uint, page int, pageSize int, sortBy string, order string) (FileListOutput, error)
+	ListFiles(ctx context.Context, userID uint, folderID uint, page int, pageSize int, sortBy string, order string, tags TagQuery) (FileListOutput, error)
 	UploadFile(ctx context.Context, userID uint, folderID uint, file multipart.File, header *multipart.FileHeader) (models.File, error)
 	InitChunkedUpload(ctx context.Context, userID uint, in InitChunkedUploadInput) (InitChunkedUploadOutput, error)
 	QueryUploadTask(ctx context.Context, userID uint, in QueryUploadTaskInput) (QueryUploadTaskOutput, error)
@@ -129,15 +133,23 @@ type FileService interface {
 	CancelUploadTask(ctx context.Context, userID uint, uploadID string) error
 	UploadChunk(ctx context.Context, userID uint, uploadID string, chunkIndex int, chunk multipart.File) (UploadChunkOutput, error)
 	CompleteUpload(ctx context.Context, userID uint, uploadID string) (models.File, error)
+	GetFileDetail(ctx context.Context, userID uint, fileID uint) (models.File, error)
 	GetDownloadInfo(ctx context.Context, userID uint, fileID uint) (FileAccessOutput, error)
+	CreateDownloadURL(ctx context.Context, userID uint, fileID uint, in DownloadURLInput) (DownloadURLOutput, error)
+	GetSignedDownloadInfo(ctx context.Context, fileID uint, in SignedDownloadInput) (FileAccessOutput, error)
 	GetPreviewInfo(ctx context.Context, userID uint, fileID uint) (FileAccessOutput, error)
 	GetThumbnailInfo(ctx context.Context, userID uint, fileID uint) (FileAccessOutput, error)
+	GetImageVariant(ctx context.Context, userID uint, fileID uint, in ImageVariantInput) (FileAccessOutput, error)
+	GetTextPreview(ctx context.Context, userID uint, fileID uint, in TextPreviewInput) (TextPreviewOutput, error)
+	ListArchiveEntries(ctx context.Context, userID uint, fileID uint) (ArchiveListOutput, error)
+	OpenArchiveEntry(ctx context.Context, userID uint, fileID uint, entryPath string) (ArchiveEntryStream, error)
+	ExtractArchive(ctx context.Context, userID uint, fileID uint, in ArchiveExtractInput) (ArchiveExtractOutput, error)
 	DeleteFile(ctx context.Context, userID uint, fileID uint) error
 	RenameFile(ctx context.Context, userID uint, fileID uint, name string) (models.File, error)
 	MoveFile(ctx context.Context, userID uint, fileID uint, folderID uint) error
 	BatchDeleteFiles(ctx context.Context, userID uint, fileIDs []uint) error
 	BatchMoveFiles(ctx context.Context, userID uint, fileIDs []uint, folderID uint) error
-	BatchGetThumbnails(ctx context.Context, userID uint, fileIDs []uint) (ThumbnailBatchOutput, error)
+	BatchGetThumbnails(ctx context.Context, userID uint, fileIDs []uint, preset string) (ThumbnailBatchOutput, error)
 }
 
 // fileService 为 FileService 的默认实现。
@@ -151,6 +163,11 @@ type fileService struct {
 	recycle        repositories.RecycleBinRepository
 	uploadProgress repositories.UploadProgressRepository
 	resolver       folderResolver
+	recycler       recycler
+	folderStats    *FolderStatsCache
+	tagAttacher    tagAttacher
+	imageMetadata  repositories.ImageMetadataRepository
+	mediaMetadata  repositories.MediaMetadataRepository
 }
 
 // NewFileService 创建文件服务并注入依赖仓储。
@@ -163,6 +180,10 @@ func NewFileService(
 	uploadTasks repositories.UploadTaskRepository,
 	recycle repositories.RecycleBinRepository,
 	uploadProgress repositories.UploadProgressRepository,
+	folderStats *FolderStatsCache,
+	tags repositories.TagRepository,
+	imageMetadata repositories.ImageMetadataRepository,
+	mediaMetadata repositories.MediaMetadataRepository,
 ) FileService {
 	return &fileService{
 		txManager:      txManager,
@@ -174,11 +195,16 @@ func NewFileService(
 		recycle:        recycle,
 		uploadProgress: uploadProgress,
 		resolver:       folderResolver{folders: folders},
+		recycler:       newRecycler(txManager, users, folders, files, fileObjects, recycle),
+		folderStats:    folderStats,
+		tagAttacher:    tagAttacher{tags: tags},
+		imageMetadata:  imageMetadata,
+		mediaMetadata:  mediaMetadata,
 	}
 }
 
-// ListFiles 按目录分页查询文件，并统一处理排序与分页参数兜底。
-func (s *fileService) ListFiles(ctx context.Context, userID uint, folderID uint, page int, pageSize int, sortBy string, order string) (FileListOutput, error) {
+// ListFiles 按目录分页查询文件，并统一处理排序与分页参数兜底；可按标签筛选，结果附带标签。
+func (s *fileService) ListFiles(ctx context.Context, userID uint, folderID uint, page int, pageSize int, sortBy string, order string, tags TagQuery) (FileListOutput, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -194,6 +220,10 @@ func (s *fileService) ListFiles(ctx context.Context, userID uint, folderID uint,
 	if order != "asc" && order != "desc" {
 		order = "desc"
 	}
+	tagFilter, err := tags.toFilter()
+	if err != nil {
+		return FileListOutput{}, err
+	}
 
 	resolvedFolderID, err := s.resolver.resolveFolderIDForUser(ctx, nil, userID, folderID)
 	if err != nil {
@@ -208,26 +238,28 @@ func (s *fileService) ListFiles(ctx context.Context, userID uint, folderID uint,
 		return FileListOutput{}, newAppError(http.StatusInternalServerError, "获取根目录失败", err)
 	}
 
-	includeLegacyRoot := resolvedFolderID == rootFolder.ID
 	// 根目录查询兼容历史“旧根目录”数据。
-	total, err := s.files.CountByFolder(ctx, nil, userID, resolvedFolderID, rootFolder.ID, includeLegacyRoot)
-	if err != nil {
-		return FileListOutput{}, newAppError(http.StatusInternalServerError, "查询文件总数失败", err)
-	}
-
-	list, err := s.files.ListByFolder(ctx, nil, repositories.ListFilesInput{
+	query := repositories.ListFilesInput{
 		UserID:            userID,
 		FolderID:          resolvedFolderID,
 		RootFolderID:      rootFolder.ID,
-		IncludeLegacyRoot: includeLegacyRoot,
+		IncludeLegacyRoot: resolvedFolderID == rootFolder.ID,
 		SortBy:            sortBy,
 		Order:             order,
 		Offset:            (page - 1) * pageSize,
 		Limit:             pageSize,
-	})
+		Tags:              tagFilter,
+	}
+	total, err := s.files.CountByFolder(ctx, nil, query)
+	if err != nil {
+		return FileListOutput{}, newAppError(http.StatusInternalServerError, "查询文件总数失败", err)
+	}
+
+	list, err := s.files.ListByFolder(ctx, nil, query)
 	if err != nil {
 		return FileListOutput{}, newAppError(http.StatusInternalServerError, "查询文件列表失败", err)
 	}
+	s.tagAttacher.files(ctx, userID, list)
 
 	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
 	if totalPages == 0 {
@@ -249,10 +281,26 @@ func (s *fileService) ListFiles(ctx context.Context, userID uint, folderID uint,
 
 // UploadFile 处理普通表单上传，支持基于 MD5 的秒传复用。
 func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint, file multipart.File, header *multipart.FileHeader) (models.File, error) {
-	if header.Size > config.AppConfig.Storage.MaxFileSize {
+	return s.storeFile(ctx, userID, folderID, file, storeFileInput{
+		Name:   header.Filename,
+		Size:   header.Size,
+		Source: "form",
+	})
+}
+
+// storeFileInput 描述一个待落盘的完整文件；Source 为指标中的来源标签。
+type storeFileInput struct {
+	Name   string
+	Size   int64
+	Source string
+}
+
+// storeFile 校验配额与扩展名后保存一个完整文件，命中相同 MD5 时复用已有文件对象；表单上传与压缩包解压共用。
+func (s *fileService) storeFile(ctx context.Context, userID uint, folderID uint, file io.ReadSeeker, in storeFileInput) (models.File, error) {
+	if in.Size > config.AppConfig.Storage.MaxFileSize {
 		return models.File{}, newAppError(http.StatusBadRequest, "文件大小超出限制", nil)
 	}
-	if !isFileExtensionAllowed(header.Filename) {
+	if !isFileExtensionAllowed(in.Name) {
 		return models.File{}, newAppError(http.StatusBadRequest, "不支持的文件类型", nil)
 	}
 
@@ -268,12 +316,12 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 	if err != nil {
 		return models.File{}, newAppError(http.StatusInternalServerError, "查询用户失败", err)
 	}
-	if user.StorageUsed+header.Size > user.StorageQuota {
+	if user.StorageUsed+in.Size > user.StorageQuota {
 		return models.File{}, newAppErrorWithData(http.StatusBadRequest, "存储空间不足", map[string]interface{}{
 			"storage_quota":   user.StorageQuota,
 			"storage_used":    user.StorageUsed,
 			"available_space": user.StorageQuota - user.StorageUsed,
-			"required_space":  header.Size,
+			"required_space":  in.Size,
 		}, nil)
 	}
 
@@ -283,11 +331,7 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 	}
 	fileMD5 := hex.EncodeToString(hasher.Sum(nil))
 
-	seeker, ok := file.(io.Seeker)
-	if !ok {
-		return models.File{}, newAppError(http.StatusInternalServerError, "文件流不支持重置", nil)
-	}
-	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
 		return models.File{}, newAppError(http.StatusInternalServerError, "重置文件流失败", err)
 	}
 
@@ -296,7 +340,7 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 		// 命中重复内容时仅新增逻辑文件记录并增加引用计数，不重复落盘。
 		fileRecord := models.File{
 			Name:         filepath.Base(existingObj.FilePath),
-			OriginalName: header.Filename,
+			OriginalName: in.Name,
 			FolderID:     resolvedFolderID,
 			UserID:       userID,
 			FileObjectID: existingObj.ID,
@@ -308,11 +352,13 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 			if err := s.files.Create(ctx, tx, &fileRecord); err != nil {
 				return err
 			}
-			return s.users.AddStorageUsed(ctx, tx, userID, header.Size)
+			return s.users.AddStorageUsed(ctx, tx, userID, in.Size)
 		})
 		if err != nil {
 			return models.File{}, newAppError(http.StatusInternalServerError, "保存文件记录失败", err)
 		}
+		s.folderStats.Invalidate(userID)
+		metrics.IncInstantUploadHit(in.Source)
 		fileRecord.FileObject = existingObj
 		return fileRecord, nil
 	}
@@ -322,7 +368,7 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 
 	now := time.Now()
 	fileUUID := uuid.New().String()
-	storageName := fileUUID + "_" + sanitizeFilename(header.Filename)
+	storageName := fileUUID + "_" + sanitizeFilename(in.Name)
 	relDir := filepath.Join("files", fmt.Sprintf("%d", userID), now.Format("2006"), now.Format("01"))
 	absDir := filepath.Join(config.AppConfig.Storage.BasePath, relDir)
 	if err := os.MkdirAll(absDir, 0o755); err != nil {
@@ -334,50 +380,56 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 	if err != nil {
 		return models.File{}, newAppError(http.StatusInternalServerError, "创建文件失败", err)
 	}
-	if _, err := io.Copy(dst, file); err != nil {
+	written, err := io.Copy(dst, file)
+	if err != nil {
 		dst.Close()
 		_ = os.Remove(absPath)
 		return models.File{}, newAppError(http.StatusInternalServerError, "保存文件失败", err)
 	}
 	_ = dst.Close()
+	metrics.AddUploadBytes(in.Source, written)
 
-	isImage := IsImageFile(header.Filename)
+	isImage := IsImageFile(in.Name)
 	var thumbnailPath string
 	var width, height int
+	var imageMeta models.ImageMetadata
+	var perceptualHash *string
 	if isImage {
 		// 缩略图生成失败不阻断主流程，仅影响附加能力。
-		w, h, dimErr := GetImageDimensions(absPath)
+		w, h, meta, dimErr := probeImage(absPath)
 		if dimErr == nil {
-			width, height = w, h
+			width, height, imageMeta = w, h, meta
+		}
+		thumbRelPath := uploadThumbnailRelPath(userID, fileUUID, now)
+		hash, err := GenerateThumbnail(absPath, filepath.Join(config.AppConfig.Storage.BasePath, thumbRelPath))
+		if err == nil {
+			thumbnailPath = thumbRelPath
 		}
-		thumbName := fileUUID + "_thumb.jpg"
-		thumbRelDir := filepath.Join("thumbnails", fmt.Sprintf("%d", userID), now.Format("2006"), now.Format("01"))
-		thumbAbsDir := filepath.Join(config.AppConfig.Storage.BasePath, thumbRelDir)
-		thumbAbsPath := filepath.Join(thumbAbsDir, thumbName)
-		if err := GenerateThumbnail(absPath, thumbAbsPath); err == nil {
-			thumbnailPath = filepath.Join(thumbRelDir, thumbName)
+		if hash != "" {
+			perceptualHash = &hash
 		}
 	}
-
-	mimeType := header.Header.Get("Content-Type")
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
+	isMedia := !isImage && IsMediaFile(in.Name)
+	var mediaMeta models.MediaMetadata
+	if isMedia {
+		mediaMeta, thumbnailPath = probeMedia(ctx, absPath, uploadThumbnailRelPath(userID, fileUUID, now))
 	}
 
 	fileObj := models.FileObject{
-		FilePath:      filepath.Join(relDir, storageName),
-		ThumbnailPath: thumbnailPath,
-		FileSize:      header.Size,
-		MimeType:      mimeType,
-		IsImage:       isImage,
-		Width:         width,
-		Height:        height,
-		FileMD5:       fileMD5,
-		RefCount:      1,
+		FilePath:       filepath.Join(relDir, storageName),
+		ThumbnailPath:  thumbnailPath,
+		FileSize:       in.Size,
+		MimeType:       sniffMimeType(absPath, in.Name),
+		IsImage:        isImage,
+		Width:          width,
+		Height:         height,
+		FileMD5:        fileMD5,
+		RefCount:       1,
+		PerceptualHash: perceptualHash,
 	}
 	fileRecord := models.File{
 		Name:         storageName,
-		OriginalName: header.Filename,
+		OriginalName: in.Name,
 		FolderID:     resolvedFolderID,
 		UserID:       userID,
 	}
@@ -391,16 +443,23 @@ func (s *fileService) UploadFile(ctx context.Context, userID uint, folderID uint
 		if err := s.files.Create(ctx, tx, &fileRecord); err != nil {
 			return err
 		}
-		return s.users.AddStorageUsed(ctx, tx, userID, header.Size)
+		return s.users.AddStorageUsed(ctx, tx, userID, in.Size)
 	})
 	if err != nil {
 		_ = os.Remove(absPath)
 		if thumbnailPath != "" {
-			_ = os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, thumbnailPath))
+			warnOnError(ctx, "删除缩略图", os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, thumbnailPath)))
 		}
 		return models.File{}, newAppError(http.StatusInternalServerError, "保存文件记录失败", err)
 	}
 
+	if isImage {
+		s.saveImageMetadata(ctx, fileObj.ID, imageMeta)
+	}
+	if isMedia {
+		s.saveMediaMetadata(ctx, fileObj.ID, mediaMeta)
+	}
+	s.folderStats.Invalidate(userID)
 	fileRecord.FileObject = fileObj
 	return fileRecord, nil
 }
@@ -455,6 +514,8 @@ func (s *fileService) InitChunkedUpload(ctx context.Context, userID uint, in Ini
 		if err != nil {
 			return InitChunkedUploadOutput{}, newAppError(http.StatusInternalServerError, "秒传失败", err)
 		}
+		s.folderStats.Invalidate(userID)
+		metrics.IncInstantUploadHit("init")
 		return InitChunkedUploadOutput{Status: "instant_upload", FileID: newFile.ID}, nil
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -514,7 +575,7 @@ func (s *fileService) QueryUploadTask(ctx context.Context, userID uint, in Query
 
 	uploadedChunks := s.listUploadedChunks(ctx, task)
 	// 刷新已上传分片快照，便于前端快速恢复状态。
-	_ = s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, task.UploadID, marshalUploadedChunks(uploadedChunks))
+	warnOnError(ctx, "更新分片快照", s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, task.UploadID, marshalUploadedChunks(uploadedChunks)))
 
 	return QueryUploadTaskOutput{
 		Resumable:      true,
@@ -586,7 +647,7 @@ func (s *fileService) GetUploadTaskDetail(ctx context.Context, userID uint, uplo
 	} else if len(uploadedChunks) > 0 {
 		uploadedSize = uploadedSizeByChunks(task, uploadedChunks)
 	}
-	_ = s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, task.UploadID, marshalUploadedChunks(uploadedChunks))
+	warnOnError(ctx, "更新分片快照", s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, task.UploadID, marshalUploadedChunks(uploadedChunks)))
 
 	return UploadTaskDetailOutput{
 		UploadID:       task.UploadID,
@@ -618,10 +679,10 @@ func (s *fileService) CancelUploadTask(ctx context.Context, userID uint, uploadI
 
 	if task.TempDir != "" {
 		// 取消任务时尽量释放临时目录，失败不阻断主逻辑。
-		_ = os.RemoveAll(task.TempDir)
+		warnOnError(ctx, "清理临时分片目录", os.RemoveAll(task.TempDir))
 	}
 	if s.uploadProgress != nil {
-		_ = s.uploadProgress.Clear(ctx, uploadID)
+		warnOnError(ctx, "清理分片进度", s.uploadProgress.Clear(ctx, uploadID))
 	}
 	if err := s.uploadTasks.DeleteByID(ctx, nil, task.ID); err != nil {
 		return newAppError(http.StatusInternalServerError, "取消上传任务失败", err)
@@ -779,8 +840,9 @@ func (s *fileService) UploadChunk(ctx context.Context, userID uint, uploadID str
 		uploadedCount := int64(len(uploadedChunks))
 		uploadedSize := uploadedSizeByChunks(task, uploadedChunks)
 		now := time.Now()
-		_ = s.uploadTasks.UpdateProgress(ctx, nil, uploadID, len(uploadedChunks), uploadedSize, now)
-		_ = s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(uploadedChunks))
+		warnOnError(ctx, "更新上传进度", s.uploadTasks.UpdateProgress(ctx, nil, uploadID, len(uploadedChunks), uploadedSize, now))
+		warnOnError(ctx, "更新分片快照", s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(uploadedChunks)))
+		metrics.IncChunkUpload(true)
 		return UploadChunkOutput{
 			ChunkIndex:     chunkIndex,
 			UploadedChunks: uploadedCount,
@@ -798,7 +860,8 @@ func (s *fileService) UploadChunk(ctx context.Context, userID uint, uploadID str
 	if err != nil {
 		return UploadChunkOutput{}, newAppError(http.StatusInternalServerError, "保存分片失败", err)
 	}
-	if _, err := io.Copy(dst, chunk); err != nil {
+	written, err := io.Copy(dst, chunk)
+	if err != nil {
 		dst.Close()
 		_ = os.Remove(chunkPath)
 		return UploadChunkOutput{}, newAppError(http.StatusInternalServerError, "写入分片失败", err)
@@ -807,18 +870,20 @@ func (s *fileService) UploadChunk(ctx context.Context, userID uint, uploadID str
 		_ = os.Remove(chunkPath)
 		return UploadChunkOutput{}, newAppError(http.StatusInternalServerError, "写入分片失败", err)
 	}
+	metrics.IncChunkUpload(false)
+	metrics.AddUploadBytes("chunk", written)
 
 	if s.uploadProgress != nil {
 		// Redis 进度记录仅用于加速查询，失败时不影响上传成功语义。
-		_ = s.uploadProgress.AddChunk(ctx, uploadID, chunkIndex, config.AppConfig.Redis.UploadTaskExpire)
+		warnOnError(ctx, "记录分片进度", s.uploadProgress.AddChunk(ctx, uploadID, chunkIndex, config.AppConfig.Redis.UploadTaskExpire))
 	}
 
 	uploadedChunks := s.listUploadedChunks(ctx, task)
 	uploadedCount := int64(len(uploadedChunks))
 	uploadedSize := uploadedSizeByChunks(task, uploadedChunks)
 	now := time.Now()
-	_ = s.uploadTasks.UpdateProgress(ctx, nil, uploadID, len(uploadedChunks), uploadedSize, now)
-	_ = s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(uploadedChunks))
+	warnOnError(ctx, "更新上传进度", s.uploadTasks.UpdateProgress(ctx, nil, uploadID, len(uploadedChunks), uploadedSize, now))
+	warnOnError(ctx, "更新分片快照", s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(uploadedChunks)))
 
 	return UploadChunkOutput{ChunkIndex: chunkIndex, UploadedChunks: uploadedCount, TotalChunks: task.TotalChunks}, nil
 }
@@ -857,6 +922,13 @@ func (s *fileService) CompleteUpload(ctx context.Context, userID uint, uploadID
 	}
 
 	finalPath := filepath.Join(absDir, storageName)
+	mergeStart := time.Now()
+	merged := false
+	defer func() {
+		if !merged {
+			metrics.ObserveMerge(time.Since(mergeStart), false)
+		}
+	}()
 	finalFile, err := os.Create(finalPath)
 	if err != nil {
 		return models.File{}, newAppError(http.StatusInternalServerError, "创建目标文件失败", err)
@@ -867,31 +939,31 @@ func (s *fileService) CompleteUpload(ctx context.Context, userID uint, uploadID
 		chunkFile, err := os.Open(chunkPath)
 		if err != nil {
 			finalFile.Close()
-			_ = os.Remove(finalPath)
+			warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 			return models.File{}, newAppError(http.StatusInternalServerError, fmt.Sprintf("读取分片 %d 失败", i), err)
 		}
 		if _, err := io.Copy(finalFile, chunkFile); err != nil {
 			chunkFile.Close()
 			finalFile.Close()
-			_ = os.Remove(finalPath)
+			warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 			return models.File{}, newAppError(http.StatusInternalServerError, "合并文件失败", err)
 		}
 		if err := chunkFile.Close(); err != nil {
 			finalFile.Close()
-			_ = os.Remove(finalPath)
+			warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 			return models.File{}, newAppError(http.StatusInternalServerError, "合并文件失败", err)
 		}
 	}
 
 	if _, err := finalFile.Seek(0, io.SeekStart); err != nil {
 		finalFile.Close()
-		_ = os.Remove(finalPath)
+		warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 		return models.File{}, newAppError(http.StatusInternalServerError, "重置文件游标失败", err)
 	}
 	hasher := md5.New()
 	if _, err := io.Copy(hasher, finalFile); err != nil {
 		finalFile.Close()
-		_ = os.Remove(finalPath)
+		warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 		return models.File{}, newAppError(http.StatusInternalServerError, "校验文件MD5失败", err)
 	}
 	_ = finalFile.Close()
@@ -899,9 +971,11 @@ func (s *fileService) CompleteUpload(ctx context.Context, userID uint, uploadID
 	// 合并完成后校验 MD5，防止落库损坏文件。
 	actualMD5 := hex.EncodeToString(hasher.Sum(nil))
 	if actualMD5 != task.FileMD5 {
-		_ = os.Remove(finalPath)
+		warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 		return models.File{}, newAppError(http.StatusBadRequest, "文件完整性校验失败，MD5不匹配", nil)
 	}
+	merged = true
+	metrics.ObserveMerge(time.Since(mergeStart), true)
 
 	// 合并后若命中已有对象则走复用路径，避免重复存储。
 	existingObj, err := s.fileObjects.GetByMD5(ctx, nil, task.FileMD5)
@@ -931,17 +1005,19 @@ func (s *fileService) CompleteUpload(ctx context.Context, userID uint, uploadID
 		if err != nil {
 			return models.File{}, newAppError(http.StatusInternalServerError, "保存文件记录失败", err)
 		}
-		_ = s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(makeRangeChunks(task.TotalChunks)))
-		_ = os.Remove(finalPath)
-		_ = os.RemoveAll(task.TempDir)
+		warnOnError(ctx, "更新分片快照", s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(makeRangeChunks(task.TotalChunks))))
+		warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
+		warnOnError(ctx, "清理临时分片目录", os.RemoveAll(task.TempDir))
 		if s.uploadProgress != nil {
-			_ = s.uploadProgress.Clear(ctx, uploadID)
+			warnOnError(ctx, "清理分片进度", s.uploadProgress.Clear(ctx, uploadID))
 		}
+		s.folderStats.Invalidate(userID)
+		metrics.IncInstantUploadHit("merge")
 		fileRecord.FileObject = existingObj
 		return fileRecord, nil
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		_ = os.Remove(finalPath)
+		warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 		return models.File{}, newAppError(http.StatusInternalServerError, "检查重复文件失败", err)
 	}
 
@@ -949,29 +1025,39 @@ func (s *fileService) CompleteUpload(ctx context.Context, userID uint, uploadID
 	isImage := IsImageFile(task.FileName)
 	var thumbnailPath string
 	var width, height int
+	var imageMeta models.ImageMetadata
+	var perceptualHash *string
 	if isImage {
-		w, h, dimErr := GetImageDimensions(finalPath)
+		w, h, meta, dimErr := probeImage(finalPath)
 		if dimErr == nil {
-			width, height = w, h
+			width, height, imageMeta = w, h, meta
 		}
-		thumbName := fileUUID + "_thumb.jpg"
-		thumbRelDir := filepath.Join("thumbnails", fmt.Sprintf("%d", userID), now.Format("2006"), now.Format("01"))
-		thumbAbsPath := filepath.Join(config.AppConfig.Storage.BasePath, thumbRelDir, thumbName)
-		if err := GenerateThumbnail(finalPath, thumbAbsPath); err == nil {
-			thumbnailPath = filepath.Join(thumbRelDir, thumbName)
+		thumbRelPath := uploadThumbnailRelPath(userID, fileUUID, now)
+		hash, err := GenerateThumbnail(finalPath, filepath.Join(config.AppConfig.Storage.BasePath, thumbRelPath))
+		if err == nil {
+			thumbnailPath = thumbRelPath
 		}
+		if hash != "" {
+			perceptualHash = &hash
+		}
+	}
+	isMedia := !isImage && IsMediaFile(task.FileName)
+	var mediaMeta models.MediaMetadata
+	if isMedia {
+		mediaMeta, thumbnailPath = probeMedia(ctx, finalPath, uploadThumbnailRelPath(userID, fileUUID, now))
 	}
 
 	fileObj := models.FileObject{
-		FilePath:      filepath.Join(relDir, storageName),
-		ThumbnailPath: thumbnailPath,
-		FileSize:      task.FileSize,
-		MimeType:      getMimeType(filepath.Ext(task.FileName)),
-		IsImage:       isImage,
-		Width:         width,
-		Height:        height,
-		FileMD5:       task.FileMD5,
-		RefCount:      1,
+		FilePath:       filepath.Join(relDir, storageName),
+		ThumbnailPath:  thumbnailPath,
+		FileSize:       task.FileSize,
+		MimeType:       sniffMimeType(finalPath, task.FileName),
+		IsImage:        isImage,
+		Width:          width,
+		Height:         height,
+		FileMD5:        task.FileMD5,
+		RefCount:       1,
+		PerceptualHash: perceptualHash,
 	}
 	fileRecord := models.File{
 		Name:         storageName,
@@ -997,22 +1083,47 @@ func (s *fileService) CompleteUpload(ctx context.Context, userID uint, uploadID
 		return s.uploadTasks.MarkCompleted(ctx, tx, uploadID, time.Now())
 	})
 	if err != nil {
-		_ = os.Remove(finalPath)
+		warnOnError(ctx, "删除合并文件", os.Remove(finalPath))
 		if thumbnailPath != "" {
-			_ = os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, thumbnailPath))
+			warnOnError(ctx, "删除缩略图", os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, thumbnailPath)))
 		}
 		return models.File{}, newAppError(http.StatusInternalServerError, "保存文件记录失败", err)
 	}
 
-	_ = s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(makeRangeChunks(task.TotalChunks)))
-	_ = os.RemoveAll(task.TempDir)
+	warnOnError(ctx, "更新分片快照", s.uploadTasks.UpdateUploadedChunksSnapshot(ctx, nil, uploadID, marshalUploadedChunks(makeRangeChunks(task.TotalChunks))))
+	warnOnError(ctx, "清理临时分片目录", os.RemoveAll(task.TempDir))
 	if s.uploadProgress != nil {
-		_ = s.uploadProgress.Clear(ctx, uploadID)
+		warnOnError(ctx, "清理分片进度", s.uploadProgress.Clear(ctx, uploadID))
+	}
+	if isImage {
+		s.saveImageMetadata(ctx, fileObj.ID, imageMeta)
+	}
+	if isMedia {
+		s.saveMediaMetadata(ctx, fileObj.ID, mediaMeta)
 	}
+	s.folderStats.Invalidate(userID)
 	fileRecord.FileObject = fileObj
 	return fileRecord, nil
 }
 
+// saveMediaMetadata 记录新音视频文件对象的元数据；失败只记日志，后台补录任务会重新解析。
+func (s *fileService) saveMediaMetadata(ctx context.Context, fileObjectID uint, meta models.MediaMetadata) {
+	if s.mediaMetadata == nil {
+		return
+	}
+	meta.FileObjectID = fileObjectID
+	warnOnError(ctx, "保存音视频元数据", s.mediaMetadata.Create(ctx, nil, &meta))
+}
+
+// saveImageMetadata 记录新图片文件对象的 EXIF 元数据；失败只记日志，后台补录任务会重新解析。
+func (s *fileService) saveImageMetadata(ctx context.Context, fileObjectID uint, meta models.ImageMetadata) {
+	if s.imageMetadata == nil {
+		return
+	}
+	meta.FileObjectID = fileObjectID
+	warnOnError(ctx, "保存图片元数据", s.imageMetadata.Create(ctx, nil, &meta))
+}
+
 // getFileAccessInfo 统一查询访问文件所需元信息并校验物理文件存在。
 func (s *fileService) getFileAccessInfo(ctx context.Context, userID uint, fileID uint) (FileAccessOutput, error) {
 	file, err := s.files.GetByIDAndUser(ctx, nil, fileID, userID, true)
@@ -1023,12 +1134,34 @@ func (s *fileService) getFileAccessInfo(ctx context.Context, userID uint, fileID
 		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
 	}
 
+	return fileAccessInfo(file)
+}
+
+// fileAccessInfo 组装原文件的访问信息并校验物理文件存在，file 需已预加载文件对象。
+func fileAccessInfo(file models.File) (FileAccessOutput, error) {
 	absPath := filepath.Join(config.AppConfig.Storage.BasePath, file.FileObject.FilePath)
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
 		return FileAccessOutput{}, newAppError(http.StatusNotFound, "文件不存在于存储中", nil)
 	}
 
-	return FileAccessOutput{File: file, AbsPath: absPath, ContentType: file.FileObject.MimeType, DownloadName: file.OriginalName}, nil
+	return FileAccessOutput{File: file, AbsPath: absPath, ContentType: file.FileObject.MimeType, DownloadName: file.OriginalName, ETag: contentETag(file.FileObject.FileMD5)}, nil
+}
+
+// contentETag 以内容 MD5 生成强 ETag；同一文件对象内容不可变，MD5 相同即字节相同。
+func contentETag(fileMD5 string) string {
+	if fileMD5 == "" {
+		return ""
+	}
+	return `"` + fileMD5 + `"`
+}
+
+// derivedETag 为缩略图、变体等派生文件生成强 ETag，在内容 MD5 外附加派生类型与文件大小、修改时间，
+// 派生文件重新生成后校验值随之变化。
+func derivedETag(fileMD5 string, kind string, stat os.FileInfo) string {
+	if fileMD5 == "" {
+		return ""
+	}
+	return fmt.Sprintf(`"%s-%s-%x-%x"`, fileMD5, kind, stat.Size(), stat.ModTime().UnixNano())
 }
 
 // GetDownloadInfo 返回下载接口所需信息。
@@ -1050,14 +1183,20 @@ func (s *fileService) GetThumbnailInfo(ctx context.Context, userID uint, fileID
 		}
 		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
 	}
+	return thumbnailAccessInfo(file)
+}
+
+// thumbnailAccessInfo 组装缩略图的访问信息并校验缩略图文件存在，file 需已预加载文件对象。
+func thumbnailAccessInfo(file models.File) (FileAccessOutput, error) {
 	if file.FileObject.ThumbnailPath == "" {
 		return FileAccessOutput{}, newAppError(http.StatusNotFound, "缩略图不存在", nil)
 	}
 	absPath := filepath.Join(config.AppConfig.Storage.BasePath, file.FileObject.ThumbnailPath)
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+	stat, err := os.Stat(absPath)
+	if err != nil {
 		return FileAccessOutput{}, newAppError(http.StatusNotFound, "缩略图文件不存在", nil)
 	}
-	return FileAccessOutput{File: file, AbsPath: absPath, ContentType: "image/jpeg"}, nil
+	return FileAccessOutput{File: file, AbsPath: absPath, ContentType: "image/jpeg", ETag: derivedETag(file.FileObject.FileMD5, "thumb", stat)}, nil
 }
 
 // DeleteFile 删除单个文件；回收站开启时先写入回收快照。
@@ -1071,42 +1210,49 @@ func (s *fileService) DeleteFile(ctx context.Context, userID uint, fileID uint)
 	}
 
 	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
-		if config.AppConfig.RecycleBin.Enabled {
-			// 回收快照保存文件对象关键信息，供恢复和彻删流程复用。
-			metadata, _ := json.Marshal(map[string]interface{}{
-				"mime_type":      file.FileObject.MimeType,
-				"thumbnail_path": file.FileObject.ThumbnailPath,
-				"is_image":       file.FileObject.IsImage,
-				"width":          file.FileObject.Width,
-				"height":         file.FileObject.Height,
-				"file_md5":       file.FileObject.FileMD5,
-				"file_object_id": file.FileObjectID,
-			})
-			fileSize := file.FileObject.FileSize
-			item := models.RecycleBinItem{
-				UserID:           userID,
-				OriginalID:       file.ID,
-				OriginalType:     "file",
-				OriginalName:     file.OriginalName,
-				OriginalPath:     file.FileObject.FilePath,
-				OriginalFolderID: &file.FolderID,
-				FileObjectID:     &file.FileObjectID,
-				FileSize:         &fileSize,
-				ExpiresAt:        time.Now().AddDate(0, 0, config.AppConfig.RecycleBin.RetentionDays),
-				Metadata:         string(metadata),
-			}
-			if err := s.recycle.Create(ctx, tx, &item); err != nil {
-				return err
-			}
-		}
-		return s.files.SoftDeleteByIDAndUser(ctx, tx, file.ID, userID)
+		return s.recycler.recycleFile(ctx, tx, userID, file)
 	})
 	if err != nil {
 		return newAppError(http.StatusInternalServerError, "删除文件失败", err)
 	}
+	s.folderStats.Invalidate(userID)
+	s.recycler.evictOverflow(ctx, userID)
 	return nil
 }
 
+// GetFileDetail 返回单个文件详情，附带图片 EXIF 或音视频元数据以及标签；元数据查询失败时只省略对应字段。
+func (s *fileService) GetFileDetail(ctx context.Context, userID uint, fileID uint) (models.File, error) {
+	file, err := s.files.GetByIDAndUser(ctx, nil, fileID, userID, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.File{}, newAppError(http.StatusNotFound, "文件不存在", nil)
+		}
+		return models.File{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+	}
+
+	if file.FileObject.IsImage && s.imageMetadata != nil {
+		meta, err := s.imageMetadata.GetByFileObjectID(ctx, nil, file.FileObjectID)
+		if err == nil {
+			file.FileObject.Metadata = &meta
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			warnOnError(ctx, "查询图片元数据", err)
+		}
+	}
+	if !file.FileObject.IsImage && s.mediaMetadata != nil {
+		meta, err := s.mediaMetadata.GetByFileObjectID(ctx, nil, file.FileObjectID)
+		// Kind 为空的记录表示解析失败的占位，不返回给前端。
+		if err == nil && meta.Kind != "" {
+			file.FileObject.Media = &meta
+		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			warnOnError(ctx, "查询音视频元数据", err)
+		}
+	}
+
+	list := []models.File{file}
+	s.tagAttacher.files(ctx, userID, list)
+	return list[0], nil
+}
+
 // RenameFile 更新文件展示名，不改变底层存储对象。
 func (s *fileService) RenameFile(ctx context.Context, userID uint, fileID uint, name string) (models.File, error) {
 	file, err := s.files.GetByIDAndUser(ctx, nil, fileID, userID, false)
@@ -1143,6 +1289,7 @@ func (s *fileService) MoveFile(ctx context.Context, userID uint, fileID uint, fo
 	if err := s.files.UpdateByIDAndUser(ctx, nil, fileID, userID, map[string]interface{}{"folder_id": resolvedFolderID}); err != nil {
 		return newAppError(http.StatusInternalServerError, "移动文件失败", err)
 	}
+	s.folderStats.Invalidate(userID)
 	return nil
 }
 
@@ -1157,32 +1304,8 @@ func (s *fileService) BatchDeleteFiles(ctx context.Context, userID uint, fileIDs
 				}
 				return err
 			}
-			if config.AppConfig.RecycleBin.Enabled {
-				// 批量删除与单删保持一致：先写回收站记录再软删除。
-				metadata, _ := json.Marshal(map[string]interface{}{
-					"mime_type":      file.FileObject.MimeType,
-					"is_image":       file.FileObject.IsImage,
-					"file_md5":       file.FileObject.FileMD5,
-					"file_object_id": file.FileObjectID,
-				})
-				fileSize := file.FileObject.FileSize
-				item := models.RecycleBinItem{
-					UserID:           userID,
-					OriginalID:       file.ID,
-					OriginalType:     "file",
-					OriginalName:     file.OriginalName,
-					OriginalPath:     file.FileObject.FilePath,
-					OriginalFolderID: &file.FolderID,
-					FileObjectID:     &file.FileObjectID,
-					FileSize:         &fileSize,
-					ExpiresAt:        time.Now().AddDate(0, 0, config.AppConfig.RecycleBin.RetentionDays),
-					Metadata:         string(metadata),
-				}
-				if err := s.recycle.Create(ctx, tx, &item); err != nil {
-					return err
-				}
-			}
-			if err := s.files.SoftDeleteByIDAndUser(ctx, tx, file.ID, userID); err != nil {
+			// 批量删除与单删保持一致：先写回收站记录再软删除。
+			if err := s.recycler.recycleFile(ctx, tx, userID, file); err != nil {
 				return err
 			}
 		}
@@ -1191,6 +1314,8 @@ func (s *fileService) BatchDeleteFiles(ctx context.Context, userID uint, fileIDs
 	if err != nil {
 		return newAppError(http.StatusInternalServerError, "批量删除失败", err)
 	}
+	s.folderStats.Invalidate(userID)
+	s.recycler.evictOverflow(ctx, userID)
 	return nil
 }
 
@@ -1207,11 +1332,19 @@ func (s *fileService) BatchMoveFiles(ctx context.Context, userID uint, fileIDs [
 	if err := s.files.UpdateByIDsAndUser(ctx, nil, fileIDs, userID, map[string]interface{}{"folder_id": resolvedFolderID}); err != nil {
 		return newAppError(http.StatusInternalServerError, "批量移动失败", err)
 	}
+	s.folderStats.Invalidate(userID)
 	return nil
 }
 
-// BatchGetThumbnails 批量查询缩略图可用性并保持入参顺序。
-func (s *fileService) BatchGetThumbnails(ctx context.Context, userID uint, fileIDs []uint) (ThumbnailBatchOutput, error) {
+// BatchGetThumbnails 批量查询缩略图可用性并保持入参顺序；preset 非空时返回对应预设尺寸的地址。
+func (s *fileService) BatchGetThumbnails(ctx context.Context, userID uint, fileIDs []uint, preset string) (ThumbnailBatchOutput, error) {
+	urlSuffix := ""
+	if preset != "" {
+		if _, ok := config.AppConfig.Thumbnail.Presets[preset]; !ok {
+			return ThumbnailBatchOutput{}, newAppError(http.StatusBadRequest, "未知的缩略图预设", nil)
+		}
+		urlSuffix = "?preset=" + url.QueryEscape(preset)
+	}
 	fileRecords, err := s.files.GetByIDsAndUser(ctx, nil, userID, fileIDs, true)
 	if err != nil {
 		return ThumbnailBatchOutput{}, newAppError(http.StatusInternalServerError, "查询缩略图信息失败", err)
@@ -1238,7 +1371,7 @@ func (s *fileService) BatchGetThumbnails(ctx context.Context, userID uint, fileI
 			"file_id":       fileID,
 			"exists":        true,
 			"has_thumbnail": hasThumb,
-			"thumbnail_url": fmt.Sprintf("/api/files/%d/thumbnail", fileID),
+			"thumbnail_url": fmt.Sprintf("/api/files/%d/thumbnail", fileID) + urlSuffix,
 		})
 	}
 	return ThumbnailBatchOutput{Items: items}, nil
@@ -62,7 +62,7 @@ func newFakeFileRepo() *fakeFileRepo {
 	return &fakeFileRepo{nextID: 1}
 }
 
-func (r *fakeFileRepo) CountByFolder(context.Context, *gorm.DB, uint, uint, uint, bool) (int64, error) {
+func (r *fakeFileRepo) CountByFolder(context.Context, *gorm.DB, repositories.ListFilesInput) (int64, error) {
 	return 0, errors.New("not implemented")
 }
 
@@ -70,10 +70,30 @@ func (r *fakeFileRepo) CountByFolderAndOriginalName(context.Context, *gorm.DB, u
 	return 0, errors.New("not implemented")
 }
 
+func (r *fakeFileRepo) GetByFolderAndOriginalName(context.Context, *gorm.DB, uint, uint, string, uint) (models.File, error) {
+	return models.File{}, errors.New("not implemented")
+}
+
 func (r *fakeFileRepo) ListByFolder(context.Context, *gorm.DB, repositories.ListFilesInput) ([]models.File, error) {
 	return nil, errors.New("not implemented")
 }
 
+func (r *fakeFileRepo) ListByFolderAfter(context.Context, *gorm.DB, repositories.KeysetListInput) ([]models.File, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeFileRepo) ListRecentUploads(context.Context, *gorm.DB, repositories.RecentFilesInput) ([]models.File, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeFileRepo) CountSearch(context.Context, *gorm.DB, repositories.SearchInput) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (r *fakeFileRepo) Search(context.Context, *gorm.DB, repositories.SearchInput) ([]models.File, error) {
+	return nil, errors.New("not implemented")
+}
+
 func (r *fakeFileRepo) ListByFolderIDs(context.Context, *gorm.DB, uint, []uint, bool, bool) ([]models.File, error) {
 	return nil, errors.New("not implemented")
 }
@@ -403,7 +423,7 @@ func TestFileServiceUploadFileInstantUploadByMD5(t *testing.T) {
 	}
 	fileObjects.objectsByMD5[fileMD5] = existing
 
-	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), files, fileObjects, nil, nil, nil)
+	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), files, fileObjects, nil, nil, nil, nil, nil, nil, nil)
 	out, err := svc.UploadFile(context.Background(), 1, 0, file, header)
 	if err != nil {
 		t.Fatalf("UploadFile returned error: %v", err)
@@ -449,7 +469,7 @@ func TestFileServiceUploadFileDuplicateCheckFailure(t *testing.T) {
 	fileObjects.getByMD5Err = errors.New("db unavailable")
 
 	file, header, _ := makeMultipartFile("hello.txt", []byte("hello world"))
-	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), files, fileObjects, nil, nil, nil)
+	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), files, fileObjects, nil, nil, nil, nil, nil, nil, nil)
 	_, err := svc.UploadFile(context.Background(), 1, 0, file, header)
 	if err == nil {
 		t.Fatalf("expected UploadFile to return error")
@@ -494,7 +514,7 @@ func TestFileServiceInitChunkedUploadInstantUploadByMD5(t *testing.T) {
 	}
 	fileObjects.objectsByMD5[fileMD5] = existing
 
-	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), files, fileObjects, nil, nil, nil)
+	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), files, fileObjects, nil, nil, nil, nil, nil, nil, nil)
 	out, err := svc.InitChunkedUpload(context.Background(), 1, InitChunkedUploadInput{
 		FileName: "movie.mp4",
 		FileSize: existing.FileSize,
@@ -559,6 +579,10 @@ func TestFileServiceUploadChunkUsesDiskFallbackWhenProgressStoreFails(t *testing
 		uploadTasks,
 		nil,
 		uploadProgress,
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	chunkA, _, _ := makeMultipartFile("chunk.bin", []byte("part-a"))
@@ -53,8 +53,14 @@ func getMimeType(ext string) string {
 		".webp": "image/webp",
 		".pdf":  "application/pdf",
 		".txt":  "text/plain",
+		".md":   "text/markdown",
+		".csv":  "text/csv",
 		".mp4":  "video/mp4",
+		".m4v":  "video/x-m4v",
+		".mov":  "video/quicktime",
 		".mp3":  "audio/mpeg",
+		".m4a":  "audio/mp4",
+		".flac": "audio/flac",
 		".zip":  "application/zip",
 		".doc":  "application/msword",
 	}
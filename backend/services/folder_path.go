@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+// 路径寻址的目标类型。
+const (
+	PathTypeFolder = "folder"
+	PathTypeFile   = "file"
+)
+
+// maxFolderNameLength 与 folders.name 列宽保持一致。
+const maxFolderNameLength = 255
+
+// FolderTreeNode 为目录树节点。
+type FolderTreeNode struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ParentID *uint  `json:"parent_id"`
+	// HasChildren 表示存在子目录；受 depth 截断时 Children 为空但该值仍为 true。
+	HasChildren bool              `json:"has_children"`
+	Children    []*FolderTreeNode `json:"children"`
+}
+
+// ResolvedPath 为按路径查找到的目录或文件。
+type ResolvedPath struct {
+	Type   string         `json:"type"`
+	Path   string         `json:"path"`
+	Folder *models.Folder `json:"folder,omitempty"`
+	File   *models.File   `json:"file,omitempty"`
+}
+
+// GetFolderTree 一次性构建用户目录树；depth<=0 表示不限深度，depth=1 只展开根目录的直接子目录。
+func (s *folderService) GetFolderTree(ctx context.Context, userID uint, depth int) (*FolderTreeNode, error) {
+	root, err := s.resolver.getOrCreateUserRootFolder(ctx, nil, userID)
+	if err != nil {
+		return nil, newAppError(http.StatusInternalServerError, "获取根目录失败", err)
+	}
+	all, err := s.folders.ListByUser(ctx, nil, userID)
+	if err != nil {
+		return nil, newAppError(http.StatusInternalServerError, "获取文件夹列表失败", err)
+	}
+
+	// ListByUser 已按路径排序，同级目录按名称先后追加。
+	children := map[uint][]models.Folder{}
+	for _, folder := range all {
+		if folder.ID == root.ID {
+			continue
+		}
+		parentID := root.ID
+		// 兼容历史数据：无父目录的非根目录视为挂在根目录下。
+		if folder.ParentID != nil {
+			parentID = *folder.ParentID
+		}
+		children[parentID] = append(children[parentID], folder)
+	}
+
+	var build func(folder models.Folder, level int) *FolderTreeNode
+	build = func(folder models.Folder, level int) *FolderTreeNode {
+		node := &FolderTreeNode{
+			ID:          folder.ID,
+			Name:        folder.Name,
+			Path:        folder.Path,
+			ParentID:    folder.ParentID,
+			HasChildren: len(children[folder.ID]) > 0,
+			Children:    []*FolderTreeNode{},
+		}
+		if depth > 0 && level >= depth {
+			return node
+		}
+		for _, child := range children[folder.ID] {
+			node.Children = append(node.Children, build(child, level+1))
+		}
+		return node
+	}
+	return build(root, 0), nil
+}
+
+// GetAncestors 返回从根目录到目标目录（含自身）的路径链，folderID=0 时只返回根目录。
+func (s *folderService) GetAncestors(ctx context.Context, userID uint, folderID uint) ([]models.Folder, error) {
+	root, err := s.resolver.getOrCreateUserRootFolder(ctx, nil, userID)
+	if err != nil {
+		return nil, newAppError(http.StatusInternalServerError, "获取根目录失败", err)
+	}
+	if folderID == 0 || folderID == root.ID {
+		return []models.Folder{root}, nil
+	}
+
+	folder, err := s.folders.GetByIDAndUser(ctx, nil, folderID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newAppError(http.StatusNotFound, "文件夹不存在", nil)
+		}
+		return nil, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+	}
+
+	// 祖先路径可由 Path 逐段推出，一次查询取回整条链；路径升序即由浅到深。
+	list, err := s.folders.ListByPaths(ctx, nil, userID, ancestorPaths(folder.Path))
+	if err != nil {
+		return nil, newAppError(http.StatusInternalServerError, "查询上级目录失败", err)
+	}
+	ancestors := []models.Folder{root}
+	for _, item := range list {
+		if item.ID != root.ID && item.ID != folder.ID {
+			ancestors = append(ancestors, item)
+		}
+	}
+	return append(ancestors, folder), nil
+}
+
+// ResolvePath 按可读路径查找目录或文件；kind 为空时目录优先，同名文件需显式指定 kind=file。
+func (s *folderService) ResolvePath(ctx context.Context, userID uint, rawPath string, kind string) (ResolvedPath, error) {
+	cleaned, err := normalizeHumanPath(rawPath)
+	if err != nil {
+		return ResolvedPath{}, err
+	}
+	if kind != "" && kind != PathTypeFolder && kind != PathTypeFile {
+		return ResolvedPath{}, newAppError(http.StatusBadRequest, "无效的路径类型", nil)
+	}
+
+	if kind != PathTypeFile {
+		folder, err := s.findFolderByPath(ctx, nil, userID, cleaned)
+		if err == nil {
+			return ResolvedPath{Type: PathTypeFolder, Path: cleaned, Folder: &folder}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return ResolvedPath{}, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+		}
+	}
+
+	if kind != PathTypeFolder && cleaned != "/" {
+		dir, name := path.Split(cleaned)
+		parent, err := s.findFolderByPath(ctx, nil, userID, path.Clean(dir))
+		if err == nil {
+			file, err := s.files.GetByFolderAndOriginalName(ctx, nil, userID, parent.ID, name, 0)
+			if err == nil {
+				return ResolvedPath{Type: PathTypeFile, Path: cleaned, File: &file}, nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return ResolvedPath{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return ResolvedPath{}, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+		}
+	}
+	return ResolvedPath{}, newAppError(http.StatusNotFound, "路径不存在", nil)
+}
+
+// EnsureFolderPath 按路径逐级创建缺失目录（类似 mkdir -p），返回最深一级目录。
+func (s *folderService) EnsureFolderPath(ctx context.Context, userID uint, rawPath string) (models.Folder, error) {
+	cleaned, err := normalizeHumanPath(rawPath)
+	if err != nil {
+		return models.Folder{}, err
+	}
+	for _, name := range strings.Split(strings.Trim(cleaned, "/"), "/") {
+		if len(name) > maxFolderNameLength {
+			return models.Folder{}, newAppError(http.StatusBadRequest, "文件夹名称过长", nil)
+		}
+	}
+
+	var target models.Folder
+	created := false
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		root, err := s.resolver.getOrCreateUserRootFolder(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		target = root
+		if cleaned == "/" {
+			return nil
+		}
+
+		paths := ancestorPaths(cleaned)
+		existing, err := s.folders.ListByPaths(ctx, tx, userID, paths)
+		if err != nil {
+			return err
+		}
+		byPath := make(map[string]models.Folder, len(existing))
+		for _, folder := range existing {
+			byPath[folder.Path] = folder
+		}
+
+		for _, p := range paths[1:] {
+			if folder, ok := byPath[p]; ok {
+				target = folder
+				continue
+			}
+			parentID := target.ID
+			folder := models.Folder{
+				Name:     path.Base(p),
+				ParentID: &parentID,
+				UserID:   userID,
+				Path:     p,
+			}
+			if err := s.folders.Create(ctx, tx, &folder); err != nil {
+				return err
+			}
+			target = folder
+			created = true
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Folder{}, newAppError(http.StatusInternalServerError, "创建文件夹失败", err)
+	}
+	if created {
+		s.folderStats.Invalidate(userID)
+	}
+	return target, nil
+}
+
+// findFolderByPath 按完整路径查找活动目录，"/" 返回用户根目录。
+func (s *folderService) findFolderByPath(ctx context.Context, tx *gorm.DB, userID uint, p string) (models.Folder, error) {
+	if p == "/" {
+		return s.resolver.getOrCreateUserRootFolder(ctx, tx, userID)
+	}
+	list, err := s.folders.ListByPaths(ctx, tx, userID, []string{p})
+	if err != nil {
+		return models.Folder{}, err
+	}
+	if len(list) == 0 {
+		return models.Folder{}, gorm.ErrRecordNotFound
+	}
+	return list[0], nil
+}
+
+// normalizeHumanPath 将用户输入的路径规范为以 "/" 开头、无多余分隔符与 "."/".." 的形式。
+func normalizeHumanPath(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", newAppError(http.StatusBadRequest, "路径不能为空", nil)
+	}
+	if strings.ContainsRune(raw, 0) {
+		return "", newAppError(http.StatusBadRequest, "路径包含非法字符", nil)
+	}
+	return path.Clean("/" + raw), nil
+}
+
+// ancestorPaths 返回从根目录到 p 的逐级路径，如 /a/b 得到 ["/", "/a", "/a/b"]。
+func ancestorPaths(p string) []string {
+	paths := []string{"/"}
+	prefix := ""
+	for _, segment := range strings.Split(strings.Trim(p, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		prefix += "/" + segment
+		paths = append(paths, prefix)
+	}
+	return paths
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+// pathFileRepo 按 (目录, 原始文件名) 查找文件。
+type pathFileRepo struct {
+	*folderServiceFileRepo
+	files []models.File
+}
+
+func (r *pathFileRepo) GetByFolderAndOriginalName(_ context.Context, _ *gorm.DB, userID uint, folderID uint, name string, _ uint) (models.File, error) {
+	for _, file := range r.files {
+		if file.UserID == userID && file.FolderID == folderID && file.OriginalName == name {
+			return file, nil
+		}
+	}
+	return models.File{}, gorm.ErrRecordNotFound
+}
+
+// newPathFixture 构建目录 /docs、/docs/2024、/docs/2024/q1、/media，以及文件 /docs/2024/report.pdf 与同名冲突的 /docs/2024/q1 文件。
+func newPathFixture() (*folderServiceFolderRepo, *pathFileRepo, FolderService) {
+	repo := newFolderServiceFolderRepo()
+	isRoot := true
+	rootID := uint(1)
+	repo.folders[rootID] = models.Folder{ID: rootID, Name: "root", UserID: 1, Path: "/", IsRoot: &isRoot}
+	repo.rootByUser[1] = rootID
+	docsID, yearID := uint(2), uint(3)
+	repo.folders[docsID] = models.Folder{ID: docsID, Name: "docs", UserID: 1, ParentID: &rootID, Path: "/docs"}
+	repo.folders[yearID] = models.Folder{ID: yearID, Name: "2024", UserID: 1, ParentID: &docsID, Path: "/docs/2024"}
+	repo.folders[4] = models.Folder{ID: 4, Name: "q1", UserID: 1, ParentID: &yearID, Path: "/docs/2024/q1"}
+	repo.folders[5] = models.Folder{ID: 5, Name: "media", UserID: 1, ParentID: &rootID, Path: "/media"}
+	repo.folders[6] = models.Folder{ID: 6, Name: "other", UserID: 2, Path: "/other"}
+	repo.nextID = 10
+
+	files := &pathFileRepo{
+		folderServiceFileRepo: newFolderServiceFileRepo(),
+		files: []models.File{
+			{ID: 20, UserID: 1, FolderID: yearID, OriginalName: "report.pdf"},
+			{ID: 21, UserID: 1, FolderID: yearID, OriginalName: "q1"},
+		},
+	}
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, files, newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
+	return repo, files, svc
+}
+
+func TestFolderServiceGetFolderTree(t *testing.T) {
+	_, _, svc := newPathFixture()
+
+	tree, err := svc.GetFolderTree(context.Background(), 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tree.ID != 1 || len(tree.Children) != 2 || tree.Children[0].Name != "docs" || tree.Children[1].Name != "media" {
+		t.Fatalf("unexpected top level: %+v", tree)
+	}
+	q1 := tree.Children[0].Children[0].Children
+	if len(q1) != 1 || q1[0].Path != "/docs/2024/q1" || q1[0].HasChildren {
+		t.Fatalf("unexpected deepest level: %+v", q1)
+	}
+
+	shallow, err := svc.GetFolderTree(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	docs := shallow.Children[0]
+	if len(docs.Children) != 0 || !docs.HasChildren {
+		t.Fatalf("expected docs truncated but marked as having children: %+v", docs)
+	}
+}
+
+func TestFolderServiceGetAncestors(t *testing.T) {
+	_, _, svc := newPathFixture()
+
+	chain, err := svc.GetAncestors(context.Background(), 1, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []uint
+	for _, folder := range chain {
+		ids = append(ids, folder.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 || ids[3] != 4 {
+		t.Fatalf("unexpected ancestor chain: %v", ids)
+	}
+
+	root, err := svc.GetAncestors(context.Background(), 1, 0)
+	if err != nil || len(root) != 1 || root[0].ID != 1 {
+		t.Fatalf("expected root only, got %+v %v", root, err)
+	}
+
+	// 其他用户的目录不可见。
+	_, err = svc.GetAncestors(context.Background(), 1, 6)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", err)
+	}
+}
+
+func TestFolderServiceResolvePath(t *testing.T) {
+	_, _, svc := newPathFixture()
+	ctx := context.Background()
+
+	cases := []struct {
+		raw, kind, wantType string
+		wantID              uint
+	}{
+		{raw: "/", wantType: PathTypeFolder, wantID: 1},
+		{raw: "docs//2024/", wantType: PathTypeFolder, wantID: 3},
+		{raw: "/docs/2024/report.pdf", wantType: PathTypeFile, wantID: 20},
+		{raw: "/docs/./x/../2024/report.pdf", wantType: PathTypeFile, wantID: 20},
+		// 目录与文件同名时默认返回目录，kind=file 时返回文件。
+		{raw: "/docs/2024/q1", wantType: PathTypeFolder, wantID: 4},
+		{raw: "/docs/2024/q1", kind: PathTypeFile, wantType: PathTypeFile, wantID: 21},
+	}
+	for _, tc := range cases {
+		got, err := svc.ResolvePath(ctx, 1, tc.raw, tc.kind)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.raw, err)
+		}
+		if got.Type != tc.wantType {
+			t.Fatalf("%s: expected %s, got %+v", tc.raw, tc.wantType, got)
+		}
+		if tc.wantType == PathTypeFolder && got.Folder.ID != tc.wantID || tc.wantType == PathTypeFile && got.File.ID != tc.wantID {
+			t.Fatalf("%s: unexpected target %+v", tc.raw, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		raw, kind string
+		code      int
+	}{
+		{raw: "/docs/missing.txt", code: http.StatusNotFound},
+		{raw: "/docs/2024/report.pdf", kind: PathTypeFolder, code: http.StatusNotFound},
+		{raw: "/other", code: http.StatusNotFound},
+		{raw: "", code: http.StatusBadRequest},
+		{raw: "/docs", kind: "link", code: http.StatusBadRequest},
+	} {
+		_, err := svc.ResolvePath(ctx, 1, tc.raw, tc.kind)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.HTTPCode != tc.code {
+			t.Fatalf("%q (%s): expected %d, got %v", tc.raw, tc.kind, tc.code, err)
+		}
+	}
+}
+
+func TestFolderServiceEnsureFolderPath(t *testing.T) {
+	repo, _, svc := newPathFixture()
+	ctx := context.Background()
+
+	existing, err := svc.EnsureFolderPath(ctx, 1, "/docs/2024")
+	if err != nil || existing.ID != 3 {
+		t.Fatalf("expected existing folder 3, got %+v %v", existing, err)
+	}
+	if len(repo.folders) != 6 {
+		t.Fatalf("expected no folders created, got %d", len(repo.folders))
+	}
+
+	created, err := svc.EnsureFolderPath(ctx, 1, "docs/2024/q2/week1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Path != "/docs/2024/q2/week1" || created.Name != "week1" {
+		t.Fatalf("unexpected created folder: %+v", created)
+	}
+	q2 := repo.folders[*created.ParentID]
+	if q2.Path != "/docs/2024/q2" || q2.ParentID == nil || *q2.ParentID != 3 {
+		t.Fatalf("unexpected intermediate folder: %+v", q2)
+	}
+
+	root, err := svc.EnsureFolderPath(ctx, 1, "/")
+	if err != nil || root.ID != 1 {
+		t.Fatalf("expected root, got %+v %v", root, err)
+	}
+}
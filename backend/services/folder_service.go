@@ -3,18 +3,22 @@ package services
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
-	"mcloud/config"
 	"mcloud/models"
 	"mcloud/repositories"
 
 	"gorm.io/gorm"
 )
 
+// FolderListItem 为目录列表项，附带递归大小与数量统计。
+type FolderListItem struct {
+	models.Folder
+	// Stats 为递归统计，统计查询失败时省略，不影响列表本身。
+	Stats *FolderStats `json:"stats,omitempty"`
+}
+
 // FolderService 定义目录管理能力。
 type FolderService interface {
 	// GetOrCreateRootFolder 获取或初始化用户根目录。
@@ -22,37 +26,59 @@ type FolderService interface {
 	// ResolveFolderID 解析目标目录，folderID=0 时返回根目录。
 	ResolveFolderID(ctx context.Context, userID uint, folderID uint) (uint, error)
 	// ListFolders 查询某个父目录下的子目录列表。
-	ListFolders(ctx context.Context, userID uint, parentID *uint) ([]models.Folder, error)
+	ListFolders(ctx context.Context, userID uint, parentID *uint) ([]FolderListItem, error)
+	// ListEntries 合并列出目录下的子目录与文件（先目录后文件），使用游标分页。
+	ListEntries(ctx context.Context, userID uint, in ListEntriesInput) (EntryListOutput, error)
+	// GetFolderStats 查询目录（含子孙）的总大小、文件数与子目录数，folderID=0 表示根目录。
+	GetFolderStats(ctx context.Context, userID uint, folderID uint) (FolderStats, error)
 	// CreateFolder 在指定父目录下创建子目录。
 	CreateFolder(ctx context.Context, userID uint, name string, parentID uint) (models.Folder, error)
 	// RenameFolder 重命名目录并同步更新全部后代路径。
 	RenameFolder(ctx context.Context, userID uint, folderID uint, name string) (models.Folder, error)
 	// DeleteFolder 删除目录（开启回收站时为软删除）。
 	DeleteFolder(ctx context.Context, userID uint, folderID uint) error
+	// GetFolderTree 返回以根目录为起点的嵌套目录树，depth<=0 表示不限深度。
+	GetFolderTree(ctx context.Context, userID uint, depth int) (*FolderTreeNode, error)
+	// GetAncestors 返回根目录到目标目录的路径链，供面包屑使用。
+	GetAncestors(ctx context.Context, userID uint, folderID uint) ([]models.Folder, error)
+	// ResolvePath 按可读路径（如 /a/b/c.txt）查找目录或文件，kind 可限定为 folder 或 file。
+	ResolvePath(ctx context.Context, userID uint, rawPath string, kind string) (ResolvedPath, error)
+	// EnsureFolderPath 按路径逐级创建缺失目录并返回最深一级目录。
+	EnsureFolderPath(ctx context.Context, userID uint, rawPath string) (models.Folder, error)
 }
 
 // folderService 为 FolderService 的默认实现。
 type folderService struct {
-	txManager TxManager
-	folders   repositories.FolderRepository
-	files     repositories.FileRepository
-	recycle   repositories.RecycleBinRepository
-	resolver  folderResolver
+	txManager   TxManager
+	folders     repositories.FolderRepository
+	files       repositories.FileRepository
+	recycle     repositories.RecycleBinRepository
+	resolver    folderResolver
+	recycler    recycler
+	folderStats *FolderStatsCache
+	tagAttacher tagAttacher
 }
 
 // NewFolderService 创建目录服务实例。
 func NewFolderService(
 	txManager TxManager,
+	users repositories.UserRepository,
 	folders repositories.FolderRepository,
 	files repositories.FileRepository,
+	fileObjects repositories.FileObjectRepository,
 	recycle repositories.RecycleBinRepository,
+	folderStats *FolderStatsCache,
+	tags repositories.TagRepository,
 ) FolderService {
 	return &folderService{
-		txManager: txManager,
-		folders:   folders,
-		files:     files,
-		recycle:   recycle,
-		resolver:  folderResolver{folders: folders},
+		txManager:   txManager,
+		folders:     folders,
+		files:       files,
+		recycle:     recycle,
+		resolver:    folderResolver{folders: folders},
+		recycler:    newRecycler(txManager, users, folders, files, fileObjects, recycle),
+		folderStats: folderStats,
+		tagAttacher: tagAttacher{tags: tags},
 	}
 }
 
@@ -78,7 +104,7 @@ func (s *folderService) ResolveFolderID(ctx context.Context, userID uint, folder
 }
 
 // ListFolders 查询父目录下的子目录列表。
-func (s *folderService) ListFolders(ctx context.Context, userID uint, parentID *uint) ([]models.Folder, error) {
+func (s *folderService) ListFolders(ctx context.Context, userID uint, parentID *uint) ([]FolderListItem, error) {
 	rootFolder, err := s.resolver.getOrCreateUserRootFolder(ctx, nil, userID)
 	if err != nil {
 		return nil, newAppError(http.StatusInternalServerError, "获取根目录失败", err)
@@ -103,7 +129,39 @@ func (s *folderService) ListFolders(ctx context.Context, userID uint, parentID *
 	if err != nil {
 		return nil, newAppError(http.StatusInternalServerError, "获取文件夹列表失败", err)
 	}
-	return list, nil
+
+	// 统计只是附加信息，失败时记录日志并返回不带统计的列表。
+	stats, err := s.folderStats.Get(ctx, userID)
+	warnOnError(ctx, "统计文件夹大小", err)
+	items := make([]FolderListItem, 0, len(list))
+	for _, folder := range list {
+		item := FolderListItem{Folder: folder}
+		if folderStats, ok := stats[folder.ID]; ok {
+			item.Stats = &folderStats
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetFolderStats 返回目录递归统计；目录存在但尚无统计行时返回零值。
+func (s *folderService) GetFolderStats(ctx context.Context, userID uint, folderID uint) (FolderStats, error) {
+	resolvedID, err := s.resolver.resolveFolderIDForUser(ctx, nil, userID, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return FolderStats{}, newAppError(http.StatusNotFound, "文件夹不存在", nil)
+		}
+		return FolderStats{}, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+	}
+
+	stats, err := s.folderStats.Get(ctx, userID)
+	if err != nil {
+		return FolderStats{}, newAppError(http.StatusInternalServerError, "统计文件夹大小失败", err)
+	}
+	if folderStats, ok := stats[resolvedID]; ok {
+		return folderStats, nil
+	}
+	return FolderStats{FolderID: resolvedID}, nil
 }
 
 // CreateFolder 在指定父目录下创建新目录。
@@ -144,6 +202,7 @@ func (s *folderService) CreateFolder(ctx context.Context, userID uint, name stri
 		return models.Folder{}, newAppError(http.StatusInternalServerError, "创建文件夹失败", err)
 	}
 
+	s.folderStats.Invalidate(userID)
 	return folder, nil
 }
 
@@ -207,6 +266,8 @@ func (s *folderService) RenameFolder(ctx context.Context, userID uint, folderID
 		}
 	}
 
+	// 统计按路径前缀归并，路径变化后需重算。
+	s.folderStats.Invalidate(userID)
 	folder.Name = name
 	folder.Path = newPath
 	return folder, nil
@@ -227,47 +288,14 @@ func (s *folderService) DeleteFolder(ctx context.Context, userID uint, folderID
 	}
 
 	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
-		if config.AppConfig.RecycleBin.Enabled {
-			// 开启回收站时先写入回收记录，再统一软删除目录及其子孙文件。
-			parentIDVal := uint(0)
-			if folder.ParentID != nil {
-				parentIDVal = *folder.ParentID
-			}
-			recycleItem := models.RecycleBinItem{
-				UserID:           userID,
-				OriginalID:       folder.ID,
-				OriginalType:     "folder",
-				OriginalName:     folder.Name,
-				OriginalPath:     folder.Path,
-				OriginalFullPath: folder.Path,
-				OriginalFolderID: folder.ParentID,
-				ExpiresAt:        time.Now().AddDate(0, 0, config.AppConfig.RecycleBin.RetentionDays),
-				Metadata:         fmt.Sprintf(`{"parent_id":%d}`, parentIDVal),
-			}
-			if err := s.recycle.Create(ctx, tx, &recycleItem); err != nil {
-				return err
-			}
-		}
-
-		affectedFolderIDs, err := s.folders.PluckIDsByPathPrefix(ctx, tx, userID, folderID, folder.Path)
-		if err != nil {
-			return err
-		}
-
-		if err := s.folders.SoftDeleteByPathPrefix(ctx, tx, userID, folderID, folder.Path); err != nil {
-			return err
-		}
-
-		if len(affectedFolderIDs) > 0 {
-			if err := s.files.SoftDeleteByFolderIDs(ctx, tx, userID, affectedFolderIDs); err != nil {
-				return err
-			}
-		}
-		return nil
+		// 开启回收站时先写入回收记录，再统一软删除目录及其子孙文件。
+		return s.recycler.recycleFolder(ctx, tx, userID, folder)
 	})
 	if err != nil {
 		return newAppError(http.StatusInternalServerError, "删除文件夹失败", err)
 	}
 
+	s.folderStats.Invalidate(userID)
+	s.recycler.evictOverflow(ctx, userID)
 	return nil
 }
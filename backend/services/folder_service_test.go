@@ -116,6 +116,78 @@ func (r *folderServiceFolderRepo) ListByParent(_ context.Context, _ *gorm.DB, us
 	return out, nil
 }
 
+// ListByParentAfter 在内存中模拟 (排序键, id) 游标分页，仅比较名称或创建时间。
+func (r *folderServiceFolderRepo) ListByParentAfter(_ context.Context, _ *gorm.DB, in repositories.KeysetListInput) ([]models.Folder, error) {
+	less := func(a, b models.Folder) bool {
+		if in.SortBy == "created_at" && !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		if in.SortBy != "created_at" && a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	}
+	desc := in.Order == "desc"
+
+	var after *models.Folder
+	if in.AfterKey != nil {
+		after = &models.Folder{ID: in.AfterID}
+		switch key := in.AfterKey.(type) {
+		case time.Time:
+			after.CreatedAt = key
+		case string:
+			after.Name = key
+		}
+	}
+
+	out := make([]models.Folder, 0)
+	for _, folder := range r.folders {
+		if folder.UserID != in.UserID || folder.ParentID == nil || *folder.ParentID != in.FolderID {
+			continue
+		}
+		if after != nil && (desc && !less(folder, *after) || !desc && !less(*after, folder)) {
+			continue
+		}
+		out = append(out, folder)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if desc {
+			return less(out[j], out[i])
+		}
+		return less(out[i], out[j])
+	})
+	if len(out) > in.Limit {
+		out = out[:in.Limit]
+	}
+	return out, nil
+}
+
+func (r *folderServiceFolderRepo) ListByUser(_ context.Context, _ *gorm.DB, userID uint) ([]models.Folder, error) {
+	out := make([]models.Folder, 0)
+	for _, folder := range r.folders {
+		if folder.UserID == userID {
+			out = append(out, folder)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+func (r *folderServiceFolderRepo) ListByPaths(_ context.Context, _ *gorm.DB, userID uint, paths []string) ([]models.Folder, error) {
+	wanted := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		wanted[p] = true
+	}
+	out := make([]models.Folder, 0)
+	for _, folder := range r.folders {
+		if folder.UserID == userID && wanted[folder.Path] {
+			out = append(out, folder)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
 func (r *folderServiceFolderRepo) CountByParentAndName(_ context.Context, _ *gorm.DB, userID uint, parentID uint, name string, excludeID uint) (int64, error) {
 	if r.countErr != nil {
 		return 0, r.countErr
@@ -245,6 +317,10 @@ func (r *folderServiceRecycleRepo) CountByUser(context.Context, *gorm.DB, uint)
 func (r *folderServiceRecycleRepo) ListByUser(context.Context, *gorm.DB, repositories.RecycleBinListInput) ([]models.RecycleBinItem, error) {
 	return nil, nil
 }
+func (r *folderServiceRecycleRepo) ListByFilter(context.Context, *gorm.DB, repositories.RecycleBinFilter) ([]models.RecycleBinItem, error) {
+	return nil, nil
+}
+
 func (r *folderServiceRecycleRepo) ListAllByUser(context.Context, *gorm.DB, uint) ([]models.RecycleBinItem, error) {
 	return nil, nil
 }
@@ -261,6 +337,9 @@ func (r *folderServiceRecycleRepo) Create(_ context.Context, _ *gorm.DB, item *m
 	r.items = append(r.items, *item)
 	return nil
 }
+func (r *folderServiceRecycleRepo) UpdateExpiresAtByUser(context.Context, *gorm.DB, uint, int) error {
+	return nil
+}
 func (r *folderServiceRecycleRepo) DeleteByID(context.Context, *gorm.DB, uint) error { return nil }
 func (r *folderServiceRecycleRepo) DeleteByUser(context.Context, *gorm.DB, uint) error {
 	return nil
@@ -273,7 +352,7 @@ func TestFolderServiceResolveFolderIDNotFound(t *testing.T) {
 	repo := newFolderServiceFolderRepo()
 	repo.getByIDErr = gorm.ErrRecordNotFound
 
-	svc := NewFolderService(fakeTxManager{}, repo, newFolderServiceFileRepo(), &folderServiceRecycleRepo{})
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
 	_, err := svc.ResolveFolderID(context.Background(), 1, 123)
 	if err == nil {
 		t.Fatalf("expected error")
@@ -295,7 +374,7 @@ func TestFolderServiceCreateFolderSuccess(t *testing.T) {
 	repo.rootByUser[1] = 1
 	repo.nextID = 2
 
-	svc := NewFolderService(fakeTxManager{}, repo, newFolderServiceFileRepo(), &folderServiceRecycleRepo{})
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
 	folder, err := svc.CreateFolder(context.Background(), 1, "docs", 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -321,7 +400,7 @@ func TestFolderServiceRenameFolderUpdatesDescendantPath(t *testing.T) {
 	repo.folders[parentID] = models.Folder{ID: parentID, Name: "old", UserID: 1, ParentID: &rootID, Path: "/old"}
 	repo.folders[3] = models.Folder{ID: 3, Name: "sub", UserID: 1, ParentID: &parentID, Path: "/old/sub"}
 
-	svc := NewFolderService(fakeTxManager{}, repo, newFolderServiceFileRepo(), &folderServiceRecycleRepo{})
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
 	renamed, err := svc.RenameFolder(context.Background(), 1, parentID, "new")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -348,7 +427,7 @@ func TestFolderServiceDeleteFolderWithRecycleBin(t *testing.T) {
 
 	files := newFolderServiceFileRepo()
 	recycle := &folderServiceRecycleRepo{}
-	svc := NewFolderService(fakeTxManager{}, repo, files, recycle)
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, files, newFakeFileObjectRepo(), recycle, nil, nil)
 
 	if err := svc.DeleteFolder(context.Background(), 1, targetID); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -377,7 +456,7 @@ func TestFolderServiceDeleteRootFolderRejected(t *testing.T) {
 	repo.folders[1] = models.Folder{ID: 1, Name: "root", UserID: 1, Path: "/", IsRoot: &isRoot}
 	repo.rootByUser[1] = 1
 
-	svc := NewFolderService(fakeTxManager{}, repo, newFolderServiceFileRepo(), &folderServiceRecycleRepo{})
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
 	err := svc.DeleteFolder(context.Background(), 1, 1)
 	if err == nil {
 		t.Fatalf("expected error")
@@ -399,7 +478,7 @@ func TestFolderServiceListFoldersUsesLegacyRootFlagForRoot(t *testing.T) {
 	repo.rootByUser[1] = rootID
 	repo.folders[2] = models.Folder{ID: 2, Name: "docs", UserID: 1, ParentID: &rootID, Path: "/docs"}
 
-	svc := NewFolderService(fakeTxManager{}, repo, newFolderServiceFileRepo(), &folderServiceRecycleRepo{})
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
 	list, err := svc.ListFolders(context.Background(), 1, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -420,7 +499,7 @@ func TestFolderServiceCreateFolderDuplicateName(t *testing.T) {
 	repo.rootByUser[1] = rootID
 	repo.folders[2] = models.Folder{ID: 2, Name: "docs", UserID: 1, ParentID: &rootID, Path: "/docs"}
 
-	svc := NewFolderService(fakeTxManager{}, repo, newFolderServiceFileRepo(), &folderServiceRecycleRepo{})
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, nil, nil)
 	_, err := svc.CreateFolder(context.Background(), 1, "docs", 0)
 	if err == nil {
 		t.Fatalf("expected duplicate-name error")
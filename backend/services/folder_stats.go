@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"mcloud/repositories"
+)
+
+// folderStatsTTL 为目录统计缓存的兜底有效期；多实例部署时其他实例的写入只能靠过期感知。
+const folderStatsTTL = 5 * time.Minute
+
+// FolderStats 为目录（含全部子孙）的递归统计。
+type FolderStats struct {
+	FolderID uint `json:"folder_id"`
+	// TotalSize 为子孙文件总大小（字节）。
+	TotalSize int64 `json:"total_size"`
+	// FileCount 为子孙文件总数。
+	FileCount int64 `json:"file_count"`
+	// FolderCount 为子孙目录总数，不含自身。
+	FolderCount int64 `json:"folder_count"`
+}
+
+// folderStatsEntry 为单个用户的统计快照。
+type folderStatsEntry struct {
+	stats      map[uint]FolderStats
+	computedAt time.Time
+}
+
+// FolderStatsCache 按用户惰性计算并缓存全部目录的递归统计。
+// 文件或目录发生变更时调用 Invalidate 使该用户的快照失效，下次读取时整体重算。
+type FolderStatsCache struct {
+	stats repositories.StorageStatsRepository
+
+	mu          sync.Mutex
+	entries     map[uint]folderStatsEntry
+	generations map[uint]uint64
+	now         func() time.Time
+}
+
+// NewFolderStatsCache 创建目录统计缓存。
+func NewFolderStatsCache(stats repositories.StorageStatsRepository) *FolderStatsCache {
+	return &FolderStatsCache{
+		stats:       stats,
+		entries:     map[uint]folderStatsEntry{},
+		generations: map[uint]uint64{},
+		now:         time.Now,
+	}
+}
+
+// Invalidate 使用户的统计快照失效；c 为 nil 时忽略，便于未启用统计的场景复用。
+func (c *FolderStatsCache) Invalidate(userID uint) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+	c.generations[userID]++
+}
+
+// Get 返回用户全部目录的统计，缓存缺失或过期时重新聚合；c 为 nil 时返回空结果。
+func (c *FolderStatsCache) Get(ctx context.Context, userID uint) (map[uint]FolderStats, error) {
+	if c == nil {
+		return nil, nil
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	generation := c.generations[userID]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.computedAt) < folderStatsTTL {
+		return entry.stats, nil
+	}
+
+	computedAt := c.now()
+	stats, err := c.compute(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算期间发生过失效则不写回，避免旧结果覆盖新变更。
+	c.mu.Lock()
+	if c.generations[userID] == generation {
+		c.entries[userID] = folderStatsEntry{stats: stats, computedAt: computedAt}
+	}
+	c.mu.Unlock()
+	return stats, nil
+}
+
+// compute 以目录直接用量为基础，沿路径前缀向上累加得到递归统计。
+func (c *FolderStatsCache) compute(ctx context.Context, userID uint) (map[uint]FolderStats, error) {
+	rows, err := c.stats.UserFolderUsage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	idByPath := make(map[string]uint, len(rows))
+	stats := make(map[uint]FolderStats, len(rows))
+	for _, row := range rows {
+		idByPath[row.Path] = row.FolderID
+		stats[row.FolderID] = FolderStats{FolderID: row.FolderID}
+	}
+
+	var rootID uint
+	for _, row := range rows {
+		if row.Path == "/" {
+			rootID = row.FolderID
+			continue
+		}
+		// 自身计入文件数与大小，严格祖先额外计入一个子孙目录。
+		prefix := ""
+		for _, segment := range strings.Split(strings.Trim(row.Path, "/"), "/") {
+			prefix += "/" + segment
+			ancestorID, ok := idByPath[prefix]
+			if !ok {
+				continue
+			}
+			ancestor := stats[ancestorID]
+			ancestor.FileCount += row.FileCount
+			ancestor.TotalSize += row.Bytes
+			if prefix != row.Path {
+				ancestor.FolderCount++
+			}
+			stats[ancestorID] = ancestor
+		}
+	}
+
+	// 根目录直接取用户全部正常文件，兼容挂在 legacy root 下的历史文件。
+	if rootID != 0 {
+		usage, err := c.stats.UserUsage(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		stats[rootID] = FolderStats{
+			FolderID:    rootID,
+			TotalSize:   usage.ActiveBytes,
+			FileCount:   usage.ActiveFiles,
+			FolderCount: int64(len(rows) - 1),
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mcloud/models"
+	"mcloud/repositories"
+)
+
+func newFolderStatsFixture() *fakeStorageStatsRepo {
+	return &fakeStorageStatsRepo{
+		usage: repositories.UserStorageUsage{ActiveFiles: 6, ActiveBytes: 700},
+		folderUsage: []repositories.FolderUsage{
+			{FolderID: 1, Path: "/", FileCount: 1, Bytes: 50},
+			{FolderID: 2, Path: "/docs", FileCount: 1, Bytes: 100},
+			{FolderID: 3, Path: "/docs/2024", FileCount: 2, Bytes: 200},
+			{FolderID: 4, Path: "/docs/2024/q1", FileCount: 1, Bytes: 300},
+			{FolderID: 5, Path: "/empty"},
+		},
+	}
+}
+
+func TestFolderStatsCacheRollsUpSubtrees(t *testing.T) {
+	cache := NewFolderStatsCache(newFolderStatsFixture())
+
+	stats, err := cache.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[uint]FolderStats{
+		// 根目录取用户全部正常文件，包含 legacy root 下未挂目录的文件。
+		1: {FolderID: 1, TotalSize: 700, FileCount: 6, FolderCount: 4},
+		2: {FolderID: 2, TotalSize: 600, FileCount: 4, FolderCount: 2},
+		3: {FolderID: 3, TotalSize: 500, FileCount: 3, FolderCount: 1},
+		4: {FolderID: 4, TotalSize: 300, FileCount: 1},
+		5: {FolderID: 5},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	for id, w := range want {
+		if stats[id] != w {
+			t.Fatalf("folder %d: got %+v want %+v", id, stats[id], w)
+		}
+	}
+}
+
+func TestFolderStatsCacheReusesUntilInvalidated(t *testing.T) {
+	repo := newFolderStatsFixture()
+	cache := NewFolderStatsCache(repo)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, 1); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if repo.folderCalls != 1 {
+		t.Fatalf("expected one aggregation, got %d", repo.folderCalls)
+	}
+
+	// 其他用户的失效不影响当前用户缓存。
+	cache.Invalidate(2)
+	if _, err := cache.Get(ctx, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.folderCalls != 1 {
+		t.Fatalf("expected cache hit after invalidating another user, got %d calls", repo.folderCalls)
+	}
+
+	cache.Invalidate(1)
+	if _, err := cache.Get(ctx, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.folderCalls != 2 {
+		t.Fatalf("expected recompute after invalidation, got %d calls", repo.folderCalls)
+	}
+}
+
+func TestFolderStatsCacheExpiresAfterTTL(t *testing.T) {
+	repo := newFolderStatsFixture()
+	cache := NewFolderStatsCache(repo)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	if _, err := cache.Get(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(folderStatsTTL)
+	if _, err := cache.Get(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.folderCalls != 2 {
+		t.Fatalf("expected recompute after ttl, got %d calls", repo.folderCalls)
+	}
+}
+
+func TestFolderStatsCacheDropsResultInvalidatedDuringCompute(t *testing.T) {
+	repo := newFolderStatsFixture()
+	cache := NewFolderStatsCache(repo)
+	// 模拟聚合期间发生写入：计算开始后立即失效。
+	cache.now = func() time.Time {
+		cache.Invalidate(1)
+		return time.Now()
+	}
+
+	if _, err := cache.Get(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := cache.entries[1]; ok {
+		t.Fatalf("expected stale result not to be cached")
+	}
+}
+
+func TestFolderStatsCacheNilIsNoop(t *testing.T) {
+	var cache *FolderStatsCache
+	cache.Invalidate(1)
+	stats, err := cache.Get(context.Background(), 1)
+	if err != nil || stats != nil {
+		t.Fatalf("expected empty result from nil cache, got %v %v", stats, err)
+	}
+}
+
+func TestFolderServiceListFoldersAttachesStats(t *testing.T) {
+	repo := newFolderServiceFolderRepo()
+	isRoot := true
+	rootID := uint(1)
+	repo.folders[rootID] = models.Folder{ID: rootID, Name: "root", UserID: 1, Path: "/", IsRoot: &isRoot}
+	repo.rootByUser[1] = rootID
+	repo.folders[2] = models.Folder{ID: 2, Name: "docs", UserID: 1, ParentID: &rootID, Path: "/docs"}
+	repo.folders[5] = models.Folder{ID: 5, Name: "empty", UserID: 1, ParentID: &rootID, Path: "/empty"}
+
+	cache := NewFolderStatsCache(newFolderStatsFixture())
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, cache, nil)
+
+	list, err := svc.ListFolders(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].Stats == nil || list[1].Stats == nil {
+		t.Fatalf("expected stats on every folder: %+v", list)
+	}
+	if *list[0].Stats != (FolderStats{FolderID: 2, TotalSize: 600, FileCount: 4, FolderCount: 2}) {
+		t.Fatalf("unexpected docs stats: %+v", *list[0].Stats)
+	}
+
+	root, err := svc.GetFolderStats(context.Background(), 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if root.FolderID != rootID || root.FileCount != 6 || root.TotalSize != 700 {
+		t.Fatalf("unexpected root stats: %+v", root)
+	}
+
+	// 创建目录会使缓存失效，下次读取重新聚合。
+	if _, err := svc.CreateFolder(context.Background(), 1, "new", 0); err != nil {
+		t.Fatalf("unexpected create error: %v", err)
+	}
+	if _, ok := cache.entries[1]; ok {
+		t.Fatalf("expected cache invalidated after create")
+	}
+}
+
+func TestFolderServiceListFoldersToleratesStatsError(t *testing.T) {
+	repo := newFolderServiceFolderRepo()
+	isRoot := true
+	rootID := uint(1)
+	repo.folders[rootID] = models.Folder{ID: rootID, Name: "root", UserID: 1, Path: "/", IsRoot: &isRoot}
+	repo.rootByUser[1] = rootID
+	repo.folders[2] = models.Folder{ID: 2, Name: "docs", UserID: 1, ParentID: &rootID, Path: "/docs"}
+
+	stats := &fakeStorageStatsRepo{folderUsageErr: errors.New("db timeout")}
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, NewFolderStatsCache(stats), nil)
+
+	list, err := svc.ListFolders(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].Stats != nil {
+		t.Fatalf("expected list without stats: %+v", list)
+	}
+
+	_, err = svc.GetFolderStats(context.Background(), 1, 2)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != 500 {
+		t.Fatalf("expected HTTP 500 from stats endpoint, got %v", err)
+	}
+}
+
+func TestFolderServiceGetFolderStatsNotFound(t *testing.T) {
+	repo := newFolderServiceFolderRepo()
+	svc := NewFolderService(fakeTxManager{}, newFakeUserRepo(), repo, newFolderServiceFileRepo(), newFakeFileObjectRepo(), &folderServiceRecycleRepo{}, NewFolderStatsCache(newFolderStatsFixture()), nil)
+
+	_, err := svc.GetFolderStats(context.Background(), 1, 99)
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != 404 {
+		t.Fatalf("expected HTTP 404, got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"mcloud/models"
+)
+
+// EXIF 中用到的标签号，IFD0、Exif 子 IFD 与 GPS 子 IFD 各自编号。
+const (
+	exifTagMake               = 0x010F
+	exifTagModel              = 0x0110
+	exifTagOrientation        = 0x0112
+	exifTagDateTime           = 0x0132
+	exifTagExifIFD            = 0x8769
+	exifTagGPSIFD             = 0x8825
+	exifTagDateTimeOriginal   = 0x9003
+	exifTagOffsetTimeOriginal = 0x9011
+	exifTagLensModel          = 0xA434
+
+	gpsTagLatitudeRef  = 0x0001
+	gpsTagLatitude     = 0x0002
+	gpsTagLongitudeRef = 0x0003
+	gpsTagLongitude    = 0x0004
+)
+
+const (
+	// maxExifScanBytes 为定位 EXIF 段时最多读取的文件头字节数，EXIF 总在像素数据之前。
+	maxExifScanBytes = 256 << 10
+	// maxExifTextLength 与元数据表中文本列的长度一致。
+	maxExifTextLength = 100
+	exifDateLayout    = "2006:01:02 15:04:05"
+)
+
+// exifTypeSizes 为 TIFF 字段类型对应的单个值字节数，未列出的类型不解析。
+var exifTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8,
+}
+
+var errNoExif = errors.New("图片不包含 EXIF 信息")
+
+// ReadImageMetadata 从 JPEG 或 PNG 文件头解析 EXIF：拍摄时间、相机、镜头、方向与 GPS 坐标。
+// 只读取文件头部，不解码像素；没有 EXIF 时返回 errNoExif。
+func ReadImageMetadata(filePath string) (models.ImageMetadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return models.ImageMetadata{}, err
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, maxExifScanBytes))
+	if err != nil {
+		return models.ImageMetadata{}, err
+	}
+	block, err := findExifBlock(head)
+	if err != nil {
+		return models.ImageMetadata{}, err
+	}
+	return parseExif(block)
+}
+
+// findExifBlock 在 JPEG 的 APP1 段或 PNG 的 eXIf 块中定位 TIFF 格式的 EXIF 数据。
+func findExifBlock(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return findJPEGExif(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return findPNGExif(data)
+	default:
+		return nil, errNoExif
+	}
+}
+
+func findJPEGExif(data []byte) ([]byte, error) {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil, errNoExif
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// 段之间允许填充 0xFF。
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// 到达扫描数据仍未找到 EXIF。
+			return nil, errNoExif
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, errNoExif
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		i += 2 + length
+	}
+	return nil, errNoExif
+}
+
+func findPNGExif(data []byte) ([]byte, error) {
+	i := 8
+	for i+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		if length < 0 || i+8+length > len(data) || chunkType == "IDAT" {
+			return nil, errNoExif
+		}
+		if chunkType == "eXIf" {
+			return data[i+8 : i+8+length], nil
+		}
+		// 跳过块数据与 4 字节 CRC。
+		i += 12 + length
+	}
+	return nil, errNoExif
+}
+
+// exifEntry 为 IFD 中的一个字段，value 已按偏移量取出。
+type exifEntry struct {
+	typ   uint16
+	count int
+	value []byte
+}
+
+type exifReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func parseExif(data []byte) (models.ImageMetadata, error) {
+	var meta models.ImageMetadata
+	if len(data) < 8 {
+		return meta, errNoExif
+	}
+	r := exifReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return meta, errNoExif
+	}
+	if r.order.Uint16(data[2:]) != 42 {
+		return meta, errNoExif
+	}
+
+	ifd0 := r.readIFD(r.order.Uint32(data[4:]))
+	meta.CameraMake = truncateExifText(ifd0[exifTagMake].text())
+	meta.CameraModel = truncateExifText(ifd0[exifTagModel].text())
+	if v, ok := r.uint(ifd0[exifTagOrientation]); ok && v >= 1 && v <= 8 {
+		meta.Orientation = int(v)
+	}
+
+	taken := ifd0[exifTagDateTime].text()
+	var offset string
+	if ptr, ok := r.uint(ifd0[exifTagExifIFD]); ok {
+		sub := r.readIFD(ptr)
+		if original := sub[exifTagDateTimeOriginal].text(); original != "" {
+			taken = original
+			offset = sub[exifTagOffsetTimeOriginal].text()
+		}
+		meta.LensModel = truncateExifText(sub[exifTagLensModel].text())
+	}
+	meta.TakenAt = parseExifTime(taken, offset)
+
+	if ptr, ok := r.uint(ifd0[exifTagGPSIFD]); ok {
+		gps := r.readIFD(ptr)
+		lat, latOK := r.coordinate(gps[gpsTagLatitude], gps[gpsTagLatitudeRef].text(), "S", 90)
+		lon, lonOK := r.coordinate(gps[gpsTagLongitude], gps[gpsTagLongitudeRef].text(), "W", 180)
+		// 未定位成功的相机常写入 0,0，视为没有坐标。
+		if latOK && lonOK && (lat != 0 || lon != 0) {
+			meta.Latitude, meta.Longitude = &lat, &lon
+		}
+	}
+	return meta, nil
+}
+
+// readIFD 读取一个 IFD 的全部字段；越界或类型未知的字段直接忽略。
+func (r exifReader) readIFD(offset uint32) map[uint16]exifEntry {
+	entries := make(map[uint16]exifEntry)
+	start := int(offset)
+	if offset == 0 || start+2 > len(r.data) {
+		return entries
+	}
+	count := int(r.order.Uint16(r.data[start:]))
+	for i := 0; i < count; i++ {
+		pos := start + 2 + i*12
+		if pos+12 > len(r.data) {
+			break
+		}
+		typ := r.order.Uint16(r.data[pos+2:])
+		size, ok := exifTypeSizes[typ]
+		if !ok {
+			continue
+		}
+		n := int(r.order.Uint32(r.data[pos+4:]))
+		total := size * n
+		if n <= 0 || total > len(r.data) {
+			continue
+		}
+		var value []byte
+		if total <= 4 {
+			value = r.data[pos+8 : pos+8+total]
+		} else {
+			valueOffset := int(r.order.Uint32(r.data[pos+8:]))
+			if valueOffset < 0 || valueOffset+total > len(r.data) {
+				continue
+			}
+			value = r.data[valueOffset : valueOffset+total]
+		}
+		entries[r.order.Uint16(r.data[pos:])] = exifEntry{typ: typ, count: n, value: value}
+	}
+	return entries
+}
+
+// text 返回 ASCII 字段去掉结尾 NUL 与空白后的内容。
+func (e exifEntry) text() string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
+
+// uint 返回 SHORT 或 LONG 字段的第一个值。
+func (r exifReader) uint(e exifEntry) (uint32, bool) {
+	switch e.typ {
+	case 3:
+		return uint32(r.order.Uint16(e.value)), true
+	case 4:
+		return r.order.Uint32(e.value), true
+	default:
+		return 0, false
+	}
+}
+
+// coordinate 将度、分、秒三个 RATIONAL 换算为十进制度数，negativeRef 表示南纬或西经。
+func (r exifReader) coordinate(e exifEntry, ref string, negativeRef string, limit float64) (float64, bool) {
+	if e.typ != 5 || e.count < 3 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num := r.order.Uint32(e.value[i*8:])
+		den := r.order.Uint32(e.value[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	value := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		value = -value
+	}
+	if math.Abs(value) > limit {
+		return 0, false
+	}
+	return value, true
+}
+
+// parseExifTime 解析 EXIF 时间；没有时区偏移时按服务器本地时区理解。
+func parseExifTime(value string, offset string) *time.Time {
+	if value == "" || strings.HasPrefix(value, "0000") {
+		return nil
+	}
+	var t time.Time
+	var err error
+	if offset != "" {
+		t, err = time.Parse(exifDateLayout+"-07:00", value+offset)
+	}
+	if offset == "" || err != nil {
+		t, err = time.ParseInLocation(exifDateLayout, value, time.Local)
+	}
+	if err != nil {
+		return nil
+	}
+	// 统一为本地时区，与上传时间的存储方式一致，时间线按两者混合排序时才可比较。
+	t = t.Local()
+	return &t
+}
+
+func truncateExifText(s string) string {
+	runes := []rune(s)
+	if len(runes) > maxExifTextLength {
+		return string(runes[:maxExifTextLength])
+	}
+	return s
+}
+
+// orientationSwapsAxes 判断 EXIF 方向是否需要旋转 90 度显示，此时显示宽高与像素宽高互换。
+func orientationSwapsAxes(orientation int) bool {
+	return orientation >= 5 && orientation <= 8
+}
+
+// probeImage 读取图片尺寸与 EXIF 元数据，尺寸已按 EXIF 方向换算为显示尺寸；
+// 元数据解析失败不影响尺寸结果，返回空元数据。
+func probeImage(filePath string) (int, int, models.ImageMetadata, error) {
+	width, height, err := GetImageDimensions(filePath)
+	if err != nil {
+		return 0, 0, models.ImageMetadata{}, err
+	}
+	meta, _ := ReadImageMetadata(filePath)
+	if orientationSwapsAxes(meta.Orientation) {
+		width, height = height, width
+	}
+	return width, height, meta, nil
+}
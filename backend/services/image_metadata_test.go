@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testExifField 为测试用的 IFD 字段，data 已按字节序编码。
+type testExifField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiExifField(tag uint16, s string) testExifField {
+	data := append([]byte(s), 0)
+	return testExifField{tag: tag, typ: 2, count: uint32(len(data)), data: data}
+}
+
+func shortExifField(order binary.ByteOrder, tag uint16, v uint16) testExifField {
+	data := make([]byte, 2)
+	order.PutUint16(data, v)
+	return testExifField{tag: tag, typ: 3, count: 1, data: data}
+}
+
+func rationalExifField(order binary.ByteOrder, tag uint16, values ...[2]uint32) testExifField {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		order.PutUint32(data[i*8:], v[0])
+		order.PutUint32(data[i*8+4:], v[1])
+	}
+	return testExifField{tag: tag, typ: 5, count: uint32(len(values)), data: data}
+}
+
+func testIFDLen(fields []testExifField) int {
+	n := 2 + 12*len(fields) + 4
+	for _, f := range fields {
+		if len(f.data) > 4 {
+			n += len(f.data)
+		}
+	}
+	return n
+}
+
+func encodeTestIFD(order binary.ByteOrder, start int, fields []testExifField) []byte {
+	out := make([]byte, 2+12*len(fields)+4)
+	order.PutUint16(out, uint16(len(fields)))
+	var extra []byte
+	for i, f := range fields {
+		p := 2 + 12*i
+		order.PutUint16(out[p:], f.tag)
+		order.PutUint16(out[p+2:], f.typ)
+		order.PutUint32(out[p+4:], f.count)
+		if len(f.data) <= 4 {
+			copy(out[p+8:], f.data)
+		} else {
+			order.PutUint32(out[p+8:], uint32(start+len(out)+len(extra)))
+			extra = append(extra, f.data...)
+		}
+	}
+	return append(out, extra...)
+}
+
+// buildTestTIFF 依次排布 IFD0、Exif 子 IFD 与 GPS 子 IFD，并在 IFD0 中写入子 IFD 指针。
+func buildTestTIFF(order binary.ByteOrder, ifd0, exifIFD, gpsIFD []testExifField) []byte {
+	pointer := func(tag uint16) testExifField {
+		return testExifField{tag: tag, typ: 4, count: 1, data: make([]byte, 4)}
+	}
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, pointer(exifTagExifIFD))
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, pointer(exifTagGPSIFD))
+	}
+	exifStart := 8 + testIFDLen(ifd0)
+	gpsStart := exifStart + testIFDLen(exifIFD)
+	for i := range ifd0 {
+		switch ifd0[i].tag {
+		case exifTagExifIFD:
+			order.PutUint32(ifd0[i].data, uint32(exifStart))
+		case exifTagGPSIFD:
+			order.PutUint32(ifd0[i].data, uint32(gpsStart))
+		}
+	}
+
+	header := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(header, "II")
+	} else {
+		copy(header, "MM")
+	}
+	order.PutUint16(header[2:], 42)
+	order.PutUint32(header[4:], 8)
+
+	out := append(header, encodeTestIFD(order, 8, ifd0)...)
+	if len(exifIFD) > 0 {
+		out = append(out, encodeTestIFD(order, exifStart, exifIFD)...)
+	}
+	if len(gpsIFD) > 0 {
+		out = append(out, encodeTestIFD(order, gpsStart, gpsIFD)...)
+	}
+	return out
+}
+
+func solidTestImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 30, G: 120, B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+// writeTestJPEGWithExif 写出一张 JPEG，并在 SOI 之后插入携带 tiff 的 APP1 段。
+func writeTestJPEGWithExif(t *testing.T, path string, width, height int, tiff []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, solidTestImage(width, height), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("encode jpeg failed: %v", err)
+	}
+	data := buf.Bytes()
+	var out []byte
+	out = append(out, data[:2]...)
+	if tiff != nil {
+		segment := append([]byte("Exif\x00\x00"), tiff...)
+		out = append(out, 0xFF, 0xE1, byte((len(segment)+2)>>8), byte(len(segment)+2))
+		out = append(out, segment...)
+	}
+	out = append(out, data[2:]...)
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatalf("write jpeg failed: %v", err)
+	}
+}
+
+func TestReadImageMetadataParsesJPEGExif(t *testing.T) {
+	order := binary.LittleEndian
+	tiff := buildTestTIFF(order,
+		[]testExifField{
+			asciiExifField(exifTagMake, "Apple"),
+			asciiExifField(exifTagModel, "iPhone 15 Pro"),
+			shortExifField(order, exifTagOrientation, 6),
+			asciiExifField(exifTagDateTime, "2024:05:02 08:00:00"),
+		},
+		[]testExifField{
+			asciiExifField(exifTagDateTimeOriginal, "2024:05:01 23:30:15"),
+			asciiExifField(exifTagOffsetTimeOriginal, "+08:00"),
+			asciiExifField(exifTagLensModel, "iPhone 15 Pro back camera 6.86mm f/1.78"),
+		},
+		[]testExifField{
+			asciiExifField(gpsTagLatitudeRef, "S"),
+			rationalExifField(order, gpsTagLatitude, [2]uint32{33, 1}, [2]uint32{51, 1}, [2]uint32{5400, 100}),
+			asciiExifField(gpsTagLongitudeRef, "E"),
+			rationalExifField(order, gpsTagLongitude, [2]uint32{151, 1}, [2]uint32{12, 1}, [2]uint32{0, 1}),
+		},
+	)
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	writeTestJPEGWithExif(t, path, 40, 20, tiff)
+
+	meta, err := ReadImageMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadImageMetadata failed: %v", err)
+	}
+	if meta.CameraMake != "Apple" || meta.CameraModel != "iPhone 15 Pro" || meta.Orientation != 6 {
+		t.Fatalf("unexpected camera fields: %+v", meta)
+	}
+	if meta.LensModel != "iPhone 15 Pro back camera 6.86mm f/1.78" {
+		t.Fatalf("unexpected lens: %q", meta.LensModel)
+	}
+	// 拍摄时间取 DateTimeOriginal 并应用时区偏移。
+	want := time.Date(2024, 5, 1, 15, 30, 15, 0, time.UTC)
+	if meta.TakenAt == nil || !meta.TakenAt.Equal(want) {
+		t.Fatalf("expected taken at %v, got %v", want, meta.TakenAt)
+	}
+	if meta.Latitude == nil || math.Abs(*meta.Latitude-(-33.865)) > 1e-9 || meta.Longitude == nil || math.Abs(*meta.Longitude-151.2) > 1e-9 {
+		t.Fatalf("unexpected coordinates: %v %v", meta.Latitude, meta.Longitude)
+	}
+}
+
+func TestReadImageMetadataParsesBigEndianPNGExif(t *testing.T) {
+	order := binary.BigEndian
+	tiff := buildTestTIFF(order,
+		[]testExifField{
+			asciiExifField(exifTagModel, "X100V"),
+			asciiExifField(exifTagDateTime, "2023:12:31 10:00:00"),
+		},
+		nil,
+		[]testExifField{
+			asciiExifField(gpsTagLatitudeRef, "N"),
+			rationalExifField(order, gpsTagLatitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+			asciiExifField(gpsTagLongitudeRef, "E"),
+			rationalExifField(order, gpsTagLongitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+		},
+	)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solidTestImage(8, 8)); err != nil {
+		t.Fatalf("encode png failed: %v", err)
+	}
+	data := buf.Bytes()
+	// eXIf 块紧跟 IHDR（8 字节签名 + 25 字节 IHDR 块）。
+	chunk := make([]byte, 8, 12+len(tiff))
+	binary.BigEndian.PutUint32(chunk, uint32(len(tiff)))
+	copy(chunk[4:], "eXIf")
+	chunk = append(chunk, tiff...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	out := append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+	path := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatalf("write png failed: %v", err)
+	}
+
+	meta, err := ReadImageMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadImageMetadata failed: %v", err)
+	}
+	want := time.Date(2023, 12, 31, 10, 0, 0, 0, time.Local)
+	if meta.CameraModel != "X100V" || meta.TakenAt == nil || !meta.TakenAt.Equal(want) {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+	// 0,0 坐标视为未定位。
+	if meta.Latitude != nil || meta.Longitude != nil {
+		t.Fatalf("expected zero coordinates to be dropped, got %v %v", meta.Latitude, meta.Longitude)
+	}
+	if w, h, err := GetImageDimensions(path); err != nil || w != 8 || h != 8 {
+		t.Fatalf("expected png with eXIf chunk to stay decodable, got %dx%d (%v)", w, h, err)
+	}
+}
+
+func TestReadImageMetadataWithoutExif(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.jpg")
+	writeTestJPEGWithExif(t, path, 10, 10, nil)
+	if _, err := ReadImageMetadata(path); err != errNoExif {
+		t.Fatalf("expected errNoExif, got %v", err)
+	}
+
+	// 截断或越界的 IFD 不应导致崩溃。
+	broken := buildTestTIFF(binary.LittleEndian, []testExifField{asciiExifField(exifTagMake, "Canon EOS R5")}, nil, nil)
+	if _, err := parseExif(broken[:20]); err != nil {
+		t.Fatalf("expected truncated IFD to be tolerated, got %v", err)
+	}
+}
+
+func TestProbeImageSwapsDimensionsForRotatedPhotos(t *testing.T) {
+	order := binary.LittleEndian
+	path := filepath.Join(t.TempDir(), "rotated.jpg")
+	writeTestJPEGWithExif(t, path, 40, 20, buildTestTIFF(order, []testExifField{shortExifField(order, exifTagOrientation, 8)}, nil, nil))
+
+	width, height, meta, err := probeImage(path)
+	if err != nil {
+		t.Fatalf("probeImage failed: %v", err)
+	}
+	if width != 20 || height != 40 || meta.Orientation != 8 {
+		t.Fatalf("expected display size 20x40 with orientation 8, got %dx%d %+v", width, height, meta)
+	}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"mcloud/config"
+	"mcloud/metrics"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	imageVariantFitContain = "contain"
+	imageVariantFitCover   = "cover"
+	imageVariantFormatJPEG = "jpeg"
+	imageVariantFormatWebP = "webp"
+	// maxVariantSourcePixels 限制参与生成变体的原图像素数，避免超大图片解码耗尽内存。
+	maxVariantSourcePixels = 100_000_000
+)
+
+var (
+	errVariantSourceTooLarge = errors.New("原图尺寸过大")
+	// variantGroup 合并同一变体的并发生成请求，variantSlots 限制同时解码的图片数量。
+	variantGroup singleflight.Group
+	variantSlots = make(chan struct{}, runtime.NumCPU())
+)
+
+// ImageVariantInput 为图片变体请求参数；Preset 非空时尺寸与裁剪方式取自预设，Format 仍可覆盖预设格式。
+type ImageVariantInput struct {
+	Preset string
+	Width  int
+	Height int
+	Fit    string
+	Format string
+}
+
+// imageVariantSpec 为校验后的变体规格，宽或高为 0 表示该方向不限制（仅 contain）。
+type imageVariantSpec struct {
+	width  int
+	height int
+	fit    string
+	format string
+}
+
+// resolveImageVariantSpec 校验变体参数：自定义尺寸必须落在白名单内，防止任意尺寸请求占满磁盘与 CPU。
+func resolveImageVariantSpec(in ImageVariantInput) (imageVariantSpec, error) {
+	cfg := config.AppConfig.Thumbnail
+	format := strings.ToLower(strings.TrimSpace(in.Format))
+	if in.Preset != "" {
+		preset, ok := cfg.Presets[in.Preset]
+		if !ok {
+			return imageVariantSpec{}, newAppError(http.StatusBadRequest, "未知的缩略图预设", nil)
+		}
+		if format == "" {
+			format = preset.Format
+		}
+		spec := imageVariantSpec{width: preset.Width, height: preset.Height, fit: preset.Fit, format: format}
+		return spec, validateImageVariantSpec(spec)
+	}
+
+	fit := strings.ToLower(strings.TrimSpace(in.Fit))
+	if fit == "" {
+		fit = imageVariantFitContain
+	}
+	if format == "" {
+		format = imageVariantFormatJPEG
+	}
+	for _, size := range []int{in.Width, in.Height} {
+		if size != 0 && !slices.Contains(cfg.VariantSizes, size) {
+			return imageVariantSpec{}, newAppError(http.StatusBadRequest, "不支持的图片尺寸", nil)
+		}
+	}
+	spec := imageVariantSpec{width: in.Width, height: in.Height, fit: fit, format: format}
+	return spec, validateImageVariantSpec(spec)
+}
+
+func validateImageVariantSpec(spec imageVariantSpec) error {
+	if spec.format != imageVariantFormatJPEG && spec.format != imageVariantFormatWebP {
+		return newAppError(http.StatusBadRequest, "不支持的图片格式", nil)
+	}
+	switch spec.fit {
+	case imageVariantFitContain:
+		if spec.width <= 0 && spec.height <= 0 {
+			return newAppError(http.StatusBadRequest, "宽高至少指定一项", nil)
+		}
+	case imageVariantFitCover:
+		if spec.width <= 0 || spec.height <= 0 {
+			return newAppError(http.StatusBadRequest, "cover 模式需要同时指定宽高", nil)
+		}
+	default:
+		return newAppError(http.StatusBadRequest, "不支持的缩放方式", nil)
+	}
+	return nil
+}
+
+func (spec imageVariantSpec) fileName() string {
+	ext := "jpg"
+	if spec.format == imageVariantFormatWebP {
+		ext = "webp"
+	}
+	return fmt.Sprintf("%dx%d_%s.%s", spec.width, spec.height, spec.fit, ext)
+}
+
+func (spec imageVariantSpec) contentType() string {
+	if spec.format == imageVariantFormatWebP {
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// imageVariantDir 返回某个文件对象的变体缓存目录（相对存储根目录）；内容相同的文件共享同一份缓存。
+func imageVariantDir(fileObjectID uint) string {
+	return filepath.Join("thumbnails", "variants", fmt.Sprintf("%d", fileObjectID))
+}
+
+// removeImageVariants 删除文件对象的全部变体缓存，在物理文件被清理时调用。
+func removeImageVariants(ctx context.Context, fileObjectID uint) {
+	warnOnError(ctx, "删除图片变体", os.RemoveAll(filepath.Join(config.AppConfig.Storage.BasePath, imageVariantDir(fileObjectID))))
+}
+
+// GetImageVariant 返回图片指定规格的变体，首次访问时从原图生成并缓存到磁盘。
+func (s *fileService) GetImageVariant(ctx context.Context, userID uint, fileID uint, in ImageVariantInput) (FileAccessOutput, error) {
+	spec, err := resolveImageVariantSpec(in)
+	if err != nil {
+		return FileAccessOutput{}, err
+	}
+	file, err := s.files.GetByIDAndUser(ctx, nil, fileID, userID, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return FileAccessOutput{}, newAppError(http.StatusNotFound, "文件不存在", nil)
+		}
+		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+	}
+	var src FileAccessOutput
+	switch {
+	case file.FileObject.IsImage:
+		src, err = fileAccessInfo(file)
+	case file.FileObject.ThumbnailPath != "":
+		// 音视频没有原图，以内嵌封面生成的缩略图为源，预设尺寸受封面缩略图大小限制。
+		src, err = thumbnailAccessInfo(file)
+	default:
+		return FileAccessOutput{}, newAppError(http.StatusBadRequest, "该文件不是图片", nil)
+	}
+	if err != nil {
+		return FileAccessOutput{}, err
+	}
+
+	absPath := filepath.Join(config.AppConfig.Storage.BasePath, imageVariantDir(file.FileObjectID), spec.fileName())
+	if err := ensureImageVariant(src.AbsPath, absPath, spec); err != nil {
+		if errors.Is(err, errVariantSourceTooLarge) {
+			return FileAccessOutput{}, newAppError(http.StatusRequestEntityTooLarge, "原图尺寸过大，无法生成变体", err)
+		}
+		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "生成图片变体失败", err)
+	}
+	stat, err := os.Stat(absPath)
+	if err != nil {
+		return FileAccessOutput{}, newAppError(http.StatusInternalServerError, "读取图片变体失败", err)
+	}
+	return FileAccessOutput{File: file, AbsPath: absPath, ContentType: spec.contentType(), ETag: derivedETag(file.FileObject.FileMD5, spec.fileName(), stat)}, nil
+}
+
+// ensureImageVariant 在变体缓存不存在时生成它；先写临时文件再改名，读者不会看到写了一半的文件。
+func ensureImageVariant(srcPath, dstPath string, spec imageVariantSpec) error {
+	if _, err := os.Stat(dstPath); err == nil {
+		return nil
+	}
+	_, err, _ := variantGroup.Do(dstPath, func() (interface{}, error) {
+		// 排队期间其他请求可能已生成完毕。
+		if _, err := os.Stat(dstPath); err == nil {
+			return nil, nil
+		}
+		variantSlots <- struct{}{}
+		defer func() { <-variantSlots }()
+
+		err := writeImageVariant(srcPath, dstPath, spec)
+		metrics.ObserveThumbnail(err)
+		return nil, err
+	})
+	return err
+}
+
+func writeImageVariant(srcPath, dstPath string, spec imageVariantSpec) error {
+	img, err := renderImageVariant(srcPath, spec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("创建变体目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), ".variant-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if spec.format == imageVariantFormatWebP {
+		err = EncodeWebPLossless(tmp, img)
+	} else {
+		err = imaging.Encode(tmp, img, imaging.JPEG, imaging.JPEGQuality(config.AppConfig.Thumbnail.Quality))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("写入图片变体失败: %w", err)
+	}
+	return os.Rename(tmp.Name(), dstPath)
+}
+
+// renderImageVariant 解码原图并按规格缩放；两种模式都不放大原图，cover 在原图不足时按比例缩小目标框。
+func renderImageVariant(srcPath string, spec imageVariantSpec) (image.Image, error) {
+	if err := checkVariantSourceSize(srcPath); err != nil {
+		return nil, err
+	}
+	img, err := openOrientedImage(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+
+	if spec.fit == imageVariantFitContain {
+		maxW, maxH := spec.width, spec.height
+		if maxW <= 0 {
+			maxW = srcW
+		}
+		if maxH <= 0 {
+			maxH = srcH
+		}
+		return imaging.Fit(img, maxW, maxH, imaging.Lanczos), nil
+	}
+
+	w, h := spec.width, spec.height
+	if srcW < w || srcH < h {
+		scale := min(float64(srcW)/float64(w), float64(srcH)/float64(h))
+		w, h = max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+	}
+	return imaging.Fill(img, w, h, imaging.Center, imaging.Lanczos), nil
+}
+
+// checkVariantSourceSize 只读取文件头判断原图像素数是否超限。
+func checkVariantSourceSize(srcPath string) error {
+	w, h, err := GetImageDimensions(srcPath)
+	if err != nil {
+		return fmt.Errorf("读取图片尺寸失败: %w", err)
+	}
+	if int64(w)*int64(h) > maxVariantSourcePixels {
+		return errVariantSourceTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+
+	"golang.org/x/image/webp"
+)
+
+func setupImageVariantFixture(t *testing.T) (FileService, string) {
+	t.Helper()
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{
+		Storage: config.StorageConfig{BasePath: baseDir},
+		Thumbnail: config.ThumbnailConfig{
+			Quality: 80,
+			Presets: map[string]config.ThumbnailPreset{
+				"small": {Width: 64, Height: 64, Fit: "cover", Format: "jpeg"},
+			},
+			VariantSizes: []int{64, 128, 256},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, patternTestImage(200, 100, false), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("encode jpeg failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(baseDir, "files"), 0755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "files", "src.jpg"), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write source failed: %v", err)
+	}
+
+	files := &quickAccessFileRepo{fakeFileRepo: newFakeFileRepo(), files: map[uint]models.File{
+		1: {ID: 1, UserID: 7, FileObjectID: 42, FileObject: models.FileObject{ID: 42, FilePath: filepath.Join("files", "src.jpg"), FileMD5: "0123456789abcdef0123456789abcdef", IsImage: true}},
+		2: {ID: 2, UserID: 7, FileObjectID: 43, FileObject: models.FileObject{ID: 43, FilePath: filepath.Join("files", "doc.txt")}},
+	}}
+	svc := NewFileService(fakeTxManager{}, nil, newFakeFolderRepo(), files, nil, nil, nil, nil, nil, nil, nil, nil)
+	return svc, baseDir
+}
+
+func decodeVariantConfig(t *testing.T, path string, decode func(*os.File) (image.Config, error)) image.Config {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open variant failed: %v", err)
+	}
+	defer f.Close()
+	cfg, err := decode(f)
+	if err != nil {
+		t.Fatalf("decode variant failed: %v", err)
+	}
+	return cfg
+}
+
+func TestGetImageVariantGeneratesAndCachesPerFileObject(t *testing.T) {
+	svc, baseDir := setupImageVariantFixture(t)
+	ctx := context.Background()
+
+	out, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Width: 128, Fit: "contain"})
+	if err != nil {
+		t.Fatalf("GetImageVariant failed: %v", err)
+	}
+	if want := filepath.Join(baseDir, "thumbnails", "variants", "42", "128x0_contain.jpg"); out.AbsPath != want || out.ContentType != "image/jpeg" {
+		t.Fatalf("unexpected variant %s (%s)", out.AbsPath, out.ContentType)
+	}
+	cfg := decodeVariantConfig(t, out.AbsPath, func(f *os.File) (image.Config, error) { return jpeg.DecodeConfig(f) })
+	if cfg.Width != 128 || cfg.Height != 64 {
+		t.Fatalf("expected 128x64 contain variant, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// 缓存命中后不再读取原图。
+	if err := os.Remove(filepath.Join(baseDir, "files", "src.jpg")); err != nil {
+		t.Fatalf("remove source failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "files", "src.jpg"), nil, 0644); err != nil {
+		t.Fatalf("truncate source failed: %v", err)
+	}
+	if _, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Width: 128}); err != nil {
+		t.Fatalf("expected cached variant, got %v", err)
+	}
+
+	removeImageVariants(ctx, 42)
+	if _, err := os.Stat(filepath.Dir(out.AbsPath)); !os.IsNotExist(err) {
+		t.Fatalf("expected variant dir to be removed, got %v", err)
+	}
+}
+
+func TestGetImageVariantPresetsAndWebP(t *testing.T) {
+	svc, _ := setupImageVariantFixture(t)
+	ctx := context.Background()
+
+	small, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Preset: "small"})
+	if err != nil {
+		t.Fatalf("preset variant failed: %v", err)
+	}
+	cfg := decodeVariantConfig(t, small.AbsPath, func(f *os.File) (image.Config, error) { return jpeg.DecodeConfig(f) })
+	if cfg.Width != 64 || cfg.Height != 64 {
+		t.Fatalf("expected 64x64 cover crop, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// 原图只有 200x100，cover 目标框按比例缩小而不是放大原图。
+	cover, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Width: 256, Height: 256, Fit: "cover", Format: "webp"})
+	if err != nil {
+		t.Fatalf("webp variant failed: %v", err)
+	}
+	if cover.ContentType != "image/webp" || filepath.Ext(cover.AbsPath) != ".webp" {
+		t.Fatalf("unexpected webp variant %s (%s)", cover.AbsPath, cover.ContentType)
+	}
+	cfg = decodeVariantConfig(t, cover.AbsPath, func(f *os.File) (image.Config, error) { return webp.DecodeConfig(f) })
+	if cfg.Width != 100 || cfg.Height != 100 {
+		t.Fatalf("expected cover box to shrink to 100x100, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestAccessInfoETags(t *testing.T) {
+	svc, _ := setupImageVariantFixture(t)
+	ctx := context.Background()
+
+	download, err := svc.GetDownloadInfo(ctx, 7, 1)
+	if err != nil || download.ETag != `"0123456789abcdef0123456789abcdef"` {
+		t.Fatalf("expected content md5 etag, got %q (%v)", download.ETag, err)
+	}
+	if contentETag("") != "" {
+		t.Fatal("expected no etag without content md5")
+	}
+
+	small, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Preset: "small"})
+	if err != nil {
+		t.Fatalf("GetImageVariant failed: %v", err)
+	}
+	again, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Preset: "small"})
+	if err != nil || again.ETag != small.ETag {
+		t.Fatalf("expected stable variant etag, got %q and %q (%v)", small.ETag, again.ETag, err)
+	}
+	webpVariant, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Preset: "small", Format: "webp"})
+	if err != nil {
+		t.Fatalf("GetImageVariant failed: %v", err)
+	}
+	if small.ETag == download.ETag || webpVariant.ETag == small.ETag || !strings.HasPrefix(small.ETag, `"0123456789abcdef0123456789abcdef-`) {
+		t.Fatalf("expected distinct variant etags, got %q, %q, %q", download.ETag, small.ETag, webpVariant.ETag)
+	}
+
+	// 变体重新生成后校验值随之变化。
+	if err := os.Chtimes(small.AbsPath, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("chtimes failed: %v", err)
+	}
+	regenerated, err := svc.GetImageVariant(ctx, 7, 1, ImageVariantInput{Preset: "small"})
+	if err != nil || regenerated.ETag == small.ETag {
+		t.Fatalf("expected etag to change with variant file, got %q (%v)", regenerated.ETag, err)
+	}
+}
+
+func TestGetImageVariantRejectsInvalidRequests(t *testing.T) {
+	svc, _ := setupImageVariantFixture(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		id   uint
+		in   ImageVariantInput
+		code int
+	}{
+		{"size outside allowlist", 1, ImageVariantInput{Width: 100}, http.StatusBadRequest},
+		{"unknown preset", 1, ImageVariantInput{Preset: "huge"}, http.StatusBadRequest},
+		{"cover needs both sides", 1, ImageVariantInput{Width: 64, Fit: "cover"}, http.StatusBadRequest},
+		{"missing size", 1, ImageVariantInput{}, http.StatusBadRequest},
+		{"unknown format", 1, ImageVariantInput{Width: 64, Format: "gif"}, http.StatusBadRequest},
+		{"unknown fit", 1, ImageVariantInput{Width: 64, Fit: "stretch"}, http.StatusBadRequest},
+		{"not an image", 2, ImageVariantInput{Width: 64}, http.StatusBadRequest},
+		{"other user's file", 3, ImageVariantInput{Width: 64}, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		_, err := svc.GetImageVariant(ctx, 7, tc.id, tc.in)
+		assertAppErrorCode(t, err, tc.code)
+	}
+}
+
+func TestBatchGetThumbnailsAppendsPreset(t *testing.T) {
+	svc, _ := setupImageVariantFixture(t)
+	ctx := context.Background()
+
+	out, err := svc.BatchGetThumbnails(ctx, 7, []uint{1}, "small")
+	if err != nil {
+		t.Fatalf("BatchGetThumbnails failed: %v", err)
+	}
+	if url := out.Items[0]["thumbnail_url"]; url != "/api/files/1/thumbnail?preset=small" {
+		t.Fatalf("unexpected thumbnail url %v", url)
+	}
+	_, err = svc.BatchGetThumbnails(ctx, 7, []uint{1}, "huge")
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"mcloud/logger"
+)
+
+// warnOnError 记录不影响主流程的失败（进度快照、临时文件清理等），
+// 日志带上 ctx 中的请求 ID；目标文件已不存在不视为失败。
+func warnOnError(ctx context.Context, action string, err error) {
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	logger.Ctx(ctx).Warnf("%s失败: %v", action, err)
+}
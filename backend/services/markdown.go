@@ -0,0 +1,559 @@
+package services
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 本文件实现一个精简的 Markdown 渲染器，覆盖 CommonMark 常用语法与 GFM 表格、删除线、任务列表。
+// 输出只包含固定白名单内的标签，所有文本与属性都经过转义，原始 HTML 按普通文本显示，因此结果无需再做清洗。
+
+// maxMarkdownDepth 限制引用与列表的嵌套层数，防止构造的深层嵌套耗尽栈空间。
+const maxMarkdownDepth = 16
+
+var (
+	mdHeadingRe     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdRuleRe        = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFenceRe       = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	mdBulletRe      = regexp.MustCompile(`^( {0,3})([-+*])[ \t]+(.*)$`)
+	mdOrderedRe     = regexp.MustCompile(`^( {0,3})(\d{1,9})[.)][ \t]+(.*)$`)
+	mdTaskRe        = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	mdTableDelimRe  = regexp.MustCompile(`^\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdAutolinkRe    = regexp.MustCompile(`^<((?:https?|mailto):[^\s<>]+)>`)
+	mdCodeLangRe    = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+	mdPunctuation   = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	mdAllowedScheme = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+// renderMarkdown 将 Markdown 渲染为可直接嵌入页面的 HTML。
+func renderMarkdown(src string) string {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\r", "\n")
+	var b strings.Builder
+	renderMarkdownBlocks(&b, strings.Split(src, "\n"), 0)
+	return b.String()
+}
+
+func renderMarkdownBlocks(b *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case mdFenceRe.MatchString(line):
+			i = renderMarkdownFence(b, lines, i)
+		case mdHeadingRe.MatchString(line):
+			m := mdHeadingRe.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderMarkdownInline(m[2]) + "</h" + level + ">\n")
+			i++
+		case mdRuleRe.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+		case isMarkdownQuote(line):
+			j := i
+			var inner []string
+			for j < len(lines) && isMarkdownQuote(lines[j]) {
+				rest := strings.TrimLeft(lines[j], " ")[1:]
+				inner = append(inner, strings.TrimPrefix(rest, " "))
+				j++
+			}
+			b.WriteString("<blockquote>\n")
+			renderMarkdownNested(b, inner, depth)
+			b.WriteString("</blockquote>\n")
+			i = j
+		case mdBulletRe.MatchString(line) || mdOrderedRe.MatchString(line):
+			i = renderMarkdownList(b, lines, i, depth)
+		case i+1 < len(lines) && strings.Contains(line, "|") && mdTableDelimRe.MatchString(lines[i+1]):
+			i = renderMarkdownTable(b, lines, i)
+		default:
+			j := i
+			var para []string
+			for j < len(lines) && strings.TrimSpace(lines[j]) != "" && (j == i || !startsMarkdownBlock(lines[j])) {
+				para = append(para, strings.TrimLeft(lines[j], " \t"))
+				j++
+			}
+			b.WriteString("<p>" + renderMarkdownInline(strings.Join(para, "\n")) + "</p>\n")
+			i = j
+		}
+	}
+}
+
+// renderMarkdownNested 渲染引用或列表项内部的块；超过嵌套上限时按纯文本输出。
+func renderMarkdownNested(b *strings.Builder, lines []string, depth int) {
+	if depth >= maxMarkdownDepth {
+		b.WriteString("<p>" + html.EscapeString(strings.Join(lines, "\n")) + "</p>\n")
+		return
+	}
+	renderMarkdownBlocks(b, lines, depth+1)
+}
+
+func isMarkdownQuote(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) <= 3 && strings.HasPrefix(trimmed, ">")
+}
+
+// startsMarkdownBlock 判断一行能否打断段落。
+func startsMarkdownBlock(line string) bool {
+	return mdFenceRe.MatchString(line) || mdHeadingRe.MatchString(line) || mdRuleRe.MatchString(line) ||
+		isMarkdownQuote(line) || mdBulletRe.MatchString(line) || mdOrderedRe.MatchString(line)
+}
+
+// renderMarkdownFence 输出围栏代码块，返回代码块之后的行号；缺少结束围栏时延续到文末。
+func renderMarkdownFence(b *strings.Builder, lines []string, start int) int {
+	m := mdFenceRe.FindStringSubmatch(lines[start])
+	fence := m[1]
+	b.WriteString("<pre><code")
+	if lang := m[2]; lang != "" && mdCodeLangRe.MatchString(lang) {
+		b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString(">")
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		b.WriteString(html.EscapeString(lines[i]) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderMarkdownList 输出连续的同类列表项；缩进的后续行属于当前列表项，按块递归渲染以支持嵌套列表。
+func renderMarkdownList(b *strings.Builder, lines []string, start int, depth int) int {
+	ordered := !mdBulletRe.MatchString(lines[start])
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		m := mdOrderedRe.FindStringSubmatch(lines[start])
+		if n, _ := strconv.Atoi(m[2]); n != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(n) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	i := start
+	for i < len(lines) {
+		var m []string
+		if ordered {
+			m = mdOrderedRe.FindStringSubmatch(lines[i])
+		} else {
+			m = mdBulletRe.FindStringSubmatch(lines[i])
+		}
+		if m == nil {
+			break
+		}
+		item := []string{m[3]}
+		// 后续行缩进到列表项内容所在的列才属于该项。
+		indent := len(lines[i]) - len(m[3])
+		j := i + 1
+		for j < len(lines) {
+			line := lines[j]
+			if strings.TrimSpace(line) == "" {
+				if j+1 < len(lines) && leadingSpaces(lines[j+1]) >= indent {
+					item = append(item, "")
+					j++
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= indent {
+				item = append(item, line[indent:])
+			} else if startsMarkdownBlock(line) {
+				break
+			} else {
+				item = append(item, strings.TrimSpace(line))
+			}
+			j++
+		}
+
+		b.WriteString("<li>")
+		if task := mdTaskRe.FindStringSubmatch(item[0]); task != nil && !ordered {
+			checked := ""
+			if task[1] != " " {
+				checked = " checked"
+			}
+			b.WriteString(`<input type="checkbox" disabled` + checked + "> ")
+			item[0] = item[0][len(task[0]):]
+		}
+		if len(item) == 1 {
+			b.WriteString(renderMarkdownInline(item[0]))
+		} else {
+			b.WriteString("\n")
+			renderMarkdownNested(b, item, depth)
+		}
+		b.WriteString("</li>\n")
+		i = j
+		if i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) {
+			// 列表项之间允许有空行。
+			next := lines[i+1]
+			if (ordered && mdOrderedRe.MatchString(next)) || (!ordered && mdBulletRe.MatchString(next)) {
+				i++
+			}
+		}
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderMarkdownTable 输出 GFM 表格，返回表格之后的行号；对齐方式写入 style 属性。
+func renderMarkdownTable(b *strings.Builder, lines []string, start int) int {
+	header := splitMarkdownRow(lines[start])
+	var aligns []string
+	for _, cell := range splitMarkdownRow(lines[start+1]) {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns = append(aligns, "center")
+		case right:
+			aligns = append(aligns, "right")
+		case left:
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+	writeRow := func(cells []string, tag string) {
+		b.WriteString("<tr>")
+		for k := range header {
+			cell := ""
+			if k < len(cells) {
+				cell = cells[k]
+			}
+			b.WriteString("<" + tag)
+			if k < len(aligns) && aligns[k] != "" {
+				b.WriteString(` style="text-align:` + aligns[k] + `"`)
+			}
+			b.WriteString(">" + renderMarkdownInline(cell) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(header, "th")
+	b.WriteString("</thead>\n<tbody>\n")
+	i := start + 2
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		writeRow(splitMarkdownRow(lines[i]), "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// splitMarkdownRow 按未转义的 | 拆分表格行。
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == '|' {
+			cur.WriteByte('|')
+			i++
+			continue
+		}
+		if line[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(line[i])
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+func leadingSpaces(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// renderMarkdownInline 渲染行内语法：转义、代码、图片、链接、自动链接、粗体、斜体与删除线。
+func renderMarkdownInline(s string) string {
+	var b strings.Builder
+	renderMarkdownInlineTo(&b, s, 0)
+	return b.String()
+}
+
+// markdownInline 保存一段行内文本的预扫描结果。括号配对一次算出，找不到闭合标记的分隔符记下后不再重复查找，
+// 未闭合的 [、*、` 不会每个都扫描到段落末尾，渲染耗时与输入长度成线性关系。
+type markdownInline struct {
+	s string
+	// brackets / parens 为 [ 与 ( 对应的闭合位置，-1 表示没有；圆括号不跨行配对。
+	brackets []int32
+	parens   []int32
+	// noCloser 记录已确认后文不存在闭合标记的强调分隔符，noTicks 记录后文不存在的反引号串长度。
+	noCloser map[string]bool
+	noTicks  map[int]bool
+}
+
+func newMarkdownInline(s string) *markdownInline {
+	in := &markdownInline{s: s, noCloser: map[string]bool{}, noTicks: map[int]bool{}}
+	if !strings.ContainsRune(s, '[') {
+		return in
+	}
+	in.brackets = make([]int32, len(s))
+	in.parens = make([]int32, len(s))
+	var bracketStack, parenStack []int32
+	for i := 0; i < len(s); i++ {
+		in.brackets[i], in.parens[i] = -1, -1
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				in.brackets[i], in.parens[i] = -1, -1
+			}
+		case '[':
+			bracketStack = append(bracketStack, int32(i))
+		case ']':
+			if n := len(bracketStack); n > 0 {
+				in.brackets[bracketStack[n-1]] = int32(i)
+				bracketStack = bracketStack[:n-1]
+			}
+		case '(':
+			parenStack = append(parenStack, int32(i))
+		case ')':
+			if n := len(parenStack); n > 0 {
+				in.parens[parenStack[n-1]] = int32(i)
+				parenStack = parenStack[:n-1]
+			}
+		case '\n':
+			parenStack = parenStack[:0]
+		}
+	}
+	return in
+}
+
+func renderMarkdownInlineTo(b *strings.Builder, s string, depth int) {
+	if depth >= maxMarkdownDepth {
+		b.WriteString(html.EscapeString(s))
+		return
+	}
+	in := newMarkdownInline(s)
+	text := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[text:end]))
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(mdPunctuation, s[i+1]) >= 0:
+			flush(i)
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			text = i
+			continue
+		case c == '\n':
+			flush(i)
+			// 行尾两个空格表示硬换行。
+			if strings.HasSuffix(s[text:i], "  ") {
+				b.WriteString("<br>")
+			}
+			b.WriteString("\n")
+			i++
+			text = i
+			continue
+		case c == '`':
+			n := runLength(s, i, '`')
+			end := -1
+			if !in.noTicks[n] {
+				if end = strings.Index(s[i+n:], s[i:i+n]); end < 0 {
+					in.noTicks[n] = true
+				}
+			}
+			if end >= 0 && runLength(s, i+n+end, '`') == n {
+				flush(i)
+				code := strings.ReplaceAll(s[i+n:i+n+end], "\n", " ")
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n + end + n
+				text = i
+				continue
+			}
+			i += n
+			continue
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if label, dest, title, end, ok := in.parseLink(i + 1); ok {
+				flush(i)
+				if src, ok := safeMarkdownURL(dest, false); ok {
+					b.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(label) + `"`)
+					if title != "" {
+						b.WriteString(` title="` + html.EscapeString(title) + `"`)
+					}
+					b.WriteString(">")
+				} else {
+					b.WriteString(html.EscapeString(label))
+				}
+				i = end
+				text = i
+				continue
+			}
+		case c == '[':
+			if label, dest, title, end, ok := in.parseLink(i); ok {
+				flush(i)
+				if href, ok := safeMarkdownURL(dest, true); ok {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `"`)
+					if title != "" {
+						b.WriteString(` title="` + html.EscapeString(title) + `"`)
+					}
+					b.WriteString(` rel="nofollow noopener noreferrer">`)
+					renderMarkdownInlineTo(b, label, depth+1)
+					b.WriteString("</a>")
+				} else {
+					renderMarkdownInlineTo(b, label, depth+1)
+				}
+				i = end
+				text = i
+				continue
+			}
+		case c == '<':
+			if m := mdAutolinkRe.FindStringSubmatch(s[i:]); m != nil {
+				if href, ok := safeMarkdownURL(m[1], true); ok {
+					flush(i)
+					b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">` + html.EscapeString(m[1]) + "</a>")
+					i += len(m[0])
+					text = i
+					continue
+				}
+			}
+		case c == '*' || c == '_' || c == '~':
+			if tag, inner, end, ok := in.matchEmphasis(i); ok {
+				flush(i)
+				b.WriteString("<" + tag + ">")
+				renderMarkdownInlineTo(b, inner, depth+1)
+				b.WriteString("</" + tag + ">")
+				i = end
+				text = i
+				continue
+			}
+			i += runLength(s, i, c)
+			continue
+		}
+		i++
+	}
+	flush(len(s))
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// matchEmphasis 匹配从 i 开始的强调：** / __ 为粗体，* / _ 为斜体，~~ 为删除线。
+// 开始标记后与结束标记前不能是空白；下划线不能出现在单词内部，避免 snake_case 被误判。
+func (in *markdownInline) matchEmphasis(i int) (string, string, int, bool) {
+	s := in.s
+	c := s[i]
+	n := min(runLength(s, i, c), 2)
+	tag := "em"
+	switch {
+	case c == '~' && n == 2:
+		tag = "del"
+	case c == '~':
+		return "", "", 0, false
+	case n == 2:
+		tag = "strong"
+	}
+	if c == '_' && i > 0 && isMarkdownWordByte(s[i-1]) {
+		return "", "", 0, false
+	}
+	delim := s[i : i+n]
+	start := i + n
+	if start >= len(s) || s[start] == ' ' || s[start] == '\n' || in.noCloser[delim] {
+		return "", "", 0, false
+	}
+	for j := start + 1; j+n <= len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j:j+n] != delim || s[j-1] == ' ' || s[j-1] == '\n' {
+			continue
+		}
+		// 单个 * 或 _ 不能匹配到 ** 或 __ 的一部分。
+		if n == 1 && ((j+1 < len(s) && s[j+1] == c) || s[j-1] == c) {
+			j++
+			continue
+		}
+		if c == '_' && j+n < len(s) && isMarkdownWordByte(s[j+n]) {
+			continue
+		}
+		return tag, s[start:j], j + n, true
+	}
+	// 闭合标记的判断与开始位置无关，此处之后的同类分隔符同样找不到闭合标记。
+	in.noCloser[delim] = true
+	return "", "", 0, false
+}
+
+func isMarkdownWordByte(c byte) bool {
+	return c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// parseLink 解析从 s[i]（'['）开始的 [label](dest "title")，返回链接之后的位置。
+func (in *markdownInline) parseLink(i int) (label, dest, title string, end int, ok bool) {
+	s := in.s
+	if in.brackets == nil || in.brackets[i] < 0 {
+		return "", "", "", 0, false
+	}
+	j := int(in.brackets[i])
+	if j+1 >= len(s) || s[j+1] != '(' || in.parens[j+1] < 0 {
+		return "", "", "", 0, false
+	}
+	label = s[i+1 : j]
+	k := int(in.parens[j+1])
+
+	inner := strings.TrimSpace(s[j+2 : k])
+	dest = inner
+	if sp := strings.IndexAny(inner, " \t"); sp >= 0 {
+		dest = inner[:sp]
+		rest := strings.TrimSpace(inner[sp:])
+		if len(rest) >= 2 && (rest[0] == '"' || rest[0] == '\'') && rest[len(rest)-1] == rest[0] {
+			title = rest[1 : len(rest)-1]
+		} else {
+			return "", "", "", 0, false
+		}
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	return label, dest, title, k + 1, true
+}
+
+// safeMarkdownURL 只放行 http、https 与相对地址，链接额外允许 mailto；javascript:、data: 等协议一律拒绝。
+func safeMarkdownURL(raw string, allowMailto bool) (string, bool) {
+	if raw == "" || strings.ContainsFunc(raw, unicode.IsControl) {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" {
+		// 没有协议时，冒号只能出现在路径第一段之后，否则浏览器可能把它解析成协议。
+		if colon := strings.IndexByte(raw, ':'); colon >= 0 && !strings.ContainsAny(raw[:colon], "/?#") {
+			return "", false
+		}
+		return raw, true
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !mdAllowedScheme[scheme] || (scheme == "mailto" && !allowMailto) {
+		return "", false
+	}
+	return raw, true
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdownBlocks(t *testing.T) {
+	src := strings.Join([]string{
+		"# 标题 #",
+		"",
+		"第一段 **粗体** 与 *斜体*，`a < b` 和 ~~删除~~。",
+		"同一段的第二行",
+		"",
+		"- 项目一",
+		"- [x] 已完成",
+		"  - 嵌套",
+		"",
+		"3. 第三",
+		"4. 第四",
+		"",
+		"> 引用 [链接](https://example.com \"标题\")",
+		"",
+		"```go",
+		"fmt.Println(\"<hi>\")",
+		"```",
+		"",
+		"| 名称 | 大小 |",
+		"|:-----|-----:|",
+		"| a\\|b | 1 |",
+		"",
+		"***",
+	}, "\n")
+
+	got := renderMarkdown(src)
+	for _, want := range []string{
+		"<h1>标题</h1>",
+		"<p>第一段 <strong>粗体</strong> 与 <em>斜体</em>，<code>a &lt; b</code> 和 <del>删除</del>。\n同一段的第二行</p>",
+		"<ul>\n<li>项目一</li>\n<li><input type=\"checkbox\" disabled checked> \n<p>已完成</p>\n<ul>\n<li>嵌套</li>\n</ul>\n</li>\n</ul>",
+		"<ol start=\"3\">\n<li>第三</li>\n<li>第四</li>\n</ol>",
+		"<blockquote>\n<p>引用 <a href=\"https://example.com\" title=\"标题\" rel=\"nofollow noopener noreferrer\">链接</a></p>\n</blockquote>",
+		"<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)\n</code></pre>",
+		"<th style=\"text-align:left\">名称</th><th style=\"text-align:right\">大小</th>",
+		"<td style=\"text-align:left\">a|b</td>",
+		"<hr>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderMarkdownInlineEdgeCases(t *testing.T) {
+	cases := map[string]string{
+		"snake_case_name":           "<p>snake_case_name</p>\n",
+		"_斜体_ 与 __粗体__":             "<p><em>斜体</em> 与 <strong>粗体</strong></p>\n",
+		`\*不是斜体\*`:                  "<p>*不是斜体*</p>\n",
+		"2 * 3 * 4":                 "<p>2 * 3 * 4</p>\n",
+		"未闭合 `代码":                   "<p>未闭合 `代码</p>\n",
+		"<https://example.com/a?b>": "<p><a href=\"https://example.com/a?b\" rel=\"nofollow noopener noreferrer\">https://example.com/a?b</a></p>\n",
+		"![图](./a.png)":             "<p><img src=\"./a.png\" alt=\"图\"></p>\n",
+		"[文档](docs/a.md)":           "<p><a href=\"docs/a.md\" rel=\"nofollow noopener noreferrer\">文档</a></p>\n",
+		"行尾硬换行  \n下一行":              "<p>行尾硬换行  <br>\n下一行</p>\n",
+	}
+	for src, want := range cases {
+		if got := renderMarkdown(src); got != want {
+			t.Fatalf("renderMarkdown(%q) = %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestRenderMarkdownEscapesUnsafeContent(t *testing.T) {
+	cases := map[string]string{
+		"<script>alert(1)</script>":                 "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		"<img src=x onerror=alert(1)>":              "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n",
+		"[点我](javascript:alert(1))":                 "<p>点我</p>\n",
+		"[点我](JaVaScRiPt:alert(1))":                 "<p>点我</p>\n",
+		"[点我](java\tscript:alert(1))":               "<p>[点我](java\tscript:alert(1))</p>\n",
+		"![x](data:image/svg+xml;base64,PHN2Zz4=)":  "<p>x</p>\n",
+		"![x](mailto:a@b.c)":                        "<p>x</p>\n",
+		`[x](https://a.com/"onmouseover="alert(1))`: "<p><a href=\"https://a.com/&#34;onmouseover=&#34;alert(1)\" rel=\"nofollow noopener noreferrer\">x</a></p>\n",
+		"```\"><script>\nx\n```":                    "<pre><code>x\n</code></pre>\n",
+	}
+	for src, want := range cases {
+		if got := renderMarkdown(src); got != want {
+			t.Fatalf("renderMarkdown(%q) = %q, want %q", src, got, want)
+		}
+	}
+
+	// 深层嵌套超过上限后按纯文本输出，不会无限递归。
+	deep := renderMarkdown(strings.Repeat(">", 100) + " x")
+	if strings.Count(deep, "<blockquote>") > maxMarkdownDepth+1 {
+		t.Fatalf("expected nesting to be capped, got %d levels", strings.Count(deep, "<blockquote>"))
+	}
+}
+
+func TestRenderMarkdownPathologicalInputIsLinear(t *testing.T) {
+	// 未闭合的 [、*、_、` 与深层嵌套的链接都曾让每个分隔符扫描到段落末尾。
+	inputs := map[string]string{
+		"brackets":   strings.Repeat("[", maxMarkdownRenderBytes),
+		"stars":      strings.Repeat("*a ", maxMarkdownRenderBytes/3),
+		"underlines": strings.Repeat("_a ", maxMarkdownRenderBytes/3),
+		"strong":     strings.Repeat("**a ", maxMarkdownRenderBytes/4),
+		"backticks":  strings.Repeat("`a", maxMarkdownRenderBytes/2),
+		"open-links": strings.Repeat("[a](", maxMarkdownRenderBytes/4),
+		"nested":     strings.Repeat(strings.Repeat("[", 15)+"x"+strings.Repeat("](u)", 15)+" ", maxMarkdownRenderBytes/80),
+	}
+	for name, src := range inputs {
+		start := time.Now()
+		renderMarkdown(src)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("%s: rendering %d bytes took %v", name, len(src), elapsed)
+		}
+	}
+}
@@ -0,0 +1,903 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"mcloud/config"
+	"mcloud/models"
+)
+
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mov": true,
+	".m4a": true, ".mp3": true, ".flac": true,
+}
+
+// IsMediaFile 根据扩展名判断是否需要解析音视频元数据。
+func IsMediaFile(filename string) bool {
+	return mediaExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+const (
+	// maxMP4MoovBytes 限制读入内存的 moov 盒大小，长视频的索引表通常只有几 MB。
+	maxMP4MoovBytes = 64 << 20
+	maxMP4TopBoxes  = 1024
+	// maxID3TagBytes 限制读入内存的 ID3v2 标签大小，内嵌封面一般不超过数 MB。
+	maxID3TagBytes = 32 << 20
+	// maxMediaCoverBytes 为内嵌封面的大小上限，超过时不再用作缩略图。
+	maxMediaCoverBytes = 16 << 20
+	// mp3SyncScanBytes 为寻找首个 MPEG 音频帧时最多扫描的字节数。
+	mp3SyncScanBytes = 64 << 10
+)
+
+var errUnsupportedMedia = errors.New("不支持的音视频格式")
+
+// ReadMediaMetadata 按文件头识别 MP4/MOV、MP3 与 FLAC，返回时长、编码、标签与内嵌封面原始数据。
+// 只读取容器头部与索引，不解码音视频数据。
+func ReadMediaMetadata(path string) (models.MediaMetadata, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return models.MediaMetadata{}, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return models.MediaMetadata{}, nil, err
+	}
+	size := info.Size()
+
+	var head [12]byte
+	n, _ := io.ReadFull(f, head[:])
+	switch {
+	case n >= 8 && isMP4BoxType(string(head[4:8])):
+		return readMP4Metadata(f, size)
+	case n >= 4 && string(head[:3]) == "ID3", n >= 4 && string(head[:4]) == "fLaC":
+		return readTaggedAudioMetadata(f, size)
+	case n >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0:
+		return readTaggedAudioMetadata(f, size)
+	}
+	return models.MediaMetadata{}, nil, errUnsupportedMedia
+}
+
+func isMP4BoxType(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide", "pnot":
+		return true
+	}
+	return false
+}
+
+// readMP4Metadata 遍历顶层盒找到 ftyp 与 moov；moov 可能位于巨大的 mdat 之后，按偏移跳过而不读取。
+func readMP4Metadata(r io.ReaderAt, size int64) (models.MediaMetadata, []byte, error) {
+	meta := models.MediaMetadata{Format: "mp4"}
+	var offset int64
+	for i := 0; i < maxMP4TopBoxes && offset+8 <= size; i++ {
+		var hdr [16]byte
+		if _, err := r.ReadAt(hdr[:8], offset); err != nil {
+			return meta, nil, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		headerLen := int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			if _, err := r.ReadAt(hdr[8:16], offset+8); err != nil {
+				return meta, nil, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			headerLen = 16
+		}
+		if boxSize < headerLen || offset+boxSize > size {
+			break
+		}
+
+		switch typ {
+		case "ftyp":
+			brand := make([]byte, 4)
+			if _, err := r.ReadAt(brand, offset+headerLen); err == nil {
+				meta.Format = mp4FormatFromBrand(string(brand))
+			}
+		case "moov":
+			if boxSize-headerLen > maxMP4MoovBytes {
+				return meta, nil, fmt.Errorf("moov 盒过大: %d", boxSize)
+			}
+			moov := make([]byte, boxSize-headerLen)
+			if _, err := r.ReadAt(moov, offset+headerLen); err != nil {
+				return meta, nil, err
+			}
+			cover := parseMP4Moov(moov, &meta)
+			return meta, cover, nil
+		}
+		offset += boxSize
+	}
+	return meta, nil, errors.New("未找到 moov 盒")
+}
+
+func mp4FormatFromBrand(brand string) string {
+	switch brand {
+	case "qt  ":
+		return "mov"
+	case "M4A ", "M4B ":
+		return "m4a"
+	case "M4V ", "M4VH", "M4VP":
+		return "m4v"
+	}
+	return "mp4"
+}
+
+// eachMP4Box 依次回调 data 中的子盒，遇到非法长度时停止。
+func eachMP4Box(data []byte, fn func(typ string, body []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		typ := string(data[4:8])
+		headerLen := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerLen = 16
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			return
+		}
+		fn(typ, data[headerLen:size])
+		data = data[size:]
+	}
+}
+
+// parseMP4Moov 从 moov 中提取总时长、各轨道的分辨率与编码，以及 iTunes 风格标签与封面。
+func parseMP4Moov(moov []byte, meta *models.MediaMetadata) []byte {
+	var cover []byte
+	eachMP4Box(moov, func(typ string, body []byte) {
+		switch typ {
+		case "mvhd":
+			if timescale, duration, ok := parseMP4Duration(body); ok {
+				meta.DurationMs = duration * 1000 / timescale
+			}
+		case "trak":
+			parseMP4Track(body, meta)
+		case "udta":
+			eachMP4Box(body, func(typ string, body []byte) {
+				if typ == "meta" {
+					cover = parseMP4Meta(body, meta, cover)
+				}
+			})
+		case "meta":
+			cover = parseMP4Meta(body, meta, cover)
+		}
+	})
+	switch {
+	case meta.VideoCodec != "":
+		meta.Kind = "video"
+	case meta.AudioCodec != "":
+		meta.Kind = "audio"
+	}
+	meta.HasCover = cover != nil
+	return cover
+}
+
+// parseMP4Duration 解析 mvhd/mdhd 的时间刻度与时长，兼容 32 位与 64 位两种版本。
+func parseMP4Duration(body []byte) (timescale, duration int64, ok bool) {
+	if len(body) >= 32 && body[0] == 1 {
+		timescale = int64(binary.BigEndian.Uint32(body[20:24]))
+		duration = int64(binary.BigEndian.Uint64(body[24:32]))
+	} else if len(body) >= 20 {
+		timescale = int64(binary.BigEndian.Uint32(body[12:16]))
+		duration = int64(binary.BigEndian.Uint32(body[16:20]))
+	}
+	if timescale <= 0 || duration < 0 {
+		return 0, 0, false
+	}
+	return timescale, duration, true
+}
+
+func parseMP4Track(trak []byte, meta *models.MediaMetadata) {
+	var handler string
+	var width, height int
+	var entry []byte
+	var trackDurationMs int64
+	eachMP4Box(trak, func(typ string, body []byte) {
+		switch typ {
+		case "tkhd":
+			width, height = parseMP4TrackSize(body)
+		case "mdia":
+			eachMP4Box(body, func(typ string, body []byte) {
+				switch typ {
+				case "hdlr":
+					if len(body) >= 12 {
+						handler = string(body[8:12])
+					}
+				case "mdhd":
+					if timescale, duration, ok := parseMP4Duration(body); ok {
+						trackDurationMs = duration * 1000 / timescale
+					}
+				case "minf":
+					entry = findMP4SampleEntry(body)
+				}
+			})
+		}
+	})
+	if len(entry) < 8 {
+		return
+	}
+	codec := mp4CodecName(string(entry[4:8]))
+	payload := entry[8:]
+	switch handler {
+	case "vide":
+		if meta.VideoCodec != "" {
+			return
+		}
+		meta.VideoCodec = codec
+		meta.Width, meta.Height = width, height
+	case "soun":
+		if meta.AudioCodec != "" {
+			return
+		}
+		meta.AudioCodec = codec
+		// AudioSampleEntry：8 字节保留与数据引用后依次为版本信息、声道数、采样位数与 16.16 定点采样率。
+		if len(payload) >= 28 {
+			meta.Channels = int(binary.BigEndian.Uint16(payload[16:18]))
+			meta.SampleRate = int(binary.BigEndian.Uint32(payload[24:28]) >> 16)
+		}
+	default:
+		return
+	}
+	if meta.DurationMs == 0 {
+		meta.DurationMs = trackDurationMs
+	}
+}
+
+// parseMP4TrackSize 读取 tkhd 末尾的 16.16 定点宽高；变换矩阵表示旋转 90/270 度时交换宽高，得到显示尺寸。
+func parseMP4TrackSize(tkhd []byte) (int, int) {
+	if len(tkhd) < 84 {
+		return 0, 0
+	}
+	end := len(tkhd)
+	width := int(binary.BigEndian.Uint32(tkhd[end-8:end-4]) >> 16)
+	height := int(binary.BigEndian.Uint32(tkhd[end-4:]) >> 16)
+	a := int32(binary.BigEndian.Uint32(tkhd[end-44 : end-40]))
+	b := int32(binary.BigEndian.Uint32(tkhd[end-40 : end-36]))
+	if a == 0 && b != 0 {
+		width, height = height, width
+	}
+	return width, height
+}
+
+// findMP4SampleEntry 返回 minf/stbl/stsd 中的第一个样本描述（含 8 字节盒头）。
+func findMP4SampleEntry(minf []byte) []byte {
+	var entry []byte
+	eachMP4Box(minf, func(typ string, body []byte) {
+		if typ != "stbl" {
+			return
+		}
+		eachMP4Box(body, func(typ string, body []byte) {
+			if typ != "stsd" || len(body) < 16 {
+				return
+			}
+			size := binary.BigEndian.Uint32(body[8:12])
+			if size >= 8 && int(size) <= len(body)-8 {
+				entry = body[8 : 8+size]
+			}
+		})
+	})
+	return entry
+}
+
+func mp4CodecName(fourcc string) string {
+	switch fourcc {
+	case "avc1", "avc3":
+		return "h264"
+	case "hvc1", "hev1":
+		return "hevc"
+	case "av01":
+		return "av1"
+	case "vp08":
+		return "vp8"
+	case "vp09":
+		return "vp9"
+	case "mp4v":
+		return "mpeg4"
+	case "mp4a":
+		return "aac"
+	case "ac-3":
+		return "ac3"
+	case "ec-3":
+		return "eac3"
+	case "Opus":
+		return "opus"
+	case "fLaC":
+		return "flac"
+	case ".mp3":
+		return "mp3"
+	}
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, fourcc))
+}
+
+// parseMP4Meta 解析 meta/ilst 中的标题、艺术家、专辑与封面（covr）。
+// ISO 的 meta 为带版本号的 FullBox，QuickTime 的 meta 没有版本号，按其后是否紧跟 hdlr 区分。
+func parseMP4Meta(body []byte, meta *models.MediaMetadata, cover []byte) []byte {
+	if len(body) >= 12 && string(body[4:8]) != "hdlr" {
+		body = body[4:]
+	}
+	eachMP4Box(body, func(typ string, body []byte) {
+		if typ != "ilst" {
+			return
+		}
+		eachMP4Box(body, func(key string, item []byte) {
+			eachMP4Box(item, func(typ string, data []byte) {
+				if typ != "data" || len(data) < 8 {
+					return
+				}
+				value := data[8:]
+				switch key {
+				case "\xa9nam":
+					setIfEmpty(&meta.Title, string(value))
+				case "\xa9ART", "aART":
+					setIfEmpty(&meta.Artist, string(value))
+				case "\xa9alb":
+					setIfEmpty(&meta.Album, string(value))
+				case "covr":
+					if cover == nil && len(value) > 0 && len(value) <= maxMediaCoverBytes {
+						cover = value
+					}
+				}
+			})
+		})
+	})
+	return cover
+}
+
+// readTaggedAudioMetadata 解析 MP3 与 FLAC：先读取可选的 ID3v2 标签，再按其后的数据判断是 FLAC 还是 MPEG 音频帧。
+func readTaggedAudioMetadata(f io.ReadSeeker, size int64) (models.MediaMetadata, []byte, error) {
+	var meta models.MediaMetadata
+	var cover []byte
+	var id3Duration int64
+	offset, err := skipAndParseID3v2(f, &meta, &cover, &id3Duration)
+	if err != nil {
+		return meta, nil, err
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return meta, nil, err
+	}
+	var magic [4]byte
+	if _, err := io.ReadFull(f, magic[:]); err == nil && string(magic[:]) == "fLaC" {
+		flacCover, err := parseFLAC(f, &meta)
+		if flacCover != nil {
+			cover = flacCover
+		}
+		meta.HasCover = cover != nil
+		return meta, cover, err
+	}
+
+	meta.Kind, meta.Format, meta.AudioCodec = "audio", "mp3", "mp3"
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return meta, nil, err
+	}
+	if err := parseMP3Frames(f, offset, size, &meta); err != nil && id3Duration == 0 {
+		return meta, nil, err
+	}
+	if id3Duration > 0 {
+		meta.DurationMs = id3Duration
+	}
+	if meta.Title == "" && meta.Artist == "" && meta.Album == "" {
+		readID3v1(f, size, &meta)
+	}
+	meta.HasCover = cover != nil
+	return meta, cover, nil
+}
+
+// skipAndParseID3v2 解析文件开头的 ID3v2 标签（如有），返回标签之后音频数据的偏移。
+func skipAndParseID3v2(f io.ReadSeeker, meta *models.MediaMetadata, cover *[]byte, durationMs *int64) (int64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var hdr [10]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil || string(hdr[:3]) != "ID3" {
+		return 0, nil
+	}
+	tagSize := int64(syncsafeInt(hdr[6:10]))
+	offset := 10 + tagSize
+	if hdr[5]&0x10 != 0 {
+		offset += 10 // 标签尾部附带 footer
+	}
+	if tagSize > maxID3TagBytes {
+		return offset, nil
+	}
+	body := make([]byte, tagSize)
+	if _, err := io.ReadFull(f, body); err != nil {
+		return offset, nil
+	}
+	parseID3v2(hdr[3], hdr[5], body, meta, cover, durationMs)
+	return offset, nil
+}
+
+func syncsafeInt(b []byte) int {
+	n := 0
+	for _, v := range b {
+		n = n<<7 | int(v&0x7f)
+	}
+	return n
+}
+
+// removeUnsync 还原“非同步化”编码：去掉 0xFF 之后插入的 0x00。
+func removeUnsync(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
+
+// parseID3v2 解析 v2.2/v2.3/v2.4 的文本帧与图片帧；压缩或加密的帧直接跳过。
+func parseID3v2(major, flags byte, body []byte, meta *models.MediaMetadata, cover *[]byte, durationMs *int64) {
+	if major < 2 || major > 4 {
+		return
+	}
+	if flags&0x80 != 0 && major < 4 {
+		body = removeUnsync(body)
+	}
+	if flags&0x40 != 0 && major >= 3 && len(body) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if major == 4 {
+			extSize = syncsafeInt(body[:4])
+		}
+		if extSize > len(body) {
+			return
+		}
+		body = body[extSize:]
+	}
+
+	idLen, headerLen := 4, 10
+	if major == 2 {
+		idLen, headerLen = 3, 6
+	}
+	var pictureType byte = 0xff
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		var size int
+		var formatFlags byte
+		switch major {
+		case 2:
+			size = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+			formatFlags = body[9]
+		case 4:
+			size = syncsafeInt(body[4:8])
+			formatFlags = body[9]
+		}
+		if size <= 0 || size > len(body)-headerLen {
+			return
+		}
+		data := body[headerLen : headerLen+size]
+		body = body[headerLen+size:]
+
+		if major == 3 {
+			if formatFlags&0xc0 != 0 {
+				continue
+			}
+			if formatFlags&0x20 != 0 && len(data) > 0 {
+				data = data[1:]
+			}
+		}
+		if major == 4 {
+			if formatFlags&0x0c != 0 {
+				continue
+			}
+			if formatFlags&0x02 != 0 {
+				data = removeUnsync(data)
+			}
+			if formatFlags&0x01 != 0 && len(data) >= 4 {
+				data = data[4:]
+			}
+		}
+
+		switch id {
+		case "TIT2", "TT2":
+			setIfEmpty(&meta.Title, decodeID3Text(data))
+		case "TPE1", "TP1":
+			setIfEmpty(&meta.Artist, decodeID3Text(data))
+		case "TALB", "TAL":
+			setIfEmpty(&meta.Album, decodeID3Text(data))
+		case "TLEN", "TLE":
+			var ms int64
+			if _, err := fmt.Sscan(decodeID3Text(data), &ms); err == nil && ms > 0 {
+				*durationMs = ms
+			}
+		case "APIC", "PIC":
+			picType, image := parseID3Picture(data, major == 2)
+			// 优先使用封面（类型 3），否则取第一张图片。
+			if image != nil && len(image) <= maxMediaCoverBytes && (*cover == nil || (picType == 3 && pictureType != 3)) {
+				*cover, pictureType = image, picType
+			}
+		}
+	}
+}
+
+// parseID3Picture 解析 APIC/PIC 帧，返回图片类型与图片数据。
+func parseID3Picture(data []byte, v22 bool) (byte, []byte) {
+	if len(data) < 2 {
+		return 0, nil
+	}
+	encoding := data[0]
+	rest := data[1:]
+	if v22 {
+		if len(rest) < 4 {
+			return 0, nil
+		}
+		rest = rest[3:]
+	} else {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return 0, nil
+		}
+		rest = rest[end+1:]
+	}
+	if len(rest) < 1 {
+		return 0, nil
+	}
+	picType := rest[0]
+	rest = rest[1:]
+	_, rest = splitID3String(rest, encoding)
+	if len(rest) == 0 {
+		return picType, nil
+	}
+	return picType, rest
+}
+
+// splitID3String 按编码对应的终止符切出一个字符串，返回字符串部分与剩余数据。
+func splitID3String(data []byte, encoding byte) ([]byte, []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[:i], data[i+2:]
+			}
+		}
+		return data, nil
+	}
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		return data[:end], data[end+1:]
+	}
+	return data, nil
+}
+
+// decodeID3Text 解码文本帧：首字节为编码（0 Latin-1、1 带 BOM 的 UTF-16、2 UTF-16BE、3 UTF-8），多值时取第一个。
+func decodeID3Text(data []byte) string {
+	if len(data) < 1 {
+		return ""
+	}
+	encoding := data[0]
+	text, _ := splitID3String(data[1:], encoding)
+	switch encoding {
+	case 0:
+		return latin1String(text)
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(text) >= 2 {
+			switch {
+			case text[0] == 0xff && text[1] == 0xfe:
+				bigEndian, text = false, text[2:]
+			case text[0] == 0xfe && text[1] == 0xff:
+				bigEndian, text = true, text[2:]
+			}
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			if bigEndian {
+				units = append(units, binary.BigEndian.Uint16(text[i:]))
+			} else {
+				units = append(units, binary.LittleEndian.Uint16(text[i:]))
+			}
+		}
+		return string(utf16.Decode(units))
+	}
+	return string(text)
+}
+
+func latin1String(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// readID3v1 读取文件末尾 128 字节的 ID3v1 标签，作为没有 ID3v2 时的兜底。
+func readID3v1(f io.ReadSeeker, size int64, meta *models.MediaMetadata) {
+	if size < 128 {
+		return
+	}
+	var tag [128]byte
+	if _, err := f.Seek(size-128, io.SeekStart); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(f, tag[:]); err != nil || string(tag[:3]) != "TAG" {
+		return
+	}
+	field := func(b []byte) string {
+		if end := bytes.IndexByte(b, 0); end >= 0 {
+			b = b[:end]
+		}
+		return strings.TrimSpace(latin1String(b))
+	}
+	setIfEmpty(&meta.Title, field(tag[3:33]))
+	setIfEmpty(&meta.Artist, field(tag[33:63]))
+	setIfEmpty(&meta.Album, field(tag[63:93]))
+}
+
+var (
+	mp3BitratesV1 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3SampleRate = map[byte][3]int{3: {44100, 48000, 32000}, 2: {22050, 24000, 16000}, 0: {11025, 12000, 8000}}
+)
+
+type mp3FrameHeader struct {
+	mpeg1      bool
+	bitrate    int
+	sampleRate int
+	mono       bool
+	length     int
+}
+
+// parseMP3FrameHeader 解析 Layer III 帧头，非法或其他 Layer 的帧头返回 false。
+func parseMP3FrameHeader(b []byte) (mp3FrameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return mp3FrameHeader{}, false
+	}
+	version := (b[1] >> 3) & 3
+	layer := (b[1] >> 1) & 3
+	bitrateIndex := b[2] >> 4
+	rateIndex := (b[2] >> 2) & 3
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3FrameHeader{}, false
+	}
+	h := mp3FrameHeader{mpeg1: version == 3, sampleRate: mp3SampleRate[version][rateIndex], mono: b[3]>>6 == 3}
+	padding := int(b[2]>>1) & 1
+	if h.mpeg1 {
+		h.bitrate = mp3BitratesV1[bitrateIndex]
+		h.length = 144*h.bitrate*1000/h.sampleRate + padding
+	} else {
+		h.bitrate = mp3BitratesV2[bitrateIndex]
+		h.length = 72*h.bitrate*1000/h.sampleRate + padding
+	}
+	return h, true
+}
+
+// parseMP3Frames 定位首个音频帧：有 Xing/Info 头时按总帧数计算时长（VBR），否则按首帧码率估算（CBR）。
+func parseMP3Frames(f io.Reader, offset, size int64, meta *models.MediaMetadata) error {
+	buf := make([]byte, mp3SyncScanBytes)
+	n, _ := io.ReadFull(f, buf)
+	buf = buf[:n]
+	for i := 0; i+4 <= len(buf); i++ {
+		h, ok := parseMP3FrameHeader(buf[i:])
+		if !ok {
+			continue
+		}
+		// 下一帧也必须是合法帧头，避免把数据中的偶然 0xFF 当成同步字。
+		if next := i + h.length; next+4 <= len(buf) {
+			if _, ok := parseMP3FrameHeader(buf[next:]); !ok {
+				continue
+			}
+		}
+		meta.SampleRate = h.sampleRate
+		meta.Channels = 2
+		if h.mono {
+			meta.Channels = 1
+		}
+		// Xing 头位于帧头与边信息之后，边信息长度取决于 MPEG 版本与声道数。
+		samplesPerFrame, sideInfo := int64(1152), 32
+		switch {
+		case h.mpeg1 && h.mono:
+			sideInfo = 17
+		case !h.mpeg1 && h.mono:
+			samplesPerFrame, sideInfo = 576, 9
+		case !h.mpeg1:
+			samplesPerFrame, sideInfo = 576, 17
+		}
+		if x := i + 4 + sideInfo; x+12 <= len(buf) {
+			tag := string(buf[x : x+4])
+			if (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(buf[x+4:x+8])&1 != 0 {
+				frames := int64(binary.BigEndian.Uint32(buf[x+8 : x+12]))
+				meta.DurationMs = frames * samplesPerFrame * 1000 / int64(h.sampleRate)
+				return nil
+			}
+		}
+		audioBytes := size - offset - int64(i)
+		meta.DurationMs = audioBytes * 8 / int64(h.bitrate)
+		return nil
+	}
+	return errors.New("未找到 MPEG 音频帧")
+}
+
+// parseFLAC 依次读取 FLAC 元数据块：STREAMINFO 提供采样参数与总采样数，VORBIS_COMMENT 提供标签，PICTURE 提供封面。
+func parseFLAC(f io.ReadSeeker, meta *models.MediaMetadata) ([]byte, error) {
+	meta.Kind, meta.Format, meta.AudioCodec = "audio", "flac", "flac"
+	var cover []byte
+	var coverType uint32 = 0xffffffff
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return cover, err
+		}
+		last := hdr[0]&0x80 != 0
+		blockType := hdr[0] & 0x7f
+		length := int64(hdr[1])<<16 | int64(hdr[2])<<8 | int64(hdr[3])
+
+		wanted := blockType == 0 || blockType == 4 || (blockType == 6 && length <= maxMediaCoverBytes+1024)
+		if !wanted {
+			if _, err := f.Seek(length, io.SeekCurrent); err != nil {
+				return cover, err
+			}
+		} else {
+			block := make([]byte, length)
+			if _, err := io.ReadFull(f, block); err != nil {
+				return cover, err
+			}
+			switch blockType {
+			case 0:
+				if len(block) >= 18 {
+					v := binary.BigEndian.Uint64(block[10:18])
+					sampleRate := int64(v >> 44)
+					meta.SampleRate = int(sampleRate)
+					meta.Channels = int((v>>41)&7) + 1
+					if total := int64(v & (1<<36 - 1)); sampleRate > 0 {
+						meta.DurationMs = total * 1000 / sampleRate
+					}
+				}
+			case 4:
+				parseVorbisComments(block, meta)
+			case 6:
+				if picType, image := parseFLACPicture(block); image != nil && len(image) <= maxMediaCoverBytes &&
+					(cover == nil || (picType == 3 && coverType != 3)) {
+					cover, coverType = image, picType
+				}
+			}
+		}
+		if last {
+			return cover, nil
+		}
+	}
+}
+
+// parseVorbisComments 解析小端长度前缀的 KEY=value 注释列表。
+func parseVorbisComments(block []byte, meta *models.MediaMetadata) {
+	next := func() ([]byte, bool) {
+		if len(block) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(block[:4])
+		if uint64(n) > uint64(len(block)-4) {
+			return nil, false
+		}
+		v := block[4 : 4+n]
+		block = block[4+n:]
+		return v, true
+	}
+	if _, ok := next(); !ok { // vendor
+		return
+	}
+	if len(block) < 4 {
+		return
+	}
+	count := binary.LittleEndian.Uint32(block[:4])
+	block = block[4:]
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return
+		}
+		key, value, found := strings.Cut(string(comment), "=")
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "TITLE":
+			setIfEmpty(&meta.Title, value)
+		case "ARTIST":
+			setIfEmpty(&meta.Artist, value)
+		case "ALBUM":
+			setIfEmpty(&meta.Album, value)
+		}
+	}
+}
+
+// parseFLACPicture 解析 PICTURE 块（大端长度前缀的 MIME、描述与图片数据）。
+func parseFLACPicture(block []byte) (uint32, []byte) {
+	readLen := func(b []byte) (int, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		n := binary.BigEndian.Uint32(b[:4])
+		if uint64(n) > uint64(len(b)-4) {
+			return 0, false
+		}
+		return int(n), true
+	}
+	if len(block) < 8 {
+		return 0, nil
+	}
+	picType := binary.BigEndian.Uint32(block[:4])
+	rest := block[4:]
+	for i := 0; i < 2; i++ { // MIME 与描述
+		n, ok := readLen(rest)
+		if !ok {
+			return 0, nil
+		}
+		rest = rest[4+n:]
+	}
+	if len(rest) < 16 {
+		return 0, nil
+	}
+	rest = rest[16:] // 宽、高、色深、索引色数
+	n, ok := readLen(rest)
+	if !ok || n == 0 {
+		return picType, nil
+	}
+	return picType, rest[4 : 4+n]
+}
+
+// setIfEmpty 写入清理过的标签值，已有值时保留先解析到的结果。
+func setIfEmpty(dst *string, value string) {
+	if *dst != "" {
+		return
+	}
+	value = strings.TrimSpace(strings.ToValidUTF8(strings.TrimRight(value, "\x00"), ""))
+	*dst = truncateRunes(value, 255)
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit])
+}
+
+// uploadThumbnailRelPath 返回上传时生成的缩略图路径（相对存储根目录），按用户与年月分目录。
+func uploadThumbnailRelPath(userID uint, fileUUID string, now time.Time) string {
+	return filepath.Join("thumbnails", fmt.Sprintf("%d", userID), now.Format("2006"), now.Format("01"), fileUUID+"_thumb.jpg")
+}
+
+// probeMedia 解析音视频元数据，并在有内嵌封面且 thumbRelPath 非空时把封面写成缩略图；返回成功写入的缩略图路径，失败时为空。
+// 解析失败不阻断上传，返回的空记录让补录任务不再重复解析同一文件。
+func probeMedia(ctx context.Context, absPath, thumbRelPath string) (models.MediaMetadata, string) {
+	meta, cover, err := ReadMediaMetadata(absPath)
+	if err != nil {
+		if !errors.Is(err, errUnsupportedMedia) {
+			warnOnError(ctx, "解析音视频元数据", err)
+		}
+		return models.MediaMetadata{}, ""
+	}
+	if len(cover) == 0 || thumbRelPath == "" {
+		return meta, ""
+	}
+	if err := GenerateCoverThumbnail(cover, filepath.Join(config.AppConfig.Storage.BasePath, thumbRelPath)); err != nil {
+		warnOnError(ctx, "生成封面缩略图", err)
+		return meta, ""
+	}
+	return meta, thumbRelPath
+}
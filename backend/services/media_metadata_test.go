@@ -0,0 +1,395 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"mcloud/config"
+	"mcloud/models"
+
+	"gorm.io/gorm"
+)
+
+type fakeMediaMetadataRepo struct {
+	unparsed []models.FileObject
+	stored   map[uint]models.MediaMetadata
+	created  []models.MediaMetadata
+	thumbs   map[uint]string
+}
+
+func (r *fakeMediaMetadataRepo) Create(_ context.Context, _ *gorm.DB, meta *models.MediaMetadata) error {
+	r.created = append(r.created, *meta)
+	return nil
+}
+
+func (r *fakeMediaMetadataRepo) GetByFileObjectID(_ context.Context, _ *gorm.DB, fileObjectID uint) (models.MediaMetadata, error) {
+	meta, ok := r.stored[fileObjectID]
+	if !ok {
+		return models.MediaMetadata{}, gorm.ErrRecordNotFound
+	}
+	return meta, nil
+}
+
+func (r *fakeMediaMetadataRepo) ListUnparsedObjects(context.Context, *gorm.DB, int) ([]models.FileObject, error) {
+	return r.unparsed, nil
+}
+
+func (r *fakeMediaMetadataRepo) UpdateThumbnailPath(_ context.Context, _ *gorm.DB, fileObjectID uint, thumbnailPath string) error {
+	if r.thumbs == nil {
+		r.thumbs = make(map[uint]string)
+	}
+	r.thumbs[fileObjectID] = thumbnailPath
+	return nil
+}
+
+// testBox 拼出一个 MP4 盒：4 字节大端长度 + 类型 + 内容。
+func testBox(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	out = append(out, typ...)
+	return append(out, body...)
+}
+
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func testCoverJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, patternTestImage(60, 40, false), nil); err != nil {
+		t.Fatalf("encode cover failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testMP4Track 构造只含解析所需字段的 trak：tkhd 末尾为变换矩阵与宽高，stsd 中为一个样本描述。
+func testMP4Track(handler, fourcc string, width, height uint32, rotated bool, entryPayload []byte) []byte {
+	tkhd := make([]byte, 84)
+	matrix := tkhd[84-44:]
+	if rotated {
+		binary.BigEndian.PutUint32(matrix[4:], 0x00010000)
+		binary.BigEndian.PutUint32(matrix[12:], 0xffff0000)
+	} else {
+		binary.BigEndian.PutUint32(matrix[0:], 0x00010000)
+		binary.BigEndian.PutUint32(matrix[16:], 0x00010000)
+	}
+	binary.BigEndian.PutUint32(tkhd[76:], width<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], height<<16)
+
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:], 1000)
+	binary.BigEndian.PutUint32(mdhd[16:], 4000)
+	hdlr := append(make([]byte, 8), handler...)
+	hdlr = append(hdlr, make([]byte, 13)...)
+	entry := testBox(fourcc, make([]byte, 8), entryPayload)
+	stsd := testBox("stsd", make([]byte, 4), be32(1), entry)
+	return testBox("trak",
+		testBox("tkhd", tkhd),
+		testBox("mdia", testBox("mdhd", mdhd), testBox("hdlr", hdlr), testBox("minf", testBox("stbl", stsd))),
+	)
+}
+
+func testMP4Item(key string, flags uint32, value []byte) []byte {
+	return testBox(key, testBox("data", be32(flags), be32(0), value))
+}
+
+func writeTestMP4(t *testing.T, path string, brand string, rotated bool, cover []byte) {
+	t.Helper()
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 600)
+	binary.BigEndian.PutUint32(mvhd[16:], 600*125/10) // 12.5 秒
+
+	audio := make([]byte, 20)
+	binary.BigEndian.PutUint16(audio[8:], 2)
+	binary.BigEndian.PutUint32(audio[16:], 48000<<16)
+
+	ilst := testBox("ilst",
+		testMP4Item("\xa9nam", 1, []byte("Clip Title")),
+		testMP4Item("\xa9ART", 1, []byte("Someone")),
+		testMP4Item("covr", 13, cover),
+	)
+	hdlr := testBox("hdlr", make([]byte, 8), []byte("mdir"), make([]byte, 13))
+	moov := testBox("moov",
+		testBox("mvhd", mvhd),
+		testMP4Track("vide", "avc1", 1920, 1080, rotated, make([]byte, 70)),
+		testMP4Track("soun", "mp4a", 0, 0, false, audio),
+		testBox("udta", testBox("meta", make([]byte, 4), hdlr, ilst)),
+	)
+	// moov 放在 mdat 之后，验证按偏移跳过大块媒体数据。
+	data := bytes.Join([][]byte{
+		testBox("ftyp", []byte(brand), be32(0), []byte("isom")),
+		testBox("mdat", make([]byte, 4096)),
+		moov,
+	}, nil)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write mp4 failed: %v", err)
+	}
+}
+
+func TestReadMediaMetadataMP4(t *testing.T) {
+	dir := t.TempDir()
+	cover := testCoverJPEG(t)
+	path := filepath.Join(dir, "clip.mp4")
+	writeTestMP4(t, path, "isom", false, cover)
+
+	meta, gotCover, err := ReadMediaMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMediaMetadata failed: %v", err)
+	}
+	want := models.MediaMetadata{
+		Kind: "video", Format: "mp4", DurationMs: 12500, Width: 1920, Height: 1080,
+		VideoCodec: "h264", AudioCodec: "aac", SampleRate: 48000, Channels: 2,
+		Title: "Clip Title", Artist: "Someone", HasCover: true,
+	}
+	if meta != want {
+		t.Fatalf("unexpected metadata:\n got %+v\nwant %+v", meta, want)
+	}
+	if !bytes.Equal(gotCover, cover) {
+		t.Fatalf("unexpected cover of %d bytes", len(gotCover))
+	}
+
+	// 竖拍视频的 tkhd 矩阵表示旋转 90 度，返回显示尺寸。
+	writeTestMP4(t, path, "qt  ", true, nil)
+	meta, gotCover, err = ReadMediaMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMediaMetadata rotated failed: %v", err)
+	}
+	if meta.Format != "mov" || meta.Width != 1080 || meta.Height != 1920 || meta.HasCover || gotCover != nil {
+		t.Fatalf("unexpected rotated metadata: %+v", meta)
+	}
+}
+
+func testID3Frame(id string, data []byte) []byte {
+	out := append([]byte(id), be32(uint32(len(data)))...)
+	out = append(out, 0, 0)
+	return append(out, data...)
+}
+
+func testID3Tag(frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	size := len(body)
+	return append([]byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}, body...)
+}
+
+// testMP3Frames 生成 MPEG-1 Layer III、128kbps、44.1kHz 的立体声帧，每帧 417 字节。
+func testMP3Frames(count int, xingFrames uint32) []byte {
+	var out []byte
+	for i := 0; i < count; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+		if i == 0 && xingFrames > 0 {
+			copy(frame[4+32:], "Xing")
+			binary.BigEndian.PutUint32(frame[4+32+4:], 1)
+			binary.BigEndian.PutUint32(frame[4+32+8:], xingFrames)
+		}
+		out = append(out, frame...)
+	}
+	return out
+}
+
+func utf16WithBOM(s string) []byte {
+	out := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return append(out, 0, 0)
+}
+
+func TestReadMediaMetadataMP3WithID3(t *testing.T) {
+	dir := t.TempDir()
+	cover := testCoverJPEG(t)
+	apic := func(picType byte, image []byte) []byte {
+		out := append([]byte{0}, "image/jpeg\x00"...)
+		out = append(out, picType)
+		out = append(out, "desc\x00"...)
+		return append(out, image...)
+	}
+	tag := testID3Tag(
+		testID3Frame("TIT2", append([]byte{0}, "Song"...)),
+		testID3Frame("TPE1", append([]byte{1}, utf16WithBOM("歌手")...)),
+		testID3Frame("APIC", apic(0, []byte("other picture"))),
+		testID3Frame("APIC", apic(3, cover)),
+	)
+	path := filepath.Join(dir, "song.mp3")
+	if err := os.WriteFile(path, append(tag, testMP3Frames(100, 0)...), 0o644); err != nil {
+		t.Fatalf("write mp3 failed: %v", err)
+	}
+
+	meta, gotCover, err := ReadMediaMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMediaMetadata failed: %v", err)
+	}
+	// CBR 按音频字节数与首帧码率估算：41700 字节 * 8 / 128kbps。
+	want := models.MediaMetadata{
+		Kind: "audio", Format: "mp3", AudioCodec: "mp3", DurationMs: 2606,
+		SampleRate: 44100, Channels: 2, Title: "Song", Artist: "歌手", HasCover: true,
+	}
+	if meta != want {
+		t.Fatalf("unexpected metadata:\n got %+v\nwant %+v", meta, want)
+	}
+	if !bytes.Equal(gotCover, cover) {
+		t.Fatal("expected front cover (type 3) to win over the first picture")
+	}
+
+	// 没有 ID3v2 的 VBR 文件：时长取自 Xing 头，标签取自文件尾的 ID3v1。
+	v1 := make([]byte, 128)
+	copy(v1, "TAG")
+	copy(v1[3:], "Old Title")
+	copy(v1[63:], "Old Album")
+	if err := os.WriteFile(path, append(testMP3Frames(3, 1000), v1...), 0o644); err != nil {
+		t.Fatalf("write mp3 failed: %v", err)
+	}
+	meta, gotCover, err = ReadMediaMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMediaMetadata VBR failed: %v", err)
+	}
+	if meta.DurationMs != 1000*1152*1000/44100 || meta.Title != "Old Title" || meta.Album != "Old Album" || gotCover != nil {
+		t.Fatalf("unexpected VBR metadata: %+v", meta)
+	}
+}
+
+func testFLACBlock(blockType byte, last bool, data []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	return append([]byte{blockType, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func TestReadMediaMetadataFLAC(t *testing.T) {
+	cover := testCoverJPEG(t)
+	streamInfo := make([]byte, 34)
+	binary.BigEndian.PutUint64(streamInfo[10:], 44100<<44|1<<41|15<<36|441000)
+
+	comments := binary.LittleEndian.AppendUint32(nil, 6)
+	comments = append(comments, "vendor"...)
+	comments = binary.LittleEndian.AppendUint32(comments, 2)
+	for _, c := range []string{"TITLE=Flac Song", "album=Record"} {
+		comments = binary.LittleEndian.AppendUint32(comments, uint32(len(c)))
+		comments = append(comments, c...)
+	}
+
+	picture := be32(3)
+	picture = append(picture, be32(10)...)
+	picture = append(picture, "image/jpeg"...)
+	picture = append(picture, be32(0)...)
+	picture = append(picture, make([]byte, 16)...)
+	picture = append(picture, be32(uint32(len(cover)))...)
+	picture = append(picture, cover...)
+
+	data := bytes.Join([][]byte{
+		[]byte("fLaC"),
+		testFLACBlock(0, false, streamInfo),
+		testFLACBlock(1, false, make([]byte, 64)), // PADDING 块直接跳过
+		testFLACBlock(4, false, comments),
+		testFLACBlock(6, true, picture),
+	}, nil)
+	path := filepath.Join(t.TempDir(), "track.flac")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write flac failed: %v", err)
+	}
+
+	meta, gotCover, err := ReadMediaMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMediaMetadata failed: %v", err)
+	}
+	want := models.MediaMetadata{
+		Kind: "audio", Format: "flac", AudioCodec: "flac", DurationMs: 10000,
+		SampleRate: 44100, Channels: 2, Title: "Flac Song", Album: "Record", HasCover: true,
+	}
+	if meta != want {
+		t.Fatalf("unexpected metadata:\n got %+v\nwant %+v", meta, want)
+	}
+	if !bytes.Equal(gotCover, cover) {
+		t.Fatalf("unexpected cover of %d bytes", len(gotCover))
+	}
+}
+
+func TestReadMediaMetadataRejectsUnknownData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.mp4")
+	if err := os.WriteFile(path, []byte("definitely not a media file"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	if _, _, err := ReadMediaMetadata(path); err != errUnsupportedMedia {
+		t.Fatalf("expected errUnsupportedMedia, got %v", err)
+	}
+}
+
+func TestCleanupServiceBackfillMediaMetadataWritesCovers(t *testing.T) {
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{
+		Storage:   config.StorageConfig{BasePath: baseDir},
+		Thumbnail: config.ThumbnailConfig{Width: 32, Height: 32, Quality: 80},
+	}
+	writeTestMP4(t, filepath.Join(baseDir, "a.mp4"), "isom", false, testCoverJPEG(t))
+	writeTestMP4(t, filepath.Join(baseDir, "b.mp4"), "isom", false, testCoverJPEG(t))
+
+	repo := &fakeMediaMetadataRepo{unparsed: []models.FileObject{
+		{ID: 1, FilePath: "a.mp4"},
+		{ID: 2, FilePath: "b.mp4", ThumbnailPath: "thumbnails/existing.jpg"},
+		{ID: 3, FilePath: "missing.mp4"},
+	}}
+	svc := &cleanupService{media: repo}
+	svc.backfillMediaMetadata(context.Background())
+
+	if len(repo.created) != 3 || repo.created[0].FileObjectID != 1 || repo.created[0].Title != "Clip Title" {
+		t.Fatalf("expected every object to be recorded, got %+v", repo.created)
+	}
+	// 解析失败的文件写入空记录，避免下一轮重复读取。
+	if repo.created[2].FileObjectID != 3 || repo.created[2].Kind != "" {
+		t.Fatalf("unexpected placeholder metadata: %+v", repo.created[2])
+	}
+	// 已有缩略图的文件对象不覆盖原缩略图。
+	wantThumb := filepath.Join("thumbnails", "covers", "1.jpg")
+	if len(repo.thumbs) != 1 || repo.thumbs[1] != wantThumb {
+		t.Fatalf("unexpected thumbnail updates: %+v", repo.thumbs)
+	}
+	f, err := os.Open(filepath.Join(baseDir, wantThumb))
+	if err != nil {
+		t.Fatalf("open cover thumbnail failed: %v", err)
+	}
+	defer f.Close()
+	cfg, err := jpeg.DecodeConfig(f)
+	if err != nil || cfg.Width != 32 || cfg.Height > 32 {
+		t.Fatalf("unexpected cover thumbnail %+v: %v", cfg, err)
+	}
+}
+
+func TestGetFileDetailAttachesMediaMetadata(t *testing.T) {
+	files := &quickAccessFileRepo{fakeFileRepo: newFakeFileRepo(), files: map[uint]models.File{
+		1: {ID: 1, UserID: 7, FileObjectID: 42, FileObject: models.FileObject{ID: 42}},
+		2: {ID: 2, UserID: 7, FileObjectID: 43, FileObject: models.FileObject{ID: 43}},
+	}}
+	media := &fakeMediaMetadataRepo{stored: map[uint]models.MediaMetadata{
+		42: {FileObjectID: 42, Kind: "audio", DurationMs: 1500},
+		43: {FileObjectID: 43},
+	}}
+	svc := NewFileService(fakeTxManager{}, nil, newFakeFolderRepo(), files, nil, nil, nil, nil, nil, nil, &fakeImageMetadataRepo{}, media)
+	ctx := context.Background()
+
+	file, err := svc.GetFileDetail(ctx, 7, 1)
+	if err != nil {
+		t.Fatalf("GetFileDetail failed: %v", err)
+	}
+	if file.FileObject.Media == nil || file.FileObject.Media.DurationMs != 1500 || file.FileObject.Metadata != nil {
+		t.Fatalf("unexpected detail: %+v", file.FileObject)
+	}
+
+	// 解析失败留下的空记录不返回给前端。
+	file, err = svc.GetFileDetail(ctx, 7, 2)
+	if err != nil {
+		t.Fatalf("GetFileDetail failed: %v", err)
+	}
+	if file.FileObject.Media != nil {
+		t.Fatalf("expected placeholder metadata to be hidden, got %+v", file.FileObject.Media)
+	}
+
+	_, err = svc.GetFileDetail(ctx, 8, 1)
+	assertAppErrorCode(t, err, http.StatusNotFound)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const mimeOctetStream = "application/octet-stream"
+
+// activeContentTypes 为浏览器内联打开时可能执行脚本的类型，预览时必须按附件下载。
+var activeContentTypes = map[string]bool{
+	"text/html":                 true,
+	"application/xhtml+xml":     true,
+	"image/svg+xml":             true,
+	"text/xml":                  true,
+	"application/xml":           true,
+	"text/xsl":                  true,
+	"application/xslt+xml":      true,
+	"text/javascript":           true,
+	"application/javascript":    true,
+	"application/x-javascript":  true,
+	"application/ecmascript":    true,
+	"multipart/x-mixed-replace": true,
+}
+
+// sniffMimeType 按文件头的魔数识别 MIME，不信任客户端声明的类型。内容无法识别（二进制流）或只能识别为纯文本时，
+// 才参考扩展名细化，且扩展名不能把文件提升为可执行脚本的类型。
+func sniffMimeType(absPath string, name string) string {
+	byExt := getMimeType(filepath.Ext(name))
+	detected, err := mimetype.DetectFile(absPath)
+	if err != nil {
+		return mimeOctetStream
+	}
+	sniffed := baseMediaType(detected.String())
+	switch {
+	case sniffed == mimeOctetStream && !IsActiveContentType(byExt):
+		return byExt
+	case sniffed == "text/plain" && strings.HasPrefix(byExt, "text/") && !IsActiveContentType(byExt):
+		return byExt
+	}
+	return sniffed
+}
+
+// IsActiveContentType 判断内容类型是否可能在浏览器中执行脚本，包括 HTML、SVG、各类 XML 与 JavaScript。
+func IsActiveContentType(contentType string) bool {
+	mediaType := baseMediaType(contentType)
+	return activeContentTypes[mediaType] || strings.HasSuffix(mediaType, "+xml")
+}
+
+// baseMediaType 去掉参数并转为小写，如 "text/HTML; charset=utf-8" 得到 "text/html"；无法解析时为二进制流。
+func baseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return mimeOctetStream
+	}
+	return mediaType
+}
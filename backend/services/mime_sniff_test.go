@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcloud/config"
+	"mcloud/models"
+)
+
+func TestSniffMimeTypeTrustsContentOverName(t *testing.T) {
+	dir := t.TempDir()
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, patternTestImage(4, 4, false)); err != nil {
+		t.Fatalf("encode png failed: %v", err)
+	}
+	cases := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"photo.txt", pngData.Bytes(), "image/png"},
+		{"photo.jpg", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), "text/html"},
+		{"icon.png", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), "image/svg+xml"},
+		// 内容只能识别为纯文本时参考扩展名细化，但扩展名不能把文件提升为 HTML。
+		{"notes.md", []byte("# title\n"), "text/markdown"},
+		{"page.html", []byte("just text\n"), "text/plain"},
+		// 无法识别的二进制内容按扩展名归类。
+		{"song.mp3", []byte{0x00, 0x01, 0x02, 0x03}, "audio/mpeg"},
+		{"blob.unknown", []byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream"},
+	}
+	for i, tc := range cases {
+		path := filepath.Join(dir, tc.name+string(rune('a'+i)))
+		if err := os.WriteFile(path, tc.content, 0644); err != nil {
+			t.Fatalf("write file failed: %v", err)
+		}
+		if got := sniffMimeType(path, tc.name); got != tc.want {
+			t.Fatalf("sniffMimeType(%s) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if got := sniffMimeType(filepath.Join(dir, "missing"), "a.txt"); got != mimeOctetStream {
+		t.Fatalf("expected octet-stream for unreadable file, got %q", got)
+	}
+}
+
+func TestIsActiveContentType(t *testing.T) {
+	cases := map[string]bool{
+		"text/html":                true,
+		"Text/HTML; charset=utf-8": true,
+		"image/svg+xml":            true,
+		"application/rss+xml":      true,
+		"application/javascript":   true,
+		"image/png":                false,
+		"text/plain":               false,
+		"application/pdf":          false,
+		"":                         false,
+	}
+	for contentType, want := range cases {
+		if got := IsActiveContentType(contentType); got != want {
+			t.Fatalf("IsActiveContentType(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestFileServiceUploadFileIgnoresClientContentType(t *testing.T) {
+	config.AppConfig = &config.Config{
+		Storage: config.StorageConfig{
+			BasePath:          t.TempDir(),
+			MaxFileSize:       10 * 1024 * 1024,
+			AllowedExtensions: []string{"*"},
+		},
+	}
+	users := newTrackingUserRepo()
+	users.usersByID[1] = models.User{ID: 1, Username: "alice", StorageQuota: 1000}
+	users.usersByName["alice"] = users.usersByID[1]
+	fileObjects := newFakeFileObjectRepo()
+
+	file, header, fileMD5 := makeMultipartFile("page.txt", []byte("<html><body><script>alert(1)</script></body></html>"))
+	header.Header.Set("Content-Type", "image/png")
+	svc := NewFileService(fakeTxManager{}, users, newFakeFolderRepo(), newFakeFileRepo(), fileObjects, nil, nil, nil, nil, nil, nil, nil)
+	out, err := svc.UploadFile(context.Background(), 1, 0, file, header)
+	if err != nil {
+		t.Fatalf("UploadFile returned error: %v", err)
+	}
+	if out.FileObject.MimeType != "text/html" || fileObjects.objectsByMD5[fileMD5].MimeType != "text/html" {
+		t.Fatalf("expected sniffed text/html, got %q", out.FileObject.MimeType)
+	}
+}
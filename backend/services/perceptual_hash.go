@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+)
+
+// DifferenceHash 计算图片的 64 位 dHash：缩放为 9x8 灰度图后逐行比较相邻像素亮度。
+// 对缩放、重新压缩与轻微调色不敏感，两张图的哈希汉明距离越小越相似。
+func DifferenceHash(img image.Image) uint64 {
+	// Box 滤波在大倍率缩小时等价于区域平均，结果不随原图分辨率明显变化。
+	small := imaging.Resize(img, 9, 8, imaging.Box)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if luminance(small, x, y) > luminance(small, x+1, y) {
+				hash |= 1 << uint(y*8+x)
+			}
+		}
+	}
+	return hash
+}
+
+// luminance 按 BT.601 权重返回像素亮度，透明像素按其预乘后的颜色计算。
+func luminance(img *image.NRGBA, x, y int) uint32 {
+	i := img.PixOffset(x, y)
+	r, g, b, a := uint32(img.Pix[i]), uint32(img.Pix[i+1]), uint32(img.Pix[i+2]), uint32(img.Pix[i+3])
+	return (299*r + 587*g + 114*b) * a / 255
+}
+
+func formatPerceptualHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func parsePerceptualHash(s string) (uint64, bool) {
+	if len(s) != 16 {
+		return 0, false
+	}
+	hash, err := strconv.ParseUint(s, 16, 64)
+	return hash, err == nil
+}
+
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// bkTree 为按汉明距离组织的 BK 树，用于在大量哈希中查找给定距离内的近邻而不必两两比较。
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	hash     uint64
+	children map[int]*bkNode
+}
+
+func (t *bkTree) add(hash uint64) {
+	if t.root == nil {
+		t.root = &bkNode{hash: hash}
+		return
+	}
+	node := t.root
+	for {
+		d := hammingDistance(node.hash, hash)
+		if d == 0 {
+			return
+		}
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[d] = &bkNode{hash: hash}
+			return
+		}
+		node = child
+	}
+}
+
+// within 返回树中与 hash 距离不超过 maxDistance 的全部哈希（含自身）。
+func (t *bkTree) within(hash uint64, maxDistance int) []uint64 {
+	var out []uint64
+	stack := []*bkNode{}
+	if t.root != nil {
+		stack = append(stack, t.root)
+	}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := hammingDistance(node.hash, hash)
+		if d <= maxDistance {
+			out = append(out, node.hash)
+		}
+		// 三角不等式：只有边距离落在 [d-max, d+max] 内的子树可能包含近邻。
+		for edge, child := range node.children {
+			if edge >= d-maxDistance && edge <= d+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return out
+}
+
+// clusterHashes 将距离不超过 maxDistance 的哈希连通为簇（单链接），返回每个哈希所属簇的代表哈希。
+func clusterHashes(hashes []uint64, maxDistance int) map[uint64]uint64 {
+	parent := make(map[uint64]uint64, len(hashes))
+	var find func(h uint64) uint64
+	find = func(h uint64) uint64 {
+		for parent[h] != h {
+			parent[h] = parent[parent[h]]
+			h = parent[h]
+		}
+		return h
+	}
+
+	var tree bkTree
+	for _, h := range hashes {
+		if _, ok := parent[h]; ok {
+			continue
+		}
+		parent[h] = h
+		tree.add(h)
+	}
+	for h := range parent {
+		for _, near := range tree.within(h, maxDistance) {
+			a, b := find(h), find(near)
+			if a != b {
+				// 固定以较小值为根，结果与遍历顺序无关。
+				if a < b {
+					parent[b] = a
+				} else {
+					parent[a] = b
+				}
+			}
+		}
+	}
+
+	roots := make(map[uint64]uint64, len(parent))
+	for h := range parent {
+		roots[h] = find(h)
+	}
+	return roots
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// patternTestImage 生成带横向渐变与色块的图片，保证 dHash 有足够的亮度差异。
+func patternTestImage(width, height int, invert bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*255/width + (y*4/height)*60) % 256)
+			if invert {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHashToleratesResizeAndRecompression(t *testing.T) {
+	original := patternTestImage(640, 480, false)
+	hash := DifferenceHash(original)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, imaging.Resize(original, 200, 150, imaging.Lanczos), &jpeg.Options{Quality: 40}); err != nil {
+		t.Fatalf("encode jpeg failed: %v", err)
+	}
+	recompressed, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode jpeg failed: %v", err)
+	}
+	if d := hammingDistance(hash, DifferenceHash(recompressed)); d > defaultSimilarDistance {
+		t.Fatalf("expected resized copy to stay similar, distance %d", d)
+	}
+	if d := hammingDistance(hash, DifferenceHash(patternTestImage(640, 480, true))); d <= maxSimilarDistance {
+		t.Fatalf("expected inverted image to differ, distance %d", d)
+	}
+
+	formatted := formatPerceptualHash(hash)
+	if parsed, ok := parsePerceptualHash(formatted); !ok || parsed != hash || len(formatted) != 16 {
+		t.Fatalf("unexpected hash round trip: %q -> %x", formatted, parsed)
+	}
+	if _, ok := parsePerceptualHash(""); ok {
+		t.Fatalf("expected empty hash to be rejected")
+	}
+}
+
+func TestClusterHashesMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	var hashes []uint64
+	for i := 0; i < 60; i++ {
+		base := rng.Uint64()
+		hashes = append(hashes, base)
+		// 每个基准哈希附带几个翻转少量位的近似副本。
+		for j := 0; j < rng.Intn(3); j++ {
+			hashes = append(hashes, base^(1<<uint(rng.Intn(64)))^(1<<uint(rng.Intn(64))))
+		}
+	}
+
+	roots := clusterHashes(hashes, 4)
+	// 暴力两两比较得到的连通关系应与 BK 树结果一致。
+	for _, a := range hashes {
+		for _, b := range hashes {
+			if hammingDistance(a, b) <= 4 && roots[a] != roots[b] {
+				t.Fatalf("expected %x and %x to share a cluster", a, b)
+			}
+		}
+	}
+	var tree bkTree
+	for _, h := range hashes {
+		tree.add(h)
+	}
+	for _, h := range hashes[:10] {
+		want := map[uint64]bool{}
+		for _, other := range hashes {
+			if hammingDistance(h, other) <= 6 {
+				want[other] = true
+			}
+		}
+		got := tree.within(h, 6)
+		if len(got) != len(want) {
+			t.Fatalf("expected %d neighbours of %x, got %d", len(want), h, len(got))
+		}
+		for _, g := range got {
+			if !want[g] {
+				t.Fatalf("unexpected neighbour %x of %x", g, h)
+			}
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+	"mcloud/repositories"
+	"mcloud/utils"
+)
+
+// photoTimelineDateLayout 为时间线分组使用的日期格式，按服务器本地时区取日期。
+const photoTimelineDateLayout = "2006-01-02"
+
+// PhotoService 定义按拍摄信息组织图片的视图。
+type PhotoService interface {
+	// Timeline 按拍摄时间倒序列出用户的图片并按拍摄日期分组，游标分页。
+	Timeline(ctx context.Context, userID uint, in PhotoTimelineQuery) (PhotoTimelineOutput, error)
+	// SimilarImages 按感知哈希把视觉上相似的图片聚成簇，供用户挑选多余副本批量删除。
+	SimilarImages(ctx context.Context, userID uint, in SimilarImagesQuery) (SimilarImagesOutput, error)
+}
+
+// PhotoTimelineQuery 为照片时间线查询参数。
+type PhotoTimelineQuery struct {
+	// Cursor 为上一页返回的 next_cursor，空串表示第一页。
+	Cursor string
+	Limit  int
+}
+
+// PhotoItem 为时间线中的一张图片，TakenAt 为拍摄时间，没有 EXIF 拍摄时间时取上传时间。
+type PhotoItem struct {
+	models.File
+	TakenAt time.Time `json:"taken_at"`
+}
+
+// PhotoTimelineGroup 为同一拍摄日期的图片；分组可能跨页，客户端按 date 合并相邻页的同日分组。
+type PhotoTimelineGroup struct {
+	Date  string      `json:"date"`
+	Items []PhotoItem `json:"items"`
+}
+
+// PhotoTimelineOutput 为照片时间线返回体。
+type PhotoTimelineOutput struct {
+	Groups     []PhotoTimelineGroup `json:"groups"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	HasMore    bool                 `json:"has_more"`
+}
+
+// photoCursor 记录上一页末张图片的 (拍摄时间, 文件 ID)。
+type photoCursor struct {
+	TakenAt string `json:"t"`
+	ID      uint   `json:"i"`
+}
+
+type photoService struct {
+	metadata    repositories.ImageMetadataRepository
+	folders     repositories.FolderRepository
+	tagAttacher tagAttacher
+}
+
+// NewPhotoService 创建照片视图服务实例。
+func NewPhotoService(metadata repositories.ImageMetadataRepository, folders repositories.FolderRepository, tags repositories.TagRepository) PhotoService {
+	return &photoService{metadata: metadata, folders: folders, tagAttacher: tagAttacher{tags: tags}}
+}
+
+func encodePhotoCursor(takenAt time.Time, id uint) string {
+	raw, _ := json.Marshal(photoCursor{TakenAt: takenAt.Format(time.RFC3339Nano), ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePhotoCursor(s string) (time.Time, uint, error) {
+	var c photoCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return time.Time{}, 0, err
+	}
+	takenAt, err := time.Parse(time.RFC3339Nano, c.TakenAt)
+	return takenAt, c.ID, err
+}
+
+// photoTakenAt 返回图片在时间线中的时间：优先 EXIF 拍摄时间，否则为上传时间。
+func photoTakenAt(file models.File) time.Time {
+	if meta := file.FileObject.Metadata; meta != nil && meta.TakenAt != nil {
+		return *meta.TakenAt
+	}
+	return file.CreatedAt
+}
+
+func (s *photoService) Timeline(ctx context.Context, userID uint, in PhotoTimelineQuery) (PhotoTimelineOutput, error) {
+	limit := in.Limit
+	if limit < 1 || limit > config.AppConfig.Pagination.MaxPageSize {
+		limit = config.AppConfig.Pagination.DefaultPageSize
+	}
+
+	query := repositories.PhotoTimelineInput{UserID: userID, Limit: limit + 1}
+	if in.Cursor != "" {
+		takenAt, id, err := decodePhotoCursor(in.Cursor)
+		if err != nil {
+			return PhotoTimelineOutput{}, newAppError(http.StatusBadRequest, "无效的分页游标", nil)
+		}
+		query.AfterTakenAt, query.AfterID = &takenAt, id
+	}
+
+	files, err := s.metadata.ListTimeline(ctx, nil, query)
+	if err != nil {
+		return PhotoTimelineOutput{}, newAppError(http.StatusInternalServerError, "获取照片时间线失败", err)
+	}
+	out := PhotoTimelineOutput{Groups: make([]PhotoTimelineGroup, 0)}
+	if len(files) > limit {
+		files = files[:limit]
+		out.HasMore = true
+	}
+	s.tagAttacher.files(ctx, userID, files)
+
+	for _, file := range files {
+		takenAt := photoTakenAt(file)
+		date := takenAt.In(time.Local).Format(photoTimelineDateLayout)
+		if n := len(out.Groups); n == 0 || out.Groups[n-1].Date != date {
+			out.Groups = append(out.Groups, PhotoTimelineGroup{Date: date})
+		}
+		group := &out.Groups[len(out.Groups)-1]
+		group.Items = append(group.Items, PhotoItem{File: file, TakenAt: takenAt})
+	}
+	if out.HasMore {
+		last := files[len(files)-1]
+		out.NextCursor = encodePhotoCursor(photoTakenAt(last), last.ID)
+	}
+	return out, nil
+}
+
+const (
+	// defaultSimilarDistance 为默认的相似阈值：64 位 dHash 中不同的位数不超过该值视为相似。
+	defaultSimilarDistance = 6
+	// maxSimilarDistance 为允许的最大阈值，再大误报会明显增多。
+	maxSimilarDistance = 12
+	// maxSimilarImageScan 为单次查找时最多参与比较的图片数量。
+	maxSimilarImageScan = 20000
+)
+
+// SimilarImagesQuery 为相似图片查询参数，MaxDistance 为 nil 时使用默认阈值。
+type SimilarImagesQuery struct {
+	MaxDistance *int
+	Page        int
+	PageSize    int
+}
+
+// SimilarImageItem 为簇中的一张图片，Distance 为与簇内首张图片的哈希距离。
+type SimilarImageItem struct {
+	models.File
+	Path     string `json:"path"`
+	Distance int    `json:"distance"`
+}
+
+// SimilarImageCluster 为一组相似图片，首张为建议保留的副本（分辨率最高、文件最大）。
+// ReclaimableSize 为删除其余副本可释放的空间估算，引用同一文件对象的副本只计一次。
+type SimilarImageCluster struct {
+	Items           []SimilarImageItem `json:"items"`
+	TotalSize       int64              `json:"total_size"`
+	ReclaimableSize int64              `json:"reclaimable_size"`
+}
+
+// SimilarImagesOutput 为相似图片查询结果；Truncated 表示图片数量超过扫描上限，只在最早上传的部分中查找。
+type SimilarImagesOutput struct {
+	Clusters   []SimilarImageCluster `json:"clusters"`
+	Pagination utils.PaginationData  `json:"pagination"`
+	Truncated  bool                  `json:"truncated"`
+}
+
+func (s *photoService) SimilarImages(ctx context.Context, userID uint, in SimilarImagesQuery) (SimilarImagesOutput, error) {
+	maxDistance := defaultSimilarDistance
+	if in.MaxDistance != nil {
+		maxDistance = *in.MaxDistance
+	}
+	if maxDistance < 0 || maxDistance > maxSimilarDistance {
+		return SimilarImagesOutput{}, newAppError(http.StatusBadRequest, "相似阈值需在 0 到 12 之间", nil)
+	}
+	page, pageSize := in.Page, in.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > config.AppConfig.Pagination.MaxPageSize {
+		pageSize = config.AppConfig.Pagination.DefaultPageSize
+	}
+
+	files, err := s.metadata.ListHashedImages(ctx, nil, userID, maxSimilarImageScan+1)
+	if err != nil {
+		return SimilarImagesOutput{}, newAppError(http.StatusInternalServerError, "查询图片失败", err)
+	}
+	out := SimilarImagesOutput{Clusters: make([]SimilarImageCluster, 0)}
+	if len(files) > maxSimilarImageScan {
+		files = files[:maxSimilarImageScan]
+		out.Truncated = true
+	}
+
+	clusters := clusterSimilarFiles(files, maxDistance)
+	total := int64(len(clusters))
+	out.Pagination = newPaginationData(page, pageSize, total)
+	start := min((page-1)*pageSize, len(clusters))
+	clusters = clusters[start:min(start+pageSize, len(clusters))]
+
+	s.attachImagePaths(ctx, userID, clusters)
+	out.Clusters = append(out.Clusters, clusters...)
+	return out, nil
+}
+
+// clusterSimilarFiles 按哈希距离聚簇，只保留包含两张及以上图片的簇；
+// 簇内按分辨率、文件大小降序排列，簇之间按可释放空间降序排列。
+func clusterSimilarFiles(files []models.File, maxDistance int) []SimilarImageCluster {
+	hashOf := make(map[uint]uint64, len(files))
+	hashes := make([]uint64, 0, len(files))
+	for _, file := range files {
+		if file.FileObject.PerceptualHash == nil {
+			continue
+		}
+		hash, ok := parsePerceptualHash(*file.FileObject.PerceptualHash)
+		if !ok {
+			continue
+		}
+		hashOf[file.ID] = hash
+		hashes = append(hashes, hash)
+	}
+	roots := clusterHashes(hashes, maxDistance)
+
+	groups := make(map[uint64][]models.File)
+	for _, file := range files {
+		hash, ok := hashOf[file.ID]
+		if !ok {
+			continue
+		}
+		root := roots[hash]
+		groups[root] = append(groups[root], file)
+	}
+
+	clusters := make([]SimilarImageCluster, 0)
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			a, b := group[i].FileObject, group[j].FileObject
+			if a.Width*a.Height != b.Width*b.Height {
+				return a.Width*a.Height > b.Width*b.Height
+			}
+			if a.FileSize != b.FileSize {
+				return a.FileSize > b.FileSize
+			}
+			return group[i].ID < group[j].ID
+		})
+
+		cluster := SimilarImageCluster{Items: make([]SimilarImageItem, 0, len(group))}
+		counted := make(map[uint]bool, len(group))
+		for _, file := range group {
+			cluster.Items = append(cluster.Items, SimilarImageItem{File: file, Distance: hammingDistance(hashOf[group[0].ID], hashOf[file.ID])})
+			if counted[file.FileObjectID] {
+				continue
+			}
+			counted[file.FileObjectID] = true
+			cluster.TotalSize += file.FileObject.FileSize
+			if file.FileObjectID != group[0].FileObjectID {
+				cluster.ReclaimableSize += file.FileObject.FileSize
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].ReclaimableSize != clusters[j].ReclaimableSize {
+			return clusters[i].ReclaimableSize > clusters[j].ReclaimableSize
+		}
+		return clusters[i].Items[0].ID < clusters[j].Items[0].ID
+	})
+	return clusters
+}
+
+// attachImagePaths 为簇中的图片补全可读路径；目录查询失败时路径留空，不影响结果。
+func (s *photoService) attachImagePaths(ctx context.Context, userID uint, clusters []SimilarImageCluster) {
+	var folderIDs []uint
+	for _, cluster := range clusters {
+		for _, item := range cluster.Items {
+			folderIDs = append(folderIDs, item.FolderID)
+		}
+	}
+	if len(folderIDs) == 0 {
+		return
+	}
+	folders, err := s.folders.GetByIDsAndUser(ctx, nil, userID, uniqueSortedIDs(folderIDs))
+	warnOnError(ctx, "查询图片所在目录", err)
+	paths := make(map[uint]string, len(folders))
+	for _, folder := range folders {
+		paths[folder.ID] = folder.Path
+	}
+	for i := range clusters {
+		for j := range clusters[i].Items {
+			item := &clusters[i].Items[j]
+			if folderPath, ok := paths[item.FolderID]; ok {
+				item.Path = buildChildFolderPath(folderPath, item.OriginalName)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mcloud/config"
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// fakeImageMetadataRepo 返回预置的时间线与图片列表，并记录查询参数、写入的元数据与哈希。
+type fakeImageMetadataRepo struct {
+	timeline []models.File
+	unparsed []models.FileObject
+	unhashed []models.FileObject
+	hashed   []models.File
+	lastIn   repositories.PhotoTimelineInput
+	created  []models.ImageMetadata
+	hashes   map[uint]string
+}
+
+func (r *fakeImageMetadataRepo) Create(_ context.Context, _ *gorm.DB, meta *models.ImageMetadata) error {
+	r.created = append(r.created, *meta)
+	return nil
+}
+
+func (r *fakeImageMetadataRepo) GetByFileObjectID(context.Context, *gorm.DB, uint) (models.ImageMetadata, error) {
+	return models.ImageMetadata{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeImageMetadataRepo) ListUnparsedObjects(context.Context, *gorm.DB, int) ([]models.FileObject, error) {
+	return r.unparsed, nil
+}
+
+func (r *fakeImageMetadataRepo) ListTimeline(_ context.Context, _ *gorm.DB, in repositories.PhotoTimelineInput) ([]models.File, error) {
+	r.lastIn = in
+	if len(r.timeline) > in.Limit {
+		return r.timeline[:in.Limit], nil
+	}
+	return r.timeline, nil
+}
+
+func (r *fakeImageMetadataRepo) ListUnhashedObjects(context.Context, *gorm.DB, int) ([]models.FileObject, error) {
+	return r.unhashed, nil
+}
+
+func (r *fakeImageMetadataRepo) UpdatePerceptualHash(_ context.Context, _ *gorm.DB, fileObjectID uint, hash string) error {
+	if r.hashes == nil {
+		r.hashes = make(map[uint]string)
+	}
+	r.hashes[fileObjectID] = hash
+	return nil
+}
+
+func (r *fakeImageMetadataRepo) ListHashedImages(_ context.Context, _ *gorm.DB, _ uint, limit int) ([]models.File, error) {
+	if len(r.hashed) > limit {
+		return r.hashed[:limit], nil
+	}
+	return r.hashed, nil
+}
+
+func TestPhotoServiceTimelineGroupsByTakenDate(t *testing.T) {
+	config.AppConfig = &config.Config{Pagination: config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100}}
+	day := func(d, h int) time.Time { return time.Date(2024, 5, d, h, 0, 0, 0, time.Local) }
+	taken := day(3, 9)
+	repo := &fakeImageMetadataRepo{timeline: []models.File{
+		{ID: 4, CreatedAt: day(9, 12), FileObject: models.FileObject{Metadata: &models.ImageMetadata{TakenAt: &taken}}},
+		// 没有拍摄时间的图片按上传时间归组。
+		{ID: 3, CreatedAt: day(3, 8), FileObject: models.FileObject{Metadata: &models.ImageMetadata{}}},
+		{ID: 2, CreatedAt: day(1, 20)},
+	}}
+	svc := NewPhotoService(repo, nil, nil)
+
+	out, err := svc.Timeline(context.Background(), 7, PhotoTimelineQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.lastIn.UserID != 7 || repo.lastIn.Limit != 3 || repo.lastIn.AfterTakenAt != nil {
+		t.Fatalf("unexpected query: %+v", repo.lastIn)
+	}
+	if len(out.Groups) != 1 || out.Groups[0].Date != "2024-05-03" || len(out.Groups[0].Items) != 2 || !out.HasMore {
+		t.Fatalf("unexpected timeline: %+v", out)
+	}
+	if !out.Groups[0].Items[0].TakenAt.Equal(taken) || !out.Groups[0].Items[1].TakenAt.Equal(day(3, 8)) {
+		t.Fatalf("unexpected taken times: %+v", out.Groups[0].Items)
+	}
+
+	// 游标记录末张图片的 (拍摄时间, ID)。
+	repo.timeline = repo.timeline[2:]
+	out, err = svc.Timeline(context.Background(), 7, PhotoTimelineQuery{Cursor: out.NextCursor, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.lastIn.AfterTakenAt == nil || !repo.lastIn.AfterTakenAt.Equal(day(3, 8)) || repo.lastIn.AfterID != 3 {
+		t.Fatalf("unexpected cursor query: %+v", repo.lastIn)
+	}
+	if len(out.Groups) != 1 || out.Groups[0].Date != "2024-05-01" || out.HasMore || out.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v", out)
+	}
+
+	_, err = svc.Timeline(context.Background(), 7, PhotoTimelineQuery{Cursor: "not-a-cursor"})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
+
+func TestCleanupServiceBackfillImageMetadataRecordsEveryObject(t *testing.T) {
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{Storage: config.StorageConfig{BasePath: baseDir}}
+	order := binary.LittleEndian
+	writeTestJPEGWithExif(t, filepath.Join(baseDir, "a.jpg"), 10, 10, buildTestTIFF(order, []testExifField{asciiExifField(exifTagModel, "Pixel 8")}, nil, nil))
+	if err := os.WriteFile(filepath.Join(baseDir, "b.jpg"), []byte("not an image"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	repo := &fakeImageMetadataRepo{unparsed: []models.FileObject{
+		{ID: 1, FilePath: "a.jpg"},
+		{ID: 2, FilePath: "b.jpg"},
+		{ID: 3, FilePath: "missing.jpg"},
+	}}
+	svc := &cleanupService{metadata: repo}
+	svc.backfillImageMetadata(context.Background())
+
+	// 无法解析的图片也写入空记录，避免下一轮重复读取。
+	if len(repo.created) != 3 {
+		t.Fatalf("expected every object to be recorded, got %+v", repo.created)
+	}
+	if repo.created[0].FileObjectID != 1 || repo.created[0].CameraModel != "Pixel 8" {
+		t.Fatalf("unexpected parsed metadata: %+v", repo.created[0])
+	}
+	if repo.created[2].FileObjectID != 3 || repo.created[2].CameraModel != "" {
+		t.Fatalf("unexpected placeholder metadata: %+v", repo.created[2])
+	}
+}
+
+// hashedImage 构造一张带感知哈希的图片文件。
+func hashedImage(id uint, objectID uint, folderID uint, hash string, width, height int, size int64) models.File {
+	return models.File{
+		ID: id, UserID: 7, FolderID: folderID, OriginalName: fmt.Sprintf("img%d.jpg", id), FileObjectID: objectID,
+		FileObject: models.FileObject{ID: objectID, IsImage: true, Width: width, Height: height, FileSize: size, PerceptualHash: &hash},
+	}
+}
+
+func TestPhotoServiceSimilarImagesClustersByHashDistance(t *testing.T) {
+	config.AppConfig = &config.Config{Pagination: config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100}}
+	repo := &fakeImageMetadataRepo{hashed: []models.File{
+		hashedImage(1, 11, 2, "ff00ff00ff00ff00", 800, 600, 100),
+		// 同一文件对象的两份副本只计一次空间。
+		hashedImage(2, 12, 3, "ff00ff00ff00ff01", 4000, 3000, 900),
+		hashedImage(3, 12, 2, "ff00ff00ff00ff01", 4000, 3000, 900),
+		// 与 1 相差 3 位、与 2 相差 4 位，单链接下并入同一簇。
+		hashedImage(4, 14, 2, "ff00ff00ff00f800", 1600, 1200, 300),
+		hashedImage(5, 15, 2, "00ff00ff00ff00ff", 800, 600, 50),
+		hashedImage(6, 16, 3, "0123456789abcdef", 10, 10, 10),
+		hashedImage(7, 17, 3, "0123456789abcdee", 10, 10, 20),
+	}}
+	folders := &quickAccessFolderRepo{fakeFolderRepo: newFakeFolderRepo(), folders: map[uint]models.Folder{
+		2: {ID: 2, UserID: 7, Path: "/"},
+		3: {ID: 3, UserID: 7, Path: "/Camera"},
+	}}
+	svc := NewPhotoService(repo, folders, nil)
+
+	out, err := svc.SimilarImages(context.Background(), 7, SimilarImagesQuery{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Clusters) != 2 || out.Pagination.Total != 2 || out.Truncated {
+		t.Fatalf("unexpected clusters: %+v", out)
+	}
+	first := out.Clusters[0]
+	ids := []uint{}
+	for _, item := range first.Items {
+		ids = append(ids, item.ID)
+	}
+	// 分辨率最高的副本排在最前，作为建议保留的一张。
+	if fmt.Sprint(ids) != "[2 3 4 1]" || first.Items[0].Path != "/Camera/img2.jpg" || first.Items[2].Path != "/img4.jpg" {
+		t.Fatalf("unexpected cluster order: %v %+v", ids, first.Items)
+	}
+	if first.TotalSize != 1300 || first.ReclaimableSize != 400 || first.Items[3].Distance != 1 {
+		t.Fatalf("unexpected cluster sizes: %+v", first)
+	}
+	if second := out.Clusters[1]; len(second.Items) != 2 || second.Items[0].ID != 7 || second.ReclaimableSize != 10 {
+		t.Fatalf("unexpected second cluster: %+v", second)
+	}
+
+	strict := 0
+	out, err = svc.SimilarImages(context.Background(), 7, SimilarImagesQuery{MaxDistance: &strict})
+	if err != nil || len(out.Clusters) != 1 || len(out.Clusters[0].Items) != 2 || out.Clusters[0].ReclaimableSize != 0 {
+		t.Fatalf("expected only identical hashes with distance 0, got %+v (%v)", out, err)
+	}
+
+	invalid := 13
+	_, err = svc.SimilarImages(context.Background(), 7, SimilarImagesQuery{MaxDistance: &invalid})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
+
+func TestCleanupServiceBackfillPerceptualHashes(t *testing.T) {
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{Storage: config.StorageConfig{BasePath: baseDir}}
+	writeTestJPEGWithExif(t, filepath.Join(baseDir, "a.jpg"), 64, 48, nil)
+
+	repo := &fakeImageMetadataRepo{unhashed: []models.FileObject{
+		{ID: 1, FilePath: "a.jpg"},
+		{ID: 2, FilePath: "missing.jpg"},
+	}}
+	svc := &cleanupService{metadata: repo}
+	svc.backfillPerceptualHashes(context.Background())
+
+	// 无法解码的图片写入空串，下一轮不再重复尝试。
+	if len(repo.hashes) != 2 || len(repo.hashes[1]) != 16 || repo.hashes[2] != "" {
+		t.Fatalf("unexpected hashes: %+v", repo.hashes)
+	}
+}
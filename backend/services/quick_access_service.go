@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"mcloud/models"
+	"mcloud/repositories"
+	"mcloud/utils"
+
+	"gorm.io/gorm"
+)
+
+// 最近视图单次返回的条目数。
+const (
+	defaultRecentLimit = 50
+	maxRecentLimit     = 200
+)
+
+// QuickAccessService 定义收藏、最近上传与最近访问等快捷视图。
+type QuickAccessService interface {
+	// AddFavorite 收藏文件或目录，重复收藏保持幂等。
+	AddFavorite(ctx context.Context, userID uint, targetType string, targetID uint) (FavoriteItem, error)
+	// RemoveFavorite 取消收藏，目标未收藏时同样返回成功。
+	RemoveFavorite(ctx context.Context, userID uint, targetType string, targetID uint) error
+	// ListFavorites 分页列出收藏，目标位于回收站或已删除时不返回。
+	ListFavorites(ctx context.Context, userID uint, in FavoriteQuery) (FavoriteListOutput, error)
+	// ListRecentUploads 按上传时间倒序列出最近上传的文件。
+	ListRecentUploads(ctx context.Context, userID uint, in RecentQuery) (RecentFilesOutput, error)
+	// ListRecentAccessed 按访问时间倒序列出最近下载或预览过的文件。
+	ListRecentAccessed(ctx context.Context, userID uint, in RecentQuery) (RecentFilesOutput, error)
+	// RecordAccess 记录一次文件访问；失败只记录日志，不影响下载与预览。
+	RecordAccess(ctx context.Context, userID uint, fileID uint)
+}
+
+// FavoriteQuery 为收藏列表的筛选与分页参数，Type 为空表示文件与目录都返回。
+type FavoriteQuery struct {
+	Type     string
+	Keyword  string
+	Page     int
+	PageSize int
+}
+
+// FavoriteItem 为一条收藏，Folder 与 File 按 TargetType 二选一。
+type FavoriteItem struct {
+	ID         uint           `json:"id"`
+	TargetType string         `json:"target_type"`
+	TargetID   uint           `json:"target_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Folder     *models.Folder `json:"folder,omitempty"`
+	File       *models.File   `json:"file,omitempty"`
+}
+
+// FavoriteListOutput 为收藏列表返回体。
+type FavoriteListOutput struct {
+	Items      []FavoriteItem       `json:"items"`
+	Pagination utils.PaginationData `json:"pagination"`
+}
+
+// RecentQuery 为最近视图的筛选参数。
+type RecentQuery struct {
+	Keyword string
+	// Category 为 image | video | audio | document | archive，与空间占用统计的分类一致。
+	Category string
+	// Days 只返回最近 N 天内的记录，0 表示不限。
+	Days  int
+	Limit int
+	Tags  TagQuery
+}
+
+// RecentFileItem 为最近视图中的一个文件，访问视图额外带上访问时间与次数。
+type RecentFileItem struct {
+	File           models.File `json:"file"`
+	LastAccessedAt *time.Time  `json:"last_accessed_at,omitempty"`
+	AccessCount    int64       `json:"access_count,omitempty"`
+}
+
+// RecentFilesOutput 为最近视图返回体。
+type RecentFilesOutput struct {
+	Items []RecentFileItem `json:"items"`
+}
+
+// quickAccessService 为 QuickAccessService 的默认实现。
+type quickAccessService struct {
+	folders   repositories.FolderRepository
+	files     repositories.FileRepository
+	favorites repositories.FavoriteRepository
+	accesses  repositories.FileAccessRepository
+	now       func() time.Time
+}
+
+// NewQuickAccessService 创建快捷视图服务。
+func NewQuickAccessService(
+	folders repositories.FolderRepository,
+	files repositories.FileRepository,
+	favorites repositories.FavoriteRepository,
+	accesses repositories.FileAccessRepository,
+) QuickAccessService {
+	return &quickAccessService{
+		folders:   folders,
+		files:     files,
+		favorites: favorites,
+		accesses:  accesses,
+		now:       time.Now,
+	}
+}
+
+// AddFavorite 校验目标属于当前用户且处于正常状态后写入收藏。
+func (s *quickAccessService) AddFavorite(ctx context.Context, userID uint, targetType string, targetID uint) (FavoriteItem, error) {
+	item := FavoriteItem{TargetType: targetType, TargetID: targetID}
+	switch targetType {
+	case models.FavoriteTargetFile:
+		file, err := s.files.GetByIDAndUser(ctx, nil, targetID, userID, true)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return FavoriteItem{}, newAppError(http.StatusNotFound, "文件不存在", nil)
+			}
+			return FavoriteItem{}, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+		}
+		item.File = &file
+	case models.FavoriteTargetFolder:
+		folder, err := s.folders.GetByIDAndUser(ctx, nil, targetID, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return FavoriteItem{}, newAppError(http.StatusNotFound, "文件夹不存在", nil)
+			}
+			return FavoriteItem{}, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+		}
+		item.Folder = &folder
+	default:
+		return FavoriteItem{}, newAppError(http.StatusBadRequest, "无效的收藏类型", nil)
+	}
+
+	favorite := models.Favorite{UserID: userID, TargetType: targetType, TargetID: targetID}
+	if err := s.favorites.Add(ctx, nil, &favorite); err != nil {
+		return FavoriteItem{}, newAppError(http.StatusInternalServerError, "收藏失败", err)
+	}
+	item.ID = favorite.ID
+	item.CreatedAt = favorite.CreatedAt
+	return item, nil
+}
+
+// RemoveFavorite 删除收藏记录。
+func (s *quickAccessService) RemoveFavorite(ctx context.Context, userID uint, targetType string, targetID uint) error {
+	if targetType != models.FavoriteTargetFile && targetType != models.FavoriteTargetFolder {
+		return newAppError(http.StatusBadRequest, "无效的收藏类型", nil)
+	}
+	if err := s.favorites.Remove(ctx, nil, userID, targetType, targetID); err != nil {
+		return newAppError(http.StatusInternalServerError, "取消收藏失败", err)
+	}
+	return nil
+}
+
+// ListFavorites 分页查询收藏并批量补齐目标详情。
+func (s *quickAccessService) ListFavorites(ctx context.Context, userID uint, in FavoriteQuery) (FavoriteListOutput, error) {
+	if in.Type != "" && in.Type != models.FavoriteTargetFile && in.Type != models.FavoriteTargetFolder {
+		return FavoriteListOutput{}, newAppError(http.StatusBadRequest, "无效的收藏类型", nil)
+	}
+	page, pageSize := in.Page, in.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := repositories.FavoriteListInput{
+		UserID:     userID,
+		TargetType: in.Type,
+		Keyword:    in.Keyword,
+		Offset:     (page - 1) * pageSize,
+		Limit:      pageSize,
+	}
+	total, err := s.favorites.CountActive(ctx, nil, query)
+	if err != nil {
+		return FavoriteListOutput{}, newAppError(http.StatusInternalServerError, "查询收藏总数失败", err)
+	}
+	favorites, err := s.favorites.ListActive(ctx, nil, query)
+	if err != nil {
+		return FavoriteListOutput{}, newAppError(http.StatusInternalServerError, "查询收藏列表失败", err)
+	}
+
+	var fileIDs, folderIDs []uint
+	for _, favorite := range favorites {
+		if favorite.TargetType == models.FavoriteTargetFile {
+			fileIDs = append(fileIDs, favorite.TargetID)
+		} else {
+			folderIDs = append(folderIDs, favorite.TargetID)
+		}
+	}
+	filesByID := map[uint]models.File{}
+	if len(fileIDs) > 0 {
+		files, err := s.files.GetByIDsAndUser(ctx, nil, userID, fileIDs, true)
+		if err != nil {
+			return FavoriteListOutput{}, newAppError(http.StatusInternalServerError, "查询收藏文件失败", err)
+		}
+		for _, file := range files {
+			filesByID[file.ID] = file
+		}
+	}
+	foldersByID := map[uint]models.Folder{}
+	folders, err := s.folders.GetByIDsAndUser(ctx, nil, userID, folderIDs)
+	if err != nil {
+		return FavoriteListOutput{}, newAppError(http.StatusInternalServerError, "查询收藏文件夹失败", err)
+	}
+	for _, folder := range folders {
+		foldersByID[folder.ID] = folder
+	}
+
+	items := make([]FavoriteItem, 0, len(favorites))
+	for _, favorite := range favorites {
+		item := FavoriteItem{ID: favorite.ID, TargetType: favorite.TargetType, TargetID: favorite.TargetID, CreatedAt: favorite.CreatedAt}
+		// 两次查询之间目标可能刚被删除，此时跳过该条。
+		if file, ok := filesByID[favorite.TargetID]; ok && favorite.TargetType == models.FavoriteTargetFile {
+			item.File = &file
+		} else if folder, ok := foldersByID[favorite.TargetID]; ok && favorite.TargetType == models.FavoriteTargetFolder {
+			item.Folder = &folder
+		} else {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	return FavoriteListOutput{
+		Items: items,
+		Pagination: utils.PaginationData{
+			Page:       page,
+			PageSize:   pageSize,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    page < totalPages,
+			HasPrev:    page > 1,
+		},
+	}, nil
+}
+
+// ListRecentUploads 查询最近上传的正常文件。
+func (s *quickAccessService) ListRecentUploads(ctx context.Context, userID uint, in RecentQuery) (RecentFilesOutput, error) {
+	query, err := s.recentFilesInput(userID, in)
+	if err != nil {
+		return RecentFilesOutput{}, err
+	}
+	files, err := s.files.ListRecentUploads(ctx, nil, query)
+	if err != nil {
+		return RecentFilesOutput{}, newAppError(http.StatusInternalServerError, "查询最近上传失败", err)
+	}
+	items := make([]RecentFileItem, 0, len(files))
+	for _, file := range files {
+		items = append(items, RecentFileItem{File: file})
+	}
+	return RecentFilesOutput{Items: items}, nil
+}
+
+// ListRecentAccessed 查询最近访问的正常文件。
+func (s *quickAccessService) ListRecentAccessed(ctx context.Context, userID uint, in RecentQuery) (RecentFilesOutput, error) {
+	query, err := s.recentFilesInput(userID, in)
+	if err != nil {
+		return RecentFilesOutput{}, err
+	}
+	accesses, err := s.accesses.ListRecent(ctx, nil, query)
+	if err != nil {
+		return RecentFilesOutput{}, newAppError(http.StatusInternalServerError, "查询最近访问失败", err)
+	}
+	items := make([]RecentFileItem, 0, len(accesses))
+	for _, access := range accesses {
+		accessedAt := access.LastAccessedAt
+		items = append(items, RecentFileItem{File: access.File, LastAccessedAt: &accessedAt, AccessCount: access.AccessCount})
+	}
+	return RecentFilesOutput{Items: items}, nil
+}
+
+// RecordAccess 刷新用户对文件的最近访问时间。
+func (s *quickAccessService) RecordAccess(ctx context.Context, userID uint, fileID uint) {
+	warnOnError(ctx, "记录文件访问", s.accesses.Touch(ctx, nil, userID, fileID, s.now()))
+}
+
+// recentFilesInput 校验最近视图参数并转换为仓储查询条件。
+func (s *quickAccessService) recentFilesInput(userID uint, in RecentQuery) (repositories.RecentFilesInput, error) {
+	limit := in.Limit
+	if limit < 1 || limit > maxRecentLimit {
+		limit = defaultRecentLimit
+	}
+	if in.Days < 0 {
+		return repositories.RecentFilesInput{}, newAppError(http.StatusBadRequest, "无效的天数", nil)
+	}
+
+	filter, err := buildFileQuickFilter(in.Keyword, in.Category, in.Tags)
+	if err != nil {
+		return repositories.RecentFilesInput{}, err
+	}
+	query := repositories.RecentFilesInput{UserID: userID, Filter: filter, Limit: limit}
+	if in.Days > 0 {
+		since := s.now().AddDate(0, 0, -in.Days)
+		query.Since = &since
+	}
+	return query, nil
+}
+
+// buildFileQuickFilter 将关键字、分类与标签参数转换为文件筛选条件。
+func buildFileQuickFilter(keyword string, category string, tags TagQuery) (repositories.FileQuickFilter, error) {
+	tagFilter, err := tags.toFilter()
+	if err != nil {
+		return repositories.FileQuickFilter{}, err
+	}
+	filter := repositories.FileQuickFilter{Keyword: keyword, Tags: tagFilter}
+	if category != "" {
+		rule, ok := findMimeCategoryRule(category)
+		if !ok {
+			return repositories.FileQuickFilter{}, newAppError(http.StatusBadRequest, "无效的文件分类", nil)
+		}
+		filter.MimePrefixes = rule.prefixes
+		filter.MimeTypes = rule.types
+	}
+	return filter, nil
+}
+
+// findMimeCategoryRule 查找可用于筛选的分类；other 是兜底分类，无法表示为正向条件。
+func findMimeCategoryRule(category string) (mimeCategoryRule, bool) {
+	for _, rule := range mimeCategoryRules {
+		if rule.category == category {
+			return rule, true
+		}
+	}
+	return mimeCategoryRule{}, false
+}
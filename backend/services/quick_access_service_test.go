@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// quickAccessFileRepo 按 ID 返回预置文件，并记录最近上传查询参数。
+type quickAccessFileRepo struct {
+	*fakeFileRepo
+	files      map[uint]models.File
+	lastRecent repositories.RecentFilesInput
+}
+
+func (r *quickAccessFileRepo) GetByIDAndUser(_ context.Context, _ *gorm.DB, fileID uint, userID uint, _ bool) (models.File, error) {
+	file, ok := r.files[fileID]
+	if !ok || file.UserID != userID {
+		return models.File{}, gorm.ErrRecordNotFound
+	}
+	return file, nil
+}
+
+func (r *quickAccessFileRepo) GetByIDsAndUser(_ context.Context, _ *gorm.DB, userID uint, fileIDs []uint, _ bool) ([]models.File, error) {
+	out := make([]models.File, 0)
+	for _, id := range fileIDs {
+		if file, ok := r.files[id]; ok && file.UserID == userID {
+			out = append(out, file)
+		}
+	}
+	return out, nil
+}
+
+func (r *quickAccessFileRepo) ListRecentUploads(_ context.Context, _ *gorm.DB, in repositories.RecentFilesInput) ([]models.File, error) {
+	r.lastRecent = in
+	return []models.File{r.files[1]}, nil
+}
+
+// quickAccessFolderRepo 按 ID 返回预置目录。
+type quickAccessFolderRepo struct {
+	*fakeFolderRepo
+	folders map[uint]models.Folder
+}
+
+func (r *quickAccessFolderRepo) GetByIDAndUser(_ context.Context, _ *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
+	folder, ok := r.folders[folderID]
+	if !ok || folder.UserID != userID {
+		return models.Folder{}, gorm.ErrRecordNotFound
+	}
+	return folder, nil
+}
+
+func (r *quickAccessFolderRepo) GetByIDsAndUser(_ context.Context, _ *gorm.DB, userID uint, folderIDs []uint) ([]models.Folder, error) {
+	out := make([]models.Folder, 0)
+	for _, id := range folderIDs {
+		if folder, ok := r.folders[id]; ok && folder.UserID == userID {
+			out = append(out, folder)
+		}
+	}
+	return out, nil
+}
+
+type fakeFavoriteRepo struct {
+	added        []models.Favorite
+	removed      []models.Favorite
+	list         []models.Favorite
+	lastList     repositories.FavoriteListInput
+	orphans      int64
+	orphanCalled bool
+}
+
+func (r *fakeFavoriteRepo) Add(_ context.Context, _ *gorm.DB, favorite *models.Favorite) error {
+	favorite.ID = uint(len(r.added) + 1)
+	r.added = append(r.added, *favorite)
+	return nil
+}
+
+func (r *fakeFavoriteRepo) Remove(_ context.Context, _ *gorm.DB, userID uint, targetType string, targetID uint) error {
+	r.removed = append(r.removed, models.Favorite{UserID: userID, TargetType: targetType, TargetID: targetID})
+	return nil
+}
+
+func (r *fakeFavoriteRepo) CountActive(_ context.Context, _ *gorm.DB, _ repositories.FavoriteListInput) (int64, error) {
+	return int64(len(r.list)), nil
+}
+
+func (r *fakeFavoriteRepo) ListActive(_ context.Context, _ *gorm.DB, in repositories.FavoriteListInput) ([]models.Favorite, error) {
+	r.lastList = in
+	return r.list, nil
+}
+
+func (r *fakeFavoriteRepo) DeleteOrphans(context.Context, *gorm.DB) (int64, error) {
+	r.orphanCalled = true
+	return r.orphans, nil
+}
+
+type fakeFileAccessRepo struct {
+	touchErr     error
+	touched      []time.Time
+	recent       []models.FileAccess
+	orphanCalled bool
+}
+
+func (r *fakeFileAccessRepo) Touch(_ context.Context, _ *gorm.DB, _ uint, _ uint, at time.Time) error {
+	r.touched = append(r.touched, at)
+	return r.touchErr
+}
+
+func (r *fakeFileAccessRepo) ListRecent(context.Context, *gorm.DB, repositories.RecentFilesInput) ([]models.FileAccess, error) {
+	return r.recent, nil
+}
+
+func (r *fakeFileAccessRepo) DeleteOrphans(context.Context, *gorm.DB) (int64, error) {
+	r.orphanCalled = true
+	return 0, nil
+}
+
+func newQuickAccessFixture() (*quickAccessService, *quickAccessFileRepo, *fakeFavoriteRepo, *fakeFileAccessRepo) {
+	files := &quickAccessFileRepo{
+		fakeFileRepo: newFakeFileRepo(),
+		files: map[uint]models.File{
+			1: {ID: 1, UserID: 7, OriginalName: "a.png"},
+			2: {ID: 2, UserID: 8, OriginalName: "other-user.png"},
+		},
+	}
+	folders := &quickAccessFolderRepo{
+		fakeFolderRepo: newFakeFolderRepo(),
+		folders:        map[uint]models.Folder{5: {ID: 5, UserID: 7, Name: "docs"}},
+	}
+	favorites := &fakeFavoriteRepo{}
+	accesses := &fakeFileAccessRepo{}
+	svc := NewQuickAccessService(folders, files, favorites, accesses).(*quickAccessService)
+	svc.now = func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) }
+	return svc, files, favorites, accesses
+}
+
+func assertAppErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != code {
+		t.Fatalf("expected AppError %d, got %v", code, err)
+	}
+}
+
+func TestQuickAccessServiceAddFavoriteValidatesTarget(t *testing.T) {
+	svc, _, favorites, _ := newQuickAccessFixture()
+	ctx := context.Background()
+
+	_, err := svc.AddFavorite(ctx, 7, "album", 1)
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	// 他人文件视为不存在。
+	_, err = svc.AddFavorite(ctx, 7, models.FavoriteTargetFile, 2)
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	if len(favorites.added) != 0 {
+		t.Fatalf("expected no favorite to be written, got %+v", favorites.added)
+	}
+
+	item, err := svc.AddFavorite(ctx, 7, models.FavoriteTargetFolder, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.Folder == nil || item.Folder.Name != "docs" || len(favorites.added) != 1 {
+		t.Fatalf("unexpected favorite: %+v added=%+v", item, favorites.added)
+	}
+}
+
+func TestQuickAccessServiceListFavoritesHydratesAndSkipsVanishedTargets(t *testing.T) {
+	svc, _, favorites, _ := newQuickAccessFixture()
+	favorites.list = []models.Favorite{
+		{ID: 1, UserID: 7, TargetType: models.FavoriteTargetFolder, TargetID: 5},
+		{ID: 2, UserID: 7, TargetType: models.FavoriteTargetFile, TargetID: 1},
+		// 查询间隙被删除的目标不返回。
+		{ID: 3, UserID: 7, TargetType: models.FavoriteTargetFile, TargetID: 99},
+	}
+
+	out, err := svc.ListFavorites(context.Background(), 7, FavoriteQuery{Type: "", Keyword: "a", Page: 2, PageSize: 500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Items) != 2 || out.Items[0].Folder == nil || out.Items[1].File == nil || out.Items[1].File.OriginalName != "a.png" {
+		t.Fatalf("unexpected items: %+v", out.Items)
+	}
+	if favorites.lastList.Keyword != "a" || favorites.lastList.Limit != 20 || favorites.lastList.Offset != 20 {
+		t.Fatalf("unexpected list input: %+v", favorites.lastList)
+	}
+
+	_, err = svc.ListFavorites(context.Background(), 7, FavoriteQuery{Type: "album"})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
+
+func TestQuickAccessServiceRecentUploadsTranslatesFilters(t *testing.T) {
+	svc, files, _, _ := newQuickAccessFixture()
+
+	_, err := svc.ListRecentUploads(context.Background(), 7, RecentQuery{Category: "document", Days: 7, Limit: 1000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := files.lastRecent
+	if got.Limit != defaultRecentLimit || got.Since == nil || !got.Since.Equal(time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected recent input: %+v", got)
+	}
+	if len(got.Filter.MimePrefixes) == 0 || got.Filter.MimePrefixes[0] != "text/" || len(got.Filter.MimeTypes) == 0 {
+		t.Fatalf("expected document category to expand into mime filters, got %+v", got.Filter)
+	}
+
+	// other 为兜底分类，无法作为筛选条件。
+	_, err = svc.ListRecentUploads(context.Background(), 7, RecentQuery{Category: "other"})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	_, err = svc.ListRecentUploads(context.Background(), 7, RecentQuery{Days: -1})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
+
+func TestQuickAccessServiceRecentAccessedCarriesAccessInfo(t *testing.T) {
+	svc, _, _, accesses := newQuickAccessFixture()
+	at := time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC)
+	accesses.recent = []models.FileAccess{{FileID: 1, AccessCount: 3, LastAccessedAt: at, File: models.File{ID: 1, OriginalName: "a.png"}}}
+
+	out, err := svc.ListRecentAccessed(context.Background(), 7, RecentQuery{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Items) != 1 || out.Items[0].AccessCount != 3 || !out.Items[0].LastAccessedAt.Equal(at) || out.Items[0].File.ID != 1 {
+		t.Fatalf("unexpected recent accessed: %+v", out.Items)
+	}
+}
+
+func TestQuickAccessServiceRecordAccessIgnoresErrors(t *testing.T) {
+	svc, _, _, accesses := newQuickAccessFixture()
+	accesses.touchErr = errors.New("db down")
+
+	svc.RecordAccess(context.Background(), 7, 1)
+
+	if len(accesses.touched) != 1 || !accesses.touched[0].Equal(svc.now()) {
+		t.Fatalf("expected access to be recorded with service clock, got %+v", accesses.touched)
+	}
+}
+
+func TestCleanupServiceCleanOrphanQuickAccess(t *testing.T) {
+	favorites := &fakeFavoriteRepo{orphans: 2}
+	accesses := &fakeFileAccessRepo{}
+	svc := &cleanupService{favorites: favorites, accesses: accesses}
+
+	svc.cleanOrphanQuickAccess(context.Background())
+
+	if !favorites.orphanCalled || !accesses.orphanCalled {
+		t.Fatalf("expected both orphan cleanups to run")
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"mcloud/config"
+	"mcloud/logger"
 	"mcloud/models"
 	"mcloud/repositories"
 	"mcloud/utils"
@@ -17,22 +21,168 @@ import (
 	"gorm.io/gorm"
 )
 
+// RecycleBinListItem 为回收站列表条目，附带距过期的剩余时间。
+type RecycleBinListItem struct {
+	models.RecycleBinItem
+	// ExpiresInSeconds 为距自动彻删的剩余秒数，已过期为 0。
+	ExpiresInSeconds int64 `json:"expires_in_seconds"`
+	// ExpiresInDays 为剩余天数（向上取整），便于列表直接展示。
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
 // RecycleBinListOutput 为回收站分页查询返回体。
 type RecycleBinListOutput struct {
-	Items      []models.RecycleBinItem `json:"items"`
-	Pagination utils.PaginationData    `json:"pagination"`
+	Items      []RecycleBinListItem `json:"items"`
+	Pagination utils.PaginationData `json:"pagination"`
+}
+
+// RecycleBinFolderContents 为已删除目录中某一层的内容。
+type RecycleBinFolderContents struct {
+	ItemID uint `json:"item_id"`
+	// Path 为相对已删除目录的路径，"/" 表示其顶层。
+	Path string `json:"path"`
+	// OriginalPath 为该层在删除前的完整路径。
+	OriginalPath string          `json:"original_path"`
+	Folders      []models.Folder `json:"folders"`
+	Files        []models.File   `json:"files"`
+}
+
+// RecycleBinSettings 为用户回收站设置及管理员限定的取值范围。
+type RecycleBinSettings struct {
+	// RetentionDays 为当前生效的保留天数。
+	RetentionDays int `json:"retention_days"`
+	// CustomRetention 表示用户设置了自定义保留天数。
+	CustomRetention      bool `json:"custom_retention"`
+	DefaultRetentionDays int  `json:"default_retention_days"`
+	MinRetentionDays     int  `json:"min_retention_days"`
+	MaxRetentionDays     int  `json:"max_retention_days"`
+	// MaxItems 为每用户条目上限，超出时最早删除的条目会被自动彻删；0 表示不限制。
+	MaxItems  int   `json:"max_items"`
+	ItemCount int64 `json:"item_count"`
+}
+
+// RecycleBinSettingsInput 为更新设置的输入；RetentionDays 为 null 时恢复为系统默认值。
+type RecycleBinSettingsInput struct {
+	RetentionDays *int `json:"retention_days"`
 }
 
 // RecycleBinService 定义回收站查询、恢复与彻删能力。
 type RecycleBinService interface {
 	// ListRecycleBin 分页查询用户回收站条目。
 	ListRecycleBin(ctx context.Context, userID uint, page int, pageSize int) (RecycleBinListOutput, error)
-	// RestoreItem 恢复单个条目（文件或文件夹）。
-	RestoreItem(ctx context.Context, userID uint, itemID uint) error
+	// RestoreItem 按目标目录与冲突策略恢复单个条目（文件或文件夹），DryRun 时只返回预计落点。
+	RestoreItem(ctx context.Context, userID uint, itemID uint, opts RestoreOptions) (RestoreResult, error)
 	// PermanentDelete 彻底删除单个条目并回收占用空间。
 	PermanentDelete(ctx context.Context, userID uint, itemID uint) error
 	// EmptyRecycleBin 清空回收站全部条目。
 	EmptyRecycleBin(ctx context.Context, userID uint) error
+	// BatchRestore 按 ID 或筛选条件批量恢复，逐条返回结果。
+	BatchRestore(ctx context.Context, userID uint, in RecycleBinBatchRestoreInput) (RecycleBinBatchOutput, error)
+	// BatchPermanentDelete 按 ID 或筛选条件批量彻删，逐条返回结果。
+	BatchPermanentDelete(ctx context.Context, userID uint, in RecycleBinBatchInput) (RecycleBinBatchOutput, error)
+	// ListFolderContents 浏览已删除目录中 path（相对该目录，"/" 为顶层）下的子目录与文件。
+	ListFolderContents(ctx context.Context, userID uint, itemID uint, path string) (RecycleBinFolderContents, error)
+	// RestoreFolderFile 从已删除目录中单独恢复一个文件，目录本身仍留在回收站。
+	RestoreFolderFile(ctx context.Context, userID uint, itemID uint, fileID uint, opts RestoreOptions) (RestoreResult, error)
+	// GetFolderFileDownloadInfo 返回已删除目录中某个文件的下载信息。
+	GetFolderFileDownloadInfo(ctx context.Context, userID uint, itemID uint, fileID uint) (FileAccessOutput, error)
+	// GetSettings 查询用户回收站设置。
+	GetSettings(ctx context.Context, userID uint) (RecycleBinSettings, error)
+	// UpdateSettings 更新用户保留天数，并按新值重算已有条目的过期时间。
+	UpdateSettings(ctx context.Context, userID uint, in RecycleBinSettingsInput) (RecycleBinSettings, error)
+}
+
+// maxRecycleBatchItems 为单次批量操作处理的条目上限，超出部分需再次调用。
+const maxRecycleBatchItems = 500
+
+// RecycleBinBatchInput 为批量操作的选择条件：指定 IDs 或至少一个筛选字段，二者同时给出时取交集。
+type RecycleBinBatchInput struct {
+	IDs []uint `json:"ids"`
+	// Type 可选 file | folder。
+	Type          string     `json:"type"`
+	DeletedBefore *time.Time `json:"deleted_before"`
+	// PathPrefix 匹配原始完整路径位于该路径下的条目，如 /docs；原记录已不存在、无法回填路径的早期条目不会被选中。
+	PathPrefix string `json:"path_prefix"`
+}
+
+// RecycleBinBatchRestoreInput 为批量恢复输入，恢复选项对选中的每个条目生效。
+type RecycleBinBatchRestoreInput struct {
+	RecycleBinBatchInput
+	RestoreOptions
+}
+
+// RecycleBinBatchItemResult 为单个条目的处理结果。
+type RecycleBinBatchItemResult struct {
+	ID           uint   `json:"id"`
+	OriginalType string `json:"original_type,omitempty"`
+	OriginalName string `json:"original_name,omitempty"`
+	Success      bool   `json:"success"`
+	Code         int    `json:"code,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Restore 为恢复操作的落点信息，彻删时为空。
+	Restore *RestoreResult `json:"restore,omitempty"`
+}
+
+// 恢复时的同名冲突策略。
+const (
+	// RestoreConflictRename 自动改名为 name(restored).ext、name(restored 2).ext 等。
+	RestoreConflictRename = "rename"
+	// RestoreConflictOverwrite 将已存在的同名条目移入回收站后再恢复，被替换的版本仍可找回；回收站关闭时不可用。
+	RestoreConflictOverwrite = "overwrite"
+	// RestoreConflictSkip 不恢复，条目保留在回收站中。
+	RestoreConflictSkip = "skip"
+	// RestoreConflictFail 直接返回 409 冲突。
+	RestoreConflictFail = "fail"
+)
+
+// 恢复结果中的动作取值。
+const (
+	RestoreActionRestored    = "restored"
+	RestoreActionRenamed     = "renamed"
+	RestoreActionOverwritten = "overwritten"
+	RestoreActionSkipped     = "skipped"
+	RestoreActionFailed      = "failed"
+)
+
+// maxRestoreRenameAttempts 为自动改名时尝试的候选名称上限。
+const maxRestoreRenameAttempts = 100
+
+// RestoreOptions 为恢复参数。
+type RestoreOptions struct {
+	// TargetFolderID 为空时恢复到原位置（原目录失效时回退根目录），为 0 表示根目录。
+	TargetFolderID *uint `json:"target_folder_id"`
+	// Conflict 为同名冲突策略：rename（默认）| overwrite | skip | fail。
+	Conflict string `json:"conflict"`
+	// DryRun 为 true 时只计算落点，不做任何修改。
+	DryRun bool `json:"dry_run"`
+}
+
+// RestoreResult 描述条目恢复后（或预演时将要）所在的位置。
+type RestoreResult struct {
+	ItemID       uint   `json:"item_id"`
+	OriginalID   uint   `json:"original_id"`
+	OriginalType string `json:"original_type"`
+	// Action 为 restored | renamed | overwritten | skipped | failed。
+	Action   string `json:"action"`
+	FolderID uint   `json:"folder_id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	// Conflict 表示目标位置存在同名条目。
+	Conflict bool `json:"conflict"`
+	// Relocated 表示原目录已不可用，条目被放到了根目录。
+	Relocated bool `json:"relocated"`
+	// ReplacedID 为覆盖策略下被移入回收站的同名文件或目录 ID。
+	ReplacedID uint `json:"replaced_id,omitempty"`
+	DryRun     bool `json:"dry_run"`
+}
+
+// RecycleBinBatchOutput 为批量操作汇总结果；HasMore 表示仍有符合筛选条件的条目未处理。
+type RecycleBinBatchOutput struct {
+	Total     int                         `json:"total"`
+	Succeeded int                         `json:"succeeded"`
+	Failed    int                         `json:"failed"`
+	HasMore   bool                        `json:"has_more"`
+	Results   []RecycleBinBatchItemResult `json:"results"`
 }
 
 // recycleBinService 为 RecycleBinService 的默认实现。
@@ -44,6 +194,8 @@ type recycleBinService struct {
 	fileObjects repositories.FileObjectRepository
 	recycle     repositories.RecycleBinRepository
 	resolver    folderResolver
+	recycler    recycler
+	folderStats *FolderStatsCache
 }
 
 // NewRecycleBinService 创建回收站服务实例。
@@ -54,6 +206,7 @@ func NewRecycleBinService(
 	files repositories.FileRepository,
 	fileObjects repositories.FileObjectRepository,
 	recycle repositories.RecycleBinRepository,
+	folderStats *FolderStatsCache,
 ) RecycleBinService {
 	return &recycleBinService{
 		txManager:   txManager,
@@ -63,6 +216,8 @@ func NewRecycleBinService(
 		fileObjects: fileObjects,
 		recycle:     recycle,
 		resolver:    folderResolver{folders: folders},
+		recycler:    newRecycler(txManager, users, folders, files, fileObjects, recycle),
+		folderStats: folderStats,
 	}
 }
 
@@ -96,8 +251,14 @@ func (s *recycleBinService) ListRecycleBin(ctx context.Context, userID uint, pag
 		totalPages = 1
 	}
 
+	now := time.Now()
+	listItems := make([]RecycleBinListItem, 0, len(items))
+	for _, item := range items {
+		listItems = append(listItems, newRecycleBinListItem(item, now))
+	}
+
 	return RecycleBinListOutput{
-		Items: items,
+		Items: listItems,
 		Pagination: utils.PaginationData{
 			Page:       page,
 			PageSize:   pageSize,
@@ -109,37 +270,318 @@ func (s *recycleBinService) ListRecycleBin(ctx context.Context, userID uint, pag
 	}, nil
 }
 
+// newRecycleBinListItem 计算条目距过期的剩余时间。
+func newRecycleBinListItem(item models.RecycleBinItem, now time.Time) RecycleBinListItem {
+	remaining := item.ExpiresAt.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	const day = 24 * time.Hour
+	return RecycleBinListItem{
+		RecycleBinItem:   item,
+		ExpiresInSeconds: int64(remaining / time.Second),
+		ExpiresInDays:    int((remaining + day - 1) / day),
+	}
+}
+
+// GetSettings 查询用户回收站设置与当前条目数。
+func (s *recycleBinService) GetSettings(ctx context.Context, userID uint) (RecycleBinSettings, error) {
+	user, err := s.users.GetByID(ctx, nil, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return RecycleBinSettings{}, newAppError(http.StatusNotFound, "用户不存在", nil)
+		}
+		return RecycleBinSettings{}, newAppError(http.StatusInternalServerError, "查询用户失败", err)
+	}
+	count, err := s.recycle.CountByUser(ctx, nil, userID)
+	if err != nil {
+		return RecycleBinSettings{}, newAppError(http.StatusInternalServerError, "查询回收站总数失败", err)
+	}
+
+	cfg := config.AppConfig.RecycleBin
+	return RecycleBinSettings{
+		RetentionDays:        effectiveRetentionDays(user.RecycleRetentionDays),
+		CustomRetention:      user.RecycleRetentionDays != nil,
+		DefaultRetentionDays: cfg.RetentionDays,
+		MinRetentionDays:     cfg.MinRetentionDays,
+		MaxRetentionDays:     cfg.MaxRetentionDays,
+		MaxItems:             cfg.MaxItemsPerUser,
+		ItemCount:            count,
+	}, nil
+}
+
+// UpdateSettings 校验保留天数在管理员限定范围内后保存，并在同一事务内重算已有条目的过期时间。
+func (s *recycleBinService) UpdateSettings(ctx context.Context, userID uint, in RecycleBinSettingsInput) (RecycleBinSettings, error) {
+	cfg := config.AppConfig.RecycleBin
+	if in.RetentionDays != nil && (*in.RetentionDays < cfg.MinRetentionDays || *in.RetentionDays > cfg.MaxRetentionDays) {
+		return RecycleBinSettings{}, newAppError(http.StatusBadRequest,
+			fmt.Sprintf("保留天数需在 %d 到 %d 天之间", cfg.MinRetentionDays, cfg.MaxRetentionDays), nil)
+	}
+
+	days := effectiveRetentionDays(in.RetentionDays)
+	err := s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.users.UpdateRecycleRetentionDays(ctx, tx, userID, in.RetentionDays); err != nil {
+			return err
+		}
+		// 缩短保留期后已超期的条目由定时清理任务处理。
+		return s.recycle.UpdateExpiresAtByUser(ctx, tx, userID, days)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return RecycleBinSettings{}, newAppError(http.StatusNotFound, "用户不存在", nil)
+		}
+		return RecycleBinSettings{}, newAppError(http.StatusInternalServerError, "更新回收站设置失败", err)
+	}
+	return s.GetSettings(ctx, userID)
+}
+
 // RestoreItem 恢复单个回收站条目。
-func (s *recycleBinService) RestoreItem(ctx context.Context, userID uint, itemID uint) error {
+func (s *recycleBinService) RestoreItem(ctx context.Context, userID uint, itemID uint, opts RestoreOptions) (RestoreResult, error) {
+	opts, err := normalizeRestoreOptions(opts)
+	if err != nil {
+		return RestoreResult{}, err
+	}
 	item, err := s.recycle.GetByIDAndUser(ctx, nil, itemID, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return newAppError(http.StatusNotFound, "回收站项目不存在", nil)
+			return RestoreResult{}, newAppError(http.StatusNotFound, "回收站项目不存在", nil)
 		}
-		return newAppError(http.StatusInternalServerError, "查询回收站项目失败", err)
+		return RestoreResult{}, newAppError(http.StatusInternalServerError, "查询回收站项目失败", err)
 	}
+	return s.restoreLoadedItem(ctx, userID, &item, opts)
+}
+
+// normalizeRestoreOptions 校验冲突策略，未指定时默认自动改名。回收站关闭时被覆盖的条目无处找回，不允许覆盖。
+func normalizeRestoreOptions(opts RestoreOptions) (RestoreOptions, error) {
+	opts.Conflict = strings.ToLower(strings.TrimSpace(opts.Conflict))
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = RestoreConflictRename
+	case RestoreConflictOverwrite:
+		if !config.AppConfig.RecycleBin.Enabled {
+			return opts, newAppError(http.StatusBadRequest, "回收站已关闭，无法覆盖同名条目，请选择其他冲突处理策略", nil)
+		}
+	case RestoreConflictRename, RestoreConflictSkip, RestoreConflictFail:
+	default:
+		return opts, newAppError(http.StatusBadRequest, "无效的冲突处理策略", nil)
+	}
+	return opts, nil
+}
+
+// restoreLoadedItem 恢复已加载的条目，成功后删除其回收站记录。
+func (s *recycleBinService) restoreLoadedItem(ctx context.Context, userID uint, item *models.RecycleBinItem, opts RestoreOptions) (RestoreResult, error) {
+	return s.executeRestore(ctx, userID, opts,
+		func(*gorm.DB) (*models.RecycleBinItem, error) { return item, nil },
+		func(tx *gorm.DB, item *models.RecycleBinItem) error { return s.recycle.DeleteByID(ctx, tx, item.ID) },
+	)
+}
+
+// executeRestore 在独立事务中规划并执行恢复；预演与跳过时不做任何修改。
+// prepare 在事务内给出待恢复条目，finish 在恢复成功后清理对应的回收站记录。
+func (s *recycleBinService) executeRestore(
+	ctx context.Context,
+	userID uint,
+	opts RestoreOptions,
+	prepare func(tx *gorm.DB) (*models.RecycleBinItem, error),
+	finish func(tx *gorm.DB, item *models.RecycleBinItem) error,
+) (RestoreResult, error) {
+	var plan restorePlan
+	err := s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		item, err := prepare(tx)
+		if err != nil {
+			return err
+		}
+		if plan, err = s.planRestore(ctx, tx, userID, item, opts); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return nil
+		}
+		switch plan.result.Action {
+		case RestoreActionSkipped:
+			return nil
+		case RestoreActionFailed:
+			return newAppErrorWithData(http.StatusConflict, "目标位置已存在同名项目", plan.result, nil)
+		}
 
-	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
 		// 文件与目录恢复路径不同，但都必须与回收站删除在同一事务内。
 		if item.OriginalType == "file" {
-			if err := s.restoreFileItem(ctx, tx, userID, &item); err != nil {
-				return err
-			}
+			err = s.restoreFileItem(ctx, tx, userID, &plan)
 		} else {
-			if err := s.restoreFolderItem(ctx, tx, userID, &item); err != nil {
-				return err
-			}
+			err = s.restoreFolderItem(ctx, tx, userID, &plan)
 		}
-		return s.recycle.DeleteByID(ctx, tx, item.ID)
+		if err != nil {
+			return err
+		}
+		return finish(tx, item)
 	})
 	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			return plan.result, appErr
+		}
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return newAppError(http.StatusNotFound, "待恢复对象不存在", nil)
+			return plan.result, newAppError(http.StatusNotFound, "待恢复对象不存在", nil)
 		}
-		return newAppError(http.StatusInternalServerError, "恢复失败", err)
+		return plan.result, newAppError(http.StatusInternalServerError, "恢复失败", err)
 	}
 
-	return nil
+	if opts.DryRun || plan.result.Action == RestoreActionSkipped {
+		return plan.result, nil
+	}
+	s.folderStats.Invalidate(userID)
+	// 覆盖恢复会把同名条目移入回收站，同样需要检查条目上限。
+	if plan.result.Action == RestoreActionOverwritten {
+		s.recycler.evictOverflow(ctx, userID)
+	}
+	return plan.result, nil
+}
+
+// ListFolderContents 列出已删除目录子树中指定层级的子目录与文件，按名称排序。
+func (s *recycleBinService) ListFolderContents(ctx context.Context, userID uint, itemID uint, relPath string) (RecycleBinFolderContents, error) {
+	item, subtree, err := s.loadDeletedFolder(ctx, nil, userID, itemID)
+	if err != nil {
+		return RecycleBinFolderContents{}, err
+	}
+
+	relPath = path.Clean("/" + strings.TrimSpace(relPath))
+	targetPath := subtree[0].Path
+	if relPath != "/" {
+		targetPath = strings.TrimRight(targetPath, "/") + relPath
+	}
+	var target *models.Folder
+	for i := range subtree {
+		if subtree[i].Path == targetPath {
+			target = &subtree[i]
+			break
+		}
+	}
+	if target == nil {
+		return RecycleBinFolderContents{}, newAppError(http.StatusNotFound, "目录不存在", nil)
+	}
+
+	out := RecycleBinFolderContents{ItemID: item.ID, Path: relPath, OriginalPath: target.Path, Folders: []models.Folder{}, Files: []models.File{}}
+	for _, f := range subtree {
+		if f.ParentID != nil && *f.ParentID == target.ID {
+			out.Folders = append(out.Folders, f)
+		}
+	}
+	files, err := s.files.ListByFolderIDs(ctx, nil, userID, []uint{target.ID}, true, true)
+	if err != nil {
+		return RecycleBinFolderContents{}, newAppError(http.StatusInternalServerError, "查询文件列表失败", err)
+	}
+	for _, f := range files {
+		if f.DeletedAt.Valid {
+			out.Files = append(out.Files, f)
+		}
+	}
+	sort.Slice(out.Folders, func(i, j int) bool { return out.Folders[i].Name < out.Folders[j].Name })
+	sort.Slice(out.Files, func(i, j int) bool { return out.Files[i].OriginalName < out.Files[j].OriginalName })
+	return out, nil
+}
+
+// RestoreFolderFile 单独恢复已删除目录中的文件；该文件若另有回收站记录一并清理。
+// 未指定目标目录时原目录仍处于删除状态，文件会回退到根目录。
+func (s *recycleBinService) RestoreFolderFile(ctx context.Context, userID uint, itemID uint, fileID uint, opts RestoreOptions) (RestoreResult, error) {
+	opts, err := normalizeRestoreOptions(opts)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	return s.executeRestore(ctx, userID, opts,
+		func(tx *gorm.DB) (*models.RecycleBinItem, error) {
+			item, subtree, err := s.loadDeletedFolder(ctx, tx, userID, itemID)
+			if err != nil {
+				return nil, err
+			}
+			file, err := s.findDeletedFolderFile(ctx, tx, userID, subtree, fileID)
+			if err != nil {
+				return nil, err
+			}
+			folderID := file.FolderID
+			return &models.RecycleBinItem{
+				ID:               item.ID,
+				UserID:           userID,
+				OriginalID:       file.ID,
+				OriginalType:     "file",
+				OriginalName:     file.OriginalName,
+				OriginalFolderID: &folderID,
+			}, nil
+		},
+		func(tx *gorm.DB, entry *models.RecycleBinItem) error {
+			return s.recycle.DeleteByOriginalIDs(ctx, tx, userID, "file", []uint{entry.OriginalID})
+		},
+	)
+}
+
+// GetFolderFileDownloadInfo 校验文件属于已删除目录子树并返回下载信息。
+func (s *recycleBinService) GetFolderFileDownloadInfo(ctx context.Context, userID uint, itemID uint, fileID uint) (FileAccessOutput, error) {
+	_, subtree, err := s.loadDeletedFolder(ctx, nil, userID, itemID)
+	if err != nil {
+		return FileAccessOutput{}, err
+	}
+	file, err := s.findDeletedFolderFile(ctx, nil, userID, subtree, fileID)
+	if err != nil {
+		return FileAccessOutput{}, err
+	}
+
+	absPath := filepath.Join(config.AppConfig.Storage.BasePath, file.FileObject.FilePath)
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return FileAccessOutput{}, newAppError(http.StatusNotFound, "文件不存在于存储中", nil)
+	}
+	return FileAccessOutput{File: file, AbsPath: absPath, ContentType: file.FileObject.MimeType, DownloadName: file.OriginalName, ETag: contentETag(file.FileObject.FileMD5)}, nil
+}
+
+// loadDeletedFolder 加载目录类型的回收站条目及其已删除子树，子树首元素为被删除的目录本身。
+func (s *recycleBinService) loadDeletedFolder(ctx context.Context, tx *gorm.DB, userID uint, itemID uint) (models.RecycleBinItem, []models.Folder, error) {
+	item, err := s.recycle.GetByIDAndUser(ctx, tx, itemID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return item, nil, newAppError(http.StatusNotFound, "回收站项目不存在", nil)
+		}
+		return item, nil, newAppError(http.StatusInternalServerError, "查询回收站项目失败", err)
+	}
+	if item.OriginalType != "folder" {
+		return item, nil, newAppError(http.StatusBadRequest, "该条目不是文件夹", nil)
+	}
+
+	root, err := s.folders.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return item, nil, newAppError(http.StatusNotFound, "文件夹不存在", nil)
+		}
+		return item, nil, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+	}
+	folders, err := s.folders.ListByPathPrefix(ctx, tx, userID, root.ID, root.Path, true)
+	if err != nil {
+		return item, nil, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+	}
+
+	subtree := []models.Folder{root}
+	for _, f := range deletedSubtree(folders, root.ID) {
+		if f.ID != root.ID {
+			subtree = append(subtree, f)
+		}
+	}
+	return item, subtree, nil
+}
+
+// findDeletedFolderFile 查找位于已删除子树中的已删除文件，不在子树内时按不存在处理。
+func (s *recycleBinService) findDeletedFolderFile(ctx context.Context, tx *gorm.DB, userID uint, subtree []models.Folder, fileID uint) (models.File, error) {
+	file, err := s.files.GetByIDAndUserUnscoped(ctx, tx, fileID, userID, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return file, newAppError(http.StatusNotFound, "文件不存在", nil)
+		}
+		return file, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+	}
+	if file.DeletedAt.Valid {
+		for _, f := range subtree {
+			if f.ID == file.FolderID {
+				return file, nil
+			}
+		}
+	}
+	return models.File{}, newAppError(http.StatusNotFound, "文件不存在", nil)
 }
 
 // PermanentDelete 彻底删除单个回收站条目及其关联数据。
@@ -151,17 +593,15 @@ func (s *recycleBinService) PermanentDelete(ctx context.Context, userID uint, it
 		}
 		return newAppError(http.StatusInternalServerError, "查询回收站项目失败", err)
 	}
+	return s.permanentDeleteLoadedItem(ctx, userID, &item)
+}
 
-	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+// permanentDeleteLoadedItem 在独立事务中彻删已加载的条目。
+func (s *recycleBinService) permanentDeleteLoadedItem(ctx context.Context, userID uint, item *models.RecycleBinItem) error {
+	err := s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
 		// 彻删会更新引用计数与用户已用空间，必须保证原子性。
-		if item.OriginalType == "file" {
-			if err := s.permanentDeleteFile(ctx, tx, &item, userID); err != nil {
-				return err
-			}
-		} else {
-			if err := s.permanentDeleteFolder(ctx, tx, &item, userID); err != nil {
-				return err
-			}
+		if err := s.recycler.purge(ctx, tx, userID, item); err != nil {
+			return err
 		}
 		return s.recycle.DeleteByID(ctx, tx, item.ID)
 	})
@@ -181,14 +621,8 @@ func (s *recycleBinService) EmptyRecycleBin(ctx context.Context, userID uint) er
 	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
 		// 顺序彻删每个条目，最后统一清理回收站记录。
 		for i := range items {
-			if items[i].OriginalType == "file" {
-				if err := s.permanentDeleteFile(ctx, tx, &items[i], userID); err != nil {
-					return err
-				}
-			} else {
-				if err := s.permanentDeleteFolder(ctx, tx, &items[i], userID); err != nil {
-					return err
-				}
+			if err := s.recycler.purge(ctx, tx, userID, &items[i]); err != nil {
+				return err
 			}
 		}
 		return s.recycle.DeleteByUser(ctx, tx, userID)
@@ -199,239 +633,362 @@ func (s *recycleBinService) EmptyRecycleBin(ctx context.Context, userID uint) er
 	return nil
 }
 
-// restoreFileItem 恢复单个文件条目并处理重名冲突。
-func (s *recycleBinService) restoreFileItem(ctx context.Context, tx *gorm.DB, userID uint, item *models.RecycleBinItem) error {
-	file, err := s.files.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID, false)
+// BatchRestore 批量恢复：先恢复目录再恢复文件，使文件尽量回到已恢复的原目录。
+// 预演按条目独立计算，不考虑同一批次内条目之间的重名。
+func (s *recycleBinService) BatchRestore(ctx context.Context, userID uint, in RecycleBinBatchRestoreInput) (RecycleBinBatchOutput, error) {
+	opts, err := normalizeRestoreOptions(in.RestoreOptions)
 	if err != nil {
-		return err
+		return RecycleBinBatchOutput{}, err
 	}
+	return s.runBatch(ctx, userID, in.RecycleBinBatchInput, "folder",
+		func(ctx context.Context, userID uint, item *models.RecycleBinItem, result *RecycleBinBatchItemResult) error {
+			restored, err := s.restoreLoadedItem(ctx, userID, item, opts)
+			if restored.ItemID != 0 {
+				result.Restore = &restored
+			}
+			return err
+		})
+}
 
-	// 原目录已失效时自动回退到根目录，避免恢复失败。
-	folderID := file.FolderID
-	if item.OriginalFolderID != nil {
-		folderID = *item.OriginalFolderID
-	}
-	folderID = s.ensureActiveFolderOrRoot(ctx, tx, userID, folderID)
+// BatchPermanentDelete 批量彻删：先删文件再删目录，避免目录级联清理后文件条目查不到。
+func (s *recycleBinService) BatchPermanentDelete(ctx context.Context, userID uint, in RecycleBinBatchInput) (RecycleBinBatchOutput, error) {
+	return s.runBatch(ctx, userID, in, "file",
+		func(ctx context.Context, userID uint, item *models.RecycleBinItem, _ *RecycleBinBatchItemResult) error {
+			return s.permanentDeleteLoadedItem(ctx, userID, item)
+		})
+}
 
-	count, err := s.files.CountByFolderAndOriginalName(ctx, tx, userID, folderID, item.OriginalName, item.OriginalID, false)
+// runBatch 选出条目后按 firstType 优先的顺序逐条处理，每条使用独立事务，单条失败不影响其余条目。
+func (s *recycleBinService) runBatch(
+	ctx context.Context,
+	userID uint,
+	in RecycleBinBatchInput,
+	firstType string,
+	apply func(ctx context.Context, userID uint, item *models.RecycleBinItem, result *RecycleBinBatchItemResult) error,
+) (RecycleBinBatchOutput, error) {
+	filter, err := buildRecycleBinFilter(userID, in)
 	if err != nil {
-		return err
-	}
-
-	updates := map[string]interface{}{
-		"deleted_at": nil,
-		"deleted_by": nil,
-		"folder_id":  folderID,
+		return RecycleBinBatchOutput{}, err
 	}
-	if count > 0 {
-		// 同目录重名时加后缀，保证恢复动作可落库。
-		updates["original_name"] = fmt.Sprintf("%s(restored)", item.OriginalName)
+	items, err := s.recycle.ListByFilter(ctx, nil, filter)
+	if err != nil {
+		return RecycleBinBatchOutput{}, newAppError(http.StatusInternalServerError, "查询回收站列表失败", err)
 	}
 
-	return s.files.UnscopedRestoreByIDAndUser(ctx, tx, item.OriginalID, userID, updates)
-}
+	out := RecycleBinBatchOutput{Results: make([]RecycleBinBatchItemResult, 0, len(items))}
+	if len(in.IDs) == 0 && len(items) > maxRecycleBatchItems {
+		items = items[:maxRecycleBatchItems]
+		out.HasMore = true
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OriginalType == firstType && items[j].OriginalType != firstType
+	})
 
-// restoreFolderItem 恢复目录条目并级联修复子目录路径。
-func (s *recycleBinService) restoreFolderItem(ctx context.Context, tx *gorm.DB, userID uint, item *models.RecycleBinItem) error {
-	folder, err := s.folders.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID)
-	if err != nil {
-		return err
+	found := make(map[uint]bool, len(items))
+	for i := range items {
+		item := &items[i]
+		found[item.ID] = true
+		result := RecycleBinBatchItemResult{ID: item.ID, OriginalType: item.OriginalType, OriginalName: item.OriginalName}
+		if err := apply(ctx, userID, item, &result); err != nil {
+			result.Code, result.Error = batchItemError(err)
+			logger.Ctx(ctx).With("recycle_item_id", item.ID).Warnf("回收站批量操作条目失败: %v", err)
+		} else {
+			result.Success = true
+		}
+		out.Results = append(out.Results, result)
 	}
-	// 根目录不属于普通可回收对象，直接拒绝恢复。
-	if folder.IsRoot != nil && *folder.IsRoot {
-		return fmt.Errorf("root folder cannot be restored")
+	// 指定 ID 但未选中的条目（不存在、不属于当前用户或不满足筛选）同样逐条报告。
+	for _, id := range in.IDs {
+		if !found[id] {
+			found[id] = true
+			out.Results = append(out.Results, RecycleBinBatchItemResult{ID: id, Code: http.StatusNotFound, Error: "回收站项目不存在"})
+		}
 	}
 
-	restoreParentID := uint(0)
-	if folder.ParentID != nil {
-		restoreParentID = *folder.ParentID
-	} else if item.OriginalFolderID != nil {
-		restoreParentID = *item.OriginalFolderID
+	for _, r := range out.Results {
+		if r.Success {
+			out.Succeeded++
+		} else {
+			out.Failed++
+		}
 	}
-	restoreParentID = s.ensureActiveFolderOrRoot(ctx, tx, userID, restoreParentID)
+	out.Total = len(out.Results)
+	return out, nil
+}
 
-	parent, err := s.folders.GetByIDAndUser(ctx, tx, restoreParentID, userID)
-	if err != nil {
-		return err
+// buildRecycleBinFilter 校验批量输入；既无 ID 也无筛选条件时拒绝，避免误操作整个回收站。
+func buildRecycleBinFilter(userID uint, in RecycleBinBatchInput) (repositories.RecycleBinFilter, error) {
+	if in.Type != "" && in.Type != "file" && in.Type != "folder" {
+		return repositories.RecycleBinFilter{}, newAppError(http.StatusBadRequest, "无效的条目类型", nil)
 	}
-
-	restoredName := item.OriginalName
-	if restoredName == "" {
-		restoredName = folder.Name
+	pathPrefix := strings.TrimSpace(in.PathPrefix)
+	if len(in.IDs) == 0 && in.Type == "" && in.DeletedBefore == nil && pathPrefix == "" {
+		return repositories.RecycleBinFilter{}, newAppError(http.StatusBadRequest, "请指定条目ID或筛选条件", nil)
+	}
+	if len(in.IDs) > maxRecycleBatchItems {
+		return repositories.RecycleBinFilter{}, newAppError(http.StatusBadRequest, fmt.Sprintf("单次最多处理 %d 个条目", maxRecycleBatchItems), nil)
 	}
 
-	count, err := s.folders.CountByParentAndName(ctx, tx, userID, restoreParentID, restoredName, folder.ID)
-	if err != nil {
-		return err
+	filter := repositories.RecycleBinFilter{
+		UserID:        userID,
+		IDs:           in.IDs,
+		OriginalType:  in.Type,
+		DeletedBefore: in.DeletedBefore,
+		PathPrefix:    pathPrefix,
 	}
-	if count > 0 {
-		restoredName = fmt.Sprintf("%s(restored)", restoredName)
+	if len(in.IDs) == 0 {
+		// 多取一条用于判断是否还有剩余条目。
+		filter.Limit = maxRecycleBatchItems + 1
 	}
+	return filter, nil
+}
 
-	// 目录恢复后需要同步修复整棵子树路径前缀。
-	oldPath := folder.Path
-	newPath := buildChildFolderPath(parent.Path, restoredName)
-	affectedFolders, err := s.folders.ListByPathPrefix(ctx, tx, userID, folder.ID, oldPath, true)
-	if err != nil {
-		return err
-	}
-	if len(affectedFolders) == 0 {
-		return gorm.ErrRecordNotFound
+// batchItemError 将单条处理错误转换为结果中的状态码与描述。
+func batchItemError(err error) (int, string) {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr.HTTPCode, appErr.Message
 	}
+	return http.StatusInternalServerError, "处理失败"
+}
 
-	folderIDs := make([]uint, 0, len(affectedFolders))
-	for i := range affectedFolders {
-		folderIDs = append(folderIDs, affectedFolders[i].ID)
+// restorePlan 为单个条目的恢复计划，预演与实际执行共用。
+type restorePlan struct {
+	result RestoreResult
+	item   *models.RecycleBinItem
+	parent models.Folder
+	// folder 为目录条目对应的已删除目录记录。
+	folder models.Folder
+}
 
-		updates := map[string]interface{}{"deleted_at": nil}
-		if affectedFolders[i].ID == folder.ID {
-			updates["name"] = restoredName
-			updates["path"] = newPath
-			updates["parent_id"] = restoreParentID
-		} else {
-			// 子目录路径保持相对结构不变，仅替换根前缀。
-			updates["path"] = strings.Replace(affectedFolders[i].Path, oldPath, newPath, 1)
-		}
+// planRestore 确定恢复目标目录与最终名称，并按冲突策略决定动作；只读，不做任何修改。
+func (s *recycleBinService) planRestore(ctx context.Context, tx *gorm.DB, userID uint, item *models.RecycleBinItem, opts RestoreOptions) (restorePlan, error) {
+	plan := restorePlan{
+		item:   item,
+		result: RestoreResult{ItemID: item.ID, OriginalID: item.OriginalID, OriginalType: item.OriginalType, Action: RestoreActionRestored, DryRun: opts.DryRun},
+	}
 
-		if err := s.folders.UpdateByIDUnscoped(ctx, tx, affectedFolders[i].ID, updates); err != nil {
-			return err
+	name := item.OriginalName
+	originalParentID := uint(0)
+	if item.OriginalType == "file" {
+		file, err := s.files.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID, false)
+		if err != nil {
+			return plan, err
+		}
+		originalParentID = file.FolderID
+		if item.OriginalFolderID != nil {
+			originalParentID = *item.OriginalFolderID
+		}
+	} else {
+		folder, err := s.folders.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID)
+		if err != nil {
+			return plan, err
+		}
+		// 根目录不属于普通可回收对象，直接拒绝恢复。
+		if folder.IsRoot != nil && *folder.IsRoot {
+			return plan, fmt.Errorf("root folder cannot be restored")
+		}
+		plan.folder = folder
+		if folder.ParentID != nil {
+			originalParentID = *folder.ParentID
+		} else if item.OriginalFolderID != nil {
+			originalParentID = *item.OriginalFolderID
+		}
+		if name == "" {
+			name = folder.Name
 		}
 	}
 
-	return s.files.UnscopedRestoreByFolderIDs(ctx, tx, userID, folderIDs, map[string]interface{}{"deleted_at": nil, "deleted_by": nil})
-}
-
-// ensureActiveFolderOrRoot 确保目录可用，不可用时回退根目录。
-func (s *recycleBinService) ensureActiveFolderOrRoot(ctx context.Context, tx *gorm.DB, userID uint, folderID uint) uint {
-	if folderID > 0 {
-		if _, err := s.folders.GetByIDAndUser(ctx, tx, folderID, userID); err == nil {
-			return folderID
+	var parentID uint
+	if opts.TargetFolderID != nil {
+		resolved, err := s.resolver.resolveFolderIDForUser(ctx, tx, userID, *opts.TargetFolderID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return plan, newAppError(http.StatusNotFound, "目标文件夹不存在", nil)
+			}
+			return plan, err
 		}
+		parentID = resolved
+	} else {
+		// 原目录已失效时自动回退到根目录，避免恢复失败。
+		parentID = s.ensureActiveFolderOrRoot(ctx, tx, userID, originalParentID)
+		plan.result.Relocated = parentID != originalParentID
 	}
+	parent, err := s.folders.GetByIDAndUser(ctx, tx, parentID, userID)
+	if err != nil {
+		return plan, err
+	}
+	plan.parent = parent
+	plan.result.FolderID = parent.ID
 
-	// 指定目录不可用时降级到根目录，保证恢复流程可继续。
-	root, err := s.resolver.getOrCreateUserRootFolder(ctx, tx, userID)
+	conflictID, err := s.findSiblingByName(ctx, tx, userID, item, parent.ID, name)
 	if err != nil {
-		return folderID
+		return plan, err
+	}
+	if conflictID > 0 {
+		plan.result.Conflict = true
+		switch opts.Conflict {
+		case RestoreConflictSkip:
+			plan.result.Action = RestoreActionSkipped
+		case RestoreConflictFail:
+			plan.result.Action = RestoreActionFailed
+		case RestoreConflictOverwrite:
+			plan.result.Action = RestoreActionOverwritten
+			plan.result.ReplacedID = conflictID
+		default:
+			if name, err = s.uniqueRestoreName(ctx, tx, userID, item, parent.ID, name); err != nil {
+				return plan, err
+			}
+			plan.result.Action = RestoreActionRenamed
+		}
 	}
-	return root.ID
+
+	plan.result.Name = name
+	plan.result.Path = buildChildFolderPath(parent.Path, name)
+	return plan, nil
 }
 
-// permanentDeleteFile 彻删文件并更新用户空间与对象引用。
-func (s *recycleBinService) permanentDeleteFile(ctx context.Context, tx *gorm.DB, item *models.RecycleBinItem, userID uint) error {
-	file, err := s.files.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID, true)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
+// findSiblingByName 查找目标目录下与条目同类型的同名活动条目，返回其 ID，不存在时返回 0。
+func (s *recycleBinService) findSiblingByName(ctx context.Context, tx *gorm.DB, userID uint, item *models.RecycleBinItem, parentID uint, name string) (uint, error) {
+	var id uint
+	var err error
+	if item.OriginalType == "file" {
+		var file models.File
+		file, err = s.files.GetByFolderAndOriginalName(ctx, tx, userID, parentID, name, item.OriginalID)
+		id = file.ID
+	} else {
+		var folder models.Folder
+		folder, err = s.folders.GetByParentAndName(ctx, tx, userID, parentID, name, item.OriginalID)
+		id = folder.ID
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
 	}
+	return id, err
+}
 
-	// 尝试从实时记录读取对象信息；缺失时回退到回收站快照。
-	fileObjectID := uint(0)
-	fileSize := int64(0)
-	if err == nil {
-		fileObjectID = file.FileObjectID
-		fileSize = file.FileObject.FileSize
-	} else {
-		if item.FileObjectID != nil {
-			fileObjectID = *item.FileObjectID
+// uniqueRestoreName 依次尝试 restoredName 生成的候选名称，返回第一个未被占用的名称。
+func (s *recycleBinService) uniqueRestoreName(ctx context.Context, tx *gorm.DB, userID uint, item *models.RecycleBinItem, parentID uint, name string) (string, error) {
+	for n := 1; n <= maxRestoreRenameAttempts; n++ {
+		candidate := restoredName(name, item.OriginalType == "file", n)
+		conflictID, err := s.findSiblingByName(ctx, tx, userID, item, parentID, candidate)
+		if err != nil {
+			return "", err
 		}
-		if item.FileSize != nil {
-			fileSize = *item.FileSize
+		if conflictID == 0 {
+			return candidate, nil
 		}
 	}
+	return "", newAppError(http.StatusConflict, "无法生成不冲突的名称", nil)
+}
 
-	if err := s.files.UnscopedDeleteByIDAndUser(ctx, tx, item.OriginalID, userID); err != nil {
-		return err
+// restoredName 生成第 n 个恢复候选名称：文件保留扩展名，如 a(restored).txt、a(restored 2).txt。
+func restoredName(name string, isFile bool, n int) string {
+	suffix := "(restored)"
+	if n > 1 {
+		suffix = fmt.Sprintf("(restored %d)", n)
+	}
+	ext := ""
+	if isFile {
+		ext = filepath.Ext(name)
+		if ext == name {
+			ext = ""
+		}
 	}
+	return strings.TrimSuffix(name, ext) + suffix + ext
+}
 
-	if fileSize > 0 {
-		if err := s.users.SubStorageUsed(ctx, tx, userID, fileSize); err != nil {
+// restoreFileItem 按计划恢复文件；覆盖策略下先将同名文件移入回收站。
+func (s *recycleBinService) restoreFileItem(ctx context.Context, tx *gorm.DB, userID uint, plan *restorePlan) error {
+	if plan.result.Action == RestoreActionOverwritten {
+		existing, err := s.files.GetByFolderAndOriginalName(ctx, tx, userID, plan.parent.ID, plan.result.Name, plan.item.OriginalID)
+		if err != nil {
 			return err
 		}
-	}
-
-	if fileObjectID > 0 {
-		if err := s.decrementFileObjectRef(ctx, tx, fileObjectID); err != nil {
+		if err := s.recycler.recycleFile(ctx, tx, userID, existing); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return s.files.UnscopedRestoreByIDAndUser(ctx, tx, plan.item.OriginalID, userID, map[string]interface{}{
+		"deleted_at":    nil,
+		"deleted_by":    nil,
+		"folder_id":     plan.parent.ID,
+		"original_name": plan.result.Name,
+	})
 }
 
-// permanentDeleteFolder 彻删目录树及其文件。
-func (s *recycleBinService) permanentDeleteFolder(ctx context.Context, tx *gorm.DB, item *models.RecycleBinItem, userID uint) error {
-	rootFolder, err := s.folders.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
-		}
-		return err
-	}
-	if rootFolder.IsRoot != nil && *rootFolder.IsRoot {
-		return fmt.Errorf("root folder cannot be deleted")
-	}
+// restoreFolderItem 按计划恢复目录并级联修复子目录路径；覆盖策略下先将同名目录移入回收站。
+func (s *recycleBinService) restoreFolderItem(ctx context.Context, tx *gorm.DB, userID uint, plan *restorePlan) error {
+	folder := plan.folder
 
-	// 按路径前缀收集整个目录子树后统一删除。
-	folders, err := s.folders.ListByPathPrefix(ctx, tx, userID, rootFolder.ID, rootFolder.Path, true)
+	// 先收集待恢复子树，再移走同名目录，避免两者路径相同而混在一起。
+	oldPath := folder.Path
+	newPath := plan.result.Path
+	subtree, err := s.folders.ListByPathPrefix(ctx, tx, userID, folder.ID, oldPath, true)
 	if err != nil {
 		return err
 	}
-	if len(folders) == 0 {
-		return nil
+	affectedFolders := deletedSubtree(subtree, folder.ID)
+	if len(affectedFolders) == 0 {
+		return gorm.ErrRecordNotFound
 	}
 
-	folderIDs := make([]uint, 0, len(folders))
-	for _, f := range folders {
-		folderIDs = append(folderIDs, f.ID)
+	if plan.result.Action == RestoreActionOverwritten {
+		existing, err := s.folders.GetByParentAndName(ctx, tx, userID, plan.parent.ID, plan.result.Name, folder.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.recycler.recycleFolder(ctx, tx, userID, existing); err != nil {
+			return err
+		}
 	}
 
-	files, err := s.files.ListByFolderIDs(ctx, tx, userID, folderIDs, true, true)
-	if err != nil {
-		return err
-	}
+	folderIDs := make([]uint, 0, len(affectedFolders))
+	for i := range affectedFolders {
+		folderIDs = append(folderIDs, affectedFolders[i].ID)
 
-	fileIDs := make([]uint, 0, len(files))
-	for i := range files {
-		// 目录内文件复用单文件彻删流程，避免逻辑分叉。
-		fileIDs = append(fileIDs, files[i].ID)
-		size := files[i].FileObject.FileSize
-		tmp := models.RecycleBinItem{OriginalID: files[i].ID, FileObjectID: &files[i].FileObjectID, FileSize: &size}
-		if err := s.permanentDeleteFile(ctx, tx, &tmp, userID); err != nil {
-			return err
+		updates := map[string]interface{}{"deleted_at": nil}
+		if affectedFolders[i].ID == folder.ID {
+			updates["name"] = plan.result.Name
+			updates["path"] = newPath
+			updates["parent_id"] = plan.parent.ID
+		} else {
+			// 子目录路径保持相对结构不变，仅替换根前缀。
+			updates["path"] = newPath + strings.TrimPrefix(affectedFolders[i].Path, oldPath)
 		}
-	}
 
-	if err := s.folders.UnscopedDeleteByIDs(ctx, tx, folderIDs); err != nil {
-		return err
-	}
-	if err := s.recycle.DeleteByOriginalIDs(ctx, tx, userID, "file", fileIDs); err != nil {
-		return err
-	}
-	if err := s.recycle.DeleteByOriginalIDs(ctx, tx, userID, "folder", folderIDs); err != nil {
-		return err
+		if err := s.folders.UpdateByIDUnscoped(ctx, tx, affectedFolders[i].ID, updates); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return s.files.UnscopedRestoreByFolderIDs(ctx, tx, userID, folderIDs, map[string]interface{}{"deleted_at": nil, "deleted_by": nil})
 }
 
-// decrementFileObjectRef 递减文件对象引用并在归零时清理物理资源。
-func (s *recycleBinService) decrementFileObjectRef(ctx context.Context, tx *gorm.DB, fileObjectID uint) error {
-	fileObj, err := s.fileObjects.GetByID(ctx, tx, fileObjectID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
+// deletedSubtree 过滤掉路径前缀匹配到的活动目录：删除后在原路径新建的同名目录不属于该回收站条目。
+func deletedSubtree(folders []models.Folder, rootID uint) []models.Folder {
+	kept := folders[:0]
+	for _, f := range folders {
+		if f.ID == rootID || f.DeletedAt.Valid {
+			kept = append(kept, f)
 		}
-		return err
 	}
+	return kept
+}
 
-	if fileObj.RefCount <= 1 {
-		// 最后引用释放时清理物理文件及缩略图。
-		_ = os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.FilePath))
-		if fileObj.ThumbnailPath != "" {
-			_ = os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.ThumbnailPath))
+// ensureActiveFolderOrRoot 确保目录可用，不可用时回退根目录。
+func (s *recycleBinService) ensureActiveFolderOrRoot(ctx context.Context, tx *gorm.DB, userID uint, folderID uint) uint {
+	if folderID > 0 {
+		if _, err := s.folders.GetByIDAndUser(ctx, tx, folderID, userID); err == nil {
+			return folderID
 		}
-		return s.fileObjects.DeleteByID(ctx, tx, fileObj.ID)
 	}
 
-	return s.fileObjects.DecrementRefCount(ctx, tx, fileObj.ID)
+	// 指定目录不可用时降级到根目录，保证恢复流程可继续。
+	root, err := s.resolver.getOrCreateUserRootFolder(ctx, tx, userID)
+	if err != nil {
+		return folderID
+	}
+	return root.ID
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -39,6 +40,10 @@ func (r *recycleServiceRecycleRepo) ListByUser(_ context.Context, _ *gorm.DB, in
 	return append([]models.RecycleBinItem(nil), r.listItems...), nil
 }
 
+func (r *recycleServiceRecycleRepo) ListByFilter(context.Context, *gorm.DB, repositories.RecycleBinFilter) ([]models.RecycleBinItem, error) {
+	return nil, nil
+}
+
 func (r *recycleServiceRecycleRepo) ListAllByUser(context.Context, *gorm.DB, uint) ([]models.RecycleBinItem, error) {
 	return nil, nil
 }
@@ -57,6 +62,9 @@ func (r *recycleServiceRecycleRepo) GetByIDAndUser(context.Context, *gorm.DB, ui
 func (r *recycleServiceRecycleRepo) Create(context.Context, *gorm.DB, *models.RecycleBinItem) error {
 	return nil
 }
+func (r *recycleServiceRecycleRepo) UpdateExpiresAtByUser(context.Context, *gorm.DB, uint, int) error {
+	return nil
+}
 func (r *recycleServiceRecycleRepo) DeleteByID(context.Context, *gorm.DB, uint) error { return nil }
 func (r *recycleServiceRecycleRepo) DeleteByUser(context.Context, *gorm.DB, uint) error {
 	return nil
@@ -105,6 +113,7 @@ func TestRecycleBinServiceListRecycleBinNormalizesPagination(t *testing.T) {
 		newFakeFileRepo(),
 		newFakeFileObjectRepo(),
 		recycleRepo,
+		nil,
 	)
 
 	out, err := svc.ListRecycleBin(context.Background(), 8, 0, 200)
@@ -134,9 +143,10 @@ func TestRecycleBinServiceRestoreItemNotFound(t *testing.T) {
 		newFakeFileRepo(),
 		newFakeFileObjectRepo(),
 		recycleRepo,
+		nil,
 	)
 
-	err := svc.RestoreItem(context.Background(), 1, 99)
+	_, err := svc.RestoreItem(context.Background(), 1, 99, RestoreOptions{})
 	if err == nil {
 		t.Fatalf("expected not found error")
 	}
@@ -149,7 +159,7 @@ func TestRecycleBinServiceRestoreItemNotFound(t *testing.T) {
 	}
 }
 
-func TestRecycleBinServiceDecrementFileObjectRefDeletesFilesForLastRef(t *testing.T) {
+func TestRecyclerDecrementFileObjectRefDeletesFilesForLastRef(t *testing.T) {
 	baseDir := t.TempDir()
 	filePath := filepath.Join(baseDir, "objects", "o-1.bin")
 	thumbPath := filepath.Join(baseDir, "thumbs", "o-1.jpg")
@@ -176,8 +186,8 @@ func TestRecycleBinServiceDecrementFileObjectRefDeletesFilesForLastRef(t *testin
 		RefCount:      1,
 	}
 
-	svc := &recycleBinService{fileObjects: fileObjects}
-	if err := svc.decrementFileObjectRef(context.Background(), nil, 10); err != nil {
+	r := recycler{fileObjects: fileObjects}
+	if err := r.decrementFileObjectRef(context.Background(), nil, 10); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if len(fileObjects.deletedIDs) != 1 || fileObjects.deletedIDs[0] != 10 {
@@ -194,7 +204,7 @@ func TestRecycleBinServiceDecrementFileObjectRefDeletesFilesForLastRef(t *testin
 	}
 }
 
-func TestRecycleBinServiceDecrementFileObjectRefDecrementsWhenShared(t *testing.T) {
+func TestRecyclerDecrementFileObjectRefDecrementsWhenShared(t *testing.T) {
 	config.AppConfig = &config.Config{Storage: config.StorageConfig{BasePath: t.TempDir()}}
 
 	fileObjects := newRecycleTrackingFileObjectRepo()
@@ -204,8 +214,8 @@ func TestRecycleBinServiceDecrementFileObjectRefDecrementsWhenShared(t *testing.
 		RefCount: 2,
 	}
 
-	svc := &recycleBinService{fileObjects: fileObjects}
-	if err := svc.decrementFileObjectRef(context.Background(), nil, 11); err != nil {
+	r := recycler{fileObjects: fileObjects}
+	if err := r.decrementFileObjectRef(context.Background(), nil, 11); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if len(fileObjects.decrementedIDs) != 1 || fileObjects.decrementedIDs[0] != 11 {
@@ -215,3 +225,704 @@ func TestRecycleBinServiceDecrementFileObjectRefDecrementsWhenShared(t *testing.
 		t.Fatalf("expected no object delete, got %#v", fileObjects.deletedIDs)
 	}
 }
+
+type batchRecycleRepo struct {
+	recycleServiceRecycleRepo
+	filterItems   []models.RecycleBinItem
+	lastFilter    repositories.RecycleBinFilter
+	failDeleteIDs map[uint]bool
+	deletedIDs    []uint
+}
+
+func (r *batchRecycleRepo) ListByFilter(_ context.Context, _ *gorm.DB, filter repositories.RecycleBinFilter) ([]models.RecycleBinItem, error) {
+	r.lastFilter = filter
+	return append([]models.RecycleBinItem(nil), r.filterItems...), nil
+}
+
+func (r *batchRecycleRepo) DeleteByID(_ context.Context, _ *gorm.DB, itemID uint) error {
+	if r.failDeleteIDs[itemID] {
+		return errors.New("delete failed")
+	}
+	r.deletedIDs = append(r.deletedIDs, itemID)
+	return nil
+}
+
+func newBatchRecycleService(files repositories.FileRepository, recycle repositories.RecycleBinRepository) RecycleBinService {
+	return NewRecycleBinService(fakeTxManager{}, newFakeUserRepo(), newFakeFolderRepo(), files, newFakeFileObjectRepo(), recycle, nil)
+}
+
+func TestRecycleBinServiceBatchRequiresSelection(t *testing.T) {
+	svc := newBatchRecycleService(newFakeFileRepo(), &batchRecycleRepo{})
+
+	cases := []RecycleBinBatchInput{
+		{},
+		{PathPrefix: "  "},
+		{Type: "link"},
+	}
+	for _, in := range cases {
+		_, err := svc.BatchPermanentDelete(context.Background(), 8, in)
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.HTTPCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for input %+v, got %v", in, err)
+		}
+	}
+}
+
+func TestRecycleBinServiceBatchPermanentDeleteReportsPerItemResults(t *testing.T) {
+	files := newCleanupServiceFileRepo()
+	files.fileByID[11] = models.File{ID: 11, UserID: 8}
+	files.fileByID[12] = models.File{ID: 12, UserID: 8}
+	recycle := &batchRecycleRepo{
+		filterItems: []models.RecycleBinItem{
+			{ID: 1, UserID: 8, OriginalType: "folder", OriginalID: 100, OriginalName: "docs"},
+			{ID: 2, UserID: 8, OriginalType: "file", OriginalID: 11, OriginalName: "a.txt"},
+			{ID: 3, UserID: 8, OriginalType: "file", OriginalID: 12, OriginalName: "b.txt"},
+		},
+		failDeleteIDs: map[uint]bool{3: true},
+	}
+	svc := newBatchRecycleService(files, recycle)
+
+	out, err := svc.BatchPermanentDelete(context.Background(), 8, RecycleBinBatchInput{IDs: []uint{1, 2, 3, 99}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recycle.lastFilter.UserID != 8 || len(recycle.lastFilter.IDs) != 4 || recycle.lastFilter.Limit != 0 {
+		t.Fatalf("unexpected filter: %+v", recycle.lastFilter)
+	}
+	if out.Total != 4 || out.Succeeded != 2 || out.Failed != 2 || out.HasMore {
+		t.Fatalf("unexpected summary: %+v", out)
+	}
+
+	// 文件先于目录处理，未选中的 ID 追加在末尾。
+	wantOrder := []uint{2, 3, 1, 99}
+	for i, id := range wantOrder {
+		if out.Results[i].ID != id {
+			t.Fatalf("expected result %d to be item %d, got %+v", i, id, out.Results)
+		}
+	}
+	if !out.Results[0].Success || !out.Results[2].Success {
+		t.Fatalf("expected items 2 and 1 to succeed, got %+v", out.Results)
+	}
+	if out.Results[1].Success || out.Results[1].Code != http.StatusInternalServerError {
+		t.Fatalf("expected item 3 to fail with 500, got %+v", out.Results[1])
+	}
+	if out.Results[3].Success || out.Results[3].Code != http.StatusNotFound {
+		t.Fatalf("expected missing item to be reported as 404, got %+v", out.Results[3])
+	}
+	if len(recycle.deletedIDs) != 2 {
+		t.Fatalf("expected two recycle records removed, got %v", recycle.deletedIDs)
+	}
+}
+
+func TestRecycleBinServiceBatchByFilterIsBounded(t *testing.T) {
+	items := make([]models.RecycleBinItem, maxRecycleBatchItems+1)
+	for i := range items {
+		items[i] = models.RecycleBinItem{ID: uint(i + 1), UserID: 8, OriginalType: "folder", OriginalID: uint(1000 + i)}
+	}
+	recycle := &batchRecycleRepo{filterItems: items}
+	svc := newBatchRecycleService(newFakeFileRepo(), recycle)
+
+	before := time.Now()
+	out, err := svc.BatchPermanentDelete(context.Background(), 8, RecycleBinBatchInput{Type: "folder", DeletedBefore: &before, PathPrefix: "/docs"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recycle.lastFilter.Limit != maxRecycleBatchItems+1 || recycle.lastFilter.OriginalType != "folder" || recycle.lastFilter.PathPrefix != "/docs" {
+		t.Fatalf("unexpected filter: %+v", recycle.lastFilter)
+	}
+	if !out.HasMore || out.Total != maxRecycleBatchItems || out.Succeeded != maxRecycleBatchItems {
+		t.Fatalf("expected a bounded batch with more remaining, got total=%d succeeded=%d has_more=%v", out.Total, out.Succeeded, out.HasMore)
+	}
+}
+
+type restoreRecycleRepo struct {
+	recycleServiceRecycleRepo
+	items              map[uint]models.RecycleBinItem
+	created            []models.RecycleBinItem
+	deletedIDs         []uint
+	deletedOriginalIDs []uint
+}
+
+func (r *restoreRecycleRepo) GetByIDAndUser(_ context.Context, _ *gorm.DB, itemID uint, userID uint) (models.RecycleBinItem, error) {
+	item, ok := r.items[itemID]
+	if !ok || item.UserID != userID {
+		return models.RecycleBinItem{}, gorm.ErrRecordNotFound
+	}
+	return item, nil
+}
+
+func (r *restoreRecycleRepo) Create(_ context.Context, _ *gorm.DB, item *models.RecycleBinItem) error {
+	r.created = append(r.created, *item)
+	return nil
+}
+
+func (r *restoreRecycleRepo) DeleteByID(_ context.Context, _ *gorm.DB, itemID uint) error {
+	r.deletedIDs = append(r.deletedIDs, itemID)
+	return nil
+}
+
+func (r *restoreRecycleRepo) DeleteByOriginalIDs(_ context.Context, _ *gorm.DB, _ uint, _ string, originalIDs []uint) error {
+	r.deletedOriginalIDs = append(r.deletedOriginalIDs, originalIDs...)
+	return nil
+}
+
+type restoreFileRepo struct {
+	*fakeFileRepo
+	deleted     map[uint]models.File
+	active      []models.File
+	restored    map[uint]map[string]interface{}
+	softDeleted []uint
+}
+
+func (r *restoreFileRepo) GetByIDAndUserUnscoped(_ context.Context, _ *gorm.DB, fileID uint, userID uint, _ bool) (models.File, error) {
+	file, ok := r.deleted[fileID]
+	if !ok || file.UserID != userID {
+		return models.File{}, gorm.ErrRecordNotFound
+	}
+	return file, nil
+}
+
+func (r *restoreFileRepo) GetByFolderAndOriginalName(_ context.Context, _ *gorm.DB, userID uint, folderID uint, name string, excludeID uint) (models.File, error) {
+	for _, f := range r.active {
+		if f.UserID == userID && f.FolderID == folderID && f.OriginalName == name && f.ID != excludeID {
+			return f, nil
+		}
+	}
+	return models.File{}, gorm.ErrRecordNotFound
+}
+
+func (r *restoreFileRepo) ListByFolderIDs(_ context.Context, _ *gorm.DB, userID uint, folderIDs []uint, _ bool, _ bool) ([]models.File, error) {
+	var out []models.File
+	all := append([]models.File(nil), r.active...)
+	for _, f := range r.deleted {
+		all = append(all, f)
+	}
+	for _, f := range all {
+		for _, id := range folderIDs {
+			if f.UserID == userID && f.FolderID == id {
+				out = append(out, f)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *restoreFileRepo) SoftDeleteByIDAndUser(_ context.Context, _ *gorm.DB, fileID uint, _ uint) error {
+	r.softDeleted = append(r.softDeleted, fileID)
+	return nil
+}
+
+func (r *restoreFileRepo) UnscopedRestoreByIDAndUser(_ context.Context, _ *gorm.DB, fileID uint, _ uint, updates map[string]interface{}) error {
+	r.restored[fileID] = updates
+	return nil
+}
+
+// newRestoreFixture 构造用户 8 的根目录 1、目录 /docs(2) 与回收站中原属 /docs 的 a.txt（文件 11，条目 5）。
+func newRestoreFixture(active ...models.File) (RecycleBinService, *restoreFileRepo, *restoreRecycleRepo) {
+	config.AppConfig = &config.Config{RecycleBin: config.RecycleBinConfig{Enabled: true, RetentionDays: 30}}
+
+	isRoot := true
+	parentID := uint(1)
+	folders := newFolderServiceFolderRepo()
+	folders.folders[1] = models.Folder{ID: 1, UserID: 8, Name: "root", Path: "/", IsRoot: &isRoot}
+	folders.folders[2] = models.Folder{ID: 2, UserID: 8, Name: "docs", Path: "/docs", ParentID: &parentID}
+	folders.rootByUser[8] = 1
+
+	files := &restoreFileRepo{
+		fakeFileRepo: newFakeFileRepo(),
+		deleted:      map[uint]models.File{11: {ID: 11, UserID: 8, FolderID: 2, OriginalName: "a.txt"}},
+		active:       active,
+		restored:     map[uint]map[string]interface{}{},
+	}
+	originalFolderID := uint(2)
+	recycle := &restoreRecycleRepo{items: map[uint]models.RecycleBinItem{
+		5: {ID: 5, UserID: 8, OriginalID: 11, OriginalType: "file", OriginalName: "a.txt", OriginalFolderID: &originalFolderID},
+	}}
+	svc := NewRecycleBinService(fakeTxManager{}, newFakeUserRepo(), folders, files, newFakeFileObjectRepo(), recycle, nil)
+	return svc, files, recycle
+}
+
+func TestRecycleBinServiceRestoreItemToOriginalFolder(t *testing.T) {
+	svc, files, recycle := newRestoreFixture()
+
+	out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Action != RestoreActionRestored || out.FolderID != 2 || out.Path != "/docs/a.txt" || out.Conflict || out.Relocated {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+	if files.restored[11]["folder_id"] != uint(2) || files.restored[11]["original_name"] != "a.txt" {
+		t.Fatalf("unexpected restore updates: %+v", files.restored[11])
+	}
+	if len(recycle.deletedIDs) != 1 || recycle.deletedIDs[0] != 5 {
+		t.Fatalf("expected recycle record 5 removed, got %v", recycle.deletedIDs)
+	}
+}
+
+func TestRecycleBinServiceRestoreItemToTargetFolder(t *testing.T) {
+	svc, files, _ := newRestoreFixture()
+
+	root := uint(0)
+	out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{TargetFolderID: &root})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.FolderID != 1 || out.Path != "/a.txt" || out.Relocated {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+	if files.restored[11]["folder_id"] != uint(1) {
+		t.Fatalf("expected file moved to root, got %+v", files.restored[11])
+	}
+
+	missing := uint(404)
+	_, err = svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{TargetFolderID: &missing})
+	appErr, ok := err.(*AppError)
+	if !ok || appErr.HTTPCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing target, got %v", err)
+	}
+}
+
+func TestRecycleBinServiceRestoreItemConflictPolicies(t *testing.T) {
+	existing := []models.File{
+		{ID: 21, UserID: 8, FolderID: 2, OriginalName: "a.txt"},
+		{ID: 22, UserID: 8, FolderID: 2, OriginalName: "a(restored).txt"},
+	}
+
+	t.Run("rename", func(t *testing.T) {
+		svc, files, _ := newRestoreFixture(existing...)
+		out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "rename"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.Action != RestoreActionRenamed || out.Name != "a(restored 2).txt" || out.Path != "/docs/a(restored 2).txt" || !out.Conflict {
+			t.Fatalf("unexpected result: %+v", out)
+		}
+		if files.restored[11]["original_name"] != "a(restored 2).txt" {
+			t.Fatalf("unexpected restore updates: %+v", files.restored[11])
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		svc, files, recycle := newRestoreFixture(existing...)
+		out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "skip"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.Action != RestoreActionSkipped || len(files.restored) != 0 || len(recycle.deletedIDs) != 0 {
+			t.Fatalf("expected nothing restored, got %+v restored=%v deleted=%v", out, files.restored, recycle.deletedIDs)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		svc, files, _ := newRestoreFixture(existing...)
+		_, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "fail"})
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.HTTPCode != http.StatusConflict {
+			t.Fatalf("expected 409, got %v", err)
+		}
+		if plan, ok := appErr.Data.(RestoreResult); !ok || plan.Path != "/docs/a.txt" {
+			t.Fatalf("expected conflicting plan in error data, got %+v", appErr.Data)
+		}
+		if len(files.restored) != 0 {
+			t.Fatalf("expected nothing restored, got %v", files.restored)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		svc, files, recycle := newRestoreFixture(existing...)
+		out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "overwrite"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.Action != RestoreActionOverwritten || out.ReplacedID != 21 || out.Name != "a.txt" {
+			t.Fatalf("unexpected result: %+v", out)
+		}
+		// 被覆盖的文件进入回收站，作为可找回的旧版本。
+		if len(files.softDeleted) != 1 || files.softDeleted[0] != 21 {
+			t.Fatalf("expected file 21 recycled, got %v", files.softDeleted)
+		}
+		if len(recycle.created) != 1 || recycle.created[0].OriginalID != 21 || recycle.created[0].OriginalFullPath != "/docs/a.txt" {
+			t.Fatalf("expected recycle snapshot for file 21, got %+v", recycle.created)
+		}
+		if files.restored[11]["original_name"] != "a.txt" {
+			t.Fatalf("unexpected restore updates: %+v", files.restored[11])
+		}
+	})
+
+	t.Run("overwrite with recycle bin disabled", func(t *testing.T) {
+		svc, files, recycle := newRestoreFixture(existing...)
+		config.AppConfig.RecycleBin.Enabled = false
+		_, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "overwrite"})
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.HTTPCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %v", err)
+		}
+		// 被覆盖的文件无法写入回收站，不能被软删除后遗留在存储中。
+		if len(files.softDeleted) != 0 || len(files.restored) != 0 || len(recycle.created) != 0 {
+			t.Fatalf("expected no changes, got softDeleted=%v restored=%v created=%v", files.softDeleted, files.restored, recycle.created)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		svc, _, _ := newRestoreFixture(existing...)
+		_, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "merge"})
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.HTTPCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %v", err)
+		}
+	})
+}
+
+func TestRecycleBinServiceRestoreItemDryRunMakesNoChanges(t *testing.T) {
+	svc, files, recycle := newRestoreFixture(models.File{ID: 21, UserID: 8, FolderID: 2, OriginalName: "a.txt"})
+
+	out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{Conflict: "overwrite", DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.DryRun || out.Action != RestoreActionOverwritten || out.ReplacedID != 21 || out.Path != "/docs/a.txt" {
+		t.Fatalf("unexpected preview: %+v", out)
+	}
+	if len(files.restored) != 0 || len(files.softDeleted) != 0 || len(recycle.created) != 0 || len(recycle.deletedIDs) != 0 {
+		t.Fatalf("dry run must not write: restored=%v softDeleted=%v created=%v deleted=%v",
+			files.restored, files.softDeleted, recycle.created, recycle.deletedIDs)
+	}
+}
+
+func TestRecycleBinServiceRestoreItemFallsBackToRoot(t *testing.T) {
+	svc, _, recycle := newRestoreFixture()
+	gone := uint(3)
+	item := recycle.items[5]
+	item.OriginalFolderID = &gone
+	recycle.items[5] = item
+
+	out, err := svc.RestoreItem(context.Background(), 8, 5, RestoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.FolderID != 1 || out.Path != "/a.txt" || !out.Relocated {
+		t.Fatalf("expected relocation to root, got %+v", out)
+	}
+}
+
+func TestRestoredName(t *testing.T) {
+	cases := []struct {
+		name   string
+		isFile bool
+		n      int
+		want   string
+	}{
+		{"a.txt", true, 1, "a(restored).txt"},
+		{"a.tar.gz", true, 2, "a.tar(restored 2).gz"},
+		{".env", true, 1, ".env(restored)"},
+		{"v1.2", false, 3, "v1.2(restored 3)"},
+	}
+	for _, tc := range cases {
+		if got := restoredName(tc.name, tc.isFile, tc.n); got != tc.want {
+			t.Fatalf("restoredName(%q, %v, %d) = %q, want %q", tc.name, tc.isFile, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestDeletedSubtreeSkipsActiveLookalikes(t *testing.T) {
+	deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	folders := []models.Folder{
+		{ID: 1, Path: "/docs", DeletedAt: deleted},
+		{ID: 2, Path: "/docs/sub", DeletedAt: deleted},
+		{ID: 3, Path: "/docs"},
+		{ID: 4, Path: "/docs/new"},
+	}
+	got := deletedSubtree(folders, 1)
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Fatalf("expected only the deleted subtree, got %+v", got)
+	}
+}
+
+func TestRecyclerEvictOverflowPurgesOldestItems(t *testing.T) {
+	config.AppConfig = &config.Config{RecycleBin: config.RecycleBinConfig{Enabled: true, MaxItemsPerUser: 3}}
+
+	files := newCleanupServiceFileRepo()
+	files.fileByID[11] = models.File{ID: 11, UserID: 8}
+	files.fileByID[12] = models.File{ID: 12, UserID: 8}
+	recycle := &batchRecycleRepo{
+		recycleServiceRecycleRepo: recycleServiceRecycleRepo{count: 5},
+		filterItems: []models.RecycleBinItem{
+			{ID: 1, UserID: 8, OriginalType: "file", OriginalID: 11},
+			{ID: 2, UserID: 8, OriginalType: "file", OriginalID: 12},
+		},
+	}
+	r := newRecycler(fakeTxManager{}, newFakeUserRepo(), newFakeFolderRepo(), files, newFakeFileObjectRepo(), recycle)
+
+	r.evictOverflow(context.Background(), 8)
+
+	if recycle.lastFilter.UserID != 8 || recycle.lastFilter.Limit != 2 {
+		t.Fatalf("expected the two oldest items to be selected, got %+v", recycle.lastFilter)
+	}
+	if len(files.deletedIDs) != 2 || len(recycle.deletedIDs) != 2 {
+		t.Fatalf("expected two items purged, got files=%v records=%v", files.deletedIDs, recycle.deletedIDs)
+	}
+}
+
+func TestRecyclerEvictOverflowWithinLimitDoesNothing(t *testing.T) {
+	cases := []config.RecycleBinConfig{
+		{Enabled: true, MaxItemsPerUser: 5},
+		{Enabled: true, MaxItemsPerUser: 0},
+		{Enabled: false, MaxItemsPerUser: 1},
+	}
+	for _, cfg := range cases {
+		config.AppConfig = &config.Config{RecycleBin: cfg}
+		recycle := &batchRecycleRepo{recycleServiceRecycleRepo: recycleServiceRecycleRepo{count: 5}}
+		r := newRecycler(fakeTxManager{}, newFakeUserRepo(), newFakeFolderRepo(), newFakeFileRepo(), newFakeFileObjectRepo(), recycle)
+
+		r.evictOverflow(context.Background(), 8)
+
+		if recycle.lastFilter.UserID != 0 {
+			t.Fatalf("expected no eviction for %+v, got filter %+v", cfg, recycle.lastFilter)
+		}
+	}
+}
+
+func TestEffectiveRetentionDaysClampsToBounds(t *testing.T) {
+	config.AppConfig = &config.Config{RecycleBin: config.RecycleBinConfig{RetentionDays: 30, MinRetentionDays: 3, MaxRetentionDays: 60}}
+
+	days := func(n int) *int { return &n }
+	cases := []struct {
+		custom *int
+		want   int
+	}{
+		{nil, 30},
+		{days(7), 7},
+		{days(1), 3},
+		{days(365), 60},
+	}
+	for _, tc := range cases {
+		if got := effectiveRetentionDays(tc.custom); got != tc.want {
+			t.Fatalf("effectiveRetentionDays(%v) = %d, want %d", tc.custom, got, tc.want)
+		}
+	}
+}
+
+type settingsRecycleRepo struct {
+	recycleServiceRecycleRepo
+	retention map[uint]int
+}
+
+func (r *settingsRecycleRepo) UpdateExpiresAtByUser(_ context.Context, _ *gorm.DB, userID uint, retentionDays int) error {
+	r.retention[userID] = retentionDays
+	return nil
+}
+
+func TestRecycleBinServiceUpdateSettings(t *testing.T) {
+	config.AppConfig = &config.Config{RecycleBin: config.RecycleBinConfig{
+		Enabled: true, RetentionDays: 30, MinRetentionDays: 1, MaxRetentionDays: 90, MaxItemsPerUser: 1000,
+	}}
+	users := newFakeUserRepo()
+	users.usersByID[8] = models.User{ID: 8}
+	recycle := &settingsRecycleRepo{
+		recycleServiceRecycleRepo: recycleServiceRecycleRepo{count: 1},
+		retention:                 map[uint]int{},
+	}
+	svc := NewRecycleBinService(fakeTxManager{}, users, newFakeFolderRepo(), newFakeFileRepo(), newFakeFileObjectRepo(), recycle, nil)
+
+	for _, days := range []int{0, 91} {
+		_, err := svc.UpdateSettings(context.Background(), 8, RecycleBinSettingsInput{RetentionDays: &days})
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.HTTPCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %d days, got %v", days, err)
+		}
+	}
+
+	days := 7
+	out, err := svc.UpdateSettings(context.Background(), 8, RecycleBinSettingsInput{RetentionDays: &days})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.RetentionDays != 7 || !out.CustomRetention || out.DefaultRetentionDays != 30 || out.MaxItems != 1000 || out.ItemCount != 1 {
+		t.Fatalf("unexpected settings: %+v", out)
+	}
+	if got := recycle.retention[8]; got != 7 {
+		t.Fatalf("expected expiries recomputed with 7 days, got %d", got)
+	}
+
+	out, err = svc.UpdateSettings(context.Background(), 8, RecycleBinSettingsInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.RetentionDays != 30 || out.CustomRetention || recycle.retention[8] != 30 {
+		t.Fatalf("expected reset to default, got %+v", out)
+	}
+}
+
+func TestNewRecycleBinListItemCountsDown(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		expiresAt time.Time
+		seconds   int64
+		days      int
+	}{
+		{now.Add(36 * time.Hour), 36 * 3600, 2},
+		{now.Add(24 * time.Hour), 24 * 3600, 1},
+		{now.Add(-time.Hour), 0, 0},
+	}
+	for _, tc := range cases {
+		got := newRecycleBinListItem(models.RecycleBinItem{ExpiresAt: tc.expiresAt}, now)
+		if got.ExpiresInSeconds != tc.seconds || got.ExpiresInDays != tc.days {
+			t.Fatalf("expected %ds/%dd, got %ds/%dd", tc.seconds, tc.days, got.ExpiresInSeconds, got.ExpiresInDays)
+		}
+	}
+}
+
+// deletedFolderRepo 在 folderServiceFolderRepo 基础上区分已删除目录：常规查询看不到它们。
+type deletedFolderRepo struct {
+	*folderServiceFolderRepo
+}
+
+func (r deletedFolderRepo) GetByIDAndUser(ctx context.Context, tx *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
+	folder, err := r.folderServiceFolderRepo.GetByIDAndUser(ctx, tx, folderID, userID)
+	if err == nil && folder.DeletedAt.Valid {
+		return models.Folder{}, gorm.ErrRecordNotFound
+	}
+	return folder, err
+}
+
+func (r deletedFolderRepo) GetByIDAndUserUnscoped(ctx context.Context, tx *gorm.DB, folderID uint, userID uint) (models.Folder, error) {
+	return r.folderServiceFolderRepo.GetByIDAndUser(ctx, tx, folderID, userID)
+}
+
+// newDeletedFolderFixture 构造回收站条目 6：已删除的 /docs(2) 及其子目录 /docs/sub(3)，
+// 另有删除后新建的同名活动目录 /docs(4) 与 /docs/new(5)。
+func newDeletedFolderFixture(t *testing.T) (RecycleBinService, *restoreFileRepo, *restoreRecycleRepo) {
+	t.Helper()
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{
+		Storage:    config.StorageConfig{BasePath: baseDir},
+		RecycleBin: config.RecycleBinConfig{Enabled: true, RetentionDays: 30},
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "o-11.bin"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("write object failed: %v", err)
+	}
+
+	deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	isRoot := true
+	id := func(n uint) *uint { return &n }
+	folders := newFolderServiceFolderRepo()
+	folders.folders[1] = models.Folder{ID: 1, UserID: 8, Name: "root", Path: "/", IsRoot: &isRoot}
+	folders.folders[2] = models.Folder{ID: 2, UserID: 8, Name: "docs", Path: "/docs", ParentID: id(1), DeletedAt: deleted}
+	folders.folders[3] = models.Folder{ID: 3, UserID: 8, Name: "sub", Path: "/docs/sub", ParentID: id(2), DeletedAt: deleted}
+	folders.folders[4] = models.Folder{ID: 4, UserID: 8, Name: "docs", Path: "/docs", ParentID: id(1)}
+	folders.folders[5] = models.Folder{ID: 5, UserID: 8, Name: "new", Path: "/docs/new", ParentID: id(4)}
+	folders.rootByUser[8] = 1
+
+	files := &restoreFileRepo{
+		fakeFileRepo: newFakeFileRepo(),
+		deleted: map[uint]models.File{
+			11: {ID: 11, UserID: 8, FolderID: 2, OriginalName: "a.txt", DeletedAt: deleted, FileObject: models.FileObject{FilePath: "o-11.bin"}},
+			12: {ID: 12, UserID: 8, FolderID: 3, OriginalName: "b.txt", DeletedAt: deleted},
+			14: {ID: 14, UserID: 8, FolderID: 1, OriginalName: "elsewhere.txt", DeletedAt: deleted},
+		},
+		active:   []models.File{{ID: 13, UserID: 8, FolderID: 4, OriginalName: "c.txt"}},
+		restored: map[uint]map[string]interface{}{},
+	}
+	recycle := &restoreRecycleRepo{items: map[uint]models.RecycleBinItem{
+		6: {ID: 6, UserID: 8, OriginalID: 2, OriginalType: "folder", OriginalName: "docs", OriginalFolderID: id(1)},
+		7: {ID: 7, UserID: 8, OriginalID: 14, OriginalType: "file", OriginalName: "elsewhere.txt"},
+	}}
+	svc := NewRecycleBinService(fakeTxManager{}, newFakeUserRepo(), deletedFolderRepo{folders}, files, newFakeFileObjectRepo(), recycle, nil)
+	return svc, files, recycle
+}
+
+func TestRecycleBinServiceListFolderContents(t *testing.T) {
+	svc, _, _ := newDeletedFolderFixture(t)
+	ctx := context.Background()
+
+	top, err := svc.ListFolderContents(ctx, 8, 6, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if top.Path != "/" || top.OriginalPath != "/docs" {
+		t.Fatalf("unexpected location: %+v", top)
+	}
+	// 删除后新建的同名活动目录及其内容不属于该条目。
+	if len(top.Folders) != 1 || top.Folders[0].ID != 3 || len(top.Files) != 1 || top.Files[0].ID != 11 {
+		t.Fatalf("unexpected top-level contents: folders=%+v files=%+v", top.Folders, top.Files)
+	}
+
+	sub, err := svc.ListFolderContents(ctx, 8, 6, "sub/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Path != "/sub" || sub.OriginalPath != "/docs/sub" || len(sub.Files) != 1 || sub.Files[0].ID != 12 {
+		t.Fatalf("unexpected sub contents: %+v", sub)
+	}
+
+	for _, tc := range []struct {
+		itemID uint
+		path   string
+		code   int
+	}{
+		{6, "/new", http.StatusNotFound},
+		{7, "/", http.StatusBadRequest},
+		{99, "/", http.StatusNotFound},
+	} {
+		_, err := svc.ListFolderContents(ctx, 8, tc.itemID, tc.path)
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.HTTPCode != tc.code {
+			t.Fatalf("item %d path %q: expected %d, got %v", tc.itemID, tc.path, tc.code, err)
+		}
+	}
+}
+
+func TestRecycleBinServiceRestoreFolderFile(t *testing.T) {
+	svc, files, recycle := newDeletedFolderFixture(t)
+	ctx := context.Background()
+
+	out, err := svc.RestoreFolderFile(ctx, 8, 6, 12, RestoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 原目录仍在回收站中，文件回退到根目录。
+	if out.ItemID != 6 || out.OriginalID != 12 || out.FolderID != 1 || out.Path != "/b.txt" || !out.Relocated {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+	if files.restored[12]["folder_id"] != uint(1) {
+		t.Fatalf("unexpected restore updates: %+v", files.restored[12])
+	}
+	if len(recycle.deletedIDs) != 0 || len(recycle.deletedOriginalIDs) != 1 || recycle.deletedOriginalIDs[0] != 12 {
+		t.Fatalf("expected folder item kept and file records cleared, got deleted=%v original=%v", recycle.deletedIDs, recycle.deletedOriginalIDs)
+	}
+
+	target := uint(4)
+	out, err = svc.RestoreFolderFile(ctx, 8, 6, 11, RestoreOptions{TargetFolderID: &target})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.FolderID != 4 || out.Path != "/docs/a.txt" || out.Relocated {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+
+	_, err = svc.RestoreFolderFile(ctx, 8, 6, 14, RestoreOptions{})
+	if appErr, ok := err.(*AppError); !ok || appErr.HTTPCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for file outside the deleted folder, got %v", err)
+	}
+}
+
+func TestRecycleBinServiceGetFolderFileDownloadInfo(t *testing.T) {
+	svc, _, _ := newDeletedFolderFixture(t)
+	ctx := context.Background()
+
+	info, err := svc.GetFolderFileDownloadInfo(ctx, 8, 6, 11)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.DownloadName != "a.txt" || filepath.Base(info.AbsPath) != "o-11.bin" {
+		t.Fatalf("unexpected download info: %+v", info)
+	}
+
+	for _, fileID := range []uint{13, 14} {
+		_, err := svc.GetFolderFileDownloadInfo(ctx, 8, 6, fileID)
+		if appErr, ok := err.(*AppError); !ok || appErr.HTTPCode != http.StatusNotFound {
+			t.Fatalf("expected 404 for file %d, got %v", fileID, err)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mcloud/config"
+	"mcloud/logger"
+	"mcloud/metrics"
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// recycler 负责回收站条目的写入（快照 + 软删除）与彻删，供文件、目录和回收站服务共用。
+type recycler struct {
+	txManager   TxManager
+	users       repositories.UserRepository
+	folders     repositories.FolderRepository
+	files       repositories.FileRepository
+	fileObjects repositories.FileObjectRepository
+	recycle     repositories.RecycleBinRepository
+}
+
+// newRecycler 创建 recycler 实例。
+func newRecycler(
+	txManager TxManager,
+	users repositories.UserRepository,
+	folders repositories.FolderRepository,
+	files repositories.FileRepository,
+	fileObjects repositories.FileObjectRepository,
+	recycle repositories.RecycleBinRepository,
+) recycler {
+	return recycler{
+		txManager:   txManager,
+		users:       users,
+		folders:     folders,
+		files:       files,
+		fileObjects: fileObjects,
+		recycle:     recycle,
+	}
+}
+
+// recycleFile 软删除单个文件；回收站开启时先写入快照。file 需预加载 FileObject。
+func (r recycler) recycleFile(ctx context.Context, tx *gorm.DB, userID uint, file models.File) error {
+	if config.AppConfig.RecycleBin.Enabled {
+		// 回收快照保存文件对象关键信息，供恢复和彻删流程复用。
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"mime_type":      file.FileObject.MimeType,
+			"thumbnail_path": file.FileObject.ThumbnailPath,
+			"is_image":       file.FileObject.IsImage,
+			"width":          file.FileObject.Width,
+			"height":         file.FileObject.Height,
+			"file_md5":       file.FileObject.FileMD5,
+			"file_object_id": file.FileObjectID,
+		})
+		fileSize := file.FileObject.FileSize
+		folderID := file.FolderID
+		fileObjectID := file.FileObjectID
+		item := models.RecycleBinItem{
+			UserID:           userID,
+			OriginalID:       file.ID,
+			OriginalType:     "file",
+			OriginalName:     file.OriginalName,
+			OriginalPath:     file.FileObject.FilePath,
+			OriginalFullPath: r.fileFullPath(ctx, tx, userID, file),
+			OriginalFolderID: &folderID,
+			FileObjectID:     &fileObjectID,
+			FileSize:         &fileSize,
+			ExpiresAt:        r.expiresAt(ctx, tx, userID, time.Now()),
+			Metadata:         string(metadata),
+		}
+		if err := r.recycle.Create(ctx, tx, &item); err != nil {
+			return err
+		}
+	}
+	return r.files.SoftDeleteByIDAndUser(ctx, tx, file.ID, userID)
+}
+
+// recycleFolder 软删除目录及其子孙目录与文件；回收站开启时只为顶层目录写一条快照。
+func (r recycler) recycleFolder(ctx context.Context, tx *gorm.DB, userID uint, folder models.Folder) error {
+	if config.AppConfig.RecycleBin.Enabled {
+		parentIDVal := uint(0)
+		if folder.ParentID != nil {
+			parentIDVal = *folder.ParentID
+		}
+		recycleItem := models.RecycleBinItem{
+			UserID:           userID,
+			OriginalID:       folder.ID,
+			OriginalType:     "folder",
+			OriginalName:     folder.Name,
+			OriginalPath:     folder.Path,
+			OriginalFullPath: folder.Path,
+			OriginalFolderID: folder.ParentID,
+			ExpiresAt:        r.expiresAt(ctx, tx, userID, time.Now()),
+			Metadata:         fmt.Sprintf(`{"parent_id":%d}`, parentIDVal),
+		}
+		if err := r.recycle.Create(ctx, tx, &recycleItem); err != nil {
+			return err
+		}
+	}
+
+	affectedFolderIDs, err := r.folders.PluckIDsByPathPrefix(ctx, tx, userID, folder.ID, folder.Path)
+	if err != nil {
+		return err
+	}
+	if err := r.folders.SoftDeleteByPathPrefix(ctx, tx, userID, folder.ID, folder.Path); err != nil {
+		return err
+	}
+	if len(affectedFolderIDs) > 0 {
+		return r.files.SoftDeleteByFolderIDs(ctx, tx, userID, affectedFolderIDs)
+	}
+	return nil
+}
+
+// fileFullPath 拼出文件的逻辑完整路径（如 /docs/a.txt），供回收站按原路径筛选；所在目录查询失败时返回空串。
+func (r recycler) fileFullPath(ctx context.Context, tx *gorm.DB, userID uint, file models.File) string {
+	folder, err := r.folders.GetByIDAndUser(ctx, tx, file.FolderID, userID)
+	if err != nil {
+		return ""
+	}
+	return buildChildFolderPath(folder.Path, file.OriginalName)
+}
+
+// expiresAt 按用户生效的保留天数计算过期时间；读取用户设置失败时使用系统默认值。
+func (r recycler) expiresAt(ctx context.Context, tx *gorm.DB, userID uint, deletedAt time.Time) time.Time {
+	var custom *int
+	if user, err := r.users.GetByID(ctx, tx, userID); err == nil {
+		custom = user.RecycleRetentionDays
+	}
+	return deletedAt.AddDate(0, 0, effectiveRetentionDays(custom))
+}
+
+// effectiveRetentionDays 返回生效的保留天数：未自定义时取系统默认值，自定义值按管理员上下限收敛。
+func effectiveRetentionDays(custom *int) int {
+	cfg := config.AppConfig.RecycleBin
+	if custom == nil {
+		return cfg.RetentionDays
+	}
+	days := *custom
+	if cfg.MinRetentionDays > 0 && days < cfg.MinRetentionDays {
+		days = cfg.MinRetentionDays
+	}
+	if cfg.MaxRetentionDays > 0 && days > cfg.MaxRetentionDays {
+		days = cfg.MaxRetentionDays
+	}
+	return days
+}
+
+// purge 彻删条目对应的文件或目录树，回收站记录本身由调用方删除。
+func (r recycler) purge(ctx context.Context, tx *gorm.DB, userID uint, item *models.RecycleBinItem) error {
+	if item.OriginalType == "file" {
+		return r.permanentDeleteFile(ctx, tx, item, userID)
+	}
+	return r.permanentDeleteFolder(ctx, tx, item, userID)
+}
+
+// evictOverflow 在条目数超过 MaxItemsPerUser 时按删除时间从早到晚彻删多出的条目。
+// 每条使用独立事务，失败只记录日志，不影响触发它的删除操作。
+func (r recycler) evictOverflow(ctx context.Context, userID uint) {
+	limit := config.AppConfig.RecycleBin.MaxItemsPerUser
+	if !config.AppConfig.RecycleBin.Enabled || limit <= 0 {
+		return
+	}
+	total, err := r.recycle.CountByUser(ctx, nil, userID)
+	if err != nil {
+		warnOnError(ctx, "统计回收站条目", err)
+		return
+	}
+	overflow := int(total) - limit
+	if overflow <= 0 {
+		return
+	}
+	items, err := r.recycle.ListByFilter(ctx, nil, repositories.RecycleBinFilter{UserID: userID, Limit: overflow})
+	if err != nil {
+		warnOnError(ctx, "查询待淘汰回收站条目", err)
+		return
+	}
+
+	evicted := 0
+	for i := range items {
+		item := &items[i]
+		err := r.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+			if err := r.purge(ctx, tx, userID, item); err != nil {
+				return err
+			}
+			return r.recycle.DeleteByID(ctx, tx, item.ID)
+		})
+		if err != nil {
+			logger.Ctx(ctx).With("recycle_item_id", item.ID).Warnf("回收站超出上限自动彻删失败: %v", err)
+			continue
+		}
+		evicted++
+	}
+	metrics.ObserveCleanup("recycle_bin_overflow", evicted)
+}
+
+// permanentDeleteFile 彻删文件并更新用户空间与对象引用。
+func (r recycler) permanentDeleteFile(ctx context.Context, tx *gorm.DB, item *models.RecycleBinItem, userID uint) error {
+	file, err := r.files.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID, true)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// 尝试从实时记录读取对象信息；缺失时回退到回收站快照。
+	fileObjectID := uint(0)
+	fileSize := int64(0)
+	if err == nil {
+		fileObjectID = file.FileObjectID
+		fileSize = file.FileObject.FileSize
+	} else {
+		if item.FileObjectID != nil {
+			fileObjectID = *item.FileObjectID
+		}
+		if item.FileSize != nil {
+			fileSize = *item.FileSize
+		}
+	}
+
+	if err := r.files.UnscopedDeleteByIDAndUser(ctx, tx, item.OriginalID, userID); err != nil {
+		return err
+	}
+
+	if fileSize > 0 {
+		if err := r.users.SubStorageUsed(ctx, tx, userID, fileSize); err != nil {
+			return err
+		}
+	}
+
+	if fileObjectID > 0 {
+		if err := r.decrementFileObjectRef(ctx, tx, fileObjectID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// permanentDeleteFolder 彻删目录树及其文件。
+func (r recycler) permanentDeleteFolder(ctx context.Context, tx *gorm.DB, item *models.RecycleBinItem, userID uint) error {
+	rootFolder, err := r.folders.GetByIDAndUserUnscoped(ctx, tx, item.OriginalID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if rootFolder.IsRoot != nil && *rootFolder.IsRoot {
+		return fmt.Errorf("root folder cannot be deleted")
+	}
+
+	// 按路径前缀收集整个目录子树后统一删除。
+	folders, err := r.folders.ListByPathPrefix(ctx, tx, userID, rootFolder.ID, rootFolder.Path, true)
+	if err != nil {
+		return err
+	}
+	folders = deletedSubtree(folders, rootFolder.ID)
+	if len(folders) == 0 {
+		return nil
+	}
+
+	folderIDs := make([]uint, 0, len(folders))
+	for _, f := range folders {
+		folderIDs = append(folderIDs, f.ID)
+	}
+
+	files, err := r.files.ListByFolderIDs(ctx, tx, userID, folderIDs, true, true)
+	if err != nil {
+		return err
+	}
+
+	fileIDs := make([]uint, 0, len(files))
+	for i := range files {
+		// 目录内文件复用单文件彻删流程，避免逻辑分叉。
+		fileIDs = append(fileIDs, files[i].ID)
+		size := files[i].FileObject.FileSize
+		tmp := models.RecycleBinItem{OriginalID: files[i].ID, FileObjectID: &files[i].FileObjectID, FileSize: &size}
+		if err := r.permanentDeleteFile(ctx, tx, &tmp, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := r.folders.UnscopedDeleteByIDs(ctx, tx, folderIDs); err != nil {
+		return err
+	}
+	if err := r.recycle.DeleteByOriginalIDs(ctx, tx, userID, "file", fileIDs); err != nil {
+		return err
+	}
+	if err := r.recycle.DeleteByOriginalIDs(ctx, tx, userID, "folder", folderIDs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// decrementFileObjectRef 递减文件对象引用并在归零时清理物理资源。
+func (r recycler) decrementFileObjectRef(ctx context.Context, tx *gorm.DB, fileObjectID uint) error {
+	fileObj, err := r.fileObjects.GetByID(ctx, tx, fileObjectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if fileObj.RefCount <= 1 {
+		// 最后引用释放时清理物理文件及缩略图。
+		warnOnError(ctx, "删除物理文件", os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.FilePath)))
+		if fileObj.ThumbnailPath != "" {
+			warnOnError(ctx, "删除缩略图", os.Remove(filepath.Join(config.AppConfig.Storage.BasePath, fileObj.ThumbnailPath)))
+		}
+		removeImageVariants(ctx, fileObj.ID)
+		return r.fileObjects.DeleteByID(ctx, tx, fileObj.ID)
+	}
+
+	return r.fileObjects.DecrementRefCount(ctx, tx, fileObj.ID)
+}
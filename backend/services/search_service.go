@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"mcloud/repositories"
+	"mcloud/utils"
+)
+
+// SearchService 定义跨目录的全盘搜索。
+type SearchService interface {
+	// Search 按名称关键字、文件分类与标签搜索正常状态的目录与文件，先目录后文件分页返回。
+	Search(ctx context.Context, userID uint, in SearchQuery) (SearchOutput, error)
+}
+
+// SearchQuery 为全盘搜索参数，Keyword、Category 与标签至少提供一项。
+type SearchQuery struct {
+	Keyword string
+	// Category 只作用于文件，指定后不返回目录。
+	Category string
+	// Type 为 file 或 folder 时只搜索对应类型，空串表示都搜索。
+	Type     string
+	Tags     TagQuery
+	Page     int
+	PageSize int
+}
+
+// SearchOutput 为全盘搜索返回体。
+type SearchOutput struct {
+	Items      []EntryItem          `json:"items"`
+	Pagination utils.PaginationData `json:"pagination"`
+}
+
+type searchService struct {
+	folders     repositories.FolderRepository
+	files       repositories.FileRepository
+	tagAttacher tagAttacher
+}
+
+// NewSearchService 创建全盘搜索服务实例。
+func NewSearchService(folders repositories.FolderRepository, files repositories.FileRepository, tags repositories.TagRepository) SearchService {
+	return &searchService{folders: folders, files: files, tagAttacher: tagAttacher{tags: tags}}
+}
+
+func (s *searchService) Search(ctx context.Context, userID uint, in SearchQuery) (SearchOutput, error) {
+	if in.Type != "" && in.Type != EntryTypeFile && in.Type != EntryTypeFolder {
+		return SearchOutput{}, newAppError(http.StatusBadRequest, "无效的搜索类型", nil)
+	}
+	in.Keyword = strings.TrimSpace(in.Keyword)
+	filter, err := buildFileQuickFilter(in.Keyword, in.Category, in.Tags)
+	if err != nil {
+		return SearchOutput{}, err
+	}
+	if in.Keyword == "" && in.Category == "" && len(filter.Tags.TagIDs) == 0 {
+		return SearchOutput{}, newAppError(http.StatusBadRequest, "请提供搜索关键字、分类或标签", nil)
+	}
+	page, pageSize := in.Page, in.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := repositories.SearchInput{UserID: userID, Filter: filter}
+	searchFolders := in.Type != EntryTypeFile && in.Category == ""
+	searchFiles := in.Type != EntryTypeFolder
+
+	var folderTotal, fileTotal int64
+	if searchFolders {
+		if folderTotal, err = s.folders.CountSearch(ctx, nil, query); err != nil {
+			return SearchOutput{}, newAppError(http.StatusInternalServerError, "搜索文件夹失败", err)
+		}
+	}
+	if searchFiles {
+		if fileTotal, err = s.files.CountSearch(ctx, nil, query); err != nil {
+			return SearchOutput{}, newAppError(http.StatusInternalServerError, "搜索文件失败", err)
+		}
+	}
+
+	// 目录排在文件之前：偏移量先落在目录区间，不足一页时从第一个文件开始补齐。
+	offset := int64((page - 1) * pageSize)
+	items := make([]EntryItem, 0, pageSize)
+	if offset < folderTotal {
+		query.Offset, query.Limit = int(offset), pageSize
+		folders, err := s.folders.Search(ctx, nil, query)
+		if err != nil {
+			return SearchOutput{}, newAppError(http.StatusInternalServerError, "搜索文件夹失败", err)
+		}
+		s.tagAttacher.folders(ctx, userID, folders)
+		for _, folder := range folders {
+			items = append(items, EntryItem{Type: EntryTypeFolder, Folder: &FolderListItem{Folder: folder}})
+		}
+	}
+	if remaining := pageSize - len(items); remaining > 0 && offset+int64(len(items)) < folderTotal+fileTotal {
+		query.Offset, query.Limit = int(max(offset-folderTotal, 0)), remaining
+		files, err := s.files.Search(ctx, nil, query)
+		if err != nil {
+			return SearchOutput{}, newAppError(http.StatusInternalServerError, "搜索文件失败", err)
+		}
+		s.tagAttacher.files(ctx, userID, files)
+		for i := range files {
+			items = append(items, EntryItem{Type: EntryTypeFile, File: &files[i]})
+		}
+	}
+
+	total := folderTotal + fileTotal
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	return SearchOutput{
+		Items: items,
+		Pagination: utils.PaginationData{
+			Page:       page,
+			PageSize:   pageSize,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    page < totalPages,
+			HasPrev:    page > 1,
+		},
+	}, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// searchFolderRepo 按偏移量返回预置的目录搜索结果。
+type searchFolderRepo struct {
+	*fakeFolderRepo
+	results []models.Folder
+	calls   int
+}
+
+func (r *searchFolderRepo) CountSearch(context.Context, *gorm.DB, repositories.SearchInput) (int64, error) {
+	return int64(len(r.results)), nil
+}
+
+func (r *searchFolderRepo) Search(_ context.Context, _ *gorm.DB, in repositories.SearchInput) ([]models.Folder, error) {
+	r.calls++
+	return pageOf(r.results, in.Offset, in.Limit), nil
+}
+
+// searchFileRepo 按偏移量返回预置的文件搜索结果，并记录最近一次查询条件。
+type searchFileRepo struct {
+	*fakeFileRepo
+	results []models.File
+	last    repositories.SearchInput
+}
+
+func (r *searchFileRepo) CountSearch(context.Context, *gorm.DB, repositories.SearchInput) (int64, error) {
+	return int64(len(r.results)), nil
+}
+
+func (r *searchFileRepo) Search(_ context.Context, _ *gorm.DB, in repositories.SearchInput) ([]models.File, error) {
+	r.last = in
+	return pageOf(r.results, in.Offset, in.Limit), nil
+}
+
+func pageOf[T any](items []T, offset int, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
+
+func newSearchFixture() (*searchFolderRepo, *searchFileRepo, SearchService) {
+	folders := &searchFolderRepo{fakeFolderRepo: newFakeFolderRepo()}
+	for i := 0; i < 3; i++ {
+		folders.results = append(folders.results, models.Folder{ID: uint(10 + i), Name: fmt.Sprintf("d%d", i)})
+	}
+	files := &searchFileRepo{fakeFileRepo: newFakeFileRepo()}
+	for i := 0; i < 4; i++ {
+		files.results = append(files.results, models.File{ID: uint(20 + i), OriginalName: fmt.Sprintf("f%d", i)})
+	}
+	return folders, files, NewSearchService(folders, files, nil)
+}
+
+func TestSearchServicePagesFoldersThenFiles(t *testing.T) {
+	_, _, svc := newSearchFixture()
+	ctx := context.Background()
+
+	var pages []string
+	for page := 1; page <= 3; page++ {
+		out, err := svc.Search(ctx, 7, SearchQuery{Keyword: "x", Page: page, PageSize: 3})
+		if err != nil {
+			t.Fatalf("unexpected error on page %d: %v", page, err)
+		}
+		labels := make([]string, 0, len(out.Items))
+		for _, item := range out.Items {
+			if item.Type == EntryTypeFolder {
+				labels = append(labels, item.Folder.Name)
+			} else {
+				labels = append(labels, item.File.OriginalName)
+			}
+		}
+		pages = append(pages, fmt.Sprint(labels))
+		if out.Pagination.Total != 7 || out.Pagination.TotalPages != 3 {
+			t.Fatalf("unexpected pagination: %+v", out.Pagination)
+		}
+	}
+	if fmt.Sprint(pages) != "[[d0 d1 d2] [f0 f1 f2] [f3]]" {
+		t.Fatalf("unexpected pages: %v", pages)
+	}
+}
+
+func TestSearchServiceCategoryAndTagsRestrictToFiles(t *testing.T) {
+	folders, files, svc := newSearchFixture()
+	ctx := context.Background()
+
+	out, err := svc.Search(ctx, 7, SearchQuery{Category: "image", Tags: TagQuery{TagIDs: []uint{3, 1}, Mode: "and"}, PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if folders.calls != 0 || len(out.Items) != 4 || out.Items[0].Type != EntryTypeFile {
+		t.Fatalf("expected category search to skip folders, got %+v", out.Items)
+	}
+	if !files.last.Filter.Tags.MatchAll || fmt.Sprint(files.last.Filter.Tags.TagIDs) != "[1 3]" || len(files.last.Filter.MimePrefixes) == 0 {
+		t.Fatalf("unexpected file filter: %+v", files.last.Filter)
+	}
+
+	_, err = svc.Search(ctx, 7, SearchQuery{Keyword: "  "})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	_, err = svc.Search(ctx, 7, SearchQuery{Keyword: "x", Type: "album"})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 标签相关限制。
+const (
+	maxTagNameLength = 32
+	// maxTagFilterIDs 限制单次筛选的标签数量，避免 IN 列表与 AND 计数子查询过大。
+	maxTagFilterIDs = 20
+	// maxTagBindingTargets 限制单次批量打标签的目标数量。
+	maxTagBindingTargets = 500
+	defaultTagColor      = "#409EFF"
+)
+
+// 标签筛选模式。
+const (
+	TagModeAny = "or"
+	TagModeAll = "and"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// TagService 定义标签管理、批量打标签与按标签筛选所需的能力。
+type TagService interface {
+	// ListTags 按名称列出全部标签及各自绑定的正常状态条目数。
+	ListTags(ctx context.Context, userID uint) ([]TagItem, error)
+	CreateTag(ctx context.Context, userID uint, name string, color string) (models.Tag, error)
+	// UpdateTag 重命名或修改颜色，零值字段保持不变。
+	UpdateTag(ctx context.Context, userID uint, tagID uint, in UpdateTagInput) (models.Tag, error)
+	DeleteTag(ctx context.Context, userID uint, tagID uint) error
+	// MergeTags 将来源标签的绑定并入目标标签后删除来源标签。
+	MergeTags(ctx context.Context, userID uint, targetID uint, sourceIDs []uint) (models.Tag, error)
+	// BindTags 为一批文件与目录同时挂上一组标签，已有的绑定保持不变。
+	BindTags(ctx context.Context, userID uint, in TagBindingInput) error
+	// UnbindTags 从一批文件与目录上摘除一组标签。
+	UnbindTags(ctx context.Context, userID uint, in TagBindingInput) error
+}
+
+// TagItem 为标签列表中的一项。
+type TagItem struct {
+	models.Tag
+	ItemCount int64 `json:"item_count"`
+}
+
+// UpdateTagInput 为修改标签的参数，nil 表示不修改。
+type UpdateTagInput struct {
+	Name  *string
+	Color *string
+}
+
+// TagBindingInput 为批量打标签或摘标签的参数。
+type TagBindingInput struct {
+	TagIDs    []uint
+	FileIDs   []uint
+	FolderIDs []uint
+}
+
+// TagQuery 为列表与搜索接口的标签筛选参数，Mode 为 and 或 or（默认）。
+type TagQuery struct {
+	TagIDs []uint
+	Mode   string
+}
+
+// toFilter 校验筛选参数并转换为仓储条件，标签 ID 去重并排序。
+func (q TagQuery) toFilter() (repositories.TagFilter, error) {
+	mode := q.Mode
+	if mode == "" {
+		mode = TagModeAny
+	}
+	if mode != TagModeAny && mode != TagModeAll {
+		return repositories.TagFilter{}, newAppError(http.StatusBadRequest, "无效的标签筛选模式", nil)
+	}
+	ids := uniqueSortedIDs(q.TagIDs)
+	if len(ids) > maxTagFilterIDs {
+		return repositories.TagFilter{}, newAppError(http.StatusBadRequest, "筛选标签数量过多", nil)
+	}
+	return repositories.TagFilter{TagIDs: ids, MatchAll: mode == TagModeAll}, nil
+}
+
+// tagFilterKey 返回筛选条件的规范化表示，用于绑定分页游标。
+func tagFilterKey(f repositories.TagFilter) string {
+	if len(f.TagIDs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(f.TagIDs))
+	for _, id := range f.TagIDs {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	mode := TagModeAny
+	if f.MatchAll {
+		mode = TagModeAll
+	}
+	return mode + ":" + strings.Join(parts, ",")
+}
+
+func uniqueSortedIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// tagAttacher 为列表结果批量附加标签；标签只是附加信息，查询失败时记录日志后返回不带标签的结果。
+type tagAttacher struct {
+	tags repositories.TagRepository
+}
+
+func (a tagAttacher) load(ctx context.Context, userID uint, targetType string, ids []uint) map[uint][]models.Tag {
+	if a.tags == nil || len(ids) == 0 {
+		return nil
+	}
+	rows, err := a.tags.ListByTargets(ctx, nil, userID, targetType, ids)
+	if err != nil {
+		warnOnError(ctx, "查询标签", err)
+		return nil
+	}
+	byTarget := make(map[uint][]models.Tag, len(ids))
+	for _, row := range rows {
+		byTarget[row.TargetID] = append(byTarget[row.TargetID], row.Tag)
+	}
+	return byTarget
+}
+
+func (a tagAttacher) files(ctx context.Context, userID uint, files []models.File) {
+	ids := make([]uint, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+	byTarget := a.load(ctx, userID, models.TagTargetFile, ids)
+	for i := range files {
+		files[i].Tags = byTarget[files[i].ID]
+	}
+}
+
+func (a tagAttacher) folders(ctx context.Context, userID uint, folders []models.Folder) {
+	ids := make([]uint, 0, len(folders))
+	for _, folder := range folders {
+		ids = append(ids, folder.ID)
+	}
+	byTarget := a.load(ctx, userID, models.TagTargetFolder, ids)
+	for i := range folders {
+		folders[i].Tags = byTarget[folders[i].ID]
+	}
+}
+
+type tagService struct {
+	txManager TxManager
+	tags      repositories.TagRepository
+	folders   repositories.FolderRepository
+	files     repositories.FileRepository
+}
+
+// NewTagService 创建标签服务实例。
+func NewTagService(
+	txManager TxManager,
+	tags repositories.TagRepository,
+	folders repositories.FolderRepository,
+	files repositories.FileRepository,
+) TagService {
+	return &tagService{txManager: txManager, tags: tags, folders: folders, files: files}
+}
+
+func (s *tagService) ListTags(ctx context.Context, userID uint) ([]TagItem, error) {
+	usages, err := s.tags.ListUsageByUser(ctx, nil, userID)
+	if err != nil {
+		return nil, newAppError(http.StatusInternalServerError, "查询标签失败", err)
+	}
+	items := make([]TagItem, 0, len(usages))
+	for _, usage := range usages {
+		items = append(items, TagItem{Tag: usage.Tag, ItemCount: usage.ItemCount})
+	}
+	return items, nil
+}
+
+func (s *tagService) CreateTag(ctx context.Context, userID uint, name string, color string) (models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if color == "" {
+		color = defaultTagColor
+	}
+	if !tagColorPattern.MatchString(color) {
+		return models.Tag{}, newAppError(http.StatusBadRequest, "标签颜色须为 #RRGGBB 格式", nil)
+	}
+	if err := s.ensureNameAvailable(ctx, nil, userID, name, 0); err != nil {
+		return models.Tag{}, err
+	}
+
+	tag := models.Tag{UserID: userID, Name: name, Color: strings.ToUpper(color)}
+	if err := s.tags.Create(ctx, nil, &tag); err != nil {
+		return models.Tag{}, newAppError(http.StatusInternalServerError, "创建标签失败", err)
+	}
+	return tag, nil
+}
+
+func (s *tagService) UpdateTag(ctx context.Context, userID uint, tagID uint, in UpdateTagInput) (models.Tag, error) {
+	tag, err := s.getTag(ctx, nil, userID, tagID)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	updates := make(map[string]interface{})
+	if in.Name != nil {
+		name, err := normalizeTagName(*in.Name)
+		if err != nil {
+			return models.Tag{}, err
+		}
+		if name != tag.Name {
+			// 改成已存在的名称会产生重复标签，应改用合并。
+			if err := s.ensureNameAvailable(ctx, nil, userID, name, tag.ID); err != nil {
+				return models.Tag{}, err
+			}
+			updates["name"] = name
+			tag.Name = name
+		}
+	}
+	if in.Color != nil {
+		if !tagColorPattern.MatchString(*in.Color) {
+			return models.Tag{}, newAppError(http.StatusBadRequest, "标签颜色须为 #RRGGBB 格式", nil)
+		}
+		color := strings.ToUpper(*in.Color)
+		if color != tag.Color {
+			updates["color"] = color
+			tag.Color = color
+		}
+	}
+	if len(updates) == 0 {
+		return tag, nil
+	}
+	if err := s.tags.UpdateByID(ctx, nil, tag.ID, updates); err != nil {
+		return models.Tag{}, newAppError(http.StatusInternalServerError, "更新标签失败", err)
+	}
+	return tag, nil
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, userID uint, tagID uint) error {
+	if _, err := s.getTag(ctx, nil, userID, tagID); err != nil {
+		return err
+	}
+	err := s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return s.tags.DeleteByIDs(ctx, tx, userID, []uint{tagID})
+	})
+	if err != nil {
+		return newAppError(http.StatusInternalServerError, "删除标签失败", err)
+	}
+	return nil
+}
+
+func (s *tagService) MergeTags(ctx context.Context, userID uint, targetID uint, sourceIDs []uint) (models.Tag, error) {
+	sources := make([]uint, 0, len(sourceIDs))
+	for _, id := range uniqueSortedIDs(sourceIDs) {
+		if id != targetID {
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return models.Tag{}, newAppError(http.StatusBadRequest, "请指定要合并的其他标签", nil)
+	}
+
+	target, err := s.getTag(ctx, nil, userID, targetID)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	found, err := s.tags.GetByIDsAndUser(ctx, nil, userID, sources)
+	if err != nil {
+		return models.Tag{}, newAppError(http.StatusInternalServerError, "查询标签失败", err)
+	}
+	if len(found) != len(sources) {
+		return models.Tag{}, newAppError(http.StatusNotFound, "标签不存在", nil)
+	}
+
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.tags.MergeBindings(ctx, tx, userID, target.ID, sources); err != nil {
+			return err
+		}
+		return s.tags.DeleteByIDs(ctx, tx, userID, sources)
+	})
+	if err != nil {
+		return models.Tag{}, newAppError(http.StatusInternalServerError, "合并标签失败", err)
+	}
+	return target, nil
+}
+
+func (s *tagService) BindTags(ctx context.Context, userID uint, in TagBindingInput) error {
+	in, err := s.validateBindingInput(ctx, userID, in)
+	if err != nil {
+		return err
+	}
+
+	bindings := make([]models.TagBinding, 0, len(in.TagIDs)*(len(in.FileIDs)+len(in.FolderIDs)))
+	for _, tagID := range in.TagIDs {
+		for _, fileID := range in.FileIDs {
+			bindings = append(bindings, models.TagBinding{UserID: userID, TagID: tagID, TargetType: models.TagTargetFile, TargetID: fileID})
+		}
+		for _, folderID := range in.FolderIDs {
+			bindings = append(bindings, models.TagBinding{UserID: userID, TagID: tagID, TargetType: models.TagTargetFolder, TargetID: folderID})
+		}
+	}
+	if err := s.tags.Bind(ctx, nil, bindings); err != nil {
+		return newAppError(http.StatusInternalServerError, "添加标签失败", err)
+	}
+	return nil
+}
+
+func (s *tagService) UnbindTags(ctx context.Context, userID uint, in TagBindingInput) error {
+	in, err := s.validateBindingInput(ctx, userID, in)
+	if err != nil {
+		return err
+	}
+	err = s.txManager.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if _, err := s.tags.Unbind(ctx, tx, userID, in.TagIDs, models.TagTargetFile, in.FileIDs); err != nil {
+			return err
+		}
+		_, err := s.tags.Unbind(ctx, tx, userID, in.TagIDs, models.TagTargetFolder, in.FolderIDs)
+		return err
+	})
+	if err != nil {
+		return newAppError(http.StatusInternalServerError, "移除标签失败", err)
+	}
+	return nil
+}
+
+// validateBindingInput 去重后校验标签与目标均属于当前用户且处于正常状态。
+func (s *tagService) validateBindingInput(ctx context.Context, userID uint, in TagBindingInput) (TagBindingInput, error) {
+	in = TagBindingInput{
+		TagIDs:    uniqueSortedIDs(in.TagIDs),
+		FileIDs:   uniqueSortedIDs(in.FileIDs),
+		FolderIDs: uniqueSortedIDs(in.FolderIDs),
+	}
+	if len(in.TagIDs) == 0 || len(in.FileIDs)+len(in.FolderIDs) == 0 {
+		return in, newAppError(http.StatusBadRequest, "请指定标签与文件或文件夹", nil)
+	}
+	if len(in.TagIDs) > maxTagFilterIDs || len(in.FileIDs)+len(in.FolderIDs) > maxTagBindingTargets {
+		return in, newAppError(http.StatusBadRequest, "单次操作的条目数量过多", nil)
+	}
+
+	tags, err := s.tags.GetByIDsAndUser(ctx, nil, userID, in.TagIDs)
+	if err != nil {
+		return in, newAppError(http.StatusInternalServerError, "查询标签失败", err)
+	}
+	if len(tags) != len(in.TagIDs) {
+		return in, newAppError(http.StatusNotFound, "标签不存在", nil)
+	}
+	if len(in.FileIDs) > 0 {
+		files, err := s.files.GetByIDsAndUser(ctx, nil, userID, in.FileIDs, false)
+		if err != nil {
+			return in, newAppError(http.StatusInternalServerError, "查询文件失败", err)
+		}
+		if len(files) != len(in.FileIDs) {
+			return in, newAppError(http.StatusNotFound, "部分文件不存在", nil)
+		}
+	}
+	if len(in.FolderIDs) > 0 {
+		folders, err := s.folders.GetByIDsAndUser(ctx, nil, userID, in.FolderIDs)
+		if err != nil {
+			return in, newAppError(http.StatusInternalServerError, "查询文件夹失败", err)
+		}
+		if len(folders) != len(in.FolderIDs) {
+			return in, newAppError(http.StatusNotFound, "部分文件夹不存在", nil)
+		}
+	}
+	return in, nil
+}
+
+func (s *tagService) getTag(ctx context.Context, tx *gorm.DB, userID uint, tagID uint) (models.Tag, error) {
+	tag, err := s.tags.GetByIDAndUser(ctx, tx, tagID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Tag{}, newAppError(http.StatusNotFound, "标签不存在", nil)
+		}
+		return models.Tag{}, newAppError(http.StatusInternalServerError, "查询标签失败", err)
+	}
+	return tag, nil
+}
+
+func (s *tagService) ensureNameAvailable(ctx context.Context, tx *gorm.DB, userID uint, name string, excludeID uint) error {
+	count, err := s.tags.CountByUserAndName(ctx, tx, userID, name, excludeID)
+	if err != nil {
+		return newAppError(http.StatusInternalServerError, "校验标签名称失败", err)
+	}
+	if count > 0 {
+		return newAppError(http.StatusConflict, "同名标签已存在，可使用合并", nil)
+	}
+	return nil
+}
+
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", newAppError(http.StatusBadRequest, "标签名称不能为空", nil)
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", newAppError(http.StatusBadRequest, "标签名称过长", nil)
+	}
+	return name, nil
+}
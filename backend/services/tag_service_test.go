@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"mcloud/models"
+	"mcloud/repositories"
+
+	"gorm.io/gorm"
+)
+
+// fakeTagRepo 在内存中保存标签，并记录绑定、合并与删除调用。
+type fakeTagRepo struct {
+	tags     map[uint]models.Tag
+	nextID   uint
+	bound    []models.TagBinding
+	merged   []uint
+	deleted  []uint
+	updates  map[string]interface{}
+	byTarget []repositories.TargetTag
+}
+
+func newFakeTagRepo(tags ...models.Tag) *fakeTagRepo {
+	repo := &fakeTagRepo{tags: map[uint]models.Tag{}, nextID: 100}
+	for _, tag := range tags {
+		repo.tags[tag.ID] = tag
+	}
+	return repo
+}
+
+func (r *fakeTagRepo) Create(_ context.Context, _ *gorm.DB, tag *models.Tag) error {
+	r.nextID++
+	tag.ID = r.nextID
+	r.tags[tag.ID] = *tag
+	return nil
+}
+
+func (r *fakeTagRepo) GetByIDAndUser(_ context.Context, _ *gorm.DB, tagID uint, userID uint) (models.Tag, error) {
+	tag, ok := r.tags[tagID]
+	if !ok || tag.UserID != userID {
+		return models.Tag{}, gorm.ErrRecordNotFound
+	}
+	return tag, nil
+}
+
+func (r *fakeTagRepo) GetByIDsAndUser(_ context.Context, _ *gorm.DB, userID uint, tagIDs []uint) ([]models.Tag, error) {
+	out := make([]models.Tag, 0)
+	for _, id := range tagIDs {
+		if tag, ok := r.tags[id]; ok && tag.UserID == userID {
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeTagRepo) CountByUserAndName(_ context.Context, _ *gorm.DB, userID uint, name string, excludeID uint) (int64, error) {
+	var count int64
+	for _, tag := range r.tags {
+		if tag.UserID == userID && tag.Name == name && tag.ID != excludeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeTagRepo) ListUsageByUser(context.Context, *gorm.DB, uint) ([]repositories.TagUsage, error) {
+	return nil, nil
+}
+
+func (r *fakeTagRepo) UpdateByID(_ context.Context, _ *gorm.DB, _ uint, updates map[string]interface{}) error {
+	r.updates = updates
+	return nil
+}
+
+func (r *fakeTagRepo) DeleteByIDs(_ context.Context, _ *gorm.DB, _ uint, tagIDs []uint) error {
+	r.deleted = append(r.deleted, tagIDs...)
+	return nil
+}
+
+func (r *fakeTagRepo) Bind(_ context.Context, _ *gorm.DB, bindings []models.TagBinding) error {
+	r.bound = append(r.bound, bindings...)
+	return nil
+}
+
+func (r *fakeTagRepo) Unbind(context.Context, *gorm.DB, uint, []uint, string, []uint) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeTagRepo) MergeBindings(_ context.Context, _ *gorm.DB, _ uint, _ uint, sourceTagIDs []uint) error {
+	r.merged = append(r.merged, sourceTagIDs...)
+	return nil
+}
+
+func (r *fakeTagRepo) ListByTargets(context.Context, *gorm.DB, uint, string, []uint) ([]repositories.TargetTag, error) {
+	return r.byTarget, nil
+}
+
+func (r *fakeTagRepo) DeleteOrphanBindings(context.Context, *gorm.DB) (int64, error) {
+	return 0, nil
+}
+
+func newTagServiceFixture() (*tagService, *fakeTagRepo) {
+	tags := newFakeTagRepo(
+		models.Tag{ID: 1, UserID: 7, Name: "work", Color: "#409EFF"},
+		models.Tag{ID: 2, UserID: 7, Name: "urgent", Color: "#F56C6C"},
+		models.Tag{ID: 3, UserID: 8, Name: "others", Color: "#409EFF"},
+	)
+	_, files, _, _ := newQuickAccessFixture()
+	folders := &quickAccessFolderRepo{
+		fakeFolderRepo: newFakeFolderRepo(),
+		folders:        map[uint]models.Folder{5: {ID: 5, UserID: 7, Name: "docs"}},
+	}
+	return NewTagService(fakeTxManager{}, tags, folders, files).(*tagService), tags
+}
+
+func TestTagQueryToFilter(t *testing.T) {
+	filter, err := TagQuery{TagIDs: []uint{5, 2, 5, 0}, Mode: "and"}.toFilter()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(filter.TagIDs) != "[2 5]" || !filter.MatchAll || tagFilterKey(filter) != "and:2,5" {
+		t.Fatalf("unexpected filter: %+v key=%q", filter, tagFilterKey(filter))
+	}
+
+	_, err = TagQuery{TagIDs: []uint{1}, Mode: "xor"}.toFilter()
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+
+	many := make([]uint, maxTagFilterIDs+1)
+	for i := range many {
+		many[i] = uint(i + 1)
+	}
+	_, err = TagQuery{TagIDs: many}.toFilter()
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+}
+
+func TestTagServiceCreateTagValidatesNameAndColor(t *testing.T) {
+	svc, _ := newTagServiceFixture()
+	ctx := context.Background()
+
+	_, err := svc.CreateTag(ctx, 7, "   ", "")
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	_, err = svc.CreateTag(ctx, 7, "photos", "red")
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	_, err = svc.CreateTag(ctx, 7, " work ", "")
+	assertAppErrorCode(t, err, http.StatusConflict)
+
+	// 其他用户的同名标签不冲突。
+	tag, err := svc.CreateTag(ctx, 7, "others", "#67c23a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag.ID == 0 || tag.Color != "#67C23A" {
+		t.Fatalf("unexpected tag: %+v", tag)
+	}
+}
+
+func TestTagServiceUpdateTagRejectsExistingName(t *testing.T) {
+	svc, tags := newTagServiceFixture()
+	ctx := context.Background()
+
+	name := "urgent"
+	_, err := svc.UpdateTag(ctx, 7, 1, UpdateTagInput{Name: &name})
+	assertAppErrorCode(t, err, http.StatusConflict)
+
+	name, color := "job", "#E6A23C"
+	tag, err := svc.UpdateTag(ctx, 7, 1, UpdateTagInput{Name: &name, Color: &color})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag.Name != "job" || tags.updates["name"] != "job" || tags.updates["color"] != "#E6A23C" {
+		t.Fatalf("unexpected update: tag=%+v updates=%+v", tag, tags.updates)
+	}
+
+	_, err = svc.UpdateTag(ctx, 7, 3, UpdateTagInput{Name: &name})
+	assertAppErrorCode(t, err, http.StatusNotFound)
+}
+
+func TestTagServiceMergeTagsDropsTargetFromSources(t *testing.T) {
+	svc, tags := newTagServiceFixture()
+	ctx := context.Background()
+
+	_, err := svc.MergeTags(ctx, 7, 1, []uint{1})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	_, err = svc.MergeTags(ctx, 7, 1, []uint{3})
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	if len(tags.merged) != 0 || len(tags.deleted) != 0 {
+		t.Fatalf("expected nothing merged on validation failure")
+	}
+
+	target, err := svc.MergeTags(ctx, 7, 1, []uint{2, 1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.ID != 1 || fmt.Sprint(tags.merged) != "[2]" || fmt.Sprint(tags.deleted) != "[2]" {
+		t.Fatalf("unexpected merge: target=%+v merged=%v deleted=%v", target, tags.merged, tags.deleted)
+	}
+}
+
+func TestTagServiceBindTagsValidatesOwnershipAndBindsAll(t *testing.T) {
+	svc, tags := newTagServiceFixture()
+	ctx := context.Background()
+
+	err := svc.BindTags(ctx, 7, TagBindingInput{TagIDs: []uint{1}})
+	assertAppErrorCode(t, err, http.StatusBadRequest)
+	// 他人的标签与文件都视为不存在。
+	err = svc.BindTags(ctx, 7, TagBindingInput{TagIDs: []uint{3}, FileIDs: []uint{1}})
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	err = svc.BindTags(ctx, 7, TagBindingInput{TagIDs: []uint{1}, FileIDs: []uint{1, 2}})
+	assertAppErrorCode(t, err, http.StatusNotFound)
+	if len(tags.bound) != 0 {
+		t.Fatalf("expected no bindings on validation failure, got %+v", tags.bound)
+	}
+
+	if err := svc.BindTags(ctx, 7, TagBindingInput{TagIDs: []uint{1, 2, 1}, FileIDs: []uint{1}, FolderIDs: []uint{5}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tags.bound) != 4 {
+		t.Fatalf("expected 2 tags x 2 targets, got %+v", tags.bound)
+	}
+	for _, binding := range tags.bound {
+		if binding.UserID != 7 || (binding.TargetType == models.TagTargetFolder) != (binding.TargetID == 5) {
+			t.Fatalf("unexpected binding: %+v", binding)
+		}
+	}
+}
+
+func TestTagAttacherGroupsTagsByTarget(t *testing.T) {
+	tags := newFakeTagRepo()
+	tags.byTarget = []repositories.TargetTag{
+		{TargetID: 1, Tag: models.Tag{ID: 1, Name: "a"}},
+		{TargetID: 1, Tag: models.Tag{ID: 2, Name: "b"}},
+		{TargetID: 3, Tag: models.Tag{ID: 1, Name: "a"}},
+	}
+	files := []models.File{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	tagAttacher{tags: tags}.files(context.Background(), 7, files)
+
+	if len(files[0].Tags) != 2 || files[1].Tags != nil || len(files[2].Tags) != 1 {
+		t.Fatalf("unexpected tags: %+v", files)
+	}
+	// 未注入标签仓储时不附加标签。
+	plain := []models.File{{ID: 1}}
+	tagAttacher{}.files(context.Background(), 7, plain)
+	if plain[0].Tags != nil {
+		t.Fatalf("expected no tags without repository, got %+v", plain[0].Tags)
+	}
+}
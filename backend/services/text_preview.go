@@ -0,0 +1,446 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+const (
+	// defaultTextPreviewBytes 与 maxTextPreviewBytes 为按字节分页时单页的默认与最大原始字节数。
+	defaultTextPreviewBytes = 64 << 10
+	maxTextPreviewBytes     = 1 << 20
+	// defaultTextPreviewLines 与 maxTextPreviewLines 为按行分页时单页的默认与最大行数，单页内容同样不超过 maxTextPreviewBytes。
+	defaultTextPreviewLines = 200
+	maxTextPreviewLines     = 5000
+	// textSniffBytes 为识别编码时读取的文件头字节数，各页共用同一识别结果。
+	textSniffBytes = 64 << 10
+	// maxMarkdownRenderBytes 为渲染 Markdown 的单页内容上限，超过时只返回原文，由调用方缩小分页后重试。
+	maxMarkdownRenderBytes = 256 << 10
+)
+
+// 识别出的编码名称，均为 htmlindex 可解析的标签。
+const (
+	textCharsetUTF8    = "utf-8"
+	textCharsetUTF16LE = "utf-16le"
+	textCharsetUTF16BE = "utf-16be"
+	textCharsetGBK     = "gbk"
+	textCharsetLatin   = "windows-1252"
+)
+
+var errNotTextFile = errors.New("文件不是文本文件")
+
+// textLanguageByExt 为按扩展名给出的语言提示，取值与常见前端高亮库的语言名一致。
+var textLanguageByExt = map[string]string{
+	".txt": "plaintext", ".log": "log", ".md": "markdown", ".markdown": "markdown",
+	".go": "go", ".py": "python", ".js": "javascript", ".mjs": "javascript", ".jsx": "javascript",
+	".ts": "typescript", ".tsx": "typescript", ".vue": "vue", ".java": "java", ".kt": "kotlin",
+	".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".rs": "rust", ".rb": "ruby", ".php": "php", ".swift": "swift", ".lua": "lua",
+	".sh": "bash", ".bash": "bash", ".zsh": "bash", ".ps1": "powershell", ".bat": "dos",
+	".sql": "sql", ".json": "json", ".xml": "xml", ".html": "html", ".htm": "html", ".svg": "xml",
+	".css": "css", ".scss": "scss", ".less": "less",
+	".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".ini": "ini", ".conf": "ini", ".properties": "properties",
+	".csv": "csv", ".diff": "diff", ".patch": "diff",
+}
+
+// textLanguageByName 为没有扩展名的常见文件。
+var textLanguageByName = map[string]string{
+	"dockerfile": "dockerfile",
+	"makefile":   "makefile",
+}
+
+// TextPreviewInput 为文本预览参数；StartLine 大于 0 时按行分页，否则按字节偏移分页。Charset 非空时跳过自动识别。
+// LineOffset 为上一页返回的 NextLineOffset，按行翻页时传回即可直接定位，不必从文件头重新扫描。
+type TextPreviewInput struct {
+	Offset     int64
+	Limit      int
+	StartLine  int
+	LineOffset int64
+	Lines      int
+	Charset    string
+}
+
+// TextPreviewOutput 为一页转码为 UTF-8 的文本。按字节分页时 NextOffset 为下一页的原始字节偏移，总在字符边界上；
+// 按行分页时 NextLine 为下一页的起始行号，NextLineOffset 为其原始字节偏移；Truncated 表示本页在行中间结束，
+// 下一页从同一行的剩余部分开始。Markdown 文件附带由本页内容渲染的 HTML，单页超过 256KB 时不渲染。
+type TextPreviewOutput struct {
+	Name           string `json:"name"`
+	Size           int64  `json:"size"`
+	Charset        string `json:"charset"`
+	Language       string `json:"language"`
+	Content        string `json:"content"`
+	Offset         int64  `json:"offset"`
+	NextOffset     int64  `json:"next_offset,omitempty"`
+	StartLine      int    `json:"start_line,omitempty"`
+	NextLine       int    `json:"next_line,omitempty"`
+	NextLineOffset int64  `json:"next_line_offset,omitempty"`
+	HasMore        bool   `json:"has_more"`
+	Truncated      bool   `json:"truncated,omitempty"`
+	HTML           string `json:"html,omitempty"`
+}
+
+// GetTextPreview 返回文本文件的一页内容：识别编码并转码为 UTF-8，大文件只读取所需的部分。
+func (s *fileService) GetTextPreview(ctx context.Context, userID uint, fileID uint, in TextPreviewInput) (TextPreviewOutput, error) {
+	if in.Offset < 0 || in.Limit < 0 || in.Limit > maxTextPreviewBytes || in.StartLine < 0 || in.LineOffset < 0 || in.Lines < 0 || in.Lines > maxTextPreviewLines {
+		return TextPreviewOutput{}, newAppError(http.StatusBadRequest, "无效的分页参数", nil)
+	}
+	info, err := s.getFileAccessInfo(ctx, userID, fileID)
+	if err != nil {
+		return TextPreviewOutput{}, err
+	}
+	f, err := os.Open(info.AbsPath)
+	if err != nil {
+		return TextPreviewOutput{}, newAppError(http.StatusInternalServerError, "打开文件失败", err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return TextPreviewOutput{}, newAppError(http.StatusInternalServerError, "读取文件信息失败", err)
+	}
+
+	sample := make([]byte, min(stat.Size(), textSniffBytes))
+	if _, err := io.ReadFull(f, sample); err != nil {
+		return TextPreviewOutput{}, newAppError(http.StatusInternalServerError, "读取文件失败", err)
+	}
+	detected, bomLen, err := detectTextCharset(sample, int64(len(sample)) == stat.Size())
+	// 指定编码时不做二进制判断，没有 BOM 的 UTF-16 文本含 NUL 字节，只能由调用方指定。
+	if err != nil && in.Charset == "" {
+		return TextPreviewOutput{}, newAppError(http.StatusUnsupportedMediaType, "文件不是文本文件", err)
+	}
+	charset := detected
+	if in.Charset != "" {
+		charset = in.Charset
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return TextPreviewOutput{}, newAppError(http.StatusBadRequest, "不支持的字符编码", err)
+	}
+	if name, err := htmlindex.Name(enc); err == nil {
+		charset = name
+	}
+	if charset != detected {
+		bomLen = 0
+	}
+
+	out := TextPreviewOutput{
+		Name:     info.File.OriginalName,
+		Size:     stat.Size(),
+		Charset:  charset,
+		Language: textLanguage(info.File.OriginalName),
+	}
+	if in.StartLine > 0 {
+		err = readTextLines(f, stat.Size(), bomLen, charset, enc, in, &out)
+	} else {
+		err = readTextBytes(f, stat.Size(), bomLen, charset, enc, in, &out)
+	}
+	if err != nil {
+		return TextPreviewOutput{}, newAppError(http.StatusInternalServerError, "读取文件失败", err)
+	}
+	if out.Language == "markdown" && len(out.Content) <= maxMarkdownRenderBytes {
+		out.HTML = renderMarkdown(out.Content)
+	}
+	return out, nil
+}
+
+// readTextBytes 读取 [offset, offset+limit) 的原始字节并对齐到字符边界后转码；页尾不完整的字符留到下一页。
+func readTextBytes(f *os.File, size int64, bomLen int64, charset string, enc encoding.Encoding, in TextPreviewInput, out *TextPreviewOutput) error {
+	limit := in.Limit
+	if limit == 0 {
+		limit = defaultTextPreviewBytes
+	}
+	offset := max(in.Offset, bomLen)
+	if offset >= size {
+		out.Offset = size
+		return nil
+	}
+
+	// 多读几个字节，用于跳过起始处被截断的字符与补全页尾字符。
+	buf := make([]byte, min(int64(limit)+4, size-offset))
+	if _, err := f.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	skip := textChunkStart(charset, buf, offset-bomLen)
+	buf = buf[skip:]
+	offset += int64(skip)
+	eof := offset+int64(len(buf)) >= size
+	if len(buf) > limit {
+		buf, eof = buf[:limit], false
+	}
+	n := textChunkEnd(charset, buf, eof)
+	if n == 0 && len(buf) > 0 {
+		// limit 小于单个字符的长度时至少返回一个字符，保证分页能够前进。
+		n = len(buf)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(buf[:n])
+	if err != nil {
+		return err
+	}
+	out.Content = string(decoded)
+	out.Offset = offset
+	out.HasMore = offset+int64(n) < size
+	if out.HasMore {
+		out.NextOffset = offset + int64(n)
+	}
+	return nil
+}
+
+// readTextLines 按行读取原始字节，按编码识别换行后整页转码。传入 LineOffset 时直接从该处开始，否则从文件头跳过前面的行。
+// 单页原始字节达到上限时立即停止，超长的行在页尾截断，下一页从 NextLineOffset 继续同一行，不再读完整行。
+func readTextLines(f *os.File, size int64, bomLen int64, charset string, enc encoding.Encoding, in TextPreviewInput, out *TextPreviewOutput) error {
+	lines := in.Lines
+	if lines == 0 {
+		lines = defaultTextPreviewLines
+	}
+	// 非 UTF-8 编码转码后最多膨胀为 3 倍，按比例收紧原始字节预算，保证转码后的内容不超过 maxTextPreviewBytes。
+	budget := maxTextPreviewBytes
+	if charset != textCharsetUTF8 {
+		budget /= 3
+	}
+	start, line := bomLen, 1
+	if in.LineOffset > 0 {
+		start, line = min(max(in.LineOffset, bomLen), size), in.StartLine
+		head := make([]byte, min(utf8.UTFMax, size-start))
+		if _, err := f.ReadAt(head, start); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		start += int64(textChunkStart(charset, head, start-bomLen))
+	}
+	out.StartLine = in.StartLine
+
+	r := bufio.NewReader(io.NewSectionReader(f, start, size-start))
+	pos := start
+	for line < in.StartLine {
+		n, found, err := skipTextLine(r, charset, pos-bomLen)
+		pos += n
+		if found {
+			line++
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	page := make([]byte, 0, min(int64(budget), size-pos))
+	taken, lineStart, eof := 0, true, false
+	for taken < lines {
+		if len(page) >= budget {
+			out.Truncated = !lineStart
+			break
+		}
+		b, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			eof = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+		prev := byte(0xFF)
+		if len(page) > 0 {
+			prev = page[len(page)-1]
+		}
+		page = append(page, b)
+		lineStart = textLineEnd(charset, prev, b, pos-bomLen+int64(len(page)))
+		if lineStart {
+			taken++
+			line++
+		}
+	}
+
+	n := textChunkEnd(charset, page, eof)
+	if n == 0 && len(page) > 0 {
+		n = len(page)
+	}
+	decoded, err := enc.NewDecoder().Bytes(page[:n])
+	if err != nil {
+		return err
+	}
+	out.Content = string(decoded)
+	if next := pos + int64(n); next < size {
+		out.HasMore = true
+		out.NextLine = line
+		out.NextLineOffset = next
+	}
+	return nil
+}
+
+// skipTextLine 跳过一行，返回消耗的字节数以及是否遇到换行；rel 为当前位置相对正文开头的偏移。
+func skipTextLine(r *bufio.Reader, charset string, rel int64) (int64, bool, error) {
+	if charset != textCharsetUTF16LE && charset != textCharsetUTF16BE {
+		var n int64
+		for {
+			chunk, err := r.ReadSlice('\n')
+			n += int64(len(chunk))
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				return n, err == nil, err
+			}
+		}
+	}
+	var n int64
+	prev := byte(0xFF)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return n, false, err
+		}
+		n++
+		if textLineEnd(charset, prev, b, rel+n) {
+			return n, true, nil
+		}
+		prev = b
+	}
+}
+
+// textLineEnd 判断读到 cur 时是否恰好结束一行；relEnd 为 cur 之后相对正文开头的偏移。
+// UTF-16 的换行为对齐的双字节码元，其余编码的多字节字符都不含 0x0A，按单字节判断即可。
+func textLineEnd(charset string, prev byte, cur byte, relEnd int64) bool {
+	switch charset {
+	case textCharsetUTF16LE:
+		return relEnd%2 == 0 && prev == '\n' && cur == 0
+	case textCharsetUTF16BE:
+		return relEnd%2 == 0 && prev == 0 && cur == '\n'
+	}
+	return cur == '\n'
+}
+
+// detectTextCharset 依次按 BOM、UTF-8 校验、GBK 双字节结构识别编码，都不符合时按 Windows-1252 处理；
+// 含 NUL 字节且没有 UTF-16 BOM 的内容视为二进制文件。complete 表示 sample 为完整文件，末尾不完整的字符视为无效。
+func detectTextCharset(sample []byte, complete bool) (string, int64, error) {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return textCharsetUTF8, 3, nil
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return textCharsetUTF16LE, 2, nil
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return textCharsetUTF16BE, 2, nil
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return "", 0, errNotTextFile
+	}
+	if !complete {
+		sample = sample[:textChunkEnd(textCharsetUTF8, sample, false)]
+	}
+	if utf8.Valid(sample) {
+		return textCharsetUTF8, 0, nil
+	}
+	if validGBK(sample, complete) {
+		return textCharsetGBK, 0, nil
+	}
+	return textCharsetLatin, 0, nil
+}
+
+// validGBK 校验字节流是否符合 GBK 的双字节结构：首字节 0x81-0xFE，尾字节 0x40-0xFE 且不为 0x7F。
+func validGBK(b []byte, complete bool) bool {
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			i++
+		case c >= 0x81 && c <= 0xFE:
+			if i+1 >= len(b) {
+				return !complete
+			}
+			if t := b[i+1]; t < 0x40 || t == 0x7F || t == 0xFF {
+				return false
+			}
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// textChunkStart 返回页首需要跳过的字节数：UTF-8 跳过续字节，UTF-16 对齐到偶数偏移。GBK 无法从中间判断字符边界，
+// 依赖调用方使用上一页返回的 NextOffset。
+func textChunkStart(charset string, b []byte, relOffset int64) int {
+	switch charset {
+	case textCharsetUTF8:
+		i := 0
+		for i < len(b) && i < utf8.UTFMax-1 && !utf8.RuneStart(b[i]) {
+			i++
+		}
+		return i
+	case textCharsetUTF16LE, textCharsetUTF16BE:
+		return int(relOffset % 2)
+	}
+	return 0
+}
+
+// textChunkEnd 返回 b 中完整字符占用的字节数；eof 为 true 时 b 已到文件末尾，原样返回全部字节交给解码器处理。
+func textChunkEnd(charset string, b []byte, eof bool) int {
+	if eof {
+		return len(b)
+	}
+	switch charset {
+	case textCharsetUTF8:
+		for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(b[i]) {
+				if utf8.FullRune(b[i:]) {
+					return len(b)
+				}
+				return i
+			}
+		}
+		return len(b)
+	case textCharsetUTF16LE, textCharsetUTF16BE:
+		n := len(b) &^ 1
+		if n >= 2 {
+			unit := b[n-2 : n]
+			hi := unit[1]
+			if charset == textCharsetUTF16BE {
+				hi = unit[0]
+			}
+			// 页尾为代理对的前半部分时留到下一页。
+			if hi >= 0xD8 && hi <= 0xDB {
+				n -= 2
+			}
+		}
+		return n
+	case textCharsetGBK, "gb18030":
+		i := 0
+		for i < len(b) {
+			if b[i] < 0x81 || b[i] == 0xFF {
+				i++
+				continue
+			}
+			width := 2
+			if charset == "gb18030" && i+1 < len(b) && b[i+1] >= 0x30 && b[i+1] <= 0x39 {
+				width = 4
+			}
+			if i+width > len(b) {
+				break
+			}
+			i += width
+		}
+		return i
+	}
+	return len(b)
+}
+
+// textLanguage 按文件名给出语言提示，无法识别时为 plaintext。
+func textLanguage(name string) string {
+	base := strings.ToLower(filepath.Base(name))
+	if lang, ok := textLanguageByName[base]; ok {
+		return lang
+	}
+	if lang, ok := textLanguageByExt[filepath.Ext(base)]; ok {
+		return lang
+	}
+	return "plaintext"
+}
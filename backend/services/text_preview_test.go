@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcloud/config"
+	"mcloud/models"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// setupTextPreviewFixture 以文件名为键写入测试文件，文件 ID 按参数顺序从 1 开始。
+func setupTextPreviewFixture(t *testing.T, contents ...[2]string) FileService {
+	t.Helper()
+	baseDir := t.TempDir()
+	config.AppConfig = &config.Config{Storage: config.StorageConfig{BasePath: baseDir}}
+
+	files := &quickAccessFileRepo{fakeFileRepo: newFakeFileRepo(), files: map[uint]models.File{}}
+	for i, c := range contents {
+		rel := filepath.Join("files", fmt.Sprintf("%d.bin", i+1))
+		if err := os.MkdirAll(filepath.Join(baseDir, "files"), 0755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(baseDir, rel), []byte(c[1]), 0644); err != nil {
+			t.Fatalf("write file failed: %v", err)
+		}
+		id := uint(i + 1)
+		files.files[id] = models.File{ID: id, UserID: 7, OriginalName: c[0], FileObject: models.FileObject{FilePath: rel}}
+	}
+	return NewFileService(fakeTxManager{}, nil, newFakeFolderRepo(), files, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+func mustGBK(t *testing.T, s string) string {
+	t.Helper()
+	out, err := simplifiedchinese.GBK.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("encode gbk failed: %v", err)
+	}
+	return out
+}
+
+func TestGetTextPreviewPagesUTF8OnCharacterBoundaries(t *testing.T) {
+	svc := setupTextPreviewFixture(t, [2]string{"notes.txt", "你好世界"})
+	ctx := context.Background()
+
+	first, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{Limit: 4})
+	if err != nil {
+		t.Fatalf("GetTextPreview failed: %v", err)
+	}
+	if first.Content != "你" || first.NextOffset != 3 || !first.HasMore || first.Charset != "utf-8" || first.Language != "plaintext" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	// 偏移落在字符中间时从下一个完整字符开始。
+	mid, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{Offset: 4, Limit: 6})
+	if err != nil {
+		t.Fatalf("GetTextPreview failed: %v", err)
+	}
+	if mid.Content != "世界" || mid.Offset != 6 || mid.HasMore || mid.NextOffset != 0 {
+		t.Fatalf("unexpected middle page: %+v", mid)
+	}
+
+	past, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{Offset: 100})
+	if err != nil || past.Content != "" || past.HasMore || past.Offset != 12 {
+		t.Fatalf("unexpected page past end: %+v (%v)", past, err)
+	}
+}
+
+func TestGetTextPreviewDetectsAndTranscodesCharsets(t *testing.T) {
+	svc := setupTextPreviewFixture(t,
+		[2]string{"gbk.txt", mustGBK(t, "中文内容\n第二行")},
+		[2]string{"utf16.txt", "\xff\xfe" + "h\x00i\x00=\x00\x3d\xd8\x00\xde"},
+		[2]string{"bom.txt", "\xef\xbb\xbfhello"},
+		[2]string{"latin.txt", "caf\xe9 cr\xe8me"},
+		[2]string{"data.bin", "a\x00b\x00"},
+	)
+	ctx := context.Background()
+
+	gbk, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{})
+	if err != nil || gbk.Charset != "gbk" || gbk.Content != "中文内容\n第二行" {
+		t.Fatalf("unexpected gbk preview: %+v (%v)", gbk, err)
+	}
+	gbkPage, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{Limit: 3})
+	if err != nil || gbkPage.Content != "中" || gbkPage.NextOffset != 2 {
+		t.Fatalf("unexpected gbk page: %+v (%v)", gbkPage, err)
+	}
+
+	utf16, err := svc.GetTextPreview(ctx, 7, 2, TextPreviewInput{})
+	if err != nil || utf16.Charset != "utf-16le" || utf16.Content != "hi=😀" {
+		t.Fatalf("unexpected utf-16 preview: %+v (%v)", utf16, err)
+	}
+	// 页尾为代理对前半部分时留到下一页。
+	utf16Page, err := svc.GetTextPreview(ctx, 7, 2, TextPreviewInput{Limit: 8})
+	if err != nil || utf16Page.Content != "hi=" || utf16Page.Offset != 2 || utf16Page.NextOffset != 8 {
+		t.Fatalf("unexpected utf-16 page: %+v (%v)", utf16Page, err)
+	}
+
+	bom, err := svc.GetTextPreview(ctx, 7, 3, TextPreviewInput{})
+	if err != nil || bom.Content != "hello" || bom.Offset != 3 {
+		t.Fatalf("expected BOM to be skipped, got %+v (%v)", bom, err)
+	}
+
+	latin, err := svc.GetTextPreview(ctx, 7, 4, TextPreviewInput{})
+	if err != nil || latin.Charset != "windows-1252" || latin.Content != "café crème" {
+		t.Fatalf("unexpected latin preview: %+v (%v)", latin, err)
+	}
+
+	_, err = svc.GetTextPreview(ctx, 7, 5, TextPreviewInput{})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for binary file, got %v", err)
+	}
+	// 指定编码时跳过二进制判断，用于没有 BOM 的 UTF-16 文本。
+	forced, err := svc.GetTextPreview(ctx, 7, 5, TextPreviewInput{Charset: "UTF-16LE"})
+	if err != nil || forced.Content != "ab" || forced.Charset != "utf-16le" {
+		t.Fatalf("unexpected forced charset preview: %+v (%v)", forced, err)
+	}
+	// 同一文件可以指定其他编码重新解读，如把 GB2312 识别为 GBK。
+	alias, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{Charset: "gb2312"})
+	if err != nil || alias.Charset != "gbk" {
+		t.Fatalf("expected gb2312 to resolve to gbk, got %+v (%v)", alias, err)
+	}
+	if _, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{Charset: "no-such-charset"}); !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown charset, got %v", err)
+	}
+}
+
+func TestGetTextPreviewPagesByLine(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("第%d行", i))
+	}
+	svc := setupTextPreviewFixture(t,
+		[2]string{"app.log", mustGBK(t, strings.Join(lines, "\n"))},
+		[2]string{"long.txt", strings.Repeat("长", maxTextPreviewBytes) + "\nnext"},
+	)
+	ctx := context.Background()
+
+	page, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{StartLine: 3, Lines: 2})
+	if err != nil {
+		t.Fatalf("GetTextPreview failed: %v", err)
+	}
+	if page.Content != "第3行\n第4行\n" || page.NextLine != 5 || !page.HasMore || page.Language != "log" || page.Charset != "gbk" {
+		t.Fatalf("unexpected line page: %+v", page)
+	}
+
+	last, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{StartLine: 9, Lines: 5})
+	if err != nil || last.Content != "第9行\n第10行" || last.HasMore || last.NextLine != 0 {
+		t.Fatalf("unexpected last page: %+v (%v)", last, err)
+	}
+	// 最后一行之后没有内容时不再提示下一页。
+	exact, err := svc.GetTextPreview(ctx, 7, 1, TextPreviewInput{StartLine: 9, Lines: 2})
+	if err != nil || exact.HasMore {
+		t.Fatalf("unexpected exact page: %+v (%v)", exact, err)
+	}
+
+	long, err := svc.GetTextPreview(ctx, 7, 2, TextPreviewInput{StartLine: 1, Lines: 5})
+	if err != nil {
+		t.Fatalf("GetTextPreview failed: %v", err)
+	}
+	// 超长的行读满一页即停止，下一页从同一行的剩余部分继续。
+	if !long.Truncated || len(long.Content) > maxTextPreviewBytes || !strings.HasSuffix(long.Content, "长") || long.NextLine != 1 ||
+		long.NextLineOffset != int64(len(long.Content)) || !long.HasMore {
+		t.Fatalf("unexpected truncated page: len=%d next=%d@%d more=%v truncated=%v", len(long.Content), long.NextLine, long.NextLineOffset, long.HasMore, long.Truncated)
+	}
+	read := len(long.Content)
+	for long.HasMore && long.NextLine == 1 {
+		long, err = svc.GetTextPreview(ctx, 7, 2, TextPreviewInput{StartLine: long.NextLine, LineOffset: long.NextLineOffset, Lines: 5})
+		if err != nil {
+			t.Fatalf("GetTextPreview failed: %v", err)
+		}
+		read += len(long.Content)
+	}
+	if read != len(strings.Repeat("长", maxTextPreviewBytes)+"\nnext") || !strings.HasSuffix(long.Content, "\nnext") || long.HasMore {
+		t.Fatalf("expected continuation pages to cover the file, read %d bytes, has_more=%v", read, long.HasMore)
+	}
+
+	for _, in := range []TextPreviewInput{{Offset: -1}, {Limit: maxTextPreviewBytes + 1}, {StartLine: 1, Lines: maxTextPreviewLines + 1}, {StartLine: 2, LineOffset: -1}} {
+		_, err := svc.GetTextPreview(ctx, 7, 1, in)
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %+v, got %v", in, err)
+		}
+	}
+}
+
+func TestGetTextPreviewSeeksToNextLineOffset(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 7; i++ {
+		lines = append(lines, fmt.Sprintf("第%d行", i))
+	}
+	text := strings.Join(lines, "\n")
+	utf16le := []byte{0xFF, 0xFE}
+	for _, r := range text {
+		utf16le = append(utf16le, byte(r), byte(r>>8))
+	}
+	svc := setupTextPreviewFixture(t,
+		[2]string{"a.log", text},
+		[2]string{"b.log", mustGBK(t, text)},
+		[2]string{"c.log", string(utf16le)},
+	)
+	ctx := context.Background()
+
+	for id := uint(1); id <= 3; id++ {
+		page, err := svc.GetTextPreview(ctx, 7, id, TextPreviewInput{StartLine: 1, Lines: 3})
+		if err != nil {
+			t.Fatalf("GetTextPreview(%d) failed: %v", id, err)
+		}
+		var got []string
+		for page.HasMore {
+			got = append(got, page.Content)
+			// 传回偏移直接定位的结果应与从文件头扫描一致。
+			scanned, err := svc.GetTextPreview(ctx, 7, id, TextPreviewInput{StartLine: page.NextLine, Lines: 3})
+			if err != nil {
+				t.Fatalf("GetTextPreview(%d) failed: %v", id, err)
+			}
+			page, err = svc.GetTextPreview(ctx, 7, id, TextPreviewInput{StartLine: page.NextLine, LineOffset: page.NextLineOffset, Lines: 3})
+			if err != nil || page.Content != scanned.Content || page.NextLineOffset != scanned.NextLineOffset {
+				t.Fatalf("file %d: seeked page %+v differs from scanned page %+v (%v)", id, page, scanned, err)
+			}
+		}
+		got = append(got, page.Content)
+		if strings.Join(got, "") != text || len(got) != 3 {
+			t.Fatalf("file %d: unexpected pages %q", id, got)
+		}
+	}
+}
+
+func TestGetTextPreviewRendersMarkdown(t *testing.T) {
+	svc := setupTextPreviewFixture(t, [2]string{"README.md", "# 说明\n\n<script>x</script>"})
+
+	out, err := svc.GetTextPreview(context.Background(), 7, 1, TextPreviewInput{})
+	if err != nil {
+		t.Fatalf("GetTextPreview failed: %v", err)
+	}
+	if out.Language != "markdown" || out.HTML != "<h1>说明</h1>\n<p>&lt;script&gt;x&lt;/script&gt;</p>\n" {
+		t.Fatalf("unexpected markdown preview: %+v", out)
+	}
+}
+
+func TestGetTextPreviewSkipsMarkdownRenderForLargePages(t *testing.T) {
+	svc := setupTextPreviewFixture(t, [2]string{"big.md", strings.Repeat("a", maxMarkdownRenderBytes+1)})
+
+	out, err := svc.GetTextPreview(context.Background(), 7, 1, TextPreviewInput{Limit: maxTextPreviewBytes})
+	if err != nil {
+		t.Fatalf("GetTextPreview failed: %v", err)
+	}
+	if out.Language != "markdown" || out.HTML != "" || len(out.Content) != maxMarkdownRenderBytes+1 {
+		t.Fatalf("expected raw content without html, got language=%s html=%d content=%d", out.Language, len(out.HTML), len(out.Content))
+	}
+}
+
+func TestTextLanguage(t *testing.T) {
+	cases := map[string]string{
+		"main.go":     "go",
+		"Dockerfile":  "dockerfile",
+		"config.YAML": "yaml",
+		"notes":       "plaintext",
+		"archive.zip": "plaintext",
+	}
+	for name, want := range cases {
+		if got := textLanguage(name); got != want {
+			t.Fatalf("textLanguage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
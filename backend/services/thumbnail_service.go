@@ -1,14 +1,19 @@
 package services
 
 import (
+	"bytes"
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"mcloud/config"
+	"mcloud/metrics"
 
 	"github.com/disintegration/imaging"
+	// 注册 WebP 解码器，上传的 WebP 图片同样可以生成缩略图与变体。
+	_ "golang.org/x/image/webp"
 )
 
 var imageExtensions = map[string]bool{
@@ -22,30 +27,62 @@ func IsImageFile(filename string) bool {
 	return imageExtensions[ext]
 }
 
-// GenerateThumbnail 按配置生成缩略图；会自动创建目标目录。
-func GenerateThumbnail(srcPath, dstPath string) error {
-	cfg := config.AppConfig
+// GenerateThumbnail 按配置生成缩略图并返回图片的感知哈希；会自动创建目标目录。
+// 哈希复用已解码的原图计算，图片解码成功即返回，即使缩略图写入失败。
+func GenerateThumbnail(srcPath, dstPath string) (hash string, err error) {
+	defer func() { metrics.ObserveThumbnail(err) }()
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return "", fmt.Errorf("创建缩略图目录失败: %w", err)
+	}
 
+	img, err := openOrientedImage(srcPath)
+	if err != nil {
+		return "", err
+	}
+	hash = formatPerceptualHash(DifferenceHash(img))
+	return hash, saveThumbnail(img, dstPath)
+}
+
+// GenerateCoverThumbnail 将音视频内嵌的封面图缩放为缩略图；会自动创建目标目录。
+func GenerateCoverThumbnail(cover []byte, dstPath string) (err error) {
+	defer func() { metrics.ObserveThumbnail(err) }()
 	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
 		return fmt.Errorf("创建缩略图目录失败: %w", err)
 	}
-
-	img, err := imaging.Open(srcPath)
+	img, err := imaging.Decode(bytes.NewReader(cover), imaging.AutoOrientation(true))
 	if err != nil {
-		return fmt.Errorf("打开图片失败: %w", err)
+		return fmt.Errorf("解码封面失败: %w", err)
 	}
+	return saveThumbnail(img, dstPath)
+}
 
-	// 使用 Fit 保持原图比例，避免缩略图拉伸变形。
+// saveThumbnail 按配置尺寸等比缩放并保存为 JPEG，Fit 保持原图比例，避免缩略图拉伸变形。
+func saveThumbnail(img image.Image, dstPath string) error {
+	cfg := config.AppConfig
 	thumb := imaging.Fit(img, cfg.Thumbnail.Width, cfg.Thumbnail.Height, imaging.Lanczos)
 	return imaging.Save(thumb, dstPath, imaging.JPEGQuality(cfg.Thumbnail.Quality))
 }
 
-// GetImageDimensions 读取图片宽高，用于记录图片元数据。
+// openOrientedImage 解码图片并按 EXIF 方向摆正，手机竖拍的照片才不会横躺。
+func openOrientedImage(srcPath string) (image.Image, error) {
+	img, err := imaging.Open(srcPath, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("打开图片失败: %w", err)
+	}
+	return img, nil
+}
+
+// GetImageDimensions 读取图片像素宽高，只解析文件头，不解码整张图片。
 func GetImageDimensions(filePath string) (int, int, error) {
-	img, err := imaging.Open(filePath)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
 	if err != nil {
 		return 0, 0, err
 	}
-	bounds := img.Bounds()
-	return bounds.Dx(), bounds.Dy(), nil
+	return cfg.Width, cfg.Height, nil
 }
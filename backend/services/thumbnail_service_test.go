@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/binary"
 	"image"
 	"image/color"
 	"image/jpeg"
@@ -52,9 +53,13 @@ func TestGenerateThumbnailAndReadDimensions(t *testing.T) {
 		},
 	}
 
-	if err := GenerateThumbnail(srcPath, dstPath); err != nil {
+	hash, err := GenerateThumbnail(srcPath, dstPath)
+	if err != nil {
 		t.Fatalf("GenerateThumbnail failed: %v", err)
 	}
+	if _, ok := parsePerceptualHash(hash); !ok {
+		t.Fatalf("expected perceptual hash, got %q", hash)
+	}
 
 	width, height, err := GetImageDimensions(dstPath)
 	if err != nil {
@@ -67,3 +72,27 @@ func TestGenerateThumbnailAndReadDimensions(t *testing.T) {
 		t.Fatalf("thumbnail should be bounded by 64x64, got %dx%d", width, height)
 	}
 }
+
+func TestGenerateThumbnailAppliesExifOrientation(t *testing.T) {
+	baseDir := t.TempDir()
+	srcPath := filepath.Join(baseDir, "portrait.jpg")
+	dstPath := filepath.Join(baseDir, "thumbs", "portrait.jpg")
+	// 横向存储的像素 + 方向 6（顺时针旋转 90 度显示），即手机竖拍的照片。
+	order := binary.LittleEndian
+	writeTestJPEGWithExif(t, srcPath, 200, 100, buildTestTIFF(order, []testExifField{shortExifField(order, exifTagOrientation, 6)}, nil, nil))
+
+	config.AppConfig = &config.Config{
+		Thumbnail: config.ThumbnailConfig{Width: 64, Height: 64, Quality: 80},
+	}
+	if _, err := GenerateThumbnail(srcPath, dstPath); err != nil {
+		t.Fatalf("GenerateThumbnail failed: %v", err)
+	}
+
+	width, height, err := GetImageDimensions(dstPath)
+	if err != nil {
+		t.Fatalf("GetImageDimensions failed: %v", err)
+	}
+	if width >= height {
+		t.Fatalf("expected upright portrait thumbnail, got %dx%d", width, height)
+	}
+}
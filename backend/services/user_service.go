@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"sort"
+	"strings"
 
+	"mcloud/models"
 	"mcloud/repositories"
 
 	"gorm.io/gorm"
@@ -20,32 +23,124 @@ type StorageQuotaOutput struct {
 	AvailableSpace int64 `json:"available_space"`
 	// UsagePercent 为使用率百分比，范围通常在 [0, 100+]。
 	UsagePercent float64 `json:"usage_percent"`
+	// ActiveUsed 为正常文件占用的空间（字节）。
+	ActiveUsed int64 `json:"active_used"`
+	// RecycleBinUsed 为回收站中文件占用的空间（字节），彻底删除前仍计入 StorageUsed。
+	RecycleBinUsed int64 `json:"recycle_bin_used"`
+	// FreeableSpace 为清空回收站后可释放的空间（字节）。
+	FreeableSpace int64 `json:"freeable_space"`
+}
+
+// StorageUsageItem 为空间明细中的一个分组。
+type StorageUsageItem struct {
+	// Category 为 MIME 分类：image / video / audio / document / archive / other，仅按类型分组时返回。
+	Category string `json:"category,omitempty"`
+	// FolderID 为顶层目录 ID，根目录下的文件归入根目录自身，仅按目录分组时返回。
+	FolderID uint `json:"folder_id,omitempty"`
+	// Name 为顶层目录名称，根目录为空。
+	Name string `json:"name,omitempty"`
+	// Path 为顶层目录路径，根目录为 "/"。
+	Path      string `json:"path,omitempty"`
+	FileCount int64  `json:"file_count"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// StorageBreakdownOutput 为用户空间占用明细。
+type StorageBreakdownOutput struct {
+	StorageQuotaOutput
+	// ActiveFiles 为正常文件数。
+	ActiveFiles int64 `json:"active_files"`
+	// RecycleBinFiles 为回收站中的文件数（含已删除文件夹内的文件）。
+	RecycleBinFiles int64 `json:"recycle_bin_files"`
+	// DedupSavedBytes 为同一文件对象被多个文件共享所节省的空间（字节）。
+	DedupSavedBytes int64 `json:"dedup_saved_bytes"`
+	// ByCategory 为正常文件按 MIME 分类的占用，按字节数降序。
+	ByCategory []StorageUsageItem `json:"by_category"`
+	// ByFolder 为正常文件按顶层目录的占用，按字节数降序。
+	ByFolder []StorageUsageItem `json:"by_folder"`
 }
 
 // UserService 定义用户侧基础能力。
 type UserService interface {
 	// GetStorageQuota 查询并计算用户空间占用信息。
 	GetStorageQuota(ctx context.Context, userID uint) (StorageQuotaOutput, error)
+	// GetStorageBreakdown 查询空间占用明细：回收站、去重节省、MIME 分类与顶层目录。
+	GetStorageBreakdown(ctx context.Context, userID uint) (StorageBreakdownOutput, error)
 }
 
 // userService 为 UserService 的默认实现。
 type userService struct {
-	users repositories.UserRepository
+	users    repositories.UserRepository
+	folders  repositories.FolderRepository
+	stats    repositories.StorageStatsRepository
+	resolver folderResolver
 }
 
 // NewUserService 创建用户服务实例。
-func NewUserService(users repositories.UserRepository) UserService {
-	return &userService{users: users}
+func NewUserService(users repositories.UserRepository, folders repositories.FolderRepository, stats repositories.StorageStatsRepository) UserService {
+	return &userService{
+		users:    users,
+		folders:  folders,
+		stats:    stats,
+		resolver: folderResolver{folders: folders},
+	}
 }
 
-// GetStorageQuota 查询用户配额并计算剩余空间与使用率。
+// GetStorageQuota 查询用户配额并计算剩余空间、使用率与回收站占用。
 func (s *userService) GetStorageQuota(ctx context.Context, userID uint) (StorageQuotaOutput, error) {
+	out, _, err := s.loadQuota(ctx, userID)
+	return out, err
+}
+
+// GetStorageBreakdown 在配额信息基础上按 MIME 分类与顶层目录汇总正常文件占用。
+func (s *userService) GetStorageBreakdown(ctx context.Context, userID uint) (StorageBreakdownOutput, error) {
+	quota, usage, err := s.loadQuota(ctx, userID)
+	if err != nil {
+		return StorageBreakdownOutput{}, err
+	}
+	out := StorageBreakdownOutput{
+		StorageQuotaOutput: quota,
+		ActiveFiles:        usage.ActiveFiles,
+		RecycleBinFiles:    usage.RecycledFiles,
+	}
+	if saved := usage.ActiveBytes + usage.RecycledBytes - usage.UniqueObjectBytes; saved > 0 {
+		out.DedupSavedBytes = saved
+	}
+
+	byMime, err := s.stats.UserUsageByMimeType(ctx, userID)
+	if err != nil {
+		return StorageBreakdownOutput{}, newAppError(http.StatusInternalServerError, "统计空间占用失败", err)
+	}
+	out.ByCategory = groupUsageByCategory(byMime)
+
+	byFolder, err := s.stats.UserUsageByFolder(ctx, userID)
+	if err != nil {
+		return StorageBreakdownOutput{}, newAppError(http.StatusInternalServerError, "统计空间占用失败", err)
+	}
+	root, err := s.resolver.getOrCreateUserRootFolder(ctx, nil, userID)
+	if err != nil {
+		return StorageBreakdownOutput{}, newAppError(http.StatusInternalServerError, "获取根目录失败", err)
+	}
+	topFolders, err := s.folders.ListByParent(ctx, nil, userID, root.ID, true)
+	if err != nil {
+		return StorageBreakdownOutput{}, newAppError(http.StatusInternalServerError, "获取文件夹列表失败", err)
+	}
+	out.ByFolder = groupUsageByTopFolder(byFolder, root, topFolders)
+	return out, nil
+}
+
+// loadQuota 读取用户配额并汇总文件用量。
+func (s *userService) loadQuota(ctx context.Context, userID uint) (StorageQuotaOutput, repositories.UserStorageUsage, error) {
 	user, err := s.users.GetByID(ctx, nil, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return StorageQuotaOutput{}, newAppError(http.StatusNotFound, "用户不存在", nil)
+			return StorageQuotaOutput{}, repositories.UserStorageUsage{}, newAppError(http.StatusNotFound, "用户不存在", nil)
 		}
-		return StorageQuotaOutput{}, newAppError(http.StatusInternalServerError, "查询用户失败", err)
+		return StorageQuotaOutput{}, repositories.UserStorageUsage{}, newAppError(http.StatusInternalServerError, "查询用户失败", err)
+	}
+	usage, err := s.stats.UserUsage(ctx, userID)
+	if err != nil {
+		return StorageQuotaOutput{}, repositories.UserStorageUsage{}, newAppError(http.StatusInternalServerError, "统计空间占用失败", err)
 	}
 
 	// 仅在总配额大于 0 时计算占比，避免除零错误。
@@ -54,10 +149,137 @@ func (s *userService) GetStorageQuota(ctx context.Context, userID uint) (Storage
 		usagePercent = float64(user.StorageUsed) / float64(user.StorageQuota) * 100
 	}
 
+	// 彻底删除时 storage_used 按下限 0 扣减，可释放空间不会超过已用空间。
+	freeable := usage.RecycledBytes
+	if freeable > user.StorageUsed {
+		freeable = user.StorageUsed
+	}
+
 	return StorageQuotaOutput{
 		StorageQuota:   user.StorageQuota,
 		StorageUsed:    user.StorageUsed,
 		AvailableSpace: user.StorageQuota - user.StorageUsed,
 		UsagePercent:   usagePercent,
-	}, nil
+		ActiveUsed:     usage.ActiveBytes,
+		RecycleBinUsed: usage.RecycledBytes,
+		FreeableSpace:  freeable,
+	}, usage, nil
+}
+
+// mimeCategoryRule 描述一个 MIME 分类：前缀或完整类型任一命中即归入该类。
+type mimeCategoryRule struct {
+	category string
+	prefixes []string
+	types    []string
+}
+
+// mimeCategoryRules 按顺序匹配，均未命中时归为 other；筛选条件也由此生成，保证统计与筛选口径一致。
+var mimeCategoryRules = []mimeCategoryRule{
+	{category: "image", prefixes: []string{"image/"}},
+	{category: "video", prefixes: []string{"video/"}},
+	{category: "audio", prefixes: []string{"audio/"}},
+	{
+		category: "document",
+		prefixes: []string{
+			"text/",
+			"application/vnd.ms-",
+			"application/vnd.openxmlformats-officedocument.",
+			"application/vnd.oasis.opendocument.",
+		},
+		types: []string{"application/pdf", "application/json", "application/msword", "application/rtf"},
+	},
+	{
+		category: "archive",
+		types: []string{
+			"application/zip",
+			"application/gzip",
+			"application/x-gzip",
+			"application/x-tar",
+			"application/x-bzip2",
+			"application/x-xz",
+			"application/x-7z-compressed",
+			"application/x-rar-compressed",
+			"application/vnd.rar",
+		},
+	},
+}
+
+// mimeCategory 将 MIME 类型归入粗粒度分类。
+func mimeCategory(mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	for _, rule := range mimeCategoryRules {
+		for _, prefix := range rule.prefixes {
+			if strings.HasPrefix(mimeType, prefix) {
+				return rule.category
+			}
+		}
+		for _, t := range rule.types {
+			if mimeType == t {
+				return rule.category
+			}
+		}
+	}
+	return "other"
+}
+
+// groupUsageByCategory 将按 MIME 类型的聚合结果归并为分类。
+func groupUsageByCategory(rows []repositories.MimeTypeUsage) []StorageUsageItem {
+	index := map[string]int{}
+	items := make([]StorageUsageItem, 0)
+	for _, row := range rows {
+		category := mimeCategory(row.MimeType)
+		i, ok := index[category]
+		if !ok {
+			i = len(items)
+			index[category] = i
+			items = append(items, StorageUsageItem{Category: category})
+		}
+		items[i].FileCount += row.FileCount
+		items[i].Bytes += row.Bytes
+	}
+	sortUsageItems(items)
+	return items
+}
+
+// groupUsageByTopFolder 将按目录路径的聚合结果归并到顶层目录；根目录及无效目录下的文件归入根目录。
+func groupUsageByTopFolder(rows []repositories.FolderPathUsage, root models.Folder, topFolders []models.Folder) []StorageUsageItem {
+	byPath := make(map[string]models.Folder, len(topFolders))
+	for _, folder := range topFolders {
+		byPath[folder.Path] = folder
+	}
+
+	index := map[string]int{}
+	items := make([]StorageUsageItem, 0)
+	for _, row := range rows {
+		top := "/"
+		if trimmed := strings.Trim(row.Path, "/"); trimmed != "" {
+			top = "/" + strings.SplitN(trimmed, "/", 2)[0]
+		}
+		i, ok := index[top]
+		if !ok {
+			item := StorageUsageItem{FolderID: root.ID, Path: "/"}
+			if folder, found := byPath[top]; found {
+				item = StorageUsageItem{FolderID: folder.ID, Name: folder.Name, Path: folder.Path}
+			} else if top != "/" {
+				item = StorageUsageItem{Name: strings.TrimPrefix(top, "/"), Path: top}
+			}
+			i = len(items)
+			index[top] = i
+			items = append(items, item)
+		}
+		items[i].FileCount += row.FileCount
+		items[i].Bytes += row.Bytes
+	}
+	sortUsageItems(items)
+	return items
+}
+
+// sortUsageItems 按字节数降序排列，字节数相同时按分类或路径升序保证结果稳定。
+func sortUsageItems(items []StorageUsageItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Bytes != items[j].Bytes {
+			return items[i].Bytes > items[j].Bytes
+		}
+		return items[i].Category+items[i].Path < items[j].Category+items[j].Path
+	})
 }
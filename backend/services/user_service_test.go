@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"mcloud/models"
+	"mcloud/repositories"
 
 	"gorm.io/gorm"
 )
@@ -26,11 +27,42 @@ func (r *quotaUserRepo) GetByID(ctx context.Context, tx *gorm.DB, userID uint) (
 	return r.fakeUserRepo.GetByID(ctx, tx, userID)
 }
 
+type fakeStorageStatsRepo struct {
+	usage          repositories.UserStorageUsage
+	byMime         []repositories.MimeTypeUsage
+	byFolder       []repositories.FolderPathUsage
+	folderUsage    []repositories.FolderUsage
+	usageErr       error
+	folderUsageErr error
+	folderCalls    int
+}
+
+func (r *fakeStorageStatsRepo) Totals(context.Context) (repositories.StorageTotals, error) {
+	return repositories.StorageTotals{}, nil
+}
+
+func (r *fakeStorageStatsRepo) UserUsage(context.Context, uint) (repositories.UserStorageUsage, error) {
+	return r.usage, r.usageErr
+}
+
+func (r *fakeStorageStatsRepo) UserUsageByMimeType(context.Context, uint) ([]repositories.MimeTypeUsage, error) {
+	return r.byMime, nil
+}
+
+func (r *fakeStorageStatsRepo) UserUsageByFolder(context.Context, uint) ([]repositories.FolderPathUsage, error) {
+	return r.byFolder, nil
+}
+
+func (r *fakeStorageStatsRepo) UserFolderUsage(context.Context, uint) ([]repositories.FolderUsage, error) {
+	r.folderCalls++
+	return r.folderUsage, r.folderUsageErr
+}
+
 func TestUserServiceGetStorageQuotaSuccess(t *testing.T) {
 	users := newQuotaUserRepo()
 	users.usersByID[10] = models.User{ID: 10, Username: "alice", StorageQuota: 1000, StorageUsed: 250}
 
-	svc := NewUserService(users)
+	svc := NewUserService(users, newFolderServiceFolderRepo(), &fakeStorageStatsRepo{})
 	out, err := svc.GetStorageQuota(context.Background(), 10)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -51,7 +83,7 @@ func TestUserServiceGetStorageQuotaZeroQuota(t *testing.T) {
 	users := newQuotaUserRepo()
 	users.usersByID[11] = models.User{ID: 11, Username: "bob", StorageQuota: 0, StorageUsed: 0}
 
-	svc := NewUserService(users)
+	svc := NewUserService(users, newFolderServiceFolderRepo(), &fakeStorageStatsRepo{})
 	out, err := svc.GetStorageQuota(context.Background(), 11)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -65,7 +97,7 @@ func TestUserServiceGetStorageQuotaNotFound(t *testing.T) {
 	users := newQuotaUserRepo()
 	users.getByIDErr = gorm.ErrRecordNotFound
 
-	svc := NewUserService(users)
+	svc := NewUserService(users, newFolderServiceFolderRepo(), &fakeStorageStatsRepo{})
 	_, err := svc.GetStorageQuota(context.Background(), 99)
 	if err == nil {
 		t.Fatalf("expected not found error")
@@ -84,7 +116,7 @@ func TestUserServiceGetStorageQuotaInternalError(t *testing.T) {
 	users := newQuotaUserRepo()
 	users.getByIDErr = errors.New("db timeout")
 
-	svc := NewUserService(users)
+	svc := NewUserService(users, newFolderServiceFolderRepo(), &fakeStorageStatsRepo{})
 	_, err := svc.GetStorageQuota(context.Background(), 99)
 	if err == nil {
 		t.Fatalf("expected internal error")
@@ -98,3 +130,110 @@ func TestUserServiceGetStorageQuotaInternalError(t *testing.T) {
 		t.Fatalf("expected HTTP 500, got %d", appErr.HTTPCode)
 	}
 }
+
+func TestUserServiceGetStorageQuotaReportsRecycleBin(t *testing.T) {
+	users := newQuotaUserRepo()
+	users.usersByID[12] = models.User{ID: 12, Username: "carol", StorageQuota: 1000, StorageUsed: 400}
+	stats := &fakeStorageStatsRepo{usage: repositories.UserStorageUsage{ActiveBytes: 300, RecycledBytes: 100}}
+
+	svc := NewUserService(users, newFolderServiceFolderRepo(), stats)
+	out, err := svc.GetStorageQuota(context.Background(), 12)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.ActiveUsed != 300 || out.RecycleBinUsed != 100 || out.FreeableSpace != 100 {
+		t.Fatalf("unexpected recycle accounting: %+v", out)
+	}
+
+	// storage_used 偏小时，可释放空间不超过已用空间。
+	users.usersByID[12] = models.User{ID: 12, StorageQuota: 1000, StorageUsed: 60}
+	out, err = svc.GetStorageQuota(context.Background(), 12)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.FreeableSpace != 60 {
+		t.Fatalf("expected freeable space capped at 60, got %d", out.FreeableSpace)
+	}
+}
+
+func TestUserServiceGetStorageQuotaStatsError(t *testing.T) {
+	users := newQuotaUserRepo()
+	users.usersByID[13] = models.User{ID: 13, StorageQuota: 1000}
+
+	svc := NewUserService(users, newFolderServiceFolderRepo(), &fakeStorageStatsRepo{usageErr: errors.New("db timeout")})
+	_, err := svc.GetStorageQuota(context.Background(), 13)
+	appErr, ok := err.(*AppError)
+	if !ok || appErr.HTTPCode != 500 {
+		t.Fatalf("expected HTTP 500 AppError, got %v", err)
+	}
+}
+
+func TestUserServiceGetStorageBreakdown(t *testing.T) {
+	users := newQuotaUserRepo()
+	users.usersByID[14] = models.User{ID: 14, StorageQuota: 10000, StorageUsed: 1600}
+
+	folders := newFolderServiceFolderRepo()
+	isRoot := true
+	rootID := uint(1)
+	folders.folders[rootID] = models.Folder{ID: rootID, UserID: 14, IsRoot: &isRoot, Path: "/"}
+	folders.rootByUser[14] = rootID
+	folders.folders[2] = models.Folder{ID: 2, UserID: 14, ParentID: &rootID, Name: "photos", Path: "/photos"}
+	folders.folders[3] = models.Folder{ID: 3, UserID: 14, ParentID: &rootID, Name: "docs", Path: "/docs"}
+
+	stats := &fakeStorageStatsRepo{
+		usage: repositories.UserStorageUsage{ActiveFiles: 5, ActiveBytes: 1500, RecycledFiles: 1, RecycledBytes: 100, UniqueObjectBytes: 1200},
+		byMime: []repositories.MimeTypeUsage{
+			{MimeType: "image/jpeg", FileCount: 2, Bytes: 800},
+			{MimeType: "image/png", FileCount: 1, Bytes: 200},
+			{MimeType: "application/pdf", FileCount: 1, Bytes: 400},
+			{MimeType: "application/octet-stream", FileCount: 1, Bytes: 100},
+		},
+		byFolder: []repositories.FolderPathUsage{
+			{Path: "/photos", FileCount: 1, Bytes: 200},
+			{Path: "/photos/2024/summer", FileCount: 2, Bytes: 800},
+			{Path: "/docs", FileCount: 1, Bytes: 400},
+			{Path: "/", FileCount: 1, Bytes: 60},
+			{Path: "", FileCount: 1, Bytes: 40},
+		},
+	}
+
+	svc := NewUserService(users, folders, stats)
+	out, err := svc.GetStorageBreakdown(context.Background(), 14)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.ActiveFiles != 5 || out.RecycleBinFiles != 1 || out.RecycleBinUsed != 100 {
+		t.Fatalf("unexpected totals: %+v", out)
+	}
+	if out.DedupSavedBytes != 400 {
+		t.Fatalf("expected dedup saved 400, got %d", out.DedupSavedBytes)
+	}
+
+	wantCategories := []StorageUsageItem{
+		{Category: "image", FileCount: 3, Bytes: 1000},
+		{Category: "document", FileCount: 1, Bytes: 400},
+		{Category: "other", FileCount: 1, Bytes: 100},
+	}
+	if len(out.ByCategory) != len(wantCategories) {
+		t.Fatalf("unexpected categories: %+v", out.ByCategory)
+	}
+	for i, want := range wantCategories {
+		if out.ByCategory[i] != want {
+			t.Fatalf("category %d: got %+v want %+v", i, out.ByCategory[i], want)
+		}
+	}
+
+	wantFolders := []StorageUsageItem{
+		{FolderID: 2, Name: "photos", Path: "/photos", FileCount: 3, Bytes: 1000},
+		{FolderID: 3, Name: "docs", Path: "/docs", FileCount: 1, Bytes: 400},
+		{FolderID: rootID, Path: "/", FileCount: 2, Bytes: 100},
+	}
+	if len(out.ByFolder) != len(wantFolders) {
+		t.Fatalf("unexpected folders: %+v", out.ByFolder)
+	}
+	for i, want := range wantFolders {
+		if out.ByFolder[i] != want {
+			t.Fatalf("folder %d: got %+v want %+v", i, out.ByFolder[i], want)
+		}
+	}
+}
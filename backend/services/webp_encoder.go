@@ -0,0 +1,591 @@
+package services
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// 标准库与 golang.org/x/image 只提供 WebP 解码，这里实现一个精简的无损 WebP（VP8L）编码器：
+// 减绿 + 分块预测变换，残差用 LZ77（仅左侧/上方两种距离）与 Huffman 编码，不使用颜色缓存与多组前缀码。
+// 压缩率不及 libwebp，但输出可被所有支持 WebP 的浏览器解码，且完整保留透明通道。
+
+const (
+	vp8lMaxDimension   = 1 << 14
+	vp8lPredictorBits  = 4 // 预测模式按 16x16 分块选择
+	vp8lMinMatchLength = 3
+	vp8lMaxMatchLength = 4096
+	vp8lLiteralCodes   = 256
+	vp8lLengthCodes    = 24
+	vp8lDistanceCodes  = 40
+	vp8lMaxCodeLength  = 15
+	// distanceMap 中距离码 1 表示正上方像素，2 表示左侧像素。
+	vp8lDistanceCodeTop  = 1
+	vp8lDistanceCodeLeft = 2
+)
+
+var vp8lCodeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// 候选预测模式：左、上、左上平均、Select、两种 ClampAddSubtract，覆盖常见的平滑与边缘区域。
+var vp8lCandidateModes = []uint8{1, 2, 7, 11, 12, 13}
+
+// EncodeWebPLossless 将图片编码为无损 WebP 写入 w。
+func EncodeWebPLossless(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return errors.New("webp: 图片尺寸超出范围")
+	}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+
+	var bw vp8lBitWriter
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(boolBit(!nrgba.Opaque()), 1)
+	bw.write(0, 3)
+
+	pix := nrgba.Pix
+	// 减绿变换：红、蓝通道减去绿色，降低三通道之间的相关性。
+	bw.write(1, 1)
+	bw.write(2, 2)
+	for p := 0; p < len(pix); p += 4 {
+		pix[p+0] -= pix[p+1]
+		pix[p+2] -= pix[p+1]
+	}
+
+	// 预测变换：每个分块挑选残差最小的预测模式，模式写入子图的绿色通道。
+	tilesX, tilesY := vp8lTiles(width), vp8lTiles(height)
+	modes := chooseVP8LPredictors(pix, width, height, tilesX)
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(vp8lPredictorBits-2, 3)
+	modeImage := make([]byte, 4*tilesX*tilesY)
+	for i, mode := range modes {
+		modeImage[4*i+1] = mode
+		modeImage[4*i+3] = 0xff
+	}
+	writeVP8LImage(&bw, modeImage, tilesX, false)
+
+	bw.write(0, 1) // 无更多变换
+	writeVP8LImage(&bw, applyVP8LPredictors(pix, width, height, modes, tilesX), width, true)
+
+	data := bw.flush()
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+	out := bufio.NewWriter(w)
+	header := make([]byte, 20)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+padded))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(chunkSize))
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+	if _, err := out.Write(data); err != nil {
+		return err
+	}
+	if padded != chunkSize {
+		if err := out.WriteByte(0); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func vp8lTiles(size int) int {
+	return (size + 1<<vp8lPredictorBits - 1) >> vp8lPredictorBits
+}
+
+func boolBit(v bool) uint32 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// chooseVP8LPredictors 以残差绝对值之和为代价，为每个分块选出最合适的预测模式。
+func chooseVP8LPredictors(pix []byte, width, height, tilesX int) []uint8 {
+	tilesY := vp8lTiles(height)
+	modes := make([]uint8, tilesX*tilesY)
+	costs := make([]int, len(modes)*len(vp8lCandidateModes))
+	for y := 1; y < height; y++ {
+		rowTiles := (y >> vp8lPredictorBits) * tilesX
+		for x := 1; x < width; x++ {
+			base := (rowTiles + x>>vp8lPredictorBits) * len(vp8lCandidateModes)
+			p := 4 * (y*width + x)
+			for i, mode := range vp8lCandidateModes {
+				pred := vp8lPredict(mode, pix, p, p-4*width)
+				for c := 0; c < 4; c++ {
+					costs[base+i] += absResidual(pix[p+c] - pred[c])
+				}
+			}
+		}
+	}
+	for t := range modes {
+		best := 0
+		for i := range vp8lCandidateModes {
+			if costs[t*len(vp8lCandidateModes)+i] < costs[t*len(vp8lCandidateModes)+best] {
+				best = i
+			}
+		}
+		modes[t] = vp8lCandidateModes[best]
+	}
+	return modes
+}
+
+func absResidual(r uint8) int {
+	if r >= 128 {
+		return 256 - int(r)
+	}
+	return int(r)
+}
+
+// applyVP8LPredictors 计算预测残差；首行固定用左侧、首列固定用上方、首像素以不透明黑色预测，与解码器约定一致。
+func applyVP8LPredictors(pix []byte, width, height int, modes []uint8, tilesX int) []byte {
+	out := make([]byte, len(pix))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := 4 * (y*width + x)
+			var pred [4]uint8
+			switch {
+			case x == 0 && y == 0:
+				pred = [4]uint8{0, 0, 0, 0xff}
+			case y == 0:
+				pred = vp8lPredict(1, pix, p, 0)
+			case x == 0:
+				pred = vp8lPredict(2, pix, p, p-4*width)
+			default:
+				pred = vp8lPredict(modes[(y>>vp8lPredictorBits)*tilesX+x>>vp8lPredictorBits], pix, p, p-4*width)
+			}
+			for c := 0; c < 4; c++ {
+				out[p+c] = pix[p+c] - pred[c]
+			}
+		}
+	}
+	return out
+}
+
+// vp8lPredict 返回像素 p 的预测值，top 为正上方像素的偏移；行末像素的右上方按规范取当前行首像素。
+func vp8lPredict(mode uint8, pix []byte, p, top int) [4]uint8 {
+	var pred [4]uint8
+	for c := 0; c < 4; c++ {
+		switch mode {
+		case 1:
+			pred[c] = pix[p-4+c]
+		case 2:
+			pred[c] = pix[top+c]
+		case 7:
+			pred[c] = avg2(pix[p-4+c], pix[top+c])
+		case 12:
+			pred[c] = clampByte(int(pix[p-4+c]) + int(pix[top+c]) - int(pix[top-4+c]))
+		case 13:
+			a := avg2(pix[p-4+c], pix[top+c])
+			pred[c] = clampByte(int(a) + (int(a)-int(pix[top-4+c]))/2)
+		}
+	}
+	if mode == 11 {
+		var l, t int
+		for c := 0; c < 4; c++ {
+			l += absInt(int(pix[top-4+c]) - int(pix[top+c]))
+			t += absInt(int(pix[top-4+c]) - int(pix[p-4+c]))
+		}
+		src := top
+		if l < t {
+			src = p - 4
+		}
+		copy(pred[:], pix[src:src+4])
+	}
+	return pred
+}
+
+func avg2(a, b uint8) uint8 {
+	return uint8((uint16(a) + uint16(b)) / 2)
+}
+
+func clampByte(v int) uint8 {
+	return uint8(min(max(v, 0), 255))
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// vp8lSymbol 是待编码的一个像素字面量或一次回溯引用。
+type vp8lSymbol struct {
+	literal  bool
+	pixel    [4]uint8
+	length   int
+	distCode int
+}
+
+// writeVP8LImage 写出一幅（主图或子图）像素数据：不用颜色缓存，主图额外声明不使用元前缀码。
+func writeVP8LImage(bw *vp8lBitWriter, pix []byte, width int, topLevel bool) {
+	symbols := lz77VP8L(pix, width)
+
+	var green [vp8lLiteralCodes + vp8lLengthCodes]int
+	var red, blue, alpha [vp8lLiteralCodes]int
+	var dist [vp8lDistanceCodes]int
+	for _, s := range symbols {
+		if s.literal {
+			green[s.pixel[1]]++
+			red[s.pixel[0]]++
+			blue[s.pixel[2]]++
+			alpha[s.pixel[3]]++
+			continue
+		}
+		lenSym, _, _ := vp8lPrefix(s.length)
+		distSym, _, _ := vp8lPrefix(s.distCode)
+		green[vp8lLiteralCodes+lenSym]++
+		dist[distSym]++
+	}
+
+	bw.write(0, 1) // 颜色缓存
+	if topLevel {
+		bw.write(0, 1) // 元前缀码
+	}
+	greenCode := writeVP8LPrefixCode(bw, green[:])
+	redCode := writeVP8LPrefixCode(bw, red[:])
+	blueCode := writeVP8LPrefixCode(bw, blue[:])
+	alphaCode := writeVP8LPrefixCode(bw, alpha[:])
+	distCode := writeVP8LPrefixCode(bw, dist[:])
+
+	for _, s := range symbols {
+		if s.literal {
+			greenCode.write(bw, int(s.pixel[1]))
+			redCode.write(bw, int(s.pixel[0]))
+			blueCode.write(bw, int(s.pixel[2]))
+			alphaCode.write(bw, int(s.pixel[3]))
+			continue
+		}
+		lenSym, lenExtra, lenBits := vp8lPrefix(s.length)
+		greenCode.write(bw, vp8lLiteralCodes+lenSym)
+		bw.write(lenExtra, lenBits)
+		distSym, distExtra, distBits := vp8lPrefix(s.distCode)
+		distCode.write(bw, distSym)
+		bw.write(distExtra, distBits)
+	}
+}
+
+// lz77VP8L 贪心查找与左侧像素或上一行同位置像素相同的连续段，平坦区域因此只需少量回溯引用。
+func lz77VP8L(pix []byte, width int) []vp8lSymbol {
+	n := len(pix) / 4
+	symbols := make([]vp8lSymbol, 0, n)
+	same := func(a, b int) bool {
+		return pix[4*a] == pix[4*b] && pix[4*a+1] == pix[4*b+1] && pix[4*a+2] == pix[4*b+2] && pix[4*a+3] == pix[4*b+3]
+	}
+	matchLength := func(i, dist int) int {
+		if i < dist {
+			return 0
+		}
+		length := 0
+		for i+length < n && length < vp8lMaxMatchLength && same(i+length, i+length-dist) {
+			length++
+		}
+		return length
+	}
+	for i := 0; i < n; {
+		length, distCode := matchLength(i, 1), vp8lDistanceCodeLeft
+		// 宽度为 1 时上方与左侧是同一像素，distanceMap 也会把两者都映射为距离 1。
+		if width > 1 {
+			if up := matchLength(i, width); up > length {
+				length, distCode = up, vp8lDistanceCodeTop
+			}
+		}
+		if length >= vp8lMinMatchLength {
+			symbols = append(symbols, vp8lSymbol{length: length, distCode: distCode})
+			i += length
+			continue
+		}
+		var px [4]uint8
+		copy(px[:], pix[4*i:4*i+4])
+		symbols = append(symbols, vp8lSymbol{literal: true, pixel: px})
+		i++
+	}
+	return symbols
+}
+
+// vp8lPrefix 将长度或距离码（从 1 开始）拆为前缀符号与附加位。
+func vp8lPrefix(v int) (symbol int, extra uint32, extraBits uint) {
+	n := v - 1
+	if n < 4 {
+		return n, 0, 0
+	}
+	hb := bits.Len(uint(n)) - 1
+	second := (n >> (hb - 1)) & 1
+	extraBits = uint(hb - 1)
+	return 2*hb + second, uint32(n) & (1<<extraBits - 1), extraBits
+}
+
+// vp8lPrefixCode 为规范 Huffman 码表；只有一个符号时不占用任何比特。
+type vp8lPrefixCode struct {
+	lengths []uint8
+	codes   []uint32
+	single  bool
+}
+
+func (c *vp8lPrefixCode) write(bw *vp8lBitWriter, symbol int) {
+	if c.single {
+		return
+	}
+	bw.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writeVP8LPrefixCode 按频次构建前缀码并写出其描述，返回用于编码符号的码表。
+func writeVP8LPrefixCode(bw *vp8lBitWriter, freq []int) *vp8lPrefixCode {
+	var used []int
+	for s, f := range freq {
+		if f > 0 {
+			used = append(used, s)
+		}
+	}
+	// 不超过一个符号时使用“简单码”：该符号编码为 0 比特。
+	if len(used) <= 1 {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+		bw.write(1, 1)
+		bw.write(0, 1)
+		if symbol < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(symbol), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(symbol), 8)
+		}
+		return &vp8lPrefixCode{single: true}
+	}
+
+	lengths := huffmanCodeLengths(freq, vp8lMaxCodeLength)
+	tokens := vp8lCodeLengthTokens(lengths)
+	var clFreq [19]int
+	for _, t := range tokens {
+		clFreq[t.symbol]++
+	}
+	clCode := newVP8LPrefixCode(huffmanCodeLengths(clFreq[:], 7))
+
+	bw.write(0, 1)
+	nCodes := 4
+	for i, s := range vp8lCodeLengthCodeOrder {
+		if clCode.lengths[s] > 0 {
+			nCodes = max(nCodes, i+1)
+		}
+	}
+	bw.write(uint32(nCodes-4), 4)
+	for _, s := range vp8lCodeLengthCodeOrder[:nCodes] {
+		bw.write(uint32(clCode.lengths[s]), 3)
+	}
+	bw.write(0, 1) // 码长序列覆盖整个字母表
+	for _, t := range tokens {
+		clCode.write(bw, t.symbol)
+		bw.write(t.extra, t.extraBits)
+	}
+	return newVP8LPrefixCode(lengths)
+}
+
+// newVP8LPrefixCode 按码长分配规范码，并按比特流的低位在前顺序预先反转。
+func newVP8LPrefixCode(lengths []uint8) *vp8lPrefixCode {
+	c := &vp8lPrefixCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+	var count [vp8lMaxCodeLength + 1]uint32
+	nonZero := 0
+	for _, l := range lengths {
+		count[l]++
+		if l > 0 {
+			nonZero++
+		}
+	}
+	c.single = nonZero == 1
+	count[0] = 0
+	var next [vp8lMaxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range lengths {
+		if l > 0 {
+			c.codes[s] = bits.Reverse32(next[l]) >> (32 - uint(l))
+			next[l]++
+		}
+	}
+	return c
+}
+
+type vp8lCodeLengthToken struct {
+	symbol    int
+	extra     uint32
+	extraBits uint
+}
+
+// vp8lCodeLengthTokens 用 16（重复上一非零码长）、17/18（连续零）压缩码长序列。
+func vp8lCodeLengthTokens(lengths []uint8) []vp8lCodeLengthToken {
+	var tokens []vp8lCodeLengthToken
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == l {
+			run++
+		}
+		i += run
+		if l == 0 {
+			for run > 0 {
+				switch {
+				case run >= 11:
+					n := min(run, 138)
+					tokens = append(tokens, vp8lCodeLengthToken{18, uint32(n - 11), 7})
+					run -= n
+				case run >= 3:
+					tokens = append(tokens, vp8lCodeLengthToken{17, uint32(run - 3), 3})
+					run = 0
+				default:
+					tokens = append(tokens, vp8lCodeLengthToken{symbol: 0})
+					run--
+				}
+			}
+			continue
+		}
+		tokens = append(tokens, vp8lCodeLengthToken{symbol: int(l)})
+		run--
+		for run > 0 {
+			if run >= 3 {
+				n := min(run, 6)
+				tokens = append(tokens, vp8lCodeLengthToken{16, uint32(n - 3), 2})
+				run -= n
+				continue
+			}
+			tokens = append(tokens, vp8lCodeLengthToken{symbol: int(l)})
+			run--
+		}
+	}
+	return tokens
+}
+
+// huffmanCodeLengths 计算限长 Huffman 码长；超过 maxLength 时压缩频次差距后重算。
+func huffmanCodeLengths(freq []int, maxLength int) []uint8 {
+	counts := append([]int(nil), freq...)
+	for {
+		lengths, deepest := buildHuffmanLengths(counts)
+		if deepest <= maxLength {
+			return lengths
+		}
+		for i, f := range counts {
+			if f > 0 {
+				counts[i] = f/2 + 1
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	weight      int
+	symbol      int
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x any)   { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// buildHuffmanLengths 构建普通 Huffman 树，返回各符号码长与最大深度；唯一符号按码长 1 处理。
+func buildHuffmanLengths(freq []int) ([]uint8, int) {
+	lengths := make([]uint8, len(freq))
+	h := &huffmanHeap{}
+	for s, f := range freq {
+		if f > 0 {
+			*h = append(*h, &huffmanNode{weight: f, symbol: s})
+		}
+	}
+	switch h.Len() {
+	case 0:
+		return lengths, 0
+	case 1:
+		lengths[(*h)[0].symbol] = 1
+		return lengths, 1
+	}
+	heap.Init(h)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanNode)
+		b := heap.Pop(h).(*huffmanNode)
+		heap.Push(h, &huffmanNode{weight: a.weight + b.weight, symbol: min(a.symbol, b.symbol), left: a, right: b})
+	}
+
+	deepest := 0
+	type item struct {
+		node  *huffmanNode
+		depth int
+	}
+	stack := []item{{(*h)[0], 0}}
+	var leaves []item
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if it.node.left == nil {
+			leaves = append(leaves, it)
+			deepest = max(deepest, it.depth)
+			continue
+		}
+		stack = append(stack, item{it.node.left, it.depth + 1}, item{it.node.right, it.depth + 1})
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].node.symbol < leaves[j].node.symbol })
+	for _, leaf := range leaves {
+		if leaf.depth > 255 {
+			return lengths, leaf.depth
+		}
+		lengths[leaf.node.symbol] = uint8(leaf.depth)
+	}
+	return lengths, deepest
+}
+
+// vp8lBitWriter 按 VP8L 约定以低位在前的顺序写入比特。
+type vp8lBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+func (w *vp8lBitWriter) write(v uint32, n uint) {
+	if n == 0 {
+		return
+	}
+	w.acc |= uint64(v&(1<<n-1)) << w.nBits
+	w.nBits += n
+	for w.nBits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nBits -= 8
+	}
+}
+
+func (w *vp8lBitWriter) flush() []byte {
+	if w.nBits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nBits = 0, 0
+	}
+	return w.buf
+}
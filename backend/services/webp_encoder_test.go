@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func assertWebPRoundTrip(t *testing.T, src *image.NRGBA) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeWebPLossless(&buf, src); err != nil {
+		t.Fatalf("EncodeWebPLossless failed: %v", err)
+	}
+	decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("decode encoded webp failed: %v", err)
+	}
+	if decoded.Bounds() != src.Bounds() {
+		t.Fatalf("unexpected bounds %v, want %v", decoded.Bounds(), src.Bounds())
+	}
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			want := src.NRGBAAt(x, y)
+			got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+			if got != want {
+				t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	return buf.Len()
+}
+
+func TestEncodeWebPLosslessRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	noise := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(rng.Intn(256))
+	}
+	assertWebPRoundTrip(t, noise)
+
+	pattern := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	pattern.Set(0, 0, patternTestImage(1, 1, false).At(0, 0))
+	assertWebPRoundTrip(t, pattern)
+
+	column := image.NewNRGBA(image.Rect(0, 0, 1, 40))
+	for y := 0; y < 40; y++ {
+		column.SetNRGBA(0, y, color.NRGBA{R: uint8(y), G: 10, B: 200, A: uint8(255 - y*3)})
+	}
+	assertWebPRoundTrip(t, column)
+}
+
+func TestEncodeWebPLosslessCompressesSmoothImages(t *testing.T) {
+	smooth := image.NewNRGBA(image.Rect(0, 0, 200, 120))
+	src := patternTestImage(200, 120, false)
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 200; x++ {
+			smooth.Set(x, y, src.At(x, y))
+		}
+	}
+	// 渐变与大面积相同色块应通过预测与回溯引用大幅压缩。
+	if size := assertWebPRoundTrip(t, smooth); size > len(smooth.Pix)/8 {
+		t.Fatalf("expected smooth image to compress well, got %d bytes", size)
+	}
+}
+
+func TestHuffmanCodeLengthsRespectsLimit(t *testing.T) {
+	// 斐波那契频次会让普通 Huffman 树退化成一条长链。
+	freq := make([]int, 30)
+	a, b := 1, 1
+	for i := range freq {
+		freq[i] = a
+		a, b = b, a+b
+	}
+	lengths := huffmanCodeLengths(freq, vp8lMaxCodeLength)
+	kraft := 0.0
+	for s, l := range lengths {
+		if l == 0 || l > vp8lMaxCodeLength {
+			t.Fatalf("symbol %d got invalid length %d", s, l)
+		}
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	if kraft != 1 {
+		t.Fatalf("expected complete prefix code, kraft sum %v", kraft)
+	}
+}
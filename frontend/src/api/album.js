@@ -0,0 +1,53 @@
+import request from '../utils/request'
+
+export function listAlbums() {
+  return request.get('/albums')
+}
+
+// data: { name, description }
+export function createAlbum(data) {
+  return request.post('/albums', data)
+}
+
+export function getAlbum(id) {
+  return request.get(`/albums/${id}`)
+}
+
+// data: { name, description, cover_file_id }，省略的字段保持不变，cover_file_id 传 0 取消手动封面
+export function updateAlbum(id, data) {
+  return request.put(`/albums/${id}`, data)
+}
+
+export function deleteAlbum(id) {
+  return request.delete(`/albums/${id}`)
+}
+
+// params: { page, page_size }，缩略图通过 batchGetThumbnails 批量获取
+export function listAlbumItems(id, params) {
+  return request.get(`/albums/${id}/items`, { params })
+}
+
+export function addAlbumItems(id, fileIds) {
+  return request.post(`/albums/${id}/items`, { file_ids: fileIds })
+}
+
+export function removeAlbumItems(id, fileIds) {
+  return request.post(`/albums/${id}/items/remove`, { file_ids: fileIds })
+}
+
+// 列出的文件依次排到最前，其余文件保持原有相对顺序
+export function reorderAlbumItems(id, fileIds) {
+  return request.put(`/albums/${id}/items/order`, { file_ids: fileIds })
+}
+
+export function shareAlbum(id) {
+  return request.post(`/albums/${id}/share`)
+}
+
+export function unshareAlbum(id) {
+  return request.delete(`/albums/${id}/share`)
+}
+
+export function getSharedAlbum(token, params) {
+  return request.get(`/shared/albums/${token}`, { params })
+}
@@ -61,6 +61,10 @@ export function cancelUploadTask(uploadId) {
   })
 }
 
+export function getFileDetail(id) {
+  return request.get(`/files/${id}`)
+}
+
 export function deleteFile(id) {
   return request.delete(`/files/${id}`)
 }
@@ -85,14 +89,58 @@ export function getStorageQuota() {
   return request.get('/user/storage/quota')
 }
 
+export function getStorageBreakdown() {
+  return request.get('/user/storage/breakdown')
+}
+
 export function downloadFileBlob(fileId) {
   return request.get(`/files/${fileId}/download`, { responseType: 'blob' })
 }
 
-export function fetchThumbnailBlob(fileId) {
-  return request.get(`/files/${fileId}/thumbnail`, { responseType: 'blob' })
+// params: { expires_in, bind_ip, ip } 均可省略；返回的 url 无需登录即可下载，可交给下载工具或 <video>
+export function createDownloadUrl(fileId, params) {
+  return request.post(`/files/${fileId}/download-url`, params || {})
+}
+
+// preset 可选：small（网格）、large（大图预览）、full（全屏），不传时返回默认缩略图
+export function fetchThumbnailBlob(fileId, preset) {
+  return request.get(`/files/${fileId}/thumbnail`, {
+    params: preset ? { preset } : undefined,
+    responseType: 'blob'
+  })
+}
+
+// params: { w, h, fit: 'contain' | 'cover', format: 'jpeg' | 'webp' }，宽高须在服务端白名单内
+export function fetchImageVariantBlob(fileId, params) {
+  return request.get(`/files/${fileId}/variant`, { params, responseType: 'blob' })
 }
 
 export function fetchPreviewBlob(fileId) {
   return request.get(`/files/${fileId}/preview`, { responseType: 'blob' })
 }
+
+// params: { offset, limit } 按字节分页，或 { start_line, line_offset, lines } 按行分页（翻页时传回 next_line 与 next_line_offset）；charset 可选，用于手动指定编码
+export function getTextPreview(fileId, params) {
+  return request.get(`/files/${fileId}/text`, { params })
+}
+
+export function listArchiveEntries(fileId) {
+  return request.get(`/files/${fileId}/archive/entries`)
+}
+
+// path 为 listArchiveEntries 返回的条目路径
+export function fetchArchiveEntryBlob(fileId, path) {
+  return request.get(`/files/${fileId}/archive/entry`, { params: { path }, responseType: 'blob' })
+}
+
+// folderId 省略时解压到压缩包所在目录
+export function extractArchive(fileId, folderId) {
+  return request.post(`/files/${fileId}/archive/extract`, folderId ? { folder_id: folderId } : {}, {
+    timeout: UPLOAD_TIMEOUT_MS,
+  })
+}
+
+// 批量查询缩略图可用性，返回每个文件的 has_thumbnail 与 thumbnail_url；preset 可选
+export function batchGetThumbnails(fileIds, preset) {
+  return request.post('/files/thumbnails/batch', { file_ids: fileIds, preset })
+}
@@ -4,6 +4,11 @@ export function listFolders(parentId = 0) {
   return request.get('/folders', { params: { parent_id: parentId } })
 }
 
+// 目录与文件合并列表；翻页时传入上一页返回的 next_cursor
+export function listEntries(params) {
+  return request.get('/entries', { params })
+}
+
 export function createFolder(data) {
   return request.post('/folders', data)
 }
@@ -15,3 +20,24 @@ export function renameFolder(id, data) {
 export function deleteFolder(id) {
   return request.delete(`/folders/${id}`)
 }
+
+export function getFolderStats(id) {
+  return request.get(`/folders/${id}/stats`)
+}
+
+// depth 省略或为 0 时返回完整目录树
+export function getFolderTree(depth = 0) {
+  return request.get('/folders/tree', { params: { depth } })
+}
+
+export function getFolderAncestors(id) {
+  return request.get(`/folders/${id}/ancestors`)
+}
+
+export function resolvePath(path, type) {
+  return request.get('/resolve', { params: { path, type } })
+}
+
+export function ensureFolderPath(path) {
+  return request.post('/folders/path', { path })
+}
@@ -0,0 +1,11 @@
+import request from '../utils/request'
+
+// params: { cursor, limit }，翻页时原样回传上一页的 next_cursor
+export function getPhotoTimeline(params) {
+  return request.get('/photos/timeline', { params })
+}
+
+// params: { max_distance, page, page_size }，选中的副本通过 batchDeleteFiles 批量删除
+export function getSimilarImages(params) {
+  return request.get('/photos/similar', { params })
+}
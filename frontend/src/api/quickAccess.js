@@ -0,0 +1,23 @@
+import request from '../utils/request'
+
+// params: { type: 'file' | 'folder', keyword, page, page_size }
+export function listFavorites(params) {
+  return request.get('/favorites', { params })
+}
+
+export function addFavorite(targetType, targetId) {
+  return request.post('/favorites', { target_type: targetType, target_id: targetId })
+}
+
+export function removeFavorite(targetType, targetId) {
+  return request.delete(`/favorites/${targetType}/${targetId}`)
+}
+
+// params: { keyword, category, days, limit }
+export function listRecentUploads(params) {
+  return request.get('/recent/uploads', { params })
+}
+
+export function listRecentAccessed(params) {
+  return request.get('/recent/accessed', { params })
+}
@@ -4,8 +4,9 @@ export function listRecycleBin(params) {
   return request.get('/recycle-bin', { params })
 }
 
-export function restoreItem(id) {
-  return request.post(`/recycle-bin/${id}/restore`)
+// options: { target_folder_id, conflict: 'rename' | 'overwrite' | 'skip' | 'fail', dry_run }
+export function restoreItem(id, options = {}) {
+  return request.post(`/recycle-bin/${id}/restore`, options)
 }
 
 export function permanentDelete(id) {
@@ -15,3 +16,33 @@ export function permanentDelete(id) {
 export function emptyRecycleBin() {
   return request.post('/recycle-bin/empty')
 }
+
+export function batchRestore(data) {
+  return request.post('/recycle-bin/batch/restore', data)
+}
+
+export function batchPermanentDelete(data) {
+  return request.post('/recycle-bin/batch/delete', data)
+}
+
+export function getRecycleBinSettings() {
+  return request.get('/recycle-bin/settings')
+}
+
+// data: { retention_days }，传 null 恢复系统默认值
+export function updateRecycleBinSettings(data) {
+  return request.put('/recycle-bin/settings', data)
+}
+
+// path 为相对已删除文件夹的子路径，如 '/docs'
+export function listRecycleBinFolderContents(id, path = '/') {
+  return request.get(`/recycle-bin/${id}/contents`, { params: { path } })
+}
+
+export function restoreRecycleBinFolderFile(id, fileId, options = {}) {
+  return request.post(`/recycle-bin/${id}/files/${fileId}/restore`, options)
+}
+
+export function downloadRecycleBinFolderFileBlob(id, fileId) {
+  return request.get(`/recycle-bin/${id}/files/${fileId}/download`, { responseType: 'blob' })
+}
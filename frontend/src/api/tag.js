@@ -0,0 +1,36 @@
+import request from '../utils/request'
+
+export function listTags() {
+  return request.get('/tags')
+}
+
+export function createTag(data) {
+  return request.post('/tags', data)
+}
+
+// data: { name, color }，省略的字段保持不变
+export function updateTag(id, data) {
+  return request.put(`/tags/${id}`, data)
+}
+
+export function deleteTag(id) {
+  return request.delete(`/tags/${id}`)
+}
+
+export function mergeTags(id, sourceIds) {
+  return request.post(`/tags/${id}/merge`, { source_ids: sourceIds })
+}
+
+// data: { tag_ids, file_ids, folder_ids }
+export function bindTags(data) {
+  return request.post('/tags/bind', data)
+}
+
+export function unbindTags(data) {
+  return request.post('/tags/unbind', data)
+}
+
+// params: { keyword, category, type, tag_ids: '1,2', tag_mode: 'or' | 'and', page, page_size }
+export function search(params) {
+  return request.get('/search', { params })
+}